@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	"github.com/gavrylenkoIvan/balance-service/models"
+)
+
+func getAccount(s *service.Service, out *printer, args []string) error {
+	userId, err := parseUserId(firstArg(args))
+	if err != nil {
+		return err
+	}
+
+	account, err := s.GetAccount(userId)
+	if err != nil {
+		return err
+	}
+
+	if out.json {
+		return out.print(account, nil, nil)
+	}
+
+	if err := printAccount(out, account); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(account.History))
+	for _, change := range account.History {
+		rows = append(rows, []string{change.Date.Format(time.DateTime), change.Status,
+			strconv.FormatBool(change.CreditsBlocked), change.Actor, change.Reason})
+	}
+
+	fmt.Fprintln(out.w)
+	return out.print(account.History, []string{"DATE", "STATUS", "CREDITS BLOCKED", "ACTOR", "REASON"}, rows)
+}
+
+// freeze blocks debits of the account, with -block-credits credits are blocked too
+func freeze(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("freeze", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason, required")
+	debitOnly := flags.Bool("debit-only", false, "only block debits, account stays debit_blocked instead of frozen")
+	blockCredits := flags.Bool("block-credits", false, "block credits of frozen account too")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	input := models.AccountStatusInput{
+		Status:       models.AccountFrozen,
+		Reason:       *reason,
+		BlockCredits: *blockCredits,
+	}
+
+	if *debitOnly {
+		input.Status = models.AccountDebitBlocked
+	}
+
+	return setStatus(s, out, input, flags.Arg(0))
+}
+
+func unfreeze(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("unfreeze", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	return setStatus(s, out, models.AccountStatusInput{Status: models.AccountActive, Reason: *reason}, flags.Arg(0))
+}
+
+func closeAccount(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("close", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason, required")
+	finalPayout := flags.Bool("final-payout", false, "debit all remaining money before closing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	return setStatus(s, out, models.AccountStatusInput{
+		Status:      models.AccountClosed,
+		Reason:      *reason,
+		FinalPayout: *finalPayout,
+	}, flags.Arg(0))
+}
+
+func setStatus(s *service.Service, out *printer, input models.AccountStatusInput, userId string) error {
+	id, err := parseUserId(userId)
+	if err != nil {
+		return err
+	}
+
+	input.UserId = id
+	input.Operator = operator
+
+	account, err := s.SetAccountStatus(input)
+	if err != nil {
+		return err
+	}
+
+	return printAccount(out, account)
+}
+
+func printAccount(out *printer, account models.Account) error {
+	var changedAt string
+	if account.StatusChangedAt != nil {
+		changedAt = account.StatusChangedAt.Format(time.DateTime)
+	}
+
+	return out.print(account, []string{"USER", "STATUS", "CREDITS BLOCKED", "CHANGED AT", "ACTOR", "REASON"},
+		[][]string{{strconv.Itoa(account.UserId), account.Status, strconv.FormatBool(account.CreditsBlocked),
+			changedAt, account.Actor, account.Reason}})
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	return args[0]
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+)
+
+// errBrokenChain makes verify-chain exit with error, so it can be used in scripts
+var errBrokenChain = errors.New("transaction chain is broken")
+
+// verifyChain walks hash chains of all wallets, it fails if any of them is broken
+func verifyChain(s *service.Service, out *printer, args []string) error {
+	result, err := s.VerifyChain()
+	if err != nil {
+		return err
+	}
+
+	row := []string{strconv.FormatBool(result.Valid), strconv.Itoa(result.Wallets), strconv.Itoa(result.Transactions),
+		strconv.Itoa(result.Unchained), "", "", "", ""}
+	if result.Break != nil {
+		row[4] = strconv.Itoa(result.Break.UserId)
+		row[5] = result.Break.Currency
+		row[6] = strconv.Itoa(result.Break.TransactionId)
+		row[7] = result.Break.Reason
+	}
+
+	err = out.print(result, []string{"VALID", "WALLETS", "TRANSACTIONS", "UNCHAINED", "BROKEN USER", "CURRENCY",
+		"TRANSACTION", "REASON"}, [][]string{row})
+	if err != nil {
+		return err
+	}
+
+	if !result.Valid {
+		return errBrokenChain
+	}
+
+	return nil
+}
+
+// checkpoint exports signed checkpoint of all chain heads
+func checkpoint(s *service.Service, out *printer, args []string) error {
+	checkpoint, err := s.CreateCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	return out.print(checkpoint, []string{"CREATED AT", "LAST TRANSACTION", "CHAINS", "ROOT"},
+		[][]string{{checkpoint.CreatedAt.Format(time.DateTime), strconv.Itoa(checkpoint.LastTransactionId),
+			strconv.Itoa(len(checkpoint.Heads)), checkpoint.Root}})
+}
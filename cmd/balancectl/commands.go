@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	"github.com/gavrylenkoIvan/balance-service/models"
+)
+
+// statementPageSize is the number of transactions read at once while exporting a statement
+const statementPageSize = 500
+
+func getBalance(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("balance", flag.ContinueOnError)
+	currency := flags.String("currency", "", "also print total converted to currency")
+	at := flags.String("at", "", "print balance at this moment")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	userId, err := parseUserId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var balance models.Balance
+	if *at != "" {
+		atTime, err := parseTime(*at)
+		if err != nil {
+			return err
+		}
+
+		balance, err = s.GetBalanceAt(userId, atTime, *currency)
+		if err != nil {
+			return err
+		}
+	} else {
+		balance, err = s.GetBalance(userId, *currency)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows := make([][]string, 0, len(balance.Wallets)+1)
+	for _, wallet := range balance.Wallets {
+		rows = append(rows, []string{wallet.Currency, money(wallet.Balance)})
+	}
+
+	if balance.Total != nil {
+		rows = append(rows, []string{"TOTAL " + balance.Currency, money(*balance.Total)})
+	}
+
+	return out.print(balance, []string{"CURRENCY", "BALANCE"}, rows)
+}
+
+func listTransactions(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("transactions", flag.ContinueOnError)
+	page := flags.Int("page", 1, "page number")
+	limit := flags.Int("limit", 10, "transactions per page")
+	sort := flags.String("sort", "date", "sort column")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	userId, err := parseUserId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	transactions, err := s.GetTransactions(userId, models.Page{Page: *page, Limit: *limit, Sort: *sort})
+	if err != nil {
+		return err
+	}
+
+	return printTransactions(out, transactions)
+}
+
+// adjust proposes adjustment, positive amount is credited and negative is debited after approval
+func adjust(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("adjust", flag.ContinueOnError)
+	reason := flags.String("reason", "", "reason of the adjustment, required")
+	ticket := flags.String("ticket", "", "ticket reference")
+	currency := flags.String("currency", models.BaseCurrency, "wallet currency")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	userId, err := parseUserId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	amount, err := strconv.ParseFloat(flags.Arg(1), 32)
+	if err != nil || amount == 0 {
+		return fmt.Errorf("incorrect amount %q", flags.Arg(1))
+	}
+
+	input := models.AdjustmentInput{
+		UserId:    userId,
+		Direction: models.AdjustmentCredit,
+		Amount:    float32(amount),
+		Currency:  *currency,
+		Reason:    *reason,
+		Ticket:    *ticket,
+		Operator:  operator,
+	}
+
+	if amount < 0 {
+		input.Direction = models.AdjustmentDebit
+		input.Amount = -input.Amount
+	}
+
+	adjustment, err := s.ProposeAdjustment(input)
+	if err != nil {
+		return err
+	}
+
+	return printAdjustments(out, adjustment, []models.Adjustment{adjustment})
+}
+
+// listAdjustments prints adjustments or a single adjustment with its audit trail
+func listAdjustments(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("adjustments", flag.ContinueOnError)
+	status := flags.String("status", models.AdjustmentPending, "pending, approved, rejected or all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		id, err := parseId(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		adjustment, err := s.GetAdjustment(id)
+		if err != nil {
+			return err
+		}
+
+		if out.json {
+			return out.print(adjustment, nil, nil)
+		}
+
+		rows := make([][]string, 0, len(adjustment.Events))
+		for _, event := range adjustment.Events {
+			rows = append(rows, []string{event.Date.Format(time.DateTime), event.Action, event.Actor, event.Comment})
+		}
+
+		if err := printAdjustments(out, adjustment, []models.Adjustment{adjustment}); err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out.w)
+		return out.print(adjustment.Events, []string{"DATE", "ACTION", "ACTOR", "COMMENT"}, rows)
+	}
+
+	if *status == "all" {
+		*status = ""
+	}
+
+	adjustments, err := s.ListAdjustments(*status)
+	if err != nil {
+		return err
+	}
+
+	return printAdjustments(out, adjustments, adjustments)
+}
+
+func approveAdjustment(s *service.Service, out *printer, args []string) error {
+	return reviewAdjustment(s.ApproveAdjustment, out, "approve", args)
+}
+
+func rejectAdjustment(s *service.Service, out *printer, args []string) error {
+	return reviewAdjustment(s.RejectAdjustment, out, "reject", args)
+}
+
+func reviewAdjustment(review func(int, models.AdjustmentReview) (models.Adjustment, error), out *printer,
+	name string, args []string) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	comment := flags.String("comment", "", "review comment")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	id, err := parseId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	adjustment, err := review(id, models.AdjustmentReview{Comment: *comment, Operator: operator})
+	if err != nil {
+		return err
+	}
+
+	return printAdjustments(out, adjustment, []models.Adjustment{adjustment})
+}
+
+func printAdjustments(out *printer, value interface{}, adjustments []models.Adjustment) error {
+	rows := make([][]string, 0, len(adjustments))
+	for _, a := range adjustments {
+		rows = append(rows, []string{strconv.Itoa(a.ID), strconv.Itoa(a.UserId), a.Direction, money(a.Amount),
+			a.Currency, a.Status, a.CreatedBy, a.ReviewedBy, a.Ticket, a.Reason})
+	}
+
+	return out.print(value, []string{"ID", "USER", "DIRECTION", "AMOUNT", "CURRENCY", "STATUS", "CREATED BY",
+		"REVIEWED BY", "TICKET", "REASON"}, rows)
+}
+
+// statement exports all user`s transactions made between from and to
+func statement(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("statement", flag.ContinueOnError)
+	from := flags.String("from", "", "start of the period")
+	to := flags.String("to", "", "end of the period (now by default)")
+	file := flags.String("file", "", "write statement to CSV file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	userId, err := parseUserId(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var fromTime time.Time
+	if *from != "" {
+		if fromTime, err = parseTime(*from); err != nil {
+			return err
+		}
+	}
+
+	toTime := time.Now()
+	if *to != "" {
+		if toTime, err = parseTime(*to); err != nil {
+			return err
+		}
+	}
+
+	var transactions []models.Transaction
+	for page := 1; ; page++ {
+		batch, err := s.GetTransactions(userId, models.Page{Page: page, Limit: statementPageSize, Sort: "date"})
+		if err != nil {
+			return err
+		}
+
+		for _, t := range batch {
+			if !t.Date.Before(fromTime) && !t.Date.After(toTime) {
+				transactions = append(transactions, t)
+			}
+		}
+
+		if len(batch) < statementPageSize {
+			break
+		}
+	}
+
+	if *file == "" {
+		return printTransactions(out, transactions)
+	}
+
+	if err := writeStatement(*file, transactions); err != nil {
+		return err
+	}
+
+	return out.print(map[string]interface{}{"file": *file, "transactions": len(transactions)},
+		[]string{"FILE", "TRANSACTIONS"}, [][]string{{*file, strconv.Itoa(len(transactions))}})
+}
+
+func writeStatement(name string, transactions []models.Transaction) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write([]string{"id", "date", "operation", "amount", "currency", "balance_after", "link_id"})
+	for _, t := range transactions {
+		w.Write(transactionRow(t.ToTransactionDTO()))
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func printTransactions(out *printer, transactions []models.Transaction) error {
+	result := make([]models.TransactionDTO, 0, len(transactions))
+	rows := make([][]string, 0, len(transactions))
+	for _, t := range transactions {
+		dto := t.ToTransactionDTO()
+		result = append(result, dto)
+		rows = append(rows, transactionRow(dto))
+	}
+
+	return out.print(result, []string{"ID", "DATE", "OPERATION", "AMOUNT", "CURRENCY", "BALANCE AFTER", "LINK ID"}, rows)
+}
+
+func transactionRow(t models.TransactionDTO) []string {
+	return []string{strconv.Itoa(t.ID), t.Date, t.Operation, money(t.Amount), t.Currency, money(t.BalanceAfter), t.LinkId}
+}
+
+// replay marks published outbox events as unpublished, the service publishes them again
+func replay(s *service.Service, out *printer, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fromId := flags.Int64("from-id", 0, "id of the first replayed event, required")
+	toId := flags.Int64("to-id", 0, "id of the last replayed event, the last event by default")
+	eventType := flags.String("type", "", "type of replayed events, e.g. "+models.OutboxBalanceChanged+", all by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	input := models.OutboxReplayInput{FromId: *fromId, ToId: *toId, Type: *eventType}
+	replayed, err := s.ReplayOutbox(input)
+	if err != nil {
+		return err
+	}
+
+	result := struct {
+		models.OutboxReplayInput
+		Replayed int `json:"replayed"`
+	}{input, replayed}
+
+	return out.print(result, []string{"REPLAYED"}, [][]string{{strconv.Itoa(replayed)}})
+}
+
+func parseId(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("incorrect id %q", value)
+	}
+
+	return id, nil
+}
+
+func parseUserId(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("incorrect user id %q", value)
+	}
+
+	return id, nil
+}
+
+// parseTime accepts the same formats as the API
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("incorrect time %q", value)
+}
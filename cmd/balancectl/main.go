@@ -0,0 +1,130 @@
+// balancectl is a command line tool for operators, it works with the same database and services as the API
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/config"
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// operatorKeyEnv holds the operator key when -operator-key is not given, keys in arguments are seen by other users
+const operatorKeyEnv = "BALANCECTL_OPERATOR_KEY"
+
+// operator is the name of the operator whose key is given, commands which change balances or accounts
+// are rejected without it
+var operator string
+
+type command struct {
+	usage string
+	run   func(s *service.Service, out *printer, args []string) error
+}
+
+var commands = map[string]command{
+	"balance":             {usage: "balance [-currency USD] [-at 2023-06-01] <user_id>", run: getBalance},
+	"transactions":        {usage: "transactions [-page 1] [-limit 10] [-sort date] <user_id>", run: listTransactions},
+	"adjust":              {usage: "adjust -reason <reason> [-ticket <ticket>] [-currency EUR] <user_id> <amount>", run: adjust},
+	"adjustments":         {usage: "adjustments [-status pending|approved|rejected|all] [<adjustment_id>]", run: listAdjustments},
+	"approve":             {usage: "approve [-comment <comment>] <adjustment_id>", run: approveAdjustment},
+	"reject":              {usage: "reject [-comment <comment>] <adjustment_id>", run: rejectAdjustment},
+	"account":             {usage: "account <user_id>", run: getAccount},
+	"freeze":              {usage: "freeze -reason <reason> [-debit-only] [-block-credits] <user_id>", run: freeze},
+	"unfreeze":            {usage: "unfreeze [-reason <reason>] <user_id>", run: unfreeze},
+	"close":               {usage: "close -reason <reason> [-final-payout] <user_id>", run: closeAccount},
+	"statement":           {usage: "statement [-from 2023-06-01] [-to 2023-07-01] [-file statement.csv] <user_id>", run: statement},
+	"reconcile":           {usage: "reconcile", run: reconcile},
+	"reconciliation":      {usage: "reconciliation [<report_id>]", run: getReconciliation},
+	"approve-corrections": {usage: "approve-corrections <report_id>", run: approveCorrections},
+	"verify-chain":        {usage: "verify-chain", run: verifyChain},
+	"checkpoint":          {usage: "checkpoint", run: checkpoint},
+	"replay":              {usage: "replay -from-id <event_id> [-to-id <event_id>] [-type <type>]", run: replay},
+}
+
+func main() {
+	flags := flag.NewFlagSet("balancectl", flag.ExitOnError)
+	output := flags.String("output", "table", "output format: table or json")
+	operatorKey := flags.String("operator-key", os.Getenv(operatorKeyEnv),
+		"key of the operator from auth.operator_keys, $"+operatorKeyEnv+" by default")
+	configFile := flags.String("config", config.DefaultFile, "path to config file")
+	flags.Usage = func() { usage(flags) }
+	flags.Parse(os.Args[1:])
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	out, err := newPrinter(*output, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.Load(*configFile, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *operatorKey != "" {
+		var ok bool
+		if operator, ok = cfg.Operator(*operatorKey); !ok {
+			log.Fatal("unknown operator key")
+		}
+	}
+
+	if cfg.Storage != config.StoragePostgres {
+		log.Fatal("balancectl works only with postgres storage")
+	}
+
+	logger, err := logging.NewLogger(cfg.Logger())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pq, err := repo.InitDB(cfg.Postgres())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	replica, err := repo.InitReplica(cfg.Postgres())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// balances changed by the tool must be invalidated in the cache shared with the service
+	cache, err := repo.NewWalletCache(cfg.WalletCache())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := service.NewService(repo.NewRepo(pq, replica, cache, nil, logger), cfg.RatesProvider(), cfg.Service(), logger)
+
+	if err := cmd.run(s, out, flags.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+}
+
+func usage(flags *flag.FlagSet) {
+	fmt.Fprintln(flags.Output(), "Usage: balancectl [-output table|json] <command> [flags] [args]")
+	fmt.Fprintln(flags.Output(), "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(flags.Output(), "  %s\n", commands[name].usage)
+	}
+
+	fmt.Fprintln(flags.Output(), "\nFlags:")
+	flags.PrintDefaults()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer prints results either as a table or as indented JSON
+type printer struct {
+	json bool
+	w    io.Writer
+}
+
+func newPrinter(output string, w io.Writer) (*printer, error) {
+	switch output {
+	case "table":
+		return &printer{w: w}, nil
+	case "json":
+		return &printer{json: true, w: w}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported output %q", output)
+}
+
+// print writes value as JSON or headers and rows as a table
+func (p *printer) print(value interface{}, headers []string, rows [][]string) error {
+	if p.json {
+		out, err := json.MarshalIndent(value, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(p.w, string(out))
+		return err
+	}
+
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func money(value float32) string {
+	return fmt.Sprintf("%.2f", value)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	"github.com/gavrylenkoIvan/balance-service/models"
+)
+
+// reconcile recomputes balances from the transaction log and prints mismatches
+func reconcile(s *service.Service, out *printer, args []string) error {
+	report, err := s.Reconcile(operator)
+	if err != nil {
+		return err
+	}
+
+	return printReconciliation(out, report)
+}
+
+// getReconciliation prints the report, the last one if id is omitted
+func getReconciliation(s *service.Service, out *printer, args []string) error {
+	var (
+		report models.ReconciliationReport
+		err    error
+	)
+
+	if len(args) > 0 {
+		id, err := parseId(args[0])
+		if err != nil {
+			return err
+		}
+
+		report, err = s.GetReconciliation(id)
+		if err != nil {
+			return err
+		}
+	} else {
+		report, err = s.GetLastReconciliation()
+		if err != nil {
+			return err
+		}
+	}
+
+	return printReconciliation(out, report)
+}
+
+func approveCorrections(s *service.Service, out *printer, args []string) error {
+	id, err := parseId(firstArg(args))
+	if err != nil {
+		return err
+	}
+
+	report, err := s.ApproveCorrections(id, operator)
+	if err != nil {
+		return err
+	}
+
+	return printReconciliation(out, report)
+}
+
+func printReconciliation(out *printer, report models.ReconciliationReport) error {
+	if out.json {
+		return out.print(report, nil, nil)
+	}
+
+	err := out.print(report, []string{"ID", "STATUS", "WALLETS", "MISMATCHES", "CREATED BY", "FINISHED AT", "APPROVED BY"},
+		[][]string{{strconv.Itoa(report.ID), report.Status, strconv.Itoa(report.Wallets), strconv.Itoa(len(report.Mismatches)),
+			report.CreatedBy, report.FinishedAt.Format(time.DateTime), report.ApprovedBy}})
+	if err != nil || len(report.Mismatches) == 0 {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Mismatches))
+	for _, m := range report.Mismatches {
+		rows = append(rows, []string{strconv.Itoa(m.UserId), m.Currency, money(m.Balance), money(m.Expected),
+			money(m.Difference), strconv.Itoa(m.Transactions)})
+	}
+
+	fmt.Fprintln(out.w)
+	return out.print(report.Mismatches, []string{"USER", "CURRENCY", "BALANCE", "EXPECTED", "DIFFERENCE", "TRANSACTIONS"}, rows)
+}
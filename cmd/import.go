@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+)
+
+// runImport imports CSV file, usage: balance import [-dry-run] file.csv
+func runImport(s *service.Service, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only validate the file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New("usage: balance import [-dry-run] file.csv")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var result interface{}
+	if *dryRun {
+		result, err = s.ValidateImport(file)
+	} else {
+		result, err = s.RunImport(file)
+	}
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(out))
+	return nil
+}
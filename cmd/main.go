@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/gavrylenkoIvan/balance-service/internal/config"
 	"github.com/gavrylenkoIvan/balance-service/internal/handler"
 	"github.com/gavrylenkoIvan/balance-service/internal/repo"
 	"github.com/gavrylenkoIvan/balance-service/internal/service"
 	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
-	"github.com/joho/godotenv"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 )
 
 // @title Balance Service
@@ -21,52 +26,112 @@ import (
 // @BasePath /
 
 func main() {
-	err := initConfig()
-	if err != nil {
-		log.Fatal(err)
+	flags := pflag.NewFlagSet("balance", pflag.ExitOnError)
+	configFile := flags.String("config", config.DefaultFile, "path to config file")
+	config.Flags(flags)
+	// flags of subcommands are parsed by the subcommands
+	flags.SetInterspersed(false)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: balance [flags] [config | migrate <command> | import [-dry-run] <file>]")
+		flags.PrintDefaults()
 	}
+	flags.Parse(os.Args[1:])
+	args := flags.Args()
 
-	host := viper.GetString("pg.host")
-	if os.Getenv("COMPOSE") == "true" {
-		log.Println("Running in compose, using pg.compose_host")
-		host = viper.GetString("pg.compose_host")
-	} else {
-		log.Println("Running in dev, using pg.host")
+	cfg, err := config.Load(*configFile, flags)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Loading environment variables
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("failed to init .env")
+	if len(args) > 0 && args[0] == "config" {
+		fmt.Print(cfg)
+		return
 	}
 
-	logger, err := logging.InitLogger()
+	logger, err := logging.NewLogger(cfg.Logger())
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	cfg := repo.Config{
-		Port:     viper.GetString("pg.port"),
-		Password: os.Getenv("PG_PASSWORD"),
-		Username: viper.GetString("pg.username"),
-		Host:     host,
-		Name:     viper.GetString("pg.name"),
-		SSL:      viper.GetString("pg.sslmode"),
+	var (
+		storage  *repo.Repo
+		migrator *repo.Migrator
+	)
+	if cfg.Storage == config.StorageMemory {
+		// system accounts are created by migrations in postgres
+		storage = repo.NewMemoryRepo(logger, append(devUsers, cfg.Fees.RevenueAccount, cfg.Escrow.Account)...)
+	} else {
+		storage, migrator, err = openPostgres(cfg, logger)
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
 	}
 
-	pq, err := repo.InitDB(cfg)
-	if err != nil {
-		logger.Fatal(err.Error())
+	if len(args) > 0 && args[0] == "migrate" {
+		if migrator == nil {
+			logger.Fatal("migrations need postgres storage")
+		}
+
+		if err := runMigrate(migrator, args[1:]); err != nil {
+			logger.Fatal(err.Error())
+		}
+		return
+	}
+
+	if migrator != nil && cfg.Migrations.OnStart {
+		if err := migrator.Up(); err != nil {
+			logger.Fatal(err.Error())
+		}
 	}
 
-	repo := repo.NewRepo(pq, logger)
-	service := service.NewService(repo, logger)
+	service := service.NewService(storage, cfg.RatesProvider(), cfg.Service(), logger)
+
+	if len(args) > 0 && args[0] == "import" {
+		if err := runImport(service, args[1:]); err != nil {
+			logger.Fatal(err.Error())
+		}
+		return
+	}
+
+	stop := make(chan struct{})
+	go service.ScheduleReconciliation(stop)
+	go service.ScheduleCheckpoints(stop)
+	go service.ListenBalanceChanges(stop)
+	go service.RunSchedules(stop)
+	go service.RunRenewals(stop)
+	go service.RunOverdraftCharges(stop)
+	go service.RunPromoExpiry(stop)
+	go service.RunEscrowExpiry(stop)
+	go service.RunOutbox(stop)
+
 	handler := handler.NewHandler(service, logger)
 
-	log.Fatal(http.ListenAndServe(":"+viper.GetString("port"), handler.InitRoutes()))
-}
+	srv := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      handler.InitRoutes(cfg.Handler()),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+
+	logger.Infof("starting with config:\n%s", cfg)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal(err.Error())
+		}
+	}()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	<-ctx.Done()
+
+	logger.Infof("shutting down")
+	shutdown, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdown); err != nil {
+		logger.Infof("failed to finish requests: %s", err.Error())
+	}
 
-func initConfig() error {
-	viper.AddConfigPath("configs")
-	viper.SetConfigName("config")
-	return viper.ReadInConfig()
+	close(stop)
+	// imports are failed after their current chunk, so no job is left running
+	service.StopImports()
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/schema"
+)
+
+const migrateUsage = "usage: balance migrate up | down [steps] | version | seed"
+
+// runMigrate applies or reverts embedded migrations, usage: balance migrate up | down [steps] | version | seed
+func runMigrate(migrator *repo.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		return migrator.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("incorrect number of steps %q", args[1])
+			}
+		}
+
+		return migrator.Down(steps)
+	case "version":
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(version)
+		return nil
+	case "seed":
+		return migrator.Seed(schema.DevFixture)
+	}
+
+	return errors.New(migrateUsage)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/gavrylenkoIvan/balance-service/internal/config"
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/schema"
+)
+
+// devUsers are created in memory storage, they are the users of the development fixture
+var devUsers = []int{1, 2, 3, 4, 5, 6, 7}
+
+// openPostgres connects to primary database, read replica and wallet cache if they are configured,
+// balance changes are listened on primary database
+func openPostgres(cfg *config.Config, logger logging.Logger) (*repo.Repo, *repo.Migrator, error) {
+	pq, err := repo.InitDB(cfg.Postgres())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replica, err := repo.InitReplica(cfg.Postgres())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cache, err := repo.NewWalletCache(cfg.WalletCache())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := repo.NewMigrator(pq, schema.Migrations, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return repo.NewRepo(pq, replica, cache, repo.NewChangeListener(cfg.Postgres().DSN(), pq, logger), logger), migrator, nil
+}
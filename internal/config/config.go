@@ -0,0 +1,710 @@
+package config
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/handler"
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/rates"
+	"github.com/joho/godotenv"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is read when no other config file is given, it may be missing
+const DefaultFile = "configs/config.yml"
+
+// EnvPrefix prefixes environment variables overriding config keys,
+// e.g. BALANCE_DB_HOST overrides db.host
+const EnvPrefix = "BALANCE"
+
+// Storages the service may keep its data in
+const (
+	StoragePostgres = "postgres"
+	// StorageMemory keeps users, wallets and transactions in memory, the rest of features is not available
+	StorageMemory = "memory"
+)
+
+// redacted replaces secrets in printed config
+const redacted = "****"
+
+// Config is the configuration shared by all binaries. Values are taken from defaults,
+// the config file, environment and flags, every next source overrides the previous one
+type Config struct {
+	Storage        string         `yaml:"storage"`
+	Server         Server         `yaml:"server"`
+	DB             DB             `yaml:"db"`
+	Cache          Cache          `yaml:"cache"`
+	Rates          Rates          `yaml:"rates"`
+	Auth           Auth           `yaml:"auth"`
+	Limits         Limits         `yaml:"limits"`
+	Logging        Logging        `yaml:"logging"`
+	Exchange       Exchange       `yaml:"exchange"`
+	Reconciliation Reconciliation `yaml:"reconciliation"`
+	Audit          Audit          `yaml:"audit"`
+	Streams        Streams        `yaml:"streams"`
+	Schedules      Schedules      `yaml:"schedules"`
+	Subscriptions  Subscriptions  `yaml:"subscriptions"`
+	Overdraft      Overdraft      `yaml:"overdraft"`
+	Fees           Fees           `yaml:"fees"`
+	Promo          Promo          `yaml:"promo"`
+	Escrow         Escrow         `yaml:"escrow"`
+	Outbox         Outbox         `yaml:"outbox"`
+	Migrations     Migrations     `yaml:"migrations"`
+}
+
+type Server struct {
+	Port         string        `yaml:"port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// ShutdownTimeout bounds waiting for requests in flight on shutdown, background imports are waited for after it
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type DB struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	// MaxOpenConns limits connections of the pool, zero means unlimited
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// StatementTimeout aborts statements running longer, zero disables it
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+	// ReplicaHost is the host of read replica serving balances and history, it is not used when empty
+	ReplicaHost string `yaml:"replica_host"`
+	// ReplicaPort defaults to port of primary
+	ReplicaPort string `yaml:"replica_port"`
+}
+
+type Cache struct {
+	// Driver is lru or redis, balances are not cached when it is empty
+	Driver string        `yaml:"driver"`
+	Size   int           `yaml:"size"`
+	TTL    time.Duration `yaml:"ttl"`
+	// RedisAddr is host:port of redis used by redis driver
+	RedisAddr     string `yaml:"redis_addr"`
+	RedisPassword string `yaml:"redis_password"`
+	RedisDB       int    `yaml:"redis_db"`
+}
+
+type Rates struct {
+	URL       string `yaml:"url"`
+	AccessKey string `yaml:"access_key"`
+}
+
+type Auth struct {
+	// APIKeys accepted in X-API-Key header, authentication is disabled when empty
+	APIKeys []string `yaml:"api_keys"`
+	// OperatorKeys are "name:key" entries, the key is accepted like API keys and identifies the operator
+	// who proposes, approves or reviews changes
+	OperatorKeys []string `yaml:"operator_keys"`
+}
+
+type Limits struct {
+	// BodySize limits request body, e.g. 4M
+	BodySize      string `yaml:"body_size"`
+	BatchMaxItems int    `yaml:"batch_max_items"`
+}
+
+type Logging struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type Exchange struct {
+	QuoteTTL       time.Duration `yaml:"quote_ttl"`
+	Spread         float64       `yaml:"spread"`
+	RevenueAccount int           `yaml:"revenue_account"`
+}
+
+type Reconciliation struct {
+	Interval time.Duration `yaml:"interval"`
+}
+
+type Audit struct {
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval"`
+	CheckpointFile     string        `yaml:"checkpoint_file"`
+	SigningKey         string        `yaml:"signing_key"`
+}
+
+type Streams struct {
+	// MaxStreams limits concurrent balance streams, zero means unlimited
+	MaxStreams        int           `yaml:"max_streams"`
+	MaxStreamsPerUser int           `yaml:"max_streams_per_user"`
+	Heartbeat         time.Duration `yaml:"heartbeat"`
+}
+
+type Schedules struct {
+	// PollInterval between checks for due schedules, zero disables the worker
+	PollInterval  time.Duration `yaml:"poll_interval"`
+	BatchSize     int           `yaml:"batch_size"`
+	MaxRetries    int           `yaml:"max_retries"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
+}
+
+type Subscriptions struct {
+	// PollInterval between checks for due renewals, zero disables the worker
+	PollInterval  time.Duration `yaml:"poll_interval"`
+	BatchSize     int           `yaml:"batch_size"`
+	RetryInterval time.Duration `yaml:"retry_interval"`
+	GracePeriod   time.Duration `yaml:"grace_period"`
+}
+
+type Overdraft struct {
+	// PollInterval between checks for overdrawn wallets, zero disables the worker
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// InterestRate is yearly, e.g. 0.2 is 20%. Nothing is charged when it and DailyFee are zero
+	InterestRate float64 `yaml:"interest_rate"`
+	DailyFee     float64 `yaml:"daily_fee"`
+}
+
+type Fees struct {
+	// RevenueAccount is the system account which receives transfer fees
+	RevenueAccount int `yaml:"revenue_account"`
+}
+
+type Promo struct {
+	TTL        time.Duration `yaml:"ttl"`
+	SpendFirst bool          `yaml:"spend_first"`
+	// PollInterval between checks for expired grants, zero disables the worker
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+}
+
+type Escrow struct {
+	// Account is the system account which holds escrowed money
+	Account int           `yaml:"account"`
+	TTL     time.Duration `yaml:"ttl"`
+	// PollInterval between checks for expired escrows, zero disables the worker
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+}
+
+type Outbox struct {
+	// WebhookURL receives outbox events, they are not published when it is empty
+	WebhookURL string `yaml:"webhook_url"`
+	// PollInterval between publications of events, zero disables the worker
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// Retention is how long events may be replayed
+	Retention time.Duration `yaml:"retention"`
+}
+
+type Migrations struct {
+	OnStart bool `yaml:"on_start"`
+}
+
+var defaults = map[string]interface{}{
+	"storage":                      StoragePostgres,
+	"server.port":                  "8080",
+	"server.read_timeout":          "10s",
+	"server.write_timeout":         "30s",
+	"server.shutdown_timeout":      "15s",
+	"db.host":                      "localhost",
+	"db.port":                      "5432",
+	"db.user":                      "postgres",
+	"db.password":                  "",
+	"db.name":                      "postgres",
+	"db.sslmode":                   "disable",
+	"db.max_open_conns":            20,
+	"db.max_idle_conns":            5,
+	"db.conn_max_lifetime":         "30m",
+	"db.conn_max_idle_time":        "5m",
+	"db.statement_timeout":         "30s",
+	"db.replica_host":              "",
+	"db.replica_port":              "",
+	"cache.driver":                 "",
+	"cache.size":                   10000,
+	"cache.ttl":                    "1m",
+	"cache.redis_addr":             "localhost:6379",
+	"cache.redis_password":         "",
+	"cache.redis_db":               0,
+	"rates.url":                    "http://api.exchangeratesapi.io/v1/latest",
+	"rates.access_key":             "",
+	"auth.api_keys":                []string{},
+	"auth.operator_keys":           []string{},
+	"limits.body_size":             "4M",
+	"limits.batch_max_items":       1000,
+	"logging.level":                "info",
+	"logging.format":               "console",
+	"exchange.quote_ttl":           "30s",
+	"exchange.spread":              0.01,
+	"exchange.revenue_account":     0,
+	"reconciliation.interval":      "24h",
+	"audit.checkpoint_interval":    "1h",
+	"audit.checkpoint_file":        "checkpoints.jsonl",
+	"audit.signing_key":            "",
+	"streams.max_streams":          1000,
+	"streams.max_streams_per_user": 5,
+	"streams.heartbeat":            "15s",
+	"schedules.poll_interval":      "1m",
+	"schedules.batch_size":         100,
+	"schedules.max_retries":        3,
+	"schedules.retry_interval":     "1h",
+	"subscriptions.poll_interval":  "1m",
+	"subscriptions.batch_size":     100,
+	"subscriptions.retry_interval": "24h",
+	"subscriptions.grace_period":   "72h",
+	"overdraft.poll_interval":      "1h",
+	"overdraft.batch_size":         100,
+	"overdraft.interest_rate":      0.0,
+	"overdraft.daily_fee":          0.0,
+	"fees.revenue_account":         0,
+	"promo.ttl":                    "720h",
+	"promo.spend_first":            true,
+	"promo.poll_interval":          "1h",
+	"promo.batch_size":             100,
+	"escrow.account":               -1,
+	"escrow.ttl":                   "336h",
+	"escrow.poll_interval":         "1m",
+	"escrow.batch_size":            100,
+	"outbox.webhook_url":           "",
+	"outbox.poll_interval":         "5s",
+	"outbox.batch_size":            100,
+	"outbox.retention":             "168h",
+	"migrations.on_start":          true,
+}
+
+// envAliases are environment variables read besides the prefixed ones,
+// they are checked in order after the prefixed variable
+var envAliases = map[string][]string{
+	"db.host":              {"DB_HOST"},
+	"db.password":          {"DB_PASSWORD", "PG_PASSWORD"},
+	"cache.redis_password": {"REDIS_PASSWORD"},
+	"rates.access_key":     {"RATES_ACCESS_KEY"},
+	"audit.signing_key":    {"AUDIT_SIGNING_KEY"},
+}
+
+// flagKeys maps flags registered by Flags to config keys
+var flagKeys = map[string]string{
+	"port":             "server.port",
+	"db-host":          "db.host",
+	"db-port":          "db.port",
+	"db-user":          "db.user",
+	"db-name":          "db.name",
+	"log-level":        "logging.level",
+	"migrate-on-start": "migrations.on_start",
+}
+
+// Flags registers flags overriding config keys
+func Flags(flags *pflag.FlagSet) {
+	flags.String("port", "", "port the API listens on (server.port)")
+	flags.String("db-host", "", "database host (db.host)")
+	flags.String("db-port", "", "database port (db.port)")
+	flags.String("db-user", "", "database user (db.user)")
+	flags.String("db-name", "", "database name (db.name)")
+	flags.String("log-level", "", "debug, info, warn or error (logging.level)")
+	flags.Bool("migrate-on-start", false, "apply pending migrations on start (migrations.on_start)")
+}
+
+// Load reads config file, .env file, environment and flags registered by Flags, flags may be nil.
+// Missing default config file and .env are not errors. The result is validated
+func Load(file string, flags *pflag.FlagSet) (*Config, error) {
+	// .env only fills environment, variables which are already set win
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read .env: %w", err)
+	}
+
+	v := viper.New()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	if file == "" {
+		file = DefaultFile
+	}
+
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		if !(file == DefaultFile && errors.Is(err, fs.ErrNotExist)) {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	for key, names := range envAliases {
+		if err := v.BindEnv(append([]string{key}, names...)...); err != nil {
+			return nil, err
+		}
+	}
+
+	if flags != nil {
+		for name, key := range flagKeys {
+			if flag := flags.Lookup(name); flag != nil {
+				if err := v.BindPFlag(key, flag); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg, func(dc *mapstructure.DecoderConfig) { dc.TagName = "yaml" }); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	return &cfg, nil
+}
+
+var (
+	bodySize   = regexp.MustCompile(`^\d+[KMGTP]?$`)
+	sslModes   = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logLevels  = []string{"debug", "info", "warn", "error"}
+	logFormats = []string{"console", "json"}
+	// cacheDrivers start with the empty one which disables the cache
+	cacheDrivers = []string{"", repo.CacheLRU, repo.CacheRedis}
+)
+
+// minAPIKeyLength rejects keys which are easy to guess
+const minAPIKeyLength = 16
+
+// Validate returns all problems of config at once, one per line
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, msgf string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(msgf, args...)))
+		}
+	}
+
+	check(oneOf(c.Storage, []string{StoragePostgres, StorageMemory}), "storage", "must be %s or %s, got %q",
+		StoragePostgres, StorageMemory, c.Storage)
+
+	check(isPort(c.Server.Port), "server.port", "must be a number from 1 to 65535, got %q", c.Server.Port)
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout", "must not be negative")
+	check(c.Server.ShutdownTimeout >= 0, "server.shutdown_timeout", "must not be negative")
+
+	check(c.DB.Host != "", "db.host", "is required")
+	check(isPort(c.DB.Port), "db.port", "must be a number from 1 to 65535, got %q", c.DB.Port)
+	check(c.DB.User != "", "db.user", "is required")
+	check(c.DB.Name != "", "db.name", "is required")
+	check(oneOf(c.DB.SSLMode, sslModes), "db.sslmode", "must be one of %s, got %q", strings.Join(sslModes, ", "), c.DB.SSLMode)
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns", "must not be negative")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns", "must not be negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "db.max_idle_conns",
+		"must not be greater than db.max_open_conns")
+	check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime", "must not be negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time", "must not be negative")
+	check(c.DB.StatementTimeout >= 0, "db.statement_timeout", "must not be negative")
+	check(c.DB.ReplicaPort == "" || isPort(c.DB.ReplicaPort), "db.replica_port",
+		"must be a number from 1 to 65535, got %q", c.DB.ReplicaPort)
+
+	check(oneOf(c.Cache.Driver, cacheDrivers), "cache.driver", "must be empty or one of %s, got %q",
+		strings.Join(cacheDrivers[1:], ", "), c.Cache.Driver)
+	if c.Cache.Driver != "" {
+		check(c.Cache.TTL > 0, "cache.ttl", "must be positive")
+	}
+	if c.Cache.Driver == repo.CacheLRU {
+		check(c.Cache.Size > 0, "cache.size", "must be positive")
+	}
+	if c.Cache.Driver == repo.CacheRedis {
+		check(c.Cache.RedisAddr != "", "cache.redis_addr", "is required")
+		check(c.Cache.RedisDB >= 0, "cache.redis_db", "must not be negative")
+	}
+
+	u, err := url.Parse(c.Rates.URL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "rates.url",
+		"must be an absolute http(s) url, got %q", c.Rates.URL)
+
+	for i, key := range c.Auth.APIKeys {
+		check(len(key) >= minAPIKeyLength, fmt.Sprintf("auth.api_keys[%d]", i), "must be at least %d characters long",
+			minAPIKeyLength)
+	}
+
+	names := make(map[string]bool, len(c.Auth.OperatorKeys))
+	for i, entry := range c.Auth.OperatorKeys {
+		key := fmt.Sprintf("auth.operator_keys[%d]", i)
+		name, secret, ok := strings.Cut(entry, ":")
+		check(ok && name != "", key, "must be like name:key")
+		check(!names[name], key, "operator %q is duplicated", name)
+		check(len(secret) >= minAPIKeyLength, key, "key must be at least %d characters long", minAPIKeyLength)
+		names[name] = true
+	}
+
+	check(bodySize.MatchString(c.Limits.BodySize), "limits.body_size", "must be a size like 512K or 4M, got %q",
+		c.Limits.BodySize)
+	check(c.Limits.BatchMaxItems > 0, "limits.batch_max_items", "must be positive")
+
+	check(oneOf(c.Logging.Level, logLevels), "logging.level", "must be one of %s, got %q", strings.Join(logLevels, ", "),
+		c.Logging.Level)
+	check(oneOf(c.Logging.Format, logFormats), "logging.format", "must be one of %s, got %q",
+		strings.Join(logFormats, ", "), c.Logging.Format)
+
+	check(c.Exchange.QuoteTTL > 0, "exchange.quote_ttl", "must be positive")
+	check(c.Exchange.Spread >= 0 && c.Exchange.Spread < 1, "exchange.spread", "must be from 0 to 1, got %g",
+		c.Exchange.Spread)
+	check(c.Exchange.RevenueAccount >= 0, "exchange.revenue_account", "must not be negative")
+
+	check(c.Reconciliation.Interval >= 0, "reconciliation.interval", "must not be negative")
+
+	check(c.Audit.CheckpointInterval >= 0, "audit.checkpoint_interval", "must not be negative")
+	check(c.Audit.CheckpointFile != "", "audit.checkpoint_file", "is required")
+	if c.Audit.SigningKey != "" {
+		seed, err := hex.DecodeString(c.Audit.SigningKey)
+		check(err == nil && len(seed) == 32, "audit.signing_key", "must be 64 hex characters")
+	}
+
+	check(c.Streams.MaxStreams >= 0, "streams.max_streams", "must not be negative")
+	check(c.Streams.MaxStreamsPerUser >= 0, "streams.max_streams_per_user", "must not be negative")
+	check(c.Streams.Heartbeat > 0, "streams.heartbeat", "must be positive")
+
+	check(c.Schedules.PollInterval >= 0, "schedules.poll_interval", "must not be negative")
+	check(c.Schedules.BatchSize > 0, "schedules.batch_size", "must be positive")
+	check(c.Schedules.MaxRetries >= 0, "schedules.max_retries", "must not be negative")
+	check(c.Schedules.RetryInterval > 0, "schedules.retry_interval", "must be positive")
+
+	check(c.Subscriptions.PollInterval >= 0, "subscriptions.poll_interval", "must not be negative")
+	check(c.Subscriptions.BatchSize > 0, "subscriptions.batch_size", "must be positive")
+	check(c.Subscriptions.RetryInterval > 0, "subscriptions.retry_interval", "must be positive")
+	check(c.Subscriptions.GracePeriod >= 0, "subscriptions.grace_period", "must not be negative")
+
+	check(c.Overdraft.PollInterval >= 0, "overdraft.poll_interval", "must not be negative")
+	check(c.Overdraft.BatchSize > 0, "overdraft.batch_size", "must be positive")
+	check(c.Overdraft.InterestRate >= 0, "overdraft.interest_rate", "must not be negative")
+	check(c.Overdraft.DailyFee >= 0, "overdraft.daily_fee", "must not be negative")
+
+	check(c.Fees.RevenueAccount >= 0, "fees.revenue_account", "must not be negative")
+
+	check(c.Promo.TTL > 0, "promo.ttl", "must be positive")
+	check(c.Promo.PollInterval >= 0, "promo.poll_interval", "must not be negative")
+	check(c.Promo.BatchSize > 0, "promo.batch_size", "must be positive")
+
+	// user and revenue accounts are never negative, so escrowed money is never mixed with theirs
+	check(c.Escrow.Account < 0, "escrow.account", "must be negative")
+	check(c.Escrow.TTL > 0, "escrow.ttl", "must be positive")
+	check(c.Escrow.PollInterval >= 0, "escrow.poll_interval", "must not be negative")
+	check(c.Escrow.BatchSize > 0, "escrow.batch_size", "must be positive")
+
+	if c.Outbox.WebhookURL != "" {
+		u, err := url.Parse(c.Outbox.WebhookURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "outbox.webhook_url",
+			"must be an absolute http(s) url, got %q", c.Outbox.WebhookURL)
+	}
+	check(c.Outbox.PollInterval >= 0, "outbox.poll_interval", "must not be negative")
+	check(c.Outbox.BatchSize > 0, "outbox.batch_size", "must be positive")
+	check(c.Outbox.Retention > 0, "outbox.retention", "must be positive")
+
+	return errors.Join(errs...)
+}
+
+func isPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port > 0 && port <= 65535
+}
+
+func oneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Redacted returns a copy of config with secrets replaced, it is safe to print or log
+func (c Config) Redacted() Config {
+	hide := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return redacted
+	}
+
+	c.DB.Password = hide(c.DB.Password)
+	c.Cache.RedisPassword = hide(c.Cache.RedisPassword)
+	c.Rates.AccessKey = hide(c.Rates.AccessKey)
+	c.Audit.SigningKey = hide(c.Audit.SigningKey)
+	// webhook urls often carry tokens of the consumer in their path or query
+	c.Outbox.WebhookURL = hide(c.Outbox.WebhookURL)
+
+	keys := make([]string, len(c.Auth.APIKeys))
+	for i, key := range c.Auth.APIKeys {
+		keys[i] = hide(key)
+	}
+	c.Auth.APIKeys = keys
+
+	operators := make([]string, len(c.Auth.OperatorKeys))
+	for i, entry := range c.Auth.OperatorKeys {
+		name, secret, _ := strings.Cut(entry, ":")
+		operators[i] = name + ":" + hide(secret)
+	}
+	c.Auth.OperatorKeys = operators
+
+	return c
+}
+
+// String returns redacted config in the format of the config file
+func (c Config) String() string {
+	var out strings.Builder
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err.Error()
+	}
+
+	return out.String()
+}
+
+func (c *Config) Postgres() repo.Config {
+	return repo.Config{
+		Username:         c.DB.User,
+		Password:         c.DB.Password,
+		Host:             c.DB.Host,
+		Port:             c.DB.Port,
+		Name:             c.DB.Name,
+		SSL:              c.DB.SSLMode,
+		MaxOpenConns:     c.DB.MaxOpenConns,
+		MaxIdleConns:     c.DB.MaxIdleConns,
+		ConnMaxLifetime:  c.DB.ConnMaxLifetime,
+		ConnMaxIdleTime:  c.DB.ConnMaxIdleTime,
+		StatementTimeout: c.DB.StatementTimeout,
+		ReplicaHost:      c.DB.ReplicaHost,
+		ReplicaPort:      c.DB.ReplicaPort,
+	}
+}
+
+func (c *Config) WalletCache() repo.CacheConfig {
+	return repo.CacheConfig{
+		Driver:        c.Cache.Driver,
+		Size:          c.Cache.Size,
+		TTL:           c.Cache.TTL,
+		RedisAddr:     c.Cache.RedisAddr,
+		RedisPassword: c.Cache.RedisPassword,
+		RedisDB:       c.Cache.RedisDB,
+	}
+}
+
+func (c *Config) RatesProvider() rates.Provider {
+	return rates.NewExchangeRatesAPI(c.Rates.URL, c.Rates.AccessKey)
+}
+
+func (c *Config) Logger() logging.Config {
+	return logging.Config{
+		Level:  c.Logging.Level,
+		Format: c.Logging.Format,
+	}
+}
+
+func (c *Config) Handler() handler.Config {
+	return handler.Config{
+		APIKeys:         c.Auth.APIKeys,
+		Operators:       c.operators(),
+		BodyLimit:       c.Limits.BodySize,
+		StreamHeartbeat: c.Streams.Heartbeat,
+	}
+}
+
+// operators maps names of operators to their keys
+func (c *Config) operators() map[string]string {
+	operators := make(map[string]string, len(c.Auth.OperatorKeys))
+	for _, entry := range c.Auth.OperatorKeys {
+		name, key, _ := strings.Cut(entry, ":")
+		operators[name] = key
+	}
+
+	return operators
+}
+
+// Operator returns the name of the operator with the key, it identifies operators of balancectl like
+// the API identifies them
+func (c *Config) Operator(key string) (string, bool) {
+	operator := ""
+	for name, k := range c.operators() {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			operator = name
+		}
+	}
+
+	return operator, operator != ""
+}
+
+func (c *Config) Service() service.Config {
+	return service.Config{
+		Exchange: service.ExchangeConfig{
+			QuoteTTL:       c.Exchange.QuoteTTL,
+			Spread:         float32(c.Exchange.Spread),
+			RevenueAccount: c.Exchange.RevenueAccount,
+		},
+		Batch: service.BatchConfig{
+			MaxItems: c.Limits.BatchMaxItems,
+		},
+		Reconciliation: service.ReconciliationConfig{
+			Interval: c.Reconciliation.Interval,
+		},
+		Audit: service.AuditConfig{
+			CheckpointInterval: c.Audit.CheckpointInterval,
+			CheckpointFile:     c.Audit.CheckpointFile,
+			SigningKey:         c.Audit.SigningKey,
+		},
+		Stream: service.StreamConfig{
+			MaxStreams:        c.Streams.MaxStreams,
+			MaxStreamsPerUser: c.Streams.MaxStreamsPerUser,
+		},
+		Schedule: service.ScheduleConfig{
+			PollInterval:  c.Schedules.PollInterval,
+			BatchSize:     c.Schedules.BatchSize,
+			MaxRetries:    c.Schedules.MaxRetries,
+			RetryInterval: c.Schedules.RetryInterval,
+		},
+		Subscription: service.SubscriptionConfig{
+			PollInterval:  c.Subscriptions.PollInterval,
+			BatchSize:     c.Subscriptions.BatchSize,
+			RetryInterval: c.Subscriptions.RetryInterval,
+			GracePeriod:   c.Subscriptions.GracePeriod,
+		},
+		Overdraft: service.OverdraftConfig{
+			PollInterval: c.Overdraft.PollInterval,
+			BatchSize:    c.Overdraft.BatchSize,
+			InterestRate: float32(c.Overdraft.InterestRate),
+			DailyFee:     float32(c.Overdraft.DailyFee),
+		},
+		Fee: service.FeeConfig{
+			RevenueAccount: c.Fees.RevenueAccount,
+		},
+		Promo: service.PromoConfig{
+			TTL:          c.Promo.TTL,
+			SpendFirst:   c.Promo.SpendFirst,
+			PollInterval: c.Promo.PollInterval,
+			BatchSize:    c.Promo.BatchSize,
+		},
+		Escrow: service.EscrowConfig{
+			Account:      c.Escrow.Account,
+			TTL:          c.Escrow.TTL,
+			PollInterval: c.Escrow.PollInterval,
+			BatchSize:    c.Escrow.BatchSize,
+		},
+		Outbox: service.OutboxConfig{
+			WebhookURL:   c.Outbox.WebhookURL,
+			PollInterval: c.Outbox.PollInterval,
+			BatchSize:    c.Outbox.BatchSize,
+			Retention:    c.Outbox.Retention,
+		},
+	}
+}
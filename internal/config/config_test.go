@@ -0,0 +1,222 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yml")
+	content := "server:\n  port: \"8081\"\ndb:\n  host: \"file-host\"\n  name: \"balance\"\nexchange:\n  quote_ttl: \"1m\"\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		check     func(t *testing.T, cfg *Config)
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "File over defaults",
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "8081", cfg.Server.Port)
+				assert.Equal(t, "file-host", cfg.DB.Host)
+				assert.Equal(t, "balance", cfg.DB.Name)
+				assert.Equal(t, time.Minute, cfg.Exchange.QuoteTTL)
+				assert.Equal(t, "5432", cfg.DB.Port)
+				assert.Equal(t, 1000, cfg.Limits.BatchMaxItems)
+			},
+		},
+		{
+			name: "Environment over file",
+			env: map[string]string{
+				"BALANCE_SERVER_PORT":   "8082",
+				"DB_HOST":               "db",
+				"PG_PASSWORD":           "legacy",
+				"BALANCE_AUTH_API_KEYS": "0123456789abcdef,fedcba9876543210",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "8082", cfg.Server.Port)
+				assert.Equal(t, "db", cfg.DB.Host)
+				assert.Equal(t, "legacy", cfg.DB.Password)
+				assert.Equal(t, []string{"0123456789abcdef", "fedcba9876543210"}, cfg.Auth.APIKeys)
+			},
+		},
+		{
+			name: "Prefixed environment over aliases",
+			env:  map[string]string{"BALANCE_DB_PASSWORD": "new", "DB_PASSWORD": "compose", "PG_PASSWORD": "legacy"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "new", cfg.DB.Password)
+			},
+		},
+		{
+			name: "Flags over environment",
+			env:  map[string]string{"BALANCE_SERVER_PORT": "8082", "BALANCE_DB_HOST": "db"},
+			args: []string{"--port", "8083"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "8083", cfg.Server.Port)
+				assert.Equal(t, "db", cfg.DB.Host)
+			},
+		},
+		{
+			name:    "Invalid values",
+			env:     map[string]string{"BALANCE_EXCHANGE_SPREAD": "1.5", "BALANCE_LOGGING_LEVEL": "loud"},
+			args:    []string{"--db-port", "postgres"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"db.port: must be a number from 1 to 65535, got \"postgres\"\n" +
+				"logging.level: must be one of debug, info, warn, error, got \"loud\"\n" +
+				"exchange.spread: must be from 0 to 1, got 1.5",
+		},
+		{
+			name:    "Invalid cache",
+			env:     map[string]string{"BALANCE_CACHE_DRIVER": "lru", "BALANCE_CACHE_SIZE": "0", "BALANCE_CACHE_TTL": "0s"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"cache.ttl: must be positive\n" +
+				"cache.size: must be positive",
+		},
+		{
+			name: "Redis cache",
+			env:  map[string]string{"BALANCE_CACHE_DRIVER": "redis", "REDIS_PASSWORD": "secret"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "localhost:6379", cfg.Cache.RedisAddr)
+				assert.Equal(t, "secret", cfg.Cache.RedisPassword)
+				assert.Equal(t, time.Minute, cfg.Cache.TTL)
+			},
+		},
+		{
+			name:    "Invalid streams",
+			env:     map[string]string{"BALANCE_STREAMS_MAX_STREAMS_PER_USER": "-1", "BALANCE_STREAMS_HEARTBEAT": "0s"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"streams.max_streams_per_user: must not be negative\n" +
+				"streams.heartbeat: must be positive",
+		},
+		{
+			name:    "Invalid schedules",
+			env:     map[string]string{"BALANCE_SCHEDULES_BATCH_SIZE": "0", "BALANCE_SCHEDULES_RETRY_INTERVAL": "0s"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"schedules.batch_size: must be positive\n" +
+				"schedules.retry_interval: must be positive",
+		},
+		{
+			name:    "Invalid subscriptions",
+			env:     map[string]string{"BALANCE_SUBSCRIPTIONS_POLL_INTERVAL": "-1m", "BALANCE_SUBSCRIPTIONS_GRACE_PERIOD": "-1h"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"subscriptions.poll_interval: must not be negative\n" +
+				"subscriptions.grace_period: must not be negative",
+		},
+		{
+			name:    "Invalid overdraft",
+			env:     map[string]string{"BALANCE_OVERDRAFT_BATCH_SIZE": "0", "BALANCE_OVERDRAFT_INTEREST_RATE": "-0.1"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"overdraft.batch_size: must be positive\n" +
+				"overdraft.interest_rate: must not be negative",
+		},
+		{
+			name:      "Invalid fees",
+			env:       map[string]string{"BALANCE_FEES_REVENUE_ACCOUNT": "-1"},
+			wantErr:   true,
+			wantedErr: "invalid config:\nfees.revenue_account: must not be negative",
+		},
+		{
+			name:    "Invalid promo",
+			env:     map[string]string{"BALANCE_PROMO_TTL": "0s", "BALANCE_PROMO_BATCH_SIZE": "0"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"promo.ttl: must be positive\n" +
+				"promo.batch_size: must be positive",
+		},
+		{
+			name:    "Invalid escrow",
+			env:     map[string]string{"BALANCE_ESCROW_ACCOUNT": "0", "BALANCE_ESCROW_POLL_INTERVAL": "-1m"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"escrow.account: must be negative\n" +
+				"escrow.poll_interval: must not be negative",
+		},
+		{
+			name:    "Invalid outbox",
+			env:     map[string]string{"BALANCE_OUTBOX_WEBHOOK_URL": "consumer:8080", "BALANCE_OUTBOX_RETENTION": "0s"},
+			wantErr: true,
+			wantedErr: "invalid config:\n" +
+				"outbox.webhook_url: must be an absolute http(s) url, got \"consumer:8080\"\n" +
+				"outbox.retention: must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			Flags(flags)
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(file, flags)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantedErr, err.Error())
+			} else {
+				assert.NoError(t, err)
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yml"), nil)
+	assert.Error(t, err)
+}
+
+func TestConfig_String(t *testing.T) {
+	t.Setenv("BALANCE_DB_PASSWORD", "secret-password")
+	t.Setenv("BALANCE_AUTH_API_KEYS", "0123456789abcdef")
+	t.Setenv("BALANCE_OUTBOX_WEBHOOK_URL", "https://consumer.example/hooks?token=webhook-token")
+
+	cfg, err := Load(filepath.Join("..", "..", DefaultFile), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := cfg.String()
+	assert.False(t, strings.Contains(out, "secret-password"))
+	assert.False(t, strings.Contains(out, "0123456789abcdef"))
+	assert.False(t, strings.Contains(out, "webhook-token"))
+	assert.True(t, strings.Contains(out, "webhook_url: '****'"))
+	assert.True(t, strings.Contains(out, "password: '****'"))
+	assert.Equal(t, "secret-password", cfg.DB.Password)
+}
+
+func TestConfig_Operator(t *testing.T) {
+	cfg := Config{Auth: Auth{OperatorKeys: []string{"alice:alice-key-0123456", "bob:bob-key-0123456789"}}}
+
+	name, ok := cfg.Operator("bob-key-0123456789")
+	assert.True(t, ok)
+	assert.Equal(t, "bob", name)
+
+	_, ok = cfg.Operator("bob")
+	assert.False(t, ok)
+
+	_, ok = cfg.Operator("")
+	assert.False(t, ok)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Get account
+// @Tags accounts
+// @Description Returns account status with history of its changes
+// @ID get-account
+// @Produce  json
+// @Param        user_id   path      int  true  "User ID"
+// @Success 200 {object} models.Account
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /accounts/{user_id} [get]
+func (h *Handler) getAccount(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	account, err := h.s.GetAccount(userId)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusNotFound, err)
+	}
+
+	return c.JSON(http.StatusOK, account)
+}
+
+// @Summary Change account status
+// @Tags accounts
+// @Description Changes account status: active, debit_blocked, frozen or closed.
+// @Description Account can be closed only with zero balance or with final payout of the remaining money
+// @ID set-account-status
+// @Accept  json
+// @Produce  json
+// @Param X-Operator header string false "Operator, only when authentication is disabled"
+// @Param        user_id   path      int  true  "User ID"
+// @Param input body models.AccountStatusInput true "status input"
+// @Success 200 {object} models.Account
+// @Failure 400,403,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /accounts/{user_id}/status [post]
+func (h *Handler) setAccountStatus(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	var input models.AccountStatusInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	input.UserId = userId
+	input.Operator, err = h.operator(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusForbidden, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	account, err := h.s.SetAccountStatus(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, account)
+}
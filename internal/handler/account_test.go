@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_SetAccountStatus(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAccount, input models.AccountStatusInput)
+
+	changedAt := time.Date(2023, 7, 10, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		url                  string
+		operator             string
+		input                models.AccountStatusInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Freeze",
+			url:      "/accounts/1/status",
+			operator: "fraud-team",
+			input: models.AccountStatusInput{
+				UserId:       1,
+				Status:       models.AccountFrozen,
+				Reason:       "chargeback investigation",
+				BlockCredits: true,
+				Operator:     "fraud-team",
+			},
+			inputBody: `{"status":"frozen","reason":"chargeback investigation","block_credits":true}`,
+			mockBehavior: func(s *mock_service.MockAccount, input models.AccountStatusInput) {
+				s.EXPECT().SetAccountStatus(input).Return(models.Account{
+					UserId:          1,
+					Status:          models.AccountFrozen,
+					CreditsBlocked:  true,
+					Reason:          "chargeback investigation",
+					Actor:           "fraud-team",
+					StatusChangedAt: &changedAt,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"user_id":1,"status":"frozen","credits_blocked":true,"reason":"chargeback investigation",` +
+				`"actor":"fraud-team","status_changed_at":"2023-07-10T12:00:00Z"}`,
+		},
+		{
+			name:                 "No operator",
+			url:                  "/accounts/1/status",
+			inputBody:            `{"status":"frozen","reason":"chargeback investigation"}`,
+			mockBehavior:         func(s *mock_service.MockAccount, input models.AccountStatusInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"operator is required"}`,
+		},
+		{
+			name:                 "Incorrect status",
+			url:                  "/accounts/1/status",
+			operator:             "fraud-team",
+			inputBody:            `{"status":"blocked","reason":"chargeback investigation"}`,
+			mockBehavior:         func(s *mock_service.MockAccount, input models.AccountStatusInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"unsupported status \"blocked\""}`,
+		},
+		{
+			name:                 "No reason",
+			url:                  "/accounts/1/status",
+			operator:             "fraud-team",
+			inputBody:            `{"status":"closed"}`,
+			mockBehavior:         func(s *mock_service.MockAccount, input models.AccountStatusInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"reason is required"}`,
+		},
+		{
+			name:                 "Credits blocked for active account",
+			url:                  "/accounts/1/status",
+			operator:             "fraud-team",
+			inputBody:            `{"status":"active","block_credits":true}`,
+			mockBehavior:         func(s *mock_service.MockAccount, input models.AccountStatusInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"credits can be blocked only for frozen account"}`,
+		},
+		{
+			name:     "Close with money",
+			url:      "/accounts/1/status",
+			operator: "support",
+			input: models.AccountStatusInput{
+				UserId:   1,
+				Status:   models.AccountClosed,
+				Reason:   "requested by user",
+				Operator: "support",
+			},
+			inputBody: `{"status":"closed","reason":"requested by user"}`,
+			mockBehavior: func(s *mock_service.MockAccount, input models.AccountStatusInput) {
+				s.EXPECT().SetAccountStatus(input).Return(models.Account{},
+					errors.New("account balance must be zero to close it"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"account balance must be zero to close it"}`,
+		},
+		{
+			name:                 "Incorrect user id",
+			url:                  "/accounts/abc/status",
+			operator:             "support",
+			inputBody:            `{"status":"active"}`,
+			mockBehavior:         func(s *mock_service.MockAccount, input models.AccountStatusInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"strconv.Atoi: parsing \"abc\": invalid syntax"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			account := mock_service.NewMockAccount(c)
+			testCase.mockBehavior(account, testCase.input)
+
+			services := &service.Service{Account: account}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/accounts/:user_id/status", handler.setAccountStatus)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", testCase.url,
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+			if testCase.operator != "" {
+				req.Header.Add(operatorHeader, testCase.operator)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_GetAccount(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAccount, id int)
+
+	changedAt := time.Date(2023, 7, 10, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		id                   int
+		url                  string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			id:   1,
+			url:  "/accounts/1",
+			mockBehavior: func(s *mock_service.MockAccount, id int) {
+				s.EXPECT().GetAccount(id).Return(models.Account{
+					UserId:          1,
+					Status:          models.AccountDebitBlocked,
+					Reason:          "court order",
+					Actor:           "legal",
+					StatusChangedAt: &changedAt,
+					History: []models.AccountStatusChange{
+						{Status: models.AccountDebitBlocked, Reason: "court order", Actor: "legal", Date: changedAt},
+					},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"user_id":1,"status":"debit_blocked","credits_blocked":false,"reason":"court order",` +
+				`"actor":"legal","status_changed_at":"2023-07-10T12:00:00Z","history":[{"status":"debit_blocked",` +
+				`"credits_blocked":false,"reason":"court order","actor":"legal","date":"2023-07-10T12:00:00Z"}]}`,
+		},
+		{
+			name: "Not found",
+			id:   100,
+			url:  "/accounts/100",
+			mockBehavior: func(s *mock_service.MockAccount, id int) {
+				s.EXPECT().GetAccount(id).Return(models.Account{}, errors.New("user not found"))
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"user not found"}`,
+		},
+		{
+			name:                 "Incorrect user id",
+			url:                  "/accounts/0",
+			mockBehavior:         func(s *mock_service.MockAccount, id int) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect user id"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			account := mock_service.NewMockAccount(c)
+			testCase.mockBehavior(account, testCase.id)
+
+			services := &service.Service{Account: account}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/accounts/:user_id", handler.getAccount)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", testCase.url, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// operatorHeader names the operator when requests are not authenticated, e.g. in local development
+const operatorHeader = "X-Operator"
+
+// operatorContextKey holds the name of the operator whose key authenticated the request
+const operatorContextKey = "operator"
+
+// errOperatorKeyRequired is returned when the request is not authenticated by a key of an operator
+var errOperatorKeyRequired = errors.New("operator key is required")
+
+// operator returns the operator of the request. Authenticated requests are made by the operator of the key,
+// the header is trusted only when authentication is disabled
+func (h *Handler) operator(c echo.Context) (string, error) {
+	if !h.authenticated {
+		return c.Request().Header.Get(operatorHeader), nil
+	}
+
+	operator, ok := c.Get(operatorContextKey).(string)
+	if !ok || operator == "" {
+		return "", errOperatorKeyRequired
+	}
+
+	return operator, nil
+}
+
+// @Summary Propose adjustment
+// @Tags adjustments
+// @Description Creates pending credit or debit of user`s wallet, it is applied only after approval by another operator
+// @ID propose-adjustment
+// @Accept  json
+// @Produce  json
+// @Param X-Operator header string false "Operator, only when authentication is disabled"
+// @Param input body models.AdjustmentInput true "adjustment input"
+// @Success 200 {object} models.Adjustment
+// @Failure 400,403,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /adjustments [post]
+func (h *Handler) proposeAdjustment(c echo.Context) error {
+	var input models.AdjustmentInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	operator, err := h.operator(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusForbidden, err)
+	}
+
+	input.Operator = operator
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	adjustment, err := h.s.ProposeAdjustment(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, adjustment)
+}
+
+// @Summary List adjustments
+// @Tags adjustments
+// @Description Returns adjustments, pending ones by default
+// @ID list-adjustments
+// @Produce  json
+// @Param        status   query      string  false  "pending, approved, rejected or all"
+// @Success 200 {object} []models.Adjustment
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /adjustments [get]
+func (h *Handler) listAdjustments(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = models.AdjustmentPending
+	} else if status == "all" {
+		status = ""
+	}
+
+	adjustments, err := h.s.ListAdjustments(status)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, adjustments)
+}
+
+// @Summary Get adjustment
+// @Tags adjustments
+// @Description Returns adjustment with its audit trail
+// @ID get-adjustment
+// @Produce  json
+// @Param        id   path      int  true  "Adjustment ID"
+// @Success 200 {object} models.Adjustment
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /adjustments/{id} [get]
+func (h *Handler) getAdjustment(c echo.Context) error {
+	id, err := adjustmentId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	adjustment, err := h.s.GetAdjustment(id)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusNotFound, err)
+	}
+
+	return c.JSON(http.StatusOK, adjustment)
+}
+
+// @Summary Approve adjustment
+// @Tags adjustments
+// @Description Applies pending adjustment, it must be approved by another operator than the one who proposed it
+// @ID approve-adjustment
+// @Accept  json
+// @Produce  json
+// @Param X-Operator header string false "Operator, only when authentication is disabled"
+// @Param        id   path      int  true  "Adjustment ID"
+// @Param input body models.AdjustmentReview false "review input"
+// @Success 200 {object} models.Adjustment
+// @Failure 400,403,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /adjustments/{id}/approve [post]
+func (h *Handler) approveAdjustment(c echo.Context) error {
+	id, review, err := h.adjustmentReview(c)
+	if err != nil {
+		if errors.Is(err, errOperatorKeyRequired) {
+			return h.log.ErrorResponse(http.StatusForbidden, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	adjustment, err := h.s.ApproveAdjustment(id, review)
+	if err != nil {
+		if errors.Is(err, models.ErrSameOperator) {
+			return h.log.ErrorResponse(http.StatusForbidden, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, adjustment)
+}
+
+// @Summary Reject adjustment
+// @Tags adjustments
+// @Description Rejects pending adjustment, balance is not changed
+// @ID reject-adjustment
+// @Accept  json
+// @Produce  json
+// @Param X-Operator header string false "Operator, only when authentication is disabled"
+// @Param        id   path      int  true  "Adjustment ID"
+// @Param input body models.AdjustmentReview false "review input"
+// @Success 200 {object} models.Adjustment
+// @Failure 400,403,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /adjustments/{id}/reject [post]
+func (h *Handler) rejectAdjustment(c echo.Context) error {
+	id, review, err := h.adjustmentReview(c)
+	if err != nil {
+		if errors.Is(err, errOperatorKeyRequired) {
+			return h.log.ErrorResponse(http.StatusForbidden, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	adjustment, err := h.s.RejectAdjustment(id, review)
+	if err != nil {
+		if errors.Is(err, models.ErrSameOperator) {
+			return h.log.ErrorResponse(http.StatusForbidden, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, adjustment)
+}
+
+func (h *Handler) adjustmentReview(c echo.Context) (int, models.AdjustmentReview, error) {
+	id, err := adjustmentId(c)
+	if err != nil {
+		return 0, models.AdjustmentReview{}, err
+	}
+
+	var review models.AdjustmentReview
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&review); err != nil {
+			return 0, models.AdjustmentReview{}, err
+		}
+	}
+
+	review.Operator, err = h.operator(c)
+	if err != nil {
+		return 0, models.AdjustmentReview{}, err
+	}
+
+	if err := review.Validate(); err != nil {
+		return 0, models.AdjustmentReview{}, err
+	}
+
+	return id, review, nil
+}
+
+func adjustmentId(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, errors.New("incorrect adjustment id")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,450 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ProposeAdjustment(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAdjustment, input models.AdjustmentInput)
+
+	createdAt := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		operator             string
+		input                models.AdjustmentInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "OK",
+			operator: "alice",
+			input: models.AdjustmentInput{
+				UserId:    1,
+				Direction: models.AdjustmentCredit,
+				Amount:    10,
+				Currency:  "EUR",
+				Reason:    "refund for order 15",
+				Ticket:    "SUP-42",
+				Operator:  "alice",
+			},
+			inputBody: `{"user_id":1,"direction":"credit","amount":10,"reason":"refund for order 15","ticket":"SUP-42"}`,
+			mockBehavior: func(s *mock_service.MockAdjustment, input models.AdjustmentInput) {
+				s.EXPECT().ProposeAdjustment(input).Return(models.Adjustment{
+					ID:        1,
+					UserId:    1,
+					Direction: models.AdjustmentCredit,
+					Amount:    10,
+					Currency:  "EUR",
+					Reason:    "refund for order 15",
+					Ticket:    "SUP-42",
+					Status:    models.AdjustmentPending,
+					CreatedBy: "alice",
+					CreatedAt: createdAt,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"direction":"credit","amount":10,"currency":"EUR",` +
+				`"reason":"refund for order 15","ticket":"SUP-42","status":"pending","created_by":"alice",` +
+				`"created_at":"2023-07-05T12:00:00Z"}`,
+		},
+		{
+			name:                 "No operator",
+			inputBody:            `{"user_id":1,"direction":"credit","amount":10,"reason":"refund for order 15"}`,
+			mockBehavior:         func(s *mock_service.MockAdjustment, input models.AdjustmentInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"operator is required"}`,
+		},
+		{
+			name:                 "No reason",
+			operator:             "alice",
+			inputBody:            `{"user_id":1,"direction":"debit","amount":10}`,
+			mockBehavior:         func(s *mock_service.MockAdjustment, input models.AdjustmentInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"reason is required"}`,
+		},
+		{
+			name:                 "Incorrect direction",
+			operator:             "alice",
+			inputBody:            `{"user_id":1,"direction":"up","amount":10,"reason":"refund for order 15"}`,
+			mockBehavior:         func(s *mock_service.MockAdjustment, input models.AdjustmentInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"unsupported direction \"up\""}`,
+		},
+		{
+			name:     "Error from service",
+			operator: "alice",
+			input: models.AdjustmentInput{
+				UserId:    100,
+				Direction: models.AdjustmentDebit,
+				Amount:    10,
+				Currency:  "EUR",
+				Reason:    "duplicate top-up",
+				Operator:  "alice",
+			},
+			inputBody: `{"user_id":100,"direction":"debit","amount":10,"reason":"duplicate top-up"}`,
+			mockBehavior: func(s *mock_service.MockAdjustment, input models.AdjustmentInput) {
+				s.EXPECT().ProposeAdjustment(input).Return(models.Adjustment{}, errors.New("user not found"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"user not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			adjustment := mock_service.NewMockAdjustment(c)
+			testCase.mockBehavior(adjustment, testCase.input)
+
+			services := &service.Service{Adjustment: adjustment}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/adjustments", handler.proposeAdjustment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/adjustments",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+			if testCase.operator != "" {
+				req.Header.Add(operatorHeader, testCase.operator)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_ReviewAdjustment(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAdjustment, review models.AdjustmentReview)
+
+	createdAt := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	reviewedAt := createdAt.Add(time.Hour)
+	balance := float32(14.13)
+
+	testTable := []struct {
+		name                 string
+		url                  string
+		operator             string
+		review               models.AdjustmentReview
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "Approve",
+			url:       "/adjustments/1/approve",
+			operator:  "bob",
+			review:    models.AdjustmentReview{Comment: "checked", Operator: "bob"},
+			inputBody: `{"comment":"checked"}`,
+			mockBehavior: func(s *mock_service.MockAdjustment, review models.AdjustmentReview) {
+				s.EXPECT().ApproveAdjustment(1, review).Return(models.Adjustment{
+					ID:            1,
+					UserId:        1,
+					Direction:     models.AdjustmentCredit,
+					Amount:        10,
+					Currency:      "EUR",
+					Reason:        "refund for order 15",
+					Status:        models.AdjustmentApproved,
+					CreatedBy:     "alice",
+					CreatedAt:     createdAt,
+					ReviewedBy:    "bob",
+					ReviewedAt:    &reviewedAt,
+					ReviewComment: "checked",
+					BalanceAfter:  &balance,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"direction":"credit","amount":10,"currency":"EUR",` +
+				`"reason":"refund for order 15","ticket":"","status":"approved","created_by":"alice",` +
+				`"created_at":"2023-07-05T12:00:00Z","reviewed_by":"bob","reviewed_at":"2023-07-05T13:00:00Z",` +
+				`"review_comment":"checked","balance_after":14.13}`,
+		},
+		{
+			name:     "Reject without body",
+			url:      "/adjustments/1/reject",
+			operator: "bob",
+			review:   models.AdjustmentReview{Operator: "bob"},
+			mockBehavior: func(s *mock_service.MockAdjustment, review models.AdjustmentReview) {
+				s.EXPECT().RejectAdjustment(1, review).Return(models.Adjustment{
+					ID:         1,
+					UserId:     1,
+					Direction:  models.AdjustmentCredit,
+					Amount:     10,
+					Currency:   "EUR",
+					Reason:     "refund for order 15",
+					Status:     models.AdjustmentRejected,
+					CreatedBy:  "alice",
+					CreatedAt:  createdAt,
+					ReviewedBy: "bob",
+					ReviewedAt: &reviewedAt,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"direction":"credit","amount":10,"currency":"EUR",` +
+				`"reason":"refund for order 15","ticket":"","status":"rejected","created_by":"alice",` +
+				`"created_at":"2023-07-05T12:00:00Z","reviewed_by":"bob","reviewed_at":"2023-07-05T13:00:00Z"}`,
+		},
+		{
+			name:                 "Incorrect id",
+			url:                  "/adjustments/abc/approve",
+			operator:             "bob",
+			mockBehavior:         func(s *mock_service.MockAdjustment, review models.AdjustmentReview) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect adjustment id"}`,
+		},
+		{
+			name:                 "No operator",
+			url:                  "/adjustments/1/approve",
+			mockBehavior:         func(s *mock_service.MockAdjustment, review models.AdjustmentReview) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"operator is required"}`,
+		},
+		{
+			name:     "Same operator",
+			url:      "/adjustments/1/approve",
+			operator: "alice",
+			review:   models.AdjustmentReview{Operator: "alice"},
+			mockBehavior: func(s *mock_service.MockAdjustment, review models.AdjustmentReview) {
+				s.EXPECT().ApproveAdjustment(1, review).Return(models.Adjustment{},
+					fmt.Errorf("adjustment %w", models.ErrSameOperator))
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"message":"adjustment must be reviewed by another operator"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			adjustment := mock_service.NewMockAdjustment(c)
+			testCase.mockBehavior(adjustment, testCase.review)
+
+			services := &service.Service{Adjustment: adjustment}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/adjustments/:id/approve", handler.approveAdjustment)
+			r.POST("/adjustments/:id/reject", handler.rejectAdjustment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", testCase.url,
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+			if testCase.operator != "" {
+				req.Header.Add(operatorHeader, testCase.operator)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_AdjustmentOperatorKeys(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAdjustment)
+
+	const (
+		apiKey = "0123456789abcdef"
+		bobKey = "bob-key-0123456789"
+	)
+
+	testTable := []struct {
+		name                 string
+		key                  string
+		operator             string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "Operator of the key",
+			key:      bobKey,
+			operator: "alice",
+			mockBehavior: func(s *mock_service.MockAdjustment) {
+				s.EXPECT().ApproveAdjustment(1, models.AdjustmentReview{Operator: "bob"}).Return(models.Adjustment{
+					ID:         1,
+					Status:     models.AdjustmentApproved,
+					CreatedBy:  "alice",
+					ReviewedBy: "bob",
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":0,"direction":"","amount":0,"currency":"","reason":"","ticket":"",` +
+				`"status":"approved","created_by":"alice","created_at":"0001-01-01T00:00:00Z","reviewed_by":"bob"}`,
+		},
+		{
+			name:                 "Key of no operator",
+			key:                  apiKey,
+			operator:             "bob",
+			mockBehavior:         func(s *mock_service.MockAdjustment) {},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"message":"operator key is required"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			adjustment := mock_service.NewMockAdjustment(c)
+			testCase.mockBehavior(adjustment)
+
+			services := &service.Service{Adjustment: adjustment}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			r := NewHandler(services, logger).InitRoutes(Config{
+				APIKeys:   []string{apiKey},
+				Operators: map[string]string{"alice": "alice-key-0123456789", "bob": bobKey},
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/adjustments/1/approve", nil)
+			req.Header.Add(apiKeyHeader, testCase.key)
+			req.Header.Add(operatorHeader, testCase.operator)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_GetAdjustment(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAdjustment)
+
+	createdAt := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		url                  string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			url:  "/adjustments/1",
+			mockBehavior: func(s *mock_service.MockAdjustment) {
+				s.EXPECT().GetAdjustment(1).Return(models.Adjustment{
+					ID:        1,
+					UserId:    1,
+					Direction: models.AdjustmentDebit,
+					Amount:    10,
+					Currency:  "EUR",
+					Reason:    "duplicate top-up",
+					Status:    models.AdjustmentPending,
+					CreatedBy: "alice",
+					CreatedAt: createdAt,
+					Events: []models.AdjustmentEvent{
+						{Action: models.AdjustmentProposed, Actor: "alice", Comment: "duplicate top-up", Date: createdAt},
+					},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"direction":"debit","amount":10,"currency":"EUR",` +
+				`"reason":"duplicate top-up","ticket":"","status":"pending","created_by":"alice",` +
+				`"created_at":"2023-07-05T12:00:00Z","events":[{"action":"proposed","actor":"alice",` +
+				`"comment":"duplicate top-up","date":"2023-07-05T12:00:00Z"}]}`,
+		},
+		{
+			name: "Not found",
+			url:  "/adjustments/2",
+			mockBehavior: func(s *mock_service.MockAdjustment) {
+				s.EXPECT().GetAdjustment(2).Return(models.Adjustment{}, errors.New("adjustment not found"))
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"adjustment not found"}`,
+		},
+		{
+			name: "List pending by default",
+			url:  "/adjustments",
+			mockBehavior: func(s *mock_service.MockAdjustment) {
+				s.EXPECT().ListAdjustments(models.AdjustmentPending).Return([]models.Adjustment{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name: "List all",
+			url:  "/adjustments?status=all",
+			mockBehavior: func(s *mock_service.MockAdjustment) {
+				s.EXPECT().ListAdjustments("").Return([]models.Adjustment{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			adjustment := mock_service.NewMockAdjustment(c)
+			testCase.mockBehavior(adjustment)
+
+			services := &service.Service{Adjustment: adjustment}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/adjustments", handler.listAdjustments)
+			r.GET("/adjustments/:id", handler.getAdjustment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", testCase.url, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
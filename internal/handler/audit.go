@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Verify transaction chain
+// @Tags audit
+// @Description Walks hash chains of all wallets and reports the first break
+// @ID verify-chain
+// @Produce  json
+// @Success 200 {object} models.ChainVerification
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /audit/verify [get]
+func (h *Handler) verifyChain(c echo.Context) error {
+	result, err := h.s.VerifyChain()
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Create checkpoint
+// @Tags audit
+// @Description Signs heads of all transaction chains and appends the checkpoint to the checkpoint file
+// @ID create-checkpoint
+// @Produce  json
+// @Success 200 {object} models.Checkpoint
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /audit/checkpoints [post]
+func (h *Handler) createCheckpoint(c echo.Context) error {
+	checkpoint, err := h.s.CreateCheckpoint()
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, checkpoint)
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_VerifyChain(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockAudit)
+
+	verifiedAt := time.Date(2023, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "Valid",
+			mockBehavior: func(s *mock_service.MockAudit) {
+				s.EXPECT().VerifyChain().Return(models.ChainVerification{
+					Valid:        true,
+					Transactions: 12,
+					Wallets:      3,
+					Unchained:    9,
+					VerifiedAt:   verifiedAt,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"valid":true,"transactions":12,"wallets":3,"unchained":9,"verified_at":"2023-07-20T12:00:00Z"}`,
+		},
+		{
+			name: "Broken",
+			mockBehavior: func(s *mock_service.MockAudit) {
+				s.EXPECT().VerifyChain().Return(models.ChainVerification{
+					Transactions: 11,
+					Wallets:      3,
+					Unchained:    9,
+					Break: &models.ChainBreak{
+						TransactionId: 11,
+						UserId:        1,
+						Currency:      "EUR",
+						Reason:        models.ChainHashMismatch,
+					},
+					VerifiedAt: verifiedAt,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"valid":false,"transactions":11,"wallets":3,"unchained":9,"break":{"transaction_id":11,"user_id":1,"currency":"EUR","reason":"hash does not match transaction content"},"verified_at":"2023-07-20T12:00:00Z"}`,
+		},
+		{
+			name: "Error from service",
+			mockBehavior: func(s *mock_service.MockAudit) {
+				s.EXPECT().VerifyChain().Return(models.ChainVerification{}, errors.New("connection reset"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"connection reset"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			audit := mock_service.NewMockAudit(c)
+			testCase.mockBehavior(audit)
+
+			services := &service.Service{Audit: audit}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/audit/verify", handler.verifyChain)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/audit/verify", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
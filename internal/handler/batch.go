@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Apply batch
+// @Tags batch
+// @Description Applies top-ups, debits and transfers. Atomic batch is applied all-or-nothing,
+// @Description best_effort batch applies every item separately and reports per-item results
+// @ID batch
+// @Accept  json
+// @Produce  json
+// @Param input body models.BatchInput true "batch input"
+// @Success 200 {object} models.BatchOutput
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /batch [post]
+func (h *Handler) batch(c echo.Context) error {
+	var input models.BatchInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if input.Mode == "" {
+		input.Mode = models.BatchAtomic
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	output, err := h.s.ApplyBatch(input)
+	if err != nil {
+		if errors.Is(err, models.ErrBatchTooLarge) {
+			return h.log.ErrorResponse(http.StatusBadRequest, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, output)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Batch(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockBatch, input models.BatchInput)
+
+	testTable := []struct {
+		name                 string
+		input                models.BatchInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			input: models.BatchInput{
+				Mode: models.BatchBestEffort,
+				Items: []models.BatchItem{
+					{Type: models.BatchTopUp, IdempotencyKey: "payout-1", UserId: 1, Amount: 10, Currency: "EUR"},
+					{Type: models.BatchTransfer, UserId: 1, ToId: 2, Amount: 100, Currency: "USD"},
+				},
+			},
+			inputBody: `{"mode":"best_effort","items":[{"type":"top-up","idempotency_key":"payout-1","user_id":1,"amount":10},` +
+				`{"type":"transfer","user_id":1,"to_id":2,"amount":100,"currency":"usd"}]}`,
+			mockBehavior: func(s *mock_service.MockBatch, input models.BatchInput) {
+				s.EXPECT().ApplyBatch(input).Return(models.BatchOutput{
+					Mode:    models.BatchBestEffort,
+					Applied: 1,
+					Failed:  1,
+					Results: []models.BatchResult{
+						{Index: 0, IdempotencyKey: "payout-1", Status: models.BatchItemOk, Balance: 15},
+						{Index: 1, Status: models.BatchItemFailed, Error: "not enough money to perform purchase"},
+					},
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"mode":"best_effort","applied":1,"failed":1,"results":[` +
+				`{"index":0,"idempotency_key":"payout-1","status":"ok","balance":15},` +
+				`{"index":1,"status":"failed","balance":0,"error":"not enough money to perform purchase"}]}`,
+		},
+		{
+			name: "Atomic by default",
+			input: models.BatchInput{
+				Mode:  models.BatchAtomic,
+				Items: []models.BatchItem{{Type: models.BatchDebit, UserId: 1, Amount: 10, Currency: "EUR"}},
+			},
+			inputBody: `{"items":[{"type":"debit","user_id":1,"amount":10}]}`,
+			mockBehavior: func(s *mock_service.MockBatch, input models.BatchInput) {
+				s.EXPECT().ApplyBatch(input).Return(models.BatchOutput{
+					Mode:    models.BatchAtomic,
+					Applied: 1,
+					Results: []models.BatchResult{{Index: 0, Status: models.BatchItemOk, Balance: 5}},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"mode":"atomic","applied":1,"failed":0,"results":[{"index":0,"status":"ok","balance":5}]}`,
+		},
+		{
+			name:                 "Incorrect mode",
+			inputBody:            `{"mode":"sometimes","items":[{"type":"debit","user_id":1,"amount":10}]}`,
+			mockBehavior:         func(s *mock_service.MockBatch, input models.BatchInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"unsupported batch mode \"sometimes\""}`,
+		},
+		{
+			name:                 "Empty batch",
+			inputBody:            `{"mode":"atomic","items":[]}`,
+			mockBehavior:         func(s *mock_service.MockBatch, input models.BatchInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"batch is empty"}`,
+		},
+		{
+			name:                 "Incorrect item",
+			inputBody:            `{"items":[{"type":"debit","user_id":1,"amount":10},{"type":"transfer","user_id":1,"to_id":1,"amount":10}]}`,
+			mockBehavior:         func(s *mock_service.MockBatch, input models.BatchInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"item 1: incorrect to id"}`,
+		},
+		{
+			name: "Batch is too large",
+			input: models.BatchInput{
+				Mode:  models.BatchAtomic,
+				Items: []models.BatchItem{{Type: models.BatchDebit, UserId: 1, Amount: 10, Currency: "EUR"}},
+			},
+			inputBody: `{"items":[{"type":"debit","user_id":1,"amount":10}]}`,
+			mockBehavior: func(s *mock_service.MockBatch, input models.BatchInput) {
+				s.EXPECT().ApplyBatch(input).Return(models.BatchOutput{},
+					fmt.Errorf("%w, it is limited to %d items", models.ErrBatchTooLarge, 0))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"batch is too large, it is limited to 0 items"}`,
+		},
+		{
+			name: "Error from service",
+			input: models.BatchInput{
+				Mode:  models.BatchAtomic,
+				Items: []models.BatchItem{{Type: models.BatchDebit, UserId: 1, Amount: 10, Currency: "EUR"}},
+			},
+			inputBody: `{"items":[{"type":"debit","user_id":1,"amount":10}]}`,
+			mockBehavior: func(s *mock_service.MockBatch, input models.BatchInput) {
+				s.EXPECT().ApplyBatch(input).Return(models.BatchOutput{}, errors.New("sql: database is closed"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"sql: database is closed"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			batch := mock_service.NewMockBatch(c)
+			testCase.mockBehavior(batch, testCase.input)
+
+			services := &service.Service{Batch: batch}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/batch", handler.batch)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/batch",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Set credit limit
+// @Tags credit
+// @Description Lets wallet of user go below zero down to minus credit limit, the wallet is opened if it does not exist.
+// @Description Limit lowered below the overdraft in use only forbids further debits
+// @ID set-credit-limit
+// @Accept  json
+// @Produce  json
+// @Param        user_id   path      int  true  "User ID"
+// @Param input body models.CreditLimitInput true "credit limit input"
+// @Success 200 {object} models.CreditLine
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /accounts/{user_id}/credit [post]
+func (h *Handler) setCreditLimit(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	var input models.CreditLimitInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	input.UserId = userId
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	line, err := h.s.SetCreditLimit(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, line)
+}
+
+// @Summary Get credit lines
+// @Tags credit
+// @Description Returns wallets of user with credit limit, held money, available real money
+// @Description = balance - promo + credit limit - held, overdraft in use and the sum of overdraft charges
+// @ID get-credit-lines
+// @Produce  json
+// @Param        user_id   path      int  true  "User ID"
+// @Success 200 {object} []models.CreditLine
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /accounts/{user_id}/credit [get]
+func (h *Handler) getCreditLines(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil || userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	lines, err := h.s.GetCreditLines(userId)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, lines)
+}
+
+// @Summary Get overdraft report
+// @Tags credit
+// @Description Returns overdrawn wallets of the currency with totals of overdraft in use and overdraft charges
+// @ID get-overdraft-report
+// @Produce  json
+// @Param        currency   query      string  false  "Currency, EUR by default"
+// @Success 200 {object} models.OverdraftReport
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /overdrafts [get]
+func (h *Handler) getOverdraftReport(c echo.Context) error {
+	currency, err := models.ParseCurrency(c.QueryParam("currency"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	report, err := h.s.GetOverdraftReport(currency)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// @Summary Place hold
+// @Tags holds
+// @Description Reserves available money of user, it is not available for debits until the hold is captured or released
+// @ID place-hold
+// @Accept  json
+// @Produce  json
+// @Param input body models.HoldInput true "hold input"
+// @Success 200 {object} models.Hold
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /holds [post]
+func (h *Handler) placeHold(c echo.Context) error {
+	var input models.HoldInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	hold, err := h.s.PlaceHold(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, hold)
+}
+
+// @Summary List holds
+// @Tags holds
+// @Description Returns holds of user
+// @ID list-holds
+// @Produce  json
+// @Param        user_id   query      int  true  "User ID"
+// @Param        status   query      string  false  "active, captured or released, all by default"
+// @Success 200 {object} []models.Hold
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /holds [get]
+func (h *Handler) listHolds(c echo.Context) error {
+	userId, err := strconv.Atoi(c.QueryParam("user_id"))
+	if err != nil || userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	holds, err := h.s.ListHolds(userId, c.QueryParam("status"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, holds)
+}
+
+// @Summary Get hold
+// @Tags holds
+// @Description Returns hold
+// @ID get-hold
+// @Produce  json
+// @Param        id   path      int  true  "Hold ID"
+// @Success 200 {object} models.Hold
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /holds/{id} [get]
+func (h *Handler) getHold(c echo.Context) error {
+	id, err := holdId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	hold, err := h.s.GetHold(id)
+	if err != nil {
+		if errors.Is(err, models.ErrHoldNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, hold)
+}
+
+// @Summary Capture hold
+// @Tags holds
+// @Description Debits the held money, 422 is returned when a spending limit would be exceeded
+// @ID capture-hold
+// @Produce  json
+// @Param        id   path      int  true  "Hold ID"
+// @Success 200 {object} models.Hold
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 422 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /holds/{id}/capture [post]
+func (h *Handler) captureHold(c echo.Context) error {
+	return h.closeHold(c, h.s.CaptureHold)
+}
+
+// @Summary Release hold
+// @Tags holds
+// @Description Returns the held money to available balance
+// @ID release-hold
+// @Produce  json
+// @Param        id   path      int  true  "Hold ID"
+// @Success 200 {object} models.Hold
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /holds/{id}/release [post]
+func (h *Handler) releaseHold(c echo.Context) error {
+	return h.closeHold(c, h.s.ReleaseHold)
+}
+
+func (h *Handler) closeHold(c echo.Context, change func(id int) (models.Hold, error)) error {
+	id, err := holdId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	hold, err := change(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrHoldNotFound):
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		case errors.Is(err, models.ErrLimitExceeded):
+			return h.log.ErrorResponse(http.StatusUnprocessableEntity, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, hold)
+}
+
+func holdId(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, errors.New("incorrect hold id")
+	}
+
+	return id, nil
+}
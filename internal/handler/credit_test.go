@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_SetCreditLimit(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockCreditLine, input models.CreditLimitInput)
+
+	testTable := []struct {
+		name                 string
+		userId               string
+		input                models.CreditLimitInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			userId:    "1",
+			input:     models.CreditLimitInput{UserId: 1, Currency: "EUR", CreditLimit: 500},
+			inputBody: `{"credit_limit":500}`,
+			mockBehavior: func(s *mock_service.MockCreditLine, input models.CreditLimitInput) {
+				s.EXPECT().SetCreditLimit(input).Return(models.CreditLine{
+					UserId: 1, Currency: "EUR", Balance: -120, CreditLimit: 500, Held: 30, Available: 350, Overdraft: 120,
+					Charged: 2.5,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"user_id":1,"currency":"EUR","balance":-120,"credit_limit":500,"held":30,` +
+				`"available":350,"overdraft":120,"charged":2.5}`,
+		},
+		{
+			name:                 "Negative credit limit",
+			userId:               "1",
+			inputBody:            `{"credit_limit":-1}`,
+			mockBehavior:         func(s *mock_service.MockCreditLine, input models.CreditLimitInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"credit limit must not be negative"}`,
+		},
+		{
+			name:      "User does not exist",
+			userId:    "300",
+			input:     models.CreditLimitInput{UserId: 300, Currency: "USD", CreditLimit: 100},
+			inputBody: `{"currency":"usd","credit_limit":100}`,
+			mockBehavior: func(s *mock_service.MockCreditLine, input models.CreditLimitInput) {
+				s.EXPECT().SetCreditLimit(input).Return(models.CreditLine{}, errors.New("user not found"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"user not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			credit := mock_service.NewMockCreditLine(c)
+			testCase.mockBehavior(credit, testCase.input)
+
+			services := &service.Service{CreditLine: credit}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/accounts/:user_id/credit", handler.setCreditLimit)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/accounts/"+testCase.userId+"/credit",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_CaptureHold(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockCreditLine)
+
+	date := time.Date(2023, 8, 15, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		id                   string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			id:   "1",
+			mockBehavior: func(s *mock_service.MockCreditLine) {
+				s.EXPECT().CaptureHold(1).Return(models.Hold{ID: 1, UserId: 1, Amount: 30, Currency: "EUR",
+					Status: models.HoldCaptured, CreatedAt: date, ClosedAt: &date}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"amount":30,"currency":"EUR","status":"captured",` +
+				`"created_at":"2023-08-15T12:00:00Z","closed_at":"2023-08-15T12:00:00Z"}`,
+		},
+		{
+			name: "Not found",
+			id:   "2",
+			mockBehavior: func(s *mock_service.MockCreditLine) {
+				s.EXPECT().CaptureHold(2).Return(models.Hold{}, models.ErrHoldNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"hold not found"}`,
+		},
+		{
+			name: "Released",
+			id:   "3",
+			mockBehavior: func(s *mock_service.MockCreditLine) {
+				s.EXPECT().CaptureHold(3).Return(models.Hold{}, errors.New("hold is released"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"hold is released"}`,
+		},
+		{
+			name: "Limit exceeded",
+			id:   "4",
+			mockBehavior: func(s *mock_service.MockCreditLine) {
+				s.EXPECT().CaptureHold(4).Return(models.Hold{},
+					fmt.Errorf("%w: limit #1 of 50.00 EUR per day on debits, 20.00 is remaining", models.ErrLimitExceeded))
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"message":"spending limit exceeded: limit #1 of 50.00 EUR per day on debits, 20.00 is remaining"}`,
+		},
+		{
+			name:                 "Incorrect id",
+			id:                   "abc",
+			mockBehavior:         func(s *mock_service.MockCreditLine) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect hold id"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			credit := mock_service.NewMockCreditLine(c)
+			testCase.mockBehavior(credit)
+
+			services := &service.Service{CreditLine: credit}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/holds/:id/capture", handler.captureHold)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/holds/"+testCase.id+"/capture", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
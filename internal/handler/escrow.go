@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Create escrow
+// @Tags escrows
+// @Description Moves money of the sender to the escrow account until the sender confirms it or the recipient
+// @Description cancels it. Held escrow is refunded when it expires, transactions are linked by escrow-{id}
+// @ID create-escrow
+// @Accept  json
+// @Produce  json
+// @Param input body models.EscrowInput true "escrow input"
+// @Success 200 {object} models.Escrow
+// @Failure 400,404,422 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /escrows [post]
+func (h *Handler) createEscrow(c echo.Context) error {
+	var input models.EscrowInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	escrow, err := h.s.CreateEscrow(input)
+	if err != nil {
+		if errors.Is(err, models.ErrLimitExceeded) {
+			return h.log.ErrorResponse(http.StatusUnprocessableEntity, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, escrow)
+}
+
+// @Summary List escrows
+// @Tags escrows
+// @Description Returns escrows the user sent or receives
+// @ID list-escrows
+// @Produce  json
+// @Param        user_id   query      int  true  "User ID"
+// @Param        status   query      string  false  "held, released or refunded, all by default"
+// @Success 200 {object} []models.Escrow
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /escrows [get]
+func (h *Handler) listEscrows(c echo.Context) error {
+	userId, err := strconv.Atoi(c.QueryParam("user_id"))
+	if err != nil || userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	escrows, err := h.s.ListEscrows(userId, c.QueryParam("status"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, escrows)
+}
+
+// @Summary Get escrow
+// @Tags escrows
+// @Description Returns escrow with the history of its changes
+// @ID get-escrow
+// @Produce  json
+// @Param        id   path      int  true  "Escrow ID"
+// @Success 200 {object} models.Escrow
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /escrows/{id} [get]
+func (h *Handler) getEscrow(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect escrow id"))
+	}
+
+	escrow, err := h.s.GetEscrow(id)
+	if err != nil {
+		if errors.Is(err, models.ErrEscrowNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, escrow)
+}
+
+// @Summary Confirm escrow
+// @Tags escrows
+// @Description Releases held escrow to the recipient, only the sender confirms escrow
+// @ID confirm-escrow
+// @Accept  json
+// @Produce  json
+// @Param        id   path      int  true  "Escrow ID"
+// @Param input body models.EscrowActionInput true "the sender"
+// @Success 200 {object} models.Escrow
+// @Failure 400,404,422 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /escrows/{id}/confirm [post]
+func (h *Handler) confirmEscrow(c echo.Context) error {
+	return h.closeEscrow(c, h.s.ConfirmEscrow)
+}
+
+// @Summary Cancel escrow
+// @Tags escrows
+// @Description Refunds held escrow to the sender, only the recipient cancels escrow
+// @ID cancel-escrow
+// @Accept  json
+// @Produce  json
+// @Param        id   path      int  true  "Escrow ID"
+// @Param input body models.EscrowActionInput true "the recipient"
+// @Success 200 {object} models.Escrow
+// @Failure 400,404,422 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /escrows/{id}/cancel [post]
+func (h *Handler) cancelEscrow(c echo.Context) error {
+	return h.closeEscrow(c, h.s.CancelEscrow)
+}
+
+func (h *Handler) closeEscrow(c echo.Context,
+	change func(id int, input models.EscrowActionInput) (models.Escrow, error)) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect escrow id"))
+	}
+
+	var input models.EscrowActionInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if input.UserId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	escrow, err := change(id, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEscrowNotFound):
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		case errors.Is(err, models.ErrEscrowNotAllowed):
+			return h.log.ErrorResponse(http.StatusUnprocessableEntity, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, escrow)
+}
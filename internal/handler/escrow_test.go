@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_CreateEscrow(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockEscrow, input models.EscrowInput)
+
+	date := time.Date(2023, 9, 5, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		input                models.EscrowInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			input:     models.EscrowInput{SenderId: 1, RecipientId: 2, Amount: 40, Currency: "EUR"},
+			inputBody: `{"sender_id":1,"recipient_id":2,"amount":40}`,
+			mockBehavior: func(s *mock_service.MockEscrow, input models.EscrowInput) {
+				s.EXPECT().CreateEscrow(input).Return(models.Escrow{ID: 3, SenderId: 1, RecipientId: 2, Amount: 40,
+					Currency: "EUR", Status: models.EscrowHeld, ExpiresAt: date.Add(24 * time.Hour), CreatedAt: date,
+					Events: []models.EscrowEvent{{ID: 5, EscrowId: 3, Type: models.EscrowCreated, UserId: 1,
+						Status: models.EscrowHeld, Date: date}}}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":3,"sender_id":1,"recipient_id":2,"amount":40,"currency":"EUR",` +
+				`"status":"held","expires_at":"2023-09-06T12:00:00Z","created_at":"2023-09-05T12:00:00Z",` +
+				`"events":[{"id":5,"escrow_id":3,"type":"created","user_id":1,"status":"held",` +
+				`"date":"2023-09-05T12:00:00Z"}]}`,
+		},
+		{
+			name:                 "Same users",
+			inputBody:            `{"sender_id":1,"recipient_id":1,"amount":40}`,
+			mockBehavior:         func(s *mock_service.MockEscrow, input models.EscrowInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"sender and recipient must differ"}`,
+		},
+		{
+			name:      "Limit exceeded",
+			input:     models.EscrowInput{SenderId: 1, RecipientId: 2, Amount: 400, Currency: "EUR"},
+			inputBody: `{"sender_id":1,"recipient_id":2,"amount":400}`,
+			mockBehavior: func(s *mock_service.MockEscrow, input models.EscrowInput) {
+				s.EXPECT().CreateEscrow(input).Return(models.Escrow{}, fmt.Errorf("%w: limit #1 of 100.00 EUR "+
+					"per operation on transfers, 100.00 is remaining", models.ErrLimitExceeded))
+			},
+			expectedStatusCode: 422,
+			expectedResponseBody: `{"message":"spending limit exceeded: limit #1 of 100.00 EUR per operation on ` +
+				`transfers, 100.00 is remaining"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			escrow := mock_service.NewMockEscrow(c)
+			testCase.mockBehavior(escrow, testCase.input)
+
+			services := &service.Service{Escrow: escrow}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/escrows", handler.createEscrow)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/escrows", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_ConfirmEscrow(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockEscrow)
+
+	date := time.Date(2023, 9, 5, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		id                   string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			id:        "3",
+			inputBody: `{"user_id":1}`,
+			mockBehavior: func(s *mock_service.MockEscrow) {
+				s.EXPECT().ConfirmEscrow(3, models.EscrowActionInput{UserId: 1}).Return(models.Escrow{ID: 3,
+					SenderId: 1, RecipientId: 2, Amount: 40, Currency: "EUR", Status: models.EscrowReleased,
+					ExpiresAt: date, CreatedAt: date, ClosedAt: &date}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":3,"sender_id":1,"recipient_id":2,"amount":40,"currency":"EUR",` +
+				`"status":"released","expires_at":"2023-09-05T12:00:00Z","created_at":"2023-09-05T12:00:00Z",` +
+				`"closed_at":"2023-09-05T12:00:00Z"}`,
+		},
+		{
+			name:      "Wrong party",
+			id:        "3",
+			inputBody: `{"user_id":2}`,
+			mockBehavior: func(s *mock_service.MockEscrow) {
+				s.EXPECT().ConfirmEscrow(3, models.EscrowActionInput{UserId: 2}).Return(models.Escrow{},
+					fmt.Errorf("%w: only the sender confirms escrow", models.ErrEscrowNotAllowed))
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"message":"escrow change is not allowed: only the sender confirms escrow"}`,
+		},
+		{
+			name:      "Not found",
+			id:        "4",
+			inputBody: `{"user_id":1}`,
+			mockBehavior: func(s *mock_service.MockEscrow) {
+				s.EXPECT().ConfirmEscrow(4, models.EscrowActionInput{UserId: 1}).Return(models.Escrow{},
+					models.ErrEscrowNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"escrow not found"}`,
+		},
+		{
+			name:                 "No user",
+			id:                   "3",
+			inputBody:            `{}`,
+			mockBehavior:         func(s *mock_service.MockEscrow) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect user id"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			escrow := mock_service.NewMockEscrow(c)
+			testCase.mockBehavior(escrow)
+
+			services := &service.Service{Escrow: escrow}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/escrows/:id/confirm", handler.confirmEscrow)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/escrows/"+testCase.id+"/confirm",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
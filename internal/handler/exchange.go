@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Quote currency exchange
+// @Tags exchange
+// @Description Locks exchange rate between two user`s wallets for a short time
+// @ID exchange-quote
+// @Accept  json
+// @Produce  json
+// @Param input body models.ExchangeQuoteInput true "quote input"
+// @Success 200 {object} models.ExchangeQuote
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /exchange/quote [post]
+func (h *Handler) quoteExchange(c echo.Context) error {
+	var input models.ExchangeQuoteInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if input.UserId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	if input.Amount <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("amount must be positive"))
+	}
+
+	quote, err := h.s.QuoteExchange(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, quote)
+}
+
+// @Summary Exchange currency
+// @Tags exchange
+// @Description Exchanges money between user`s wallets at the quoted rate
+// @ID exchange
+// @Accept  json
+// @Produce  json
+// @Param input body models.ExchangeInput true "exchange input"
+// @Success 200 {object} models.ExchangeResult
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /exchange [post]
+func (h *Handler) exchange(c echo.Context) error {
+	var input models.ExchangeInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if input.UserId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	if input.QuoteId == "" {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("quote id is required"))
+	}
+
+	result, err := h.s.ExecuteExchange(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
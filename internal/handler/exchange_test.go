@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_QuoteExchange(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockExchange, input models.ExchangeQuoteInput)
+
+	expiresAt := time.Date(2023, 6, 20, 12, 0, 30, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		input                models.ExchangeQuoteInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			input: models.ExchangeQuoteInput{
+				UserId: 1,
+				From:   "EUR",
+				To:     "USD",
+				Amount: 100,
+			},
+			inputBody: `{"user_id":1,"from":"EUR","to":"USD","amount":100}`,
+			mockBehavior: func(s *mock_service.MockExchange, input models.ExchangeQuoteInput) {
+				s.EXPECT().QuoteExchange(input).Return(models.ExchangeQuote{
+					ID:        "7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10",
+					UserId:    1,
+					From:      "EUR",
+					To:        "USD",
+					Amount:    100,
+					Rate:      1.1,
+					Fee:       1.1,
+					ToAmount:  108.9,
+					ExpiresAt: expiresAt,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":"7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10","user_id":1,"from":"EUR","to":"USD","amount":100,"rate":1.1,"fee":1.1,"to_amount":108.9,"expires_at":"2023-06-20T12:00:30Z"}`,
+		},
+		{
+			name:                 "Incorrect user id",
+			inputBody:            `{"user_id":0,"from":"EUR","to":"USD","amount":100}`,
+			mockBehavior:         func(s *mock_service.MockExchange, input models.ExchangeQuoteInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect user id"}`,
+		},
+		{
+			name:                 "Negative amount",
+			inputBody:            `{"user_id":1,"from":"EUR","to":"USD","amount":-1}`,
+			mockBehavior:         func(s *mock_service.MockExchange, input models.ExchangeQuoteInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"amount must be positive"}`,
+		},
+		{
+			name: "Error from service",
+			input: models.ExchangeQuoteInput{
+				UserId: 1,
+				From:   "EUR",
+				To:     "EUR",
+				Amount: 100,
+			},
+			inputBody: `{"user_id":1,"from":"EUR","to":"EUR","amount":100}`,
+			mockBehavior: func(s *mock_service.MockExchange, input models.ExchangeQuoteInput) {
+				s.EXPECT().QuoteExchange(input).Return(models.ExchangeQuote{}, errors.New("cannot exchange currency to itself"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"cannot exchange currency to itself"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			exchange := mock_service.NewMockExchange(c)
+			testCase.mockBehavior(exchange, testCase.input)
+
+			services := &service.Service{Exchange: exchange}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/exchange/quote", handler.quoteExchange)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/exchange/quote",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_Exchange(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockExchange, input models.ExchangeInput)
+
+	testTable := []struct {
+		name                 string
+		input                models.ExchangeInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			input: models.ExchangeInput{
+				UserId:  1,
+				QuoteId: "7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10",
+			},
+			inputBody: `{"user_id":1,"quote_id":"7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10"}`,
+			mockBehavior: func(s *mock_service.MockExchange, input models.ExchangeInput) {
+				s.EXPECT().ExecuteExchange(input).Return(models.ExchangeResult{
+					UserId: 1,
+					LinkId: input.QuoteId,
+					From:   models.Wallet{UserId: 1, Currency: "EUR", Balance: 0},
+					To:     models.Wallet{UserId: 1, Currency: "USD", Balance: 108.9},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user_id":1,"link_id":"7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10","from":{"currency":"EUR","balance":0},"to":{"currency":"USD","balance":108.9}}`,
+		},
+		{
+			name:                 "Missing quote id",
+			inputBody:            `{"user_id":1}`,
+			mockBehavior:         func(s *mock_service.MockExchange, input models.ExchangeInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"quote id is required"}`,
+		},
+		{
+			name:                 "Incorrect user id",
+			inputBody:            `{"user_id":-1,"quote_id":"7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10"}`,
+			mockBehavior:         func(s *mock_service.MockExchange, input models.ExchangeInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect user id"}`,
+		},
+		{
+			name: "Expired quote",
+			input: models.ExchangeInput{
+				UserId:  1,
+				QuoteId: "7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10",
+			},
+			inputBody: `{"user_id":1,"quote_id":"7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10"}`,
+			mockBehavior: func(s *mock_service.MockExchange, input models.ExchangeInput) {
+				s.EXPECT().ExecuteExchange(input).Return(models.ExchangeResult{}, errors.New("quote is expired"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"quote is expired"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			exchange := mock_service.NewMockExchange(c)
+			testCase.mockBehavior(exchange, testCase.input)
+
+			services := &service.Service{Exchange: exchange}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/exchange", handler.exchange)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/exchange",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Create fee rule
+// @Tags fees
+// @Description Charges transfers or exchanges a part of the amount plus a fixed fee on top of the amount.
+// @Description The rule may be restricted to one client, currency and amount bracket, the most specific rule applies
+// @ID create-fee-rule
+// @Accept  json
+// @Produce  json
+// @Param input body models.FeeRuleInput true "fee rule input"
+// @Success 200 {object} models.FeeRule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /fees/rules [post]
+func (h *Handler) createFeeRule(c echo.Context) error {
+	var input models.FeeRuleInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	rule, err := h.s.CreateFeeRule(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// @Summary List fee rules
+// @Tags fees
+// @Description Returns fee rules
+// @ID list-fee-rules
+// @Produce  json
+// @Param        operation   query      string  false  "transfer or exchange, all by default"
+// @Success 200 {object} []models.FeeRule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /fees/rules [get]
+func (h *Handler) listFeeRules(c echo.Context) error {
+	rules, err := h.s.ListFeeRules(c.QueryParam("operation"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+// @Summary Delete fee rule
+// @Tags fees
+// @Description Removes fee rule, fees already charged are kept
+// @ID delete-fee-rule
+// @Param        id   path      int  true  "Fee rule ID"
+// @Success 204
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /fees/rules/{id} [delete]
+func (h *Handler) deleteFeeRule(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect fee rule id"))
+	}
+
+	if err := h.s.DeleteFeeRule(id); err != nil {
+		if errors.Is(err, models.ErrFeeRuleNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Quote fee
+// @Tags fees
+// @Description Returns the fee the operation would be charged now. For exchanges amount and currency are
+// @Description the exchanged ones, the spread is quoted by exchange quote
+// @ID quote-fee
+// @Produce  json
+// @Param        operation   query      string  true  "transfer or exchange"
+// @Param        user_id   query      int  true  "User ID"
+// @Param        amount   query      number  true  "Amount"
+// @Param        currency   query      string  false  "Currency, EUR by default"
+// @Success 200 {object} models.FeeQuote
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /fees/quote [get]
+func (h *Handler) quoteFee(c echo.Context) error {
+	var input models.FeeQuoteInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	quote, err := h.s.QuoteFee(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, quote)
+}
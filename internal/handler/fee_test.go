@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_CreateFeeRule(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockFee, input models.FeeRuleInput)
+
+	date := time.Date(2023, 8, 20, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		input                models.FeeRuleInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			input:     models.FeeRuleInput{Operation: models.FeeTransfer, Currency: "EUR", MaxAmount: 1000, Rate: 0.01, Fixed: 0.5},
+			inputBody: `{"operation":"transfer","currency":"eur","max_amount":1000,"rate":0.01,"fixed":0.5}`,
+			mockBehavior: func(s *mock_service.MockFee, input models.FeeRuleInput) {
+				s.EXPECT().CreateFeeRule(input).Return(models.FeeRule{ID: 1, Operation: models.FeeTransfer,
+					Currency: "EUR", MaxAmount: 1000, Rate: 0.01, Fixed: 0.5, CreatedAt: date}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"operation":"transfer","currency":"EUR","min_amount":0,"max_amount":1000,` +
+				`"rate":0.01,"fixed":0.5,"created_at":"2023-08-20T12:00:00Z"}`,
+		},
+		{
+			name:                 "Empty bracket",
+			inputBody:            `{"operation":"exchange","min_amount":100,"max_amount":50,"rate":0.01}`,
+			mockBehavior:         func(s *mock_service.MockFee, input models.FeeRuleInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"max amount must be greater than min amount"}`,
+		},
+		{
+			name:                 "Charges nothing",
+			inputBody:            `{"operation":"transfer"}`,
+			mockBehavior:         func(s *mock_service.MockFee, input models.FeeRuleInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"rule charges nothing"}`,
+		},
+		{
+			name:      "User does not exist",
+			input:     models.FeeRuleInput{Operation: models.FeeTransfer, UserId: 300, Fixed: 1},
+			inputBody: `{"operation":"transfer","user_id":300,"fixed":1}`,
+			mockBehavior: func(s *mock_service.MockFee, input models.FeeRuleInput) {
+				s.EXPECT().CreateFeeRule(input).Return(models.FeeRule{}, errors.New("user not found"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"user not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			fee := mock_service.NewMockFee(c)
+			testCase.mockBehavior(fee, testCase.input)
+
+			services := &service.Service{Fee: fee}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/fees/rules", handler.createFeeRule)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/fees/rules", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_QuoteFee(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockFee, input models.FeeQuoteInput)
+
+	testTable := []struct {
+		name                 string
+		query                string
+		input                models.FeeQuoteInput
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "OK",
+			query: "operation=transfer&user_id=1&amount=100&currency=usd",
+			input: models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 1, Amount: 100, Currency: "USD"},
+			mockBehavior: func(s *mock_service.MockFee, input models.FeeQuoteInput) {
+				s.EXPECT().QuoteFee(input).Return(models.FeeQuote{Operation: models.FeeTransfer, UserId: 1, Amount: 100,
+					Currency: "USD", RuleId: 2, Fee: 1.5, Total: 101.5}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"operation":"transfer","user_id":1,"amount":100,"currency":"USD","rule_id":2,` +
+				`"fee":1.5,"total":101.5}`,
+		},
+		{
+			name:  "No rule applies",
+			query: "operation=exchange&user_id=1&amount=10",
+			input: models.FeeQuoteInput{Operation: models.FeeExchange, UserId: 1, Amount: 10, Currency: "EUR"},
+			mockBehavior: func(s *mock_service.MockFee, input models.FeeQuoteInput) {
+				s.EXPECT().QuoteFee(input).Return(models.FeeQuote{Operation: models.FeeExchange, UserId: 1, Amount: 10,
+					Currency: "EUR", Total: 10}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"operation":"exchange","user_id":1,"amount":10,"currency":"EUR","fee":0,"total":10}`,
+		},
+		{
+			name:                 "Unsupported operation",
+			query:                "operation=debit&user_id=1&amount=10",
+			mockBehavior:         func(s *mock_service.MockFee, input models.FeeQuoteInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"unsupported operation \"debit\""}`,
+		},
+		{
+			name:                 "Negative amount",
+			query:                "operation=transfer&user_id=1&amount=-10",
+			mockBehavior:         func(s *mock_service.MockFee, input models.FeeQuoteInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"amount must be positive"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			fee := mock_service.NewMockFee(c)
+			testCase.mockBehavior(fee, testCase.input)
+
+			services := &service.Service{Fee: fee}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/fees/quote", handler.quoteFee)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/fees/quote?"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"crypto/subtle"
+	"strings"
+	"time"
+
 	"github.com/gavrylenkoIvan/balance-service/internal/service"
 	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
 	"github.com/labstack/echo/v4"
@@ -10,9 +14,27 @@ import (
 	_ "github.com/gavrylenkoIvan/balance-service/docs"
 )
 
+// apiKeyHeader carries one of configured API keys
+const apiKeyHeader = "X-API-Key"
+
+// Config holds HTTP settings of the API
+type Config struct {
+	// APIKeys accepted in X-API-Key header, authentication is disabled when empty
+	APIKeys []string
+	// Operators maps names of operators to their keys, the keys are accepted like APIKeys and identify
+	// the operator of the request
+	Operators map[string]string
+	// BodyLimit limits request body, e.g. 4M, no limit when empty
+	BodyLimit string
+	// StreamHeartbeat is the interval of heartbeats sent to idle balance streams
+	StreamHeartbeat time.Duration
+}
+
 type Handler struct {
 	s   *service.Service
 	log logging.Logger
+	// authenticated is set when requests are authenticated, operators are identified only by their keys then
+	authenticated bool
 }
 
 func NewHandler(s *service.Service, log logging.Logger) *Handler {
@@ -22,17 +44,123 @@ func NewHandler(s *service.Service, log logging.Logger) *Handler {
 	}
 }
 
-func (h *Handler) InitRoutes() *echo.Echo {
+func (h *Handler) InitRoutes(cfg Config) *echo.Echo {
 	r := echo.New()
 
 	r.Use(middleware.Logger())
+	if cfg.BodyLimit != "" {
+		r.Use(middleware.BodyLimit(cfg.BodyLimit))
+	}
+	if len(cfg.APIKeys) > 0 || len(cfg.Operators) > 0 {
+		h.authenticated = true
+		r.Use(middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+			KeyLookup: "header:" + apiKeyHeader,
+			Skipper: func(c echo.Context) bool {
+				return strings.HasPrefix(c.Path(), "/swagger/")
+			},
+			Validator: func(key string, c echo.Context) (bool, error) {
+				if name, ok := operatorOf(key, cfg.Operators); ok {
+					c.Set(operatorContextKey, name)
+					return true, nil
+				}
+
+				return validAPIKey(key, cfg.APIKeys), nil
+			},
+		}))
+	}
+
 	r.GET("/balance/:user_id", h.getBalance)
+	r.GET("/balance/:user_id/history", h.getBalanceHistory)
+	r.GET("/balance/:user_id/stream", h.streamBalance(cfg.StreamHeartbeat))
 	r.GET("/transactions/:user_id", h.getTransactions)
 	r.POST("/top-up", h.topUp)
 	r.POST("/debit", h.debit)
 	r.POST("/transfer", h.transfer)
+	r.POST("/exchange/quote", h.quoteExchange)
+	r.POST("/exchange", h.exchange)
+	r.POST("/batch", h.batch)
+	r.POST("/import", h.importOperations)
+	r.GET("/import/:id", h.getImport)
+	r.POST("/adjustments", h.proposeAdjustment)
+	r.GET("/adjustments", h.listAdjustments)
+	r.GET("/adjustments/:id", h.getAdjustment)
+	r.POST("/adjustments/:id/approve", h.approveAdjustment)
+	r.POST("/adjustments/:id/reject", h.rejectAdjustment)
+	r.GET("/accounts/:user_id", h.getAccount)
+	r.POST("/accounts/:user_id/status", h.setAccountStatus)
+	r.GET("/accounts/:user_id/credit", h.getCreditLines)
+	r.POST("/accounts/:user_id/credit", h.setCreditLimit)
+	r.POST("/reconciliation", h.reconcile)
+	r.GET("/reconciliation", h.getLastReconciliation)
+	r.GET("/reconciliation/:id", h.getReconciliation)
+	r.POST("/reconciliation/:id/approve", h.approveCorrections)
+	r.GET("/metrics", h.metrics)
+	r.GET("/audit/verify", h.verifyChain)
+	r.POST("/audit/checkpoints", h.createCheckpoint)
+	r.POST("/schedules", h.createSchedule)
+	r.GET("/schedules", h.listSchedules)
+	r.GET("/schedules/:id", h.getSchedule)
+	r.POST("/schedules/:id/pause", h.pauseSchedule)
+	r.POST("/schedules/:id/resume", h.resumeSchedule)
+	r.POST("/schedules/:id/cancel", h.cancelSchedule)
+	r.POST("/plans", h.createPlan)
+	r.GET("/plans", h.listPlans)
+	r.GET("/plans/:id", h.getPlan)
+	r.POST("/subscriptions", h.subscribe)
+	r.GET("/subscriptions", h.listSubscriptions)
+	r.GET("/subscriptions/events", h.getSubscriptionEvents)
+	r.GET("/subscriptions/:id", h.getSubscription)
+	r.POST("/subscriptions/:id/plan", h.changePlan)
+	r.POST("/subscriptions/:id/cancel", h.cancelSubscription)
+	r.POST("/limits", h.setLimit)
+	r.GET("/limits", h.getLimits)
+	r.DELETE("/limits/:id", h.deleteLimit)
+	r.GET("/overdrafts", h.getOverdraftReport)
+	r.POST("/holds", h.placeHold)
+	r.GET("/holds", h.listHolds)
+	r.GET("/holds/:id", h.getHold)
+	r.POST("/holds/:id/capture", h.captureHold)
+	r.POST("/holds/:id/release", h.releaseHold)
+	r.POST("/fees/rules", h.createFeeRule)
+	r.GET("/fees/rules", h.listFeeRules)
+	r.DELETE("/fees/rules/:id", h.deleteFeeRule)
+	r.GET("/fees/quote", h.quoteFee)
+	r.POST("/promo/grants", h.grantPromo)
+	r.GET("/promo/grants", h.listPromoGrants)
+	r.GET("/promo/grants/:id", h.getPromoGrant)
+	r.POST("/vouchers/batches", h.createVoucherBatch)
+	r.POST("/vouchers/redeem", h.redeemVoucher)
+	r.GET("/vouchers/report", h.getVoucherReport)
+	r.POST("/escrows", h.createEscrow)
+	r.GET("/escrows", h.listEscrows)
+	r.GET("/escrows/:id", h.getEscrow)
+	r.POST("/escrows/:id/confirm", h.confirmEscrow)
+	r.POST("/escrows/:id/cancel", h.cancelEscrow)
 
 	r.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	return r
 }
+
+func validAPIKey(key string, keys []string) bool {
+	valid := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// operatorOf returns the name of the operator with the key
+func operatorOf(key string, operators map[string]string) (string, bool) {
+	operator := ""
+	for name, k := range operators {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			operator = name
+		}
+	}
+
+	return operator, operator != ""
+}
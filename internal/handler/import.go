@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Import operations
+// @Tags import
+// @Description Imports top-ups and debits from CSV file with user_id, amount, type, comment and external_ref columns.
+// @Description With dry_run the file is only validated, otherwise an import job is started
+// @ID import
+// @Accept  text/csv,mpfd
+// @Produce  json
+// @Param        file   formData      file  false  "CSV file, request body is used if it is not set"
+// @Param        dry_run   query      bool  false  "Only validate the file"
+// @Success 200 {object} models.ImportReport
+// @Success 202 {object} models.ImportJob
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /import [post]
+func (h *Handler) importOperations(c echo.Context) error {
+	file, err := importFile(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+	defer file.Close()
+
+	if c.QueryParam("dry_run") == "true" {
+		report, err := h.s.ValidateImport(file)
+		if err != nil {
+			return h.log.ErrorResponse(http.StatusBadRequest, err)
+		}
+
+		return c.JSON(http.StatusOK, report)
+	}
+
+	job, err := h.s.StartImport(file)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidImport) {
+			return h.log.ErrorResponse(http.StatusBadRequest, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// @Summary Get import job
+// @Tags import
+// @Description Returns progress and result of import job
+// @ID get-import
+// @Produce  json
+// @Param        id   path      string  true  "Import job ID"
+// @Success 200 {object} models.ImportJob
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /import/{id} [get]
+func (h *Handler) getImport(c echo.Context) error {
+	job, err := h.s.GetImportJob(c.Param("id"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusNotFound, err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// importFile returns uploaded file or request body if nothing is uploaded
+func importFile(c echo.Context) (io.ReadCloser, error) {
+	header, err := c.FormFile("file")
+	if err == http.ErrMissingFile || err == http.ErrNotMultipart {
+		return c.Request().Body, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return header.Open()
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Import(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockImport)
+
+	createdAt := time.Date(2023, 6, 30, 12, 0, 0, 0, time.UTC)
+
+	validFile := "user_id,amount,type,comment,external_ref\n" +
+		"1,10,top-up,Refund for order 15,ref-1\n" +
+		"2,5.5,debit,,ref-2\n"
+
+	invalidFile := "user_id,amount,type,comment,external_ref\n" +
+		"1,10,top-up,,ref-1\n" +
+		"x,10,top-up,,ref-2\n" +
+		"2,10,transfer,,ref-3\n" +
+		"3,-1,debit,,ref-4\n" +
+		"4,1,debit,,ref-1\n"
+
+	testTable := []struct {
+		name                 string
+		url                  string
+		contentType          string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:        "Dry run",
+			url:         "/import?dry_run=true",
+			contentType: "text/csv",
+			inputBody:   invalidFile,
+			mockBehavior: func(s *mock_service.MockImport) {
+				s.EXPECT().ValidateImport(gomock.Any()).DoAndReturn(func(r io.Reader) (models.ImportReport, error) {
+					_, report, err := models.ParseImport(r)
+					return report, err
+				})
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"lines":5,"valid":1,"errors":[` +
+				`{"line":3,"error":"incorrect user_id \"x\""},` +
+				`{"line":4,"error":"unsupported type \"transfer\""},` +
+				`{"line":5,"error":"amount must be positive"},` +
+				`{"line":6,"error":"external_ref is already used on line 2"}]}`,
+		},
+		{
+			name:        "Dry run without required column",
+			url:         "/import?dry_run=true",
+			contentType: "text/csv",
+			inputBody:   "user_id,amount\n1,10\n",
+			mockBehavior: func(s *mock_service.MockImport) {
+				s.EXPECT().ValidateImport(gomock.Any()).DoAndReturn(func(r io.Reader) (models.ImportReport, error) {
+					_, report, err := models.ParseImport(r)
+					return report, err
+				})
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"column \"type\" is required"}`,
+		},
+		{
+			name:        "Start",
+			url:         "/import",
+			contentType: "text/csv",
+			inputBody:   validFile,
+			mockBehavior: func(s *mock_service.MockImport) {
+				s.EXPECT().StartImport(gomock.Any()).DoAndReturn(func(r io.Reader) (models.ImportJob, error) {
+					lines, _, err := models.ParseImport(r)
+					if err != nil {
+						return models.ImportJob{}, err
+					}
+
+					return models.ImportJob{
+						ID:        "2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21",
+						Status:    models.ImportPending,
+						Total:     len(lines),
+						Errors:    models.ImportErrors{},
+						CreatedAt: createdAt,
+					}, nil
+				})
+			},
+			expectedStatusCode: 202,
+			expectedResponseBody: `{"id":"2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21","status":"pending","total":2,"processed":0,` +
+				`"applied":0,"failed":0,"errors":[],"created_at":"2023-06-30T12:00:00Z"}`,
+		},
+		{
+			name:        "Invalid file",
+			url:         "/import",
+			contentType: "text/csv",
+			inputBody:   invalidFile,
+			mockBehavior: func(s *mock_service.MockImport) {
+				s.EXPECT().StartImport(gomock.Any()).Return(models.ImportJob{},
+					fmt.Errorf("%w, 4 of 5 lines have errors, validate it to see them", models.ErrInvalidImport))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"import file is invalid, 4 of 5 lines have errors, validate it to see them"}`,
+		},
+		{
+			name:        "Error from service",
+			url:         "/import",
+			contentType: "text/csv",
+			inputBody:   validFile,
+			mockBehavior: func(s *mock_service.MockImport) {
+				s.EXPECT().StartImport(gomock.Any()).Return(models.ImportJob{}, errors.New("sql: database is closed"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"sql: database is closed"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			imports := mock_service.NewMockImport(c)
+			testCase.mockBehavior(imports)
+
+			services := &service.Service{Import: imports}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/import", handler.importOperations)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", testCase.url,
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", testCase.contentType)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_ImportMultipart(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	imports := mock_service.NewMockImport(c)
+	imports.EXPECT().ValidateImport(gomock.Any()).DoAndReturn(func(r io.Reader) (models.ImportReport, error) {
+		_, report, err := models.ParseImport(r)
+		return report, err
+	})
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	handler := NewHandler(&service.Service{Import: imports}, logger)
+
+	r := echo.New()
+	r.POST("/import", handler.importOperations)
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile("file", "corrections.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("user_id,amount,type\n1,10,top-up\n"))
+	form.Close()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/import?dry_run=true", body)
+	req.Header.Add("Content-Type", form.FormDataContentType())
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"lines":1,"valid":1,"errors":[]}`, strings.ReplaceAll(w.Body.String(), "\n", ""))
+}
+
+func TestHandler_GetImport(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockImport, id string)
+
+	createdAt := time.Date(2023, 6, 30, 12, 0, 0, 0, time.UTC)
+	finishedAt := createdAt.Add(time.Minute)
+
+	testTable := []struct {
+		name                 string
+		id                   string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			id:   "2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21",
+			mockBehavior: func(s *mock_service.MockImport, id string) {
+				s.EXPECT().GetImportJob(id).Return(models.ImportJob{
+					ID:         id,
+					Status:     models.ImportCompleted,
+					Total:      2,
+					Processed:  2,
+					Applied:    1,
+					Failed:     1,
+					Errors:     models.ImportErrors{{Line: 3, Error: "not enough money to perform purchase"}},
+					CreatedAt:  createdAt,
+					FinishedAt: &finishedAt,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":"2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21","status":"completed","total":2,"processed":2,` +
+				`"applied":1,"failed":1,"errors":[{"line":3,"error":"not enough money to perform purchase"}],` +
+				`"created_at":"2023-06-30T12:00:00Z","finished_at":"2023-06-30T12:01:00Z"}`,
+		},
+		{
+			name: "Not found",
+			id:   "2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21",
+			mockBehavior: func(s *mock_service.MockImport, id string) {
+				s.EXPECT().GetImportJob(id).Return(models.ImportJob{}, errors.New("import job not found"))
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"import job not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			imports := mock_service.NewMockImport(c)
+			testCase.mockBehavior(imports, testCase.id)
+
+			services := &service.Service{Import: imports}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/import/:id", handler.getImport)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/import/"+testCase.id, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
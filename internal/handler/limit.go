@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Set spending limit
+// @Tags limits
+// @Description Caps debits, transfers or both of user per operation or per rolling day, week or month.
+// @Description Transfer limit may be restricted to one counterparty. Setting the same limit again replaces its amount
+// @ID set-limit
+// @Accept  json
+// @Produce  json
+// @Param input body models.LimitInput true "limit input"
+// @Success 200 {object} models.Limit
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /limits [post]
+func (h *Handler) setLimit(c echo.Context) error {
+	var input models.LimitInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	limit, err := h.s.SetLimit(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, limit)
+}
+
+// @Summary Get spending limits
+// @Tags limits
+// @Description Returns spending limits of user with amounts used and remaining in their windows ending now
+// @ID get-limits
+// @Produce  json
+// @Param        user_id   query      int  true  "User ID"
+// @Success 200 {object} []models.LimitUsage
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /limits [get]
+func (h *Handler) getLimits(c echo.Context) error {
+	userId, err := strconv.Atoi(c.QueryParam("user_id"))
+	if err != nil || userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	limits, err := h.s.GetLimits(userId)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, limits)
+}
+
+// @Summary Delete spending limit
+// @Tags limits
+// @Description Removes spending limit, operations already made are kept in the usage of other limits
+// @ID delete-limit
+// @Param        id   path      int  true  "Limit ID"
+// @Success 204
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /limits/{id} [delete]
+func (h *Handler) deleteLimit(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect limit id"))
+	}
+
+	if err := h.s.DeleteLimit(id); err != nil {
+		if errors.Is(err, models.ErrLimitNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_SetLimit(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockLimit, input models.LimitInput)
+
+	date := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		input                models.LimitInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			input: models.LimitInput{
+				UserId: 1, Operation: models.LimitTransfer, Period: models.LimitMonthly, CounterpartyId: 3, Amount: 100,
+				Currency: "EUR",
+			},
+			inputBody: `{"user_id":1,"operation":"transfer","period":"month","counterparty_id":3,"amount":100}`,
+			mockBehavior: func(s *mock_service.MockLimit, input models.LimitInput) {
+				s.EXPECT().SetLimit(input).Return(models.Limit{
+					ID: 1, UserId: 1, Operation: models.LimitTransfer, Period: models.LimitMonthly, CounterpartyId: 3,
+					Amount: 100, Currency: "EUR", CreatedAt: date,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"operation":"transfer","period":"month","counterparty_id":3,` +
+				`"amount":100,"currency":"EUR","created_at":"2023-08-10T12:00:00Z"}`,
+		},
+		{
+			name:                 "Counterparty of debit limit",
+			inputBody:            `{"user_id":1,"operation":"debit","period":"day","counterparty_id":3,"amount":100}`,
+			mockBehavior:         func(s *mock_service.MockLimit, input models.LimitInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"counterparty is allowed only for transfer limit"}`,
+		},
+		{
+			name:                 "Unsupported period",
+			inputBody:            `{"user_id":1,"operation":"any","period":"year","amount":100}`,
+			mockBehavior:         func(s *mock_service.MockLimit, input models.LimitInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"unsupported period \"year\""}`,
+		},
+		{
+			name: "User does not exist",
+			input: models.LimitInput{
+				UserId: 300, Operation: models.LimitDebit, Period: models.LimitDaily, Amount: 50, Currency: "EUR",
+			},
+			inputBody: `{"user_id":300,"operation":"debit","period":"day","amount":50}`,
+			mockBehavior: func(s *mock_service.MockLimit, input models.LimitInput) {
+				s.EXPECT().SetLimit(input).Return(models.Limit{}, errors.New("user not found"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"user not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			limit := mock_service.NewMockLimit(c)
+			testCase.mockBehavior(limit, testCase.input)
+
+			services := &service.Service{Limit: limit}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/limits", handler.setLimit)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/limits",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_GetLimits(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockLimit)
+
+	date := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "OK",
+			query: "?user_id=1",
+			mockBehavior: func(s *mock_service.MockLimit) {
+				s.EXPECT().GetLimits(1).Return([]models.LimitUsage{{
+					Limit: models.Limit{ID: 1, UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily,
+						Amount: 50, Currency: "EUR", CreatedAt: date},
+					Used:      30,
+					Remaining: 20,
+				}}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `[{"id":1,"user_id":1,"operation":"debit","period":"day","amount":50,` +
+				`"currency":"EUR","created_at":"2023-08-10T12:00:00Z","used":30,"remaining":20}]`,
+		},
+		{
+			name:                 "Incorrect user id",
+			query:                "?user_id=abc",
+			mockBehavior:         func(s *mock_service.MockLimit) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect user id"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			limit := mock_service.NewMockLimit(c)
+			testCase.mockBehavior(limit)
+
+			services := &service.Service{Limit: limit}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/limits", handler.getLimits)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/limits"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_DeleteLimit(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockLimit)
+
+	testTable := []struct {
+		name                 string
+		id                   string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			id:   "1",
+			mockBehavior: func(s *mock_service.MockLimit) {
+				s.EXPECT().DeleteLimit(1).Return(nil)
+			},
+			expectedStatusCode: 204,
+		},
+		{
+			name: "Not found",
+			id:   "2",
+			mockBehavior: func(s *mock_service.MockLimit) {
+				s.EXPECT().DeleteLimit(2).Return(fmt.Errorf("delete: %w", models.ErrLimitNotFound))
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"delete: spending limit not found"}`,
+		},
+		{
+			name:                 "Incorrect id",
+			id:                   "0",
+			mockBehavior:         func(s *mock_service.MockLimit) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect limit id"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			limit := mock_service.NewMockLimit(c)
+			testCase.mockBehavior(limit)
+
+			services := &service.Service{Limit: limit}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.DELETE("/limits/:id", handler.deleteLimit)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/limits/"+testCase.id, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
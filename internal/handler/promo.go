@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Grant promo
+// @Tags promo
+// @Description Credits promotional money of the campaign to the promo part of the wallet. Purchases spend promo
+// @Description before or after real money, promo can not be transferred, held or exchanged.
+// @Description The unspent rest is debited when the grant expires
+// @ID grant-promo
+// @Accept  json
+// @Produce  json
+// @Param input body models.PromoGrantInput true "promo grant input"
+// @Success 200 {object} models.PromoGrant
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /promo/grants [post]
+func (h *Handler) grantPromo(c echo.Context) error {
+	var input models.PromoGrantInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	grant, err := h.s.GrantPromo(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, grant)
+}
+
+// @Summary List promo grants
+// @Tags promo
+// @Description Returns promo grants of the user
+// @ID list-promo-grants
+// @Produce  json
+// @Param        user_id   query      int  true  "User ID"
+// @Param        status   query      string  false  "active or expired, all by default"
+// @Success 200 {object} []models.PromoGrant
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /promo/grants [get]
+func (h *Handler) listPromoGrants(c echo.Context) error {
+	userId, err := strconv.Atoi(c.QueryParam("user_id"))
+	if err != nil || userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	grants, err := h.s.ListPromoGrants(userId, c.QueryParam("status"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, grants)
+}
+
+// @Summary Get promo grant
+// @Tags promo
+// @Description Returns promo grant with the rest not spent yet
+// @ID get-promo-grant
+// @Produce  json
+// @Param        id   path      int  true  "Promo grant ID"
+// @Success 200 {object} models.PromoGrant
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /promo/grants/{id} [get]
+func (h *Handler) getPromoGrant(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect promo grant id"))
+	}
+
+	grant, err := h.s.GetPromoGrant(id)
+	if err != nil {
+		if errors.Is(err, models.ErrPromoGrantNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, grant)
+}
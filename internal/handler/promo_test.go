@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_GrantPromo(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockPromo, input models.PromoGrantInput)
+
+	date := time.Date(2023, 8, 25, 12, 0, 0, 0, time.UTC)
+	expires := date.Add(30 * 24 * time.Hour)
+
+	testTable := []struct {
+		name                 string
+		input                models.PromoGrantInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			input:     models.PromoGrantInput{UserId: 1, Amount: 20, Currency: "EUR", CampaignId: "summer"},
+			inputBody: `{"user_id":1,"amount":20,"campaign_id":"summer"}`,
+			mockBehavior: func(s *mock_service.MockPromo, input models.PromoGrantInput) {
+				s.EXPECT().GrantPromo(input).Return(models.PromoGrant{ID: 1, UserId: 1, Amount: 20, Remaining: 20,
+					Currency: "EUR", CampaignId: "summer", Status: models.PromoActive, ExpiresAt: expires,
+					CreatedAt: date}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"amount":20,"remaining":20,"currency":"EUR",` +
+				`"campaign_id":"summer","status":"active","expires_at":"2023-09-24T12:00:00Z",` +
+				`"created_at":"2023-08-25T12:00:00Z"}`,
+		},
+		{
+			name:                 "No campaign",
+			inputBody:            `{"user_id":1,"amount":20}`,
+			mockBehavior:         func(s *mock_service.MockPromo, input models.PromoGrantInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"campaign id is required"}`,
+		},
+		{
+			name:      "Expiry in the past",
+			input:     models.PromoGrantInput{UserId: 1, Amount: 20, Currency: "EUR", CampaignId: "summer", ExpiresAt: &date},
+			inputBody: `{"user_id":1,"amount":20,"campaign_id":"summer","expires_at":"2023-08-25T12:00:00Z"}`,
+			mockBehavior: func(s *mock_service.MockPromo, input models.PromoGrantInput) {
+				s.EXPECT().GrantPromo(input).Return(models.PromoGrant{}, errors.New("expiry must be in the future"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"expiry must be in the future"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			promo := mock_service.NewMockPromo(c)
+			testCase.mockBehavior(promo, testCase.input)
+
+			services := &service.Service{Promo: promo}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/promo/grants", handler.grantPromo)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/promo/grants", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_GetPromoGrant(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockPromo)
+
+	date := time.Date(2023, 8, 25, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		id                   string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "Expired",
+			id:   "2",
+			mockBehavior: func(s *mock_service.MockPromo) {
+				s.EXPECT().GetPromoGrant(2).Return(models.PromoGrant{ID: 2, UserId: 1, Amount: 20, Currency: "EUR",
+					CampaignId: "summer", Status: models.PromoExpired, ExpiresAt: date, CreatedAt: date,
+					ExpiredAt: &date}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":2,"user_id":1,"amount":20,"remaining":0,"currency":"EUR",` +
+				`"campaign_id":"summer","status":"expired","expires_at":"2023-08-25T12:00:00Z",` +
+				`"created_at":"2023-08-25T12:00:00Z","expired_at":"2023-08-25T12:00:00Z"}`,
+		},
+		{
+			name: "Not found",
+			id:   "3",
+			mockBehavior: func(s *mock_service.MockPromo) {
+				s.EXPECT().GetPromoGrant(3).Return(models.PromoGrant{}, models.ErrPromoGrantNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"promo grant not found"}`,
+		},
+		{
+			name:                 "Incorrect id",
+			id:                   "abc",
+			mockBehavior:         func(s *mock_service.MockPromo) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect promo grant id"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			promo := mock_service.NewMockPromo(c)
+			testCase.mockBehavior(promo)
+
+			services := &service.Service{Promo: promo}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/promo/grants/:id", handler.getPromoGrant)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/promo/grants/"+testCase.id, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Run reconciliation
+// @Tags reconciliation
+// @Description Recomputes balance of every wallet from the transaction log and reports mismatches
+// @ID reconcile
+// @Produce  json
+// @Param X-Operator header string false "Operator, only when authentication is disabled"
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 400,403 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /reconciliation [post]
+func (h *Handler) reconcile(c echo.Context) error {
+	operator, err := h.operator(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusForbidden, err)
+	}
+	if operator == "" {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("operator is required"))
+	}
+
+	report, err := h.s.Reconcile(operator)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// @Summary Get last reconciliation
+// @Tags reconciliation
+// @Description Returns report of the last reconciliation
+// @ID get-last-reconciliation
+// @Produce  json
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /reconciliation [get]
+func (h *Handler) getLastReconciliation(c echo.Context) error {
+	report, err := h.s.GetLastReconciliation()
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusNotFound, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// @Summary Get reconciliation
+// @Tags reconciliation
+// @Description Returns reconciliation report by id
+// @ID get-reconciliation
+// @Produce  json
+// @Param        id   path      int  true  "Report ID"
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /reconciliation/{id} [get]
+func (h *Handler) getReconciliation(c echo.Context) error {
+	id, err := reconciliationId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	report, err := h.s.GetReconciliation(id)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusNotFound, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// @Summary Approve reconciliation corrections
+// @Tags reconciliation
+// @Description Writes correcting entries for every mismatch of the report, so the transaction log matches wallet balances.
+// @Description Corrections must be approved by another operator than the one who ran reconciliation
+// @ID approve-reconciliation
+// @Produce  json
+// @Param X-Operator header string false "Operator, only when authentication is disabled"
+// @Param        id   path      int  true  "Report ID"
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 400,403,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /reconciliation/{id}/approve [post]
+func (h *Handler) approveCorrections(c echo.Context) error {
+	id, err := reconciliationId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	operator, err := h.operator(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusForbidden, err)
+	}
+	if operator == "" {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("operator is required"))
+	}
+
+	report, err := h.s.ApproveCorrections(id, operator)
+	if err != nil {
+		if errors.Is(err, models.ErrReconciliationNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+		if errors.Is(err, models.ErrSameOperator) {
+			return h.log.ErrorResponse(http.StatusForbidden, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// @Summary Metrics
+// @Tags reconciliation
+// @Description Returns metrics in Prometheus text format
+// @ID metrics
+// @Produce  plain
+// @Success 200 {string} string
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /metrics [get]
+func (h *Handler) metrics(c echo.Context) error {
+	report, err := h.s.GetLastReconciliation()
+	if err != nil {
+		if errors.Is(err, models.ErrReconciliationNotFound) {
+			return c.String(http.StatusOK, "")
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.String(http.StatusOK, fmt.Sprintf(`# HELP balance_reconciliation_mismatches Wallets whose balance differs from the transaction log at the last reconciliation.
+# TYPE balance_reconciliation_mismatches gauge
+balance_reconciliation_mismatches %d
+# HELP balance_reconciliation_wallets Wallets checked by the last reconciliation.
+# TYPE balance_reconciliation_wallets gauge
+balance_reconciliation_wallets %d
+# HELP balance_reconciliation_last_run_timestamp_seconds Time when the last reconciliation finished.
+# TYPE balance_reconciliation_last_run_timestamp_seconds gauge
+balance_reconciliation_last_run_timestamp_seconds %d
+`, len(report.Mismatches), report.Wallets, report.FinishedAt.Unix()))
+}
+
+func reconciliationId(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, errors.New("incorrect report id")
+	}
+
+	return id, nil
+}
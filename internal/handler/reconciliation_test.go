@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Reconcile(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockReconciliation, operator string)
+
+	date := time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		operator             string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "OK",
+			operator: "alice",
+			mockBehavior: func(s *mock_service.MockReconciliation, operator string) {
+				s.EXPECT().Reconcile(operator).Return(models.ReconciliationReport{
+					ID:      1,
+					Status:  models.ReconciliationMismatched,
+					Wallets: 3,
+					Mismatches: models.ReconciliationMismatches{
+						{UserId: 1, Currency: "EUR", Balance: 4.13, Expected: 30, Difference: -25.87, Transactions: 1},
+					},
+					CreatedBy:  operator,
+					StartedAt:  date,
+					FinishedAt: date,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":1,"status":"mismatched","wallets":3,"mismatches":[{"user_id":1,"currency":"EUR","balance":4.13,"expected":30,"difference":-25.87,"transactions":1}],"created_by":"alice","started_at":"2023-07-15T12:00:00Z","finished_at":"2023-07-15T12:00:00Z"}`,
+		},
+		{
+			name:                 "Missing operator",
+			mockBehavior:         func(s *mock_service.MockReconciliation, operator string) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"operator is required"}`,
+		},
+		{
+			name:     "Error from service",
+			operator: "alice",
+			mockBehavior: func(s *mock_service.MockReconciliation, operator string) {
+				s.EXPECT().Reconcile(operator).Return(models.ReconciliationReport{}, errors.New("connection reset"))
+			},
+			expectedStatusCode:   500,
+			expectedResponseBody: `{"message":"connection reset"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			reconciliation := mock_service.NewMockReconciliation(c)
+			testCase.mockBehavior(reconciliation, testCase.operator)
+
+			services := &service.Service{Reconciliation: reconciliation}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/reconciliation", handler.reconcile)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/reconciliation", nil)
+			req.Header.Add(operatorHeader, testCase.operator)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_ApproveCorrections(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockReconciliation, id int, operator string)
+
+	date := time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		url                  string
+		id                   int
+		operator             string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "OK",
+			url:      "/reconciliation/1/approve",
+			id:       1,
+			operator: "bob",
+			mockBehavior: func(s *mock_service.MockReconciliation, id int, operator string) {
+				s.EXPECT().ApproveCorrections(id, operator).Return(models.ReconciliationReport{
+					ID:         id,
+					Status:     models.ReconciliationCorrected,
+					Wallets:    3,
+					Mismatches: models.ReconciliationMismatches{},
+					CreatedBy:  "alice",
+					StartedAt:  date,
+					FinishedAt: date,
+					ApprovedBy: operator,
+					ApprovedAt: &date,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"id":1,"status":"corrected","wallets":3,"mismatches":[],"created_by":"alice","started_at":"2023-07-15T12:00:00Z","finished_at":"2023-07-15T12:00:00Z","approved_by":"bob","approved_at":"2023-07-15T12:00:00Z"}`,
+		},
+		{
+			name:     "Report does not exist",
+			url:      "/reconciliation/100/approve",
+			id:       100,
+			operator: "bob",
+			mockBehavior: func(s *mock_service.MockReconciliation, id int, operator string) {
+				s.EXPECT().ApproveCorrections(id, operator).Return(models.ReconciliationReport{}, models.ErrReconciliationNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"reconciliation report not found"}`,
+		},
+		{
+			name:     "Same operator",
+			url:      "/reconciliation/1/approve",
+			id:       1,
+			operator: "alice",
+			mockBehavior: func(s *mock_service.MockReconciliation, id int, operator string) {
+				s.EXPECT().ApproveCorrections(id, operator).
+					Return(models.ReconciliationReport{}, fmt.Errorf("corrections %w", models.ErrSameOperator))
+			},
+			expectedStatusCode:   403,
+			expectedResponseBody: `{"message":"corrections must be reviewed by another operator"}`,
+		},
+		{
+			name:                 "Incorrect id",
+			url:                  "/reconciliation/abc/approve",
+			operator:             "bob",
+			mockBehavior:         func(s *mock_service.MockReconciliation, id int, operator string) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect report id"}`,
+		},
+		{
+			name:                 "Missing operator",
+			url:                  "/reconciliation/1/approve",
+			id:                   1,
+			mockBehavior:         func(s *mock_service.MockReconciliation, id int, operator string) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"operator is required"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			reconciliation := mock_service.NewMockReconciliation(c)
+			testCase.mockBehavior(reconciliation, testCase.id, testCase.operator)
+
+			services := &service.Service{Reconciliation: reconciliation}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/reconciliation/:id/approve", handler.approveCorrections)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", testCase.url, nil)
+			req.Header.Add(operatorHeader, testCase.operator)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_Metrics(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockReconciliation)
+
+	date := time.Date(2023, 7, 15, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			mockBehavior: func(s *mock_service.MockReconciliation) {
+				s.EXPECT().GetLastReconciliation().Return(models.ReconciliationReport{
+					ID:      1,
+					Status:  models.ReconciliationMismatched,
+					Wallets: 3,
+					Mismatches: models.ReconciliationMismatches{
+						{UserId: 1, Currency: "EUR", Balance: 4.13, Expected: 30, Difference: -25.87, Transactions: 1},
+					},
+					FinishedAt: date,
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: "balance_reconciliation_mismatches 1",
+		},
+		{
+			name: "No reports",
+			mockBehavior: func(s *mock_service.MockReconciliation) {
+				s.EXPECT().GetLastReconciliation().Return(models.ReconciliationReport{}, models.ErrReconciliationNotFound)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: "",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			reconciliation := mock_service.NewMockReconciliation(c)
+			testCase.mockBehavior(reconciliation)
+
+			services := &service.Service{Reconciliation: reconciliation}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/metrics", handler.metrics)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/metrics", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Contains(t, w.Body.String(), testCase.expectedResponseBody)
+		})
+	}
+}
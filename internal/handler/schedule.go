@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Create schedule
+// @Tags schedules
+// @Description Creates one-off or recurring transfer or debit. Without cron and interval it runs once at run_at,
+// @Description with interval it runs at run_at and then every interval, with cron it runs at every matching time in UTC.
+// @Description Failed occurrence, e.g. on insufficient funds, is retried and skipped after the configured retries
+// @ID create-schedule
+// @Accept  json
+// @Produce  json
+// @Param input body models.ScheduleInput true "schedule input"
+// @Success 200 {object} models.Schedule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /schedules [post]
+func (h *Handler) createSchedule(c echo.Context) error {
+	var input models.ScheduleInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	schedule, err := h.s.CreateSchedule(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// @Summary List schedules
+// @Tags schedules
+// @Description Returns schedules of user
+// @ID list-schedules
+// @Produce  json
+// @Param        user_id   query      int  true  "User ID"
+// @Param        status   query      string  false  "active, paused, cancelled, completed or failed, all by default"
+// @Success 200 {object} []models.Schedule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /schedules [get]
+func (h *Handler) listSchedules(c echo.Context) error {
+	userId, err := strconv.Atoi(c.QueryParam("user_id"))
+	if err != nil || userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	schedules, err := h.s.ListSchedules(userId, c.QueryParam("status"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, schedules)
+}
+
+// @Summary Get schedule
+// @Tags schedules
+// @Description Returns schedule with its runs
+// @ID get-schedule
+// @Produce  json
+// @Param        id   path      int  true  "Schedule ID"
+// @Success 200 {object} models.Schedule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /schedules/{id} [get]
+func (h *Handler) getSchedule(c echo.Context) error {
+	id, err := scheduleId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	schedule, err := h.s.GetSchedule(id)
+	if err != nil {
+		if errors.Is(err, models.ErrScheduleNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// @Summary Pause schedule
+// @Tags schedules
+// @Description Stops runs of active schedule until it is resumed
+// @ID pause-schedule
+// @Produce  json
+// @Param        id   path      int  true  "Schedule ID"
+// @Success 200 {object} models.Schedule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /schedules/{id}/pause [post]
+func (h *Handler) pauseSchedule(c echo.Context) error {
+	return h.changeSchedule(c, h.s.PauseSchedule)
+}
+
+// @Summary Resume schedule
+// @Tags schedules
+// @Description Activates paused schedule, recurring occurrences missed while it was paused are skipped
+// @ID resume-schedule
+// @Produce  json
+// @Param        id   path      int  true  "Schedule ID"
+// @Success 200 {object} models.Schedule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /schedules/{id}/resume [post]
+func (h *Handler) resumeSchedule(c echo.Context) error {
+	return h.changeSchedule(c, h.s.ResumeSchedule)
+}
+
+// @Summary Cancel schedule
+// @Tags schedules
+// @Description Stops active or paused schedule for good
+// @ID cancel-schedule
+// @Produce  json
+// @Param        id   path      int  true  "Schedule ID"
+// @Success 200 {object} models.Schedule
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /schedules/{id}/cancel [post]
+func (h *Handler) cancelSchedule(c echo.Context) error {
+	return h.changeSchedule(c, h.s.CancelSchedule)
+}
+
+func (h *Handler) changeSchedule(c echo.Context, change func(id int) (models.Schedule, error)) error {
+	id, err := scheduleId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	schedule, err := change(id)
+	if err != nil {
+		if errors.Is(err, models.ErrScheduleNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+func scheduleId(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, errors.New("incorrect schedule id")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_CreateSchedule(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockSchedule, input models.ScheduleInput)
+
+	date := time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		input                models.ScheduleInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "OK",
+			input: models.ScheduleInput{
+				Type: models.ScheduleTransfer, UserId: 1, ToId: 2, Amount: 10, Currency: "EUR", Cron: "0 9 1 * *",
+			},
+			inputBody: `{"type":"transfer","user_id":1,"to_id":2,"amount":10,"cron":"0 9 1 * *"}`,
+			mockBehavior: func(s *mock_service.MockSchedule, input models.ScheduleInput) {
+				s.EXPECT().CreateSchedule(input).Return(models.Schedule{
+					ID: 1, Type: models.ScheduleTransfer, UserId: 1, ToId: 2, Amount: 10, Currency: "EUR",
+					Cron: "0 9 1 * *", Status: models.ScheduleActive, Occurrence: date, NextRunAt: date, CreatedAt: date,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"type":"transfer","user_id":1,"to_id":2,"amount":10,"currency":"EUR",` +
+				`"cron":"0 9 1 * *","status":"active","occurrence":"2023-08-01T09:00:00Z",` +
+				`"next_run_at":"2023-08-01T09:00:00Z","attempts":0,"created_at":"2023-08-01T09:00:00Z"}`,
+		},
+		{
+			name:                 "Cron and interval",
+			inputBody:            `{"type":"debit","user_id":1,"amount":10,"cron":"0 9 1 * *","interval":"24h"}`,
+			mockBehavior:         func(s *mock_service.MockSchedule, input models.ScheduleInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"cron and interval are mutually exclusive"}`,
+		},
+		{
+			name:                 "Short interval",
+			inputBody:            `{"type":"debit","user_id":1,"amount":10,"interval":"1s"}`,
+			mockBehavior:         func(s *mock_service.MockSchedule, input models.ScheduleInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"interval must be at least 1m0s"}`,
+		},
+		{
+			name: "Incorrect cron",
+			input: models.ScheduleInput{
+				Type: models.ScheduleDebit, UserId: 1, Amount: 10, Currency: "EUR", Cron: "0 9 32 * *",
+			},
+			inputBody: `{"type":"debit","user_id":1,"amount":10,"cron":"0 9 32 * *"}`,
+			mockBehavior: func(s *mock_service.MockSchedule, input models.ScheduleInput) {
+				s.EXPECT().CreateSchedule(input).Return(models.Schedule{},
+					errors.New("day of month: value 32 out of range 1-31"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"day of month: value 32 out of range 1-31"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			schedule := mock_service.NewMockSchedule(c)
+			testCase.mockBehavior(schedule, testCase.input)
+
+			services := &service.Service{Schedule: schedule}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/schedules", handler.createSchedule)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/schedules",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_ChangeSchedule(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockSchedule)
+
+	testTable := []struct {
+		name                 string
+		path                 string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "Pause",
+			path: "/schedules/1/pause",
+			mockBehavior: func(s *mock_service.MockSchedule) {
+				s.EXPECT().PauseSchedule(1).Return(models.Schedule{ID: 1, Status: models.SchedulePaused}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"type":"","user_id":0,"amount":0,"currency":"","status":"paused",` +
+				`"occurrence":"0001-01-01T00:00:00Z","next_run_at":"0001-01-01T00:00:00Z","attempts":0,` +
+				`"created_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name: "Resume cancelled",
+			path: "/schedules/1/resume",
+			mockBehavior: func(s *mock_service.MockSchedule) {
+				s.EXPECT().ResumeSchedule(1).Return(models.Schedule{}, fmt.Errorf("schedule is %s", models.ScheduleCancelled))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"schedule is cancelled"}`,
+		},
+		{
+			name: "Cancel not found",
+			path: "/schedules/3/cancel",
+			mockBehavior: func(s *mock_service.MockSchedule) {
+				s.EXPECT().CancelSchedule(3).Return(models.Schedule{}, models.ErrScheduleNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"schedule not found"}`,
+		},
+		{
+			name:                 "Incorrect id",
+			path:                 "/schedules/first/pause",
+			mockBehavior:         func(s *mock_service.MockSchedule) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect schedule id"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			schedule := mock_service.NewMockSchedule(c)
+			testCase.mockBehavior(schedule)
+
+			services := &service.Service{Schedule: schedule}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/schedules/:id/pause", handler.pauseSchedule)
+			r.POST("/schedules/:id/resume", handler.resumeSchedule)
+			r.POST("/schedules/:id/cancel", handler.cancelSchedule)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", testCase.path, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
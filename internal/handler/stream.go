@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// lastEventIdHeader is sent by a client resuming the stream
+	lastEventIdHeader = "Last-Event-ID"
+	// defaultStreamHeartbeat is used when no heartbeat interval is configured
+	defaultStreamHeartbeat = 15 * time.Second
+)
+
+// @Summary Stream balance changes
+// @Tags balance
+// @Description Server-sent events with the new balance of the wallet and the transaction, sent whenever user`s balance changes.
+// @Description Event id is the transaction id, the stream is resumed after Last-Event-ID. Idle stream gets heartbeat comments.
+// @Description The server closes the stream if it lags behind, the client reconnects with Last-Event-ID
+// @ID stream-balance
+// @Produce  text/event-stream
+// @Param        id   path      int  true  "User ID"
+// @Param        Last-Event-ID   header      int  false  "Resume after this event"
+// @Success 200 {object} models.BalanceEvent
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 429 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /balance/{id}/stream [get]
+func (h *Handler) streamBalance(heartbeat time.Duration) echo.HandlerFunc {
+	if heartbeat <= 0 {
+		heartbeat = defaultStreamHeartbeat
+	}
+
+	return func(c echo.Context) error {
+		userId, err := strconv.Atoi(c.Param("user_id"))
+		if err != nil {
+			return h.log.ErrorResponse(http.StatusBadRequest, err)
+		}
+
+		if userId <= 0 {
+			return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+		}
+
+		lastEventId := 0
+		if id := c.Request().Header.Get(lastEventIdHeader); id != "" {
+			lastEventId, err = strconv.Atoi(id)
+			if err != nil || lastEventId < 0 {
+				return h.log.ErrorResponse(http.StatusBadRequest, fmt.Errorf("incorrect %s %q", lastEventIdHeader, id))
+			}
+		}
+
+		events, err := h.s.SubscribeBalance(userId, lastEventId, c.Request().Context().Done())
+		if err != nil {
+			if errors.Is(err, models.ErrTooManyStreams) {
+				return h.log.ErrorResponse(http.StatusTooManyRequests, err)
+			}
+
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		// the stream lives longer than write timeout of the server, it is not supported by every writer
+		http.NewResponseController(c.Response().Writer).SetWriteDeadline(time.Time{})
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set(echo.HeaderCacheControl, "no-cache")
+		res.Header().Set(echo.HeaderConnection, "keep-alive")
+		res.WriteHeader(http.StatusOK)
+		res.Flush()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+					return nil
+				}
+			case event, ok := <-events:
+				if !ok {
+					return nil
+				}
+
+				data, err := json.Marshal(event)
+				if err != nil {
+					h.log.Infof("failed to encode balance event %d: %s", event.ID, err.Error())
+					return nil
+				}
+
+				if _, err := fmt.Fprintf(res, "id: %d\nevent: balance\ndata: %s\n\n", event.ID, data); err != nil {
+					return nil
+				}
+			}
+
+			res.Flush()
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_StreamBalance(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockStream)
+
+	date := time.Date(2023, 7, 25, 12, 0, 0, 0, time.UTC)
+	event := models.NewBalanceEvent(models.Transaction{
+		ID: 7, UserId: 1, Amount: 10, Currency: "EUR", BalanceAfter: 25, Operation: "Top-up", Date: date,
+	})
+
+	// events returns closed channel with the given events
+	events := func(events ...models.BalanceEvent) <-chan models.BalanceEvent {
+		ch := make(chan models.BalanceEvent, len(events))
+		for _, event := range events {
+			ch <- event
+		}
+		close(ch)
+		return ch
+	}
+
+	testTable := []struct {
+		name                 string
+		userID               string
+		lastEventID          string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "OK",
+			userID: "1",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().SubscribeBalance(1, 0, gomock.Any()).Return(events(event), nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: "id: 7\nevent: balance\n" +
+				`data: {"id":7,"user_id":1,"balance":{"currency":"EUR","balance":25},"transaction":{"id":7,"user_id":1,` +
+				`"amount":10,"currency":"EUR","balance_after":25,"operation":"Top-up","date":"2023-07-25 12:00:00"}}` + "\n\n",
+		},
+		{
+			name:        "Resume",
+			userID:      "1",
+			lastEventID: "6",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().SubscribeBalance(1, 6, gomock.Any()).Return(events(), nil)
+			},
+			expectedStatusCode: 200,
+		},
+		{
+			name:                 "Incorrect last event id",
+			userID:               "1",
+			lastEventID:          "last",
+			mockBehavior:         func(s *mock_service.MockStream) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect Last-Event-ID \"last\""}` + "\n",
+		},
+		{
+			name:                 "Incorrect user id",
+			userID:               "0",
+			mockBehavior:         func(s *mock_service.MockStream) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect user id"}` + "\n",
+		},
+		{
+			name:   "User not found",
+			userID: "3",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().SubscribeBalance(3, 0, gomock.Any()).Return(nil, errors.New("user not found"))
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"user not found"}` + "\n",
+		},
+		{
+			name:   "Too many streams",
+			userID: "1",
+			mockBehavior: func(s *mock_service.MockStream) {
+				s.EXPECT().SubscribeBalance(1, 0, gomock.Any()).
+					Return(nil, fmt.Errorf("%w of user %d, limit is %d", models.ErrTooManyStreams, 1, 5))
+			},
+			expectedStatusCode:   429,
+			expectedResponseBody: `{"message":"too many balance streams of user 1, limit is 5"}` + "\n",
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			stream := mock_service.NewMockStream(c)
+			testCase.mockBehavior(stream)
+
+			services := &service.Service{Stream: stream}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/balance/:user_id/stream", handler.streamBalance(time.Minute))
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/balance/"+testCase.userID+"/stream", nil)
+			if testCase.lastEventID != "" {
+				req.Header.Set(lastEventIdHeader, testCase.lastEventID)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, w.Body.String())
+			if w.Code == 200 {
+				assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestHandler_StreamBalance_Heartbeat(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	events := make(chan models.BalanceEvent)
+	stream := mock_service.NewMockStream(c)
+	stream.EXPECT().SubscribeBalance(1, 0, gomock.Any()).Return(events, nil)
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	handler := NewHandler(&service.Service{Stream: stream}, logger)
+
+	r := echo.New()
+	r.GET("/balance/:user_id/stream", handler.streamBalance(10*time.Millisecond))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(events)
+	}()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/balance/1/stream", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), ": heartbeat\n\n"))
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// maxEventsLimit limits subscription events returned by one request
+const maxEventsLimit = 1000
+
+// @Summary Create plan
+// @Tags subscriptions
+// @Description Creates subscription plan, price is charged every period after the trial days
+// @ID create-plan
+// @Accept  json
+// @Produce  json
+// @Param input body models.PlanInput true "plan input"
+// @Success 200 {object} models.Plan
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /plans [post]
+func (h *Handler) createPlan(c echo.Context) error {
+	var input models.PlanInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	plan, err := h.s.CreatePlan(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+// @Summary List plans
+// @Tags subscriptions
+// @Description Returns all subscription plans
+// @ID list-plans
+// @Produce  json
+// @Success 200 {object} []models.Plan
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /plans [get]
+func (h *Handler) listPlans(c echo.Context) error {
+	plans, err := h.s.ListPlans()
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, plans)
+}
+
+// @Summary Get plan
+// @Tags subscriptions
+// @Description Returns subscription plan
+// @ID get-plan
+// @Produce  json
+// @Param        id   path      int  true  "Plan ID"
+// @Success 200 {object} models.Plan
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /plans/{id} [get]
+func (h *Handler) getPlan(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect plan id"))
+	}
+
+	plan, err := h.s.GetPlan(id)
+	if err != nil {
+		if errors.Is(err, models.ErrPlanNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+// @Summary Subscribe
+// @Tags subscriptions
+// @Description Subscribes user to plan. Plan with trial starts with free trial, otherwise the first period is charged
+// @Description right away. Every period is renewed by a debit of the plan price, unpaid renewal makes subscription
+// @Description past due and is retried until the grace period is over, then subscription is cancelled
+// @ID subscribe
+// @Accept  json
+// @Produce  json
+// @Param input body models.SubscriptionInput true "subscription input"
+// @Success 200 {object} models.Subscription
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /subscriptions [post]
+func (h *Handler) subscribe(c echo.Context) error {
+	var input models.SubscriptionInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	subscription, err := h.s.Subscribe(input)
+	if err != nil {
+		if errors.Is(err, models.ErrPlanNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+// @Summary List subscriptions
+// @Tags subscriptions
+// @Description Returns subscriptions of user
+// @ID list-subscriptions
+// @Produce  json
+// @Param        user_id   query      int  true  "User ID"
+// @Param        status   query      string  false  "trialing, active, past_due or cancelled, all by default"
+// @Success 200 {object} []models.Subscription
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /subscriptions [get]
+func (h *Handler) listSubscriptions(c echo.Context) error {
+	userId, err := strconv.Atoi(c.QueryParam("user_id"))
+	if err != nil || userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	subscriptions, err := h.s.ListSubscriptions(userId, c.QueryParam("status"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, subscriptions)
+}
+
+// @Summary Get subscription
+// @Tags subscriptions
+// @Description Returns subscription with its events
+// @ID get-subscription
+// @Produce  json
+// @Param        id   path      int  true  "Subscription ID"
+// @Success 200 {object} models.Subscription
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /subscriptions/{id} [get]
+func (h *Handler) getSubscription(c echo.Context) error {
+	id, err := subscriptionId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	subscription, err := h.s.GetSubscription(id)
+	if err != nil {
+		if errors.Is(err, models.ErrSubscriptionNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+// @Summary Subscription events
+// @Tags subscriptions
+// @Description Returns created, renewed, renewal_failed, plan_changed and cancelled events of all subscriptions
+// @Description in order, consumers poll with the id of the last handled event
+// @ID subscription-events
+// @Produce  json
+// @Param        after_id   query      int  false  "return events after this id, 0 by default"
+// @Param        limit   query      int  false  "100 by default, at most 1000"
+// @Success 200 {object} []models.SubscriptionEvent
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /subscriptions/events [get]
+func (h *Handler) getSubscriptionEvents(c echo.Context) error {
+	afterId := 0
+	if value := c.QueryParam("after_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 0 {
+			return h.log.ErrorResponse(http.StatusBadRequest, fmt.Errorf("incorrect after_id %q", value))
+		}
+		afterId = id
+	}
+
+	limit := 100
+	if value := c.QueryParam("limit"); value != "" {
+		l, err := strconv.Atoi(value)
+		if err != nil || l <= 0 || l > maxEventsLimit {
+			return h.log.ErrorResponse(http.StatusBadRequest,
+				fmt.Errorf("limit must be between 1 and %d", maxEventsLimit))
+		}
+		limit = l
+	}
+
+	events, err := h.s.GetSubscriptionEvents(afterId, limit)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
+
+// @Summary Change plan
+// @Tags subscriptions
+// @Description Moves trialing or active subscription to another plan of the same currency. Active subscription
+// @Description is charged the prorated difference of prices for the rest of the period or refunded if the plan is cheaper
+// @ID change-plan
+// @Accept  json
+// @Produce  json
+// @Param        id   path      int  true  "Subscription ID"
+// @Param input body models.PlanChangeInput true "new plan"
+// @Success 200 {object} models.Subscription
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /subscriptions/{id}/plan [post]
+func (h *Handler) changePlan(c echo.Context) error {
+	id, err := subscriptionId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	var input models.PlanChangeInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	subscription, err := h.s.ChangePlan(id, input)
+	if err != nil {
+		if errors.Is(err, models.ErrSubscriptionNotFound) || errors.Is(err, models.ErrPlanNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+// @Summary Cancel subscription
+// @Tags subscriptions
+// @Description Cancels subscription right away or, with at_period_end, at the end of the paid period
+// @ID cancel-subscription
+// @Accept  json
+// @Produce  json
+// @Param        id   path      int  true  "Subscription ID"
+// @Param input body models.CancelSubscriptionInput false "cancel input"
+// @Success 200 {object} models.Subscription
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /subscriptions/{id}/cancel [post]
+func (h *Handler) cancelSubscription(c echo.Context) error {
+	id, err := subscriptionId(c)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	var input models.CancelSubscriptionInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	subscription, err := h.s.CancelSubscription(id, input)
+	if err != nil {
+		if errors.Is(err, models.ErrSubscriptionNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+func subscriptionId(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		return 0, errors.New("incorrect subscription id")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Subscribe(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockSubscription)
+
+	date := time.Date(2023, 8, 5, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			inputBody: `{"user_id":1,"plan_id":2}`,
+			mockBehavior: func(s *mock_service.MockSubscription) {
+				s.EXPECT().Subscribe(models.SubscriptionInput{UserId: 1, PlanId: 2}).Return(models.Subscription{
+					ID: 1, UserId: 1, PlanId: 2, Status: models.SubscriptionActive, PeriodStart: date,
+					PeriodEnd: date.AddDate(0, 1, 0), NextAttemptAt: date.AddDate(0, 1, 0), CreatedAt: date,
+				}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"user_id":1,"plan_id":2,"status":"active","period_start":"2023-08-05T12:00:00Z",` +
+				`"period_end":"2023-09-05T12:00:00Z","next_attempt_at":"2023-09-05T12:00:00Z","cancel_at_period_end":false,` +
+				`"attempts":0,"created_at":"2023-08-05T12:00:00Z"}`,
+		},
+		{
+			name:      "Plan not found",
+			inputBody: `{"user_id":1,"plan_id":9}`,
+			mockBehavior: func(s *mock_service.MockSubscription) {
+				s.EXPECT().Subscribe(models.SubscriptionInput{UserId: 1, PlanId: 9}).
+					Return(models.Subscription{}, models.ErrPlanNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"plan not found"}`,
+		},
+		{
+			name:      "Not enough money",
+			inputBody: `{"user_id":1,"plan_id":2}`,
+			mockBehavior: func(s *mock_service.MockSubscription) {
+				s.EXPECT().Subscribe(models.SubscriptionInput{UserId: 1, PlanId: 2}).
+					Return(models.Subscription{}, errors.New("not enough money to perform purchase"))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"not enough money to perform purchase"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			subscription := mock_service.NewMockSubscription(c)
+			testCase.mockBehavior(subscription)
+
+			services := &service.Service{Subscription: subscription}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/subscriptions", handler.subscribe)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/subscriptions",
+				bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_CreatePlan(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	subscription := mock_service.NewMockSubscription(c)
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	handler := NewHandler(&service.Service{Subscription: subscription}, logger)
+
+	r := echo.New()
+	r.POST("/plans", handler.createPlan)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/plans",
+		bytes.NewBufferString(`{"name":"Basic","price":10,"period":"fortnight"}`))
+	req.Header.Add("Content-Type", "application/json")
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, `{"message":"unsupported period \"fortnight\""}`, strings.ReplaceAll(w.Body.String(), "\n", ""))
+}
+
+func TestHandler_GetSubscriptionEvents(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockSubscription)
+
+	date := time.Date(2023, 9, 5, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		query                string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:  "OK",
+			query: "?after_id=4&limit=1",
+			mockBehavior: func(s *mock_service.MockSubscription) {
+				s.EXPECT().GetSubscriptionEvents(4, 1).Return([]models.SubscriptionEvent{{
+					ID: 5, SubscriptionId: 1, UserId: 1, PlanId: 2, Type: models.SubscriptionRenewalFailed,
+					Currency: "EUR", Error: "not enough money to perform purchase", Date: date,
+				}}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `[{"id":5,"subscription_id":1,"user_id":1,"plan_id":2,"type":"renewal_failed",` +
+				`"amount":0,"currency":"EUR","error":"not enough money to perform purchase","date":"2023-09-05T12:00:00Z"}]`,
+		},
+		{
+			name: "Defaults",
+			mockBehavior: func(s *mock_service.MockSubscription) {
+				s.EXPECT().GetSubscriptionEvents(0, 100).Return([]models.SubscriptionEvent{}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[]`,
+		},
+		{
+			name:                 "Incorrect limit",
+			query:                "?limit=5000",
+			mockBehavior:         func(s *mock_service.MockSubscription) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"limit must be between 1 and 1000"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			subscription := mock_service.NewMockSubscription(c)
+			testCase.mockBehavior(subscription)
+
+			services := &service.Service{Subscription: subscription}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/subscriptions/events", handler.getSubscriptionEvents)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/subscriptions/events"+testCase.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -2,27 +2,49 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
 	"github.com/gavrylenkoIvan/balance-service/models"
 	"github.com/labstack/echo/v4"
 )
 
+// readYourWritesHeader set to true makes reads go to primary database,
+// so the client sees its own writes which have not reached read replica yet
+const readYourWritesHeader = "X-Read-Your-Writes"
+
+// users returns service reading from read replica and cache unless request asks for a strong read,
+// by reading its own writes or by Cache-Control: no-cache
+func (h *Handler) users(c echo.Context) service.User {
+	header := c.Request().Header
+	strong := header.Get(readYourWritesHeader) == "true" || strings.Contains(header.Get(echo.HeaderCacheControl), "no-cache")
+	if h.s.Primary != nil && strong {
+		return h.s.Primary
+	}
+
+	return h.s.User
+}
+
 type transactionResponse struct {
-	UserId  int     `json:"user_id"`
-	Balance float32 `json:"balance"`
+	UserId   int     `json:"user_id"`
+	Balance  float32 `json:"balance"`
+	Currency string  `json:"currency"`
 }
 
 // @Summary Transfer money
 // @Tags balance
-// @Description Transfer money from one user to another
+// @Description Transfer money from one user to another, 422 is returned when a spending limit would be exceeded
 // @ID transfer
 // @Accept  json
 // @Produce  json
 // @Param input body models.TransferInput true "transfer info"
 // @Success 200 {object} transactionResponse
 // @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 422 {object} logging.ErrorResponse
 // @Failure 500 {object} logging.ErrorResponse
 // @Failure default {object} logging.ErrorResponse
 // @Router /transfer [post]
@@ -38,26 +60,42 @@ func (h *Handler) transfer(c echo.Context) error {
 		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect to id"))
 	}
 
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	currency, err := models.ParseCurrency(input.Currency)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	input.Currency = currency
 	balance, err := h.s.Transfer(input)
 	if err != nil {
+		if errors.Is(err, models.ErrLimitExceeded) {
+			return h.log.ErrorResponse(http.StatusUnprocessableEntity, err)
+		}
+
 		return h.log.ErrorResponse(http.StatusInternalServerError, err)
 	}
 
 	return c.JSON(http.StatusOK, transactionResponse{
-		UserId:  input.UserId,
-		Balance: balance,
+		UserId:   input.UserId,
+		Balance:  balance,
+		Currency: input.Currency,
 	})
 }
 
 // @Summary Debit from card
 // @Tags balance
-// @Description Decreases user`s balance by input.Amount
+// @Description Decreases user`s balance by input.Amount, 422 is returned when a spending limit would be exceeded
 // @ID debit
 // @Accept  json
 // @Produce  json
 // @Param input body models.Input true "debit input"
 // @Success 200 {object} transactionResponse
 // @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 422 {object} logging.ErrorResponse
 // @Failure 500 {object} logging.ErrorResponse
 // @Failure default {object} logging.ErrorResponse
 // @Router /debit [post]
@@ -71,14 +109,29 @@ func (h *Handler) debit(c echo.Context) error {
 		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
 	}
 
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	currency, err := models.ParseCurrency(input.Currency)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	input.Currency = currency
 	balance, err := h.s.Debit(input)
 	if err != nil {
+		if errors.Is(err, models.ErrLimitExceeded) {
+			return h.log.ErrorResponse(http.StatusUnprocessableEntity, err)
+		}
+
 		return h.log.ErrorResponse(http.StatusInternalServerError, err)
 	}
 
 	return c.JSON(http.StatusOK, transactionResponse{
-		UserId:  input.UserId,
-		Balance: balance,
+		UserId:   input.UserId,
+		Balance:  balance,
+		Currency: input.Currency,
 	})
 }
 
@@ -104,24 +157,40 @@ func (h *Handler) topUp(c echo.Context) error {
 		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
 	}
 
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	currency, err := models.ParseCurrency(input.Currency)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	input.Currency = currency
 	balance, err := h.s.TopUp(input)
 	if err != nil {
 		return h.log.ErrorResponse(http.StatusInternalServerError, err)
 	}
 
 	return c.JSON(http.StatusOK, transactionResponse{
-		UserId:  input.UserId,
-		Balance: balance,
+		UserId:   input.UserId,
+		Balance:  balance,
+		Currency: input.Currency,
 	})
 }
 
 // @Summary Get balance
 // @Tags balance
-// @Description Returns user`s balance
+// @Description Returns all user`s wallets and, if currency is set, their total converted to it.
+// @Description Promo is the promotional part of wallet balance, the rest is real money
 // @ID get-balance
 // @Produce  json
 // @Param        id   path      int  true  "User ID"
-// @Success 200 {object} transactionResponse
+// @Param        currency   query      string  false  "Currency to convert total balance to"
+// @Param        at   query      string  false  "Return balance at this moment (RFC3339, 2006-01-02 15:04:05 or 2006-01-02)"
+// @Param        X-Read-Your-Writes   header      bool  false  "Read from primary database"
+// @Param        Cache-Control   header      string  false  "no-cache reads from primary database bypassing cache"
+// @Success 200 {object} models.Balance
 // @Failure 400,404 {object} logging.ErrorResponse
 // @Failure 500 {object} logging.ErrorResponse
 // @Failure default {object} logging.ErrorResponse
@@ -136,15 +205,82 @@ func (h *Handler) getBalance(c echo.Context) error {
 		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
 	}
 
-	balance, err := h.s.GetBalance(userId, c.QueryParam("currency"))
+	var balance models.Balance
+	if at := c.QueryParam("at"); at != "" {
+		atTime, err := parseTime(at)
+		if err != nil {
+			return h.log.ErrorResponse(http.StatusBadRequest, err)
+		}
+
+		balance, err = h.users(c).GetBalanceAt(userId, atTime, c.QueryParam("currency"))
+		if err != nil {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+	} else {
+		balance, err = h.users(c).GetBalance(userId, c.QueryParam("currency"))
+		if err != nil {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, balance)
+}
+
+// @Summary Get balance history
+// @Tags balance
+// @Description Returns balance of user`s wallet at the end of every interval between from and to
+// @ID get-balance-history
+// @Produce  json
+// @Param        id   path      int  true  "User ID"
+// @Param        wallet   query      string  false  "Wallet currency (EUR by default)"
+// @Param        from   query      string  true  "Start of the range"
+// @Param        to   query      string  false  "End of the range (now by default)"
+// @Param        interval   query      string  false  "hour, day or week (day by default)"
+// @Param        currency   query      string  false  "Currency to convert balance to"
+// @Param        X-Read-Your-Writes   header      bool  false  "Read from primary database"
+// @Success 200 {object} []models.BalancePoint
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /balance/{id}/history [get]
+func (h *Handler) getBalanceHistory(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if userId <= 0 {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("incorrect user id"))
+	}
+
+	if c.QueryParam("from") == "" {
+		return h.log.ErrorResponse(http.StatusBadRequest, errors.New("from is required"))
+	}
+
+	from, err := parseTime(c.QueryParam("from"))
 	if err != nil {
-		return h.log.ErrorResponse(http.StatusNotFound, err)
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
 	}
 
-	return c.JSON(http.StatusOK, transactionResponse{
-		UserId:  userId,
-		Balance: balance,
-	})
+	to := time.Now()
+	if t := c.QueryParam("to"); t != "" {
+		to, err = parseTime(t)
+		if err != nil {
+			return h.log.ErrorResponse(http.StatusBadRequest, err)
+		}
+	}
+
+	interval := "day"
+	if i := c.QueryParam("interval"); i != "" {
+		interval = i
+	}
+
+	history, err := h.users(c).GetBalanceHistory(userId, c.QueryParam("wallet"), from, to, interval, c.QueryParam("currency"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, history)
 }
 
 // @Summary Get transactions
@@ -153,6 +289,7 @@ func (h *Handler) getBalance(c echo.Context) error {
 // @ID get-transactions
 // @Produce  json
 // @Param        id   path      int  true  "User ID"
+// @Param        X-Read-Your-Writes   header      bool  false  "Read from primary database"
 // @Success 200 {object} []models.Transaction
 // @Failure 400,404 {object} logging.ErrorResponse
 // @Failure 500 {object} logging.ErrorResponse
@@ -170,7 +307,7 @@ func (h *Handler) getTransactions(c echo.Context) error {
 
 	page := models.PageFromRequest(c)
 
-	transactions, err := h.s.GetTransactions(userId, page)
+	transactions, err := h.users(c).GetTransactions(userId, page)
 	if err != nil {
 		return h.log.ErrorResponse(http.StatusInternalServerError, err)
 	}
@@ -182,3 +319,14 @@ func (h *Handler) getTransactions(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, result)
 }
+
+// parseTime accepts RFC3339, time.DateTime and time.DateOnly formatted values
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("incorrect time %q", value)
+}
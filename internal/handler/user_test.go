@@ -2,7 +2,6 @@ package handler
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gavrylenkoIvan/balance-service/internal/service"
@@ -13,7 +12,6 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
-	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -21,8 +19,8 @@ import (
 	"time"
 )
 
-func returnFirstValue(values ...interface{}) interface{} {
-	return values[0]
+func totalOf(value float32) *float32 {
+	return &value
 }
 
 func TestHandler_GetBalance(t *testing.T) {
@@ -41,44 +39,68 @@ func TestHandler_GetBalance(t *testing.T) {
 		{
 			name:     "OK",
 			userID:   1,
-			currency: "EUR",
+			currency: "",
 			mockBehavior: func(s *mock_service.MockUser, user int, currency string) {
-				s.EXPECT().GetBalance(user, currency).Return(float32(4.13), nil)
+				s.EXPECT().GetBalance(user, currency).Return(models.Balance{
+					UserId:  user,
+					Wallets: []models.Wallet{{Currency: "EUR", Balance: 4.13}},
+				}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"user_id":1,"balance":4}`,
+			expectedResponseBody: `{"user_id":1,"wallets":[{"currency":"EUR","balance":4.13}]}`,
 		},
 		{
-			name:     "OneMoreOK",
+			name:     "Multiple wallets",
 			userID:   2,
-			currency: "EUR",
+			currency: "",
 			mockBehavior: func(s *mock_service.MockUser, user int, currency string) {
-				s.EXPECT().GetBalance(user, currency).Return(float32(32), nil)
+				s.EXPECT().GetBalance(user, currency).Return(models.Balance{
+					UserId: user,
+					Wallets: []models.Wallet{
+						{Currency: "EUR", Balance: 32},
+						{Currency: "USD", Balance: 10},
+					},
+				}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"user_id":2,"balance":32}`,
+			expectedResponseBody: `{"user_id":2,"wallets":[{"currency":"EUR","balance":32},{"currency":"USD","balance":10}]}`,
 		},
 		{
 			name:     "OKinUAH",
 			userID:   2,
 			currency: "UAH",
 			mockBehavior: func(s *mock_service.MockUser, user int, currency string) {
-				uah, err := utils.Convert(32, "UAH")
-				if err != nil {
-					t.Error(err)
-				}
-
-				s.EXPECT().GetBalance(user, currency).Return(uah, nil)
+				s.EXPECT().GetBalance(user, currency).Return(models.Balance{
+					UserId:   user,
+					Wallets:  []models.Wallet{{Currency: "EUR", Balance: 32}},
+					Currency: "UAH",
+					Total:    totalOf(1280),
+				}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: fmt.Sprintf(`{"user_id":2,"balance":%.0f}`, returnFirstValue(utils.Convert(32, "UAH")).(float32)),
+			expectedResponseBody: `{"user_id":2,"wallets":[{"currency":"EUR","balance":32}],"currency":"UAH","total":1280}`,
+		},
+		{
+			name:     "Zero total",
+			userID:   3,
+			currency: "USD",
+			mockBehavior: func(s *mock_service.MockUser, user int, currency string) {
+				s.EXPECT().GetBalance(user, currency).Return(models.Balance{
+					UserId:   user,
+					Wallets:  []models.Wallet{},
+					Currency: "USD",
+					Total:    totalOf(0),
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user_id":3,"wallets":[],"currency":"USD","total":0}`,
 		},
 		{
 			name:     "NotValid",
 			userID:   0,
 			currency: "EUR",
 			mockBehavior: func(s *mock_service.MockUser, user int, currency string) {
-				s.EXPECT().GetBalance(user, currency).Return(float32(0), errors.New("user not found")).AnyTimes()
+				s.EXPECT().GetBalance(user, currency).Return(models.Balance{}, errors.New("user not found")).AnyTimes()
 			},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"incorrect user id"}`,
@@ -88,7 +110,7 @@ func TestHandler_GetBalance(t *testing.T) {
 			userID:   400,
 			currency: "EUR",
 			mockBehavior: func(s *mock_service.MockUser, user int, currency string) {
-				s.EXPECT().GetBalance(user, currency).Return(float32(0), errors.New("user not found"))
+				s.EXPECT().GetBalance(user, currency).Return(models.Balance{}, errors.New("user not found"))
 			},
 			expectedStatusCode:   404,
 			expectedResponseBody: `{"message":"user not found"}`,
@@ -98,7 +120,7 @@ func TestHandler_GetBalance(t *testing.T) {
 			userID:   1,
 			currency: "EUR",
 			mockBehavior: func(s *mock_service.MockUser, user int, currency string) {
-				s.EXPECT().GetBalance(user, currency).Return(float32(0), errors.New("user not found")).AnyTimes()
+				s.EXPECT().GetBalance(user, currency).Return(models.Balance{}, errors.New("user not found")).AnyTimes()
 			},
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"strconv.Atoi: parsing \"abs\": invalid syntax"}`,
@@ -138,36 +160,76 @@ func TestHandler_GetBalance(t *testing.T) {
 
 			r.ServeHTTP(w, req)
 
-			var resBody string
-			if testCase.expectedStatusCode == 200 {
-				var body struct {
-					UserID  int     `json:"user_id"`
-					Balance float64 `json:"balance"`
-				}
-
-				err = json.Unmarshal(w.Body.Bytes(), &body)
-				if err != nil {
-					t.Error(err)
-				}
-
-				newBody, err := json.Marshal(struct {
-					UserID  int     `json:"user_id"`
-					Balance float64 `json:"balance"`
-				}{
-					UserID:  body.UserID,
-					Balance: math.Round(body.Balance),
-				})
-
-				if err != nil {
-					t.Error(err)
-				}
-				resBody = string(newBody)
-			} else {
-				resBody = strings.ReplaceAll(w.Body.String(), "\n", "")
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_GetBalance_ReadYourWrites(t *testing.T) {
+	testTable := []struct {
+		name          string
+		header        string
+		cacheControl  string
+		expectReplica bool
+	}{
+		{
+			name:          "Replica",
+			expectReplica: true,
+		},
+		{
+			name:   "Primary",
+			header: "true",
+		},
+		{
+			name:         "Cache bypass",
+			cacheControl: "no-cache",
+		},
+		{
+			name:          "Cache allowed",
+			cacheControl:  "max-age=60",
+			expectReplica: true,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			replica := mock_service.NewMockUser(c)
+			primary := mock_service.NewMockUser(c)
+
+			expected := primary
+			if testCase.expectReplica {
+				expected = replica
 			}
+			expected.EXPECT().GetBalance(1, "").Return(models.Balance{UserId: 1, Wallets: []models.Wallet{}}, nil)
 
-			assert.Equal(t, testCase.expectedStatusCode, w.Code)
-			assert.Equal(t, testCase.expectedResponseBody, resBody)
+			services := &service.Service{User: replica, Primary: primary}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/balance/:user_id", handler.getBalance)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/balance/1", nil)
+			if testCase.header != "" {
+				req.Header.Set(readYourWritesHeader, testCase.header)
+			}
+			if testCase.cacheControl != "" {
+				req.Header.Set(echo.HeaderCacheControl, testCase.cacheControl)
+			}
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, `{"user_id":1,"wallets":[]}`, strings.ReplaceAll(w.Body.String(), "\n", ""))
 		})
 	}
 }
@@ -198,12 +260,13 @@ func TestHandler_GetTransactions(t *testing.T) {
 					ID:        1,
 					UserId:    1,
 					Amount:    30,
+					Currency:  "EUR",
 					Operation: "",
 					Date:      utils.ParseTime(time.DateTime, t),
 				}}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: fmt.Sprintf(`[{"id":1,"user_id":1,"amount":30,"operation":"","date":"%s"}]`, time.DateTime),
+			expectedResponseBody: fmt.Sprintf(`[{"id":1,"user_id":1,"amount":30,"currency":"EUR","balance_after":0,"operation":"","date":"%s"}]`, time.DateTime),
 		},
 		{
 			name:   "Multiple values + sort by ID",
@@ -218,18 +281,20 @@ func TestHandler_GetTransactions(t *testing.T) {
 					ID:        2,
 					UserId:    2,
 					Amount:    101,
+					Currency:  "EUR",
 					Operation: "",
 					Date:      utils.ParseTime(time.DateTime, t),
 				}, {
 					ID:        3,
 					UserId:    2,
 					Amount:    32,
+					Currency:  "EUR",
 					Operation: "",
 					Date:      utils.ParseTime(time.DateTime, t),
 				}}, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: fmt.Sprintf(`[{"id":2,"user_id":2,"amount":101,"operation":"","date":"%s"},{"id":3,"user_id":2,"amount":32,"operation":"","date":"%s"}]`, time.DateTime, time.DateTime),
+			expectedResponseBody: fmt.Sprintf(`[{"id":2,"user_id":2,"amount":101,"currency":"EUR","balance_after":0,"operation":"","date":"%s"},{"id":3,"user_id":2,"amount":32,"currency":"EUR","balance_after":0,"operation":"","date":"%s"}]`, time.DateTime, time.DateTime),
 		},
 		{
 			name:   "Multiple values + sort by ID + 2 page",
@@ -244,18 +309,20 @@ func TestHandler_GetTransactions(t *testing.T) {
 					ID:        8,
 					UserId:    3,
 					Amount:    101,
+					Currency:  "EUR",
 					Operation: "",
 					Date:      utils.ParseTime(time.DateTime, t),
 				}, {
 					ID:        9,
 					UserId:    3,
 					Amount:    103,
+					Currency:  "EUR",
 					Operation: "",
 					Date:      utils.ParseTime(time.DateTime, t),
 				}}, nil)
 			},
 			expectedStatusCode: 200,
-			expectedResponseBody: fmt.Sprintf(`[{"id":8,"user_id":3,"amount":101,"operation":"","date":"%s"},{"id":9,"user_id":3,"amount":103,"operation":"","date":"%s"}]`,
+			expectedResponseBody: fmt.Sprintf(`[{"id":8,"user_id":3,"amount":101,"currency":"EUR","balance_after":0,"operation":"","date":"%s"},{"id":9,"user_id":3,"amount":103,"currency":"EUR","balance_after":0,"operation":"","date":"%s"}]`,
 				time.DateTime, time.DateTime),
 		},
 		{
@@ -363,21 +430,23 @@ func TestHandler_TopUp(t *testing.T) {
 		{
 			name: "OK",
 			input: models.Input{
-				UserId: 1,
-				Amount: 30,
+				UserId:   1,
+				Amount:   30,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":1,"amount":30}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
 				s.EXPECT().TopUp(input).Return(4.13+input.Amount, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"user_id":1,"balance":34.13}`,
+			expectedResponseBody: `{"user_id":1,"balance":34.13,"currency":"EUR"}`,
 		},
 		{
 			name: "Incorrect user id",
 			input: models.Input{
-				UserId: 0,
-				Amount: 30,
+				UserId:   0,
+				Amount:   30,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":0,"amount":30}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
@@ -389,8 +458,9 @@ func TestHandler_TopUp(t *testing.T) {
 		{
 			name: "User does not exist",
 			input: models.Input{
-				UserId: 300,
-				Amount: 30,
+				UserId:   300,
+				Amount:   30,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":300,"amount":30}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
@@ -399,11 +469,38 @@ func TestHandler_TopUp(t *testing.T) {
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"user not found"}`,
 		},
+		{
+			name: "Incorrect currency",
+			input: models.Input{
+				UserId:   1,
+				Amount:   30,
+				Currency: "EURO",
+			},
+			inputBody:            `{"user_id":1,"amount":30,"currency":"euro"}`,
+			mockBehavior:         func(s *mock_service.MockUser, input models.Input) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect currency \"EURO\""}`,
+		},
+		{
+			name: "Other currency",
+			input: models.Input{
+				UserId:   1,
+				Amount:   30,
+				Currency: "USD",
+			},
+			inputBody: `{"user_id":1,"amount":30,"currency":"usd"}`,
+			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
+				s.EXPECT().TopUp(input).Return(input.Amount, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user_id":1,"balance":30,"currency":"USD"}`,
+		},
 		{
 			name: "Incorrect input body",
 			input: models.Input{
-				UserId: 1,
-				Amount: 30,
+				UserId:   1,
+				Amount:   30,
+				Currency: "EUR",
 			},
 			inputBody:            `dfsdfdsfsdf`,
 			mockBehavior:         func(s *mock_service.MockUser, input models.Input) {},
@@ -459,21 +556,23 @@ func TestHandler_Debit(t *testing.T) {
 		{
 			name: "OK",
 			input: models.Input{
-				UserId: 1,
-				Amount: 1,
+				UserId:   1,
+				Amount:   1,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":1,"amount":1}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
 				s.EXPECT().Debit(input).Return(4.13-input.Amount, nil)
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"user_id":1,"balance":3.13}`,
+			expectedResponseBody: `{"user_id":1,"balance":3.13,"currency":"EUR"}`,
 		},
 		{
 			name: "Incorrect user id",
 			input: models.Input{
-				UserId: 0,
-				Amount: 30,
+				UserId:   0,
+				Amount:   30,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":0,"amount":30}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
@@ -482,11 +581,26 @@ func TestHandler_Debit(t *testing.T) {
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"incorrect user id"}`,
 		},
+		{
+			name: "Negative amount",
+			input: models.Input{
+				UserId:   1,
+				Amount:   -30,
+				Currency: "EUR",
+			},
+			inputBody: `{"user_id":1,"amount":-30}`,
+			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
+				s.EXPECT().Debit(input).Return(float32(0), nil).Times(0)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"amount must be positive"}`,
+		},
 		{
 			name: "User does not exist",
 			input: models.Input{
-				UserId: 300,
-				Amount: 30,
+				UserId:   300,
+				Amount:   30,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":300,"amount":30}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
@@ -495,11 +609,27 @@ func TestHandler_Debit(t *testing.T) {
 			expectedStatusCode:   500,
 			expectedResponseBody: `{"message":"user not found"}`,
 		},
+		{
+			name: "Limit exceeded",
+			input: models.Input{
+				UserId:   1,
+				Amount:   30,
+				Currency: "EUR",
+			},
+			inputBody: `{"user_id":1,"amount":30}`,
+			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
+				s.EXPECT().Debit(input).Return(float32(0),
+					fmt.Errorf("%w: limit #1 of 50.00 EUR per day on debits, 20.00 is remaining", models.ErrLimitExceeded))
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"message":"spending limit exceeded: limit #1 of 50.00 EUR per day on debits, 20.00 is remaining"}`,
+		},
 		{
 			name: "Incorrect URL",
 			input: models.Input{
-				UserId: 1,
-				Amount: 30,
+				UserId:   1,
+				Amount:   30,
+				Currency: "EUR",
 			},
 			inputBody: `dsalknfdlf14`,
 			mockBehavior: func(s *mock_service.MockUser, input models.Input) {
@@ -557,23 +687,25 @@ func TestHandler_Transfer(t *testing.T) {
 		{
 			name: "OK",
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 4.13,
+				UserId:   1,
+				ToId:     2,
+				Amount:   4.13,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":1,"to_id":2,"amount":4.13}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.TransferInput) {
 				s.EXPECT().Transfer(input).Return(4.13-input.Amount, nil).AnyTimes()
 			},
 			expectedStatusCode:   200,
-			expectedResponseBody: `{"user_id":1,"balance":0}`,
+			expectedResponseBody: `{"user_id":1,"balance":0,"currency":"EUR"}`,
 		},
 		{
 			name: "Incorrect user id",
 			input: models.TransferInput{
-				UserId: 0,
-				ToId:   2,
-				Amount: 4.13,
+				UserId:   0,
+				ToId:     2,
+				Amount:   4.13,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":0,"to_id":2,"amount":4.13}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.TransferInput) {
@@ -585,9 +717,10 @@ func TestHandler_Transfer(t *testing.T) {
 		{
 			name: "Incorrect to id",
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   0,
-				Amount: 4.13,
+				UserId:   1,
+				ToId:     0,
+				Amount:   4.13,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":1,"to_id":0,"amount":4.13}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.TransferInput) {
@@ -596,12 +729,28 @@ func TestHandler_Transfer(t *testing.T) {
 			expectedStatusCode:   400,
 			expectedResponseBody: `{"message":"incorrect to id"}`,
 		},
+		{
+			name: "Negative amount",
+			input: models.TransferInput{
+				UserId:   1,
+				ToId:     2,
+				Amount:   -4.13,
+				Currency: "EUR",
+			},
+			inputBody: `{"user_id":1,"to_id":2,"amount":-4.13}`,
+			mockBehavior: func(s *mock_service.MockUser, input models.TransferInput) {
+				s.EXPECT().Transfer(input).Return(float32(0), nil).Times(0)
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"amount must be positive"}`,
+		},
 		{
 			name: "Incorrect input body",
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   0,
-				Amount: 4.13,
+				UserId:   1,
+				ToId:     0,
+				Amount:   4.13,
+				Currency: "EUR",
 			},
 			inputBody: `da90fd-9sfs2k13l1`,
 			mockBehavior: func(s *mock_service.MockUser, input models.TransferInput) {
@@ -613,9 +762,10 @@ func TestHandler_Transfer(t *testing.T) {
 		{
 			name: "Some error from repo",
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 100,
+				UserId:   1,
+				ToId:     2,
+				Amount:   100,
+				Currency: "EUR",
 			},
 			inputBody: `{"user_id":1,"to_id":2,"amount":100}`,
 			mockBehavior: func(s *mock_service.MockUser, input models.TransferInput) {
@@ -658,3 +808,208 @@ func TestHandler_Transfer(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_GetBalanceAt(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUser, user int, at time.Time)
+
+	testTable := []struct {
+		name                 string
+		userID               int
+		at                   string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "OK",
+			userID: 1,
+			at:     "2023-05-25",
+			mockBehavior: func(s *mock_service.MockUser, user int, at time.Time) {
+				s.EXPECT().GetBalanceAt(user, at, "").Return(models.Balance{
+					UserId:  user,
+					Wallets: []models.Wallet{{Currency: "EUR", Balance: 30}},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user_id":1,"wallets":[{"currency":"EUR","balance":30}]}`,
+		},
+		{
+			name:   "OK with date time",
+			userID: 1,
+			at:     "2023-05-25T19:01:23Z",
+			mockBehavior: func(s *mock_service.MockUser, user int, at time.Time) {
+				s.EXPECT().GetBalanceAt(user, at, "").Return(models.Balance{
+					UserId: user,
+					Wallets: []models.Wallet{
+						{Currency: "EUR", Balance: 4.5},
+						{Currency: "USD", Balance: 1},
+					},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `{"user_id":1,"wallets":[{"currency":"EUR","balance":4.5},{"currency":"USD","balance":1}]}`,
+		},
+		{
+			name:                 "Incorrect time",
+			userID:               1,
+			at:                   "yesterday",
+			mockBehavior:         func(s *mock_service.MockUser, user int, at time.Time) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect time \"yesterday\""}`,
+		},
+		{
+			name:   "User does not exist",
+			userID: 400,
+			at:     "2023-05-25",
+			mockBehavior: func(s *mock_service.MockUser, user int, at time.Time) {
+				s.EXPECT().GetBalanceAt(user, at, "").Return(models.Balance{}, errors.New("user not found"))
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"user not found"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			user := mock_service.NewMockUser(c)
+			at, _ := parseTime(testCase.at)
+			testCase.mockBehavior(user, testCase.userID, at)
+
+			services := &service.Service{User: user}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/balance/:user_id", handler.getBalance)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET",
+				fmt.Sprintf("/balance/%d?at=%s", testCase.userID, testCase.at), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_GetBalanceHistory(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockUser, userID int, wallet string, from, to time.Time, interval, currency string)
+
+	testTable := []struct {
+		name                 string
+		userID               int
+		query                string
+		wallet               string
+		from                 string
+		to                   string
+		interval             string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:     "OK",
+			userID:   1,
+			query:    "from=2023-05-25&to=2023-05-26&interval=day",
+			from:     "2023-05-25",
+			to:       "2023-05-26",
+			interval: "day",
+			mockBehavior: func(s *mock_service.MockUser, userID int, wallet string, from, to time.Time, interval, currency string) {
+				s.EXPECT().GetBalanceHistory(userID, wallet, from, to, interval, currency).Return([]models.BalancePoint{
+					{Date: from, Balance: 30},
+					{Date: to, Balance: 12.5},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"date":"2023-05-25T00:00:00Z","balance":30},{"date":"2023-05-26T00:00:00Z","balance":12.5}]`,
+		},
+		{
+			name:     "Default interval",
+			userID:   2,
+			query:    "from=2023-05-25&to=2023-05-25&wallet=USD",
+			wallet:   "USD",
+			from:     "2023-05-25",
+			to:       "2023-05-25",
+			interval: "day",
+			mockBehavior: func(s *mock_service.MockUser, userID int, wallet string, from, to time.Time, interval, currency string) {
+				s.EXPECT().GetBalanceHistory(userID, wallet, from, to, interval, currency).Return([]models.BalancePoint{
+					{Date: from, Balance: 1},
+				}, nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: `[{"date":"2023-05-25T00:00:00Z","balance":1}]`,
+		},
+		{
+			name:   "Missing from",
+			userID: 1,
+			query:  "to=2023-05-26",
+			mockBehavior: func(s *mock_service.MockUser, userID int, wallet string, from, to time.Time, interval, currency string) {
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"from is required"}`,
+		},
+		{
+			name:   "Incorrect to",
+			userID: 1,
+			query:  "from=2023-05-25&to=abc",
+			mockBehavior: func(s *mock_service.MockUser, userID int, wallet string, from, to time.Time, interval, currency string) {
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"incorrect time \"abc\""}`,
+		},
+		{
+			name:     "Error from service",
+			userID:   1,
+			query:    "from=2023-05-25&to=2023-05-26&interval=year",
+			from:     "2023-05-25",
+			to:       "2023-05-26",
+			interval: "year",
+			mockBehavior: func(s *mock_service.MockUser, userID int, wallet string, from, to time.Time, interval, currency string) {
+				s.EXPECT().GetBalanceHistory(userID, wallet, from, to, interval, currency).Return(nil, errors.New("unsupported interval \"year\""))
+			},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"unsupported interval \"year\""}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			user := mock_service.NewMockUser(c)
+			from, _ := parseTime(testCase.from)
+			to, _ := parseTime(testCase.to)
+			testCase.mockBehavior(user, testCase.userID, testCase.wallet, from, to, testCase.interval, "")
+
+			services := &service.Service{User: user}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.GET("/balance/:user_id/history", handler.getBalanceHistory)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET",
+				fmt.Sprintf("/balance/%d/history?%s", testCase.userID, testCase.query), nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Create voucher batch
+// @Tags vouchers
+// @Description Generates random voucher codes which top up the amount on redemption. Codes are stored only
+// @Description as hashes, so they are returned only in this response. Every code may be redeemed by max_uses users
+// @ID create-voucher-batch
+// @Accept  json
+// @Produce  json
+// @Param input body models.VoucherBatchInput true "voucher batch input"
+// @Success 200 {object} models.VoucherBatch
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /vouchers/batches [post]
+func (h *Handler) createVoucherBatch(c echo.Context) error {
+	var input models.VoucherBatchInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	batch, err := h.s.CreateVoucherBatch(input)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, batch)
+}
+
+// @Summary Redeem voucher
+// @Tags vouchers
+// @Description Tops up the user by the voucher amount and counts the use of the voucher in one transaction.
+// @Description The top-up is linked by voucher-{redemption id}, a user may redeem a code once
+// @ID redeem-voucher
+// @Accept  json
+// @Produce  json
+// @Param input body models.VoucherRedeemInput true "voucher redeem input"
+// @Success 200 {object} models.VoucherRedemption
+// @Failure 400,404,422 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /vouchers/redeem [post]
+func (h *Handler) redeemVoucher(c echo.Context) error {
+	var input models.VoucherRedeemInput
+	if err := c.Bind(&input); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	if err := input.Validate(); err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	redemption, err := h.s.RedeemVoucher(input)
+	if err != nil {
+		if errors.Is(err, models.ErrVoucherNotFound) {
+			return h.log.ErrorResponse(http.StatusNotFound, err)
+		}
+
+		if errors.Is(err, models.ErrVoucherNotRedeemable) {
+			return h.log.ErrorResponse(http.StatusUnprocessableEntity, err)
+		}
+
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return c.JSON(http.StatusOK, redemption)
+}
+
+// @Summary Get voucher report
+// @Tags vouchers
+// @Description Returns voucher batches of the currency with issued and redeemed value. Issued value counts every
+// @Description use of every code, the part not redeemed is expired or outstanding
+// @ID get-voucher-report
+// @Produce  json
+// @Param        currency   query      string  false  "Currency, EUR by default"
+// @Success 200 {object} models.VoucherReport
+// @Failure 400,404 {object} logging.ErrorResponse
+// @Failure 500 {object} logging.ErrorResponse
+// @Failure default {object} logging.ErrorResponse
+// @Router /vouchers/report [get]
+func (h *Handler) getVoucherReport(c echo.Context) error {
+	currency, err := models.ParseCurrency(c.QueryParam("currency"))
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	report, err := h.s.GetVoucherReport(currency)
+	if err != nil {
+		return h.log.ErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
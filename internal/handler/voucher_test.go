@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/service"
+	mock_service "github.com/gavrylenkoIvan/balance-service/internal/service/mocks"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_RedeemVoucher(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockVoucher, input models.VoucherRedeemInput)
+
+	date := time.Date(2023, 8, 30, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		input                models.VoucherRedeemInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			input:     models.VoucherRedeemInput{UserId: 1, Code: "ABCD-EFGH-JKLM-NPQR"},
+			inputBody: `{"user_id":1,"code":"ABCD-EFGH-JKLM-NPQR"}`,
+			mockBehavior: func(s *mock_service.MockVoucher, input models.VoucherRedeemInput) {
+				s.EXPECT().RedeemVoucher(input).Return(models.VoucherRedemption{ID: 3, VoucherId: 7, BatchId: 2,
+					UserId: 1, Amount: 25, Currency: "USD", RedeemedAt: date, Balance: 125}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":3,"voucher_id":7,"batch_id":2,"user_id":1,"amount":25,"currency":"USD",` +
+				`"redeemed_at":"2023-08-30T12:00:00Z","balance":125}`,
+		},
+		{
+			name:                 "No code",
+			inputBody:            `{"user_id":1,"code":" - "}`,
+			mockBehavior:         func(s *mock_service.MockVoucher, input models.VoucherRedeemInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"code is required"}`,
+		},
+		{
+			name:      "Not found",
+			input:     models.VoucherRedeemInput{UserId: 1, Code: "abcd"},
+			inputBody: `{"user_id":1,"code":"abcd"}`,
+			mockBehavior: func(s *mock_service.MockVoucher, input models.VoucherRedeemInput) {
+				s.EXPECT().RedeemVoucher(input).Return(models.VoucherRedemption{}, models.ErrVoucherNotFound)
+			},
+			expectedStatusCode:   404,
+			expectedResponseBody: `{"message":"voucher not found"}`,
+		},
+		{
+			name:      "Used up",
+			input:     models.VoucherRedeemInput{UserId: 1, Code: "ABCD-EFGH-JKLM-NPQR"},
+			inputBody: `{"user_id":1,"code":"ABCD-EFGH-JKLM-NPQR"}`,
+			mockBehavior: func(s *mock_service.MockVoucher, input models.VoucherRedeemInput) {
+				s.EXPECT().RedeemVoucher(input).Return(models.VoucherRedemption{},
+					fmt.Errorf("%w: voucher is used up", models.ErrVoucherNotRedeemable))
+			},
+			expectedStatusCode:   422,
+			expectedResponseBody: `{"message":"voucher can not be redeemed: voucher is used up"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			voucher := mock_service.NewMockVoucher(c)
+			testCase.mockBehavior(voucher, testCase.input)
+
+			services := &service.Service{Voucher: voucher}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/vouchers/redeem", handler.redeemVoucher)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/vouchers/redeem", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
+
+func TestHandler_CreateVoucherBatch(t *testing.T) {
+	type mockBehavior func(s *mock_service.MockVoucher, input models.VoucherBatchInput)
+
+	date := time.Date(2023, 8, 30, 12, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		name                 string
+		input                models.VoucherBatchInput
+		inputBody            string
+		mockBehavior         mockBehavior
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:      "OK",
+			input:     models.VoucherBatchInput{Amount: 25, Currency: "USD", Count: 2, MaxUses: 1},
+			inputBody: `{"amount":25,"currency":"usd","count":2}`,
+			mockBehavior: func(s *mock_service.MockVoucher, input models.VoucherBatchInput) {
+				s.EXPECT().CreateVoucherBatch(input).Return(models.VoucherBatch{ID: 1, Amount: 25, Currency: "USD",
+					Count: 2, MaxUses: 1, CreatedAt: date, Codes: []string{"ABCD-EFGH-JKLM-NPQR",
+						"STUV-WXYZ-2345-6789"}}, nil)
+			},
+			expectedStatusCode: 200,
+			expectedResponseBody: `{"id":1,"amount":25,"currency":"USD","count":2,"max_uses":1,` +
+				`"created_at":"2023-08-30T12:00:00Z","codes":["ABCD-EFGH-JKLM-NPQR","STUV-WXYZ-2345-6789"],` +
+				`"redemptions":0,"redeemed_value":0}`,
+		},
+		{
+			name:                 "Too large",
+			inputBody:            `{"amount":25,"count":5000}`,
+			mockBehavior:         func(s *mock_service.MockVoucher, input models.VoucherBatchInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"batch is too large: at most 1000 codes"}`,
+		},
+		{
+			name:                 "Negative max uses",
+			inputBody:            `{"amount":25,"count":5,"max_uses":-1}`,
+			mockBehavior:         func(s *mock_service.MockVoucher, input models.VoucherBatchInput) {},
+			expectedStatusCode:   400,
+			expectedResponseBody: `{"message":"max uses must not be negative"}`,
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			voucher := mock_service.NewMockVoucher(c)
+			testCase.mockBehavior(voucher, testCase.input)
+
+			services := &service.Service{Voucher: voucher}
+			logger, err := logging.InitLogger()
+			if err != nil {
+				t.Error(err)
+			}
+
+			handler := NewHandler(services, logger)
+
+			r := echo.New()
+			r.POST("/vouchers/batches", handler.createVoucherBatch)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/vouchers/batches", bytes.NewBufferString(testCase.inputBody))
+			req.Header.Add("Content-Type", "application/json")
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedStatusCode, w.Code)
+			assert.Equal(t, testCase.expectedResponseBody, strings.ReplaceAll(w.Body.String(), "\n", ""))
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Account interface {
+	GetAccount(id int) (models.Account, error)
+	SetAccountStatus(input models.AccountStatusInput) (models.Account, error)
+}
+
+type AccountRepo struct {
+	db   *sqlx.DB
+	user User
+	log  logging.Logger
+}
+
+func NewAccountRepo(db *sqlx.DB, user User, log logging.Logger) *AccountRepo {
+	return &AccountRepo{
+		db:   db,
+		user: user,
+		log:  log,
+	}
+}
+
+// GetAccount returns account status with history of its changes
+func (r *AccountRepo) GetAccount(id int) (models.Account, error) {
+	var account models.Account
+	query := fmt.Sprintf(`SELECT id, status, credits_blocked, status_reason, status_actor, status_changed_at
+		FROM %s WHERE id = $1`, usersTable)
+	if err := r.db.Get(&account, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Account{}, errors.New("user not found")
+		}
+
+		return models.Account{}, err
+	}
+
+	history := fmt.Sprintf(`SELECT status, credits_blocked, reason, actor, date FROM %s
+		WHERE user_id = $1 ORDER BY id`, accountStatusHistoryTable)
+	if err := r.db.Select(&account.History, history, id); err != nil {
+		return models.Account{}, err
+	}
+
+	r.log.LogRepo("GET", "GetAccount", true, account)
+	return account, nil
+}
+
+// SetAccountStatus changes account status. Account can be closed only if all its wallets are empty,
+// or with final payout which debits all remaining money first
+func (r *AccountRepo) SetAccountStatus(input models.AccountStatusInput) (models.Account, error) {
+	var account models.Account
+	err := NewSQLTransactor(r.db).WithinTx(func(tx Tx) error {
+		var err error
+		account, err = r.setStatusTx(input, tx)
+		return err
+	})
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	r.log.LogRepo("POST", "SetAccountStatus", true, account)
+	return account, nil
+}
+
+func (r *AccountRepo) setStatusTx(input models.AccountStatusInput, unit Tx) (models.Account, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	var status string
+	query := fmt.Sprintf("SELECT status FROM %s WHERE id = $1 FOR UPDATE", usersTable)
+	if err := tx.QueryRow(query, input.UserId).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Account{}, errors.New("user not found")
+		}
+
+		return models.Account{}, err
+	}
+
+	if status == models.AccountClosed {
+		return models.Account{}, errors.New("account is closed")
+	}
+
+	if input.Status == models.AccountClosed {
+		if err := r.empty(input, unit); err != nil {
+			return models.Account{}, err
+		}
+	}
+
+	now := time.Now()
+	account := models.Account{
+		UserId:          input.UserId,
+		Status:          input.Status,
+		CreditsBlocked:  input.BlockCredits,
+		Reason:          input.Reason,
+		Actor:           input.Operator,
+		StatusChangedAt: &now,
+	}
+
+	update := fmt.Sprintf(`UPDATE %s SET status = $2, credits_blocked = $3, status_reason = $4, status_actor = $5,
+		status_changed_at = $6 WHERE id = $1`, usersTable)
+	_, err = tx.Exec(update, account.UserId, account.Status, account.CreditsBlocked, account.Reason, account.Actor, now)
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	history := fmt.Sprintf(`INSERT INTO %s (user_id, status, credits_blocked, reason, actor, date)
+		VALUES ($1, $2, $3, $4, $5, $6)`, accountStatusHistoryTable)
+	_, err = tx.Exec(history, account.UserId, account.Status, account.CreditsBlocked, account.Reason, account.Actor, now)
+	if err != nil {
+		return models.Account{}, err
+	}
+
+	return account, nil
+}
+
+// empty checks that all account`s wallets are empty or pays remaining money out if final payout is requested.
+// Promo is forfeited and only real money is paid out. Overdrawn wallets and wallets with held money are never
+// paid out, their debt must be repaid and their holds captured or released first
+func (r *AccountRepo) empty(input models.AccountStatusInput, unit Tx) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	type wallet struct {
+		currency string
+		balance  float32
+		promo    float32
+		held     float32
+	}
+
+	query := fmt.Sprintf(`SELECT currency, balance, promo, held FROM %s
+		WHERE user_id = $1 AND (balance <> 0 OR promo <> 0 OR held <> 0) ORDER BY currency FOR UPDATE`, walletsTable)
+	rows, err := tx.Query(query, input.UserId)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wallets []wallet
+		promo   bool
+	)
+	for rows.Next() {
+		var w wallet
+		if err := rows.Scan(&w.currency, &w.balance, &w.promo, &w.held); err != nil {
+			rows.Close()
+			return err
+		}
+
+		wallets = append(wallets, w)
+		promo = promo || w.promo != 0
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, w := range wallets {
+		if w.balance-w.promo != 0 && !input.FinalPayout {
+			return errors.New("account balance must be zero to close it")
+		}
+	}
+
+	for _, w := range wallets {
+		if w.balance-w.promo < 0 {
+			return fmt.Errorf("%s wallet is overdrawn, debt must be repaid to close account", w.currency)
+		}
+
+		if w.held != 0 {
+			return fmt.Errorf("%s wallet has held money, holds must be captured or released to close account",
+				w.currency)
+		}
+	}
+
+	if promo {
+		if err := r.forfeitPromo(input.UserId, unit); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range wallets {
+		amount := w.balance - w.promo
+		if amount == 0 {
+			continue
+		}
+
+		_, err := r.user.Debit(unit, models.Input{
+			UserId:   input.UserId,
+			Amount:   amount,
+			Currency: w.currency,
+		}, models.Operation{
+			Comment: fmt.Sprintf("Final payout %f%s", amount, w.currency),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// forfeitPromo takes the unspent rest of active grants back like expiry does, promo is never paid out
+func (r *AccountRepo) forfeitPromo(userId int, unit Tx) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`SELECT id, remaining, currency, campaign_id FROM %s WHERE user_id = $1 AND status = $2
+		ORDER BY id FOR UPDATE`, promoGrantsTable)
+	rows, err := tx.Query(query, userId, models.PromoActive)
+	if err != nil {
+		return err
+	}
+
+	var grants []models.PromoGrant
+	for rows.Next() {
+		grant := models.PromoGrant{UserId: userId}
+		if err := rows.Scan(&grant.ID, &grant.Remaining, &grant.Currency, &grant.CampaignId); err != nil {
+			rows.Close()
+			return err
+		}
+
+		grants = append(grants, grant)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		if grant.Remaining <= 0 {
+			continue
+		}
+
+		_, err := r.user.Debit(unit, models.Input{
+			UserId:   userId,
+			Amount:   grant.Remaining,
+			Currency: grant.Currency,
+		}, models.Operation{
+			Comment:  fmt.Sprintf("Promo forfeited %f%s of campaign %s", grant.Remaining, grant.Currency, grant.CampaignId),
+			LinkId:   grant.GrantKey(),
+			Overdraw: true,
+			Promo:    grant.Remaining,
+			System:   true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET status = $3, remaining = 0, expired_at = $4 WHERE user_id = $1 AND status = $2",
+		promoGrantsTable)
+	_, err = tx.Exec(update, userId, models.PromoActive, models.PromoExpired, time.Now().UTC())
+	return err
+}
@@ -0,0 +1,345 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountRepository_SetAccountStatus(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewAccountRepo(sqlxDB, NewUserRepo(sqlxDB, logger), logger)
+
+	type mockBehavior func(input models.AccountStatusInput)
+
+	expectUpdate := func(input models.AccountStatusInput) {
+		mock.ExpectExec(fmt.Sprintf("UPDATE %s SET status", usersTable)).
+			WithArgs(input.UserId, input.Status, input.BlockCredits, input.Reason, input.Operator, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", accountStatusHistoryTable)).
+			WithArgs(input.UserId, input.Status, input.BlockCredits, input.Reason, input.Operator, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	walletRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"currency", "balance", "promo", "held"})
+	}
+
+	tests := []struct {
+		name      string
+		input     models.AccountStatusInput
+		mock      mockBehavior
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Freeze",
+			input: models.AccountStatusInput{
+				UserId:       1,
+				Status:       models.AccountFrozen,
+				Reason:       "chargeback investigation",
+				BlockCredits: true,
+				Operator:     "fraud-team",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountActive))
+				expectUpdate(input)
+
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Close empty account",
+			input: models.AccountStatusInput{
+				UserId:   1,
+				Status:   models.AccountClosed,
+				Reason:   "requested by user",
+				Operator: "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountActive))
+				mock.ExpectQuery(fmt.Sprintf("SELECT currency, balance, promo, held FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId).WillReturnRows(walletRows())
+				expectUpdate(input)
+
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Close with money",
+			input: models.AccountStatusInput{
+				UserId:   1,
+				Status:   models.AccountClosed,
+				Reason:   "requested by user",
+				Operator: "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountActive))
+				mock.ExpectQuery(fmt.Sprintf("SELECT currency, balance, promo, held FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId).WillReturnRows(walletRows().AddRow("EUR", 4.13, 0, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "account balance must be zero to close it",
+		},
+		{
+			name: "Close with final payout",
+			input: models.AccountStatusInput{
+				UserId:      1,
+				Status:      models.AccountClosed,
+				Reason:      "requested by user",
+				FinalPayout: true,
+				Operator:    "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountActive))
+				mock.ExpectQuery(fmt.Sprintf("SELECT currency, balance, promo, held FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId).WillReturnRows(walletRows().AddRow("EUR", 4.13, 0, 0))
+
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(4.13, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, "EUR", float32(4.13), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(input.UserId, float32(4.13), "EUR", fmt.Sprintf("Final payout %fEUR", float32(4.13)),
+						time.Now().Format("01-02-2006 15:04:05"), float32(0), "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				expectUpdate(input)
+
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Close with promo",
+			input: models.AccountStatusInput{
+				UserId:      1,
+				Status:      models.AccountClosed,
+				Reason:      "requested by user",
+				FinalPayout: true,
+				Operator:    "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountActive))
+				mock.ExpectQuery(fmt.Sprintf("SELECT currency, balance, promo, held FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId).WillReturnRows(walletRows().AddRow("EUR", 10, 4, 0))
+
+				// promo is forfeited by the debit linked to its grant
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", promoGrantsTable)).
+					WithArgs(input.UserId, models.PromoActive).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "remaining", "currency", "campaign_id"}).
+						AddRow(3, 4, "EUR", "spring"))
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, "EUR").
+					WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(10, "", -4))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET balance (.+)", walletsTable)).
+					WithArgs(input.UserId, "EUR", float32(4), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET promo (.+)", walletsTable)).
+					WithArgs(input.UserId, "EUR", float32(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(input.UserId, float32(4), "EUR", fmt.Sprintf("Promo forfeited %fEUR of campaign spring", float32(4)),
+						sqlmock.AnyArg(), float32(6), "promo-3", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET status (.+)", promoGrantsTable)).
+					WithArgs(input.UserId, models.PromoActive, models.PromoExpired, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				// only real money is paid out
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, "EUR").
+					WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(6, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET balance (.+)", walletsTable)).
+					WithArgs(input.UserId, "EUR", float32(6), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(input.UserId, float32(6), "EUR", fmt.Sprintf("Final payout %fEUR", float32(6)),
+						sqlmock.AnyArg(), float32(0), "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				expectUpdate(input)
+
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Close with overdraft hidden by promo",
+			input: models.AccountStatusInput{
+				UserId:      1,
+				Status:      models.AccountClosed,
+				Reason:      "requested by user",
+				FinalPayout: true,
+				Operator:    "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountActive))
+				mock.ExpectQuery(fmt.Sprintf("SELECT currency, balance, promo, held FROM %s WHERE (.+) OR promo <> 0 (.+)",
+					walletsTable)).WithArgs(input.UserId).WillReturnRows(walletRows().AddRow("EUR", 0, 30, 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "EUR wallet is overdrawn, debt must be repaid to close account",
+		},
+		{
+			name: "Close with held money",
+			input: models.AccountStatusInput{
+				UserId:      1,
+				Status:      models.AccountClosed,
+				Reason:      "requested by user",
+				FinalPayout: true,
+				Operator:    "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountActive))
+				mock.ExpectQuery(fmt.Sprintf("SELECT currency, balance, promo, held FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId).WillReturnRows(walletRows().AddRow("EUR", 10, 0, 3))
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "EUR wallet has held money, holds must be captured or released to close account",
+		},
+		{
+			name: "Account is closed",
+			input: models.AccountStatusInput{
+				UserId:   1,
+				Status:   models.AccountActive,
+				Operator: "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(models.AccountClosed))
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "account is closed",
+		},
+		{
+			name: "User does not exist",
+			input: models.AccountStatusInput{
+				UserId:   100,
+				Status:   models.AccountActive,
+				Operator: "support",
+			},
+			mock: func(input models.AccountStatusInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT status FROM %s WHERE (.+) FOR UPDATE", usersTable)).
+					WithArgs(input.UserId).WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(tt.input)
+
+			got, err := r.SetAccountStatus(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.input.Status, got.Status)
+				assert.Equal(t, tt.input.Operator, got.Actor)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAccountRepository_GetAccount(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewAccountRepo(sqlxDB, NewUserRepo(sqlxDB, logger), logger)
+
+	changedAt := time.Date(2023, 7, 10, 12, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "status", "credits_blocked", "status_reason", "status_actor", "status_changed_at"}).
+		AddRow(1, models.AccountFrozen, false, "chargeback investigation", "fraud-team", changedAt)
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
+		WithArgs(1).WillReturnRows(rows)
+
+	history := sqlmock.NewRows([]string{"status", "credits_blocked", "reason", "actor", "date"}).
+		AddRow(models.AccountFrozen, false, "chargeback investigation", "fraud-team", changedAt)
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", accountStatusHistoryTable)).
+		WithArgs(1).WillReturnRows(history)
+
+	got, err := r.GetAccount(1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Account{
+		UserId:          1,
+		Status:          models.AccountFrozen,
+		Reason:          "chargeback investigation",
+		Actor:           "fraud-team",
+		StatusChangedAt: &changedAt,
+		History: []models.AccountStatusChange{
+			{Status: models.AccountFrozen, Reason: "chargeback investigation", Actor: "fraud-team", Date: changedAt},
+		},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
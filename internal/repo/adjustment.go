@@ -0,0 +1,234 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Adjustment interface {
+	CreateAdjustment(input models.AdjustmentInput) (models.Adjustment, error)
+	GetAdjustment(id int) (models.Adjustment, error)
+	ListAdjustments(status string) ([]models.Adjustment, error)
+	ApproveAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error)
+	RejectAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error)
+}
+
+type AdjustmentRepo struct {
+	db   *sqlx.DB
+	user User
+	log  logging.Logger
+}
+
+func NewAdjustmentRepo(db *sqlx.DB, user User, log logging.Logger) *AdjustmentRepo {
+	return &AdjustmentRepo{
+		db:   db,
+		user: user,
+		log:  log,
+	}
+}
+
+// adjustmentColumns are selected for every adjustment
+const adjustmentColumns = `id, user_id, direction, amount, currency, reason, ticket, status,
+	created_by, created_at, reviewed_by, reviewed_at, review_comment, balance_after`
+
+func (r *AdjustmentRepo) CreateAdjustment(input models.AdjustmentInput) (models.Adjustment, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	adjustment := models.Adjustment{
+		UserId:    input.UserId,
+		Direction: input.Direction,
+		Amount:    input.Amount,
+		Currency:  input.Currency,
+		Reason:    input.Reason,
+		Ticket:    input.Ticket,
+		Status:    models.AdjustmentPending,
+		CreatedBy: input.Operator,
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, direction, amount, currency, reason, ticket, status, created_by)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8 FROM %s WHERE id = $1 RETURNING id, created_at`, adjustmentsTable, usersTable)
+
+	err = tx.QueryRow(query, input.UserId, input.Direction, input.Amount, input.Currency, input.Reason,
+		input.Ticket, adjustment.Status, input.Operator).Scan(&adjustment.ID, &adjustment.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return models.Adjustment{}, errors.New("user not found")
+		}
+
+		return models.Adjustment{}, err
+	}
+
+	err = r.addEvent(adjustment.ID, models.AdjustmentEvent{
+		Action:  models.AdjustmentProposed,
+		Actor:   input.Operator,
+		Comment: input.Reason,
+		Date:    adjustment.CreatedAt,
+	}, tx)
+	if err != nil {
+		tx.Rollback()
+		return models.Adjustment{}, err
+	}
+
+	r.log.LogRepo("POST", "CreateAdjustment", true, adjustment)
+	return adjustment, tx.Commit()
+}
+
+// GetAdjustment returns adjustment with its audit trail
+func (r *AdjustmentRepo) GetAdjustment(id int) (models.Adjustment, error) {
+	var adjustment models.Adjustment
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", adjustmentColumns, adjustmentsTable)
+	if err := r.db.Get(&adjustment, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Adjustment{}, errors.New("adjustment not found")
+		}
+
+		return models.Adjustment{}, err
+	}
+
+	events := fmt.Sprintf("SELECT action, actor, comment, date FROM %s WHERE adjustment_id = $1 ORDER BY id",
+		adjustmentEventsTable)
+	if err := r.db.Select(&adjustment.Events, events, id); err != nil {
+		return models.Adjustment{}, err
+	}
+
+	r.log.LogRepo("GET", "GetAdjustment", true, adjustment)
+	return adjustment, nil
+}
+
+// ListAdjustments returns adjustments with the status or all of them if status is empty
+func (r *AdjustmentRepo) ListAdjustments(status string) ([]models.Adjustment, error) {
+	adjustments := []models.Adjustment{}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE $1 = '' OR status = $1 ORDER BY id", adjustmentColumns, adjustmentsTable)
+	if err := r.db.Select(&adjustments, query, status); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListAdjustments", true, adjustments)
+	return adjustments, nil
+}
+
+// ApproveAdjustment applies adjustment to user`s wallet, it must be approved by another operator
+func (r *AdjustmentRepo) ApproveAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	return r.review(id, models.AdjustmentApproved, review)
+}
+
+func (r *AdjustmentRepo) RejectAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	return r.review(id, models.AdjustmentRejected, review)
+}
+
+func (r *AdjustmentRepo) review(id int, status string, review models.AdjustmentReview) (models.Adjustment, error) {
+	var adjustment models.Adjustment
+	err := NewSQLTransactor(r.db).WithinTx(func(tx Tx) error {
+		var err error
+		adjustment, err = r.reviewTx(id, status, review, tx)
+		return err
+	})
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	r.log.LogRepo("POST", "ReviewAdjustment", true, adjustment)
+	return adjustment, nil
+}
+
+func (r *AdjustmentRepo) reviewTx(id int, status string, review models.AdjustmentReview, unit Tx) (models.Adjustment, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	var adjustment models.Adjustment
+	query := fmt.Sprintf(`SELECT id, user_id, direction, amount, currency, reason, ticket, status, created_by, created_at
+		FROM %s WHERE id = $1 FOR UPDATE`, adjustmentsTable)
+
+	err = tx.QueryRow(query, id).Scan(&adjustment.ID, &adjustment.UserId, &adjustment.Direction, &adjustment.Amount,
+		&adjustment.Currency, &adjustment.Reason, &adjustment.Ticket, &adjustment.Status, &adjustment.CreatedBy,
+		&adjustment.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Adjustment{}, errors.New("adjustment not found")
+		}
+
+		return models.Adjustment{}, err
+	}
+
+	if adjustment.Status != models.AdjustmentPending {
+		return models.Adjustment{}, fmt.Errorf("adjustment is already %s", adjustment.Status)
+	}
+
+	if adjustment.CreatedBy == review.Operator {
+		return models.Adjustment{}, fmt.Errorf("adjustment %w", models.ErrSameOperator)
+	}
+
+	if status == models.AdjustmentApproved {
+		balance, err := r.apply(adjustment, unit)
+		if err != nil {
+			return models.Adjustment{}, err
+		}
+
+		adjustment.BalanceAfter = &balance
+	}
+
+	now := time.Now()
+	adjustment.Status = status
+	adjustment.ReviewedBy = review.Operator
+	adjustment.ReviewedAt = &now
+	adjustment.ReviewComment = review.Comment
+
+	update := fmt.Sprintf(`UPDATE %s SET status = $2, reviewed_by = $3, reviewed_at = $4, review_comment = $5,
+		balance_after = $6 WHERE id = $1`, adjustmentsTable)
+	_, err = tx.Exec(update, id, adjustment.Status, adjustment.ReviewedBy, adjustment.ReviewedAt,
+		adjustment.ReviewComment, adjustment.BalanceAfter)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	err = r.addEvent(id, models.AdjustmentEvent{
+		Action:  status,
+		Actor:   review.Operator,
+		Comment: review.Comment,
+		Date:    now,
+	}, tx)
+	if err != nil {
+		return models.Adjustment{}, err
+	}
+
+	return adjustment, nil
+}
+
+// apply posts adjustment transaction through the usual balance change
+func (r *AdjustmentRepo) apply(adjustment models.Adjustment, tx Tx) (float32, error) {
+	input := models.Input{
+		UserId:   adjustment.UserId,
+		Amount:   adjustment.Amount,
+		Currency: adjustment.Currency,
+	}
+
+	operation := models.Operation{
+		Comment: fmt.Sprintf("Adjustment #%d: %s", adjustment.ID, adjustment.Reason),
+	}
+
+	if adjustment.Direction == models.AdjustmentDebit {
+		return r.user.Debit(tx, input, operation)
+	}
+
+	return r.user.Credit(tx, input, operation)
+}
+
+func (r *AdjustmentRepo) addEvent(id int, event models.AdjustmentEvent, tx *sql.Tx) error {
+	query := fmt.Sprintf("INSERT INTO %s (adjustment_id, action, actor, comment, date) VALUES ($1, $2, $3, $4, $5)",
+		adjustmentEventsTable)
+
+	_, err := tx.Exec(query, id, event.Action, event.Actor, event.Comment, event.Date)
+	return err
+}
@@ -0,0 +1,305 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjustmentRepository_CreateAdjustment(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewAdjustmentRepo(sqlxDB, NewUserRepo(sqlxDB, logger), logger)
+
+	type mockBehavior func(input models.AdjustmentInput)
+
+	createdAt := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	input := models.AdjustmentInput{
+		UserId:    1,
+		Direction: models.AdjustmentCredit,
+		Amount:    10,
+		Currency:  "EUR",
+		Reason:    "refund for order 15",
+		Ticket:    "SUP-42",
+		Operator:  "alice",
+	}
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		want      models.Adjustment
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func(input models.AdjustmentInput) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, createdAt)
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s WHERE (.+) RETURNING id, created_at",
+					adjustmentsTable, usersTable)).
+					WithArgs(input.UserId, input.Direction, input.Amount, input.Currency, input.Reason, input.Ticket,
+						models.AdjustmentPending, input.Operator).
+					WillReturnRows(rows)
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", adjustmentEventsTable)).
+					WithArgs(1, models.AdjustmentProposed, input.Operator, input.Reason, createdAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectCommit()
+			},
+			want: models.Adjustment{
+				ID:        1,
+				UserId:    1,
+				Direction: models.AdjustmentCredit,
+				Amount:    10,
+				Currency:  "EUR",
+				Reason:    "refund for order 15",
+				Ticket:    "SUP-42",
+				Status:    models.AdjustmentPending,
+				CreatedBy: "alice",
+				CreatedAt: createdAt,
+			},
+			wantErr: false,
+		},
+		{
+			name: "User does not exist",
+			mock: func(input models.AdjustmentInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s WHERE (.+) RETURNING id, created_at",
+					adjustmentsTable, usersTable)).
+					WithArgs(input.UserId, input.Direction, input.Amount, input.Currency, input.Reason, input.Ticket,
+						models.AdjustmentPending, input.Operator).
+					WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(input)
+
+			got, err := r.CreateAdjustment(input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAdjustmentRepository_ApproveAdjustment(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewAdjustmentRepo(sqlxDB, NewUserRepo(sqlxDB, logger), logger)
+
+	type mockBehavior func(id int)
+
+	createdAt := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "direction", "amount", "currency", "reason", "ticket", "status", "created_by", "created_at"}
+	review := models.AdjustmentReview{Comment: "checked", Operator: "bob"}
+
+	tests := []struct {
+		name      string
+		review    models.AdjustmentReview
+		mock      mockBehavior
+		want      float32
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name:   "Ok",
+			review: review,
+			mock: func(id int) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows(columns).
+					AddRow(id, 1, models.AdjustmentDebit, 10, "EUR", "duplicate top-up", "", models.AdjustmentPending, "alice", createdAt)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", adjustmentsTable)).
+					WithArgs(id).WillReturnRows(rows)
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(14.13, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(1, "EUR", float32(10), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(1, float32(10), "EUR", "Adjustment #1: duplicate top-up", time.Now().Format("01-02-2006 15:04:05"),
+						float32(4.13), "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET status", adjustmentsTable)).
+					WithArgs(id, models.AdjustmentApproved, "bob", sqlmock.AnyArg(), "checked", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", adjustmentEventsTable)).
+					WithArgs(id, models.AdjustmentApproved, "bob", "checked", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(2, 1))
+
+				mock.ExpectCommit()
+			},
+			want:    4.13,
+			wantErr: false,
+		},
+		{
+			name:   "Not enough money",
+			review: review,
+			mock: func(id int) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows(columns).
+					AddRow(id, 1, models.AdjustmentDebit, 10, "EUR", "duplicate top-up", "", models.AdjustmentPending, "alice", createdAt)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", adjustmentsTable)).
+					WithArgs(id).WillReturnRows(rows)
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(5, "", 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "not enough money to perform purchase",
+		},
+		{
+			name:   "Same operator",
+			review: models.AdjustmentReview{Operator: "alice"},
+			mock: func(id int) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows(columns).
+					AddRow(id, 1, models.AdjustmentDebit, 10, "EUR", "duplicate top-up", "", models.AdjustmentPending, "alice", createdAt)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", adjustmentsTable)).
+					WithArgs(id).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "adjustment must be reviewed by another operator",
+		},
+		{
+			name:   "Already reviewed",
+			review: review,
+			mock: func(id int) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows(columns).
+					AddRow(id, 1, models.AdjustmentDebit, 10, "EUR", "duplicate top-up", "", models.AdjustmentRejected, "alice", createdAt)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", adjustmentsTable)).
+					WithArgs(id).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "adjustment is already rejected",
+		},
+		{
+			name:   "Adjustment does not exist",
+			review: review,
+			mock: func(id int) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", adjustmentsTable)).
+					WithArgs(id).WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "adjustment not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(1)
+
+			got, err := r.ApproveAdjustment(1, tt.review)
+			if tt.wantErr {
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, models.AdjustmentApproved, got.Status)
+				assert.Equal(t, "bob", got.ReviewedBy)
+				assert.Equal(t, tt.want, *got.BalanceAfter)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAdjustmentRepository_RejectAdjustment(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewAdjustmentRepo(sqlxDB, NewUserRepo(sqlxDB, logger), logger)
+
+	createdAt := time.Date(2023, 7, 5, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "user_id", "direction", "amount", "currency", "reason", "ticket", "status", "created_by", "created_at"}
+
+	mock.ExpectBegin()
+
+	rows := sqlmock.NewRows(columns).
+		AddRow(1, 1, models.AdjustmentCredit, 10, "EUR", "refund for order 15", "", models.AdjustmentPending, "alice", createdAt)
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", adjustmentsTable)).
+		WithArgs(1).WillReturnRows(rows)
+
+	mock.ExpectExec(fmt.Sprintf("UPDATE %s SET status", adjustmentsTable)).
+		WithArgs(1, models.AdjustmentRejected, "bob", sqlmock.AnyArg(), "no ticket", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", adjustmentEventsTable)).
+		WithArgs(1, models.AdjustmentRejected, "bob", "no ticket", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+
+	mock.ExpectCommit()
+
+	got, err := r.RejectAdjustment(1, models.AdjustmentReview{Comment: "no ticket", Operator: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.AdjustmentRejected, got.Status)
+	assert.Nil(t, got.BalanceAfter)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,181 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Audit interface {
+	VerifyChain() (models.ChainVerification, error)
+	GetChainHeads() ([]models.ChainHead, int, error)
+}
+
+type AuditRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewAuditRepo(db *sqlx.DB, log logging.Logger) *AuditRepo {
+	return &AuditRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+type walletKey struct {
+	userId   int
+	currency string
+}
+
+// VerifyChain walks transactions of every wallet and stops at the first break of the chain.
+// Hash of the last transaction must also match the head kept by the wallet, so removed transactions are noticed
+func (r *AuditRepo) VerifyChain() (models.ChainVerification, error) {
+	tx, err := r.db.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return models.ChainVerification{}, err
+	}
+	defer tx.Rollback()
+
+	var heads []models.ChainHead
+	query := fmt.Sprintf("SELECT user_id, currency, last_hash FROM %s", walletsTable)
+	if err := tx.Select(&heads, query); err != nil {
+		return models.ChainVerification{}, err
+	}
+
+	result := models.ChainVerification{Wallets: len(heads)}
+
+	expected := make(map[walletKey]string, len(heads))
+	for _, head := range heads {
+		expected[walletKey{head.UserId, head.Currency}] = head.Hash
+	}
+
+	rows, err := tx.Queryx(fmt.Sprintf(`SELECT id, user_id, currency, direction, amount, operation, date, balance_after,
+		link_id, prev_hash, hash FROM %s ORDER BY user_id, currency, id`, transactionsTable))
+	if err != nil {
+		return models.ChainVerification{}, err
+	}
+	defer rows.Close()
+
+	var (
+		current walletKey
+		prev    string
+		started bool
+	)
+
+	for rows.Next() {
+		var link models.ChainLink
+		if err := rows.StructScan(&link); err != nil {
+			return models.ChainVerification{}, err
+		}
+
+		key := walletKey{link.UserId, link.Currency}
+		if key != current || result.Transactions == 0 {
+			if result.Transactions > 0 {
+				if chainBreak := checkHead(current, prev, expected); chainBreak != nil {
+					return r.broken(result, chainBreak), nil
+				}
+			}
+
+			current, prev, started = key, "", false
+		}
+
+		result.Transactions++
+
+		if link.Hash == "" {
+			if started {
+				return r.broken(result, &models.ChainBreak{TransactionId: link.ID, UserId: link.UserId,
+					Currency: link.Currency, Reason: models.ChainNotHashed}), nil
+			}
+
+			result.Unchained++
+			continue
+		}
+
+		started = true
+		if link.PrevHash != prev {
+			return r.broken(result, &models.ChainBreak{TransactionId: link.ID, UserId: link.UserId,
+				Currency: link.Currency, Reason: models.ChainPrevHashMismatch}), nil
+		}
+
+		if link.ComputeHash() != link.Hash {
+			return r.broken(result, &models.ChainBreak{TransactionId: link.ID, UserId: link.UserId,
+				Currency: link.Currency, Reason: models.ChainHashMismatch}), nil
+		}
+
+		prev = link.Hash
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.ChainVerification{}, err
+	}
+
+	if result.Transactions > 0 {
+		if chainBreak := checkHead(current, prev, expected); chainBreak != nil {
+			return r.broken(result, chainBreak), nil
+		}
+	}
+
+	// wallets left have no transactions, so their heads must be empty
+	for key, hash := range expected {
+		if hash != "" {
+			return r.broken(result, &models.ChainBreak{UserId: key.userId, Currency: key.currency,
+				Reason: models.ChainHeadMismatch}), nil
+		}
+	}
+
+	result.Valid = true
+	result.VerifiedAt = time.Now()
+
+	r.log.LogRepo("GET", "VerifyChain", true, result)
+	return result, nil
+}
+
+// checkHead compares the last hash of the wallet chain with the wallet head, checked wallet is removed from expected
+func checkHead(key walletKey, last string, expected map[walletKey]string) *models.ChainBreak {
+	head, ok := expected[key]
+	delete(expected, key)
+
+	// transactions of removed wallet are not checked against the head
+	if !ok || head == last {
+		return nil
+	}
+
+	return &models.ChainBreak{UserId: key.userId, Currency: key.currency, Reason: models.ChainHeadMismatch}
+}
+
+func (r *AuditRepo) broken(result models.ChainVerification, chainBreak *models.ChainBreak) models.ChainVerification {
+	result.Break = chainBreak
+	result.VerifiedAt = time.Now()
+
+	r.log.LogRepo("GET", "VerifyChain", false, result)
+	return result
+}
+
+// GetChainHeads returns heads of all chains along with the id of the last transaction, both from one snapshot
+func (r *AuditRepo) GetChainHeads() ([]models.ChainHead, int, error) {
+	tx, err := r.db.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	heads := []models.ChainHead{}
+	query := fmt.Sprintf("SELECT user_id, currency, last_hash FROM %s WHERE last_hash <> '' ORDER BY user_id, currency",
+		walletsTable)
+	if err := tx.Select(&heads, query); err != nil {
+		return nil, 0, err
+	}
+
+	var last int
+	if err := tx.Get(&last, fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s", transactionsTable)); err != nil {
+		return nil, 0, err
+	}
+
+	return heads, last, nil
+}
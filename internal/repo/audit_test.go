@@ -0,0 +1,107 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditRepository_VerifyChain(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewAuditRepo(sqlxDB, logger)
+
+	type mockBehavior func()
+
+	date := time.Date(2023, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	first := models.ChainLink{ID: 2, UserId: 1, Currency: "EUR", Direction: 1, Amount: 10, Operation: "Top-up by bank_card",
+		Date: date, BalanceAfter: 10}
+	first.Hash = first.ComputeHash()
+
+	second := models.ChainLink{ID: 3, UserId: 1, Currency: "EUR", Direction: -1, Amount: 4, Operation: "Debit by purchase",
+		Date: date, BalanceAfter: 6, PrevHash: first.Hash}
+	second.Hash = second.ComputeHash()
+
+	linkColumns := []string{"id", "user_id", "currency", "direction", "amount", "operation", "date", "balance_after",
+		"link_id", "prev_hash", "hash"}
+	addLink := func(rows *sqlmock.Rows, l models.ChainLink) *sqlmock.Rows {
+		return rows.AddRow(l.ID, l.UserId, l.Currency, l.Direction, l.Amount, l.Operation, l.Date, l.BalanceAfter,
+			l.LinkId, l.PrevHash, l.Hash)
+	}
+
+	expectChain := func(head string, links ...models.ChainLink) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s", walletsTable)).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "currency", "last_hash"}).AddRow(1, "EUR", head))
+
+		rows := sqlmock.NewRows(linkColumns)
+		// transaction written before hashing was introduced
+		rows.AddRow(1, 1, "EUR", 1, 5, "", date, 5, "", "", "")
+		for _, l := range links {
+			addLink(rows, l)
+		}
+		mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s ORDER BY", transactionsTable)).WillReturnRows(rows)
+		mock.ExpectRollback()
+	}
+
+	tampered := second
+	tampered.Amount = 1
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		wantValid bool
+		wantBreak *models.ChainBreak
+	}{
+		{
+			name:      "Valid",
+			mock:      func() { expectChain(second.Hash, first, second) },
+			wantValid: true,
+		},
+		{
+			name:      "Edited transaction",
+			mock:      func() { expectChain(second.Hash, first, tampered) },
+			wantBreak: &models.ChainBreak{TransactionId: 3, UserId: 1, Currency: "EUR", Reason: models.ChainHashMismatch},
+		},
+		{
+			name:      "Removed transaction",
+			mock:      func() { expectChain(second.Hash, second) },
+			wantBreak: &models.ChainBreak{TransactionId: 3, UserId: 1, Currency: "EUR", Reason: models.ChainPrevHashMismatch},
+		},
+		{
+			name:      "Removed last transaction",
+			mock:      func() { expectChain(second.Hash, first) },
+			wantBreak: &models.ChainBreak{UserId: 1, Currency: "EUR", Reason: models.ChainHeadMismatch},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.VerifyChain()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantValid, got.Valid)
+			assert.Equal(t, tt.wantBreak, got.Break)
+			assert.Equal(t, 1, got.Unchained)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Batch interface {
+	// ClaimKey saves idempotency key of a batch item as a part of tx, if the key is already used
+	// returns balance saved with it. Concurrent claims wait for each other on the primary key
+	ClaimKey(tx Tx, key string) (float32, bool, error)
+	// SaveKey saves balance of the item applied with the claimed key
+	SaveKey(tx Tx, key string, balance float32) error
+}
+
+type BatchRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewBatchRepo(db *sqlx.DB, log logging.Logger) *BatchRepo {
+	return &BatchRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *BatchRepo) ClaimKey(unit Tx, key string) (float32, bool, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return 0, false, err
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (key) VALUES ($1) ON CONFLICT (key) DO NOTHING", idempotencyKeysTable)
+	res, err := tx.Exec(insert, key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+
+	if affected == 1 {
+		return 0, false, nil
+	}
+
+	var balance float32
+	query := fmt.Sprintf("SELECT balance FROM %s WHERE key = $1", idempotencyKeysTable)
+	if err := tx.QueryRow(query, key).Scan(&balance); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, errors.New("idempotency key not found")
+		}
+
+		return 0, false, err
+	}
+
+	return balance, true, nil
+}
+
+func (r *BatchRepo) SaveKey(unit Tx, key string, balance float32) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET balance = $2 WHERE key = $1", idempotencyKeysTable)
+	_, err = tx.Exec(query, key, balance)
+	return err
+}
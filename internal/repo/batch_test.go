@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchRepository_ClaimKey(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewBatchRepo(sqlxDB, logger)
+
+	type mockBehavior func()
+
+	tests := []struct {
+		name          string
+		mock          mockBehavior
+		wantBalance   float32
+		wantDuplicate bool
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) ON CONFLICT", idempotencyKeysTable)).
+					WithArgs("payout-1").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET balance", idempotencyKeysTable)).
+					WithArgs("payout-1", float32(15)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Duplicate",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) ON CONFLICT", idempotencyKeysTable)).
+					WithArgs("payout-1").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(fmt.Sprintf("SELECT balance FROM %s WHERE (.+)", idempotencyKeysTable)).
+					WithArgs("payout-1").WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(15))
+				mock.ExpectCommit()
+			},
+			wantBalance:   15,
+			wantDuplicate: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				balance, duplicate, err := r.ClaimKey(tx, "payout-1")
+				if err != nil {
+					return err
+				}
+
+				assert.Equal(t, tt.wantBalance, balance)
+				assert.Equal(t, tt.wantDuplicate, duplicate)
+				if duplicate {
+					return nil
+				}
+
+				return r.SaveKey(tx, "payout-1", 15)
+			})
+
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// Wallet cache drivers
+const (
+	CacheLRU   = "lru"
+	CacheRedis = "redis"
+)
+
+type CacheConfig struct {
+	// Driver is lru or redis, the cache is disabled when it is empty
+	Driver string
+	// Size limits the number of users kept by lru cache
+	Size int
+	// TTL limits how long wallets are kept, it bounds staleness if an invalidation is lost
+	TTL           time.Duration
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+}
+
+// WalletCache keeps wallets of users. Every user has a version which is increased by Invalidate,
+// Set stores wallets only if the version is still the one read before they were loaded,
+// so a slow reader never overwrites the cache with wallets changed after it loaded them
+type WalletCache interface {
+	// Get returns cached wallets, false if there are none
+	Get(userId int) ([]models.Wallet, bool, error)
+	Version(userId int) (int64, error)
+	Set(userId int, version int64, wallets []models.Wallet) error
+	Invalidate(userId int) error
+}
+
+// NewWalletCache returns the cache configured by cfg, it returns nil if the cache is disabled
+func NewWalletCache(cfg CacheConfig) (WalletCache, error) {
+	switch cfg.Driver {
+	case "":
+		return nil, nil
+	case CacheLRU:
+		return NewLRUCache(cfg.Size, cfg.TTL), nil
+	case CacheRedis:
+		return InitRedisCache(cfg)
+	}
+
+	return nil, fmt.Errorf("unknown cache driver %q", cfg.Driver)
+}
+
+// CachedUser serves wallets of users from cache and loads them from User on a miss.
+// Balance changes made through it invalidate the cache once their unit of work is committed
+type CachedUser struct {
+	User
+	cache WalletCache
+	log   logging.Logger
+}
+
+func NewCachedUser(user User, cache WalletCache, log logging.Logger) *CachedUser {
+	return &CachedUser{
+		User:  user,
+		cache: cache,
+		log:   log,
+	}
+}
+
+// GetWallets falls back to User if the cache fails, the cache never makes the request fail
+func (r *CachedUser) GetWallets(id int) ([]models.Wallet, error) {
+	wallets, ok, err := r.cache.Get(id)
+	if err != nil {
+		r.log.Infof("failed to get wallets of user %d from cache: %s", id, err.Error())
+	}
+	if ok {
+		return wallets, nil
+	}
+
+	version, err := r.cache.Version(id)
+	if err != nil {
+		r.log.Infof("failed to get cache version of user %d: %s", id, err.Error())
+		return r.User.GetWallets(id)
+	}
+
+	wallets, err = r.User.GetWallets(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.cache.Set(id, version, wallets); err != nil {
+		r.log.Infof("failed to cache wallets of user %d: %s", id, err.Error())
+	}
+
+	return wallets, nil
+}
+
+func (r *CachedUser) Credit(tx Tx, input models.Input, operation models.Operation) (float32, error) {
+	balance, err := r.User.Credit(tx, input, operation)
+	if err == nil {
+		r.invalidate(tx, input.UserId)
+	}
+
+	return balance, err
+}
+
+func (r *CachedUser) Debit(tx Tx, input models.Input, operation models.Operation) (float32, error) {
+	balance, err := r.User.Debit(tx, input, operation)
+	if err == nil {
+		r.invalidate(tx, input.UserId)
+	}
+
+	return balance, err
+}
+
+// Reader returns reader serving wallets through the cache, the rest is read from reader itself
+func (r *CachedUser) Reader(reader User) User {
+	return cachedReader{
+		User:    reader,
+		wallets: r,
+	}
+}
+
+// invalidate drops wallets of user after tx is committed, until then readers may cache only the old wallets
+func (r *CachedUser) invalidate(tx Tx, userId int) {
+	tx.AfterCommit(func() {
+		if err := r.cache.Invalidate(userId); err != nil {
+			r.log.Infof("failed to invalidate cached wallets of user %d: %s", userId, err.Error())
+		}
+	})
+}
+
+// cachedReader reads wallets from cache filled from primary database, a lagging replica
+// must not fill the cache, otherwise wallets would stay stale until their next change
+type cachedReader struct {
+	User
+	wallets *CachedUser
+}
+
+func (r cachedReader) GetWallets(id int) ([]models.Wallet, error) {
+	return r.wallets.GetWallets(id)
+}
@@ -0,0 +1,240 @@
+package repo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	testWalletCache(t, func(t *testing.T) WalletCache {
+		return NewLRUCache(10, time.Minute)
+	})
+}
+
+func TestRedisCache(t *testing.T) {
+	testWalletCache(t, func(t *testing.T) WalletCache {
+		return NewRedisCache(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}), time.Minute)
+	})
+}
+
+func TestLRUCache_Eviction(t *testing.T) {
+	cache := NewLRUCache(1, time.Minute)
+	wallets := []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 10}}
+
+	version, err := cache.Version(1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.Invalidate(1))
+	assert.NoError(t, cache.Set(2, 0, nil))
+
+	// user 1 is evicted, but its version must not go back for the reader which started before invalidation
+	assert.NoError(t, cache.Set(1, version, wallets))
+	_, ok, err := cache.Get(1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	version, err = cache.Version(1)
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Set(1, version, wallets))
+
+	got, ok, err := cache.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, wallets, got)
+}
+
+func TestRedisCache_TTL(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := NewRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}), time.Minute)
+
+	assert.NoError(t, cache.Set(1, 0, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 10}}))
+	server.FastForward(2 * time.Minute)
+
+	_, ok, err := cache.Get(1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+// testWalletCache checks behaviour every WalletCache implementation must have
+func testWalletCache(t *testing.T, newCache func(t *testing.T) WalletCache) {
+	// promo must survive the round trip, otherwise cached balance shows promotional money as real
+	wallets := []models.Wallet{
+		{UserId: 1, Currency: "EUR", Balance: 10, Promo: 4},
+		{UserId: 1, Currency: "USD", Balance: 5},
+	}
+
+	t.Run("Miss", func(t *testing.T) {
+		cache := newCache(t)
+
+		_, ok, err := cache.Get(1)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Set and get", func(t *testing.T) {
+		cache := newCache(t)
+
+		version, err := cache.Version(1)
+		assert.NoError(t, err)
+		assert.NoError(t, cache.Set(1, version, wallets))
+
+		got, ok, err := cache.Get(1)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, wallets, got)
+
+		_, ok, err = cache.Get(2)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("User without wallets", func(t *testing.T) {
+		cache := newCache(t)
+		assert.NoError(t, cache.Set(1, 0, nil))
+
+		got, ok, err := cache.Get(1)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		cache := newCache(t)
+		assert.NoError(t, cache.Set(1, 0, wallets))
+		assert.NoError(t, cache.Invalidate(1))
+
+		_, ok, err := cache.Get(1)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		version, err := cache.Version(1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), version)
+	})
+
+	t.Run("Stale set is ignored", func(t *testing.T) {
+		cache := newCache(t)
+
+		version, err := cache.Version(1)
+		assert.NoError(t, err)
+
+		// balance is changed while the reader loads wallets
+		assert.NoError(t, cache.Invalidate(1))
+		assert.NoError(t, cache.Set(1, version, wallets))
+
+		_, ok, err := cache.Get(1)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Versions are per user", func(t *testing.T) {
+		cache := newCache(t)
+		assert.NoError(t, cache.Invalidate(2))
+
+		version, err := cache.Version(1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), version)
+	})
+}
+
+func TestCachedUser(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	eur := models.Input{UserId: 1, Amount: 10, Currency: "EUR"}
+
+	newRepo := func() (*MemoryUserRepo, *CachedUser, WalletCache) {
+		user := NewMemoryUserRepo(logger)
+		user.AddUsers(1, 2)
+		cache := NewLRUCache(10, time.Minute)
+		return user, NewCachedUser(user, cache, logger), cache
+	}
+
+	t.Run("Wallets are cached", func(t *testing.T) {
+		user, r, cache := newRepo()
+		if _, err := topUp(user, r, eur); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.GetWallets(1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 10}}, got)
+
+		cached, ok, err := cache.Get(1)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, got, cached)
+	})
+
+	t.Run("Unknown user is not cached", func(t *testing.T) {
+		_, r, cache := newRepo()
+
+		_, err := r.GetWallets(3)
+		assert.EqualError(t, err, "user not found")
+
+		_, ok, err := cache.Get(3)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Committed change invalidates", func(t *testing.T) {
+		user, r, _ := newRepo()
+		if _, err := r.GetWallets(1); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := transfer(user, r, models.TransferInput{UserId: 1, ToId: 2, Amount: 10, Currency: "EUR"})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+
+		if _, err := topUp(user, r, eur); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.GetWallets(1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 10}}, got)
+	})
+
+	t.Run("Rolled back change keeps cache", func(t *testing.T) {
+		user, r, cache := newRepo()
+		if _, err := r.GetWallets(1); err != nil {
+			t.Fatal(err)
+		}
+
+		err := user.WithinTx(func(tx Tx) error {
+			if _, err := r.Credit(tx, eur, models.Operation{}); err != nil {
+				return err
+			}
+
+			return errors.New("payment declined")
+		})
+		assert.EqualError(t, err, "payment declined")
+
+		version, err := cache.Version(1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), version)
+	})
+
+	t.Run("Reader fills cache from primary", func(t *testing.T) {
+		user, r, _ := newRepo()
+		replica := NewMemoryUserRepo(logger)
+		replica.AddUsers(1)
+
+		if _, err := topUp(user, r, eur); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.Reader(replica).GetWallets(1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 10}}, got)
+	})
+}
@@ -0,0 +1,1072 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/schema"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// conformanceDSN points to a postgres database the conformance suite may wipe,
+// the suite runs only against memory storage when it is not set
+const conformanceDSN = "BALANCE_TEST_DSN"
+
+// repoFactory returns repositories with the given users and no wallets
+type repoFactory func(t *testing.T, users ...int) *Repo
+
+func TestMemoryRepo_Conformance(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	testConformance(t, func(t *testing.T, users ...int) *Repo {
+		return NewMemoryRepo(logger, users...)
+	})
+}
+
+func TestRepo_Conformance(t *testing.T) {
+	dsn := os.Getenv(conformanceDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", conformanceDSN)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrator, err := NewMigrator(db, schema.Migrations, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := []string{usersTable, walletsTable, transactionsTable, spendingLimitsTable, spendsTable, feeRulesTable,
+		holdsTable, overdraftChargesTable, promoGrantsTable, schedulesTable, scheduleRunsTable, plansTable,
+		subscriptionsTable, subscriptionEventsTable, voucherBatchesTable, vouchersTable, voucherRedemptionsTable,
+		escrowsTable, escrowEventsTable}
+
+	testConformance(t, func(t *testing.T, users ...int) *Repo {
+		truncate := fmt.Sprintf("TRUNCATE %s RESTART IDENTITY CASCADE", strings.Join(tables, ", "))
+		if _, err := db.Exec(truncate); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range users {
+			if _, err := db.Exec(fmt.Sprintf("INSERT INTO %s (id) VALUES ($1)", usersTable), id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return NewRepo(db, nil, nil, nil, logger)
+	})
+}
+
+// testConformance checks behaviour every implementation of repositories must have
+func testConformance(t *testing.T, newRepo repoFactory) {
+	t.Run("User", func(t *testing.T) { testUserConformance(t, newRepo) })
+	t.Run("Limit", func(t *testing.T) { testLimitConformance(t, newRepo) })
+	t.Run("Fee", func(t *testing.T) { testFeeConformance(t, newRepo) })
+	t.Run("CreditLine", func(t *testing.T) { testCreditLineConformance(t, newRepo) })
+	t.Run("Promo", func(t *testing.T) { testPromoConformance(t, newRepo) })
+	t.Run("Schedule", func(t *testing.T) { testScheduleConformance(t, newRepo) })
+	t.Run("Subscription", func(t *testing.T) { testSubscriptionConformance(t, newRepo) })
+	t.Run("Voucher", func(t *testing.T) { testVoucherConformance(t, newRepo) })
+	t.Run("Escrow", func(t *testing.T) { testEscrowConformance(t, newRepo) })
+}
+
+// testUserConformance checks behaviour every User implementation must have
+func testUserConformance(t *testing.T, newRepo repoFactory) {
+	eur := func(user int, amount float32) models.Input {
+		return models.Input{UserId: user, Amount: amount, Currency: "EUR"}
+	}
+
+	mustTopUp := func(t *testing.T, r *Repo, input models.Input) {
+		if _, err := topUp(r, r.User, input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wallets := func(t *testing.T, r *Repo, user int) map[string]float32 {
+		got, err := r.GetWallets(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		balances := make(map[string]float32, len(got))
+		for _, w := range got {
+			balances[w.Currency] = w.Balance
+		}
+		return balances
+	}
+
+	t.Run("Top-up opens wallet", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		balance, err := topUp(r, r.User, eur(1, 10))
+		assert.NoError(t, err)
+		assert.Equal(t, float32(10), balance)
+
+		got, err := r.GetWallets(1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 10}}, got)
+	})
+
+	t.Run("Wallets are sorted by currency", func(t *testing.T) {
+		r := newRepo(t, 1)
+		mustTopUp(t, r, models.Input{UserId: 1, Amount: 5, Currency: "USD"})
+		mustTopUp(t, r, eur(1, 10))
+
+		got, err := r.GetWallets(1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{
+			{UserId: 1, Currency: "EUR", Balance: 10},
+			{UserId: 1, Currency: "USD", Balance: 5},
+		}, got)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		_, err := topUp(r, r.User, eur(2, 10))
+		assert.EqualError(t, err, "user not found")
+
+		_, err = r.GetWallets(2)
+		assert.EqualError(t, err, "user not found")
+
+		got, err := r.GetWallets(1)
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Debit", func(t *testing.T) {
+		r := newRepo(t, 1)
+		mustTopUp(t, r, eur(1, 10))
+
+		balance, err := debit(r, r.User, eur(1, 4))
+		assert.NoError(t, err)
+		assert.Equal(t, float32(6), balance)
+	})
+
+	t.Run("Debit more than balance", func(t *testing.T) {
+		r := newRepo(t, 1)
+		mustTopUp(t, r, eur(1, 10))
+
+		_, err := debit(r, r.User, eur(1, 10.5))
+		assert.EqualError(t, err, "not enough money to perform purchase")
+		assert.Equal(t, map[string]float32{"EUR": 10}, wallets(t, r, 1))
+	})
+
+	t.Run("Debit without wallet", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		_, err := debit(r, r.User, eur(1, 1))
+		assert.EqualError(t, err, "not enough money to perform purchase")
+		assert.Empty(t, wallets(t, r, 1))
+	})
+
+	t.Run("Transfer", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+		mustTopUp(t, r, eur(1, 10))
+
+		balance, err := transfer(r, r.User, models.TransferInput{UserId: 1, ToId: 2, Amount: 4, Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(4), balance)
+		assert.Equal(t, map[string]float32{"EUR": 6}, wallets(t, r, 1))
+		assert.Equal(t, map[string]float32{"EUR": 4}, wallets(t, r, 2))
+	})
+
+	t.Run("Failed transfer changes nothing", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+		mustTopUp(t, r, eur(1, 10))
+
+		_, err := transfer(r, r.User, models.TransferInput{UserId: 1, ToId: 3, Amount: 4, Currency: "EUR"})
+		assert.EqualError(t, err, "user not found")
+
+		_, err = transfer(r, r.User, models.TransferInput{UserId: 1, ToId: 2, Amount: 11, Currency: "EUR"})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+
+		assert.Equal(t, map[string]float32{"EUR": 10}, wallets(t, r, 1))
+		assert.Empty(t, wallets(t, r, 2))
+
+		transactions, err := r.GetTransactions(1, models.Page{Page: 1, Limit: 10, Sort: "id"})
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 1)
+	})
+
+	t.Run("Concurrent debits do not overdraw", func(t *testing.T) {
+		r := newRepo(t, 1)
+		mustTopUp(t, r, eur(1, 10))
+
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			succeeded int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := debit(r, r.User, eur(1, 1)); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 10, succeeded)
+		assert.Equal(t, map[string]float32{"EUR": 0}, wallets(t, r, 1))
+	})
+
+	t.Run("Transactions", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+		mustTopUp(t, r, eur(1, 10))
+		if _, err := debit(r, r.User, eur(1, 4)); err != nil {
+			t.Fatal(err)
+		}
+		mustTopUp(t, r, eur(2, 1))
+
+		got, err := r.GetTransactions(1, models.Page{Page: 1, Limit: 10, Sort: "id"})
+		assert.NoError(t, err)
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, float32(10), got[0].Amount)
+			assert.Equal(t, float32(10), got[0].BalanceAfter)
+			assert.Equal(t, "Top-up by bank_card 10.000000EUR", got[0].Operation)
+			assert.Equal(t, float32(4), got[1].Amount)
+			assert.Equal(t, float32(6), got[1].BalanceAfter)
+			assert.Equal(t, "Debit by purchase 4.000000EUR", got[1].Operation)
+		}
+
+		got, err = r.GetTransactions(1, models.Page{Page: 2, Limit: 1, Sort: "id"})
+		assert.NoError(t, err)
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, float32(4), got[0].Amount)
+		}
+
+		got, err = r.GetTransactions(1, models.Page{Page: 1, Limit: 1, Sort: "amount DESC"})
+		assert.NoError(t, err)
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, float32(10), got[0].Amount)
+		}
+
+		got, err = r.GetTransactions(3, models.Page{Page: 1, Limit: 10, Sort: "id"})
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("Balance at", func(t *testing.T) {
+		r := newRepo(t, 1)
+		mustTopUp(t, r, eur(1, 10))
+
+		got, err := r.GetBalanceAt(1, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 0}}, got)
+
+		got, err = r.GetBalanceAt(1, time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: 10}}, got)
+
+		_, err = r.GetBalanceAt(2, time.Now())
+		assert.EqualError(t, err, "user not found")
+	})
+
+	t.Run("Balance history", func(t *testing.T) {
+		r := newRepo(t, 1)
+		mustTopUp(t, r, eur(1, 10))
+
+		now := time.Now()
+		got, err := r.GetBalanceHistory(1, "EUR", now.Add(-150*time.Minute), now.Add(30*time.Minute), "1 hour")
+		assert.NoError(t, err)
+
+		balances := make([]float32, 0, len(got))
+		for _, point := range got {
+			balances = append(balances, point.Balance)
+		}
+		assert.Equal(t, []float32{0, 0, 10, 10}, balances)
+
+		_, err = r.GetBalanceHistory(1, "USD", now.Add(-time.Hour), now, "1 hour")
+		assert.EqualError(t, err, "wallet not found")
+	})
+}
+
+// withinTx runs f as a unit of work of r and fails the test if it can not be committed
+func withinTx(t *testing.T, r *Repo, f func(tx Tx) error) {
+	if err := r.WithinTx(f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testLimitConformance checks behaviour every Limit implementation must have
+func testLimitConformance(t *testing.T, newRepo repoFactory) {
+	spend := func(r *Repo, amount float32, now time.Time) error {
+		return r.WithinTx(func(tx Tx) error {
+			return r.Spend(tx, models.Spend{UserId: 1, Operation: models.LimitDebit, Amount: amount, Currency: "EUR"}, now)
+		})
+	}
+
+	t.Run("Set replaces limit", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		first, err := r.SetLimit(models.LimitInput{UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily,
+			Amount: 10, Currency: "EUR"})
+		assert.NoError(t, err)
+
+		second, err := r.SetLimit(models.LimitInput{UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily,
+			Amount: 20, Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, float32(20), second.Amount)
+
+		_, err = r.SetLimit(models.LimitInput{UserId: 2, Operation: models.LimitDebit, Period: models.LimitDaily,
+			Amount: 10, Currency: "EUR"})
+		assert.EqualError(t, err, "user not found")
+	})
+
+	t.Run("Spends are counted within window", func(t *testing.T) {
+		r := newRepo(t, 1)
+		_, err := r.SetLimit(models.LimitInput{UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily,
+			Amount: 15, Currency: "EUR"})
+		assert.NoError(t, err)
+
+		now := time.Now()
+		assert.NoError(t, spend(r, 10, now.Add(-25*time.Hour)))
+		assert.NoError(t, spend(r, 10, now))
+		assert.ErrorIs(t, spend(r, 10, now), models.ErrLimitExceeded)
+
+		usage, err := r.GetLimitUsage(1, now)
+		assert.NoError(t, err)
+		if assert.Len(t, usage, 1) {
+			assert.Equal(t, float32(10), usage[0].Used)
+			assert.Equal(t, float32(5), usage[0].Remaining)
+		}
+	})
+
+	t.Run("Rolled back spend is not counted", func(t *testing.T) {
+		r := newRepo(t, 1)
+		_, err := r.SetLimit(models.LimitInput{UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily,
+			Amount: 15, Currency: "EUR"})
+		assert.NoError(t, err)
+
+		now := time.Now()
+		err = r.WithinTx(func(tx Tx) error {
+			if err := r.Spend(tx, models.Spend{UserId: 1, Operation: models.LimitDebit, Amount: 10, Currency: "EUR"},
+				now); err != nil {
+				return err
+			}
+			return errors.New("rollback")
+		})
+		assert.EqualError(t, err, "rollback")
+		assert.NoError(t, spend(r, 15, now))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepo(t, 1)
+		limit, err := r.SetLimit(models.LimitInput{UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily,
+			Amount: 1, Currency: "EUR"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, r.DeleteLimit(limit.ID))
+		assert.ErrorIs(t, r.DeleteLimit(limit.ID), models.ErrLimitNotFound)
+		assert.NoError(t, spend(r, 10, time.Now()))
+	})
+}
+
+// testFeeConformance checks behaviour every Fee implementation must have
+func testFeeConformance(t *testing.T, newRepo repoFactory) {
+	t.Run("The most specific rule matches", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		general, err := r.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, Fixed: 1})
+		assert.NoError(t, err)
+		currency, err := r.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, Currency: "EUR", Fixed: 2})
+		assert.NoError(t, err)
+		user, err := r.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, UserId: 1, Fixed: 3})
+		assert.NoError(t, err)
+
+		rule, ok, err := r.MatchFeeRule(models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 2, Amount: 10,
+			Currency: "USD"})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, general.ID, rule.ID)
+
+		rule, ok, err = r.MatchFeeRule(models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 2, Amount: 10,
+			Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, currency.ID, rule.ID)
+
+		rule, ok, err = r.MatchFeeRule(models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 1, Amount: 10,
+			Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, user.ID, rule.ID)
+
+		_, ok, err = r.MatchFeeRule(models.FeeQuoteInput{Operation: models.FeeExchange, UserId: 1, Amount: 10,
+			Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Amount bracket", func(t *testing.T) {
+		r := newRepo(t, 1)
+		_, err := r.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, MinAmount: 10, MaxAmount: 20,
+			Rate: 0.01})
+		assert.NoError(t, err)
+
+		for amount, applies := range map[float32]bool{5: false, 10: true, 19: true, 20: false} {
+			_, ok, err := r.MatchFeeRule(models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 1,
+				Amount: amount, Currency: "EUR"})
+			assert.NoError(t, err)
+			assert.Equal(t, applies, ok, amount)
+		}
+	})
+
+	t.Run("List and delete", func(t *testing.T) {
+		r := newRepo(t, 1)
+		transfer, err := r.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, Fixed: 1})
+		assert.NoError(t, err)
+		_, err = r.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeExchange, Rate: 0.01})
+		assert.NoError(t, err)
+
+		rules, err := r.ListFeeRules("")
+		assert.NoError(t, err)
+		assert.Len(t, rules, 2)
+
+		rules, err = r.ListFeeRules(models.FeeTransfer)
+		assert.NoError(t, err)
+		if assert.Len(t, rules, 1) {
+			assert.Equal(t, transfer.ID, rules[0].ID)
+		}
+
+		assert.NoError(t, r.DeleteFeeRule(transfer.ID))
+		assert.ErrorIs(t, r.DeleteFeeRule(transfer.ID), models.ErrFeeRuleNotFound)
+	})
+}
+
+// testCreditLineConformance checks behaviour every CreditLine implementation must have
+func testCreditLineConformance(t *testing.T, newRepo repoFactory) {
+	placeHold := func(r *Repo, amount float32) (models.Hold, error) {
+		var hold models.Hold
+		err := r.WithinTx(func(tx Tx) error {
+			var err error
+			hold, err = r.PlaceHold(tx, models.HoldInput{UserId: 1, Amount: amount, Currency: "EUR"})
+			return err
+		})
+
+		return hold, err
+	}
+
+	t.Run("Credit limit allows overdraft", func(t *testing.T) {
+		r := newRepo(t, 1)
+		if _, err := topUp(r, r.User, models.Input{UserId: 1, Amount: 10, Currency: "EUR"}); err != nil {
+			t.Fatal(err)
+		}
+
+		line, err := r.SetCreditLimit(models.CreditLimitInput{UserId: 1, Currency: "EUR", CreditLimit: 5})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(15), line.Available)
+
+		balance, err := debit(r, r.User, models.Input{UserId: 1, Amount: 14, Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(-4), balance)
+
+		_, err = debit(r, r.User, models.Input{UserId: 1, Amount: 2, Currency: "EUR"})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+
+		lines, err := r.GetCreditLines(1)
+		assert.NoError(t, err)
+		if assert.Len(t, lines, 1) {
+			assert.Equal(t, float32(-4), lines[0].Balance)
+			assert.Equal(t, float32(1), lines[0].Available)
+			assert.Equal(t, float32(4), lines[0].Overdraft)
+		}
+
+		report, err := r.GetOverdraftReport("EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, float32(4), report.TotalOverdraft)
+		assert.Len(t, report.Wallets, 1)
+
+		_, err = r.SetCreditLimit(models.CreditLimitInput{UserId: 2, Currency: "EUR", CreditLimit: 5})
+		assert.EqualError(t, err, "user not found")
+	})
+
+	t.Run("Holds reserve available money", func(t *testing.T) {
+		r := newRepo(t, 1)
+		if _, err := topUp(r, r.User, models.Input{UserId: 1, Amount: 10, Currency: "EUR"}); err != nil {
+			t.Fatal(err)
+		}
+
+		hold, err := placeHold(r, 6)
+		assert.NoError(t, err)
+		assert.Equal(t, models.HoldActive, hold.Status)
+
+		_, err = placeHold(r, 5)
+		assert.EqualError(t, err, "not enough money to perform purchase")
+
+		_, err = debit(r, r.User, models.Input{UserId: 1, Amount: 5, Currency: "EUR"})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+
+		got, err := r.GetHold(hold.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, hold.Amount, got.Amount)
+
+		withinTx(t, r, func(tx Tx) error {
+			locked, err := r.LockHold(tx, hold.ID)
+			if err != nil {
+				return err
+			}
+
+			closedAt := time.Now()
+			locked.Status = models.HoldReleased
+			locked.ClosedAt = &closedAt
+			return r.CloseHold(tx, locked)
+		})
+
+		holds, err := r.ListHolds(1, models.HoldReleased)
+		assert.NoError(t, err)
+		assert.Len(t, holds, 1)
+
+		_, err = debit(r, r.User, models.Input{UserId: 1, Amount: 10, Currency: "EUR"})
+		assert.NoError(t, err)
+
+		_, err = r.GetHold(hold.ID + 1)
+		assert.ErrorIs(t, err, models.ErrHoldNotFound)
+	})
+
+	t.Run("Hold without wallet", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		_, err := placeHold(r, 1)
+		assert.EqualError(t, err, "not enough money to perform purchase")
+	})
+
+	t.Run("Overdrawn wallet is charged once a day", func(t *testing.T) {
+		r := newRepo(t, 1)
+		if _, err := r.SetCreditLimit(models.CreditLimitInput{UserId: 1, Currency: "EUR", CreditLimit: 10}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := debit(r, r.User, models.Input{UserId: 1, Amount: 5, Currency: "EUR"}); err != nil {
+			t.Fatal(err)
+		}
+
+		day := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+		wallets, err := r.OverdrawnWallets(day, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.Wallet{{UserId: 1, Currency: "EUR", Balance: -5}}, wallets)
+
+		withinTx(t, r, func(tx Tx) error {
+			line, ok, err := r.LockOverdrawnWallet(tx, 1, "EUR", day)
+			if err != nil || !ok {
+				return fmt.Errorf("wallet is not locked: %v", err)
+			}
+
+			return r.AddOverdraftCharge(tx, models.OverdraftCharge{UserId: 1, Currency: "EUR", Date: day,
+				Balance: line.Balance, Amount: 1})
+		})
+
+		wallets, err = r.OverdrawnWallets(day, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, wallets)
+
+		err = r.WithinTx(func(tx Tx) error {
+			return r.AddOverdraftCharge(tx, models.OverdraftCharge{UserId: 1, Currency: "EUR", Date: day,
+				Balance: -5, Amount: 1})
+		})
+		assert.Error(t, err)
+
+		report, err := r.GetOverdraftReport("EUR")
+		assert.NoError(t, err)
+		assert.Equal(t, float32(1), report.TotalCharged)
+	})
+}
+
+// testPromoConformance checks behaviour every Promo implementation must have
+func testPromoConformance(t *testing.T, newRepo repoFactory) {
+	grant := func(t *testing.T, r *Repo, amount float32, expiresAt time.Time) models.PromoGrant {
+		var created models.PromoGrant
+		withinTx(t, r, func(tx Tx) error {
+			var err error
+			created, err = r.CreateGrant(tx, models.PromoGrant{UserId: 1, Amount: amount, Currency: "EUR",
+				CampaignId: "welcome", Status: models.PromoActive, ExpiresAt: expiresAt})
+			if err != nil {
+				return err
+			}
+
+			_, err = r.Credit(tx, models.Input{UserId: 1, Amount: amount, Currency: "EUR"},
+				models.Operation{Comment: "Promo", Promo: amount})
+			return err
+		})
+
+		return created
+	}
+
+	spendPromo := func(r *Repo, amount float32, first bool, now time.Time) (float32, error) {
+		var spent float32
+		err := r.WithinTx(func(tx Tx) error {
+			var err error
+			spent, err = r.SpendPromo(tx, 1, "EUR", amount, first, now)
+			return err
+		})
+
+		return spent, err
+	}
+
+	t.Run("Grant expiring first is spent first", func(t *testing.T) {
+		r := newRepo(t, 1)
+		now := time.Now()
+		late := grant(t, r, 5, now.Add(48*time.Hour))
+		early := grant(t, r, 5, now.Add(24*time.Hour))
+
+		spent, err := spendPromo(r, 7, true, now)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(7), spent)
+
+		got, err := r.GetGrant(early.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(0), got.Remaining)
+
+		got, err = r.GetGrant(late.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(3), got.Remaining)
+
+		_, err = r.GetGrant(early.ID + 1)
+		assert.ErrorIs(t, err, models.ErrPromoGrantNotFound)
+	})
+
+	t.Run("Promo pays what real money can not", func(t *testing.T) {
+		r := newRepo(t, 1)
+		now := time.Now()
+		grant(t, r, 5, now.Add(time.Hour))
+		if _, err := topUp(r, r.User, models.Input{UserId: 1, Amount: 4, Currency: "EUR"}); err != nil {
+			t.Fatal(err)
+		}
+
+		spent, err := spendPromo(r, 6, false, now)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(2), spent)
+
+		spent, err = spendPromo(r, 1, true, now)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(1), spent)
+	})
+
+	t.Run("Expired grants", func(t *testing.T) {
+		r := newRepo(t, 1)
+		now := time.Now()
+		expired := grant(t, r, 5, now.Add(-time.Hour))
+		active := grant(t, r, 5, now.Add(time.Hour))
+
+		spent, err := spendPromo(r, 10, true, now)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(5), spent)
+
+		ids, err := r.ExpiredGrants(now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{expired.ID}, ids)
+
+		withinTx(t, r, func(tx Tx) error {
+			_, ok, err := r.LockExpiredGrant(tx, active.ID, now)
+			if err != nil || ok {
+				return fmt.Errorf("active grant is locked: %v", err)
+			}
+
+			locked, ok, err := r.LockExpiredGrant(tx, expired.ID, now)
+			if err != nil || !ok {
+				return fmt.Errorf("expired grant is not locked: %v", err)
+			}
+
+			locked.Status = models.PromoExpired
+			locked.Remaining = 0
+			locked.ExpiredAt = &now
+			return r.ExpireGrant(tx, locked)
+		})
+
+		ids, err = r.ExpiredGrants(now, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+
+		grants, err := r.ListGrants(1, models.PromoExpired)
+		assert.NoError(t, err)
+		if assert.Len(t, grants, 1) {
+			assert.Equal(t, expired.ID, grants[0].ID)
+		}
+	})
+}
+
+// testScheduleConformance checks behaviour every Schedule implementation must have
+func testScheduleConformance(t *testing.T, newRepo repoFactory) {
+	newSchedule := func(next time.Time) models.Schedule {
+		return models.Schedule{Type: models.ScheduleTransfer, UserId: 1, ToId: 2, Amount: 1, Currency: "EUR",
+			Interval: "1h", Status: models.ScheduleActive, Occurrence: next, NextRunAt: next}
+	}
+
+	t.Run("Create and get", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+
+		schedule, err := r.CreateSchedule(newSchedule(time.Now()))
+		assert.NoError(t, err)
+
+		got, err := r.GetSchedule(schedule.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, schedule.ID, got.ID)
+		assert.Equal(t, 2, got.ToId)
+		assert.Empty(t, got.Runs)
+
+		_, err = r.GetSchedule(schedule.ID + 1)
+		assert.ErrorIs(t, err, models.ErrScheduleNotFound)
+
+		unknown := newSchedule(time.Now())
+		unknown.ToId = 3
+		_, err = r.CreateSchedule(unknown)
+		assert.EqualError(t, err, "user not found")
+	})
+
+	t.Run("Due schedules", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+		now := time.Now()
+
+		later, err := r.CreateSchedule(newSchedule(now.Add(-time.Minute)))
+		assert.NoError(t, err)
+		earlier, err := r.CreateSchedule(newSchedule(now.Add(-time.Hour)))
+		assert.NoError(t, err)
+		_, err = r.CreateSchedule(newSchedule(now.Add(time.Hour)))
+		assert.NoError(t, err)
+
+		ids, err := r.DueSchedules(now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{earlier.ID, later.ID}, ids)
+
+		ids, err = r.DueSchedules(now, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{earlier.ID}, ids)
+	})
+
+	t.Run("Runs", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+		now := time.Now()
+		schedule, err := r.CreateSchedule(newSchedule(now.Add(-time.Minute)))
+		assert.NoError(t, err)
+
+		withinTx(t, r, func(tx Tx) error {
+			locked, ok, err := r.LockDueSchedule(tx, schedule.ID, now)
+			if err != nil || !ok {
+				return fmt.Errorf("schedule is not locked: %v", err)
+			}
+
+			if err := r.AddRun(tx, locked.ID, models.ScheduleRun{Occurrence: locked.Occurrence, Attempt: 1,
+				Status: models.ScheduleRunSucceeded, Date: now}); err != nil {
+				return err
+			}
+
+			locked.Occurrence = locked.Occurrence.Add(time.Hour)
+			locked.NextRunAt = locked.Occurrence
+			return r.UpdateSchedule(tx, locked)
+		})
+
+		withinTx(t, r, func(tx Tx) error {
+			_, ok, err := r.LockDueSchedule(tx, schedule.ID, now)
+			if err != nil || ok {
+				return fmt.Errorf("schedule which is not due is locked: %v", err)
+			}
+			return nil
+		})
+
+		err = r.WithinTx(func(tx Tx) error {
+			return r.AddRun(tx, schedule.ID, models.ScheduleRun{Occurrence: schedule.Occurrence, Attempt: 2,
+				Status: models.ScheduleRunSucceeded, Date: now})
+		})
+		assert.Error(t, err)
+
+		got, err := r.GetSchedule(schedule.ID)
+		assert.NoError(t, err)
+		assert.Len(t, got.Runs, 1)
+		assert.True(t, got.NextRunAt.After(now))
+
+		schedules, err := r.ListSchedules(1, models.ScheduleActive)
+		assert.NoError(t, err)
+		assert.Len(t, schedules, 1)
+
+		schedules, err = r.ListSchedules(1, models.SchedulePaused)
+		assert.NoError(t, err)
+		assert.Empty(t, schedules)
+	})
+}
+
+// testSubscriptionConformance checks behaviour every Subscription implementation must have
+func testSubscriptionConformance(t *testing.T, newRepo repoFactory) {
+	subscribe := func(r *Repo, user, plan int, next time.Time) (models.Subscription, error) {
+		var created models.Subscription
+		err := r.WithinTx(func(tx Tx) error {
+			var err error
+			created, err = r.CreateSubscription(tx, models.Subscription{UserId: user, PlanId: plan,
+				Status: models.SubscriptionActive, PeriodStart: next.Add(-time.Hour), PeriodEnd: next,
+				NextAttemptAt: next})
+			return err
+		})
+
+		return created, err
+	}
+
+	t.Run("Plans", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		plan, err := r.CreatePlan(models.PlanInput{Name: "Pro", Price: 10, Currency: "EUR", Period: models.PeriodMonth})
+		assert.NoError(t, err)
+
+		got, err := r.GetPlan(plan.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Pro", got.Name)
+
+		plans, err := r.ListPlans()
+		assert.NoError(t, err)
+		assert.Len(t, plans, 1)
+
+		_, err = r.GetPlan(plan.ID + 1)
+		assert.ErrorIs(t, err, models.ErrPlanNotFound)
+	})
+
+	t.Run("Subscriptions", func(t *testing.T) {
+		r := newRepo(t, 1)
+		now := time.Now()
+		plan, err := r.CreatePlan(models.PlanInput{Name: "Pro", Price: 10, Currency: "EUR", Period: models.PeriodDay})
+		assert.NoError(t, err)
+
+		subscription, err := subscribe(r, 1, plan.ID, now.Add(-time.Minute))
+		assert.NoError(t, err)
+
+		_, err = subscribe(r, 2, plan.ID, now)
+		assert.EqualError(t, err, "user not found")
+
+		_, err = subscribe(r, 1, plan.ID+1, now)
+		assert.Error(t, err)
+
+		ids, err := r.DueSubscriptions(now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{subscription.ID}, ids)
+
+		withinTx(t, r, func(tx Tx) error {
+			locked, ok, err := r.LockDueSubscription(tx, subscription.ID, now)
+			if err != nil || !ok {
+				return fmt.Errorf("subscription is not locked: %v", err)
+			}
+
+			locked.Status = models.SubscriptionCancelled
+			locked.CancelledAt = &now
+			if err := r.UpdateSubscription(tx, locked); err != nil {
+				return err
+			}
+
+			return r.AddEvent(tx, models.SubscriptionEvent{SubscriptionId: locked.ID, UserId: 1, PlanId: plan.ID,
+				Type: models.SubscriptionEnded, Currency: "EUR", Date: now})
+		})
+
+		ids, err = r.DueSubscriptions(now, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+
+		got, err := r.GetSubscription(subscription.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.SubscriptionCancelled, got.Status)
+		assert.Len(t, got.Events, 1)
+
+		subscriptions, err := r.ListSubscriptions(1, models.SubscriptionActive)
+		assert.NoError(t, err)
+		assert.Empty(t, subscriptions)
+
+		events, err := r.GetSubscriptionEvents(0, 10)
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+
+		err = r.WithinTx(func(tx Tx) error {
+			return r.AddEvent(tx, models.SubscriptionEvent{SubscriptionId: subscription.ID + 1, UserId: 1,
+				PlanId: plan.ID, Type: models.SubscriptionEnded, Currency: "EUR", Date: now})
+		})
+		assert.Error(t, err)
+
+		_, err = r.GetSubscription(subscription.ID + 1)
+		assert.ErrorIs(t, err, models.ErrSubscriptionNotFound)
+	})
+}
+
+// testVoucherConformance checks behaviour every Voucher implementation must have
+func testVoucherConformance(t *testing.T, newRepo repoFactory) {
+	createBatch := func(r *Repo, hashes ...string) (models.VoucherBatch, error) {
+		var batch models.VoucherBatch
+		err := r.WithinTx(func(tx Tx) error {
+			var err error
+			batch, err = r.CreateBatch(tx, models.VoucherBatch{Amount: 5, Currency: "EUR", MaxUses: 2}, hashes)
+			return err
+		})
+
+		return batch, err
+	}
+
+	redeem := func(r *Repo, hash string, user int) error {
+		return r.WithinTx(func(tx Tx) error {
+			voucher, err := r.LockVoucher(tx, hash)
+			if err != nil {
+				return err
+			}
+
+			_, err = r.Redeem(tx, voucher, user)
+			return err
+		})
+	}
+
+	t.Run("Redeem", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+
+		batch, err := createBatch(r, "a", "b")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, batch.Count)
+
+		assert.NoError(t, redeem(r, "a", 1))
+		assert.ErrorIs(t, redeem(r, "a", 1), models.ErrVoucherNotRedeemable)
+		assert.NoError(t, redeem(r, "a", 2))
+		assert.ErrorIs(t, redeem(r, "c", 1), models.ErrVoucherNotFound)
+
+		withinTx(t, r, func(tx Tx) error {
+			voucher, err := r.LockVoucher(tx, "a")
+			if err != nil {
+				return err
+			}
+
+			assert.Equal(t, 2, voucher.Uses)
+			assert.Equal(t, float32(5), voucher.Amount)
+			return nil
+		})
+
+		report, err := r.GetVoucherReport("EUR", time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, float32(20), report.IssuedValue)
+		assert.Equal(t, float32(10), report.RedeemedValue)
+		assert.Equal(t, float32(10), report.OutstandingValue)
+	})
+
+	t.Run("Codes are unique", func(t *testing.T) {
+		r := newRepo(t, 1)
+
+		_, err := createBatch(r, "a")
+		assert.NoError(t, err)
+
+		_, err = createBatch(r, "b", "a")
+		assert.Error(t, err)
+		assert.ErrorIs(t, redeem(r, "b", 1), models.ErrVoucherNotFound)
+	})
+}
+
+// testEscrowConformance checks behaviour every Escrow implementation must have
+func testEscrowConformance(t *testing.T, newRepo repoFactory) {
+	createEscrow := func(r *Repo, sender, recipient int, expiresAt time.Time) (models.Escrow, error) {
+		var escrow models.Escrow
+		err := r.WithinTx(func(tx Tx) error {
+			var err error
+			escrow, err = r.CreateEscrow(tx, models.Escrow{SenderId: sender, RecipientId: recipient, Amount: 5,
+				Currency: "EUR", Status: models.EscrowHeld, ExpiresAt: expiresAt})
+			if err != nil {
+				return err
+			}
+
+			return r.AddEscrowEvent(tx, models.EscrowEvent{EscrowId: escrow.ID, Type: models.EscrowCreated,
+				UserId: sender, Status: models.EscrowHeld, Date: time.Now()})
+		})
+
+		return escrow, err
+	}
+
+	t.Run("Create and close", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+		now := time.Now()
+
+		escrow, err := createEscrow(r, 1, 2, now.Add(time.Hour))
+		assert.NoError(t, err)
+
+		_, err = createEscrow(r, 1, 3, now.Add(time.Hour))
+		assert.EqualError(t, err, "user not found")
+
+		withinTx(t, r, func(tx Tx) error {
+			locked, err := r.LockEscrow(tx, escrow.ID)
+			if err != nil {
+				return err
+			}
+
+			locked.Status = models.EscrowReleased
+			locked.ClosedAt = &now
+			return r.CloseEscrow(tx, locked, models.EscrowEvent{EscrowId: locked.ID, Type: models.EscrowConfirmed,
+				UserId: 1, Status: models.EscrowReleased, Date: now})
+		})
+
+		got, err := r.GetEscrow(escrow.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.EscrowReleased, got.Status)
+		if assert.Len(t, got.Events, 2) {
+			assert.Equal(t, models.EscrowCreated, got.Events[0].Type)
+			assert.Equal(t, models.EscrowConfirmed, got.Events[1].Type)
+		}
+
+		escrows, err := r.ListEscrows(2, "")
+		assert.NoError(t, err)
+		assert.Len(t, escrows, 1)
+
+		escrows, err = r.ListEscrows(2, models.EscrowHeld)
+		assert.NoError(t, err)
+		assert.Empty(t, escrows)
+
+		_, err = r.GetEscrow(escrow.ID + 1)
+		assert.ErrorIs(t, err, models.ErrEscrowNotFound)
+
+		err = r.WithinTx(func(tx Tx) error {
+			return r.AddEscrowEvent(tx, models.EscrowEvent{EscrowId: escrow.ID + 1, Type: models.EscrowConfirmed,
+				Status: models.EscrowReleased, Date: now})
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Expired escrows", func(t *testing.T) {
+		r := newRepo(t, 1, 2)
+		now := time.Now()
+
+		later, err := createEscrow(r, 1, 2, now.Add(-time.Minute))
+		assert.NoError(t, err)
+		earlier, err := createEscrow(r, 1, 2, now.Add(-time.Hour))
+		assert.NoError(t, err)
+		held, err := createEscrow(r, 1, 2, now.Add(time.Hour))
+		assert.NoError(t, err)
+
+		ids, err := r.ExpiredEscrows(now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{earlier.ID, later.ID}, ids)
+
+		withinTx(t, r, func(tx Tx) error {
+			if _, ok, err := r.LockExpiredEscrow(tx, held.ID, now); err != nil || ok {
+				return fmt.Errorf("escrow which is not expired is locked: %v", err)
+			}
+
+			if _, ok, err := r.LockExpiredEscrow(tx, earlier.ID, now); err != nil || !ok {
+				return fmt.Errorf("expired escrow is not locked: %v", err)
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,303 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type CreditLine interface {
+	// SetCreditLimit saves credit limit of wallet, the wallet is opened if it does not exist. Limit lowered
+	// below the overdraft in use only forbids further debits
+	SetCreditLimit(input models.CreditLimitInput) (models.CreditLine, error)
+	// GetCreditLines returns wallets of user with their credit limits
+	GetCreditLines(userId int) ([]models.CreditLine, error)
+	// GetOverdraftReport returns overdrawn wallets of the currency
+	GetOverdraftReport(currency string) (models.OverdraftReport, error)
+	// PlaceHold reserves available money of the wallet as a part of tx
+	PlaceHold(tx Tx, input models.HoldInput) (models.Hold, error)
+	GetHold(id int) (models.Hold, error)
+	// ListHolds returns holds of user with the status, all statuses if it is empty
+	ListHolds(userId int, status string) ([]models.Hold, error)
+	// LockHold locks hold until tx ends
+	LockHold(tx Tx, id int) (models.Hold, error)
+	// CloseHold saves status of captured or released hold and returns its money to available balance
+	// as a part of tx
+	CloseHold(tx Tx, hold models.Hold) error
+	// OverdrawnWallets returns at most limit wallets with negative balance which were not charged on day
+	OverdrawnWallets(day time.Time, limit int) ([]models.Wallet, error)
+	// LockOverdrawnWallet locks wallet until tx ends if it is still overdrawn and not charged on day, false
+	// is returned otherwise or if another unit of work holds the lock
+	LockOverdrawnWallet(tx Tx, userId int, currency string, day time.Time) (models.CreditLine, bool, error)
+	// AddOverdraftCharge saves charge as a part of tx
+	AddOverdraftCharge(tx Tx, charge models.OverdraftCharge) error
+}
+
+type CreditLineRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewCreditLineRepo(db *sqlx.DB, log logging.Logger) *CreditLineRepo {
+	return &CreditLineRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// creditLineColumns are selected from wallets aliased as w, overdraft is the debt of real money which promo
+// never repays
+var creditLineColumns = fmt.Sprintf(`w.user_id, w.currency, w.balance, w.credit_limit, w.held,
+	w.balance - w.promo + w.credit_limit - w.held AS available, GREATEST(w.promo - w.balance, 0) AS overdraft,
+	COALESCE((SELECT SUM(c.amount) FROM %s c WHERE c.user_id = w.user_id AND c.currency = w.currency), 0) AS charged`,
+	overdraftChargesTable)
+
+// holdColumns are selected for every hold
+const holdColumns = "id, user_id, amount, currency, comment, status, created_at, closed_at"
+
+func (r *CreditLineRepo) SetCreditLimit(input models.CreditLimitInput) (models.CreditLine, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, currency, balance, credit_limit) SELECT id, $2, 0, $3 FROM %s
+		WHERE id = $1 ON CONFLICT (user_id, currency) DO UPDATE SET credit_limit = EXCLUDED.credit_limit`,
+		walletsTable, usersTable)
+
+	res, err := r.db.Exec(query, input.UserId, input.Currency, input.CreditLimit)
+	if err != nil {
+		return models.CreditLine{}, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return models.CreditLine{}, err
+	}
+
+	if affected == 0 {
+		return models.CreditLine{}, errors.New("user not found")
+	}
+
+	var line models.CreditLine
+	query = fmt.Sprintf("SELECT %s FROM %s w WHERE w.user_id = $1 AND w.currency = $2", creditLineColumns, walletsTable)
+	if err := r.db.Get(&line, query, input.UserId, input.Currency); err != nil {
+		return models.CreditLine{}, err
+	}
+
+	r.log.LogRepo("POST", "SetCreditLimit", true, line)
+	return line, nil
+}
+
+func (r *CreditLineRepo) GetCreditLines(userId int) ([]models.CreditLine, error) {
+	lines := []models.CreditLine{}
+	query := fmt.Sprintf("SELECT %s FROM %s w WHERE w.user_id = $1 ORDER BY w.currency", creditLineColumns,
+		walletsTable)
+	if err := r.db.Select(&lines, query, userId); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "GetCreditLines", true, lines)
+	return lines, nil
+}
+
+func (r *CreditLineRepo) GetOverdraftReport(currency string) (models.OverdraftReport, error) {
+	report := models.OverdraftReport{Currency: currency, Wallets: []models.CreditLine{}}
+	query := fmt.Sprintf(`SELECT %s FROM %s w WHERE w.currency = $1 AND w.balance - w.promo < 0
+		ORDER BY w.balance - w.promo, w.user_id`, creditLineColumns, walletsTable)
+	if err := r.db.Select(&report.Wallets, query, currency); err != nil {
+		return models.OverdraftReport{}, err
+	}
+
+	for _, line := range report.Wallets {
+		report.TotalOverdraft += line.Overdraft
+		report.TotalCharged += line.Charged
+	}
+
+	r.log.LogRepo("GET", "GetOverdraftReport", true, report)
+	return report, nil
+}
+
+// PlaceHold locks the user and the wallet like debits do, so money can not be held and debited twice
+func (r *CreditLineRepo) PlaceHold(unit Tx, input models.HoldInput) (models.Hold, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	if err := lockUser(input.UserId, tx); err != nil {
+		return models.Hold{}, err
+	}
+
+	var (
+		available float32
+		status    string
+	)
+
+	check := fmt.Sprintf(`SELECT w.balance - w.promo + w.credit_limit - w.held, u.status
+		FROM %s w JOIN %s u ON u.id = w.user_id WHERE w.user_id = $1 AND w.currency = $2 FOR UPDATE OF w`,
+		walletsTable, usersTable)
+	err = tx.QueryRow(check, input.UserId, input.Currency).Scan(&available, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Hold{}, errors.New("not enough money to perform purchase")
+		}
+
+		return models.Hold{}, err
+	}
+
+	// money is held only on accounts accepting debits, it is debited when the hold is captured
+	if status != models.AccountActive {
+		return models.Hold{}, fmt.Errorf("account is %s", strings.ReplaceAll(status, "_", " "))
+	}
+
+	if available-input.Amount < 0 {
+		return models.Hold{}, errors.New("not enough money to perform purchase")
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET held = held + $3 WHERE user_id = $1 AND currency = $2", walletsTable)
+	if _, err := tx.Exec(update, input.UserId, input.Currency, input.Amount); err != nil {
+		return models.Hold{}, err
+	}
+
+	hold := models.Hold{
+		UserId:   input.UserId,
+		Amount:   input.Amount,
+		Currency: input.Currency,
+		Comment:  input.Comment,
+		Status:   models.HoldActive,
+	}
+
+	insert := fmt.Sprintf(`INSERT INTO %s (user_id, amount, currency, comment, status) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`, holdsTable)
+	err = tx.QueryRow(insert, hold.UserId, hold.Amount, hold.Currency, hold.Comment, hold.Status).
+		Scan(&hold.ID, &hold.CreatedAt)
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	return hold, nil
+}
+
+func (r *CreditLineRepo) GetHold(id int) (models.Hold, error) {
+	var hold models.Hold
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", holdColumns, holdsTable)
+	if err := r.db.Get(&hold, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Hold{}, models.ErrHoldNotFound
+		}
+
+		return models.Hold{}, err
+	}
+
+	r.log.LogRepo("GET", "GetHold", true, hold)
+	return hold, nil
+}
+
+func (r *CreditLineRepo) ListHolds(userId int, status string) ([]models.Hold, error) {
+	holds := []models.Hold{}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = $1 AND ($2 = '' OR status = $2) ORDER BY id",
+		holdColumns, holdsTable)
+	if err := r.db.Select(&holds, query, userId, status); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListHolds", true, holds)
+	return holds, nil
+}
+
+func (r *CreditLineRepo) LockHold(unit Tx, id int) (models.Hold, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	var hold models.Hold
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1 FOR UPDATE", holdColumns, holdsTable)
+	err = tx.QueryRow(query, id).Scan(&hold.ID, &hold.UserId, &hold.Amount, &hold.Currency, &hold.Comment,
+		&hold.Status, &hold.CreatedAt, &hold.ClosedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Hold{}, models.ErrHoldNotFound
+		}
+
+		return models.Hold{}, err
+	}
+
+	return hold, nil
+}
+
+func (r *CreditLineRepo) CloseHold(unit Tx, hold models.Hold) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET status = $2, closed_at = $3 WHERE id = $1", holdsTable)
+	if _, err := tx.Exec(query, hold.ID, hold.Status, hold.ClosedAt); err != nil {
+		return err
+	}
+
+	release := fmt.Sprintf("UPDATE %s SET held = held - $3 WHERE user_id = $1 AND currency = $2", walletsTable)
+	_, err = tx.Exec(release, hold.UserId, hold.Currency, hold.Amount)
+	return err
+}
+
+func (r *CreditLineRepo) OverdrawnWallets(day time.Time, limit int) ([]models.Wallet, error) {
+	wallets := []models.Wallet{}
+	query := fmt.Sprintf(`SELECT w.user_id, w.currency, w.balance, w.promo FROM %s w WHERE w.balance - w.promo < 0
+		AND NOT EXISTS
+		(SELECT 1 FROM %s c WHERE c.user_id = w.user_id AND c.currency = w.currency AND c.date = $1)
+		ORDER BY w.user_id, w.currency LIMIT $2`, walletsTable, overdraftChargesTable)
+	if err := r.db.Select(&wallets, query, day, limit); err != nil {
+		return nil, err
+	}
+
+	return wallets, nil
+}
+
+// LockOverdrawnWallet skips locked wallet, its overdraft is charged on the next poll if it is still negative.
+// The user is locked first, the charge locks it again like any debit
+func (r *CreditLineRepo) LockOverdrawnWallet(unit Tx, userId int, currency string,
+	day time.Time) (models.CreditLine, bool, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.CreditLine{}, false, err
+	}
+
+	if err := lockUser(userId, tx); err != nil {
+		return models.CreditLine{}, false, err
+	}
+
+	var promo float32
+	line := models.CreditLine{UserId: userId, Currency: currency}
+	query := fmt.Sprintf(`SELECT w.balance, w.promo, w.credit_limit, w.held FROM %s w
+		WHERE w.user_id = $1 AND w.currency = $2 AND w.balance - w.promo < 0 AND NOT EXISTS
+		(SELECT 1 FROM %s c WHERE c.user_id = w.user_id AND c.currency = w.currency AND c.date = $3)
+		FOR UPDATE SKIP LOCKED`, walletsTable, overdraftChargesTable)
+	err = tx.QueryRow(query, userId, currency, day).Scan(&line.Balance, &promo, &line.CreditLimit, &line.Held)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.CreditLine{}, false, nil
+		}
+
+		return models.CreditLine{}, false, err
+	}
+
+	line.Available = line.Balance - promo + line.CreditLimit - line.Held
+	line.Overdraft = promo - line.Balance
+	return line, true, nil
+}
+
+func (r *CreditLineRepo) AddOverdraftCharge(unit Tx, charge models.OverdraftCharge) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (user_id, currency, date, balance, amount) VALUES ($1, $2, $3, $4, $5)",
+		overdraftChargesTable)
+	_, err = tx.Exec(query, charge.UserId, charge.Currency, charge.Date, charge.Balance, charge.Amount)
+	return err
+}
@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreditLineRepository_PlaceHold(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewCreditLineRepo(sqlxDB, logger)
+
+	createdAt := time.Date(2023, 8, 15, 12, 0, 0, 0, time.UTC)
+	input := models.HoldInput{UserId: 1, Amount: 30, Currency: "EUR", Comment: "hotel"}
+	lockUser := func() {
+		mock.ExpectExec(fmt.Sprintf("SELECT 1 FROM %s WHERE (.+) FOR SHARE", usersTable)).WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	expectWallet := func(available float32, status string) {
+		lockUser()
+		mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s w JOIN %s u (.+) FOR UPDATE OF w", walletsTable, usersTable)).
+			WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"available", "status"}).AddRow(available, status))
+	}
+
+	tests := []struct {
+		name      string
+		mock      func()
+		want      models.Hold
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				expectWallet(40, models.AccountActive)
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET held = held (.+)", walletsTable)).
+					WithArgs(1, "EUR", input.Amount).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) RETURNING id, created_at", holdsTable)).
+					WithArgs(1, input.Amount, "EUR", "hotel", models.HoldActive).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, createdAt))
+				mock.ExpectCommit()
+			},
+			want: models.Hold{ID: 1, UserId: 1, Amount: 30, Currency: "EUR", Comment: "hotel",
+				Status: models.HoldActive, CreatedAt: createdAt},
+		},
+		{
+			name: "Not enough money",
+			mock: func() {
+				expectWallet(20, models.AccountActive)
+				mock.ExpectRollback()
+			},
+			wantedErr: "not enough money to perform purchase",
+		},
+		{
+			name: "Account is debit blocked",
+			mock: func() {
+				expectWallet(40, models.AccountDebitBlocked)
+				mock.ExpectRollback()
+			},
+			wantedErr: "account is debit blocked",
+		},
+		{
+			name: "Wallet does not exist",
+			mock: func() {
+				lockUser()
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s w JOIN %s u (.+) FOR UPDATE OF w", walletsTable,
+					usersTable)).WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"available", "status"}))
+				mock.ExpectRollback()
+			},
+			wantedErr: "not enough money to perform purchase",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+
+			var got models.Hold
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, err = r.PlaceHold(tx, input)
+				return err
+			})
+
+			if tt.wantedErr != "" {
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreditLineRepository_LockOverdrawnWallet(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewCreditLineRepo(sqlxDB, logger)
+	day := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		want   models.CreditLine
+		wantOk bool
+	}{
+		{
+			name: "Overdrawn",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"balance", "promo", "credit_limit", "held"}).AddRow(-40, 0, 100, 10)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s w WHERE (.+) FOR UPDATE SKIP LOCKED", walletsTable)).
+					WithArgs(1, "EUR", day).WillReturnRows(rows)
+			},
+			want: models.CreditLine{UserId: 1, Currency: "EUR", Balance: -40, CreditLimit: 100, Held: 10,
+				Available: 50, Overdraft: 40},
+			wantOk: true,
+		},
+		{
+			// promo granted to overdrawn wallet does not repay its debt
+			name: "Overdrawn with promo",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"balance", "promo", "credit_limit", "held"}).AddRow(0, 30, 100, 0)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s w WHERE (.+) FOR UPDATE SKIP LOCKED", walletsTable)).
+					WithArgs(1, "EUR", day).WillReturnRows(rows)
+			},
+			want: models.CreditLine{UserId: 1, Currency: "EUR", Balance: 0, CreditLimit: 100, Held: 0,
+				Available: 70, Overdraft: 30},
+			wantOk: true,
+		},
+		{
+			name: "Charged, repaid or locked",
+			mock: func() {
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s w WHERE (.+) FOR UPDATE SKIP LOCKED", walletsTable)).
+					WithArgs(1, "EUR", day).WillReturnRows(sqlmock.NewRows([]string{"balance", "promo", "credit_limit",
+					"held"}))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			// the user is locked before the wallet like by debits, so the charge never deadlocks with them
+			mock.ExpectExec(fmt.Sprintf("SELECT 1 FROM %s WHERE (.+) FOR SHARE", usersTable)).WithArgs(1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			tt.mock()
+			mock.ExpectCommit()
+
+			var (
+				got models.CreditLine
+				ok  bool
+			)
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, ok, err = r.LockOverdrawnWallet(tx, 1, "EUR", day)
+				return err
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,15 +1,43 @@
 package repo
 
 import (
-	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
 const (
-	usersTable        = "users"
-	transactionsTable = "transactions"
+	usersTable                = "users"
+	walletsTable              = "wallets"
+	transactionsTable         = "transactions"
+	exchangeQuotesTable       = "exchange_quotes"
+	idempotencyKeysTable      = "idempotency_keys"
+	importJobsTable           = "import_jobs"
+	adjustmentsTable          = "adjustments"
+	adjustmentEventsTable     = "adjustment_events"
+	accountStatusHistoryTable = "account_status_history"
+	reconciliationTable       = "reconciliation_reports"
+	schedulesTable            = "schedules"
+	scheduleRunsTable         = "schedule_runs"
+	plansTable                = "plans"
+	subscriptionsTable        = "subscriptions"
+	subscriptionEventsTable   = "subscription_events"
+	spendingLimitsTable       = "spending_limits"
+	spendsTable               = "spends"
+	holdsTable                = "holds"
+	overdraftChargesTable     = "overdraft_charges"
+	feeRulesTable             = "fee_rules"
+	promoGrantsTable          = "promo_grants"
+	voucherBatchesTable       = "voucher_batches"
+	vouchersTable             = "vouchers"
+	voucherRedemptionsTable   = "voucher_redemptions"
+	escrowsTable              = "escrows"
+	escrowEventsTable         = "escrow_events"
+	outboxEventsTable         = "outbox_events"
 )
 
 type Config struct {
@@ -19,15 +47,62 @@ type Config struct {
 	Port     string
 	Name     string
 	SSL      string
+	// MaxOpenConns limits connections of the pool, zero means unlimited
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout aborts statements running longer, zero disables it
+	StatementTimeout time.Duration
+	// ReplicaHost is the host of read replica, it is not used when empty
+	ReplicaHost string
+	// ReplicaPort defaults to Port
+	ReplicaPort string
+}
+
+// DSN builds connection url, user, password and database name are escaped
+func (cfg Config) DSN() string {
+	query := url.Values{}
+	query.Set("sslmode", cfg.SSL)
+	if cfg.StatementTimeout > 0 {
+		query.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.Username, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, cfg.Port),
+		Path:     "/" + cfg.Name,
+		RawQuery: query.Encode(),
+	}
+
+	return dsn.String()
 }
 
 func InitDB(cfg Config) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("postgres", fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s", cfg.Host, cfg.Port, cfg.Username, cfg.Name, cfg.Password, cfg.SSL))
+	db, err := sqlx.Connect("postgres", cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
 
-	db.SetMaxIdleConns(0)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	return db, nil
 }
+
+// InitReplica connects to read replica with the settings of primary, it returns nil if no replica is configured
+func InitReplica(cfg Config) (*sqlx.DB, error) {
+	if cfg.ReplicaHost == "" {
+		return nil, nil
+	}
+
+	cfg.Host = cfg.ReplicaHost
+	if cfg.ReplicaPort != "" {
+		cfg.Port = cfg.ReplicaPort
+	}
+
+	return InitDB(cfg)
+}
@@ -0,0 +1,181 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Escrow interface {
+	// CreateEscrow saves held escrow as a part of tx, the sender and the recipient must exist
+	CreateEscrow(tx Tx, escrow models.Escrow) (models.Escrow, error)
+	// GetEscrow returns escrow with its events
+	GetEscrow(id int) (models.Escrow, error)
+	// ListEscrows returns escrows the user sent or receives with the status, all statuses if it is empty
+	ListEscrows(userId int, status string) ([]models.Escrow, error)
+	// ExpiredEscrows returns ids of at most limit held escrows expired at now
+	ExpiredEscrows(now time.Time, limit int) ([]int, error)
+	// LockEscrow locks escrow until tx ends, it waits for the unit of work holding the lock
+	LockEscrow(tx Tx, id int) (models.Escrow, error)
+	// LockExpiredEscrow locks escrow until tx ends if it is still held and expired, false is returned
+	// if it is not or another unit of work holds the lock
+	LockExpiredEscrow(tx Tx, id int, now time.Time) (models.Escrow, bool, error)
+	// CloseEscrow saves status of escrow and its event as a part of tx
+	CloseEscrow(tx Tx, escrow models.Escrow, event models.EscrowEvent) error
+	// AddEscrowEvent saves event of escrow as a part of tx
+	AddEscrowEvent(tx Tx, event models.EscrowEvent) error
+}
+
+type EscrowRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewEscrowRepo(db *sqlx.DB, log logging.Logger) *EscrowRepo {
+	return &EscrowRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// escrowColumns are selected for every escrow
+const escrowColumns = `id, sender_id, recipient_id, amount, currency, description, status, expires_at, created_at,
+	closed_at`
+
+func (r *EscrowRepo) CreateEscrow(unit Tx, escrow models.Escrow) (models.Escrow, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Escrow{}, err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (sender_id, recipient_id, amount, currency, description, status, expires_at)
+		SELECT s.id, r.id, $3, $4, $5, $6, $7 FROM %s s JOIN %s r ON r.id = $2 WHERE s.id = $1
+		RETURNING id, created_at`, escrowsTable, usersTable, usersTable)
+	err = tx.QueryRow(query, escrow.SenderId, escrow.RecipientId, escrow.Amount, escrow.Currency, escrow.Description,
+		escrow.Status, escrow.ExpiresAt).Scan(&escrow.ID, &escrow.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Escrow{}, errors.New("user not found")
+		}
+
+		return models.Escrow{}, err
+	}
+
+	return escrow, nil
+}
+
+func (r *EscrowRepo) GetEscrow(id int) (models.Escrow, error) {
+	var escrow models.Escrow
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", escrowColumns, escrowsTable)
+	if err := r.db.Get(&escrow, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Escrow{}, models.ErrEscrowNotFound
+		}
+
+		return models.Escrow{}, err
+	}
+
+	events := fmt.Sprintf("SELECT id, escrow_id, type, user_id, status, date FROM %s WHERE escrow_id = $1 ORDER BY id",
+		escrowEventsTable)
+	if err := r.db.Select(&escrow.Events, events, id); err != nil {
+		return models.Escrow{}, err
+	}
+
+	r.log.LogRepo("GET", "GetEscrow", true, escrow)
+	return escrow, nil
+}
+
+func (r *EscrowRepo) ListEscrows(userId int, status string) ([]models.Escrow, error) {
+	escrows := []models.Escrow{}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE (sender_id = $1 OR recipient_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY id`, escrowColumns, escrowsTable)
+	if err := r.db.Select(&escrows, query, userId, status); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListEscrows", true, escrows)
+	return escrows, nil
+}
+
+func (r *EscrowRepo) ExpiredEscrows(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	query := fmt.Sprintf("SELECT id FROM %s WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at, id LIMIT $3",
+		escrowsTable)
+	if err := r.db.Select(&ids, query, models.EscrowHeld, now, limit); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *EscrowRepo) LockEscrow(tx Tx, id int) (models.Escrow, error) {
+	escrow, ok, err := r.lock(tx, fmt.Sprintf("SELECT %s FROM %s WHERE id = $1 FOR UPDATE", escrowColumns,
+		escrowsTable), id)
+	if err != nil {
+		return models.Escrow{}, err
+	}
+
+	if !ok {
+		return models.Escrow{}, models.ErrEscrowNotFound
+	}
+
+	return escrow, nil
+}
+
+func (r *EscrowRepo) LockExpiredEscrow(tx Tx, id int, now time.Time) (models.Escrow, bool, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND status = $2 AND expires_at <= $3
+		FOR UPDATE SKIP LOCKED`, escrowColumns, escrowsTable)
+
+	return r.lock(tx, query, id, models.EscrowHeld, now)
+}
+
+func (r *EscrowRepo) lock(unit Tx, query string, args ...interface{}) (models.Escrow, bool, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Escrow{}, false, err
+	}
+
+	var e models.Escrow
+	err = tx.QueryRow(query, args...).Scan(&e.ID, &e.SenderId, &e.RecipientId, &e.Amount, &e.Currency,
+		&e.Description, &e.Status, &e.ExpiresAt, &e.CreatedAt, &e.ClosedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Escrow{}, false, nil
+		}
+
+		return models.Escrow{}, false, err
+	}
+
+	return e, true, nil
+}
+
+func (r *EscrowRepo) CloseEscrow(unit Tx, escrow models.Escrow, event models.EscrowEvent) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET status = $2, closed_at = $3 WHERE id = $1", escrowsTable)
+	if _, err := tx.Exec(query, escrow.ID, escrow.Status, escrow.ClosedAt); err != nil {
+		return err
+	}
+
+	return r.AddEscrowEvent(unit, event)
+}
+
+func (r *EscrowRepo) AddEscrowEvent(unit Tx, event models.EscrowEvent) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (escrow_id, type, user_id, status, date) VALUES ($1, $2, $3, $4, $5)",
+		escrowEventsTable)
+	_, err = tx.Exec(query, event.EscrowId, event.Type, event.UserId, event.Status, event.Date)
+	return err
+}
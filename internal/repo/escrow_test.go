@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscrowRepository_CreateEscrow(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewEscrowRepo(sqlxDB, logger)
+	createdAt := time.Date(2023, 9, 5, 12, 0, 0, 0, time.UTC)
+	escrow := models.Escrow{SenderId: 1, RecipientId: 2, Amount: 40, Currency: "EUR", Description: "order 17",
+		Status: models.EscrowHeld, ExpiresAt: createdAt.Add(24 * time.Hour)}
+	query := fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s s JOIN %s r (.+) RETURNING id, created_at",
+		escrowsTable, usersTable, usersTable)
+
+	tests := []struct {
+		name      string
+		mock      func()
+		want      models.Escrow
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectQuery(query).WithArgs(1, 2, escrow.Amount, "EUR", "order 17", models.EscrowHeld,
+					escrow.ExpiresAt).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, createdAt))
+				mock.ExpectCommit()
+			},
+			want: models.Escrow{ID: 3, SenderId: 1, RecipientId: 2, Amount: 40, Currency: "EUR",
+				Description: "order 17", Status: models.EscrowHeld, ExpiresAt: escrow.ExpiresAt, CreatedAt: createdAt},
+		},
+		{
+			name: "User does not exist",
+			mock: func() {
+				mock.ExpectQuery(query).WithArgs(1, 2, escrow.Amount, "EUR", "order 17", models.EscrowHeld,
+					escrow.ExpiresAt).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+
+			var got models.Escrow
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, err = r.CreateEscrow(tx, escrow)
+				return err
+			})
+
+			if tt.wantedErr != "" {
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestEscrowRepository_LockExpiredEscrow(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewEscrowRepo(sqlxDB, logger)
+	now := time.Date(2023, 9, 5, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "sender_id", "recipient_id", "amount", "currency", "description", "status",
+		"expires_at", "created_at", "closed_at"}
+	query := fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE SKIP LOCKED", escrowsTable)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		want   models.Escrow
+		wantOk bool
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectQuery(query).WithArgs(3, models.EscrowHeld, now).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(3, 1, 2, 40, "EUR", "", models.EscrowHeld, now, now, nil))
+			},
+			want: models.Escrow{ID: 3, SenderId: 1, RecipientId: 2, Amount: 40, Currency: "EUR",
+				Status: models.EscrowHeld, ExpiresAt: now, CreatedAt: now},
+			wantOk: true,
+		},
+		{
+			name: "Closed or locked",
+			mock: func() {
+				mock.ExpectQuery(query).WithArgs(3, models.EscrowHeld, now).WillReturnError(sql.ErrNoRows)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+			mock.ExpectCommit()
+
+			var (
+				got models.Escrow
+				ok  bool
+			)
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, ok, err = r.LockExpiredEscrow(tx, 3, now)
+				return err
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Exchange interface {
+	CreateQuote(quote models.ExchangeQuote) (models.ExchangeQuote, error)
+	Exchange(tx Tx, input models.ExchangeInput, revenueAccount int) (models.ExchangeResult, error)
+}
+
+type ExchangeRepo struct {
+	db   *sqlx.DB
+	user User
+	log  logging.Logger
+}
+
+func NewExchangeRepo(db *sqlx.DB, user User, log logging.Logger) *ExchangeRepo {
+	return &ExchangeRepo{
+		db:   db,
+		user: user,
+		log:  log,
+	}
+}
+
+func (r *ExchangeRepo) CreateQuote(quote models.ExchangeQuote) (models.ExchangeQuote, error) {
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, from_currency, to_currency, amount, rate, fee, to_amount, from_fee,
+		expires_at) SELECT id, $2, $3, $4, $5, $6, $7, $8, $9 FROM %s WHERE id = $1 RETURNING id`, exchangeQuotesTable, usersTable)
+
+	err := r.db.Get(&quote.ID, query, quote.UserId, quote.From, quote.To, quote.Amount,
+		quote.Rate, quote.Fee, quote.ToAmount, quote.FromFee, quote.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ExchangeQuote{}, errors.New("user not found")
+		}
+
+		return models.ExchangeQuote{}, err
+	}
+
+	r.log.LogRepo("POST", "CreateQuote", true, quote)
+	return quote, nil
+}
+
+// Exchange uses the quote as a part of tx: debits user`s from wallet with the fee of fee rules, credits to wallet
+// and credits both fees to revenueAccount. All transactions are linked by quote id
+func (r *ExchangeRepo) Exchange(tx Tx, input models.ExchangeInput, revenueAccount int) (models.ExchangeResult, error) {
+	dbTx, err := txOf(tx)
+	if err != nil {
+		return models.ExchangeResult{}, err
+	}
+
+	var quote models.ExchangeQuote
+	query := fmt.Sprintf(`SELECT user_id, from_currency, to_currency, amount, fee, to_amount, from_fee, expires_at,
+		used_at FROM %s WHERE id = $1 AND user_id = $2 FOR UPDATE`, exchangeQuotesTable)
+
+	err = dbTx.QueryRow(query, input.QuoteId, input.UserId).Scan(&quote.UserId, &quote.From, &quote.To,
+		&quote.Amount, &quote.Fee, &quote.ToAmount, &quote.FromFee, &quote.ExpiresAt, &quote.UsedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ExchangeResult{}, errors.New("quote not found")
+		}
+
+		return models.ExchangeResult{}, err
+	}
+
+	if quote.UsedAt != nil {
+		return models.ExchangeResult{}, errors.New("quote is already used")
+	}
+
+	now := time.Now()
+	if now.After(quote.ExpiresAt) {
+		return models.ExchangeResult{}, errors.New("quote is expired")
+	}
+
+	use := fmt.Sprintf("UPDATE %s SET used_at = $2 WHERE id = $1", exchangeQuotesTable)
+	if _, err := dbTx.Exec(use, input.QuoteId, now); err != nil {
+		return models.ExchangeResult{}, err
+	}
+
+	fromBalance, err := r.user.Debit(tx, models.Input{
+		UserId:   quote.UserId,
+		Amount:   quote.Amount,
+		Currency: quote.From,
+	}, models.Operation{
+		Comment: fmt.Sprintf("Exchange %f%s to %s", quote.Amount, quote.From, quote.To),
+		LinkId:  input.QuoteId,
+	})
+	if err != nil {
+		return models.ExchangeResult{}, err
+	}
+
+	if quote.FromFee > 0 {
+		fromBalance, err = r.user.Debit(tx, models.Input{
+			UserId:   quote.UserId,
+			Amount:   quote.FromFee,
+			Currency: quote.From,
+		}, models.Operation{
+			Comment: fmt.Sprintf("Exchange fee %f%s", quote.FromFee, quote.From),
+			LinkId:  input.QuoteId,
+		})
+		if err != nil {
+			return models.ExchangeResult{}, err
+		}
+
+		_, err = r.user.Credit(tx, models.Input{
+			UserId:   revenueAccount,
+			Amount:   quote.FromFee,
+			Currency: quote.From,
+		}, models.Operation{
+			Comment: fmt.Sprintf("Exchange fee %f%s", quote.FromFee, quote.From),
+			LinkId:  input.QuoteId,
+		})
+		if err != nil {
+			return models.ExchangeResult{}, err
+		}
+	}
+
+	toBalance, err := r.user.Credit(tx, models.Input{
+		UserId:   quote.UserId,
+		Amount:   quote.ToAmount,
+		Currency: quote.To,
+	}, models.Operation{
+		Comment: fmt.Sprintf("Exchange %f%s from %s", quote.ToAmount, quote.To, quote.From),
+		LinkId:  input.QuoteId,
+	})
+	if err != nil {
+		return models.ExchangeResult{}, err
+	}
+
+	if quote.Fee > 0 {
+		_, err = r.user.Credit(tx, models.Input{
+			UserId:   revenueAccount,
+			Amount:   quote.Fee,
+			Currency: quote.To,
+		}, models.Operation{
+			Comment: fmt.Sprintf("Exchange fee %f%s", quote.Fee, quote.To),
+			LinkId:  input.QuoteId,
+		})
+		if err != nil {
+			return models.ExchangeResult{}, err
+		}
+	}
+
+	return models.ExchangeResult{
+		UserId: quote.UserId,
+		LinkId: input.QuoteId,
+		From:   models.Wallet{UserId: quote.UserId, Currency: quote.From, Balance: fromBalance},
+		To:     models.Wallet{UserId: quote.UserId, Currency: quote.To, Balance: toBalance},
+	}, nil
+}
@@ -0,0 +1,327 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchangeRepository_CreateQuote(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewExchangeRepo(sqlxDB, NewUserRepo(sqlxDB, logger), logger)
+
+	type mockBehavior func(quote models.ExchangeQuote)
+
+	quote := models.ExchangeQuote{
+		UserId:    1,
+		From:      "EUR",
+		To:        "USD",
+		Amount:    100,
+		Rate:      1.1,
+		Fee:       1.1,
+		ToAmount:  108.9,
+		FromFee:   0.5,
+		ExpiresAt: time.Now().Add(30 * time.Second),
+	}
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		want      string
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func(quote models.ExchangeQuote) {
+				rows := sqlmock.NewRows([]string{"id"}).AddRow("7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10")
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s WHERE (.+) RETURNING id", exchangeQuotesTable, usersTable)).
+					WithArgs(quote.UserId, quote.From, quote.To, quote.Amount, quote.Rate, quote.Fee, quote.ToAmount, quote.FromFee,
+						quote.ExpiresAt).
+					WillReturnRows(rows)
+			},
+			want:    "7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10",
+			wantErr: false,
+		},
+		{
+			name: "User does not exist",
+			mock: func(quote models.ExchangeQuote) {
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s WHERE (.+) RETURNING id", exchangeQuotesTable, usersTable)).
+					WithArgs(quote.UserId, quote.From, quote.To, quote.Amount, quote.Rate, quote.Fee, quote.ToAmount, quote.FromFee,
+						quote.ExpiresAt).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:   true,
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(quote)
+
+			got, err := r.CreateQuote(quote)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got.ID)
+				assert.Equal(t, quote.ToAmount, got.ToAmount)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestExchangeRepository_Exchange(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewExchangeRepo(sqlxDB, NewUserRepo(sqlxDB, logger), logger)
+
+	type mockBehavior func(input models.ExchangeInput)
+
+	quoteColumns := []string{"user_id", "from_currency", "to_currency", "amount", "fee", "to_amount", "from_fee", "expires_at",
+		"used_at"}
+	input := models.ExchangeInput{
+		UserId:  1,
+		QuoteId: "7f1c5f3e-2a53-4a8e-9a8b-6f0e0c6d1a10",
+	}
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		want      models.ExchangeResult
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func(input models.ExchangeInput) {
+				mock.ExpectBegin()
+
+				quote := sqlmock.NewRows(quoteColumns).
+					AddRow(1, "EUR", "USD", 10, 0.11, 10.89, 0, time.Now().Add(time.Minute), nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, input.UserId).WillReturnRows(quote)
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET used_at", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+				date := time.Now().Format("01-02-2006 15:04:05")
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(10, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(1, "EUR", float32(10), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(1, float32(10), "EUR", fmt.Sprintf("Exchange %fEUR to USD", float32(10)), date, float32(0), input.QuoteId, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "USD").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(0, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(1, "USD", float32(10.89), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(1, float32(10.89), "USD", fmt.Sprintf("Exchange %fUSD from EUR", float32(10.89)), date, float32(10.89), input.QuoteId, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(2, 1))
+
+				expectStatus(mock, 0, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(0, "USD").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(5, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(0, "USD", float32(0.11), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(0, float32(0.11), "USD", fmt.Sprintf("Exchange fee %fUSD", float32(0.11)), date, float32(5.11), input.QuoteId, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+
+				mock.ExpectCommit()
+			},
+			want: models.ExchangeResult{
+				UserId: 1,
+				LinkId: input.QuoteId,
+				From:   models.Wallet{UserId: 1, Currency: "EUR", Balance: 0},
+				To:     models.Wallet{UserId: 1, Currency: "USD", Balance: 10.89},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Fee of fee rules",
+			mock: func(input models.ExchangeInput) {
+				mock.ExpectBegin()
+
+				quote := sqlmock.NewRows(quoteColumns).
+					AddRow(1, "EUR", "USD", 10, 0, 11, 0.5, time.Now().Add(time.Minute), nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, input.UserId).WillReturnRows(quote)
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET used_at", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+				date := time.Now().Format("01-02-2006 15:04:05")
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(10.5, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(1, "EUR", float32(10), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(1, float32(10), "EUR", fmt.Sprintf("Exchange %fEUR to USD", float32(10)), date, float32(0.5), input.QuoteId, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(0.5, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(1, "EUR", float32(0.5), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(1, float32(0.5), "EUR", fmt.Sprintf("Exchange fee %fEUR", float32(0.5)), date, float32(0), input.QuoteId, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(2, 1))
+
+				expectStatus(mock, 0, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(0, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(0, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(0, "EUR", float32(0.5), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(0, float32(0.5), "EUR", fmt.Sprintf("Exchange fee %fEUR", float32(0.5)), date, float32(0.5), input.QuoteId, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "USD").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(0, "", 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(1, "USD", float32(11), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(1, float32(11), "USD", fmt.Sprintf("Exchange %fUSD from EUR", float32(11)), date, float32(11), input.QuoteId, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(4, 1))
+
+				mock.ExpectCommit()
+			},
+			want: models.ExchangeResult{
+				UserId: 1,
+				LinkId: input.QuoteId,
+				From:   models.Wallet{UserId: 1, Currency: "EUR", Balance: 0},
+				To:     models.Wallet{UserId: 1, Currency: "USD", Balance: 11},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Quote does not exist",
+			mock: func(input models.ExchangeInput) {
+				mock.ExpectBegin()
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, input.UserId).WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "quote not found",
+		},
+		{
+			name: "Quote is expired",
+			mock: func(input models.ExchangeInput) {
+				mock.ExpectBegin()
+
+				quote := sqlmock.NewRows(quoteColumns).
+					AddRow(1, "EUR", "USD", 10, 0.11, 10.89, 0, time.Now().Add(-time.Minute), nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, input.UserId).WillReturnRows(quote)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "quote is expired",
+		},
+		{
+			name: "Quote is already used",
+			mock: func(input models.ExchangeInput) {
+				mock.ExpectBegin()
+
+				quote := sqlmock.NewRows(quoteColumns).
+					AddRow(1, "EUR", "USD", 10, 0.11, 10.89, 0, time.Now().Add(time.Minute), time.Now())
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, input.UserId).WillReturnRows(quote)
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "quote is already used",
+		},
+		{
+			name: "Not enough money",
+			mock: func(input models.ExchangeInput) {
+				mock.ExpectBegin()
+
+				quote := sqlmock.NewRows(quoteColumns).
+					AddRow(1, "EUR", "USD", 10, 0.11, 10.89, 0, time.Now().Add(time.Minute), nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, input.UserId).WillReturnRows(quote)
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET used_at", exchangeQuotesTable)).
+					WithArgs(input.QuoteId, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+				expectStatus(mock, 1, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(5, "", 0))
+
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "not enough money to perform purchase",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(input)
+
+			var got models.ExchangeResult
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, err = r.Exchange(tx, input, 0)
+				return err
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Fee interface {
+	CreateFeeRule(input models.FeeRuleInput) (models.FeeRule, error)
+	ListFeeRules(operation string) ([]models.FeeRule, error)
+	DeleteFeeRule(id int) error
+	// MatchFeeRule returns the most specific rule charging the operation, false when no rule applies
+	MatchFeeRule(input models.FeeQuoteInput) (models.FeeRule, bool, error)
+}
+
+type FeeRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewFeeRepo(db *sqlx.DB, log logging.Logger) *FeeRepo {
+	return &FeeRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// feeRuleColumns are selected for every fee rule
+const feeRuleColumns = "id, operation, user_id, currency, min_amount, max_amount, rate, fixed, created_at"
+
+func (r *FeeRepo) CreateFeeRule(input models.FeeRuleInput) (models.FeeRule, error) {
+	rule := models.FeeRule{
+		Operation: input.Operation,
+		UserId:    input.UserId,
+		Currency:  input.Currency,
+		MinAmount: input.MinAmount,
+		MaxAmount: input.MaxAmount,
+		Rate:      input.Rate,
+		Fixed:     input.Fixed,
+	}
+
+	// client rules are saved only for existing users
+	query := fmt.Sprintf(`INSERT INTO %s (operation, user_id, currency, min_amount, max_amount, rate, fixed)
+		SELECT $1, $2, $3, $4, $5, $6, $7 WHERE $2 = 0 OR EXISTS (SELECT 1 FROM %s WHERE id = $2)
+		RETURNING id, created_at`, feeRulesTable, usersTable)
+
+	err := r.db.QueryRow(query, rule.Operation, rule.UserId, rule.Currency, rule.MinAmount, rule.MaxAmount,
+		rule.Rate, rule.Fixed).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.FeeRule{}, errors.New("user not found")
+		}
+
+		return models.FeeRule{}, err
+	}
+
+	r.log.LogRepo("POST", "CreateFeeRule", true, rule)
+	return rule, nil
+}
+
+func (r *FeeRepo) ListFeeRules(operation string) ([]models.FeeRule, error) {
+	rules := []models.FeeRule{}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE $1 = '' OR operation = $1 ORDER BY id", feeRuleColumns,
+		feeRulesTable)
+	if err := r.db.Select(&rules, query, operation); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListFeeRules", true, rules)
+	return rules, nil
+}
+
+func (r *FeeRepo) DeleteFeeRule(id int) error {
+	res, err := r.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = $1", feeRulesTable), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return models.ErrFeeRuleNotFound
+	}
+
+	r.log.LogRepo("DELETE", "DeleteFeeRule", true, id)
+	return nil
+}
+
+// MatchFeeRule orders applying rules the same way as FeeRule.MoreSpecific does
+func (r *FeeRepo) MatchFeeRule(input models.FeeQuoteInput) (models.FeeRule, bool, error) {
+	var rule models.FeeRule
+	query := fmt.Sprintf(`SELECT %s FROM %s
+		WHERE operation = $1 AND user_id IN (0, $2) AND currency IN ('', $3)
+		AND min_amount <= $4 AND (max_amount = 0 OR $4 < max_amount)
+		ORDER BY user_id <> 0 DESC, currency <> '' DESC, min_amount DESC, id DESC LIMIT 1`,
+		feeRuleColumns, feeRulesTable)
+
+	err := r.db.Get(&rule, query, input.Operation, input.UserId, input.Currency, input.Amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.FeeRule{}, false, nil
+		}
+
+		return models.FeeRule{}, false, err
+	}
+
+	return rule, true, nil
+}
@@ -0,0 +1,132 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeRepository_CreateFeeRule(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewFeeRepo(sqlxDB, logger)
+	createdAt := time.Date(2023, 8, 20, 12, 0, 0, 0, time.UTC)
+	input := models.FeeRuleInput{Operation: models.FeeTransfer, UserId: 3, Currency: "EUR", MaxAmount: 1000,
+		Rate: 0.01, Fixed: 0.5}
+	query := fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) WHERE (.+) EXISTS (.+) FROM %s (.+) RETURNING id, created_at",
+		feeRulesTable, usersTable)
+
+	tests := []struct {
+		name      string
+		mock      func()
+		want      models.FeeRule
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectQuery(query).WithArgs(models.FeeTransfer, 3, "EUR", input.MinAmount, input.MaxAmount,
+					input.Rate, input.Fixed).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, createdAt))
+			},
+			want: models.FeeRule{ID: 1, Operation: models.FeeTransfer, UserId: 3, Currency: "EUR", MaxAmount: 1000,
+				Rate: 0.01, Fixed: 0.5, CreatedAt: createdAt},
+		},
+		{
+			name: "User does not exist",
+			mock: func() {
+				mock.ExpectQuery(query).WithArgs(models.FeeTransfer, 3, "EUR", input.MinAmount, input.MaxAmount,
+					input.Rate, input.Fixed).WillReturnError(sql.ErrNoRows)
+			},
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.CreateFeeRule(input)
+			if tt.wantedErr != "" {
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestFeeRepository_MatchFeeRule(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewFeeRepo(sqlxDB, logger)
+	createdAt := time.Date(2023, 8, 20, 12, 0, 0, 0, time.UTC)
+	input := models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 3, Amount: 100, Currency: "EUR"}
+	columns := []string{"id", "operation", "user_id", "currency", "min_amount", "max_amount", "rate", "fixed",
+		"created_at"}
+	query := fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) ORDER BY (.+) LIMIT 1", feeRulesTable)
+
+	tests := []struct {
+		name   string
+		mock   func()
+		want   models.FeeRule
+		wantOk bool
+	}{
+		{
+			name: "Matched",
+			mock: func() {
+				rows := sqlmock.NewRows(columns).AddRow(2, models.FeeTransfer, 3, "", 0, 0, 0, 0.1, createdAt)
+				mock.ExpectQuery(query).WithArgs(models.FeeTransfer, 3, "EUR", input.Amount).WillReturnRows(rows)
+			},
+			want:   models.FeeRule{ID: 2, Operation: models.FeeTransfer, UserId: 3, Fixed: 0.1, CreatedAt: createdAt},
+			wantOk: true,
+		},
+		{
+			name: "No rule applies",
+			mock: func() {
+				mock.ExpectQuery(query).WithArgs(models.FeeTransfer, 3, "EUR", input.Amount).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, ok, err := r.MatchFeeRule(input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
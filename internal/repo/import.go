@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Import interface {
+	CreateImportJob(job models.ImportJob) (models.ImportJob, error)
+	UpdateImportJob(job models.ImportJob) error
+	GetImportJob(id string) (models.ImportJob, error)
+}
+
+type ImportRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewImportRepo(db *sqlx.DB, log logging.Logger) *ImportRepo {
+	return &ImportRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *ImportRepo) CreateImportJob(job models.ImportJob) (models.ImportJob, error) {
+	query := fmt.Sprintf("INSERT INTO %s (status, total) VALUES ($1, $2) RETURNING id, created_at", importJobsTable)
+	err := r.db.QueryRow(query, job.Status, job.Total).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return models.ImportJob{}, err
+	}
+
+	r.log.LogRepo("POST", "CreateImportJob", true, job)
+	return job, nil
+}
+
+func (r *ImportRepo) UpdateImportJob(job models.ImportJob) error {
+	query := fmt.Sprintf(`UPDATE %s SET status = $2, processed = $3, applied = $4, failed = $5, errors = $6, finished_at = $7
+		WHERE id = $1`, importJobsTable)
+
+	res, err := r.db.Exec(query, job.ID, job.Status, job.Processed, job.Applied, job.Failed, job.Errors, job.FinishedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return errors.New("import job not found")
+	}
+
+	return nil
+}
+
+func (r *ImportRepo) GetImportJob(id string) (models.ImportJob, error) {
+	var job models.ImportJob
+	query := fmt.Sprintf(`SELECT id, status, total, processed, applied, failed, errors, created_at, finished_at
+		FROM %s WHERE id = $1`, importJobsTable)
+
+	err := r.db.Get(&job, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ImportJob{}, errors.New("import job not found")
+		}
+
+		return models.ImportJob{}, err
+	}
+
+	r.log.LogRepo("GET", "GetImportJob", true, job)
+	return job, nil
+}
@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportRepository_CreateImportJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewImportRepo(sqlxDB, logger)
+
+	createdAt := time.Date(2023, 6, 30, 12, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "created_at"}).AddRow("2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21", createdAt)
+	mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) RETURNING id, created_at", importJobsTable)).
+		WithArgs(models.ImportPending, 2).WillReturnRows(rows)
+
+	got, err := r.CreateImportJob(models.ImportJob{Status: models.ImportPending, Total: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ImportJob{
+		ID:        "2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21",
+		Status:    models.ImportPending,
+		Total:     2,
+		CreatedAt: createdAt,
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportRepository_UpdateImportJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewImportRepo(sqlxDB, logger)
+
+	type mockBehavior func(job models.ImportJob)
+
+	job := models.ImportJob{
+		ID:        "2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21",
+		Status:    models.ImportRunning,
+		Total:     2,
+		Processed: 2,
+		Applied:   1,
+		Failed:    1,
+		Errors:    models.ImportErrors{{Line: 3, Error: "not enough money to perform purchase"}},
+	}
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func(job models.ImportJob) {
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", importJobsTable)).
+					WithArgs(job.ID, job.Status, job.Processed, job.Applied, job.Failed,
+						[]byte(`[{"line":3,"error":"not enough money to perform purchase"}]`), nil).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: false,
+		},
+		{
+			name: "Job does not exist",
+			mock: func(job models.ImportJob) {
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", importJobsTable)).
+					WithArgs(job.ID, job.Status, job.Processed, job.Applied, job.Failed, sqlmock.AnyArg(), nil).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr:   true,
+			wantedErr: "import job not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(job)
+
+			err := r.UpdateImportJob(job)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestImportRepository_GetImportJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewImportRepo(sqlxDB, logger)
+
+	type mockBehavior func(id string)
+
+	id := "2d3b0c1e-6f3a-4c55-9b0e-3f7f1c0e9a21"
+	createdAt := time.Date(2023, 6, 30, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "status", "total", "processed", "applied", "failed", "errors", "created_at", "finished_at"}
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		want      models.ImportJob
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func(id string) {
+				rows := sqlmock.NewRows(columns).
+					AddRow(id, models.ImportRunning, 2, 1, 1, 0, []byte("[]"), createdAt, nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", importJobsTable)).
+					WithArgs(id).WillReturnRows(rows)
+			},
+			want: models.ImportJob{
+				ID:        id,
+				Status:    models.ImportRunning,
+				Total:     2,
+				Processed: 1,
+				Applied:   1,
+				Errors:    models.ImportErrors{},
+				CreatedAt: createdAt,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Job does not exist",
+			mock: func(id string) {
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", importJobsTable)).
+					WithArgs(id).WillReturnError(sql.ErrNoRows)
+			},
+			wantErr:   true,
+			wantedErr: "import job not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(id)
+
+			got, err := r.GetImportJob(id)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Limit interface {
+	// SetLimit saves spending limit, the limit with the same user, operation, period, counterparty
+	// and currency is replaced
+	SetLimit(input models.LimitInput) (models.Limit, error)
+	DeleteLimit(id int) error
+	// GetLimitUsage returns limits of user with amounts spent within their windows ending at now
+	GetLimitUsage(userId int, now time.Time) ([]models.LimitUsage, error)
+	// Spend checks spend against limits of its user and counts it as a part of tx. Limits are locked
+	// until tx ends, so concurrent spends of user are checked one after another
+	Spend(tx Tx, spend models.Spend, now time.Time) error
+}
+
+type LimitRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewLimitRepo(db *sqlx.DB, log logging.Logger) *LimitRepo {
+	return &LimitRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// limitColumns are selected for every spending limit
+const limitColumns = "id, user_id, operation, period, counterparty_id, amount, currency, created_at"
+
+func (r *LimitRepo) SetLimit(input models.LimitInput) (models.Limit, error) {
+	limit := models.Limit{
+		UserId:         input.UserId,
+		Operation:      input.Operation,
+		Period:         input.Period,
+		CounterpartyId: input.CounterpartyId,
+		Amount:         input.Amount,
+		Currency:       input.Currency,
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, operation, period, counterparty_id, amount, currency)
+		SELECT id, $2, $3, $4, $5, $6 FROM %s WHERE id = $1
+		ON CONFLICT (user_id, operation, period, counterparty_id, currency) DO UPDATE SET amount = EXCLUDED.amount
+		RETURNING id, created_at`, spendingLimitsTable, usersTable)
+
+	err := r.db.QueryRow(query, limit.UserId, limit.Operation, limit.Period, limit.CounterpartyId, limit.Amount,
+		limit.Currency).Scan(&limit.ID, &limit.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Limit{}, errors.New("user not found")
+		}
+
+		return models.Limit{}, err
+	}
+
+	r.log.LogRepo("POST", "SetLimit", true, limit)
+	return limit, nil
+}
+
+func (r *LimitRepo) DeleteLimit(id int) error {
+	res, err := r.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = $1", spendingLimitsTable), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return models.ErrLimitNotFound
+	}
+
+	r.log.LogRepo("DELETE", "DeleteLimit", true, id)
+	return nil
+}
+
+func (r *LimitRepo) GetLimitUsage(userId int, now time.Time) ([]models.LimitUsage, error) {
+	limits := []models.Limit{}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = $1 ORDER BY id", limitColumns, spendingLimitsTable)
+	if err := r.db.Select(&limits, query, userId); err != nil {
+		return nil, err
+	}
+
+	usage := make([]models.LimitUsage, 0, len(limits))
+	for _, limit := range limits {
+		used, err := r.used(r.db.QueryRow, limit, now)
+		if err != nil {
+			return nil, err
+		}
+
+		remaining := limit.Amount - used
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		usage = append(usage, models.LimitUsage{Limit: limit, Used: used, Remaining: remaining})
+	}
+
+	r.log.LogRepo("GET", "GetLimitUsage", true, usage)
+	return usage, nil
+}
+
+func (r *LimitRepo) Spend(unit Tx, spend models.Spend, now time.Time) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = $1 AND currency = $2 ORDER BY id FOR UPDATE",
+		limitColumns, spendingLimitsTable)
+	rows, err := tx.Query(query, spend.UserId, spend.Currency)
+	if err != nil {
+		return err
+	}
+
+	limits := []models.Limit{}
+	for rows.Next() {
+		var l models.Limit
+		err := rows.Scan(&l.ID, &l.UserId, &l.Operation, &l.Period, &l.CounterpartyId, &l.Amount, &l.Currency,
+			&l.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+
+		limits = append(limits, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, limit := range limits {
+		if !limit.Applies(spend) {
+			continue
+		}
+
+		used, err := r.used(tx.QueryRow, limit, now)
+		if err != nil {
+			return err
+		}
+
+		if used+spend.Amount > limit.Amount {
+			return fmt.Errorf("%w: %s, %.2f is remaining", models.ErrLimitExceeded, limit, limit.Amount-used)
+		}
+	}
+
+	insert := fmt.Sprintf(`INSERT INTO %s (user_id, operation, counterparty_id, amount, currency, date)
+		VALUES ($1, $2, $3, $4, $5, $6)`, spendsTable)
+	_, err = tx.Exec(insert, spend.UserId, spend.Operation, spend.CounterpartyId, spend.Amount, spend.Currency, now)
+	return err
+}
+
+// used sums spends restricted by limit within its window ending at now, nothing is used by operation limit
+func (r *LimitRepo) used(queryRow func(query string, args ...interface{}) *sql.Row, limit models.Limit,
+	now time.Time) (float32, error) {
+	window, ok := models.LimitWindows[limit.Period]
+	if !ok {
+		return 0, nil
+	}
+
+	query := fmt.Sprintf(`SELECT COALESCE(SUM(amount), 0) FROM %s
+		WHERE user_id = $1 AND currency = $2 AND date > $3
+		AND ($4 = '%s' OR operation = $4) AND ($5 = 0 OR counterparty_id = $5)`, spendsTable, models.LimitAny)
+
+	var used float32
+	err := queryRow(query, limit.UserId, limit.Currency, now.Add(-window), limit.Operation, limit.CounterpartyId).
+		Scan(&used)
+	return used, err
+}
@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitRepository_Spend(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewLimitRepo(sqlxDB, logger)
+	now := time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC)
+	spend := models.Spend{UserId: 1, Operation: models.LimitTransfer, CounterpartyId: 3, Amount: 30, Currency: "EUR"}
+
+	columns := []string{"id", "user_id", "operation", "period", "counterparty_id", "amount", "currency", "created_at"}
+	lockLimits := func(rows *sqlmock.Rows) {
+		mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", spendingLimitsTable)).
+			WithArgs(1, "EUR").WillReturnRows(rows)
+	}
+
+	tests := []struct {
+		name      string
+		mock      func()
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				lockLimits(sqlmock.NewRows(columns).
+					AddRow(1, 1, models.LimitDebit, models.LimitDaily, 0, 50, "EUR", now).
+					AddRow(2, 1, models.LimitAny, models.LimitWeekly, 0, 100, "EUR", now))
+				mock.ExpectQuery(fmt.Sprintf("SELECT COALESCE(.+) FROM %s WHERE (.+)", spendsTable)).
+					WithArgs(1, "EUR", now.Add(-7*24*time.Hour), models.LimitAny, 0).
+					WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(70))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", spendsTable)).
+					WithArgs(1, models.LimitTransfer, 3, spend.Amount, "EUR", now).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Exceeded",
+			mock: func() {
+				lockLimits(sqlmock.NewRows(columns).
+					AddRow(3, 1, models.LimitTransfer, models.LimitMonthly, 3, 100, "EUR", now))
+				mock.ExpectQuery(fmt.Sprintf("SELECT COALESCE(.+) FROM %s WHERE (.+)", spendsTable)).
+					WithArgs(1, "EUR", now.Add(-30*24*time.Hour), models.LimitTransfer, 3).
+					WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(80))
+				mock.ExpectRollback()
+			},
+			wantedErr: "spending limit exceeded: limit #3 of 100.00 EUR per month on transfers to user 3, " +
+				"20.00 is remaining",
+		},
+		{
+			name: "Per operation",
+			mock: func() {
+				lockLimits(sqlmock.NewRows(columns).
+					AddRow(4, 1, models.LimitTransfer, models.LimitPerOperation, 0, 20, "EUR", now))
+				mock.ExpectRollback()
+			},
+			wantedErr: "spending limit exceeded: limit #4 of 20.00 EUR per operation on transfers, 20.00 is remaining",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				return r.Spend(tx, spend, now)
+			})
+
+			if tt.wantedErr != "" {
+				assert.ErrorIs(t, err, models.ErrLimitExceeded)
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
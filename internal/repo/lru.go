@@ -0,0 +1,120 @@
+package repo
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+)
+
+type lruEntry struct {
+	userId  int
+	version int64
+	// wallets are nil after invalidation, the entry is kept to remember the version
+	wallets   []models.Wallet
+	expiresAt time.Time
+}
+
+// LRUCache is an in-process WalletCache keeping the most recently used users
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[int]*list.Element
+	order   *list.List
+	// floor is the highest version of evicted entries, users without entry have this version,
+	// so a reader which started before an eviction can not fill the cache with outdated wallets
+	floor int64
+}
+
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[int]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *LRUCache) Get(userId int) ([]models.Wallet, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[userId]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*lruEntry)
+	if entry.wallets == nil || time.Now().After(entry.expiresAt) {
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(element)
+	return append([]models.Wallet{}, entry.wallets...), true, nil
+}
+
+func (c *LRUCache) Version(userId int) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version(userId), nil
+}
+
+func (c *LRUCache) Set(userId int, version int64, wallets []models.Wallet) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version(userId) != version {
+		return nil
+	}
+
+	entry := c.entry(userId)
+	entry.wallets = append([]models.Wallet{}, wallets...)
+	entry.expiresAt = time.Now().Add(c.ttl)
+	return nil
+}
+
+func (c *LRUCache) Invalidate(userId int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	version := c.version(userId)
+
+	entry := c.entry(userId)
+	entry.version = version + 1
+	entry.wallets = nil
+	return nil
+}
+
+func (c *LRUCache) version(userId int) int64 {
+	if element, ok := c.entries[userId]; ok {
+		return element.Value.(*lruEntry).version
+	}
+
+	return c.floor
+}
+
+// entry returns entry of user moved to the front, the least recently used entry is evicted to make room for a new one
+func (c *LRUCache) entry(userId int) *lruEntry {
+	if element, ok := c.entries[userId]; ok {
+		c.order.MoveToFront(element)
+		return element.Value.(*lruEntry)
+	}
+
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		evicted := oldest.Value.(*lruEntry)
+		if evicted.version > c.floor {
+			c.floor = evicted.version
+		}
+
+		c.order.Remove(oldest)
+		delete(c.entries, evicted.userId)
+	}
+
+	entry := &lruEntry{userId: userId, version: c.floor}
+	c.entries[userId] = c.order.PushFront(entry)
+	return entry
+}
@@ -0,0 +1,605 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// memoryChangesBuffer is the number of committed transactions a change listener may lag behind
+const memoryChangesBuffer = 256
+
+// ErrNotSupported is returned by repositories which have no in-memory implementation
+var ErrNotSupported = errors.New("not supported by memory storage")
+
+// NewMemoryRepo builds repositories keeping data in memory, exchange, batches, imports, adjustments, accounts,
+// reconciliation, audit and outbox return ErrNotSupported. It is meant for tests and local development
+func NewMemoryRepo(log logging.Logger, users ...int) *Repo {
+	user := NewMemoryUserRepo(log)
+	user.AddUsers(users...)
+
+	return &Repo{
+		Transactor:     user,
+		Changes:        user,
+		User:           user,
+		Replica:        user,
+		Primary:        user,
+		Exchange:       memoryUnsupported{},
+		Batch:          memoryUnsupported{},
+		Import:         memoryUnsupported{},
+		Adjustment:     memoryUnsupported{},
+		Account:        memoryUnsupported{},
+		Reconciliation: memoryUnsupported{},
+		Audit:          memoryUnsupported{},
+		Schedule:       NewMemoryScheduleRepo(user, log),
+		Subscription:   NewMemorySubscriptionRepo(user, log),
+		Limit:          NewMemoryLimitRepo(user, log),
+		CreditLine:     NewMemoryCreditLineRepo(user, log),
+		Fee:            NewMemoryFeeRepo(user, log),
+		Promo:          NewMemoryPromoRepo(user, log),
+		Voucher:        NewMemoryVoucherRepo(user, log),
+		Escrow:         NewMemoryEscrowRepo(user, log),
+		Outbox:         memoryUnsupported{},
+	}
+}
+
+type memoryUser struct {
+	status         string
+	creditsBlocked bool
+}
+
+type memoryWalletKey struct {
+	userId   int
+	currency string
+}
+
+type memoryWallet struct {
+	balance     float32
+	promo       float32
+	creditLimit float32
+	held        float32
+	lastHash    string
+}
+
+// MemoryUserRepo implements User and Transactor with the same semantics as UserRepo. Every unit of work
+// holds the lock of the whole repository, so transfers are atomic and concurrent debits never overdraw a wallet.
+// Other memory repositories keep users and wallets in it and make their changes in its units of work
+type MemoryUserRepo struct {
+	mu           sync.Mutex
+	users        map[int]*memoryUser
+	wallets      map[memoryWalletKey]*memoryWallet
+	transactions []models.ChainLink
+	// lastId is the id of the last transaction, ids are not reused after a rollback like postgres serial
+	lastId    int
+	listeners map[chan models.Transaction]struct{}
+	log       logging.Logger
+}
+
+func NewMemoryUserRepo(log logging.Logger) *MemoryUserRepo {
+	return &MemoryUserRepo{
+		users:     make(map[int]*memoryUser),
+		wallets:   make(map[memoryWalletKey]*memoryWallet),
+		listeners: make(map[chan models.Transaction]struct{}),
+		log:       log,
+	}
+}
+
+// exists tells if the user exists, it must not be called within a unit of work
+func (r *MemoryUserRepo) exists(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.users[id]
+	return ok
+}
+
+// AddUsers creates active users without wallets, existing users are left as they are
+func (r *MemoryUserRepo) AddUsers(ids ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := r.users[id]; !ok {
+			r.users[id] = &memoryUser{status: models.AccountActive}
+		}
+	}
+}
+
+type memoryTx struct {
+	// undo reverts changes made in the unit of work, it is applied in reverse order
+	undo        []func()
+	afterCommit []func()
+	// links are transactions written in the unit of work, listeners get them after commit
+	links []models.ChainLink
+}
+
+func (t *memoryTx) AfterCommit(f func()) {
+	t.afterCommit = append(t.afterCommit, f)
+}
+
+func (*memoryTx) unitOfWork() {}
+
+// onRollback registers f reverting a change made in the unit of work
+func (t *memoryTx) onRollback(f func()) {
+	t.undo = append(t.undo, f)
+}
+
+// memoryTxOf returns the unit of work of memory storage, methods taking it run under the lock it holds
+func memoryTxOf(tx Tx) (*memoryTx, error) {
+	t, ok := tx.(*memoryTx)
+	if !ok {
+		return nil, errForeignTx
+	}
+
+	return t, nil
+}
+
+// WithinTx holds the lock of the whole repository while f runs and reverts changes made by f if it fails.
+// f must not call methods of memory repositories reading users or wallets without the unit of work
+func (r *MemoryUserRepo) WithinTx(f func(tx Tx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &memoryTx{}
+	if err := f(tx); err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+
+		return err
+	}
+
+	r.notify(tx.links)
+	for _, f := range tx.afterCommit {
+		f()
+	}
+
+	return nil
+}
+
+func (r *MemoryUserRepo) Credit(tx Tx, input models.Input, operation models.Operation) (float32, error) {
+	return r.changeTx(tx, input, "+", operation)
+}
+
+func (r *MemoryUserRepo) Debit(tx Tx, input models.Input, operation models.Operation) (float32, error) {
+	return r.changeTx(tx, input, "-", operation)
+}
+
+// changeTx applies the change and remembers how to revert it in the unit of work
+func (r *MemoryUserRepo) changeTx(tx Tx, input models.Input, action string, operation models.Operation) (float32, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	undo, balance, err := r.change(input, action, operation)
+	if err != nil {
+		return 0, err
+	}
+
+	memTx.onRollback(undo)
+	memTx.links = append(memTx.links, r.transactions[len(r.transactions)-1])
+	return balance, nil
+}
+
+// change applies the change under the lock and returns function reverting it
+func (r *MemoryUserRepo) change(input models.Input, action string, operation models.Operation) (func(), float32, error) {
+	if err := r.checkStatus(input.UserId, action, operation.System); err != nil {
+		return nil, 0, err
+	}
+
+	key := memoryWalletKey{userId: input.UserId, currency: input.Currency}
+	wallet, ok := r.wallets[key]
+	if !ok {
+		if action == "-" && !operation.Overdraw {
+			return nil, 0, errors.New("not enough money to perform purchase")
+		}
+
+		wallet = &memoryWallet{}
+	}
+
+	credit := wallet.creditLimit - wallet.held - wallet.promo
+	if wallet.balance+credit-(input.Amount-operation.Promo) < 0 && action == "-" && !operation.Overdraw {
+		return nil, 0, errors.New("not enough money to perform purchase")
+	}
+
+	r.lastId++
+	link := models.ChainLink{
+		ID:        r.lastId,
+		UserId:    input.UserId,
+		Currency:  input.Currency,
+		Direction: 1,
+		Amount:    input.Amount,
+		Operation: operation.Comment,
+		Date:      time.Now().Truncate(time.Second),
+		LinkId:    operation.LinkId,
+		PrevHash:  wallet.lastHash,
+	}
+
+	if action == "+" {
+		link.BalanceAfter = wallet.balance + input.Amount
+	} else {
+		link.Direction = -1
+		link.BalanceAfter = wallet.balance - input.Amount
+	}
+	link.Hash = link.ComputeHash()
+
+	previous := *wallet
+	wallet.balance = link.BalanceAfter
+	if action == "+" {
+		wallet.promo += operation.Promo
+	} else {
+		wallet.promo -= operation.Promo
+	}
+	wallet.lastHash = link.Hash
+	r.wallets[key] = wallet
+	r.transactions = append(r.transactions, link)
+
+	undo := func() {
+		r.transactions = r.transactions[:len(r.transactions)-1]
+		if ok {
+			*wallet = previous
+		} else {
+			delete(r.wallets, key)
+		}
+	}
+
+	return undo, link.BalanceAfter, nil
+}
+
+// checkStatus returns error if account status does not allow the change, system postings are allowed
+// on every account which is not closed
+func (r *MemoryUserRepo) checkStatus(userId int, action string, system bool) error {
+	user, ok := r.users[userId]
+	if !ok {
+		return errors.New("user not found")
+	}
+
+	switch user.status {
+	case models.AccountClosed:
+		return errors.New("account is closed")
+	case models.AccountFrozen:
+		if (action == "-" && !system) || (action == "+" && user.creditsBlocked) {
+			return errors.New("account is frozen")
+		}
+	case models.AccountDebitBlocked:
+		if action == "-" && !system {
+			return errors.New("account is debit blocked")
+		}
+	}
+
+	return nil
+}
+
+// memorySortFields are the columns transactions may be sorted by
+var memorySortFields = map[string]func(a, b models.ChainLink) bool{
+	"id":            func(a, b models.ChainLink) bool { return a.ID < b.ID },
+	"date":          func(a, b models.ChainLink) bool { return a.Date.Before(b.Date) },
+	"amount":        func(a, b models.ChainLink) bool { return a.Amount < b.Amount },
+	"currency":      func(a, b models.ChainLink) bool { return a.Currency < b.Currency },
+	"balance_after": func(a, b models.ChainLink) bool { return a.BalanceAfter < b.BalanceAfter },
+	"operation":     func(a, b models.ChainLink) bool { return a.Operation < b.Operation },
+}
+
+func (r *MemoryUserRepo) GetTransactions(id int, page models.Page) ([]models.Transaction, error) {
+	fields := strings.Fields(strings.ToLower(page.Sort))
+	if len(fields) == 0 || len(fields) > 2 || len(fields) == 2 && fields[1] != "asc" && fields[1] != "desc" {
+		return nil, fmt.Errorf("unsupported sort %q", page.Sort)
+	}
+
+	less, ok := memorySortFields[fields[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort %q", page.Sort)
+	}
+
+	if page.Limit < 0 || page.Page < 1 {
+		return nil, errors.New("incorrect page")
+	}
+
+	r.mu.Lock()
+	var links []models.ChainLink
+	for _, link := range r.transactions {
+		if link.UserId == id {
+			links = append(links, link)
+		}
+	}
+	r.mu.Unlock()
+
+	desc := len(fields) == 2 && fields[1] == "desc"
+	sort.SliceStable(links, func(i, j int) bool {
+		if desc {
+			return less(links[j], links[i])
+		}
+		return less(links[i], links[j])
+	})
+
+	result := make([]models.Transaction, 0)
+	for i := (page.Page - 1) * page.Limit; i < len(links) && len(result) < page.Limit; i++ {
+		result = append(result, memoryTransaction(links[i]))
+	}
+
+	return result, nil
+}
+
+func (r *MemoryUserRepo) GetTransactionsAfter(id, afterId, limit int) ([]models.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]models.Transaction, 0)
+	for _, link := range r.transactions {
+		if link.UserId == id && link.ID > afterId && len(result) < limit {
+			result = append(result, memoryTransaction(link))
+		}
+	}
+
+	return result, nil
+}
+
+// ListenChanges sends transactions committed by units of work, transactions are dropped
+// if out is full, since memory storage is meant for development only
+func (r *MemoryUserRepo) ListenChanges(stop <-chan struct{}) (<-chan models.Transaction, error) {
+	out := make(chan models.Transaction, memoryChangesBuffer)
+
+	r.mu.Lock()
+	r.listeners[out] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		<-stop
+
+		r.mu.Lock()
+		delete(r.listeners, out)
+		close(out)
+		r.mu.Unlock()
+	}()
+
+	return out, nil
+}
+
+// notify sends committed transactions to listeners, it is called under the lock
+func (r *MemoryUserRepo) notify(links []models.ChainLink) {
+	for out := range r.listeners {
+		for _, link := range links {
+			select {
+			case out <- memoryTransaction(link):
+			default:
+				r.log.Infof("change listener is full, transaction %d is dropped", link.ID)
+			}
+		}
+	}
+}
+
+func memoryTransaction(link models.ChainLink) models.Transaction {
+	return models.Transaction{
+		ID:           link.ID,
+		UserId:       link.UserId,
+		Amount:       link.Amount,
+		Currency:     link.Currency,
+		BalanceAfter: link.BalanceAfter,
+		Operation:    link.Operation,
+		LinkId:       link.LinkId,
+		Date:         link.Date,
+	}
+}
+
+func (r *MemoryUserRepo) GetWallets(id int) ([]models.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return nil, errors.New("user not found")
+	}
+
+	var wallets []models.Wallet
+	for key, wallet := range r.wallets {
+		if key.userId == id {
+			wallets = append(wallets, models.Wallet{UserId: id, Currency: key.currency, Balance: wallet.balance,
+				Promo: wallet.promo})
+		}
+	}
+
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].Currency < wallets[j].Currency })
+
+	r.log.LogRepo("GET", "GetWallets", true, wallets)
+	return wallets, nil
+}
+
+// GetBalanceAt returns balances of user`s wallets right after the last transaction made before or at the given moment
+func (r *MemoryUserRepo) GetBalanceAt(id int, at time.Time) ([]models.Wallet, error) {
+	wallets, err := r.GetWallets(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range wallets {
+		wallets[i].Balance = r.balanceBefore(id, wallets[i].Currency, func(date time.Time) bool { return !date.After(at) })
+	}
+
+	return wallets, nil
+}
+
+// GetBalanceHistory returns balance of user`s wallet at the end of every interval between from and to.
+// interval is written like postgres one, e.g. "1 day"
+func (r *MemoryUserRepo) GetBalanceHistory(id int, currency string, from, to time.Time, interval string) ([]models.BalancePoint, error) {
+	step, err := parseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var points []models.BalancePoint
+	if _, ok := r.wallets[memoryWalletKey{userId: id, currency: currency}]; ok {
+		for point := from; !point.After(to); point = point.Add(step) {
+			end := point.Add(step)
+			points = append(points, models.BalancePoint{
+				Date:    point,
+				Balance: r.balanceBefore(id, currency, func(date time.Time) bool { return date.Before(end) }),
+			})
+		}
+	}
+
+	if len(points) == 0 {
+		return nil, errors.New("wallet not found")
+	}
+
+	return points, nil
+}
+
+// balanceBefore returns balance after the last transaction of the wallet matching before, zero if there is none
+func (r *MemoryUserRepo) balanceBefore(id int, currency string, before func(date time.Time) bool) float32 {
+	var last *models.ChainLink
+	for i, link := range r.transactions {
+		if link.UserId != id || link.Currency != currency || !before(link.Date) {
+			continue
+		}
+
+		if last == nil || !link.Date.Before(last.Date) {
+			last = &r.transactions[i]
+		}
+	}
+
+	if last == nil {
+		return 0
+	}
+
+	return last.BalanceAfter
+}
+
+// intervalUnits are the units of intervals balance history is requested with
+var intervalUnits = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+func parseInterval(interval string) (time.Duration, error) {
+	var (
+		n    int
+		unit string
+	)
+
+	if _, err := fmt.Sscanf(interval, "%d %s", &n, &unit); err != nil || n <= 0 {
+		return 0, fmt.Errorf("incorrect interval %q", interval)
+	}
+
+	step, ok := intervalUnits[strings.TrimSuffix(unit, "s")]
+	if !ok {
+		return 0, fmt.Errorf("incorrect interval %q", interval)
+	}
+
+	return time.Duration(n) * step, nil
+}
+
+// memoryUnsupported stands for repositories which need postgres
+type memoryUnsupported struct{}
+
+func (memoryUnsupported) CreateQuote(quote models.ExchangeQuote) (models.ExchangeQuote, error) {
+	return models.ExchangeQuote{}, ErrNotSupported
+}
+
+func (memoryUnsupported) Exchange(tx Tx, input models.ExchangeInput, revenueAccount int) (models.ExchangeResult, error) {
+	return models.ExchangeResult{}, ErrNotSupported
+}
+
+func (memoryUnsupported) ClaimKey(tx Tx, key string) (float32, bool, error) {
+	return 0, false, ErrNotSupported
+}
+
+func (memoryUnsupported) SaveKey(tx Tx, key string, balance float32) error {
+	return ErrNotSupported
+}
+
+func (memoryUnsupported) CreateImportJob(job models.ImportJob) (models.ImportJob, error) {
+	return models.ImportJob{}, ErrNotSupported
+}
+
+func (memoryUnsupported) UpdateImportJob(job models.ImportJob) error {
+	return ErrNotSupported
+}
+
+func (memoryUnsupported) GetImportJob(id string) (models.ImportJob, error) {
+	return models.ImportJob{}, ErrNotSupported
+}
+
+func (memoryUnsupported) CreateAdjustment(input models.AdjustmentInput) (models.Adjustment, error) {
+	return models.Adjustment{}, ErrNotSupported
+}
+
+func (memoryUnsupported) GetAdjustment(id int) (models.Adjustment, error) {
+	return models.Adjustment{}, ErrNotSupported
+}
+
+func (memoryUnsupported) ListAdjustments(status string) ([]models.Adjustment, error) {
+	return nil, ErrNotSupported
+}
+
+func (memoryUnsupported) ApproveAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	return models.Adjustment{}, ErrNotSupported
+}
+
+func (memoryUnsupported) RejectAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	return models.Adjustment{}, ErrNotSupported
+}
+
+func (memoryUnsupported) GetAccount(id int) (models.Account, error) {
+	return models.Account{}, ErrNotSupported
+}
+
+func (memoryUnsupported) SetAccountStatus(input models.AccountStatusInput) (models.Account, error) {
+	return models.Account{}, ErrNotSupported
+}
+
+func (memoryUnsupported) Reconcile(actor string) (models.ReconciliationReport, error) {
+	return models.ReconciliationReport{}, ErrNotSupported
+}
+
+func (memoryUnsupported) GetReconciliation(id int) (models.ReconciliationReport, error) {
+	return models.ReconciliationReport{}, ErrNotSupported
+}
+
+func (memoryUnsupported) GetLastReconciliation() (models.ReconciliationReport, error) {
+	return models.ReconciliationReport{}, ErrNotSupported
+}
+
+func (memoryUnsupported) ApproveCorrections(id int, operator string) (models.ReconciliationReport, error) {
+	return models.ReconciliationReport{}, ErrNotSupported
+}
+
+func (memoryUnsupported) VerifyChain() (models.ChainVerification, error) {
+	return models.ChainVerification{}, ErrNotSupported
+}
+
+func (memoryUnsupported) GetChainHeads() ([]models.ChainHead, int, error) {
+	return nil, 0, ErrNotSupported
+}
+
+func (memoryUnsupported) LockOutboxEvents(tx Tx, limit int) ([]models.OutboxEvent, error) {
+	return nil, ErrNotSupported
+}
+
+func (memoryUnsupported) MarkOutboxPublished(tx Tx, ids []int64, at time.Time) error {
+	return ErrNotSupported
+}
+
+func (memoryUnsupported) FailOutboxEvents(tx Tx, ids []int64, reason string) error {
+	return ErrNotSupported
+}
+
+func (memoryUnsupported) ReplayOutbox(input models.OutboxReplayInput) (int, error) {
+	return 0, ErrNotSupported
+}
+
+func (memoryUnsupported) PruneOutbox(before time.Time, undelivered bool) (int, error) {
+	return 0, ErrNotSupported
+}
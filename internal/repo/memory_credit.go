@@ -0,0 +1,349 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemoryCreditLineRepo implements CreditLine with the same semantics as CreditLineRepo. Credit limits and held
+// money are kept by wallets of the memory users repository, holds and charges are kept here
+type MemoryCreditLineRepo struct {
+	user    *MemoryUserRepo
+	mu      sync.Mutex
+	holds   []models.Hold
+	charges []models.OverdraftCharge
+	lastId  int
+	log     logging.Logger
+}
+
+func NewMemoryCreditLineRepo(user *MemoryUserRepo, log logging.Logger) *MemoryCreditLineRepo {
+	return &MemoryCreditLineRepo{
+		user: user,
+		log:  log,
+	}
+}
+
+func (r *MemoryCreditLineRepo) SetCreditLimit(input models.CreditLimitInput) (models.CreditLine, error) {
+	r.user.mu.Lock()
+	defer r.user.mu.Unlock()
+
+	if _, ok := r.user.users[input.UserId]; !ok {
+		return models.CreditLine{}, errors.New("user not found")
+	}
+
+	key := memoryWalletKey{userId: input.UserId, currency: input.Currency}
+	wallet, ok := r.user.wallets[key]
+	if !ok {
+		wallet = &memoryWallet{}
+		r.user.wallets[key] = wallet
+	}
+	wallet.creditLimit = input.CreditLimit
+
+	line := r.creditLine(key, wallet)
+	r.log.LogRepo("POST", "SetCreditLimit", true, line)
+	return line, nil
+}
+
+func (r *MemoryCreditLineRepo) GetCreditLines(userId int) ([]models.CreditLine, error) {
+	lines := r.creditLines(func(key memoryWalletKey, wallet *memoryWallet) bool { return key.userId == userId })
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Currency < lines[j].Currency })
+
+	r.log.LogRepo("GET", "GetCreditLines", true, lines)
+	return lines, nil
+}
+
+func (r *MemoryCreditLineRepo) GetOverdraftReport(currency string) (models.OverdraftReport, error) {
+	report := models.OverdraftReport{Currency: currency}
+	report.Wallets = r.creditLines(func(key memoryWalletKey, wallet *memoryWallet) bool {
+		return key.currency == currency && wallet.balance-wallet.promo < 0
+	})
+
+	// the deepest overdraft goes first
+	sort.Slice(report.Wallets, func(i, j int) bool {
+		a, b := report.Wallets[i], report.Wallets[j]
+		if a.Overdraft != b.Overdraft {
+			return a.Overdraft > b.Overdraft
+		}
+		return a.UserId < b.UserId
+	})
+
+	for _, line := range report.Wallets {
+		report.TotalOverdraft += line.Overdraft
+		report.TotalCharged += line.Charged
+	}
+
+	r.log.LogRepo("GET", "GetOverdraftReport", true, report)
+	return report, nil
+}
+
+// creditLines returns credit lines of wallets matching match, it must not be called within a unit of work
+func (r *MemoryCreditLineRepo) creditLines(match func(key memoryWalletKey, wallet *memoryWallet) bool) []models.CreditLine {
+	r.user.mu.Lock()
+	defer r.user.mu.Unlock()
+
+	lines := []models.CreditLine{}
+	for key, wallet := range r.user.wallets {
+		if match(key, wallet) {
+			lines = append(lines, r.creditLine(key, wallet))
+		}
+	}
+
+	return lines
+}
+
+// creditLine describes the wallet, it is called under the lock of the users repository. Overdraft is the debt
+// of real money which promo never repays
+func (r *MemoryCreditLineRepo) creditLine(key memoryWalletKey, wallet *memoryWallet) models.CreditLine {
+	line := models.CreditLine{
+		UserId:      key.userId,
+		Currency:    key.currency,
+		Balance:     wallet.balance,
+		CreditLimit: wallet.creditLimit,
+		Held:        wallet.held,
+		Available:   wallet.balance - wallet.promo + wallet.creditLimit - wallet.held,
+	}
+
+	if wallet.promo > wallet.balance {
+		line.Overdraft = wallet.promo - wallet.balance
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, charge := range r.charges {
+		if charge.UserId == key.userId && charge.Currency == key.currency {
+			line.Charged += charge.Amount
+		}
+	}
+
+	return line
+}
+
+func (r *MemoryCreditLineRepo) PlaceHold(tx Tx, input models.HoldInput) (models.Hold, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	user, ok := r.user.users[input.UserId]
+	wallet, found := r.user.wallets[memoryWalletKey{userId: input.UserId, currency: input.Currency}]
+	if !ok || !found {
+		return models.Hold{}, errors.New("not enough money to perform purchase")
+	}
+
+	// money is held only on accounts accepting debits, it is debited when the hold is captured
+	if user.status != models.AccountActive {
+		return models.Hold{}, fmt.Errorf("account is %s", strings.ReplaceAll(user.status, "_", " "))
+	}
+
+	if wallet.balance-wallet.promo+wallet.creditLimit-wallet.held-input.Amount < 0 {
+		return models.Hold{}, errors.New("not enough money to perform purchase")
+	}
+
+	wallet.held += input.Amount
+	memTx.onRollback(func() { wallet.held -= input.Amount })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastId++
+	hold := models.Hold{
+		ID:        r.lastId,
+		UserId:    input.UserId,
+		Amount:    input.Amount,
+		Currency:  input.Currency,
+		Comment:   input.Comment,
+		Status:    models.HoldActive,
+		CreatedAt: time.Now(),
+	}
+
+	count := len(r.holds)
+	r.holds = append(r.holds, hold)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.holds = r.holds[:count]
+		r.mu.Unlock()
+	})
+
+	return hold, nil
+}
+
+func (r *MemoryCreditLineRepo) GetHold(id int) (models.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.hold(id)
+	if !ok {
+		return models.Hold{}, models.ErrHoldNotFound
+	}
+
+	r.log.LogRepo("GET", "GetHold", true, r.holds[i])
+	return r.holds[i], nil
+}
+
+func (r *MemoryCreditLineRepo) ListHolds(userId int, status string) ([]models.Hold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	holds := []models.Hold{}
+	for _, hold := range r.holds {
+		if hold.UserId == userId && (status == "" || hold.Status == status) {
+			holds = append(holds, hold)
+		}
+	}
+
+	r.log.LogRepo("GET", "ListHolds", true, holds)
+	return holds, nil
+}
+
+func (r *MemoryCreditLineRepo) LockHold(tx Tx, id int) (models.Hold, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Hold{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.hold(id)
+	if !ok {
+		return models.Hold{}, models.ErrHoldNotFound
+	}
+
+	return r.holds[i], nil
+}
+
+func (r *MemoryCreditLineRepo) CloseHold(tx Tx, hold models.Hold) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.hold(hold.ID)
+	if !ok {
+		return models.ErrHoldNotFound
+	}
+
+	previous := r.holds[i]
+	r.holds[i].Status = hold.Status
+	r.holds[i].ClosedAt = hold.ClosedAt
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.holds[i] = previous
+		r.mu.Unlock()
+	})
+
+	if wallet, ok := r.user.wallets[memoryWalletKey{userId: hold.UserId, currency: hold.Currency}]; ok {
+		wallet.held -= hold.Amount
+		memTx.onRollback(func() { wallet.held += hold.Amount })
+	}
+
+	return nil
+}
+
+// hold returns the index of the hold, it is called under the lock
+func (r *MemoryCreditLineRepo) hold(id int) (int, bool) {
+	for i, hold := range r.holds {
+		if hold.ID == id {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (r *MemoryCreditLineRepo) OverdrawnWallets(day time.Time, limit int) ([]models.Wallet, error) {
+	r.user.mu.Lock()
+	defer r.user.mu.Unlock()
+
+	wallets := []models.Wallet{}
+	for key, wallet := range r.user.wallets {
+		if wallet.balance-wallet.promo < 0 && !r.charged(key, day) {
+			wallets = append(wallets, models.Wallet{UserId: key.userId, Currency: key.currency,
+				Balance: wallet.balance, Promo: wallet.promo})
+		}
+	}
+
+	sort.Slice(wallets, func(i, j int) bool {
+		if wallets[i].UserId != wallets[j].UserId {
+			return wallets[i].UserId < wallets[j].UserId
+		}
+		return wallets[i].Currency < wallets[j].Currency
+	})
+
+	if len(wallets) > limit {
+		wallets = wallets[:limit]
+	}
+
+	return wallets, nil
+}
+
+func (r *MemoryCreditLineRepo) LockOverdrawnWallet(tx Tx, userId int, currency string,
+	day time.Time) (models.CreditLine, bool, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.CreditLine{}, false, err
+	}
+
+	key := memoryWalletKey{userId: userId, currency: currency}
+	wallet, ok := r.user.wallets[key]
+	if !ok || wallet.balance-wallet.promo >= 0 || r.charged(key, day) {
+		return models.CreditLine{}, false, nil
+	}
+
+	return models.CreditLine{
+		UserId:      userId,
+		Currency:    currency,
+		Balance:     wallet.balance,
+		CreditLimit: wallet.creditLimit,
+		Held:        wallet.held,
+		Available:   wallet.balance - wallet.promo + wallet.creditLimit - wallet.held,
+		Overdraft:   wallet.promo - wallet.balance,
+	}, true, nil
+}
+
+// charged tells if the wallet was charged on day
+func (r *MemoryCreditLineRepo) charged(key memoryWalletKey, day time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, charge := range r.charges {
+		if charge.UserId == key.userId && charge.Currency == key.currency && charge.Date.Equal(day) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *MemoryCreditLineRepo) AddOverdraftCharge(tx Tx, charge models.OverdraftCharge) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	if r.charged(memoryWalletKey{userId: charge.UserId, currency: charge.Currency}, charge.Date) {
+		return errors.New("wallet is already charged on the day")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := len(r.charges)
+	r.charges = append(r.charges, charge)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.charges = r.charges[:count]
+		r.mu.Unlock()
+	})
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package repo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemoryEscrowRepo implements Escrow with the same semantics as EscrowRepo
+type MemoryEscrowRepo struct {
+	user    *MemoryUserRepo
+	mu      sync.Mutex
+	escrows []models.Escrow
+	events  []models.EscrowEvent
+	// lastId and lastEventId are not reused after a rollback like postgres serials
+	lastId      int
+	lastEventId int
+	log         logging.Logger
+}
+
+func NewMemoryEscrowRepo(user *MemoryUserRepo, log logging.Logger) *MemoryEscrowRepo {
+	return &MemoryEscrowRepo{
+		user: user,
+		log:  log,
+	}
+}
+
+func (r *MemoryEscrowRepo) CreateEscrow(tx Tx, escrow models.Escrow) (models.Escrow, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return models.Escrow{}, err
+	}
+
+	_, sender := r.user.users[escrow.SenderId]
+	_, recipient := r.user.users[escrow.RecipientId]
+	if !sender || !recipient {
+		return models.Escrow{}, errors.New("user not found")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastId++
+	escrow.ID = r.lastId
+	escrow.CreatedAt = time.Now()
+	escrow.Events = nil
+
+	count := len(r.escrows)
+	r.escrows = append(r.escrows, escrow)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.escrows = r.escrows[:count]
+		r.mu.Unlock()
+	})
+
+	return escrow, nil
+}
+
+func (r *MemoryEscrowRepo) GetEscrow(id int) (models.Escrow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.escrow(id)
+	if !ok {
+		return models.Escrow{}, models.ErrEscrowNotFound
+	}
+
+	escrow := r.escrows[i]
+	for _, event := range r.events {
+		if event.EscrowId == id {
+			escrow.Events = append(escrow.Events, event)
+		}
+	}
+
+	r.log.LogRepo("GET", "GetEscrow", true, escrow)
+	return escrow, nil
+}
+
+func (r *MemoryEscrowRepo) ListEscrows(userId int, status string) ([]models.Escrow, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	escrows := []models.Escrow{}
+	for _, escrow := range r.escrows {
+		if (escrow.SenderId == userId || escrow.RecipientId == userId) && (status == "" || escrow.Status == status) {
+			escrows = append(escrows, escrow)
+		}
+	}
+
+	r.log.LogRepo("GET", "ListEscrows", true, escrows)
+	return escrows, nil
+}
+
+func (r *MemoryEscrowRepo) ExpiredEscrows(now time.Time, limit int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []models.Escrow
+	for _, escrow := range r.escrows {
+		if escrow.Status == models.EscrowHeld && !escrow.ExpiresAt.After(now) {
+			expired = append(expired, escrow)
+		}
+	}
+
+	sort.SliceStable(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+
+	ids := []int{}
+	for _, escrow := range expired {
+		if len(ids) == limit {
+			break
+		}
+
+		ids = append(ids, escrow.ID)
+	}
+
+	return ids, nil
+}
+
+func (r *MemoryEscrowRepo) LockEscrow(tx Tx, id int) (models.Escrow, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Escrow{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.escrow(id)
+	if !ok {
+		return models.Escrow{}, models.ErrEscrowNotFound
+	}
+
+	return r.escrows[i], nil
+}
+
+func (r *MemoryEscrowRepo) LockExpiredEscrow(tx Tx, id int, now time.Time) (models.Escrow, bool, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Escrow{}, false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.escrow(id)
+	if !ok || r.escrows[i].Status != models.EscrowHeld || r.escrows[i].ExpiresAt.After(now) {
+		return models.Escrow{}, false, nil
+	}
+
+	return r.escrows[i], true, nil
+}
+
+func (r *MemoryEscrowRepo) CloseEscrow(tx Tx, escrow models.Escrow, event models.EscrowEvent) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	i, ok := r.escrow(escrow.ID)
+	if !ok {
+		r.mu.Unlock()
+		return models.ErrEscrowNotFound
+	}
+
+	previous := r.escrows[i]
+	r.escrows[i].Status = escrow.Status
+	r.escrows[i].ClosedAt = escrow.ClosedAt
+	r.mu.Unlock()
+
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.escrows[i] = previous
+		r.mu.Unlock()
+	})
+
+	return r.AddEscrowEvent(tx, event)
+}
+
+func (r *MemoryEscrowRepo) AddEscrowEvent(tx Tx, event models.EscrowEvent) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.escrow(event.EscrowId); !ok {
+		return models.ErrEscrowNotFound
+	}
+
+	r.lastEventId++
+	event.ID = r.lastEventId
+
+	count := len(r.events)
+	r.events = append(r.events, event)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.events = r.events[:count]
+		r.mu.Unlock()
+	})
+
+	return nil
+}
+
+// escrow returns the index of the escrow, it is called under the lock
+func (r *MemoryEscrowRepo) escrow(id int) (int, bool) {
+	for i, escrow := range r.escrows {
+		if escrow.ID == id {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
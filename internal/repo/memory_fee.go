@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemoryFeeRepo implements Fee with the same semantics as FeeRepo
+type MemoryFeeRepo struct {
+	user   *MemoryUserRepo
+	mu     sync.Mutex
+	rules  []models.FeeRule
+	lastId int
+	log    logging.Logger
+}
+
+func NewMemoryFeeRepo(user *MemoryUserRepo, log logging.Logger) *MemoryFeeRepo {
+	return &MemoryFeeRepo{
+		user: user,
+		log:  log,
+	}
+}
+
+func (r *MemoryFeeRepo) CreateFeeRule(input models.FeeRuleInput) (models.FeeRule, error) {
+	// client rules are saved only for existing users
+	if input.UserId != 0 && !r.user.exists(input.UserId) {
+		return models.FeeRule{}, errors.New("user not found")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastId++
+	rule := models.FeeRule{
+		ID:        r.lastId,
+		Operation: input.Operation,
+		UserId:    input.UserId,
+		Currency:  input.Currency,
+		MinAmount: input.MinAmount,
+		MaxAmount: input.MaxAmount,
+		Rate:      input.Rate,
+		Fixed:     input.Fixed,
+		CreatedAt: time.Now(),
+	}
+	r.rules = append(r.rules, rule)
+
+	r.log.LogRepo("POST", "CreateFeeRule", true, rule)
+	return rule, nil
+}
+
+func (r *MemoryFeeRepo) ListFeeRules(operation string) ([]models.FeeRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := []models.FeeRule{}
+	for _, rule := range r.rules {
+		if operation == "" || rule.Operation == operation {
+			rules = append(rules, rule)
+		}
+	}
+
+	r.log.LogRepo("GET", "ListFeeRules", true, rules)
+	return rules, nil
+}
+
+func (r *MemoryFeeRepo) DeleteFeeRule(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, rule := range r.rules {
+		if rule.ID == id {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			r.log.LogRepo("DELETE", "DeleteFeeRule", true, id)
+			return nil
+		}
+	}
+
+	return models.ErrFeeRuleNotFound
+}
+
+func (r *MemoryFeeRepo) MatchFeeRule(input models.FeeQuoteInput) (models.FeeRule, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		match models.FeeRule
+		found bool
+	)
+	for _, rule := range r.rules {
+		if rule.Applies(input) && (!found || rule.MoreSpecific(match)) {
+			match, found = rule, true
+		}
+	}
+
+	return match, found, nil
+}
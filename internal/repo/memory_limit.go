@@ -0,0 +1,155 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemoryLimitRepo implements Limit with the same semantics as LimitRepo, spends are counted
+// in units of work of the memory users repository
+type MemoryLimitRepo struct {
+	user   *MemoryUserRepo
+	mu     sync.Mutex
+	limits []models.Limit
+	spends []memorySpend
+	lastId int
+	log    logging.Logger
+}
+
+type memorySpend struct {
+	models.Spend
+	date time.Time
+}
+
+func NewMemoryLimitRepo(user *MemoryUserRepo, log logging.Logger) *MemoryLimitRepo {
+	return &MemoryLimitRepo{
+		user: user,
+		log:  log,
+	}
+}
+
+func (r *MemoryLimitRepo) SetLimit(input models.LimitInput) (models.Limit, error) {
+	if !r.user.exists(input.UserId) {
+		return models.Limit{}, errors.New("user not found")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit := models.Limit{
+		UserId:         input.UserId,
+		Operation:      input.Operation,
+		Period:         input.Period,
+		CounterpartyId: input.CounterpartyId,
+		Amount:         input.Amount,
+		Currency:       input.Currency,
+	}
+
+	for i, l := range r.limits {
+		if l.UserId == limit.UserId && l.Operation == limit.Operation && l.Period == limit.Period &&
+			l.CounterpartyId == limit.CounterpartyId && l.Currency == limit.Currency {
+			r.limits[i].Amount = limit.Amount
+			r.log.LogRepo("POST", "SetLimit", true, r.limits[i])
+			return r.limits[i], nil
+		}
+	}
+
+	r.lastId++
+	limit.ID = r.lastId
+	limit.CreatedAt = time.Now()
+	r.limits = append(r.limits, limit)
+
+	r.log.LogRepo("POST", "SetLimit", true, limit)
+	return limit, nil
+}
+
+func (r *MemoryLimitRepo) DeleteLimit(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, limit := range r.limits {
+		if limit.ID == id {
+			r.limits = append(r.limits[:i], r.limits[i+1:]...)
+			r.log.LogRepo("DELETE", "DeleteLimit", true, id)
+			return nil
+		}
+	}
+
+	return models.ErrLimitNotFound
+}
+
+func (r *MemoryLimitRepo) GetLimitUsage(userId int, now time.Time) ([]models.LimitUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usage := []models.LimitUsage{}
+	for _, limit := range r.limits {
+		if limit.UserId != userId {
+			continue
+		}
+
+		used := r.used(limit, now)
+		remaining := limit.Amount - used
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		usage = append(usage, models.LimitUsage{Limit: limit, Used: used, Remaining: remaining})
+	}
+
+	r.log.LogRepo("GET", "GetLimitUsage", true, usage)
+	return usage, nil
+}
+
+func (r *MemoryLimitRepo) Spend(tx Tx, spend models.Spend, now time.Time) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, limit := range r.limits {
+		if !limit.Applies(spend) {
+			continue
+		}
+
+		used := r.used(limit, now)
+		if used+spend.Amount > limit.Amount {
+			return fmt.Errorf("%w: %s, %.2f is remaining", models.ErrLimitExceeded, limit, limit.Amount-used)
+		}
+	}
+
+	count := len(r.spends)
+	r.spends = append(r.spends, memorySpend{Spend: spend, date: now})
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.spends = r.spends[:count]
+		r.mu.Unlock()
+	})
+
+	return nil
+}
+
+// used sums spends restricted by limit within its window ending at now, nothing is used by operation limit
+func (r *MemoryLimitRepo) used(limit models.Limit, now time.Time) float32 {
+	window, ok := models.LimitWindows[limit.Period]
+	if !ok {
+		return 0
+	}
+
+	var used float32
+	for _, spend := range r.spends {
+		if spend.date.After(now.Add(-window)) && limit.Applies(spend.Spend) {
+			used += spend.Amount
+		}
+	}
+
+	return used
+}
@@ -0,0 +1,230 @@
+package repo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemoryPromoRepo implements Promo with the same semantics as PromoRepo, promo of wallets is kept
+// by the memory users repository
+type MemoryPromoRepo struct {
+	user   *MemoryUserRepo
+	mu     sync.Mutex
+	grants []models.PromoGrant
+	lastId int
+	log    logging.Logger
+}
+
+func NewMemoryPromoRepo(user *MemoryUserRepo, log logging.Logger) *MemoryPromoRepo {
+	return &MemoryPromoRepo{
+		user: user,
+		log:  log,
+	}
+}
+
+func (r *MemoryPromoRepo) CreateGrant(tx Tx, grant models.PromoGrant) (models.PromoGrant, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return models.PromoGrant{}, err
+	}
+
+	if _, ok := r.user.users[grant.UserId]; !ok {
+		return models.PromoGrant{}, errors.New("user not found")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastId++
+	grant.ID = r.lastId
+	grant.Remaining = grant.Amount
+	grant.CreatedAt = time.Now()
+
+	count := len(r.grants)
+	r.grants = append(r.grants, grant)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.grants = r.grants[:count]
+		r.mu.Unlock()
+	})
+
+	return grant, nil
+}
+
+func (r *MemoryPromoRepo) GetGrant(id int) (models.PromoGrant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.grant(id)
+	if !ok {
+		return models.PromoGrant{}, models.ErrPromoGrantNotFound
+	}
+
+	r.log.LogRepo("GET", "GetGrant", true, r.grants[i])
+	return r.grants[i], nil
+}
+
+func (r *MemoryPromoRepo) ListGrants(userId int, status string) ([]models.PromoGrant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	grants := []models.PromoGrant{}
+	for _, grant := range r.grants {
+		if grant.UserId == userId && (status == "" || grant.Status == status) {
+			grants = append(grants, grant)
+		}
+	}
+
+	r.log.LogRepo("GET", "ListGrants", true, grants)
+	return grants, nil
+}
+
+func (r *MemoryPromoRepo) SpendPromo(tx Tx, userId int, currency string, amount float32, first bool,
+	now time.Time) (float32, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	wallet, ok := r.user.wallets[memoryWalletKey{userId: userId, currency: currency}]
+	if !ok {
+		return 0, nil
+	}
+
+	want := amount
+	if !first {
+		want = amount - (wallet.balance - wallet.promo + wallet.creditLimit - wallet.held)
+	}
+
+	if want > wallet.promo {
+		want = wallet.promo
+	}
+
+	if want <= 0 {
+		return 0, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var active []int
+	for i, grant := range r.grants {
+		if grant.UserId == userId && grant.Currency == currency && grant.Status == models.PromoActive &&
+			grant.Remaining > 0 && grant.ExpiresAt.After(now) {
+			active = append(active, i)
+		}
+	}
+
+	// the grant expiring first is spent first
+	sort.SliceStable(active, func(i, j int) bool {
+		return r.grants[active[i]].ExpiresAt.Before(r.grants[active[j]].ExpiresAt)
+	})
+
+	var spent float32
+	for _, i := range active {
+		if spent >= want {
+			break
+		}
+
+		part := r.grants[i].Remaining
+		if part > want-spent {
+			part = want - spent
+		}
+
+		r.grants[i].Remaining -= part
+		spent += part
+
+		i := i
+		memTx.onRollback(func() {
+			r.mu.Lock()
+			r.grants[i].Remaining += part
+			r.mu.Unlock()
+		})
+	}
+
+	return spent, nil
+}
+
+func (r *MemoryPromoRepo) ExpiredGrants(now time.Time, limit int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []models.PromoGrant
+	for _, grant := range r.grants {
+		if grant.Status == models.PromoActive && !grant.ExpiresAt.After(now) {
+			expired = append(expired, grant)
+		}
+	}
+
+	sort.SliceStable(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+
+	ids := []int{}
+	for _, grant := range expired {
+		if len(ids) == limit {
+			break
+		}
+
+		ids = append(ids, grant.ID)
+	}
+
+	return ids, nil
+}
+
+func (r *MemoryPromoRepo) LockExpiredGrant(tx Tx, id int, now time.Time) (models.PromoGrant, bool, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.PromoGrant{}, false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.grant(id)
+	if !ok || r.grants[i].Status != models.PromoActive || r.grants[i].ExpiresAt.After(now) {
+		return models.PromoGrant{}, false, nil
+	}
+
+	return r.grants[i], true, nil
+}
+
+func (r *MemoryPromoRepo) ExpireGrant(tx Tx, grant models.PromoGrant) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.grant(grant.ID)
+	if !ok {
+		return models.ErrPromoGrantNotFound
+	}
+
+	previous := r.grants[i]
+	r.grants[i].Status = grant.Status
+	r.grants[i].Remaining = grant.Remaining
+	r.grants[i].ExpiredAt = grant.ExpiredAt
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.grants[i] = previous
+		r.mu.Unlock()
+	})
+
+	return nil
+}
+
+// grant returns the index of the grant, it is called under the lock
+func (r *MemoryPromoRepo) grant(id int) (int, bool) {
+	for i, grant := range r.grants {
+		if grant.ID == id {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,208 @@
+package repo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemoryScheduleRepo implements Schedule with the same semantics as ScheduleRepo
+type MemoryScheduleRepo struct {
+	user      *MemoryUserRepo
+	mu        sync.Mutex
+	schedules []models.Schedule
+	runs      map[int][]models.ScheduleRun
+	lastId    int
+	log       logging.Logger
+}
+
+func NewMemoryScheduleRepo(user *MemoryUserRepo, log logging.Logger) *MemoryScheduleRepo {
+	return &MemoryScheduleRepo{
+		user: user,
+		runs: make(map[int][]models.ScheduleRun),
+		log:  log,
+	}
+}
+
+// CreateSchedule saves schedule, both sender and receiver must exist
+func (r *MemoryScheduleRepo) CreateSchedule(schedule models.Schedule) (models.Schedule, error) {
+	if !r.user.exists(schedule.UserId) || (schedule.ToId != 0 && !r.user.exists(schedule.ToId)) {
+		return models.Schedule{}, errors.New("user not found")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastId++
+	schedule.ID = r.lastId
+	schedule.CreatedAt = time.Now()
+	schedule.Runs = nil
+	r.schedules = append(r.schedules, schedule)
+
+	r.log.LogRepo("POST", "CreateSchedule", true, schedule)
+	return schedule, nil
+}
+
+func (r *MemoryScheduleRepo) GetSchedule(id int) (models.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.schedule(id)
+	if !ok {
+		return models.Schedule{}, models.ErrScheduleNotFound
+	}
+
+	schedule := r.schedules[i]
+	schedule.Runs = append([]models.ScheduleRun(nil), r.runs[id]...)
+
+	r.log.LogRepo("GET", "GetSchedule", true, schedule)
+	return schedule, nil
+}
+
+func (r *MemoryScheduleRepo) ListSchedules(userId int, status string) ([]models.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schedules := []models.Schedule{}
+	for _, schedule := range r.schedules {
+		if schedule.UserId == userId && (status == "" || schedule.Status == status) {
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	r.log.LogRepo("GET", "ListSchedules", true, schedules)
+	return schedules, nil
+}
+
+func (r *MemoryScheduleRepo) DueSchedules(now time.Time, limit int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.Schedule
+	for _, schedule := range r.schedules {
+		if schedule.Status == models.ScheduleActive && !schedule.NextRunAt.After(now) {
+			due = append(due, schedule)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextRunAt.Before(due[j].NextRunAt) })
+
+	ids := []int{}
+	for _, schedule := range due {
+		if len(ids) == limit {
+			break
+		}
+
+		ids = append(ids, schedule.ID)
+	}
+
+	return ids, nil
+}
+
+func (r *MemoryScheduleRepo) LockSchedule(tx Tx, id int) (models.Schedule, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Schedule{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.schedule(id)
+	if !ok {
+		return models.Schedule{}, models.ErrScheduleNotFound
+	}
+
+	return r.schedules[i], nil
+}
+
+func (r *MemoryScheduleRepo) LockDueSchedule(tx Tx, id int, now time.Time) (models.Schedule, bool, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Schedule{}, false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.schedule(id)
+	if !ok || r.schedules[i].Status != models.ScheduleActive || r.schedules[i].NextRunAt.After(now) {
+		return models.Schedule{}, false, nil
+	}
+
+	return r.schedules[i], true, nil
+}
+
+func (r *MemoryScheduleRepo) UpdateSchedule(tx Tx, schedule models.Schedule) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.schedule(schedule.ID)
+	if !ok {
+		return models.ErrScheduleNotFound
+	}
+
+	previous := r.schedules[i]
+	r.schedules[i].Status = schedule.Status
+	r.schedules[i].Occurrence = schedule.Occurrence
+	r.schedules[i].NextRunAt = schedule.NextRunAt
+	r.schedules[i].Attempts = schedule.Attempts
+	r.schedules[i].LastError = schedule.LastError
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.schedules[i] = previous
+		r.mu.Unlock()
+	})
+
+	return nil
+}
+
+func (r *MemoryScheduleRepo) AddRun(tx Tx, id int, run models.ScheduleRun) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schedule(id); !ok {
+		return models.ErrScheduleNotFound
+	}
+
+	// an occurrence succeeds once like the unique index of postgres makes sure
+	for _, previous := range r.runs[id] {
+		if run.Status == models.ScheduleRunSucceeded && previous.Status == models.ScheduleRunSucceeded &&
+			previous.Occurrence.Equal(run.Occurrence) {
+			return errors.New("occurrence has already succeeded")
+		}
+	}
+
+	count := len(r.runs[id])
+	r.runs[id] = append(r.runs[id], run)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.runs[id] = r.runs[id][:count]
+		r.mu.Unlock()
+	})
+
+	return nil
+}
+
+// schedule returns the index of the schedule, it is called under the lock
+func (r *MemoryScheduleRepo) schedule(id int) (int, bool) {
+	for i, schedule := range r.schedules {
+		if schedule.ID == id {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
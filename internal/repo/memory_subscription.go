@@ -0,0 +1,298 @@
+package repo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemorySubscriptionRepo implements Subscription with the same semantics as SubscriptionRepo
+type MemorySubscriptionRepo struct {
+	user          *MemoryUserRepo
+	mu            sync.Mutex
+	plans         []models.Plan
+	subscriptions []models.Subscription
+	events        []models.SubscriptionEvent
+	// lastPlanId, lastId and lastEventId are not reused after a rollback like postgres serials
+	lastPlanId  int
+	lastId      int
+	lastEventId int
+	log         logging.Logger
+}
+
+func NewMemorySubscriptionRepo(user *MemoryUserRepo, log logging.Logger) *MemorySubscriptionRepo {
+	return &MemorySubscriptionRepo{
+		user: user,
+		log:  log,
+	}
+}
+
+func (r *MemorySubscriptionRepo) CreatePlan(input models.PlanInput) (models.Plan, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastPlanId++
+	plan := models.Plan{
+		ID:        r.lastPlanId,
+		Name:      input.Name,
+		Price:     input.Price,
+		Currency:  input.Currency,
+		Period:    input.Period,
+		TrialDays: input.TrialDays,
+		CreatedAt: time.Now(),
+	}
+	r.plans = append(r.plans, plan)
+
+	r.log.LogRepo("POST", "CreatePlan", true, plan)
+	return plan, nil
+}
+
+func (r *MemorySubscriptionRepo) GetPlan(id int) (models.Plan, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.plan(id)
+	if !ok {
+		return models.Plan{}, models.ErrPlanNotFound
+	}
+
+	return r.plans[i], nil
+}
+
+func (r *MemorySubscriptionRepo) ListPlans() ([]models.Plan, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plans := append([]models.Plan{}, r.plans...)
+
+	r.log.LogRepo("GET", "ListPlans", true, plans)
+	return plans, nil
+}
+
+func (r *MemorySubscriptionRepo) CreateSubscription(tx Tx, subscription models.Subscription) (models.Subscription, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	if _, ok := r.user.users[subscription.UserId]; !ok {
+		return models.Subscription{}, errors.New("user not found")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.plan(subscription.PlanId); !ok {
+		return models.Subscription{}, models.ErrPlanNotFound
+	}
+
+	r.lastId++
+	subscription.ID = r.lastId
+	subscription.CreatedAt = time.Now()
+	subscription.Events = nil
+
+	count := len(r.subscriptions)
+	r.subscriptions = append(r.subscriptions, subscription)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.subscriptions = r.subscriptions[:count]
+		r.mu.Unlock()
+	})
+
+	r.log.LogRepo("POST", "CreateSubscription", true, subscription)
+	return subscription, nil
+}
+
+func (r *MemorySubscriptionRepo) GetSubscription(id int) (models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.subscription(id)
+	if !ok {
+		return models.Subscription{}, models.ErrSubscriptionNotFound
+	}
+
+	subscription := r.subscriptions[i]
+	for _, event := range r.events {
+		if event.SubscriptionId == id {
+			subscription.Events = append(subscription.Events, event)
+		}
+	}
+
+	r.log.LogRepo("GET", "GetSubscription", true, subscription)
+	return subscription, nil
+}
+
+func (r *MemorySubscriptionRepo) ListSubscriptions(userId int, status string) ([]models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscriptions := []models.Subscription{}
+	for _, subscription := range r.subscriptions {
+		if subscription.UserId == userId && (status == "" || subscription.Status == status) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	r.log.LogRepo("GET", "ListSubscriptions", true, subscriptions)
+	return subscriptions, nil
+}
+
+func (r *MemorySubscriptionRepo) DueSubscriptions(now time.Time, limit int) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []models.Subscription
+	for _, subscription := range r.subscriptions {
+		if subscription.Status != models.SubscriptionCancelled && !subscription.NextAttemptAt.After(now) {
+			due = append(due, subscription)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	ids := []int{}
+	for _, subscription := range due {
+		if len(ids) == limit {
+			break
+		}
+
+		ids = append(ids, subscription.ID)
+	}
+
+	return ids, nil
+}
+
+func (r *MemorySubscriptionRepo) LockSubscription(tx Tx, id int) (models.Subscription, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Subscription{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.subscription(id)
+	if !ok {
+		return models.Subscription{}, models.ErrSubscriptionNotFound
+	}
+
+	return r.subscriptions[i], nil
+}
+
+func (r *MemorySubscriptionRepo) LockDueSubscription(tx Tx, id int, now time.Time) (models.Subscription, bool, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Subscription{}, false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.subscription(id)
+	if !ok || r.subscriptions[i].Status == models.SubscriptionCancelled || r.subscriptions[i].NextAttemptAt.After(now) {
+		return models.Subscription{}, false, nil
+	}
+
+	return r.subscriptions[i], true, nil
+}
+
+func (r *MemorySubscriptionRepo) UpdateSubscription(tx Tx, s models.Subscription) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.subscription(s.ID)
+	if !ok {
+		return models.ErrSubscriptionNotFound
+	}
+
+	previous := r.subscriptions[i]
+	updated := previous
+	updated.PlanId = s.PlanId
+	updated.Status = s.Status
+	updated.PeriodStart = s.PeriodStart
+	updated.PeriodEnd = s.PeriodEnd
+	updated.NextAttemptAt = s.NextAttemptAt
+	updated.CancelAtPeriodEnd = s.CancelAtPeriodEnd
+	updated.Attempts = s.Attempts
+	updated.LastError = s.LastError
+	updated.CancelledAt = s.CancelledAt
+	r.subscriptions[i] = updated
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.subscriptions[i] = previous
+		r.mu.Unlock()
+	})
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) AddEvent(tx Tx, event models.SubscriptionEvent) error {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subscription(event.SubscriptionId); !ok {
+		return models.ErrSubscriptionNotFound
+	}
+
+	r.lastEventId++
+	event.ID = r.lastEventId
+
+	count := len(r.events)
+	r.events = append(r.events, event)
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.events = r.events[:count]
+		r.mu.Unlock()
+	})
+
+	return nil
+}
+
+func (r *MemorySubscriptionRepo) GetSubscriptionEvents(afterId, limit int) ([]models.SubscriptionEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []models.SubscriptionEvent{}
+	for _, event := range r.events {
+		if event.ID > afterId && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// plan returns the index of the plan, it is called under the lock
+func (r *MemorySubscriptionRepo) plan(id int) (int, bool) {
+	for i, plan := range r.plans {
+		if plan.ID == id {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// subscription returns the index of the subscription, it is called under the lock
+func (r *MemorySubscriptionRepo) subscription(id int) (int, bool) {
+	for i, subscription := range r.subscriptions {
+		if subscription.ID == id {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
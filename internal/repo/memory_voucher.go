@@ -0,0 +1,203 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// MemoryVoucherRepo implements Voucher with the same semantics as VoucherRepo
+type MemoryVoucherRepo struct {
+	user    *MemoryUserRepo
+	mu      sync.Mutex
+	batches []models.VoucherBatch
+	// vouchers are found by hashes of their codes
+	vouchers    map[string]*memoryVoucher
+	redemptions []models.VoucherRedemption
+	// lastBatchId, lastId and lastRedemptionId are not reused after a rollback like postgres serials
+	lastBatchId      int
+	lastId           int
+	lastRedemptionId int
+	log              logging.Logger
+}
+
+type memoryVoucher struct {
+	id      int
+	batchId int
+	uses    int
+}
+
+func NewMemoryVoucherRepo(user *MemoryUserRepo, log logging.Logger) *MemoryVoucherRepo {
+	return &MemoryVoucherRepo{
+		user:     user,
+		vouchers: make(map[string]*memoryVoucher),
+		log:      log,
+	}
+}
+
+func (r *MemoryVoucherRepo) CreateBatch(tx Tx, batch models.VoucherBatch, hashes []string) (models.VoucherBatch, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return models.VoucherBatch{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		if _, ok := r.vouchers[hash]; ok || seen[hash] {
+			return models.VoucherBatch{}, errors.New("voucher code already exists")
+		}
+
+		seen[hash] = true
+	}
+
+	r.lastBatchId++
+	batch.ID = r.lastBatchId
+	batch.Count = len(hashes)
+	batch.CreatedAt = time.Now()
+	batch.Codes = nil
+
+	count := len(r.batches)
+	r.batches = append(r.batches, batch)
+	for _, hash := range hashes {
+		r.lastId++
+		r.vouchers[hash] = &memoryVoucher{id: r.lastId, batchId: batch.ID}
+	}
+
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.batches = r.batches[:count]
+		for _, hash := range hashes {
+			delete(r.vouchers, hash)
+		}
+		r.mu.Unlock()
+	})
+
+	return batch, nil
+}
+
+func (r *MemoryVoucherRepo) LockVoucher(tx Tx, hash string) (models.Voucher, error) {
+	if _, err := memoryTxOf(tx); err != nil {
+		return models.Voucher{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	voucher, ok := r.vouchers[hash]
+	if !ok {
+		return models.Voucher{}, models.ErrVoucherNotFound
+	}
+
+	for _, batch := range r.batches {
+		if batch.ID == voucher.batchId {
+			return models.Voucher{
+				ID:        voucher.id,
+				BatchId:   batch.ID,
+				Uses:      voucher.uses,
+				Amount:    batch.Amount,
+				Currency:  batch.Currency,
+				MaxUses:   batch.MaxUses,
+				ExpiresAt: batch.ExpiresAt,
+			}, nil
+		}
+	}
+
+	return models.Voucher{}, models.ErrVoucherNotFound
+}
+
+func (r *MemoryVoucherRepo) Redeem(tx Tx, voucher models.Voucher, userId int) (models.VoucherRedemption, error) {
+	memTx, err := memoryTxOf(tx)
+	if err != nil {
+		return models.VoucherRedemption{}, err
+	}
+
+	if _, ok := r.user.users[userId]; !ok {
+		return models.VoucherRedemption{}, errors.New("user not found")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, redemption := range r.redemptions {
+		if redemption.VoucherId == voucher.ID && redemption.UserId == userId {
+			return models.VoucherRedemption{}, fmt.Errorf("%w: voucher is already redeemed by the user",
+				models.ErrVoucherNotRedeemable)
+		}
+	}
+
+	var used *memoryVoucher
+	for _, v := range r.vouchers {
+		if v.id == voucher.ID {
+			used = v
+		}
+	}
+
+	if used == nil {
+		return models.VoucherRedemption{}, models.ErrVoucherNotFound
+	}
+
+	r.lastRedemptionId++
+	redemption := models.VoucherRedemption{
+		ID:         r.lastRedemptionId,
+		VoucherId:  voucher.ID,
+		BatchId:    voucher.BatchId,
+		UserId:     userId,
+		Amount:     voucher.Amount,
+		Currency:   voucher.Currency,
+		RedeemedAt: time.Now(),
+	}
+
+	count := len(r.redemptions)
+	r.redemptions = append(r.redemptions, redemption)
+	used.uses++
+	memTx.onRollback(func() {
+		r.mu.Lock()
+		r.redemptions = r.redemptions[:count]
+		used.uses--
+		r.mu.Unlock()
+	})
+
+	return redemption, nil
+}
+
+func (r *MemoryVoucherRepo) GetVoucherReport(currency string, now time.Time) (models.VoucherReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := models.VoucherReport{Currency: currency, Batches: []models.VoucherBatch{}}
+	for _, batch := range r.batches {
+		if batch.Currency != currency {
+			continue
+		}
+
+		for _, redemption := range r.redemptions {
+			if redemption.BatchId == batch.ID {
+				batch.Redemptions++
+				batch.RedeemedValue += redemption.Amount
+			}
+		}
+
+		report.Batches = append(report.Batches, batch)
+	}
+
+	for _, batch := range report.Batches {
+		issued := batch.IssuedValue()
+		report.IssuedValue += issued
+		report.RedeemedValue += batch.RedeemedValue
+		if batch.Expired(now) {
+			report.ExpiredValue += issued - batch.RedeemedValue
+		} else {
+			report.OutstandingValue += issued - batch.RedeemedValue
+		}
+	}
+
+	r.log.LogRepo("GET", "GetVoucherReport", true, report)
+	return report, nil
+}
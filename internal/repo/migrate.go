@@ -0,0 +1,243 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+// migrationsTable has the same layout as the one of golang-migrate,
+// so databases migrated by the migrate binary keep their version
+const migrationsTable = "schema_migrations"
+
+// migrationLock is the key of advisory lock held while migrations are applied,
+// so concurrently started instances do not apply them twice
+const migrationLock = 7240511
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []migration
+	log        logging.Logger
+}
+
+// NewMigrator reads migrations from files, every version must have both up and down file
+func NewMigrator(db *sqlx.DB, files fs.FS, log logging.Logger) (*Migrator, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: match[2]}
+			byVersion[version] = m
+		}
+
+		if match[3] == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.version, m.name)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		log:        log,
+	}, nil
+}
+
+// Up applies all pending migrations, every migration is applied in its own transaction
+func (m *Migrator) Up() error {
+	return m.locked(func(conn *sql.Conn) error {
+		current, err := m.version(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.version <= current {
+				continue
+			}
+
+			if err := m.apply(conn, migration.up, migration.version); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.version, migration.name, err)
+			}
+
+			m.log.Infof("migration %d_%s applied", migration.version, migration.name)
+		}
+
+		return nil
+	})
+}
+
+// Down reverts the last steps migrations
+func (m *Migrator) Down(steps int) error {
+	return m.locked(func(conn *sql.Conn) error {
+		current, err := m.version(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if migration.version > current {
+				continue
+			}
+
+			var previous int64
+			if i > 0 {
+				previous = m.migrations[i-1].version
+			}
+
+			if err := m.apply(conn, migration.down, previous); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.version, migration.name, err)
+			}
+
+			m.log.Infof("migration %d_%s reverted", migration.version, migration.name)
+			steps--
+		}
+
+		return nil
+	})
+}
+
+// Version returns the version of the last applied migration, zero if none is applied
+func (m *Migrator) Version() (int64, error) {
+	var version int64
+	err := m.locked(func(conn *sql.Conn) error {
+		var err error
+		version, err = m.version(conn)
+		return err
+	})
+
+	return version, err
+}
+
+// Seed executes fixture, it is meant for development databases only
+func (m *Migrator) Seed(fixture string) error {
+	return m.locked(func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(context.Background(), fixture)
+		return err
+	})
+}
+
+// locked runs f on a single connection holding migration lock
+func (m *Migrator) locked(f func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version bigint not null primary key, dirty boolean not null)",
+		migrationsTable)
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return err
+	}
+
+	return f(conn)
+}
+
+func (m *Migrator) version(conn *sql.Conn) (int64, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	query := fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", migrationsTable)
+	err := conn.QueryRowContext(context.Background(), query).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("database is dirty at version %d, fix it and force the version manually", version)
+	}
+
+	return version, nil
+}
+
+// apply executes migration and saves the new version in one transaction
+func (m *Migrator) apply(conn *sql.Conn, query string, version int64) error {
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", migrationsTable)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if version > 0 {
+		insert := fmt.Sprintf("INSERT INTO %s (version, dirty) VALUES ($1, false)", migrationsTable)
+		if _, err := tx.ExecContext(ctx, insert, version); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,185 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = fstest.MapFS{
+	"1_users.up.sql":          {Data: []byte("CREATE TABLE users (id serial primary key)")},
+	"1_users.down.sql":        {Data: []byte("DROP TABLE users")},
+	"2_wallets.up.sql":        {Data: []byte("CREATE TABLE wallets (user_id int)")},
+	"2_wallets.down.sql":      {Data: []byte("DROP TABLE wallets")},
+	"3_transactions.up.sql":   {Data: []byte("CREATE TABLE transactions (id serial primary key)")},
+	"3_transactions.down.sql": {Data: []byte("DROP TABLE transactions")},
+	"schema.go":               {Data: []byte("package schema")},
+}
+
+func TestMigrator_Up(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	m, err := NewMigrator(sqlxDB, testMigrations, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type mockBehavior func()
+
+	expectLock := func() {
+		mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(migrationLock).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s", migrationsTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	expectUnlock := func() {
+		mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(migrationLock).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	expectApply := func(query string, version int64) {
+		mock.ExpectBegin()
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(fmt.Sprintf("DELETE FROM %s", migrationsTable)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", migrationsTable)).WithArgs(version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Empty database",
+			mock: func() {
+				expectLock()
+				mock.ExpectQuery(fmt.Sprintf("SELECT version, dirty FROM %s", migrationsTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+				expectApply("CREATE TABLE users", 1)
+				expectApply("CREATE TABLE wallets", 2)
+				expectApply("CREATE TABLE transactions", 3)
+				expectUnlock()
+			},
+		},
+		{
+			name: "Pending migrations",
+			mock: func() {
+				expectLock()
+				mock.ExpectQuery(fmt.Sprintf("SELECT version, dirty FROM %s", migrationsTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(2, false))
+				expectApply("CREATE TABLE transactions", 3)
+				expectUnlock()
+			},
+		},
+		{
+			name: "Dirty database",
+			mock: func() {
+				expectLock()
+				mock.ExpectQuery(fmt.Sprintf("SELECT version, dirty FROM %s", migrationsTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(2, true))
+				expectUnlock()
+			},
+			wantErr:   true,
+			wantedErr: "database is dirty at version 2, fix it and force the version manually",
+		},
+		{
+			name: "Failed migration",
+			mock: func() {
+				expectLock()
+				mock.ExpectQuery(fmt.Sprintf("SELECT version, dirty FROM %s", migrationsTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(2, false))
+				mock.ExpectBegin()
+				mock.ExpectExec("CREATE TABLE transactions").WillReturnError(errors.New(`relation "transactions" already exists`))
+				mock.ExpectRollback()
+				expectUnlock()
+			},
+			wantErr:   true,
+			wantedErr: `migration 3_transactions: relation "transactions" already exists`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := m.Up()
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantedErr, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrator_Down(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	m, err := NewMigrator(sqlxDB, testMigrations, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WithArgs(migrationLock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s", migrationsTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(fmt.Sprintf("SELECT version, dirty FROM %s", migrationsTable)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(2, false))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP TABLE wallets").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(fmt.Sprintf("DELETE FROM %s", migrationsTable)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", migrationsTable)).WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DROP TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(fmt.Sprintf("DELETE FROM %s", migrationsTable)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(migrationLock).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, m.Down(5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewMigrator_MissingDown(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = NewMigrator(nil, fstest.MapFS{
+		"1_users.up.sql": {Data: []byte("CREATE TABLE users (id serial primary key)")},
+	}, logger)
+	assert.Equal(t, errors.New("migration 1_users must have both up and down files"), err)
+}
@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// balanceChangesChannel is notified by transactions_notify_balance_change trigger with "user_id:id" of every transaction
+const balanceChangesChannel = "balance_changes"
+
+// changesPingInterval is how often an idle listener checks its connection
+const changesPingInterval = 30 * time.Second
+
+type Changes interface {
+	// ListenChanges sends committed transactions in commit order until stop is closed.
+	// A zero transaction is sent when notifications may have been lost, e.g. after a reconnect
+	ListenChanges(stop <-chan struct{}) (<-chan models.Transaction, error)
+}
+
+// ChangeListener listens to notifications of postgres, they are sent on commit,
+// so committed transactions are received in commit order
+type ChangeListener struct {
+	dsn string
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewChangeListener(dsn string, db *sqlx.DB, log logging.Logger) *ChangeListener {
+	return &ChangeListener{
+		dsn: dsn,
+		db:  db,
+		log: log,
+	}
+}
+
+func (l *ChangeListener) ListenChanges(stop <-chan struct{}) (<-chan models.Transaction, error) {
+	listener := pq.NewListener(l.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			l.log.Infof("balance changes listener: %s", err.Error())
+		}
+	})
+
+	if err := listener.Listen(balanceChangesChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	out := make(chan models.Transaction)
+	go func() {
+		defer close(out)
+		defer listener.Close()
+
+		ticker := time.NewTicker(changesPingInterval)
+		defer ticker.Stop()
+
+		for {
+			var transaction models.Transaction
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				go listener.Ping()
+				continue
+			case notification := <-listener.Notify:
+				// nil notification means the connection was reestablished and notifications may be lost
+				if notification != nil {
+					var err error
+					transaction, err = l.transaction(notification.Extra)
+					// a transaction which can not be loaded is reported as lost
+					if err != nil {
+						l.log.Infof("failed to load changed transaction %q: %s", notification.Extra, err.Error())
+					}
+				}
+			}
+
+			select {
+			case <-stop:
+				return
+			case out <- transaction:
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// transaction loads transaction of notification payload
+func (l *ChangeListener) transaction(payload string) (models.Transaction, error) {
+	_, id, ok := strings.Cut(payload, ":")
+	if !ok {
+		return models.Transaction{}, errors.New("incorrect payload")
+	}
+
+	transactionId, err := strconv.Atoi(id)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	var transactions []models.TransactionDTO
+	query := fmt.Sprintf(`SELECT id, user_id, amount, currency, balance_after, operation, link_id,
+		to_char(date, 'YYYY-MM-DD HH24:MI:SS') AS date
+		FROM %s WHERE id = $1`, transactionsTable)
+
+	if err := l.db.Select(&transactions, query, transactionId); err != nil {
+		return models.Transaction{}, err
+	}
+
+	result, err := toTransactions(transactions)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	if len(result) == 0 {
+		return models.Transaction{}, errors.New("transaction not found")
+	}
+
+	return result[0], nil
+}
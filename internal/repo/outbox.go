@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type Outbox interface {
+	// LockOutboxEvents locks at most limit unpublished events in id order until tx ends,
+	// events locked by another unit of work are skipped
+	LockOutboxEvents(tx Tx, limit int) ([]models.OutboxEvent, error)
+	// MarkOutboxPublished saves events as published at the moment as a part of tx
+	MarkOutboxPublished(tx Tx, ids []int64, at time.Time) error
+	// FailOutboxEvents counts a failed attempt to publish events as a part of tx
+	FailOutboxEvents(tx Tx, ids []int64, reason string) error
+	// ReplayOutbox marks selected events as unpublished, so they are published again, and returns their number
+	ReplayOutbox(input models.OutboxReplayInput) (int, error)
+	// PruneOutbox deletes published events created before the moment, undelivered ones too if undelivered
+	// is true, and returns their number
+	PruneOutbox(before time.Time, undelivered bool) (int, error)
+}
+
+type OutboxRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewOutboxRepo(db *sqlx.DB, log logging.Logger) *OutboxRepo {
+	return &OutboxRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *OutboxRepo) LockOutboxEvents(unit Tx, limit int) ([]models.OutboxEvent, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`SELECT id, type, key, payload, created_at, attempts, last_error FROM %s
+		WHERE published_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, outboxEventsTable)
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var (
+			event   models.OutboxEvent
+			payload []byte
+		)
+
+		err := rows.Scan(&event.ID, &event.Type, &event.Key, &payload, &event.CreatedAt, &event.Attempts,
+			&event.LastError)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *OutboxRepo) MarkOutboxPublished(unit Tx, ids []int64, at time.Time) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET published_at = $2, last_error = '' WHERE id = ANY($1)", outboxEventsTable)
+	_, err = tx.Exec(query, pq.Array(ids), at)
+	return err
+}
+
+func (r *OutboxRepo) FailOutboxEvents(unit Tx, ids []int64, reason string) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, last_error = $2 WHERE id = ANY($1)",
+		outboxEventsTable)
+	_, err = tx.Exec(query, pq.Array(ids), reason)
+	return err
+}
+
+func (r *OutboxRepo) ReplayOutbox(input models.OutboxReplayInput) (int, error) {
+	query := fmt.Sprintf(`UPDATE %s SET published_at = NULL, attempts = 0, last_error = ''
+		WHERE id >= $1 AND ($2 = 0 OR id <= $2) AND ($3 = '' OR type = $3) AND published_at IS NOT NULL`,
+		outboxEventsTable)
+	res, err := r.db.Exec(query, input.FromId, input.ToId, input.Type)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	r.log.LogRepo("POST", "ReplayOutbox", true, input)
+	return int(replayed), nil
+}
+
+func (r *OutboxRepo) PruneOutbox(before time.Time, undelivered bool) (int, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE created_at < $1 AND ($2 OR published_at IS NOT NULL)",
+		outboxEventsTable)
+	res, err := r.db.Exec(query, before, undelivered)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned, err := res.RowsAffected()
+	return int(pruned), err
+}
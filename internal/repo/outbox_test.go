@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxRepository_LockOutboxEvents(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewOutboxRepo(sqlxDB, logger)
+	createdAt := time.Date(2023, 9, 10, 12, 0, 0, 0, time.UTC)
+	payload := `{"transaction_id": 7, "user_id": 1, "amount": -10}`
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE published_at IS NULL ORDER BY id LIMIT (.+) FOR UPDATE SKIP LOCKED",
+		outboxEventsTable)).WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type", "key", "payload", "created_at", "attempts", "last_error"}).
+			AddRow(3, models.OutboxBalanceChanged, "1", []byte(payload), createdAt, 1, "timeout"))
+	mock.ExpectExec(fmt.Sprintf("UPDATE %s SET published_at (.+) WHERE id = ANY", outboxEventsTable)).
+		WithArgs(sqlmock.AnyArg(), createdAt).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var events []models.OutboxEvent
+	err = NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+		var err error
+		events, err = r.LockOutboxEvents(tx, 10)
+		if err != nil {
+			return err
+		}
+
+		return r.MarkOutboxPublished(tx, []int64{3}, createdAt)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.OutboxEvent{{ID: 3, Type: models.OutboxBalanceChanged, Key: "1",
+		Payload: json.RawMessage(payload), CreatedAt: createdAt, Attempts: 1, LastError: "timeout"}}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxRepository_ReplayOutbox(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewOutboxRepo(sqlxDB, logger)
+	input := models.OutboxReplayInput{FromId: 10, ToId: 20, Type: models.OutboxBalanceChanged}
+
+	mock.ExpectExec(fmt.Sprintf("UPDATE %s SET published_at = NULL(.+) WHERE (.+) published_at IS NOT NULL",
+		outboxEventsTable)).WithArgs(input.FromId, input.ToId, input.Type).WillReturnResult(sqlmock.NewResult(0, 11))
+
+	replayed, err := r.ReplayOutbox(input)
+	assert.NoError(t, err)
+	assert.Equal(t, 11, replayed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Promo interface {
+	// CreateGrant saves active grant as a part of tx, the grant is credited by the caller
+	CreateGrant(tx Tx, grant models.PromoGrant) (models.PromoGrant, error)
+	GetGrant(id int) (models.PromoGrant, error)
+	ListGrants(userId int, status string) ([]models.PromoGrant, error)
+	// SpendPromo takes the promo part of a purchase of amount from grants of the wallet active at now, the grant
+	// expiring first is spent first. When first is false promo pays only what real money can not.
+	// It returns the part paid with promo, the wallet stays locked until tx ends
+	SpendPromo(tx Tx, userId int, currency string, amount float32, first bool, now time.Time) (float32, error)
+	// ExpiredGrants returns ids of active grants expired at now, at most limit
+	ExpiredGrants(now time.Time, limit int) ([]int, error)
+	// LockExpiredGrant locks the user, the wallet and the grant if it is still active and expired, locked grant is skipped
+	LockExpiredGrant(tx Tx, id int, now time.Time) (models.PromoGrant, bool, error)
+	ExpireGrant(tx Tx, grant models.PromoGrant) error
+}
+
+type PromoRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewPromoRepo(db *sqlx.DB, log logging.Logger) *PromoRepo {
+	return &PromoRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// promoGrantColumns are selected for every promo grant
+const promoGrantColumns = `id, user_id, amount, remaining, currency, campaign_id, status, expires_at, created_at,
+	expired_at`
+
+func (r *PromoRepo) CreateGrant(unit Tx, grant models.PromoGrant) (models.PromoGrant, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.PromoGrant{}, err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, amount, remaining, currency, campaign_id, status, expires_at)
+		SELECT id, $2, $2, $3, $4, $5, $6 FROM %s WHERE id = $1 RETURNING id, created_at`, promoGrantsTable, usersTable)
+
+	err = tx.QueryRow(query, grant.UserId, grant.Amount, grant.Currency, grant.CampaignId, grant.Status,
+		grant.ExpiresAt).Scan(&grant.ID, &grant.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.PromoGrant{}, errors.New("user not found")
+		}
+
+		return models.PromoGrant{}, err
+	}
+
+	return grant, nil
+}
+
+func (r *PromoRepo) GetGrant(id int) (models.PromoGrant, error) {
+	var grant models.PromoGrant
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", promoGrantColumns, promoGrantsTable)
+	if err := r.db.Get(&grant, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.PromoGrant{}, models.ErrPromoGrantNotFound
+		}
+
+		return models.PromoGrant{}, err
+	}
+
+	r.log.LogRepo("GET", "GetGrant", true, grant)
+	return grant, nil
+}
+
+func (r *PromoRepo) ListGrants(userId int, status string) ([]models.PromoGrant, error) {
+	grants := []models.PromoGrant{}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = $1 AND ($2 = '' OR status = $2) ORDER BY id",
+		promoGrantColumns, promoGrantsTable)
+	if err := r.db.Select(&grants, query, userId, status); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListGrants", true, grants)
+	return grants, nil
+}
+
+func (r *PromoRepo) SpendPromo(unit Tx, userId int, currency string, amount float32, first bool,
+	now time.Time) (float32, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return 0, err
+	}
+
+	// the user and the wallet are locked before grants like by debits and expiry, so they never wait for each
+	// other in reverse order
+	if err := lockUser(userId, tx); err != nil {
+		return 0, err
+	}
+
+	var available, promo float32
+	wallet := fmt.Sprintf(`SELECT balance - promo + credit_limit - held, promo FROM %s
+		WHERE user_id = $1 AND currency = $2 FOR UPDATE`, walletsTable)
+	err = tx.QueryRow(wallet, userId, currency).Scan(&available, &promo)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	want := amount
+	if !first {
+		want = amount - available
+	}
+
+	if want > promo {
+		want = promo
+	}
+
+	if want <= 0 {
+		return 0, nil
+	}
+
+	query := fmt.Sprintf(`SELECT id, remaining FROM %s
+		WHERE user_id = $1 AND currency = $2 AND status = $3 AND remaining > 0 AND expires_at > $4
+		ORDER BY expires_at, id FOR UPDATE`, promoGrantsTable)
+	rows, err := tx.Query(query, userId, currency, models.PromoActive, now)
+	if err != nil {
+		return 0, err
+	}
+
+	type grant struct {
+		id        int
+		remaining float32
+	}
+
+	var grants []grant
+	for rows.Next() {
+		var g grant
+		if err := rows.Scan(&g.id, &g.remaining); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		grants = append(grants, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var spent float32
+	update := fmt.Sprintf("UPDATE %s SET remaining = remaining - $2 WHERE id = $1", promoGrantsTable)
+	for _, g := range grants {
+		if spent >= want {
+			break
+		}
+
+		part := g.remaining
+		if part > want-spent {
+			part = want - spent
+		}
+
+		if _, err := tx.Exec(update, g.id, part); err != nil {
+			return 0, err
+		}
+
+		spent += part
+	}
+
+	return spent, nil
+}
+
+func (r *PromoRepo) ExpiredGrants(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	query := fmt.Sprintf("SELECT id FROM %s WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at, id LIMIT $3",
+		promoGrantsTable)
+	if err := r.db.Select(&ids, query, models.PromoActive, now, limit); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *PromoRepo) LockExpiredGrant(unit Tx, id int, now time.Time) (models.PromoGrant, bool, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.PromoGrant{}, false, err
+	}
+
+	var (
+		userId   int
+		currency string
+	)
+	err = tx.QueryRow(fmt.Sprintf("SELECT user_id, currency FROM %s WHERE id = $1", promoGrantsTable), id).
+		Scan(&userId, &currency)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.PromoGrant{}, false, nil
+		}
+
+		return models.PromoGrant{}, false, err
+	}
+
+	if err := lockUser(userId, tx); err != nil {
+		return models.PromoGrant{}, false, err
+	}
+
+	wallet := fmt.Sprintf("SELECT 1 FROM %s WHERE user_id = $1 AND currency = $2 FOR UPDATE", walletsTable)
+	if _, err := tx.Exec(wallet, userId, currency); err != nil {
+		return models.PromoGrant{}, false, err
+	}
+
+	var grant models.PromoGrant
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND status = $2 AND expires_at <= $3
+		FOR UPDATE SKIP LOCKED`, promoGrantColumns, promoGrantsTable)
+	err = tx.QueryRow(query, id, models.PromoActive, now).Scan(&grant.ID, &grant.UserId, &grant.Amount,
+		&grant.Remaining, &grant.Currency, &grant.CampaignId, &grant.Status, &grant.ExpiresAt, &grant.CreatedAt,
+		&grant.ExpiredAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.PromoGrant{}, false, nil
+		}
+
+		return models.PromoGrant{}, false, err
+	}
+
+	return grant, true, nil
+}
+
+func (r *PromoRepo) ExpireGrant(unit Tx, grant models.PromoGrant) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET status = $2, remaining = $3, expired_at = $4 WHERE id = $1", promoGrantsTable)
+	_, err = tx.Exec(query, grant.ID, grant.Status, grant.Remaining, grant.ExpiredAt)
+	return err
+}
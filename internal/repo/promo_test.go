@@ -0,0 +1,164 @@
+package repo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromoRepository_SpendPromo(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewPromoRepo(sqlxDB, logger)
+	now := time.Date(2023, 8, 25, 12, 0, 0, 0, time.UTC)
+
+	lockWallet := func(available, promo float32) {
+		mock.ExpectExec(fmt.Sprintf("SELECT 1 FROM %s WHERE (.+) FOR SHARE", usersTable)).WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+			WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"available", "promo"}).AddRow(available, promo))
+	}
+	lockGrants := func() {
+		mock.ExpectQuery(fmt.Sprintf("SELECT id, remaining FROM %s WHERE (.+) ORDER BY expires_at, id FOR UPDATE",
+			promoGrantsTable)).WithArgs(1, "EUR", models.PromoActive, now).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "remaining"}).AddRow(3, 10).AddRow(5, 30))
+	}
+	spend := func(id int, amount float32) {
+		mock.ExpectExec(fmt.Sprintf("UPDATE %s SET remaining = remaining - (.+) WHERE id = (.+)", promoGrantsTable)).
+			WithArgs(id, amount).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	tests := []struct {
+		name   string
+		amount float32
+		first  bool
+		mock   func()
+		want   float32
+	}{
+		{
+			name:   "Promo first",
+			amount: 25,
+			first:  true,
+			mock: func() {
+				lockWallet(100, 40)
+				lockGrants()
+				spend(3, 10)
+				spend(5, 15)
+			},
+			want: 25,
+		},
+		{
+			name:   "Promo pays the rest",
+			amount: 110,
+			mock: func() {
+				lockWallet(100, 40)
+				lockGrants()
+				spend(3, 10)
+			},
+			want: 10,
+		},
+		{
+			name:   "Real money is enough",
+			amount: 50,
+			mock: func() {
+				lockWallet(100, 40)
+			},
+		},
+		{
+			name:   "Promo is not enough",
+			amount: 60,
+			first:  true,
+			mock: func() {
+				lockWallet(10, 40)
+				lockGrants()
+				spend(3, 10)
+				spend(5, 30)
+			},
+			want: 40,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+			mock.ExpectCommit()
+
+			var got float32
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, err = r.SpendPromo(tx, 1, "EUR", tt.amount, tt.first, now)
+				return err
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPromoRepository_LockExpiredGrant(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewPromoRepo(sqlxDB, logger)
+	now := time.Date(2023, 8, 25, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(-time.Hour)
+
+	// the user is locked before the wallet like by debits, so expiry never deadlocks with them
+	mock.ExpectBegin()
+	mock.ExpectQuery(fmt.Sprintf("SELECT user_id, currency FROM %s WHERE (.+)", promoGrantsTable)).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "currency"}).AddRow(1, "EUR"))
+	mock.ExpectExec(fmt.Sprintf("SELECT 1 FROM %s WHERE (.+) FOR SHARE", usersTable)).WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(fmt.Sprintf("SELECT 1 FROM %s WHERE (.+) FOR UPDATE", walletsTable)).WithArgs(1, "EUR").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE SKIP LOCKED", promoGrantsTable)).
+		WithArgs(3, models.PromoActive, now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "remaining", "currency", "campaign_id",
+			"status", "expires_at", "created_at", "expired_at"}).
+			AddRow(3, 1, 30, 10, "EUR", "spring", models.PromoActive, expiresAt, expiresAt, nil))
+	mock.ExpectCommit()
+
+	var (
+		got models.PromoGrant
+		ok  bool
+	)
+	err = NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+		var err error
+		got, ok, err = r.LockExpiredGrant(tx, 3, now)
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, models.PromoGrant{ID: 3, UserId: 1, Amount: 30, Remaining: 10, Currency: "EUR",
+		CampaignId: "spring", Status: models.PromoActive, ExpiresAt: expiresAt, CreatedAt: expiresAt}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
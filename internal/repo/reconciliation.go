@@ -0,0 +1,242 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Reconciliation interface {
+	Reconcile(actor string) (models.ReconciliationReport, error)
+	GetReconciliation(id int) (models.ReconciliationReport, error)
+	GetLastReconciliation() (models.ReconciliationReport, error)
+	ApproveCorrections(id int, operator string) (models.ReconciliationReport, error)
+}
+
+type ReconciliationRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewReconciliationRepo(db *sqlx.DB, log logging.Logger) *ReconciliationRepo {
+	return &ReconciliationRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// reconciliationColumns are selected for every report
+const reconciliationColumns = "id, status, wallets, mismatches, created_by, started_at, finished_at, approved_by, approved_at"
+
+// Reconcile recomputes balance of every wallet from the transaction log and saves the report.
+// Transactions without a wallet are reported too. Both are read from one snapshot,
+// so balance changes made during reconciliation are not reported as mismatches
+func (r *ReconciliationRepo) Reconcile(actor string) (models.ReconciliationReport, error) {
+	report := models.ReconciliationReport{
+		CreatedBy:  actor,
+		StartedAt:  time.Now(),
+		Mismatches: models.ReconciliationMismatches{},
+	}
+
+	tx, err := r.db.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return models.ReconciliationReport{}, err
+	}
+
+	count := fmt.Sprintf("SELECT COUNT(*) FROM %s", walletsTable)
+	if err := tx.Get(&report.Wallets, count); err != nil {
+		tx.Rollback()
+		return models.ReconciliationReport{}, err
+	}
+
+	query := fmt.Sprintf(`SELECT user_id, currency, balance, expected, balance - expected AS difference, transactions FROM (
+		SELECT COALESCE(w.user_id, t.user_id) AS user_id, COALESCE(w.currency, t.currency) AS currency,
+			COALESCE(w.balance, 0) AS balance, COALESCE(t.expected, 0) AS expected,
+			COALESCE(t.transactions, 0) AS transactions
+		FROM %s w FULL JOIN (SELECT user_id, currency, SUM(direction * amount) AS expected, COUNT(*) AS transactions
+			FROM %s GROUP BY user_id, currency) t ON t.user_id = w.user_id AND t.currency = w.currency
+		) s WHERE abs(balance - expected) > $1 ORDER BY user_id, currency`, walletsTable, transactionsTable)
+	if err := tx.Select(&report.Mismatches, query, models.ReconciliationTolerance); err != nil {
+		tx.Rollback()
+		return models.ReconciliationReport{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ReconciliationReport{}, err
+	}
+
+	report.Status = models.ReconciliationOk
+	if len(report.Mismatches) > 0 {
+		report.Status = models.ReconciliationMismatched
+	}
+	report.FinishedAt = time.Now()
+
+	insert := fmt.Sprintf(`INSERT INTO %s (status, wallets, mismatches, created_by, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`, reconciliationTable)
+	err = r.db.QueryRow(insert, report.Status, report.Wallets, report.Mismatches, report.CreatedBy,
+		report.StartedAt, report.FinishedAt).Scan(&report.ID)
+	if err != nil {
+		return models.ReconciliationReport{}, err
+	}
+
+	r.log.LogRepo("POST", "Reconcile", true, report)
+	return report, nil
+}
+
+func (r *ReconciliationRepo) GetReconciliation(id int) (models.ReconciliationReport, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", reconciliationColumns, reconciliationTable)
+	return r.get(query, id)
+}
+
+func (r *ReconciliationRepo) GetLastReconciliation() (models.ReconciliationReport, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY id DESC LIMIT 1", reconciliationColumns, reconciliationTable)
+	return r.get(query)
+}
+
+func (r *ReconciliationRepo) get(query string, args ...interface{}) (models.ReconciliationReport, error) {
+	var report models.ReconciliationReport
+	if err := r.db.Get(&report, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return models.ReconciliationReport{}, models.ErrReconciliationNotFound
+		}
+
+		return models.ReconciliationReport{}, err
+	}
+
+	r.log.LogRepo("GET", "GetReconciliation", true, report)
+	return report, nil
+}
+
+// ApproveCorrections writes correcting entries for every mismatch of the report, so the transaction log
+// matches wallet balances again. Wallet balances are not changed. Corrections must be approved
+// by another operator than the one who ran reconciliation
+func (r *ReconciliationRepo) ApproveCorrections(id int, operator string) (models.ReconciliationReport, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.ReconciliationReport{}, err
+	}
+
+	report, err := r.approveTx(id, operator, tx)
+	if err != nil {
+		tx.Rollback()
+		return models.ReconciliationReport{}, err
+	}
+
+	r.log.LogRepo("POST", "ApproveCorrections", true, report)
+	return report, tx.Commit()
+}
+
+func (r *ReconciliationRepo) approveTx(id int, operator string, tx *sql.Tx) (models.ReconciliationReport, error) {
+	var report models.ReconciliationReport
+	query := fmt.Sprintf("SELECT id, status, wallets, mismatches, created_by, started_at, finished_at FROM %s WHERE id = $1 FOR UPDATE",
+		reconciliationTable)
+
+	err := tx.QueryRow(query, id).Scan(&report.ID, &report.Status, &report.Wallets, &report.Mismatches,
+		&report.CreatedBy, &report.StartedAt, &report.FinishedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ReconciliationReport{}, models.ErrReconciliationNotFound
+		}
+
+		return models.ReconciliationReport{}, err
+	}
+
+	switch report.Status {
+	case models.ReconciliationOk:
+		return models.ReconciliationReport{}, errors.New("reconciliation report has no mismatches")
+	case models.ReconciliationCorrected:
+		return models.ReconciliationReport{}, errors.New("corrections are already approved")
+	}
+
+	if report.CreatedBy == operator {
+		return models.ReconciliationReport{}, fmt.Errorf("corrections %w", models.ErrSameOperator)
+	}
+
+	for _, mismatch := range report.Mismatches {
+		if err := r.correct(report.ID, mismatch, tx); err != nil {
+			return models.ReconciliationReport{}, err
+		}
+	}
+
+	now := time.Now()
+	report.Status = models.ReconciliationCorrected
+	report.ApprovedBy = operator
+	report.ApprovedAt = &now
+
+	update := fmt.Sprintf("UPDATE %s SET status = $2, approved_by = $3, approved_at = $4 WHERE id = $1", reconciliationTable)
+	if _, err := tx.Exec(update, report.ID, report.Status, report.ApprovedBy, report.ApprovedAt); err != nil {
+		return models.ReconciliationReport{}, err
+	}
+
+	return report, nil
+}
+
+// correct writes correcting entry for the mismatch. Wallet is locked and the difference is recomputed,
+// entry is written only if it has not changed since the report was made. Missing wallet is restored
+// with zero balance, so the correcting entry is chained after the last transaction of the wallet
+func (r *ReconciliationRepo) correct(id int, mismatch models.ReconciliationMismatch, tx *sql.Tx) error {
+	var (
+		balance  float32
+		prevHash string
+	)
+
+	wallet := fmt.Sprintf("SELECT balance, last_hash FROM %s WHERE user_id = $1 AND currency = $2 FOR UPDATE", walletsTable)
+	err := tx.QueryRow(wallet, mismatch.UserId, mismatch.Currency).Scan(&balance, &prevHash)
+	if err == sql.ErrNoRows {
+		restore := fmt.Sprintf(`INSERT INTO %s (user_id, currency, balance, last_hash) VALUES ($1, $2, 0,
+			COALESCE((SELECT hash FROM %s WHERE user_id = $1 AND currency = $2 ORDER BY id DESC LIMIT 1), ''))
+			RETURNING last_hash`, walletsTable, transactionsTable)
+		err = tx.QueryRow(restore, mismatch.UserId, mismatch.Currency).Scan(&prevHash)
+	}
+	if err != nil {
+		return err
+	}
+
+	var expected float32
+	sum := fmt.Sprintf("SELECT COALESCE(SUM(direction * amount), 0) FROM %s WHERE user_id = $1 AND currency = $2",
+		transactionsTable)
+	if err := tx.QueryRow(sum, mismatch.UserId, mismatch.Currency).Scan(&expected); err != nil {
+		return err
+	}
+
+	if math.Abs(float64(balance-expected-mismatch.Difference)) > models.ReconciliationTolerance {
+		return fmt.Errorf("balance of user %d in %s has changed since reconciliation, run it again",
+			mismatch.UserId, mismatch.Currency)
+	}
+
+	link := models.ChainLink{
+		UserId:       mismatch.UserId,
+		Currency:     mismatch.Currency,
+		Direction:    1,
+		Amount:       float32(math.Abs(float64(mismatch.Difference))),
+		Operation:    fmt.Sprintf("Reconciliation #%d correction %f%s", id, mismatch.Difference, mismatch.Currency),
+		Date:         time.Now(),
+		BalanceAfter: balance,
+		PrevHash:     prevHash,
+	}
+
+	if mismatch.Difference < 0 {
+		link.Direction = -1
+	}
+	link.Hash = link.ComputeHash()
+
+	insert := fmt.Sprintf(`INSERT INTO %s (user_id, amount, currency, operation, date, balance_after, link_id, direction,
+		prev_hash, hash) VALUES ($1, $2, $3, $4, $5, $6, '', $7, $8, $9)`, transactionsTable)
+
+	_, err = tx.Exec(insert, link.UserId, link.Amount, link.Currency, link.Operation,
+		link.Date.Format(models.TransactionDateLayout), link.BalanceAfter, link.Direction, link.PrevHash, link.Hash)
+	if err != nil {
+		return err
+	}
+
+	head := fmt.Sprintf("UPDATE %s SET last_hash = $3 WHERE user_id = $1 AND currency = $2", walletsTable)
+	_, err = tx.Exec(head, link.UserId, link.Currency, link.Hash)
+	return err
+}
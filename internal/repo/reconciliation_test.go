@@ -0,0 +1,245 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconciliationRepository_Reconcile(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewReconciliationRepo(sqlxDB, logger)
+
+	type mockBehavior func()
+
+	mismatchColumns := []string{"user_id", "currency", "balance", "expected", "difference", "transactions"}
+
+	tests := []struct {
+		name       string
+		mock       mockBehavior
+		wantStatus string
+		want       models.ReconciliationMismatches
+		wantErr    bool
+	}{
+		{
+			name: "Mismatches",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("SELECT COUNT(.+) FROM %s", walletsTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s w FULL JOIN (.+) FROM %s", walletsTable, transactionsTable)).
+					WithArgs(models.ReconciliationTolerance).
+					WillReturnRows(sqlmock.NewRows(mismatchColumns).
+						AddRow(1, "EUR", 4.13, 30, -25.87, 1).
+						AddRow(2, "USD", 0, 10, -10, 2))
+				mock.ExpectCommit()
+
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) RETURNING id", reconciliationTable)).
+					WithArgs(models.ReconciliationMismatched, 3, sqlmock.AnyArg(), "alice", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+			wantStatus: models.ReconciliationMismatched,
+			want: models.ReconciliationMismatches{
+				{UserId: 1, Currency: "EUR", Balance: 4.13, Expected: 30, Difference: -25.87, Transactions: 1},
+				{UserId: 2, Currency: "USD", Balance: 0, Expected: 10, Difference: -10, Transactions: 2},
+			},
+		},
+		{
+			name: "No mismatches",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("SELECT COUNT(.+) FROM %s", walletsTable)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s w FULL JOIN (.+) FROM %s", walletsTable, transactionsTable)).
+					WithArgs(models.ReconciliationTolerance).
+					WillReturnRows(sqlmock.NewRows(mismatchColumns))
+				mock.ExpectCommit()
+
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) RETURNING id", reconciliationTable)).
+					WithArgs(models.ReconciliationOk, 3, sqlmock.AnyArg(), "alice", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			},
+			wantStatus: models.ReconciliationOk,
+			want:       models.ReconciliationMismatches{},
+		},
+		{
+			name: "Query fails",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("SELECT COUNT(.+) FROM %s", walletsTable)).
+					WillReturnError(errors.New("connection reset"))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.Reconcile("alice")
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantStatus, got.Status)
+				assert.Equal(t, tt.want, got.Mismatches)
+				assert.Equal(t, 3, got.Wallets)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReconciliationRepository_ApproveCorrections(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewReconciliationRepo(sqlxDB, logger)
+
+	type mockBehavior func(id int)
+
+	reportColumns := []string{"id", "status", "wallets", "mismatches", "created_by", "started_at", "finished_at"}
+	mismatches := `[{"user_id":1,"currency":"EUR","balance":4.13,"expected":30,"difference":-25.87,"transactions":1}]`
+
+	expectReport := func(id int, status string) {
+		rows := sqlmock.NewRows(reportColumns).AddRow(id, status, 3, mismatches, "alice", time.Now(), time.Now())
+		mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", reconciliationTable)).
+			WithArgs(id).WillReturnRows(rows)
+	}
+
+	tests := []struct {
+		name      string
+		id        int
+		operator  string
+		mock      mockBehavior
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name:     "Ok",
+			id:       1,
+			operator: "bob",
+			mock: func(id int) {
+				mock.ExpectBegin()
+				expectReport(id, models.ReconciliationMismatched)
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash"}).AddRow(4.13, ""))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", transactionsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(30))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(1, float32(25.87), "EUR", fmt.Sprintf("Reconciliation #%d correction %fEUR", id, float32(-25.87)),
+						time.Now().Format("01-02-2006 15:04:05"), float32(4.13), -1, "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET last_hash", walletsTable)).
+					WithArgs(1, "EUR", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET status", reconciliationTable)).
+					WithArgs(id, models.ReconciliationCorrected, "bob", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:     "Balance changed",
+			id:       1,
+			operator: "bob",
+			mock: func(id int) {
+				mock.ExpectBegin()
+				expectReport(id, models.ReconciliationMismatched)
+
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash"}).AddRow(14.13, ""))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", transactionsTable)).
+					WithArgs(1, "EUR").WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(30))
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "balance of user 1 in EUR has changed since reconciliation, run it again",
+		},
+		{
+			name:     "Same operator",
+			id:       1,
+			operator: "alice",
+			mock: func(id int) {
+				mock.ExpectBegin()
+				expectReport(id, models.ReconciliationMismatched)
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "corrections must be reviewed by another operator",
+		},
+		{
+			name:     "Already corrected",
+			id:       1,
+			operator: "bob",
+			mock: func(id int) {
+				mock.ExpectBegin()
+				expectReport(id, models.ReconciliationCorrected)
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "corrections are already approved",
+		},
+		{
+			name:     "Report does not exist",
+			id:       100,
+			operator: "bob",
+			mock: func(id int) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", reconciliationTable)).
+					WithArgs(id).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr:   true,
+			wantedErr: "reconciliation report not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(tt.id)
+
+			got, err := r.ApproveCorrections(tt.id, tt.operator)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, tt.wantedErr, err.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, models.ReconciliationCorrected, got.Status)
+				assert.Equal(t, tt.operator, got.ApprovedBy)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout bounds every cache call, a slow cache must not be slower than the database
+const redisTimeout = 100 * time.Millisecond
+
+// setIfVersion stores wallets only if version of user was not changed since the reader got it
+var setIfVersion = redis.NewScript(`
+if (redis.call("GET", KEYS[1]) or "0") ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
+// redisWallet keeps every field of models.Wallet shown by the balance, except the user which is in the key
+type redisWallet struct {
+	Currency string  `json:"currency"`
+	Balance  float32 `json:"balance"`
+	Promo    float32 `json:"promo,omitempty"`
+}
+
+// RedisCache is a WalletCache shared by all instances of the service. Versions are kept without expiration,
+// so a version never goes back while a reader may still hold it
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// InitRedisCache connects to redis and checks it is available
+func InitRedisCache(cfg CacheConfig) (*RedisCache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return NewRedisCache(client, cfg.TTL), nil
+}
+
+func (c *RedisCache) Get(userId int) ([]models.Wallet, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, walletsKey(userId)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var cached []redisWallet
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false, err
+	}
+
+	wallets := make([]models.Wallet, 0, len(cached))
+	for _, wallet := range cached {
+		wallets = append(wallets, models.Wallet{UserId: userId, Currency: wallet.Currency, Balance: wallet.Balance,
+			Promo: wallet.Promo})
+	}
+
+	return wallets, true, nil
+}
+
+func (c *RedisCache) Version(userId int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	version, err := c.client.Get(ctx, versionKey(userId)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+
+	return version, err
+}
+
+func (c *RedisCache) Set(userId int, version int64, wallets []models.Wallet) error {
+	cached := make([]redisWallet, 0, len(wallets))
+	for _, wallet := range wallets {
+		cached = append(cached, redisWallet{Currency: wallet.Currency, Balance: wallet.Balance, Promo: wallet.Promo})
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	keys := []string{versionKey(userId), walletsKey(userId)}
+	return setIfVersion.Run(ctx, c.client, keys, strconv.FormatInt(version, 10), data, c.ttl.Milliseconds()).Err()
+}
+
+func (c *RedisCache) Invalidate(userId int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, versionKey(userId))
+		pipe.Del(ctx, walletsKey(userId))
+		return nil
+	})
+
+	return err
+}
+
+func walletsKey(userId int) string {
+	return fmt.Sprintf("balance:wallets:%d", userId)
+}
+
+func versionKey(userId int) string {
+	return fmt.Sprintf("balance:version:%d", userId)
+}
@@ -6,11 +6,70 @@ import (
 )
 
 type Repo struct {
+	Transactor
+	Changes
 	User
+	// Replica reads users data from read replica, it is the same as User when there is no replica.
+	// Wallets are read through cache if it is enabled
+	Replica User
+	// Primary reads users data from primary database bypassing cache, it is the same as User when there is no cache
+	Primary User
+	Exchange
+	Batch
+	Import
+	Adjustment
+	Account
+	Reconciliation
+	Audit
+	Schedule
+	Subscription
+	Limit
+	CreditLine
+	Fee
+	Promo
+	Voucher
+	Escrow
+	Outbox
 }
 
-func NewRepo(db *sqlx.DB, log logging.Logger) *Repo {
+// NewRepo builds repositories, replica, cache and changes may be nil
+func NewRepo(db, replica *sqlx.DB, cache WalletCache, changes Changes, log logging.Logger) *Repo {
+	primary := NewUserRepo(db, log)
+
+	var reader User = primary
+	if replica != nil {
+		reader = NewUserRepo(replica, log)
+	}
+
+	// every balance change must go through cached user, so it invalidates the cache
+	var user User = primary
+	if cache != nil {
+		cached := NewCachedUser(primary, cache, log)
+		user = cached
+		reader = cached.Reader(reader)
+	}
+
 	return &Repo{
-		User: NewUserRepo(db, log),
+		Transactor:     NewSQLTransactor(db),
+		Changes:        changes,
+		User:           user,
+		Replica:        reader,
+		Primary:        primary,
+		Exchange:       NewExchangeRepo(db, user, log),
+		Batch:          NewBatchRepo(db, log),
+		Import:         NewImportRepo(db, log),
+		Adjustment:     NewAdjustmentRepo(db, user, log),
+		Account:        NewAccountRepo(db, user, log),
+		Reconciliation: NewReconciliationRepo(db, log),
+		Audit:          NewAuditRepo(db, log),
+		Schedule:       NewScheduleRepo(db, log),
+		Subscription:   NewSubscriptionRepo(db, log),
+		Limit:          NewLimitRepo(db, log),
+		CreditLine:     NewCreditLineRepo(db, log),
+		Fee:            NewFeeRepo(db, log),
+		Promo:          NewPromoRepo(db, log),
+		Voucher:        NewVoucherRepo(db, log),
+		Escrow:         NewEscrowRepo(db, log),
+		Outbox:         NewOutboxRepo(db, log),
 	}
 }
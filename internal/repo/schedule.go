@@ -0,0 +1,182 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Schedule interface {
+	CreateSchedule(schedule models.Schedule) (models.Schedule, error)
+	// GetSchedule returns schedule with its runs
+	GetSchedule(id int) (models.Schedule, error)
+	// ListSchedules returns schedules of user with the status, all statuses if it is empty
+	ListSchedules(userId int, status string) ([]models.Schedule, error)
+	// DueSchedules returns ids of at most limit active schedules whose next run is not after now
+	DueSchedules(now time.Time, limit int) ([]int, error)
+	// LockSchedule locks schedule until tx ends, it waits for the unit of work holding the lock
+	LockSchedule(tx Tx, id int) (models.Schedule, error)
+	// LockDueSchedule locks schedule until tx ends if it is still due, false is returned
+	// if it is not due anymore or another unit of work holds the lock
+	LockDueSchedule(tx Tx, id int, now time.Time) (models.Schedule, bool, error)
+	// UpdateSchedule saves status, occurrence and attempts of schedule as a part of tx
+	UpdateSchedule(tx Tx, schedule models.Schedule) error
+	// AddRun saves run of schedule as a part of tx
+	AddRun(tx Tx, id int, run models.ScheduleRun) error
+}
+
+type ScheduleRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewScheduleRepo(db *sqlx.DB, log logging.Logger) *ScheduleRepo {
+	return &ScheduleRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// scheduleColumns are selected for every schedule
+const scheduleColumns = `id, type, user_id, to_id, amount, currency, comment, cron, repeat_interval, status,
+	occurrence, next_run_at, attempts, last_error, created_at`
+
+// CreateSchedule saves schedule, both sender and receiver must exist
+func (r *ScheduleRepo) CreateSchedule(schedule models.Schedule) (models.Schedule, error) {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (type, user_id, to_id, amount, currency, comment, cron, repeat_interval,
+		status, occurrence, next_run_at)
+		SELECT $1, id, $3, $4, $5, $6, $7, $8, $9, $10, $11 FROM %[2]s
+		WHERE id = $2 AND ($3 = 0 OR EXISTS (SELECT 1 FROM %[2]s WHERE id = $3))
+		RETURNING id, created_at`, schedulesTable, usersTable)
+
+	err := r.db.QueryRow(query, schedule.Type, schedule.UserId, schedule.ToId, schedule.Amount, schedule.Currency,
+		schedule.Comment, schedule.Cron, schedule.Interval, schedule.Status, schedule.Occurrence,
+		schedule.NextRunAt).Scan(&schedule.ID, &schedule.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Schedule{}, errors.New("user not found")
+		}
+
+		return models.Schedule{}, err
+	}
+
+	r.log.LogRepo("POST", "CreateSchedule", true, schedule)
+	return schedule, nil
+}
+
+func (r *ScheduleRepo) GetSchedule(id int) (models.Schedule, error) {
+	var schedule models.Schedule
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", scheduleColumns, schedulesTable)
+	if err := r.db.Get(&schedule, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Schedule{}, models.ErrScheduleNotFound
+		}
+
+		return models.Schedule{}, err
+	}
+
+	runs := fmt.Sprintf(`SELECT occurrence, attempt, status, error, balance_after, date FROM %s
+		WHERE schedule_id = $1 ORDER BY id`, scheduleRunsTable)
+	if err := r.db.Select(&schedule.Runs, runs, id); err != nil {
+		return models.Schedule{}, err
+	}
+
+	r.log.LogRepo("GET", "GetSchedule", true, schedule)
+	return schedule, nil
+}
+
+func (r *ScheduleRepo) ListSchedules(userId int, status string) ([]models.Schedule, error) {
+	schedules := []models.Schedule{}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = $1 AND ($2 = '' OR status = $2) ORDER BY id",
+		scheduleColumns, schedulesTable)
+	if err := r.db.Select(&schedules, query, userId, status); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListSchedules", true, schedules)
+	return schedules, nil
+}
+
+func (r *ScheduleRepo) DueSchedules(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	query := fmt.Sprintf("SELECT id FROM %s WHERE status = $1 AND next_run_at <= $2 ORDER BY next_run_at LIMIT $3",
+		schedulesTable)
+	if err := r.db.Select(&ids, query, models.ScheduleActive, now, limit); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *ScheduleRepo) LockSchedule(tx Tx, id int) (models.Schedule, error) {
+	schedule, ok, err := r.lock(tx, fmt.Sprintf("SELECT %s FROM %s WHERE id = $1 FOR UPDATE",
+		scheduleColumns, schedulesTable), id)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	if !ok {
+		return models.Schedule{}, models.ErrScheduleNotFound
+	}
+
+	return schedule, nil
+}
+
+// LockDueSchedule skips locked schedule, so workers of several instances never run the same occurrence
+func (r *ScheduleRepo) LockDueSchedule(tx Tx, id int, now time.Time) (models.Schedule, bool, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND status = $2 AND next_run_at <= $3
+		FOR UPDATE SKIP LOCKED`, scheduleColumns, schedulesTable)
+
+	return r.lock(tx, query, id, models.ScheduleActive, now)
+}
+
+func (r *ScheduleRepo) lock(unit Tx, query string, args ...interface{}) (models.Schedule, bool, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Schedule{}, false, err
+	}
+
+	var schedule models.Schedule
+	err = tx.QueryRow(query, args...).Scan(&schedule.ID, &schedule.Type, &schedule.UserId, &schedule.ToId,
+		&schedule.Amount, &schedule.Currency, &schedule.Comment, &schedule.Cron, &schedule.Interval, &schedule.Status,
+		&schedule.Occurrence, &schedule.NextRunAt, &schedule.Attempts, &schedule.LastError, &schedule.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Schedule{}, false, nil
+		}
+
+		return models.Schedule{}, false, err
+	}
+
+	return schedule, true, nil
+}
+
+func (r *ScheduleRepo) UpdateSchedule(unit Tx, schedule models.Schedule) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET status = $2, occurrence = $3, next_run_at = $4, attempts = $5, last_error = $6
+		WHERE id = $1`, schedulesTable)
+	_, err = tx.Exec(query, schedule.ID, schedule.Status, schedule.Occurrence, schedule.NextRunAt, schedule.Attempts,
+		schedule.LastError)
+	return err
+}
+
+func (r *ScheduleRepo) AddRun(unit Tx, id int, run models.ScheduleRun) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (schedule_id, occurrence, attempt, status, error, balance_after, date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, scheduleRunsTable)
+	_, err = tx.Exec(query, id, run.Occurrence, run.Attempt, run.Status, run.Error, run.BalanceAfter, run.Date)
+	return err
+}
@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleRepository_CreateSchedule(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewScheduleRepo(sqlxDB, logger)
+
+	createdAt := time.Date(2023, 7, 30, 12, 0, 0, 0, time.UTC)
+	schedule := models.Schedule{
+		Type:       models.ScheduleTransfer,
+		UserId:     1,
+		ToId:       2,
+		Amount:     10,
+		Currency:   "EUR",
+		Cron:       "0 9 1 * *",
+		Status:     models.ScheduleActive,
+		Occurrence: time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC),
+		NextRunAt:  time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name      string
+		mock      func()
+		want      models.Schedule
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				rows := sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, createdAt)
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s WHERE (.+) RETURNING id, created_at",
+					schedulesTable, usersTable)).
+					WithArgs(schedule.Type, schedule.UserId, schedule.ToId, schedule.Amount, schedule.Currency, "",
+						schedule.Cron, "", schedule.Status, schedule.Occurrence, schedule.NextRunAt).
+					WillReturnRows(rows)
+			},
+			want: func() models.Schedule {
+				want := schedule
+				want.ID = 1
+				want.CreatedAt = createdAt
+				return want
+			}(),
+		},
+		{
+			name: "User does not exist",
+			mock: func() {
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", schedulesTable)).WillReturnError(sql.ErrNoRows)
+			},
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			got, err := r.CreateSchedule(schedule)
+			if tt.wantedErr != "" {
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestScheduleRepository_LockDueSchedule(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewScheduleRepo(sqlxDB, logger)
+	now := time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)
+
+	columns := []string{"id", "type", "user_id", "to_id", "amount", "currency", "comment", "cron", "repeat_interval",
+		"status", "occurrence", "next_run_at", "attempts", "last_error", "created_at"}
+
+	tests := []struct {
+		name   string
+		mock   func()
+		want   models.Schedule
+		wantOk bool
+	}{
+		{
+			name: "Due",
+			mock: func() {
+				rows := sqlmock.NewRows(columns).AddRow(1, models.ScheduleDebit, 1, 0, 5, "EUR", "", "", "24h",
+					models.ScheduleActive, now, now, 0, "", now)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE SKIP LOCKED", schedulesTable)).
+					WithArgs(1, models.ScheduleActive, now).WillReturnRows(rows)
+			},
+			want: models.Schedule{ID: 1, Type: models.ScheduleDebit, UserId: 1, Amount: 5, Currency: "EUR",
+				Interval: "24h", Status: models.ScheduleActive, Occurrence: now, NextRunAt: now, CreatedAt: now},
+			wantOk: true,
+		},
+		{
+			name: "Locked or not due",
+			mock: func() {
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE SKIP LOCKED", schedulesTable)).
+					WithArgs(1, models.ScheduleActive, now).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+			mock.ExpectCommit()
+
+			var (
+				got models.Schedule
+				ok  bool
+			)
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, ok, err = r.LockDueSchedule(tx, 1, now)
+				return err
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestScheduleRepository_GetSchedule(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewScheduleRepo(sqlxDB, logger)
+
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = (.+)", schedulesTable)).
+		WithArgs(3).WillReturnError(sql.ErrNoRows)
+
+	_, err = r.GetSchedule(3)
+	assert.ErrorIs(t, err, models.ErrScheduleNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,249 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Subscription interface {
+	CreatePlan(input models.PlanInput) (models.Plan, error)
+	GetPlan(id int) (models.Plan, error)
+	ListPlans() ([]models.Plan, error)
+	// CreateSubscription saves subscription as a part of tx, the user must exist
+	CreateSubscription(tx Tx, subscription models.Subscription) (models.Subscription, error)
+	// GetSubscription returns subscription with its events
+	GetSubscription(id int) (models.Subscription, error)
+	// ListSubscriptions returns subscriptions of user with the status, all statuses if it is empty
+	ListSubscriptions(userId int, status string) ([]models.Subscription, error)
+	// DueSubscriptions returns ids of at most limit not cancelled subscriptions whose next attempt is not after now
+	DueSubscriptions(now time.Time, limit int) ([]int, error)
+	// LockSubscription locks subscription until tx ends, it waits for the unit of work holding the lock
+	LockSubscription(tx Tx, id int) (models.Subscription, error)
+	// LockDueSubscription locks subscription until tx ends if it is still due, false is returned
+	// if it is not due anymore or another unit of work holds the lock
+	LockDueSubscription(tx Tx, id int, now time.Time) (models.Subscription, bool, error)
+	// UpdateSubscription saves plan, status, period and attempts of subscription as a part of tx
+	UpdateSubscription(tx Tx, subscription models.Subscription) error
+	// AddEvent saves event of subscription as a part of tx
+	AddEvent(tx Tx, event models.SubscriptionEvent) error
+	// GetSubscriptionEvents returns up to limit events of all subscriptions with id greater than afterId ordered by id
+	GetSubscriptionEvents(afterId, limit int) ([]models.SubscriptionEvent, error)
+}
+
+type SubscriptionRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewSubscriptionRepo(db *sqlx.DB, log logging.Logger) *SubscriptionRepo {
+	return &SubscriptionRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+// subscriptionColumns are selected for every subscription
+const subscriptionColumns = `id, user_id, plan_id, status, period_start, period_end, next_attempt_at,
+	cancel_at_period_end, attempts, last_error, created_at, cancelled_at`
+
+func (r *SubscriptionRepo) CreatePlan(input models.PlanInput) (models.Plan, error) {
+	plan := models.Plan{
+		Name:      input.Name,
+		Price:     input.Price,
+		Currency:  input.Currency,
+		Period:    input.Period,
+		TrialDays: input.TrialDays,
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (name, price, currency, period, trial_days) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`, plansTable)
+	err := r.db.QueryRow(query, plan.Name, plan.Price, plan.Currency, plan.Period, plan.TrialDays).
+		Scan(&plan.ID, &plan.CreatedAt)
+	if err != nil {
+		return models.Plan{}, err
+	}
+
+	r.log.LogRepo("POST", "CreatePlan", true, plan)
+	return plan, nil
+}
+
+func (r *SubscriptionRepo) GetPlan(id int) (models.Plan, error) {
+	var plan models.Plan
+	query := fmt.Sprintf("SELECT id, name, price, currency, period, trial_days, created_at FROM %s WHERE id = $1",
+		plansTable)
+	if err := r.db.Get(&plan, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Plan{}, models.ErrPlanNotFound
+		}
+
+		return models.Plan{}, err
+	}
+
+	return plan, nil
+}
+
+func (r *SubscriptionRepo) ListPlans() ([]models.Plan, error) {
+	plans := []models.Plan{}
+	query := fmt.Sprintf("SELECT id, name, price, currency, period, trial_days, created_at FROM %s ORDER BY id",
+		plansTable)
+	if err := r.db.Select(&plans, query); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListPlans", true, plans)
+	return plans, nil
+}
+
+func (r *SubscriptionRepo) CreateSubscription(unit Tx, subscription models.Subscription) (models.Subscription, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, plan_id, status, period_start, period_end, next_attempt_at)
+		SELECT id, $2, $3, $4, $5, $6 FROM %s WHERE id = $1
+		RETURNING id, created_at`, subscriptionsTable, usersTable)
+
+	err = tx.QueryRow(query, subscription.UserId, subscription.PlanId, subscription.Status, subscription.PeriodStart,
+		subscription.PeriodEnd, subscription.NextAttemptAt).Scan(&subscription.ID, &subscription.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Subscription{}, errors.New("user not found")
+		}
+
+		return models.Subscription{}, err
+	}
+
+	r.log.LogRepo("POST", "CreateSubscription", true, subscription)
+	return subscription, nil
+}
+
+func (r *SubscriptionRepo) GetSubscription(id int) (models.Subscription, error) {
+	var subscription models.Subscription
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", subscriptionColumns, subscriptionsTable)
+	if err := r.db.Get(&subscription, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Subscription{}, models.ErrSubscriptionNotFound
+		}
+
+		return models.Subscription{}, err
+	}
+
+	events := fmt.Sprintf(`SELECT id, subscription_id, user_id, plan_id, type, amount, currency, error, date FROM %s
+		WHERE subscription_id = $1 ORDER BY id`, subscriptionEventsTable)
+	if err := r.db.Select(&subscription.Events, events, id); err != nil {
+		return models.Subscription{}, err
+	}
+
+	r.log.LogRepo("GET", "GetSubscription", true, subscription)
+	return subscription, nil
+}
+
+func (r *SubscriptionRepo) ListSubscriptions(userId int, status string) ([]models.Subscription, error) {
+	subscriptions := []models.Subscription{}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE user_id = $1 AND ($2 = '' OR status = $2) ORDER BY id",
+		subscriptionColumns, subscriptionsTable)
+	if err := r.db.Select(&subscriptions, query, userId, status); err != nil {
+		return nil, err
+	}
+
+	r.log.LogRepo("GET", "ListSubscriptions", true, subscriptions)
+	return subscriptions, nil
+}
+
+func (r *SubscriptionRepo) DueSubscriptions(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE status <> $1 AND next_attempt_at <= $2
+		ORDER BY next_attempt_at LIMIT $3`, subscriptionsTable)
+	if err := r.db.Select(&ids, query, models.SubscriptionCancelled, now, limit); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *SubscriptionRepo) LockSubscription(tx Tx, id int) (models.Subscription, error) {
+	subscription, ok, err := r.lock(tx, fmt.Sprintf("SELECT %s FROM %s WHERE id = $1 FOR UPDATE",
+		subscriptionColumns, subscriptionsTable), id)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	if !ok {
+		return models.Subscription{}, models.ErrSubscriptionNotFound
+	}
+
+	return subscription, nil
+}
+
+// LockDueSubscription skips locked subscription, so workers of several instances never charge the same period twice
+func (r *SubscriptionRepo) LockDueSubscription(tx Tx, id int, now time.Time) (models.Subscription, bool, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id = $1 AND status <> $2 AND next_attempt_at <= $3
+		FOR UPDATE SKIP LOCKED`, subscriptionColumns, subscriptionsTable)
+
+	return r.lock(tx, query, id, models.SubscriptionCancelled, now)
+}
+
+func (r *SubscriptionRepo) lock(unit Tx, query string, args ...interface{}) (models.Subscription, bool, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Subscription{}, false, err
+	}
+
+	var s models.Subscription
+	err = tx.QueryRow(query, args...).Scan(&s.ID, &s.UserId, &s.PlanId, &s.Status, &s.PeriodStart, &s.PeriodEnd,
+		&s.NextAttemptAt, &s.CancelAtPeriodEnd, &s.Attempts, &s.LastError, &s.CreatedAt, &s.CancelledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Subscription{}, false, nil
+		}
+
+		return models.Subscription{}, false, err
+	}
+
+	return s, true, nil
+}
+
+func (r *SubscriptionRepo) UpdateSubscription(unit Tx, s models.Subscription) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET plan_id = $2, status = $3, period_start = $4, period_end = $5,
+		next_attempt_at = $6, cancel_at_period_end = $7, attempts = $8, last_error = $9, cancelled_at = $10
+		WHERE id = $1`, subscriptionsTable)
+	_, err = tx.Exec(query, s.ID, s.PlanId, s.Status, s.PeriodStart, s.PeriodEnd, s.NextAttemptAt,
+		s.CancelAtPeriodEnd, s.Attempts, s.LastError, s.CancelledAt)
+	return err
+}
+
+func (r *SubscriptionRepo) AddEvent(unit Tx, event models.SubscriptionEvent) error {
+	tx, err := txOf(unit)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (subscription_id, user_id, plan_id, type, amount, currency, error, date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, subscriptionEventsTable)
+	_, err = tx.Exec(query, event.SubscriptionId, event.UserId, event.PlanId, event.Type, event.Amount,
+		event.Currency, event.Error, event.Date)
+	return err
+}
+
+func (r *SubscriptionRepo) GetSubscriptionEvents(afterId, limit int) ([]models.SubscriptionEvent, error) {
+	events := []models.SubscriptionEvent{}
+	query := fmt.Sprintf(`SELECT id, subscription_id, user_id, plan_id, type, amount, currency, error, date FROM %s
+		WHERE id > $1 ORDER BY id LIMIT $2`, subscriptionEventsTable)
+	if err := r.db.Select(&events, query, afterId, limit); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,190 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionRepository_CreateSubscription(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewSubscriptionRepo(sqlxDB, logger)
+
+	start := time.Date(2023, 8, 5, 12, 0, 0, 0, time.UTC)
+	subscription := models.Subscription{
+		UserId:        1,
+		PlanId:        2,
+		Status:        models.SubscriptionActive,
+		PeriodStart:   start,
+		PeriodEnd:     start.AddDate(0, 1, 0),
+		NextAttemptAt: start.AddDate(0, 1, 0),
+	}
+
+	tests := []struct {
+		name      string
+		mock      func()
+		want      models.Subscription
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectBegin()
+				rows := sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, start)
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s WHERE id = (.+) RETURNING id, created_at",
+					subscriptionsTable, usersTable)).
+					WithArgs(subscription.UserId, subscription.PlanId, subscription.Status, subscription.PeriodStart,
+						subscription.PeriodEnd, subscription.NextAttemptAt).
+					WillReturnRows(rows)
+				mock.ExpectCommit()
+			},
+			want: func() models.Subscription {
+				want := subscription
+				want.ID = 1
+				want.CreatedAt = start
+				return want
+			}(),
+		},
+		{
+			name: "User does not exist",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(fmt.Sprintf("INSERT INTO %s", subscriptionsTable)).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			var got models.Subscription
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, err = r.CreateSubscription(tx, subscription)
+				return err
+			})
+			if tt.wantedErr != "" {
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSubscriptionRepository_LockDueSubscription(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewSubscriptionRepo(sqlxDB, logger)
+	now := time.Date(2023, 9, 5, 12, 0, 0, 0, time.UTC)
+
+	columns := []string{"id", "user_id", "plan_id", "status", "period_start", "period_end", "next_attempt_at",
+		"cancel_at_period_end", "attempts", "last_error", "created_at", "cancelled_at"}
+
+	tests := []struct {
+		name   string
+		mock   func()
+		want   models.Subscription
+		wantOk bool
+	}{
+		{
+			name: "Due",
+			mock: func() {
+				rows := sqlmock.NewRows(columns).AddRow(1, 1, 2, models.SubscriptionPastDue, now.AddDate(0, -1, 0), now,
+					now, false, 1, "not enough money to perform purchase", now, nil)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE SKIP LOCKED", subscriptionsTable)).
+					WithArgs(1, models.SubscriptionCancelled, now).WillReturnRows(rows)
+			},
+			want: models.Subscription{ID: 1, UserId: 1, PlanId: 2, Status: models.SubscriptionPastDue,
+				PeriodStart: now.AddDate(0, -1, 0), PeriodEnd: now, NextAttemptAt: now, Attempts: 1,
+				LastError: "not enough money to perform purchase", CreatedAt: now},
+			wantOk: true,
+		},
+		{
+			name: "Locked or not due",
+			mock: func() {
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE SKIP LOCKED", subscriptionsTable)).
+					WithArgs(1, models.SubscriptionCancelled, now).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+			mock.ExpectCommit()
+
+			var (
+				got models.Subscription
+				ok  bool
+			)
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, ok, err = r.LockDueSubscription(tx, 1, now)
+				return err
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSubscriptionRepository_GetPlan(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewSubscriptionRepo(sqlxDB, logger)
+
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE id = (.+)", plansTable)).
+		WithArgs(3).WillReturnError(sql.ErrNoRows)
+
+	_, err = r.GetPlan(3)
+	assert.ErrorIs(t, err, models.ErrPlanNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
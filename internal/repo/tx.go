@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// errForeignTx is returned when a repository gets a unit of work started by another storage
+var errForeignTx = errors.New("unit of work of another storage")
+
+// Tx is a unit of work started by Transactor. Repositories taking it make their
+// changes as a part of it, so the changes are committed or rolled back together
+type Tx interface {
+	// AfterCommit registers f to be called once the unit of work is committed, f is not called on rollback
+	AfterCommit(f func())
+	unitOfWork()
+}
+
+type Transactor interface {
+	// WithinTx runs f in a new unit of work, it is committed if f returns nil and rolled back otherwise
+	WithinTx(f func(tx Tx) error) error
+}
+
+type SQLTransactor struct {
+	db *sqlx.DB
+}
+
+func NewSQLTransactor(db *sqlx.DB) *SQLTransactor {
+	return &SQLTransactor{db: db}
+}
+
+func (t *SQLTransactor) WithinTx(f func(tx Tx) error) error {
+	tx, err := t.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	unit := &sqlTx{tx: tx}
+	if err := f(unit); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	unit.committed()
+	return nil
+}
+
+type sqlTx struct {
+	tx          *sql.Tx
+	afterCommit []func()
+}
+
+func (t *sqlTx) AfterCommit(f func()) {
+	t.afterCommit = append(t.afterCommit, f)
+}
+
+func (t *sqlTx) committed() {
+	for _, f := range t.afterCommit {
+		f()
+	}
+}
+
+func (*sqlTx) unitOfWork() {}
+
+// txOf returns sql transaction of the unit of work
+func txOf(tx Tx) (*sql.Tx, error) {
+	t, ok := tx.(*sqlTx)
+	if !ok {
+		return nil, errForeignTx
+	}
+
+	return t.tx, nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLTransactor_WithinTx(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	tr := NewSQLTransactor(sqlxDB)
+
+	type mockBehavior func()
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		f         func(tx Tx) error
+		wantedErr error
+	}{
+		{
+			name: "Committed",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+			},
+			f: func(tx Tx) error { return nil },
+		},
+		{
+			name: "Rolled back",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+			f:         func(tx Tx) error { return errors.New("not enough money to perform purchase") },
+			wantedErr: errors.New("not enough money to perform purchase"),
+		},
+		{
+			name: "Begin failed",
+			mock: func() {
+				mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+			},
+			f: func(tx Tx) error {
+				t.Error("f must not run without transaction")
+				return nil
+			},
+			wantedErr: errors.New("connection refused"),
+		},
+		{
+			name: "Unit of work of memory storage",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectRollback()
+			},
+			f: func(tx Tx) error {
+				_, err := txOf(&memoryTx{})
+				return err
+			},
+			wantedErr: errForeignTx,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := tr.WithinTx(tt.f)
+			assert.Equal(t, tt.wantedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
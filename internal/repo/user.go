@@ -11,14 +11,18 @@ import (
 )
 
 type User interface {
-	GetBalance(id int) (float32, error)
+	GetWallets(id int) ([]models.Wallet, error)
 	GetTransactions(id int, page models.Page) ([]models.Transaction, error)
-	DecreaseBalanceTx(input models.Input, operation string, tx *sql.Tx) (float32, error)
-	IncreaseBalanceTx(input models.Input, operation string, tx *sql.Tx) (float32, error)
-	TopUp(input models.Input) (float32, error)
-	Debit(input models.Input) (float32, error)
-	Transfer(input models.TransferInput) (float32, error)
-	ChangeBalance(input models.Input, action string, operation string, tx *sql.Tx) (float32, error)
+	// GetTransactionsAfter returns up to limit transactions of user with id greater than afterId ordered by id
+	GetTransactionsAfter(id, afterId, limit int) ([]models.Transaction, error)
+	// Credit adds amount to the wallet as a part of tx, the wallet is opened if it does not exist
+	Credit(tx Tx, input models.Input, operation models.Operation) (float32, error)
+	// Debit takes amount from the wallet as a part of tx, the wallet must have enough real money
+	// within its credit limit less held money besides the promo part of the operation, unless the operation
+	// may overdraw it
+	Debit(tx Tx, input models.Input, operation models.Operation) (float32, error)
+	GetBalanceAt(id int, at time.Time) ([]models.Wallet, error)
+	GetBalanceHistory(id int, currency string, from, to time.Time, interval string) ([]models.BalancePoint, error)
 }
 
 type UserRepo struct {
@@ -33,88 +37,86 @@ func NewUserRepo(db *sqlx.DB, log logging.Logger) *UserRepo {
 	}
 }
 
-func (r *UserRepo) Transfer(input models.TransferInput) (float32, error) {
-	tx, err := r.db.Begin()
+func (r *UserRepo) Credit(tx Tx, input models.Input, operation models.Operation) (float32, error) {
+	dbTx, err := txOf(tx)
 	if err != nil {
 		return 0, err
 	}
 
-	_, err = r.DecreaseBalanceTx(models.Input{
-		UserId: input.UserId,
-		Amount: input.Amount,
-	}, fmt.Sprintf("Debit by transfer %fEUR", input.Amount), tx)
-	if err != nil {
-		tx.Rollback()
-		return 0, err
-	}
+	return r.changeBalance(input, "+", operation, dbTx)
+}
 
-	balance, err := r.IncreaseBalanceTx(models.Input{
-		UserId: input.ToId,
-		Amount: input.Amount,
-	}, fmt.Sprintf("Top-up by transfer %fEUR", input.Amount), tx)
+func (r *UserRepo) Debit(tx Tx, input models.Input, operation models.Operation) (float32, error) {
+	dbTx, err := txOf(tx)
 	if err != nil {
-		tx.Rollback()
 		return 0, err
 	}
 
-	return balance, tx.Commit()
+	return r.changeBalance(input, "-", operation, dbTx)
 }
 
-func (r *UserRepo) Debit(input models.Input) (float32, error) {
-	tx, err := r.db.Begin()
-	if err != nil {
+func (r *UserRepo) changeBalance(input models.Input, action string, operation models.Operation, tx *sql.Tx) (float32, error) {
+	if err := r.checkStatus(input.UserId, action, operation.System, tx); err != nil {
 		return 0, err
 	}
 
-	res, err := r.DecreaseBalanceTx(input, fmt.Sprintf("Debit by purchase %fEUR", input.Amount), tx)
-	if err != nil {
-		tx.Rollback()
-		return 0, err
-	}
+	var (
+		balance  float32
+		prevHash string
+		// credit is credit limit less held and promotional money, real money may be spent below zero by it
+		credit float32
+	)
+
+	check := fmt.Sprintf(`SELECT balance, last_hash, credit_limit - held - promo FROM %s
+		WHERE user_id = $1 AND currency = $2 FOR UPDATE`, walletsTable)
+	err := tx.QueryRow(check, input.UserId, input.Currency).Scan(&balance, &prevHash, &credit)
+	if err == sql.ErrNoRows {
+		if action == "-" && !operation.Overdraw {
+			return 0, errors.New("not enough money to perform purchase")
+		}
 
-	return res, tx.Commit()
-}
+		if err = r.openWallet(input.UserId, input.Currency, tx); err != nil {
+			return 0, err
+		}
 
-func (r *UserRepo) TopUp(input models.Input) (float32, error) {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return 0, err
+		// the wallet may have been opened by a concurrent change, so it is locked as it is now
+		err = tx.QueryRow(check, input.UserId, input.Currency).Scan(&balance, &prevHash, &credit)
+		if err == sql.ErrNoRows {
+			return 0, errors.New("user not found")
+		}
 	}
-
-	balance, err := r.IncreaseBalanceTx(input, fmt.Sprintf("Top-up by bank_card %fEUR", input.Amount), tx)
 	if err != nil {
-		tx.Rollback()
 		return 0, err
 	}
 
-	return balance, tx.Commit()
-}
-
-func (r *UserRepo) IncreaseBalanceTx(input models.Input, operation string, tx *sql.Tx) (float32, error) {
-	return r.ChangeBalance(input, "+", operation, tx)
-}
-
-func (r *UserRepo) DecreaseBalanceTx(input models.Input, operation string, tx *sql.Tx) (float32, error) {
-	return r.ChangeBalance(input, "-", operation, tx)
-}
-
-func (r *UserRepo) ChangeBalance(input models.Input, action string, operation string, tx *sql.Tx) (float32, error) {
-	var balance float32
+	if balance+credit-(input.Amount-operation.Promo) < 0 && action == "-" && !operation.Overdraw {
+		return 0, errors.New("not enough money to perform purchase")
+	}
 
-	check := fmt.Sprintf("SELECT balance FROM %s WHERE id = $1", usersTable)
-	err := r.db.Get(&balance, check, input.UserId)
-	if err != nil {
-		return 0, err
+	link := models.ChainLink{
+		UserId:    input.UserId,
+		Currency:  input.Currency,
+		Direction: 1,
+		Amount:    input.Amount,
+		Operation: operation.Comment,
+		Date:      time.Now(),
+		LinkId:    operation.LinkId,
+		PrevHash:  prevHash,
 	}
 
-	if balance-input.Amount < 0 && action == "-" {
-		return 0, errors.New("not enough money to perform purchase")
+	if action == "+" {
+		link.BalanceAfter = balance + input.Amount
+	} else {
+		link.Direction = -1
+		link.BalanceAfter = balance - input.Amount
 	}
+	link.Hash = link.ComputeHash()
 
-	query := fmt.Sprintf("UPDATE %s SET balance = balance %s %f WHERE id = $1 RETURNING balance",
-		usersTable, action, input.Amount)
+	// wallet keeps the hash of its last transaction, the chain is extended under the wallet lock
+	query := fmt.Sprintf("UPDATE %s SET balance = balance %s $3, last_hash = $4 WHERE user_id = $1 AND currency = $2",
+		walletsTable, action)
 
-	res, err := tx.Exec(query, input.UserId)
+	res, err := tx.Exec(query, input.UserId, input.Currency, input.Amount, link.Hash)
 	if err != nil {
 		return 0, err
 	}
@@ -125,13 +127,21 @@ func (r *UserRepo) ChangeBalance(input models.Input, action string, operation st
 	}
 
 	if affected == 0 {
-		return 0, errors.New("user not found")
+		return 0, errors.New("wallet not found")
 	}
 
-	insert := fmt.Sprintf("INSERT INTO %s (user_id, amount, operation, date) VALUES ($1, $2, $3, $4)",
-		transactionsTable)
+	if operation.Promo != 0 {
+		promo := fmt.Sprintf("UPDATE %s SET promo = promo %s $3 WHERE user_id = $1 AND currency = $2", walletsTable, action)
+		if _, err := tx.Exec(promo, input.UserId, input.Currency, operation.Promo); err != nil {
+			return 0, err
+		}
+	}
 
-	result, err := tx.Exec(insert, input.UserId, input.Amount, operation, time.Now().Format("01-02-2006 15:04:05"))
+	insert := fmt.Sprintf(`INSERT INTO %s (user_id, amount, currency, operation, date, balance_after, link_id, direction,
+		prev_hash, hash) VALUES ($1, $2, $3, $4, $5, $6, $7, %s1, $8, $9)`, transactionsTable, action)
+
+	result, err := tx.Exec(insert, link.UserId, link.Amount, link.Currency, link.Operation,
+		link.Date.Format(models.TransactionDateLayout), link.BalanceAfter, link.LinkId, link.PrevHash, link.Hash)
 	if err != nil {
 		return 0, err
 	}
@@ -145,16 +155,67 @@ func (r *UserRepo) ChangeBalance(input models.Input, action string, operation st
 		return 0, errors.New("failed to insert new transaction, rollback")
 	}
 
-	if action == "+" {
-		return balance + input.Amount, nil
-	} else {
-		return balance - input.Amount, nil
+	return link.BalanceAfter, nil
+}
+
+// checkStatus returns error if account status does not allow the change, system postings are allowed on every
+// account which is not closed. User row is locked until the end of the transaction, so status can not be changed
+// in the middle of it
+func (r *UserRepo) checkStatus(userId int, action string, system bool, tx *sql.Tx) error {
+	var (
+		status         string
+		creditsBlocked bool
+	)
+
+	query := fmt.Sprintf("SELECT status, credits_blocked FROM %s WHERE id = $1 FOR SHARE", usersTable)
+	err := tx.QueryRow(query, userId).Scan(&status, &creditsBlocked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("user not found")
+		}
+
+		return err
+	}
+
+	switch status {
+	case models.AccountClosed:
+		return errors.New("account is closed")
+	case models.AccountFrozen:
+		if (action == "-" && !system) || (action == "+" && creditsBlocked) {
+			return errors.New("account is frozen")
+		}
+	case models.AccountDebitBlocked:
+		if action == "-" && !system {
+			return errors.New("account is debit blocked")
+		}
 	}
+
+	return nil
+}
+
+// lockUser locks the user for share like checkStatus does. Units of work lock the user before its wallets,
+// so they never wait for each other in reverse order
+func lockUser(userId int, tx *sql.Tx) error {
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE id = $1 FOR SHARE", usersTable)
+	_, err := tx.Exec(query, userId)
+	return err
+}
+
+// openWallet creates an empty wallet, wallets are opened on the first top-up in their currency.
+// Nothing is done if a concurrent change opened it first
+func (r *UserRepo) openWallet(userId int, currency string, tx *sql.Tx) error {
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, currency, balance) SELECT id, $2, 0 FROM %s WHERE id = $1
+		ON CONFLICT (user_id, currency) DO NOTHING`, walletsTable, usersTable)
+
+	_, err := tx.Exec(query, userId, currency)
+	return err
 }
 
 func (r *UserRepo) GetTransactions(id int, page models.Page) ([]models.Transaction, error) {
 	var transactions []models.TransactionDTO
-	query := fmt.Sprintf("SELECT * FROM %s WHERE user_id = $1 ORDER BY %s LIMIT %d OFFSET %d",
+	query := fmt.Sprintf(`SELECT id, user_id, amount, currency, balance_after, operation, link_id,
+		to_char(date, 'YYYY-MM-DD HH24:MI:SS') AS date
+		FROM %s WHERE user_id = $1 ORDER BY %s LIMIT %d OFFSET %d`,
 		transactionsTable, page.Sort, page.Limit, (page.Page-1)*page.Limit)
 
 	err := r.db.Select(&transactions, query, id)
@@ -165,6 +226,24 @@ func (r *UserRepo) GetTransactions(id int, page models.Page) ([]models.Transacti
 		return nil, err
 	}
 
+	return toTransactions(transactions)
+}
+
+// GetTransactionsAfter returns up to limit transactions of user with id greater than afterId ordered by id
+func (r *UserRepo) GetTransactionsAfter(id, afterId, limit int) ([]models.Transaction, error) {
+	var transactions []models.TransactionDTO
+	query := fmt.Sprintf(`SELECT id, user_id, amount, currency, balance_after, operation, link_id,
+		to_char(date, 'YYYY-MM-DD HH24:MI:SS') AS date
+		FROM %s WHERE user_id = $1 AND id > $2 ORDER BY id LIMIT $3`, transactionsTable)
+
+	if err := r.db.Select(&transactions, query, id, afterId, limit); err != nil {
+		return nil, err
+	}
+
+	return toTransactions(transactions)
+}
+
+func toTransactions(transactions []models.TransactionDTO) ([]models.Transaction, error) {
 	result := make([]models.Transaction, 0, len(transactions))
 	for i := 0; i < len(transactions); i++ {
 		t, err := time.Parse(time.DateTime, transactions[i].Date)
@@ -173,29 +252,92 @@ func (r *UserRepo) GetTransactions(id int, page models.Page) ([]models.Transacti
 		}
 
 		result = append(result, models.Transaction{
-			ID:        transactions[i].ID,
-			UserId:    transactions[i].UserId,
-			Amount:    transactions[i].Amount,
-			Operation: transactions[i].Operation,
-			Date:      t,
+			ID:           transactions[i].ID,
+			UserId:       transactions[i].UserId,
+			Amount:       transactions[i].Amount,
+			Currency:     transactions[i].Currency,
+			BalanceAfter: transactions[i].BalanceAfter,
+			Operation:    transactions[i].Operation,
+			LinkId:       transactions[i].LinkId,
+			Date:         t,
 		})
 	}
 
 	return result, nil
 }
 
-func (r *UserRepo) GetBalance(id int) (float32, error) {
-	var balance float32
-	query := fmt.Sprintf("SELECT balance FROM %s WHERE id = $1", usersTable)
-	err := r.db.Get(&balance, query, id)
+func (r *UserRepo) GetWallets(id int) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	query := fmt.Sprintf("SELECT user_id, currency, balance, promo FROM %s WHERE user_id = $1 ORDER BY currency", walletsTable)
+	err := r.db.Select(&wallets, query, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return 0, errors.New("user not found")
+		return nil, err
+	}
+
+	if len(wallets) == 0 {
+		if err := r.checkUser(id); err != nil {
+			return nil, err
 		}
+	}
 
-		return 0, err
+	r.log.LogRepo("GET", "GetWallets", true, wallets)
+	return wallets, nil
+}
+
+// GetBalanceAt returns balances of user`s wallets right after the last transaction made before or at the given moment
+func (r *UserRepo) GetBalanceAt(id int, at time.Time) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	query := fmt.Sprintf(`SELECT w.user_id, w.currency, COALESCE((SELECT t.balance_after FROM %s t
+		WHERE t.user_id = w.user_id AND t.currency = w.currency AND t.date <= $2
+		ORDER BY t.date DESC, t.id DESC LIMIT 1), 0) AS balance
+		FROM %s w WHERE w.user_id = $1 ORDER BY w.currency`, transactionsTable, walletsTable)
+	err := r.db.Select(&wallets, query, id, at)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wallets) == 0 {
+		if err := r.checkUser(id); err != nil {
+			return nil, err
+		}
+	}
+
+	r.log.LogRepo("GET", "GetBalanceAt", true, wallets)
+	return wallets, nil
+}
+
+// GetBalanceHistory returns balance of user`s wallet at the end of every interval between from and to.
+// interval must be a valid postgres interval, e.g. "1 day"
+func (r *UserRepo) GetBalanceHistory(id int, currency string, from, to time.Time, interval string) ([]models.BalancePoint, error) {
+	var points []models.BalancePoint
+	query := fmt.Sprintf(`SELECT s.point AS date, COALESCE((SELECT t.balance_after FROM %s t
+		WHERE t.user_id = w.user_id AND t.currency = w.currency AND t.date < s.point + $5::interval
+		ORDER BY t.date DESC, t.id DESC LIMIT 1), 0) AS balance
+		FROM %s w CROSS JOIN generate_series($3::timestamp, $4::timestamp, $5::interval) AS s(point)
+		WHERE w.user_id = $1 AND w.currency = $2 ORDER BY s.point`, transactionsTable, walletsTable)
+	err := r.db.Select(&points, query, id, currency, from, to, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(points) == 0 {
+		return nil, errors.New("wallet not found")
+	}
+
+	return points, nil
+}
+
+// checkUser returns error if user does not exist
+func (r *UserRepo) checkUser(id int) error {
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM %s WHERE id = $1)", usersTable)
+	if err := r.db.Get(&exists, query, id); err != nil {
+		return err
+	}
+
+	if !exists {
+		return errors.New("user not found")
 	}
 
-	r.log.LogRepo("GET", "GetBalance", true, balance)
-	return balance, nil
+	return nil
 }
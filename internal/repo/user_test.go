@@ -14,7 +14,7 @@ import (
 	"time"
 )
 
-func TestUserRepository_GetBalance(t *testing.T) {
+func TestUserRepository_GetWallets(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	defer mockDB.Close()
 	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
@@ -35,40 +35,63 @@ func TestUserRepository_GetBalance(t *testing.T) {
 		name      string
 		mock      mockBehavior
 		userID    int
-		want      float32
+		want      []models.Wallet
 		wantErr   bool
 		wantedErr string
 	}{
 		{
 			name: "Ok",
 			mock: func(userID int) {
-				rows := sqlmock.NewRows([]string{"balance"}).AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
+				rows := sqlmock.NewRows([]string{"user_id", "currency", "balance"}).
+					AddRow(userID, "EUR", 10).
+					AddRow(userID, "USD", 2.5)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", walletsTable)).
 					WithArgs(userID).WillReturnRows(rows)
 			},
-			userID:  1,
-			want:    10,
+			userID: 1,
+			want: []models.Wallet{
+				{UserId: 1, Currency: "EUR", Balance: 10},
+				{UserId: 1, Currency: "USD", Balance: 2.5},
+			},
 			wantErr: false,
 		},
 		{
-			name: "Error no rows",
+			name: "No wallets yet",
 			mock: func(userID int) {
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(userID).WillReturnError(sql.ErrNoRows)
+				rows := sqlmock.NewRows([]string{"user_id", "currency", "balance"})
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", walletsTable)).
+					WithArgs(userID).WillReturnRows(rows)
+
+				exists := sqlmock.NewRows([]string{"exists"}).AddRow(true)
+				mock.ExpectQuery(fmt.Sprintf("SELECT EXISTS(.+) FROM %s WHERE (.+)", usersTable)).
+					WithArgs(userID).WillReturnRows(exists)
+			},
+			userID:  2,
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "User does not exist",
+			mock: func(userID int) {
+				rows := sqlmock.NewRows([]string{"user_id", "currency", "balance"})
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", walletsTable)).
+					WithArgs(userID).WillReturnRows(rows)
+
+				exists := sqlmock.NewRows([]string{"exists"}).AddRow(false)
+				mock.ExpectQuery(fmt.Sprintf("SELECT EXISTS(.+) FROM %s WHERE (.+)", usersTable)).
+					WithArgs(userID).WillReturnRows(exists)
 			},
 			userID:    100,
-			want:      0,
 			wantErr:   true,
 			wantedErr: "user not found",
 		},
 		{
 			name: "Random error",
 			mock: func(userID int) {
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", walletsTable)).
 					WithArgs(userID).WillReturnError(errors.New("db is not valid"))
 			},
 			userID:    100,
-			want:      0,
 			wantErr:   true,
 			wantedErr: "db is not valid",
 		},
@@ -78,7 +101,7 @@ func TestUserRepository_GetBalance(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock(tt.userID)
 
-			got, err := r.GetBalance(tt.userID)
+			got, err := r.GetWallets(tt.userID)
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Equal(t, err, errors.New(tt.wantedErr))
@@ -268,6 +291,37 @@ func TestUserRepository_GetTransactions(t *testing.T) {
 	}
 }
 
+func TestUserRepository_GetTransactionsAfter(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewUserRepo(sqlxDB, logger)
+	date := time.Now().Format(time.DateTime)
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "amount", "operation", "date"}).
+		AddRow(8, 1, 10, "Top-up by bank_card 10EUR", date).
+		AddRow(9, 1, 5, "Debit by purchase 5EUR", date)
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE user_id = (.+) AND id > (.+) ORDER BY id LIMIT (.+)", transactionsTable)).
+		WithArgs(1, 7, 100).WillReturnRows(rows)
+
+	got, err := r.GetTransactionsAfter(1, 7, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Transaction{
+		{ID: 8, UserId: 1, Amount: 10, Operation: "Top-up by bank_card 10EUR", Date: utils.ParseTime(date, t)},
+		{ID: 9, UserId: 1, Amount: 5, Operation: "Debit by purchase 5EUR", Date: utils.ParseTime(date, t)},
+	}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUserRepository_TopUp(t *testing.T) {
 	mockDB, mock, err := sqlmock.New()
 	defer mockDB.Close()
@@ -298,26 +352,28 @@ func TestUserRepository_TopUp(t *testing.T) {
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date := time.Now().Format("01-02-2006 15:04:05")
 				result := sqlmock.NewResult(1, 1)
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Top-up by bank_card %fEUR", input.Amount), date).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Top-up by bank_card %f%s", input.Amount, input.Currency), date, float32(20), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result)
 
 				mock.ExpectCommit()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:    20,
 			wantErr: false,
@@ -327,63 +383,196 @@ func TestUserRepository_TopUp(t *testing.T) {
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date := time.Now().Format("01-02-2006 15:04:05")
 				result := sqlmock.NewResult(0, 0)
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Top-up by bank_card %fEUR", input.Amount), date).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Top-up by bank_card %f%s", input.Amount, input.Currency), date, float32(20), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result)
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
 			wantedErr: "failed to insert new transaction, rollback",
 		},
+		{
+			name: "Wallet is opened on first top-up",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s", walletsTable, usersTable)).
+					WithArgs(input.UserId, input.Currency).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(0, "", 0))
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+				date := time.Now().Format("01-02-2006 15:04:05")
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Top-up by bank_card %f%s", input.Amount, input.Currency), date, float32(10), "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectCommit()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   10,
+				Currency: "USD",
+			},
+			want:    10,
+			wantErr: false,
+		},
+		{
+			name: "Wallet is opened by concurrent top-up",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) ON CONFLICT (.+) DO NOTHING", walletsTable)).
+					WithArgs(input.UserId, input.Currency).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(5, "hash", 0))
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+				date := time.Now().Format("01-02-2006 15:04:05")
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Top-up by bank_card %f%s", input.Amount, input.Currency), date, float32(15), "", "hash", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectCommit()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   10,
+				Currency: "USD",
+			},
+			want:    15,
+			wantErr: false,
+		},
 		{
 			name: "User does not exist",
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
+				mock.ExpectQuery(fmt.Sprintf("SELECT status, credits_blocked FROM %s WHERE (.+) FOR SHARE", usersTable)).
 					WithArgs(input.UserId).
-					WillReturnRows(selectRows)
-
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 0))
+					WillReturnError(sql.ErrNoRows)
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
 			wantedErr: "user not found",
 		},
+		{
+			name: "Frozen account accepts credits",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				expectStatus(mock, input.UserId, models.AccountFrozen)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(10, "", 0))
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+				date := time.Now().Format("01-02-2006 15:04:05")
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Top-up by bank_card %f%s", input.Amount, input.Currency), date, float32(20), "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectCommit()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
+			},
+			want:    20,
+			wantErr: false,
+		},
+		{
+			name: "Frozen account with blocked credits",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				rows := sqlmock.NewRows([]string{"status", "credits_blocked"}).AddRow(models.AccountFrozen, true)
+				mock.ExpectQuery(fmt.Sprintf("SELECT status, credits_blocked FROM %s WHERE (.+) FOR SHARE", usersTable)).
+					WithArgs(input.UserId).WillReturnRows(rows)
+
+				mock.ExpectRollback()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
+			},
+			want:      0,
+			wantErr:   true,
+			wantedErr: "account is frozen",
+		},
+		{
+			name: "Account is closed",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				expectStatus(mock, input.UserId, models.AccountClosed)
+
+				mock.ExpectRollback()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
+			},
+			want:      0,
+			wantErr:   true,
+			wantedErr: "account is closed",
+		},
 		{
 			name: "Failed to begin tx",
 			mock: func(input models.Input) {
 				mock.ExpectBegin().WillReturnError(errors.New("failed to begin tx"))
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -394,20 +583,22 @@ func TestUserRepository_TopUp(t *testing.T) {
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnError(errors.New("no rows in a result set"))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnError(errors.New("no rows in a result set"))
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -419,7 +610,7 @@ func TestUserRepository_TopUp(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock(tt.input)
 
-			got, err := r.TopUp(tt.input)
+			got, err := topUp(NewSQLTransactor(sqlxDB), r, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Equal(t, err, errors.New(tt.wantedErr))
@@ -462,26 +653,28 @@ func TestUserRepository_Debit(t *testing.T) {
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date := time.Now().Format("01-02-2006 15:04:05")
 				result := sqlmock.NewResult(1, 1)
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by purchase %fEUR", input.Amount), date).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by purchase %f%s", input.Amount, input.Currency), date, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result)
 
 				mock.ExpectCommit()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:    0,
 			wantErr: false,
@@ -491,54 +684,53 @@ func TestUserRepository_Debit(t *testing.T) {
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date := time.Now().Format("01-02-2006 15:04:05")
 				result := sqlmock.NewResult(0, 0)
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by purchase %fEUR", input.Amount), date).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by purchase %f%s", input.Amount, input.Currency), date, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result)
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
 			wantedErr: "failed to insert new transaction, rollback",
 		},
 		{
-			name: "User does not exist",
+			name: "Wallet does not exist",
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
-					WillReturnRows(selectRows)
-
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 0))
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnError(sql.ErrNoRows)
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
-			wantedErr: "user not found",
+			wantedErr: "not enough money to perform purchase",
 		},
 		{
 			name: "Failed to begin tx",
@@ -546,27 +738,66 @@ func TestUserRepository_Debit(t *testing.T) {
 				mock.ExpectBegin().WillReturnError(errors.New("failed to begin tx"))
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
 			wantedErr: "failed to begin tx",
 		},
+		{
+			name: "Account is frozen",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				expectStatus(mock, input.UserId, models.AccountFrozen)
+
+				mock.ExpectRollback()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
+			},
+			want:      0,
+			wantErr:   true,
+			wantedErr: "account is frozen",
+		},
+		{
+			name: "Account is debit blocked",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				expectStatus(mock, input.UserId, models.AccountDebitBlocked)
+
+				mock.ExpectRollback()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
+			},
+			want:      0,
+			wantErr:   true,
+			wantedErr: "account is debit blocked",
+		},
 		{
 			name: "Select returned error",
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnError(errors.New("failed to connect to db"))
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -577,17 +808,74 @@ func TestUserRepository_Debit(t *testing.T) {
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnRows(selectRows)
+
+				mock.ExpectRollback()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   11,
+				Currency: "EUR",
+			},
+			want:      0,
+			wantErr:   true,
+			wantedErr: "not enough money to perform purchase",
+		},
+		{
+			name: "Overdraft within credit limit",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				// credit limit of 8 with 3 held
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 5)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
+					WillReturnRows(selectRows)
+
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+				date := time.Now().Format("01-02-2006 15:04:05")
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by purchase %f%s", input.Amount, input.Currency), date, float32(-5), "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				mock.ExpectCommit()
+			},
+			input: models.Input{
+				UserId:   1,
+				Amount:   15,
+				Currency: "EUR",
+			},
+			want:    -5,
+			wantErr: false,
+		},
+		{
+			name: "Held money is not available",
+			mock: func(input models.Input) {
+				mock.ExpectBegin()
+
+				// no credit limit with 4 held
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", -4)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 11,
+				UserId:   1,
+				Amount:   8,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -598,25 +886,27 @@ func TestUserRepository_Debit(t *testing.T) {
 			mock: func(input models.Input) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date := time.Now().Format("01-02-2006 15:04:05")
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by purchase %fEUR", input.Amount), date).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by purchase %f%s", input.Amount, input.Currency), date, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnError(errors.New("failed to insert"))
 
 				mock.ExpectRollback()
 			},
 			input: models.Input{
-				UserId: 1,
-				Amount: 10,
+				UserId:   1,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -628,7 +918,7 @@ func TestUserRepository_Debit(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock(tt.input)
 
-			got, err := r.Debit(tt.input)
+			got, err := debit(NewSQLTransactor(sqlxDB), r, tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Equal(t, err, errors.New(tt.wantedErr))
@@ -671,45 +961,48 @@ func TestUserRepository_Transfer(t *testing.T) {
 			mock: func(input models.TransferInput) {
 				mock.ExpectBegin()
 
-				selectRows2 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows2 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows2)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date2 := time.Now().Format("01-02-2006 15:04:05")
 				result2 := sqlmock.NewResult(1, 1)
 
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by transfer %fEUR", input.Amount), date2).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency), date2, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result2)
 
-				selectRows1 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows1 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.ToId).
+				expectStatus(mock, input.ToId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.ToId, input.Currency).
 					WillReturnRows(selectRows1)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.ToId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.ToId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date1 := time.Now().Format("01-02-2006 15:04:05")
 				result1 := sqlmock.NewResult(1, 1)
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.ToId, input.Amount, fmt.Sprintf("Top-up by transfer %fEUR", input.Amount), date1).
+					WithArgs(input.ToId, input.Amount, input.Currency, fmt.Sprintf("Top-up by transfer %f%s", input.Amount, input.Currency), date1, float32(20), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result1)
 
 				mock.ExpectCommit()
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:    20,
 			wantErr: false,
@@ -719,27 +1012,29 @@ func TestUserRepository_Transfer(t *testing.T) {
 			mock: func(input models.TransferInput) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date := time.Now().Format("01-02-2006 15:04:05")
 				result := sqlmock.NewResult(0, 0)
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by transfer %fEUR", input.Amount), date).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency), date, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result)
 
 				mock.ExpectRollback()
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -750,113 +1045,121 @@ func TestUserRepository_Transfer(t *testing.T) {
 			mock: func(input models.TransferInput) {
 				mock.ExpectBegin()
 
-				selectRows2 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows2 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows2)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date2 := time.Now().Format("01-02-2006 15:04:05")
 				result2 := sqlmock.NewResult(1, 1)
 
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by transfer %fEUR", input.Amount), date2).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency), date2, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result2)
 
-				selectRows1 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows1 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.ToId).
+				expectStatus(mock, input.ToId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.ToId, input.Currency).
 					WillReturnRows(selectRows1)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.ToId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.ToId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date1 := time.Now().Format("01-02-2006 15:04:05")
 				result1 := sqlmock.NewResult(1, 0)
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.ToId, input.Amount, fmt.Sprintf("Top-up by transfer %fEUR", input.Amount), date1).
+					WithArgs(input.ToId, input.Amount, input.Currency, fmt.Sprintf("Top-up by transfer %f%s", input.Amount, input.Currency), date1, float32(20), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result1)
 
 				mock.ExpectRollback()
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
 			wantedErr: "failed to insert new transaction, rollback",
 		},
 		{
-			name: "User does not exist",
+			name: "Wallet was not updated",
 			mock: func(input models.TransferInput) {
 				mock.ExpectBegin()
 
-				selectRows := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				selectRows := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 0))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 0))
 
 				mock.ExpectRollback()
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
-			wantedErr: "user not found",
+			wantedErr: "wallet not found",
 		},
 		{
 			name: "Receiver does not exist",
 			mock: func(input models.TransferInput) {
 				mock.ExpectBegin()
 
-				selectRows2 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows2 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows2)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date2 := time.Now().Format("01-02-2006 15:04:05")
 				result2 := sqlmock.NewResult(1, 1)
 
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by transfer %fEUR", input.Amount), date2).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency), date2, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result2)
 
-				selectRows1 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
-
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.ToId).
-					WillReturnRows(selectRows1)
+				expectStatus(mock, input.ToId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.ToId, input.Currency).
+					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.ToId).WillReturnResult(sqlmock.NewResult(1, 0))
+				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s", walletsTable, usersTable)).
+					WithArgs(input.ToId, input.Currency).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.ToId, input.Currency).
+					WillReturnError(sql.ErrNoRows)
 
 				mock.ExpectRollback()
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -868,9 +1171,10 @@ func TestUserRepository_Transfer(t *testing.T) {
 				mock.ExpectBegin().WillReturnError(errors.New("failed to begin tx"))
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -881,29 +1185,31 @@ func TestUserRepository_Transfer(t *testing.T) {
 			mock: func(input models.TransferInput) {
 				mock.ExpectBegin()
 
-				selectRows2 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows2 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows2)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date2 := time.Now().Format("01-02-2006 15:04:05")
 				result2 := sqlmock.NewErrorResult(errors.New("incorrect rowsAffected value"))
 
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by transfer %fEUR", input.Amount), date2).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency), date2, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result2)
 
 				mock.ExpectRollback()
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -914,39 +1220,42 @@ func TestUserRepository_Transfer(t *testing.T) {
 			mock: func(input models.TransferInput) {
 				mock.ExpectBegin()
 
-				selectRows2 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows2 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.UserId).
+				expectStatus(mock, input.UserId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.UserId, input.Currency).
 					WillReturnRows(selectRows2)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.UserId).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
 				date2 := time.Now().Format("01-02-2006 15:04:05")
 				result2 := sqlmock.NewResult(1, 1)
 
 				mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
-					WithArgs(input.UserId, input.Amount, fmt.Sprintf("Debit by transfer %fEUR", input.Amount), date2).
+					WithArgs(input.UserId, input.Amount, input.Currency, fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency), date2, float32(0), "", "", sqlmock.AnyArg()).
 					WillReturnResult(result2)
 
-				selectRows1 := sqlmock.NewRows([]string{"balance"}).
-					AddRow(10)
+				selectRows1 := sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).
+					AddRow(10, "", 0)
 
-				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+)", usersTable)).
-					WithArgs(input.ToId).
+				expectStatus(mock, input.ToId, models.AccountActive)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+					WithArgs(input.ToId, input.Currency).
 					WillReturnRows(selectRows1)
 
-				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+) RETURNING (.+)", usersTable)).
-					WithArgs(input.ToId).WillReturnResult(sqlmock.NewErrorResult(errors.New("incorrect rowsAffected value")))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+					WithArgs(input.ToId, input.Currency, input.Amount, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewErrorResult(errors.New("incorrect rowsAffected value")))
 
 				mock.ExpectRollback()
 			},
 			input: models.TransferInput{
-				UserId: 1,
-				ToId:   2,
-				Amount: 10,
+				UserId:   1,
+				ToId:     2,
+				Amount:   10,
+				Currency: "EUR",
 			},
 			want:      0,
 			wantErr:   true,
@@ -958,7 +1267,178 @@ func TestUserRepository_Transfer(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock(tt.input)
 
-			got, err := r.Transfer(tt.input)
+			got, err := transfer(NewSQLTransactor(sqlxDB), r, tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepository_GetBalanceAt(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewUserRepo(sqlxDB, logger)
+
+	type mockBehavior func(userID int, at time.Time)
+
+	at := utils.ParseTime("2023-05-25 19:01:23", t)
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		userID    int
+		want      []models.Wallet
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func(userID int, at time.Time) {
+				rows := sqlmock.NewRows([]string{"user_id", "currency", "balance"}).
+					AddRow(userID, "EUR", 30).
+					AddRow(userID, "USD", 0)
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s t WHERE (.+) FROM %s w WHERE (.+)", transactionsTable, walletsTable)).
+					WithArgs(userID, at).WillReturnRows(rows)
+			},
+			userID: 1,
+			want: []models.Wallet{
+				{UserId: 1, Currency: "EUR", Balance: 30},
+				{UserId: 1, Currency: "USD", Balance: 0},
+			},
+			wantErr: false,
+		},
+		{
+			name: "User does not exist",
+			mock: func(userID int, at time.Time) {
+				rows := sqlmock.NewRows([]string{"user_id", "currency", "balance"})
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s t WHERE (.+) FROM %s w WHERE (.+)", transactionsTable, walletsTable)).
+					WithArgs(userID, at).WillReturnRows(rows)
+
+				exists := sqlmock.NewRows([]string{"exists"}).AddRow(false)
+				mock.ExpectQuery(fmt.Sprintf("SELECT EXISTS(.+) FROM %s WHERE (.+)", usersTable)).
+					WithArgs(userID).WillReturnRows(exists)
+			},
+			userID:    100,
+			wantErr:   true,
+			wantedErr: "user not found",
+		},
+		{
+			name: "Random error",
+			mock: func(userID int, at time.Time) {
+				mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s t WHERE (.+) FROM %s w WHERE (.+)", transactionsTable, walletsTable)).
+					WithArgs(userID, at).WillReturnError(errors.New("db is not valid"))
+			},
+			userID:    100,
+			wantErr:   true,
+			wantedErr: "db is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(tt.userID, at)
+
+			got, err := r.GetBalanceAt(tt.userID, at)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, err, errors.New(tt.wantedErr))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepository_GetBalanceHistory(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewUserRepo(sqlxDB, logger)
+
+	type mockBehavior func(userID int, from, to time.Time)
+
+	from := utils.ParseTime("2023-05-25 00:00:00", t)
+	to := utils.ParseTime("2023-05-26 00:00:00", t)
+
+	tests := []struct {
+		name      string
+		mock      mockBehavior
+		userID    int
+		want      []models.BalancePoint
+		wantErr   bool
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func(userID int, from, to time.Time) {
+				rows := sqlmock.NewRows([]string{"date", "balance"}).
+					AddRow(from, 30).
+					AddRow(to, 12.5)
+				mock.ExpectQuery("SELECT (.+) FROM (.+) generate_series(.+)").
+					WithArgs(userID, "EUR", from, to, "1 day").WillReturnRows(rows)
+			},
+			userID: 1,
+			want: []models.BalancePoint{
+				{Date: from, Balance: 30},
+				{Date: to, Balance: 12.5},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Wallet does not exist",
+			mock: func(userID int, from, to time.Time) {
+				rows := sqlmock.NewRows([]string{"date", "balance"})
+				mock.ExpectQuery("SELECT (.+) FROM (.+) generate_series(.+)").
+					WithArgs(userID, "EUR", from, to, "1 day").WillReturnRows(rows)
+			},
+			userID:    100,
+			wantErr:   true,
+			wantedErr: "wallet not found",
+		},
+		{
+			name: "Random error",
+			mock: func(userID int, from, to time.Time) {
+				mock.ExpectQuery("SELECT (.+) FROM (.+) generate_series(.+)").
+					WithArgs(userID, "EUR", from, to, "1 day").WillReturnError(errors.New("db is not valid"))
+			},
+			userID:    1,
+			wantErr:   true,
+			wantedErr: "db is not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock(tt.userID, from, to)
+
+			got, err := r.GetBalanceHistory(tt.userID, "EUR", from, to, "1 day")
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Equal(t, err, errors.New(tt.wantedErr))
@@ -970,3 +1450,117 @@ func TestUserRepository_Transfer(t *testing.T) {
 		})
 	}
 }
+
+// expectStatus expects account status check made by Credit and Debit
+func TestUserRepository_SystemDebit(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewUserRepo(sqlxDB, logger)
+	input := models.Input{UserId: 1, Amount: 2, Currency: "EUR"}
+	operation := models.Operation{Comment: "Overdraft charge", LinkId: "overdraft-1", Overdraw: true, System: true}
+
+	for _, status := range []string{models.AccountFrozen, models.AccountDebitBlocked} {
+		t.Run(status, func(t *testing.T) {
+			// system postings go on while the account is frozen or debit blocked
+			mock.ExpectBegin()
+			expectStatus(mock, input.UserId, status)
+			mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s WHERE (.+) FOR UPDATE", walletsTable)).
+				WithArgs(input.UserId, input.Currency).
+				WillReturnRows(sqlmock.NewRows([]string{"balance", "last_hash", "credit"}).AddRow(-10, "", 0))
+			mock.ExpectExec(fmt.Sprintf("UPDATE %s SET (.+) WHERE (.+)", walletsTable)).
+				WithArgs(input.UserId, input.Currency, input.Amount, sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(fmt.Sprintf("INSERT INTO %s", transactionsTable)).
+				WithArgs(input.UserId, input.Amount, input.Currency, operation.Comment, sqlmock.AnyArg(), float32(-12),
+					operation.LinkId, "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+
+			var got float32
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, err = r.Debit(tx, input, operation)
+				return err
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, float32(-12), got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("closed", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectStatus(mock, input.UserId, models.AccountClosed)
+		mock.ExpectRollback()
+
+		err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+			_, err := r.Debit(tx, input, operation)
+			return err
+		})
+
+		assert.EqualError(t, err, "account is closed")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func expectStatus(mock sqlmock.Sqlmock, userId int, status string) {
+	rows := sqlmock.NewRows([]string{"status", "credits_blocked"}).AddRow(status, false)
+	mock.ExpectQuery(fmt.Sprintf("SELECT status, credits_blocked FROM %s WHERE (.+) FOR SHARE", usersTable)).
+		WithArgs(userId).WillReturnRows(rows)
+}
+
+// topUp, debit and transfer compose balance changes in a unit of work the same way as service does
+
+func topUp(tr Transactor, r User, input models.Input) (float32, error) {
+	var balance float32
+	err := tr.WithinTx(func(tx Tx) error {
+		var err error
+		balance, err = r.Credit(tx, input, models.Operation{
+			Comment: fmt.Sprintf("Top-up by bank_card %f%s", input.Amount, input.Currency),
+		})
+		return err
+	})
+
+	return balance, err
+}
+
+func debit(tr Transactor, r User, input models.Input) (float32, error) {
+	var balance float32
+	err := tr.WithinTx(func(tx Tx) error {
+		var err error
+		balance, err = r.Debit(tx, input, models.Operation{
+			Comment: fmt.Sprintf("Debit by purchase %f%s", input.Amount, input.Currency),
+		})
+		return err
+	})
+
+	return balance, err
+}
+
+func transfer(tr Transactor, r User, input models.TransferInput) (float32, error) {
+	var balance float32
+	err := tr.WithinTx(func(tx Tx) error {
+		_, err := r.Debit(tx, models.Input{UserId: input.UserId, Amount: input.Amount, Currency: input.Currency},
+			models.Operation{Comment: fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency)})
+		if err != nil {
+			return err
+		}
+
+		balance, err = r.Credit(tx, models.Input{UserId: input.ToId, Amount: input.Amount, Currency: input.Currency},
+			models.Operation{Comment: fmt.Sprintf("Top-up by transfer %f%s", input.Amount, input.Currency)})
+		return err
+	})
+
+	return balance, err
+}
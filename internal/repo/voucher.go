@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+)
+
+type Voucher interface {
+	// CreateBatch saves the batch with hashes of its codes as a part of tx
+	CreateBatch(tx Tx, batch models.VoucherBatch, hashes []string) (models.VoucherBatch, error)
+	// LockVoucher locks the voucher with the code hash until tx ends
+	LockVoucher(tx Tx, hash string) (models.Voucher, error)
+	// Redeem saves the redemption of the locked voucher by the user and counts the use, the redemption is
+	// credited by the caller
+	Redeem(tx Tx, voucher models.Voucher, userId int) (models.VoucherRedemption, error)
+	// GetVoucherReport returns batches of the currency with their redemptions, value is summed up at now
+	GetVoucherReport(currency string, now time.Time) (models.VoucherReport, error)
+}
+
+type VoucherRepo struct {
+	db  *sqlx.DB
+	log logging.Logger
+}
+
+func NewVoucherRepo(db *sqlx.DB, log logging.Logger) *VoucherRepo {
+	return &VoucherRepo{
+		db:  db,
+		log: log,
+	}
+}
+
+func (r *VoucherRepo) CreateBatch(unit Tx, batch models.VoucherBatch, hashes []string) (models.VoucherBatch, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.VoucherBatch{}, err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (amount, currency, codes, max_uses, expires_at) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`, voucherBatchesTable)
+	err = tx.QueryRow(query, batch.Amount, batch.Currency, len(hashes), batch.MaxUses, batch.ExpiresAt).
+		Scan(&batch.ID, &batch.CreatedAt)
+	if err != nil {
+		return models.VoucherBatch{}, err
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (batch_id, code_hash) VALUES ($1, $2)", vouchersTable)
+	for _, hash := range hashes {
+		if _, err := tx.Exec(insert, batch.ID, hash); err != nil {
+			return models.VoucherBatch{}, err
+		}
+	}
+
+	batch.Count = len(hashes)
+	return batch, nil
+}
+
+func (r *VoucherRepo) LockVoucher(unit Tx, hash string) (models.Voucher, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.Voucher{}, err
+	}
+
+	var voucher models.Voucher
+	query := fmt.Sprintf(`SELECT v.id, v.batch_id, v.uses, b.amount, b.currency, b.max_uses, b.expires_at
+		FROM %s v JOIN %s b ON b.id = v.batch_id WHERE v.code_hash = $1 FOR UPDATE OF v`,
+		vouchersTable, voucherBatchesTable)
+	err = tx.QueryRow(query, hash).Scan(&voucher.ID, &voucher.BatchId, &voucher.Uses, &voucher.Amount,
+		&voucher.Currency, &voucher.MaxUses, &voucher.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Voucher{}, models.ErrVoucherNotFound
+		}
+
+		return models.Voucher{}, err
+	}
+
+	return voucher, nil
+}
+
+func (r *VoucherRepo) Redeem(unit Tx, voucher models.Voucher, userId int) (models.VoucherRedemption, error) {
+	tx, err := txOf(unit)
+	if err != nil {
+		return models.VoucherRedemption{}, err
+	}
+
+	redemption := models.VoucherRedemption{
+		VoucherId: voucher.ID,
+		BatchId:   voucher.BatchId,
+		UserId:    userId,
+		Amount:    voucher.Amount,
+		Currency:  voucher.Currency,
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (voucher_id, batch_id, user_id, amount, currency)
+		SELECT $1, $2, id, $4, $5 FROM %s WHERE id = $3
+		ON CONFLICT (voucher_id, user_id) DO NOTHING RETURNING id, redeemed_at`, voucherRedemptionsTable, usersTable)
+	err = tx.QueryRow(query, voucher.ID, voucher.BatchId, userId, voucher.Amount, voucher.Currency).
+		Scan(&redemption.ID, &redemption.RedeemedAt)
+	if err == sql.ErrNoRows {
+		var exists bool
+		check := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)", usersTable)
+		if err := tx.QueryRow(check, userId).Scan(&exists); err != nil {
+			return models.VoucherRedemption{}, err
+		}
+
+		if !exists {
+			return models.VoucherRedemption{}, errors.New("user not found")
+		}
+
+		return models.VoucherRedemption{}, fmt.Errorf("%w: voucher is already redeemed by the user",
+			models.ErrVoucherNotRedeemable)
+	}
+	if err != nil {
+		return models.VoucherRedemption{}, err
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET uses = uses + 1 WHERE id = $1", vouchersTable)
+	if _, err := tx.Exec(update, voucher.ID); err != nil {
+		return models.VoucherRedemption{}, err
+	}
+
+	return redemption, nil
+}
+
+func (r *VoucherRepo) GetVoucherReport(currency string, now time.Time) (models.VoucherReport, error) {
+	report := models.VoucherReport{Currency: currency, Batches: []models.VoucherBatch{}}
+	query := fmt.Sprintf(`SELECT b.id, b.amount, b.currency, b.codes, b.max_uses, b.expires_at, b.created_at,
+		COUNT(r.id) AS redemptions, COALESCE(SUM(r.amount), 0) AS redeemed_value
+		FROM %s b LEFT JOIN %s r ON r.batch_id = b.id WHERE b.currency = $1 GROUP BY b.id ORDER BY b.id`,
+		voucherBatchesTable, voucherRedemptionsTable)
+	if err := r.db.Select(&report.Batches, query, currency); err != nil {
+		return models.VoucherReport{}, err
+	}
+
+	for _, batch := range report.Batches {
+		issued := batch.IssuedValue()
+		report.IssuedValue += issued
+		report.RedeemedValue += batch.RedeemedValue
+		if batch.Expired(now) {
+			report.ExpiredValue += issued - batch.RedeemedValue
+		} else {
+			report.OutstandingValue += issued - batch.RedeemedValue
+		}
+	}
+
+	r.log.LogRepo("GET", "GetVoucherReport", true, report)
+	return report, nil
+}
@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoucherRepository_Redeem(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewVoucherRepo(sqlxDB, logger)
+	redeemedAt := time.Date(2023, 8, 30, 12, 0, 0, 0, time.UTC)
+	voucher := models.Voucher{ID: 4, BatchId: 2, Uses: 1, Amount: 25, Currency: "USD", MaxUses: 3}
+	insert := fmt.Sprintf("INSERT INTO %s (.+) SELECT (.+) FROM %s WHERE (.+) ON CONFLICT (.+) DO NOTHING",
+		voucherRedemptionsTable, usersTable)
+	exists := fmt.Sprintf("SELECT EXISTS (.+) FROM %s", usersTable)
+
+	tests := []struct {
+		name      string
+		mock      func()
+		want      models.VoucherRedemption
+		wantedErr string
+	}{
+		{
+			name: "Ok",
+			mock: func() {
+				mock.ExpectQuery(insert).WithArgs(4, 2, 1, voucher.Amount, "USD").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "redeemed_at"}).AddRow(7, redeemedAt))
+				mock.ExpectExec(fmt.Sprintf("UPDATE %s SET uses = uses \\+ 1", vouchersTable)).WithArgs(4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: models.VoucherRedemption{ID: 7, VoucherId: 4, BatchId: 2, UserId: 1, Amount: 25, Currency: "USD",
+				RedeemedAt: redeemedAt},
+		},
+		{
+			name: "Already redeemed",
+			mock: func() {
+				mock.ExpectQuery(insert).WithArgs(4, 2, 1, voucher.Amount, "USD").WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(exists).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			wantedErr: "voucher can not be redeemed: voucher is already redeemed by the user",
+		},
+		{
+			name: "User does not exist",
+			mock: func() {
+				mock.ExpectQuery(insert).WithArgs(4, 2, 1, voucher.Amount, "USD").WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery(exists).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectRollback()
+			},
+			wantedErr: "user not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+
+			var got models.VoucherRedemption
+			err := NewSQLTransactor(sqlxDB).WithinTx(func(tx Tx) error {
+				var err error
+				got, err = r.Redeem(tx, voucher, 1)
+				return err
+			})
+
+			if tt.wantedErr != "" {
+				assert.EqualError(t, err, tt.wantedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestVoucherRepository_GetVoucherReport(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	r := NewVoucherRepo(sqlxDB, logger)
+	now := time.Date(2023, 8, 30, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+
+	columns := []string{"id", "amount", "currency", "codes", "max_uses", "expires_at", "created_at", "redemptions",
+		"redeemed_value"}
+	mock.ExpectQuery(fmt.Sprintf("SELECT (.+) FROM %s b LEFT JOIN %s r (.+) GROUP BY b.id",
+		voucherBatchesTable, voucherRedemptionsTable)).WithArgs("EUR").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 10, "EUR", 5, 1, nil, expired, 2, 20).
+			AddRow(2, 5, "EUR", 2, 3, expired, expired, 1, 5))
+
+	report, err := r.GetVoucherReport("EUR", now)
+	assert.NoError(t, err)
+	assert.Len(t, report.Batches, 2)
+	assert.Equal(t, float32(50+30), report.IssuedValue)
+	assert.Equal(t, float32(25), report.RedeemedValue)
+	assert.Equal(t, float32(25), report.ExpiredValue)
+	assert.Equal(t, float32(30), report.OutstandingValue)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+type AccountService struct {
+	repo repo.Account
+	log  logging.Logger
+}
+
+func NewAccountService(repo repo.Account, log logging.Logger) *AccountService {
+	return &AccountService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+func (s *AccountService) GetAccount(id int) (models.Account, error) {
+	return s.repo.GetAccount(id)
+}
+
+func (s *AccountService) SetAccountStatus(input models.AccountStatusInput) (models.Account, error) {
+	if err := input.Validate(); err != nil {
+		return models.Account{}, err
+	}
+
+	return s.repo.SetAccountStatus(input)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+type AdjustmentService struct {
+	repo repo.Adjustment
+	log  logging.Logger
+}
+
+func NewAdjustmentService(repo repo.Adjustment, log logging.Logger) *AdjustmentService {
+	return &AdjustmentService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// ProposeAdjustment creates pending adjustment, balance is not changed until it is approved
+func (s *AdjustmentService) ProposeAdjustment(input models.AdjustmentInput) (models.Adjustment, error) {
+	if err := input.Validate(); err != nil {
+		return models.Adjustment{}, err
+	}
+
+	return s.repo.CreateAdjustment(input)
+}
+
+func (s *AdjustmentService) GetAdjustment(id int) (models.Adjustment, error) {
+	return s.repo.GetAdjustment(id)
+}
+
+func (s *AdjustmentService) ListAdjustments(status string) ([]models.Adjustment, error) {
+	switch status {
+	case "", models.AdjustmentPending, models.AdjustmentApproved, models.AdjustmentRejected:
+		return s.repo.ListAdjustments(status)
+	}
+
+	return nil, fmt.Errorf("unsupported status %q", status)
+}
+
+func (s *AdjustmentService) ApproveAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	if err := review.Validate(); err != nil {
+		return models.Adjustment{}, err
+	}
+
+	return s.repo.ApproveAdjustment(id, review)
+}
+
+func (s *AdjustmentService) RejectAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	if err := review.Validate(); err != nil {
+		return models.Adjustment{}, err
+	}
+
+	return s.repo.RejectAdjustment(id, review)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+type AuditConfig struct {
+	// CheckpointInterval between scheduled checkpoints, zero disables them
+	CheckpointInterval time.Duration
+	// CheckpointFile is the file checkpoints are appended to, one JSON per line
+	CheckpointFile string
+	// SigningKey is hex encoded ed25519 seed checkpoints are signed with
+	SigningKey string
+}
+
+type AuditService struct {
+	repo repo.Audit
+	cfg  AuditConfig
+	log  logging.Logger
+}
+
+func NewAuditService(repo repo.Audit, cfg AuditConfig, log logging.Logger) *AuditService {
+	return &AuditService{
+		repo: repo,
+		cfg:  cfg,
+		log:  log,
+	}
+}
+
+func (s *AuditService) VerifyChain() (models.ChainVerification, error) {
+	result, err := s.repo.VerifyChain()
+	if err != nil {
+		return models.ChainVerification{}, err
+	}
+
+	if result.Break != nil {
+		s.log.Infof("transaction chain of user %d in %s is broken at transaction %d: %s", result.Break.UserId,
+			result.Break.Currency, result.Break.TransactionId, result.Break.Reason)
+	}
+
+	return result, nil
+}
+
+// CreateCheckpoint signs heads of all chains and appends the checkpoint to the checkpoint file
+func (s *AuditService) CreateCheckpoint() (models.Checkpoint, error) {
+	key, err := s.signingKey()
+	if err != nil {
+		return models.Checkpoint{}, err
+	}
+
+	if s.cfg.CheckpointFile == "" {
+		return models.Checkpoint{}, errors.New("checkpoint file is not configured")
+	}
+
+	heads, last, err := s.repo.GetChainHeads()
+	if err != nil {
+		return models.Checkpoint{}, err
+	}
+
+	checkpoint := models.Checkpoint{
+		CreatedAt:         time.Now().UTC(),
+		LastTransactionId: last,
+		Heads:             heads,
+		PublicKey:         hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+	}
+	checkpoint.Root = checkpoint.ComputeRoot()
+	checkpoint.Signature = hex.EncodeToString(ed25519.Sign(key, checkpoint.SignedContent()))
+
+	if err := s.export(checkpoint); err != nil {
+		return models.Checkpoint{}, err
+	}
+
+	s.log.Infof("checkpoint of %d chains up to transaction %d exported to %s", len(heads), last, s.cfg.CheckpointFile)
+	return checkpoint, nil
+}
+
+// ScheduleCheckpoints creates checkpoint every configured interval until stop is closed
+func (s *AuditService) ScheduleCheckpoints(stop <-chan struct{}) {
+	if s.cfg.CheckpointInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.CheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.CreateCheckpoint(); err != nil {
+				s.log.Infof("scheduled checkpoint failed: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (s *AuditService) signingKey() (ed25519.PrivateKey, error) {
+	if s.cfg.SigningKey == "" {
+		return nil, errors.New("audit signing key is not configured")
+	}
+
+	seed, err := hex.DecodeString(s.cfg.SigningKey)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("audit signing key must be hex encoded 32 bytes seed")
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func (s *AuditService) export(checkpoint models.Checkpoint) error {
+	file, err := os.OpenFile(s.cfg.CheckpointFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(checkpoint)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
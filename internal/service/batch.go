@@ -0,0 +1,250 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+type BatchConfig struct {
+	// MaxItems limits the number of items in a single batch
+	MaxItems int
+}
+
+// errItemFailed rolls back the unit of work of a failed item, the failure itself is reported in its result
+var errItemFailed = errors.New("batch item failed")
+
+type BatchService struct {
+	tx   repo.Transactor
+	repo repo.Batch
+	user repo.User
+	// payments make debits and transfers of items like the ones made by hand
+	payments *Payments
+	cfg      BatchConfig
+	log      logging.Logger
+}
+
+func NewBatchService(tx repo.Transactor, repo repo.Batch, user repo.User, payments *Payments, cfg BatchConfig,
+	log logging.Logger) *BatchService {
+	return &BatchService{
+		tx:       tx,
+		repo:     repo,
+		user:     user,
+		payments: payments,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+func (s *BatchService) ApplyBatch(input models.BatchInput) (models.BatchOutput, error) {
+	if err := input.Validate(); err != nil {
+		return models.BatchOutput{}, err
+	}
+
+	if len(input.Items) > s.cfg.MaxItems {
+		return models.BatchOutput{}, fmt.Errorf("%w, it is limited to %d items", models.ErrBatchTooLarge, s.cfg.MaxItems)
+	}
+
+	return s.apply(input)
+}
+
+// apply applies all items in a single unit of work if the batch is atomic,
+// otherwise every item is applied in its own unit of work
+func (s *BatchService) apply(input models.BatchInput) (models.BatchOutput, error) {
+	var (
+		results []models.BatchResult
+		err     error
+	)
+
+	if input.Mode == models.BatchAtomic {
+		results, err = s.applyAtomic(input.Items)
+	} else {
+		results, err = s.applyBestEffort(input.Items)
+	}
+	if err != nil {
+		return models.BatchOutput{}, err
+	}
+
+	output := models.BatchOutput{
+		Mode:    input.Mode,
+		Results: results,
+	}
+
+	for _, result := range results {
+		switch result.Status {
+		case models.BatchItemOk, models.BatchItemDuplicate:
+			output.Applied++
+		case models.BatchItemFailed:
+			output.Failed++
+		}
+	}
+
+	s.log.LogRepo("POST", "ApplyBatch", output.Failed == 0, output)
+	return output, nil
+}
+
+func (s *BatchService) applyAtomic(items []models.BatchItem) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, 0, len(items))
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		for i, item := range items {
+			result := s.applyItem(tx, i, item)
+			results = append(results, result)
+
+			if result.Status == models.BatchItemFailed {
+				return errItemFailed
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, errItemFailed) {
+		return rollBack(results, items), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// applyItem applies a single item as a part of tx, the result tells if it failed. Item with idempotency key
+// which is already used is not applied again
+func (s *BatchService) applyItem(tx repo.Tx, index int, item models.BatchItem) models.BatchResult {
+	if item.IdempotencyKey != "" {
+		balance, duplicate, err := s.repo.ClaimKey(tx, item.IdempotencyKey)
+		if err != nil {
+			return failed(index, item, err)
+		}
+
+		if duplicate {
+			return models.BatchResult{
+				Index:          index,
+				IdempotencyKey: item.IdempotencyKey,
+				Status:         models.BatchItemDuplicate,
+				Balance:        balance,
+			}
+		}
+	}
+
+	balance, err := s.applyOperation(tx, item)
+	if err != nil {
+		return failed(index, item, err)
+	}
+
+	if item.IdempotencyKey != "" {
+		if err := s.repo.SaveKey(tx, item.IdempotencyKey, balance); err != nil {
+			return failed(index, item, err)
+		}
+	}
+
+	return models.BatchResult{
+		Index:          index,
+		IdempotencyKey: item.IdempotencyKey,
+		Status:         models.BatchItemOk,
+		Balance:        balance,
+	}
+}
+
+// applyOperation returns balance of the user of top-up and debit and of the recipient of transfer
+func (s *BatchService) applyOperation(tx repo.Tx, item models.BatchItem) (float32, error) {
+	input := models.Input{
+		UserId:   item.UserId,
+		Amount:   item.Amount,
+		Currency: item.Currency,
+	}
+
+	switch item.Type {
+	case models.BatchTopUp:
+		return s.user.Credit(tx, input, models.Operation{
+			Comment: comment(item, fmt.Sprintf("Top-up by batch %f%s", item.Amount, item.Currency)),
+		})
+	case models.BatchDebit:
+		return s.payments.Debit(tx, input, models.Operation{
+			Comment: comment(item, fmt.Sprintf("Debit by batch %f%s", item.Amount, item.Currency)),
+		})
+	case models.BatchTransfer:
+		_, balance, err := s.payments.Transfer(tx, models.TransferInput{
+			UserId:   item.UserId,
+			ToId:     item.ToId,
+			Amount:   item.Amount,
+			Currency: item.Currency,
+		}, "")
+		return balance, err
+	}
+
+	return 0, fmt.Errorf("unsupported type %q", item.Type)
+}
+
+// comment returns item`s own comment if it is set
+func comment(item models.BatchItem, fallback string) string {
+	if item.Comment != "" {
+		return item.Comment
+	}
+
+	return fallback
+}
+
+func failed(index int, item models.BatchItem, err error) models.BatchResult {
+	return models.BatchResult{
+		Index:          index,
+		IdempotencyKey: item.IdempotencyKey,
+		Status:         models.BatchItemFailed,
+		Error:          err.Error(),
+	}
+}
+
+// rollBack marks all items except the failed one as rolled back
+func rollBack(results []models.BatchResult, items []models.BatchItem) []models.BatchResult {
+	for i := range results {
+		if results[i].Status != models.BatchItemFailed {
+			results[i].Status = models.BatchItemRolledBack
+			results[i].Balance = 0
+		}
+	}
+
+	for i := len(results); i < len(items); i++ {
+		results = append(results, models.BatchResult{
+			Index:          i,
+			IdempotencyKey: items[i].IdempotencyKey,
+			Status:         models.BatchItemRolledBack,
+		})
+	}
+
+	return results
+}
+
+func (s *BatchService) applyBestEffort(items []models.BatchItem) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, 0, len(items))
+	for i, item := range items {
+		var result models.BatchResult
+		err := s.tx.WithinTx(func(tx repo.Tx) error {
+			result = s.applyItem(tx, i, item)
+			if result.Status == models.BatchItemFailed {
+				return errItemFailed
+			}
+
+			return nil
+		})
+
+		if err != nil && !errors.Is(err, errItemFailed) {
+			// the item has no result when its unit of work could not be started
+			if result.Status == "" {
+				return nil, err
+			}
+
+			result = models.BatchResult{
+				Index:          i,
+				IdempotencyKey: item.IdempotencyKey,
+				Status:         models.BatchItemFailed,
+				Error:          err.Error(),
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
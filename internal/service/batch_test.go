@@ -0,0 +1,213 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryBatch keeps idempotency keys, units of work of memory users repository serialize the access
+type memoryBatch struct {
+	keys map[string]float32
+}
+
+func (b memoryBatch) ClaimKey(tx repo.Tx, key string) (float32, bool, error) {
+	balance, ok := b.keys[key]
+	if !ok {
+		b.keys[key] = 0
+	}
+
+	return balance, ok, nil
+}
+
+func (b memoryBatch) SaveKey(tx repo.Tx, key string, balance float32) error {
+	b.keys[key] = balance
+	return nil
+}
+
+func TestBatchService_ApplyBatch(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	topUp := models.BatchItem{Type: models.BatchTopUp, UserId: 1, Amount: 10, Currency: "EUR"}
+	debit := models.BatchItem{Type: models.BatchDebit, UserId: 1, Amount: 15, Currency: "EUR"}
+
+	tests := []struct {
+		name        string
+		input       models.BatchInput
+		want        models.BatchOutput
+		wantBalance float32
+	}{
+		{
+			name:  "Atomic",
+			input: models.BatchInput{Mode: models.BatchAtomic, Items: []models.BatchItem{topUp, topUp}},
+			want: models.BatchOutput{
+				Mode:    models.BatchAtomic,
+				Applied: 2,
+				Results: []models.BatchResult{
+					{Index: 0, Status: models.BatchItemOk, Balance: 10},
+					{Index: 1, Status: models.BatchItemOk, Balance: 20},
+				},
+			},
+			wantBalance: 20,
+		},
+		{
+			name:  "Atomic is rolled back",
+			input: models.BatchInput{Mode: models.BatchAtomic, Items: []models.BatchItem{topUp, debit, topUp}},
+			want: models.BatchOutput{
+				Mode:   models.BatchAtomic,
+				Failed: 1,
+				Results: []models.BatchResult{
+					{Index: 0, Status: models.BatchItemRolledBack},
+					{Index: 1, Status: models.BatchItemFailed, Error: "not enough money to perform purchase"},
+					{Index: 2, Status: models.BatchItemRolledBack},
+				},
+			},
+		},
+		{
+			name:  "Best effort",
+			input: models.BatchInput{Mode: models.BatchBestEffort, Items: []models.BatchItem{topUp, debit, topUp}},
+			want: models.BatchOutput{
+				Mode:    models.BatchBestEffort,
+				Applied: 2,
+				Failed:  1,
+				Results: []models.BatchResult{
+					{Index: 0, Status: models.BatchItemOk, Balance: 10},
+					{Index: 1, Status: models.BatchItemFailed, Error: "not enough money to perform purchase"},
+					{Index: 2, Status: models.BatchItemOk, Balance: 20},
+				},
+			},
+			wantBalance: 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := repo.NewMemoryUserRepo(logger)
+			user.AddUsers(1)
+
+			s := NewBatchService(user, memoryBatch{keys: map[string]float32{}}, user, testPayments(user),
+				BatchConfig{MaxItems: 10}, logger)
+
+			got, err := s.ApplyBatch(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			var balance float32
+			wallets, err := user.GetWallets(1)
+			assert.NoError(t, err)
+			for _, wallet := range wallets {
+				balance += wallet.Balance
+			}
+			assert.Equal(t, tt.wantBalance, balance)
+		})
+	}
+}
+
+func TestBatchService_Limits(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2)
+	limits := &memoryLimits{}
+	payments := NewPayments(user, limits, &memoryFees{}, &memoryPromo{}, PaymentsConfig{})
+	s := NewBatchService(user, memoryBatch{keys: map[string]float32{}}, user, payments,
+		BatchConfig{MaxItems: 10}, logger)
+
+	_, err = limits.SetLimit(models.LimitInput{UserId: 1, Operation: models.LimitAny, Period: models.LimitDaily,
+		Amount: 50, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	output, err := s.ApplyBatch(models.BatchInput{Mode: models.BatchBestEffort, Items: []models.BatchItem{
+		{Type: models.BatchTopUp, UserId: 1, Amount: 100, Currency: "EUR"},
+		{Type: models.BatchDebit, UserId: 1, Amount: 40, Currency: "EUR"},
+		{Type: models.BatchDebit, UserId: 1, Amount: 20, Currency: "EUR"},
+		{Type: models.BatchTransfer, UserId: 1, ToId: 2, Amount: 20, Currency: "EUR"},
+		{Type: models.BatchTransfer, UserId: 1, ToId: 2, Amount: 10, Currency: "EUR"},
+	}})
+	assert.NoError(t, err)
+
+	statuses := make([]string, 0, len(output.Results))
+	for _, result := range output.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []string{models.BatchItemOk, models.BatchItemOk, models.BatchItemFailed, models.BatchItemFailed,
+		models.BatchItemOk}, statuses)
+	assert.Equal(t, "spending limit exceeded: limit #1 of 50.00 EUR per day on debits and transfers, 10.00 is remaining",
+		output.Results[2].Error)
+
+	wallets, err := user.GetWallets(1)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(50), wallets[0].Balance)
+}
+
+func TestBatchService_TransferFee(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2, 100)
+	fees := &memoryFees{}
+	payments := NewPayments(user, &memoryLimits{}, fees, &memoryPromo{},
+		PaymentsConfig{Fee: FeeConfig{RevenueAccount: 100}})
+	s := NewBatchService(user, memoryBatch{keys: map[string]float32{}}, user, payments, BatchConfig{MaxItems: 10},
+		logger)
+
+	_, err = fees.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, Fixed: 1})
+	assert.NoError(t, err)
+
+	output, err := s.ApplyBatch(models.BatchInput{Mode: models.BatchAtomic, Items: []models.BatchItem{
+		{Type: models.BatchTopUp, UserId: 1, Amount: 20, Currency: "EUR"},
+		{Type: models.BatchTransfer, UserId: 1, ToId: 2, Amount: 10, Currency: "EUR"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, output.Applied)
+
+	for id, want := range map[int]float32{1: 9, 2: 10, 100: 1} {
+		wallets, err := user.GetWallets(id)
+		assert.NoError(t, err)
+		assert.Equal(t, want, wallets[0].Balance)
+	}
+}
+
+func TestBatchService_Promo(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1)
+	promo := &memoryPromo{}
+	cfg := PromoConfig{TTL: 24 * time.Hour, SpendFirst: true}
+	payments := NewPayments(user, &memoryLimits{}, &memoryFees{}, promo, PaymentsConfig{Promo: cfg})
+	s := NewBatchService(user, memoryBatch{keys: map[string]float32{}}, user, payments, BatchConfig{MaxItems: 10},
+		logger)
+
+	_, err = NewPromoService(user, promo, user, cfg, logger).GrantPromo(models.PromoGrantInput{UserId: 1, Amount: 5,
+		Currency: "EUR", CampaignId: "spring"})
+	assert.NoError(t, err)
+
+	output, err := s.ApplyBatch(models.BatchInput{Mode: models.BatchAtomic, Items: []models.BatchItem{
+		{Type: models.BatchTopUp, UserId: 1, Amount: 20, Currency: "EUR"},
+		{Type: models.BatchDebit, UserId: 1, Amount: 8, Currency: "EUR"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, output.Applied)
+
+	wallets, err := user.GetWallets(1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Wallet{UserId: 1, Currency: "EUR", Balance: 17}, wallets[0])
+	assert.Equal(t, float32(0), promo.grants[0].Remaining)
+}
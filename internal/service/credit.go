@@ -0,0 +1,254 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/utils"
+)
+
+type OverdraftConfig struct {
+	// PollInterval between checks for overdrawn wallets, zero disables the worker
+	PollInterval time.Duration
+	// BatchSize limits wallets charged by one check
+	BatchSize int
+	// InterestRate is the yearly interest on overdraft, e.g. 0.2 is 20%, it is charged daily
+	InterestRate float32
+	// DailyFee is charged for every day the wallet is overdrawn
+	DailyFee float32
+}
+
+type CreditLineService struct {
+	tx       repo.Transactor
+	repo     repo.CreditLine
+	user     repo.User
+	payments *Payments
+	cfg      OverdraftConfig
+	log      logging.Logger
+}
+
+func NewCreditLineService(tx repo.Transactor, repo repo.CreditLine, user repo.User, payments *Payments,
+	cfg OverdraftConfig, log logging.Logger) *CreditLineService {
+	return &CreditLineService{
+		tx:       tx,
+		repo:     repo,
+		user:     user,
+		payments: payments,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+func (s *CreditLineService) SetCreditLimit(input models.CreditLimitInput) (models.CreditLine, error) {
+	if err := input.Validate(); err != nil {
+		return models.CreditLine{}, err
+	}
+
+	return s.repo.SetCreditLimit(input)
+}
+
+func (s *CreditLineService) GetCreditLines(userId int) ([]models.CreditLine, error) {
+	return s.repo.GetCreditLines(userId)
+}
+
+func (s *CreditLineService) GetOverdraftReport(currency string) (models.OverdraftReport, error) {
+	currency, err := models.ParseCurrency(currency)
+	if err != nil {
+		return models.OverdraftReport{}, err
+	}
+
+	return s.repo.GetOverdraftReport(currency)
+}
+
+// PlaceHold reserves money of user until the hold is captured or released. Held money is counted against
+// debit limits when it is reserved, so a placed hold never fails to be captured because of them
+func (s *CreditLineService) PlaceHold(input models.HoldInput) (models.Hold, error) {
+	if err := input.Validate(); err != nil {
+		return models.Hold{}, err
+	}
+
+	var hold models.Hold
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		err := s.payments.Spend(tx, models.Spend{
+			UserId:    input.UserId,
+			Operation: models.LimitDebit,
+			Amount:    input.Amount,
+			Currency:  input.Currency,
+		})
+		if err != nil {
+			return err
+		}
+
+		hold, err = s.repo.PlaceHold(tx, input)
+		return err
+	})
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	return hold, nil
+}
+
+func (s *CreditLineService) GetHold(id int) (models.Hold, error) {
+	return s.repo.GetHold(id)
+}
+
+func (s *CreditLineService) ListHolds(userId int, status string) ([]models.Hold, error) {
+	switch status {
+	case "", models.HoldActive, models.HoldCaptured, models.HoldReleased:
+	default:
+		return nil, fmt.Errorf("unsupported status %q", status)
+	}
+
+	return s.repo.ListHolds(userId, status)
+}
+
+// CaptureHold debits the held money, it was counted against spending limits when it was held
+func (s *CreditLineService) CaptureHold(id int) (models.Hold, error) {
+	return s.closeHold(id, models.HoldCaptured, func(tx repo.Tx, hold models.Hold) error {
+		_, err := s.payments.Capture(tx, models.Input{
+			UserId:   hold.UserId,
+			Amount:   hold.Amount,
+			Currency: hold.Currency,
+		}, models.Operation{
+			Comment: fmt.Sprintf("Debit by captured hold %f%s", hold.Amount, hold.Currency),
+			LinkId:  hold.CaptureKey(),
+		})
+		return err
+	})
+}
+
+// ReleaseHold returns the held money to available balance
+func (s *CreditLineService) ReleaseHold(id int) (models.Hold, error) {
+	return s.closeHold(id, models.HoldReleased, nil)
+}
+
+// closeHold closes active hold and runs after in the same unit of work, the held money is already
+// available to after
+func (s *CreditLineService) closeHold(id int, status string, after func(tx repo.Tx, hold models.Hold) error) (models.Hold, error) {
+	var hold models.Hold
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		hold, err = s.repo.LockHold(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if hold.Status != models.HoldActive {
+			return fmt.Errorf("hold is %s", hold.Status)
+		}
+
+		now := time.Now().UTC()
+		hold.Status = status
+		hold.ClosedAt = &now
+		if err := s.repo.CloseHold(tx, hold); err != nil {
+			return err
+		}
+
+		if after == nil {
+			return nil
+		}
+
+		return after(tx, hold)
+	})
+	if err != nil {
+		return models.Hold{}, err
+	}
+
+	return hold, nil
+}
+
+// RunOverdraftCharges charges overdrawn wallets once a day until stop is closed
+func (s *CreditLineService) RunOverdraftCharges(stop <-chan struct{}) {
+	if s.cfg.PollInterval <= 0 || (s.cfg.InterestRate == 0 && s.cfg.DailyFee == 0) {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.chargeOverdrafts(time.Now().UTC()); err != nil {
+				s.log.Infof("failed to charge overdrafts: %s", err.Error())
+			}
+		}
+	}
+}
+
+// chargeOverdrafts charges wallets overdrawn at now which were not charged on its day yet
+// and returns the number of charged ones
+func (s *CreditLineService) chargeOverdrafts(now time.Time) (int, error) {
+	day := now.Truncate(24 * time.Hour)
+	wallets, err := s.repo.OverdrawnWallets(day, s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	charged := 0
+	for _, wallet := range wallets {
+		ok, err := s.charge(wallet, day)
+		if err != nil {
+			s.log.Infof("failed to charge overdraft of user %d in %s: %s", wallet.UserId, wallet.Currency, err.Error())
+			continue
+		}
+
+		if ok {
+			charged++
+		}
+	}
+
+	return charged, nil
+}
+
+// charge debits daily interest and fee of the overdrawn wallet. The debit may go beyond the credit limit,
+// the charge is saved even when it rounds to zero, so the wallet is charged once a day
+func (s *CreditLineService) charge(wallet models.Wallet, day time.Time) (bool, error) {
+	var charged bool
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		line, ok, err := s.repo.LockOverdrawnWallet(tx, wallet.UserId, wallet.Currency, day)
+		if err != nil || !ok {
+			return err
+		}
+
+		interest, err := utils.ConvertWithRate(line.Overdraft, s.cfg.InterestRate/365)
+		if err != nil {
+			return err
+		}
+
+		charge := models.OverdraftCharge{
+			UserId:   line.UserId,
+			Currency: line.Currency,
+			Date:     day,
+			Balance:  line.Balance,
+			Amount:   interest + s.cfg.DailyFee,
+		}
+
+		if charge.Amount > 0 {
+			_, err := s.user.Debit(tx, models.Input{
+				UserId:   charge.UserId,
+				Amount:   charge.Amount,
+				Currency: charge.Currency,
+			}, models.Operation{
+				Comment:  fmt.Sprintf("Overdraft charge %f%s", charge.Amount, charge.Currency),
+				LinkId:   charge.ChargeKey(),
+				Overdraw: true,
+				System:   true,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		charged = true
+		return s.repo.AddOverdraftCharge(tx, charge)
+	})
+
+	return charged, err
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryCreditLines keeps holds and charges next to wallets of memory users repository, its units of work
+// serialize the access. Holds are not checked against balances, memory wallets have no credit limits
+type memoryCreditLines struct {
+	repo.CreditLine
+	user    *repo.MemoryUserRepo
+	holds   map[int]models.Hold
+	charges map[string]models.OverdraftCharge
+	// overdrawn are wallets found by the last OverdrawnWallets, wallets of memory users repository
+	// can not be read within its units of work
+	overdrawn []models.Wallet
+}
+
+func (r *memoryCreditLines) PlaceHold(tx repo.Tx, input models.HoldInput) (models.Hold, error) {
+	hold := models.Hold{ID: len(r.holds) + 1, UserId: input.UserId, Amount: input.Amount, Currency: input.Currency,
+		Status: models.HoldActive}
+	r.holds[hold.ID] = hold
+	return hold, nil
+}
+
+func (r *memoryCreditLines) LockHold(tx repo.Tx, id int) (models.Hold, error) {
+	hold, ok := r.holds[id]
+	if !ok {
+		return models.Hold{}, models.ErrHoldNotFound
+	}
+
+	return hold, nil
+}
+
+func (r *memoryCreditLines) CloseHold(tx repo.Tx, hold models.Hold) error {
+	r.holds[hold.ID] = hold
+	return nil
+}
+
+func (r *memoryCreditLines) OverdrawnWallets(day time.Time, limit int) ([]models.Wallet, error) {
+	r.overdrawn = []models.Wallet{}
+	for _, id := range []int{1, 2} {
+		wallets, err := r.user.GetWallets(id)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, wallet := range wallets {
+			wallet.UserId = id
+			if _, ok := r.charges[r.key(wallet.UserId, wallet.Currency, day)]; !ok && wallet.Balance-wallet.Promo < 0 {
+				r.overdrawn = append(r.overdrawn, wallet)
+			}
+		}
+	}
+
+	return r.overdrawn, nil
+}
+
+func (r *memoryCreditLines) LockOverdrawnWallet(tx repo.Tx, userId int, currency string,
+	day time.Time) (models.CreditLine, bool, error) {
+	_, charged := r.charges[r.key(userId, currency, day)]
+	for _, wallet := range r.overdrawn {
+		if wallet.UserId == userId && wallet.Currency == currency && !charged {
+			return models.CreditLine{UserId: userId, Currency: currency, Balance: wallet.Balance,
+				Overdraft: wallet.Promo - wallet.Balance}, true, nil
+		}
+	}
+
+	return models.CreditLine{}, false, nil
+}
+
+func (r *memoryCreditLines) AddOverdraftCharge(tx repo.Tx, charge models.OverdraftCharge) error {
+	r.charges[r.key(charge.UserId, charge.Currency, charge.Date)] = charge
+	return nil
+}
+
+func (r *memoryCreditLines) key(userId int, currency string, day time.Time) string {
+	return models.OverdraftCharge{UserId: userId, Currency: currency, Date: day}.ChargeKey()
+}
+
+func TestCreditLineService_Holds(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1)
+	credit := &memoryCreditLines{user: user, holds: map[int]models.Hold{}}
+	s := NewCreditLineService(user, credit, user, testPayments(user), OverdraftConfig{}, logger)
+
+	users := NewUserService(user, user, user, testPayments(user), nil, logger)
+	_, err = users.TopUp(models.Input{UserId: 1, Amount: 100, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	_, err = s.PlaceHold(models.HoldInput{UserId: 1, Amount: 0})
+	assert.EqualError(t, err, "amount must be positive")
+
+	captured, err := s.PlaceHold(models.HoldInput{UserId: 1, Amount: 30, Currency: "eur"})
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", captured.Currency)
+
+	released, err := s.PlaceHold(models.HoldInput{UserId: 1, Amount: 20})
+	assert.NoError(t, err)
+
+	captured, err = s.CaptureHold(captured.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HoldCaptured, captured.Status)
+	assert.NotNil(t, captured.ClosedAt)
+
+	released, err = s.ReleaseHold(released.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.HoldReleased, released.Status)
+
+	_, err = s.CaptureHold(released.ID)
+	assert.EqualError(t, err, "hold is released")
+
+	_, err = s.ReleaseHold(3)
+	assert.ErrorIs(t, err, models.ErrHoldNotFound)
+
+	transactions, err := user.GetTransactions(1, models.Page{Page: 1, Limit: 10, Sort: "date"})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+
+	wallets, err := user.GetWallets(1)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(70), wallets[0].Balance)
+
+	_, err = s.ListHolds(1, "pending")
+	assert.EqualError(t, err, `unsupported status "pending"`)
+}
+
+func TestCreditLineService_HoldLimits(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1)
+	limits := &memoryLimits{}
+	payments := NewPayments(user, limits, &memoryFees{}, &memoryPromo{}, PaymentsConfig{})
+	credit := &memoryCreditLines{user: user, holds: map[int]models.Hold{}}
+	s := NewCreditLineService(user, credit, user, payments, OverdraftConfig{}, logger)
+
+	_, err = NewUserService(user, user, user, payments, nil, logger).TopUp(models.Input{UserId: 1, Amount: 100,
+		Currency: "EUR"})
+	assert.NoError(t, err)
+
+	_, err = limits.SetLimit(models.LimitInput{UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily,
+		Amount: 50, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	hold, err := s.PlaceHold(models.HoldInput{UserId: 1, Amount: 40})
+	assert.NoError(t, err)
+
+	// the limit is used when money is held, not when it is captured
+	_, err = s.PlaceHold(models.HoldInput{UserId: 1, Amount: 20})
+	assert.ErrorIs(t, err, models.ErrLimitExceeded)
+
+	_, err = s.CaptureHold(hold.ID)
+	assert.NoError(t, err)
+
+	usage, err := limits.GetLimitUsage(1, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, float32(40), usage[0].Used)
+}
+
+func TestCreditLineService_ChargeOverdrafts(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2)
+	credit := &memoryCreditLines{user: user, charges: map[string]models.OverdraftCharge{}}
+	cfg := OverdraftConfig{BatchSize: 10, InterestRate: 0.365, DailyFee: 1}
+	s := NewCreditLineService(user, credit, user, testPayments(user), cfg, logger)
+
+	// memory wallets go below zero only by overdrawing debits
+	err = user.WithinTx(func(tx repo.Tx) error {
+		_, err := user.Debit(tx, models.Input{UserId: 1, Amount: 100, Currency: "EUR"}, models.Operation{Overdraw: true})
+		return err
+	})
+	assert.NoError(t, err)
+
+	balance := func() float32 {
+		wallets, err := user.GetWallets(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return wallets[0].Balance
+	}
+
+	now := time.Date(2023, 8, 15, 10, 0, 0, 0, time.UTC)
+	charged, err := s.chargeOverdrafts(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, charged)
+	// 0.1% of 100 and the fee
+	assert.Equal(t, float32(-101.1), balance())
+
+	day := time.Date(2023, 8, 15, 0, 0, 0, 0, time.UTC)
+	charge := credit.charges[credit.key(1, "EUR", day)]
+	assert.Equal(t, float32(-100), charge.Balance)
+	assert.Equal(t, float32(1.1), charge.Amount)
+
+	transactions, err := user.GetTransactions(1, models.Page{Page: 1, Limit: 10, Sort: "date"})
+	assert.NoError(t, err)
+	linked := 0
+	for _, transaction := range transactions {
+		if transaction.LinkId == charge.ChargeKey() {
+			linked++
+		}
+	}
+	assert.Equal(t, 1, linked)
+
+	// the wallet is charged once a day
+	charged, err = s.chargeOverdrafts(now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, charged)
+
+	charged, err = s.chargeOverdrafts(now.Add(24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, charged)
+	assert.Equal(t, float32(-102.2), balance())
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+type EscrowConfig struct {
+	// Account is the system account which holds escrowed money
+	Account int
+	// TTL is how long escrow is held unless it sets its own expiry, expired escrow is refunded
+	TTL time.Duration
+	// PollInterval between checks for expired escrows, zero disables the worker
+	PollInterval time.Duration
+	// BatchSize limits escrows refunded by one check
+	BatchSize int
+}
+
+type EscrowService struct {
+	tx       repo.Transactor
+	repo     repo.Escrow
+	user     repo.User
+	payments *Payments
+	cfg      EscrowConfig
+	log      logging.Logger
+}
+
+func NewEscrowService(tx repo.Transactor, repo repo.Escrow, user repo.User, payments *Payments, cfg EscrowConfig,
+	log logging.Logger) *EscrowService {
+	return &EscrowService{
+		tx:       tx,
+		repo:     repo,
+		user:     user,
+		payments: payments,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+// CreateEscrow moves money of the sender to the escrow account like a transfer to the recipient, with its limits
+// and fee. All transactions of the escrow are linked by its key
+func (s *EscrowService) CreateEscrow(input models.EscrowInput) (models.Escrow, error) {
+	if err := input.Validate(); err != nil {
+		return models.Escrow{}, err
+	}
+
+	now := time.Now().UTC()
+	escrow := models.Escrow{
+		SenderId:    input.SenderId,
+		RecipientId: input.RecipientId,
+		Amount:      input.Amount,
+		Currency:    input.Currency,
+		Description: input.Description,
+		Status:      models.EscrowHeld,
+		ExpiresAt:   now.Add(s.cfg.TTL),
+	}
+
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(now) {
+			return models.Escrow{}, errors.New("expiry must be in the future")
+		}
+
+		escrow.ExpiresAt = input.ExpiresAt.UTC()
+	}
+
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		escrow, err = s.repo.CreateEscrow(tx, escrow)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.payments.Escrow(tx, models.TransferInput{
+			UserId:   escrow.SenderId,
+			ToId:     escrow.RecipientId,
+			Amount:   escrow.Amount,
+			Currency: escrow.Currency,
+		}, s.cfg.Account, models.Operation{
+			Comment: fmt.Sprintf("Escrow %f%s for user %d", escrow.Amount, escrow.Currency, escrow.RecipientId),
+			LinkId:  escrow.EscrowKey(),
+		})
+		if err != nil {
+			return err
+		}
+
+		event := models.EscrowEvent{EscrowId: escrow.ID, Type: models.EscrowCreated, UserId: escrow.SenderId,
+			Status: escrow.Status, Date: now}
+		escrow.Events = []models.EscrowEvent{event}
+		return s.repo.AddEscrowEvent(tx, event)
+	})
+	if err != nil {
+		return models.Escrow{}, err
+	}
+
+	return escrow, nil
+}
+
+func (s *EscrowService) GetEscrow(id int) (models.Escrow, error) {
+	return s.repo.GetEscrow(id)
+}
+
+func (s *EscrowService) ListEscrows(userId int, status string) ([]models.Escrow, error) {
+	switch status {
+	case "", models.EscrowHeld, models.EscrowReleased, models.EscrowRefunded:
+	default:
+		return nil, fmt.Errorf("unsupported status %q", status)
+	}
+
+	return s.repo.ListEscrows(userId, status)
+}
+
+// ConfirmEscrow releases escrowed money to the recipient, only the sender confirms escrow
+func (s *EscrowService) ConfirmEscrow(id int, input models.EscrowActionInput) (models.Escrow, error) {
+	return s.act(id, models.EscrowConfirmed, input.UserId)
+}
+
+// CancelEscrow refunds escrowed money to the sender, only the recipient cancels escrow
+func (s *EscrowService) CancelEscrow(id int, input models.EscrowActionInput) (models.Escrow, error) {
+	return s.act(id, models.EscrowCancelled, input.UserId)
+}
+
+// act closes escrow by the event of the user and returns it with its history
+func (s *EscrowService) act(id int, event string, userId int) (models.Escrow, error) {
+	_, err := s.close(func(tx repo.Tx) (models.Escrow, bool, error) {
+		escrow, err := s.repo.LockEscrow(tx, id)
+		return escrow, true, err
+	}, event, userId, time.Now().UTC())
+	if err != nil {
+		return models.Escrow{}, err
+	}
+
+	return s.repo.GetEscrow(id)
+}
+
+// RunEscrowExpiry refunds expired escrows until stop is closed
+func (s *EscrowService) RunEscrowExpiry(stop <-chan struct{}) {
+	if s.cfg.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.expireEscrows(time.Now().UTC()); err != nil {
+				s.log.Infof("failed to expire escrows: %s", err.Error())
+			}
+		}
+	}
+}
+
+// expireEscrows refunds escrows which are expired at now and returns the number of refunded ones
+func (s *EscrowService) expireEscrows(now time.Time) (int, error) {
+	ids, err := s.repo.ExpiredEscrows(now, s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := s.close(func(tx repo.Tx) (models.Escrow, bool, error) {
+			return s.repo.LockExpiredEscrow(tx, id, now)
+		}, models.EscrowExpired, 0, now)
+		if err != nil {
+			s.log.Infof("failed to expire escrow %d: %s", id, err.Error())
+			continue
+		}
+
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// close changes escrow locked by lock by the event of the user in one unit of work with the move of escrowed
+// money, false is returned if lock skipped the escrow
+func (s *EscrowService) close(lock func(tx repo.Tx) (models.Escrow, bool, error), event string, userId int,
+	now time.Time) (bool, error) {
+	var closed bool
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		escrow, ok, err := lock(tx)
+		if err != nil || !ok {
+			return err
+		}
+
+		status, err := escrow.Next(event, userId)
+		if err != nil {
+			return err
+		}
+
+		to := escrow.RecipientId
+		comment := fmt.Sprintf("Escrow %f%s released by user %d", escrow.Amount, escrow.Currency, escrow.SenderId)
+		if status == models.EscrowRefunded {
+			to = escrow.SenderId
+			comment = fmt.Sprintf("Escrow %f%s refunded, %s", escrow.Amount, escrow.Currency, event)
+		}
+
+		if err := s.move(tx, escrow, s.cfg.Account, to, comment); err != nil {
+			return err
+		}
+
+		escrow.Status = status
+		escrow.ClosedAt = &now
+		closed = true
+		return s.repo.CloseEscrow(tx, escrow, models.EscrowEvent{EscrowId: escrow.ID, Type: event, UserId: userId,
+			Status: status, Date: now})
+	})
+
+	return closed, err
+}
+
+// move debits escrow amount from one account and credits it to another, both transactions are linked
+// by the escrow key
+func (s *EscrowService) move(tx repo.Tx, escrow models.Escrow, from, to int, comment string) error {
+	operation := models.Operation{Comment: comment, LinkId: escrow.EscrowKey()}
+	_, err := s.user.Debit(tx, models.Input{UserId: from, Amount: escrow.Amount, Currency: escrow.Currency},
+		operation)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.user.Credit(tx, models.Input{UserId: to, Amount: escrow.Amount, Currency: escrow.Currency},
+		operation)
+	return err
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryEscrows keeps escrows like the postgres repository does, units of work of memory users
+// repository serialize the access. Changes are not reverted when the unit of work fails
+type memoryEscrows struct {
+	escrows []models.Escrow
+	events  []models.EscrowEvent
+}
+
+func (r *memoryEscrows) CreateEscrow(tx repo.Tx, escrow models.Escrow) (models.Escrow, error) {
+	escrow.ID = len(r.escrows) + 1
+	r.escrows = append(r.escrows, escrow)
+	return escrow, nil
+}
+
+func (r *memoryEscrows) GetEscrow(id int) (models.Escrow, error) {
+	if id <= 0 || id > len(r.escrows) {
+		return models.Escrow{}, models.ErrEscrowNotFound
+	}
+
+	escrow := r.escrows[id-1]
+	for _, event := range r.events {
+		if event.EscrowId == id {
+			escrow.Events = append(escrow.Events, event)
+		}
+	}
+
+	return escrow, nil
+}
+
+func (r *memoryEscrows) ListEscrows(userId int, status string) ([]models.Escrow, error) {
+	escrows := []models.Escrow{}
+	for _, escrow := range r.escrows {
+		if (escrow.SenderId == userId || escrow.RecipientId == userId) && (status == "" || escrow.Status == status) {
+			escrows = append(escrows, escrow)
+		}
+	}
+
+	return escrows, nil
+}
+
+func (r *memoryEscrows) ExpiredEscrows(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	for _, escrow := range r.escrows {
+		if escrow.Status == models.EscrowHeld && !escrow.ExpiresAt.After(now) && len(ids) < limit {
+			ids = append(ids, escrow.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *memoryEscrows) LockEscrow(tx repo.Tx, id int) (models.Escrow, error) {
+	if id <= 0 || id > len(r.escrows) {
+		return models.Escrow{}, models.ErrEscrowNotFound
+	}
+
+	return r.escrows[id-1], nil
+}
+
+func (r *memoryEscrows) LockExpiredEscrow(tx repo.Tx, id int, now time.Time) (models.Escrow, bool, error) {
+	escrow, err := r.LockEscrow(tx, id)
+	if err != nil {
+		return models.Escrow{}, false, nil
+	}
+
+	return escrow, escrow.Status == models.EscrowHeld && !escrow.ExpiresAt.After(now), nil
+}
+
+func (r *memoryEscrows) CloseEscrow(tx repo.Tx, escrow models.Escrow, event models.EscrowEvent) error {
+	r.escrows[escrow.ID-1] = escrow
+	return r.AddEscrowEvent(tx, event)
+}
+
+func (r *memoryEscrows) AddEscrowEvent(tx repo.Tx, event models.EscrowEvent) error {
+	event.ID = len(r.events) + 1
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestEscrowService(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	const account = -1
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(account, 1, 2)
+	escrows := &memoryEscrows{}
+	s := NewEscrowService(user, escrows, user, testPayments(user),
+		EscrowConfig{Account: account, TTL: 24 * time.Hour, BatchSize: 10}, logger)
+	users := NewUserService(user, user, user, testPayments(user), nil, logger)
+
+	_, err = users.TopUp(models.Input{UserId: 1, Amount: 100, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	balances := func(t *testing.T, want map[int]float32) {
+		for id, amount := range want {
+			balance, err := users.GetBalance(id, "")
+			if assert.NoError(t, err) && assert.Len(t, balance.Wallets, 1) {
+				assert.Equal(t, amount, balance.Wallets[0].Balance, "balance of user %d", id)
+			}
+		}
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		escrow, err := s.CreateEscrow(models.EscrowInput{SenderId: 1, RecipientId: 2, Amount: 40,
+			Description: "order 17"})
+		assert.NoError(t, err)
+		assert.Equal(t, models.EscrowHeld, escrow.Status)
+		assert.Len(t, escrow.Events, 1)
+		balances(t, map[int]float32{1: 60, account: 40})
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		_, err := s.ConfirmEscrow(1, models.EscrowActionInput{UserId: 2})
+		assert.ErrorIs(t, err, models.ErrEscrowNotAllowed)
+		assert.EqualError(t, err, "escrow change is not allowed: only the sender confirms escrow")
+
+		escrow, err := s.ConfirmEscrow(1, models.EscrowActionInput{UserId: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, models.EscrowReleased, escrow.Status)
+		assert.NotNil(t, escrow.ClosedAt)
+		if assert.Len(t, escrow.Events, 2) {
+			assert.Equal(t, models.EscrowEvent{ID: 2, EscrowId: 1, Type: models.EscrowConfirmed, UserId: 1,
+				Status: models.EscrowReleased, Date: *escrow.ClosedAt}, escrow.Events[1])
+		}
+		balances(t, map[int]float32{1: 60, 2: 40, account: 0})
+
+		_, err = s.CancelEscrow(1, models.EscrowActionInput{UserId: 2})
+		assert.EqualError(t, err, "escrow change is not allowed: escrow is released")
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		escrow, err := s.CreateEscrow(models.EscrowInput{SenderId: 1, RecipientId: 2, Amount: 10})
+		assert.NoError(t, err)
+
+		_, err = s.CancelEscrow(escrow.ID, models.EscrowActionInput{UserId: 1})
+		assert.EqualError(t, err, "escrow change is not allowed: only the recipient cancels escrow")
+
+		escrow, err = s.CancelEscrow(escrow.ID, models.EscrowActionInput{UserId: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, models.EscrowRefunded, escrow.Status)
+		balances(t, map[int]float32{1: 60, 2: 40, account: 0})
+
+		_, err = s.CancelEscrow(100, models.EscrowActionInput{UserId: 2})
+		assert.ErrorIs(t, err, models.ErrEscrowNotFound)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		escrow, err := s.CreateEscrow(models.EscrowInput{SenderId: 1, RecipientId: 2, Amount: 20})
+		assert.NoError(t, err)
+		balances(t, map[int]float32{1: 40, account: 20})
+
+		expired, err := s.expireEscrows(time.Now().UTC())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, expired)
+
+		expired, err = s.expireEscrows(escrow.ExpiresAt)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, expired)
+		balances(t, map[int]float32{1: 60, account: 0})
+
+		escrow, err = s.GetEscrow(escrow.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.EscrowRefunded, escrow.Status)
+		if assert.Len(t, escrow.Events, 2) {
+			assert.Equal(t, models.EscrowExpired, escrow.Events[1].Type)
+			assert.Equal(t, 0, escrow.Events[1].UserId)
+		}
+
+		history, err := user.GetTransactions(1, models.Page{Page: 1, Limit: 2, Sort: "id desc"})
+		if assert.NoError(t, err) && assert.Len(t, history, 2) {
+			assert.Equal(t, escrow.EscrowKey(), history[0].LinkId)
+			assert.Equal(t, escrow.EscrowKey(), history[1].LinkId)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		list, err := s.ListEscrows(2, models.EscrowRefunded)
+		assert.NoError(t, err)
+		assert.Len(t, list, 2)
+
+		_, err = s.ListEscrows(2, "open")
+		assert.EqualError(t, err, `unsupported status "open"`)
+	})
+
+	t.Run("Not enough money", func(t *testing.T) {
+		_, err := s.CreateEscrow(models.EscrowInput{SenderId: 1, RecipientId: 2, Amount: 150})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+		balances(t, map[int]float32{1: 60, account: 0})
+	})
+}
+
+func TestEscrowService_TransferFee(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	const account = -1
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(account, 1, 2, 100)
+	fees := &memoryFees{}
+	payments := NewPayments(user, &memoryLimits{}, fees, &memoryPromo{},
+		PaymentsConfig{Fee: FeeConfig{RevenueAccount: 100}})
+	s := NewEscrowService(user, &memoryEscrows{}, user, payments,
+		EscrowConfig{Account: account, TTL: 24 * time.Hour, BatchSize: 10}, logger)
+
+	_, err = fees.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, Fixed: 1})
+	assert.NoError(t, err)
+
+	err = user.WithinTx(func(tx repo.Tx) error {
+		_, err := user.Credit(tx, models.Input{UserId: 1, Amount: 50, Currency: "EUR"}, models.Operation{})
+		return err
+	})
+	assert.NoError(t, err)
+
+	// an escrow confirmed straight away is charged like a transfer
+	escrow, err := s.CreateEscrow(models.EscrowInput{SenderId: 1, RecipientId: 2, Amount: 40, Currency: "EUR"})
+	assert.NoError(t, err)
+	_, err = s.ConfirmEscrow(escrow.ID, models.EscrowActionInput{UserId: 1})
+	assert.NoError(t, err)
+
+	for id, want := range map[int]float32{1: 9, 2: 40, account: 0, 100: 1} {
+		wallets, err := user.GetWallets(id)
+		assert.NoError(t, err)
+		if assert.Len(t, wallets, 1) {
+			assert.Equal(t, want, wallets[0].Balance, "balance of user %d", id)
+		}
+	}
+
+	revenue, err := user.GetTransactions(100, models.Page{Page: 1, Limit: 10, Sort: "date"})
+	assert.NoError(t, err)
+	if assert.Len(t, revenue, 1) {
+		assert.Equal(t, escrow.EscrowKey(), revenue[0].LinkId)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/rates"
+	"github.com/gavrylenkoIvan/balance-service/pkg/utils"
+)
+
+type ExchangeConfig struct {
+	// QuoteTTL is how long the quoted rate is locked
+	QuoteTTL time.Duration
+	// Spread is a part of the exchanged amount taken as a fee, e.g. 0.01 is 1%
+	Spread float32
+	// RevenueAccount is the system account which receives fees
+	RevenueAccount int
+}
+
+type ExchangeService struct {
+	tx    repo.Transactor
+	repo  repo.Exchange
+	fees  repo.Fee
+	rates rates.Provider
+	cfg   ExchangeConfig
+	log   logging.Logger
+}
+
+func NewExchangeService(tx repo.Transactor, repo repo.Exchange, fees repo.Fee, rates rates.Provider,
+	cfg ExchangeConfig, log logging.Logger) *ExchangeService {
+	return &ExchangeService{
+		tx:    tx,
+		repo:  repo,
+		fees:  fees,
+		rates: rates,
+		cfg:   cfg,
+		log:   log,
+	}
+}
+
+func (s *ExchangeService) QuoteExchange(input models.ExchangeQuoteInput) (models.ExchangeQuote, error) {
+	from, err := models.ParseCurrency(input.From)
+	if err != nil {
+		return models.ExchangeQuote{}, err
+	}
+
+	to, err := models.ParseCurrency(input.To)
+	if err != nil {
+		return models.ExchangeQuote{}, err
+	}
+
+	if from == to {
+		return models.ExchangeQuote{}, errors.New("cannot exchange currency to itself")
+	}
+
+	if input.Amount <= 0 {
+		return models.ExchangeQuote{}, errors.New("amount must be positive")
+	}
+
+	rate, err := s.rates.Rate(from, to)
+	if err != nil {
+		return models.ExchangeQuote{}, err
+	}
+
+	gross, err := utils.ConvertWithRate(input.Amount, rate)
+	if err != nil {
+		return models.ExchangeQuote{}, err
+	}
+
+	fee, err := utils.ConvertWithRate(gross, s.cfg.Spread)
+	if err != nil {
+		return models.ExchangeQuote{}, err
+	}
+
+	if gross-fee <= 0 {
+		return models.ExchangeQuote{}, errors.New("amount is too small to exchange")
+	}
+
+	// fee rules charge the exchanged amount in from currency on top of it
+	fromFee, err := quoteFee(s.fees, models.FeeQuoteInput{
+		Operation: models.FeeExchange,
+		UserId:    input.UserId,
+		Amount:    input.Amount,
+		Currency:  from,
+	})
+	if err != nil {
+		return models.ExchangeQuote{}, err
+	}
+
+	return s.repo.CreateQuote(models.ExchangeQuote{
+		UserId:    input.UserId,
+		From:      from,
+		To:        to,
+		Amount:    input.Amount,
+		Rate:      rate,
+		Fee:       fee,
+		ToAmount:  gross - fee,
+		FromFee:   fromFee.Fee,
+		ExpiresAt: time.Now().Add(s.cfg.QuoteTTL),
+	})
+}
+
+func (s *ExchangeService) ExecuteExchange(input models.ExchangeInput) (models.ExchangeResult, error) {
+	var result models.ExchangeResult
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		result, err = s.repo.Exchange(tx, input, s.cfg.RevenueAccount)
+		return err
+	})
+
+	return result, err
+}
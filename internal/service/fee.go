@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/utils"
+)
+
+type FeeConfig struct {
+	// RevenueAccount is the system account which receives transfer fees
+	RevenueAccount int
+}
+
+type FeeService struct {
+	repo repo.Fee
+	log  logging.Logger
+}
+
+func NewFeeService(repo repo.Fee, log logging.Logger) *FeeService {
+	return &FeeService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+func (s *FeeService) CreateFeeRule(input models.FeeRuleInput) (models.FeeRule, error) {
+	if err := input.Validate(); err != nil {
+		return models.FeeRule{}, err
+	}
+
+	return s.repo.CreateFeeRule(input)
+}
+
+func (s *FeeService) ListFeeRules(operation string) ([]models.FeeRule, error) {
+	switch operation {
+	case "", models.FeeTransfer, models.FeeExchange:
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", operation)
+	}
+
+	return s.repo.ListFeeRules(operation)
+}
+
+func (s *FeeService) DeleteFeeRule(id int) error {
+	return s.repo.DeleteFeeRule(id)
+}
+
+// QuoteFee returns the fee the operation would be charged now
+func (s *FeeService) QuoteFee(input models.FeeQuoteInput) (models.FeeQuote, error) {
+	if err := input.Validate(); err != nil {
+		return models.FeeQuote{}, err
+	}
+
+	return quoteFee(s.repo, input)
+}
+
+// quoteFee applies the most specific fee rule to validated input, the fee is rounded to cents
+func quoteFee(fees repo.Fee, input models.FeeQuoteInput) (models.FeeQuote, error) {
+	quote := models.FeeQuote{
+		Operation: input.Operation,
+		UserId:    input.UserId,
+		Amount:    input.Amount,
+		Currency:  input.Currency,
+		Total:     input.Amount,
+	}
+
+	rule, ok, err := fees.MatchFeeRule(input)
+	if err != nil || !ok {
+		return quote, err
+	}
+
+	fee, err := utils.ConvertWithRate(input.Amount, rule.Rate)
+	if err != nil {
+		return models.FeeQuote{}, err
+	}
+
+	fee, err = utils.ConvertWithRate(fee+rule.Fixed, 1)
+	if err != nil {
+		return models.FeeQuote{}, err
+	}
+
+	quote.RuleId = rule.ID
+	quote.Fee = fee
+	quote.Total = input.Amount + fee
+	return quote, nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryFees matches rules like the postgres repository does
+type memoryFees struct {
+	rules []models.FeeRule
+}
+
+func (r *memoryFees) CreateFeeRule(input models.FeeRuleInput) (models.FeeRule, error) {
+	rule := models.FeeRule{ID: len(r.rules) + 1, Operation: input.Operation, UserId: input.UserId,
+		Currency: input.Currency, MinAmount: input.MinAmount, MaxAmount: input.MaxAmount, Rate: input.Rate,
+		Fixed: input.Fixed}
+	r.rules = append(r.rules, rule)
+	return rule, nil
+}
+
+func (r *memoryFees) ListFeeRules(operation string) ([]models.FeeRule, error) {
+	return r.rules, nil
+}
+
+func (r *memoryFees) DeleteFeeRule(id int) error {
+	return models.ErrFeeRuleNotFound
+}
+
+func (r *memoryFees) MatchFeeRule(input models.FeeQuoteInput) (models.FeeRule, bool, error) {
+	var (
+		match models.FeeRule
+		ok    bool
+	)
+	for _, rule := range r.rules {
+		if rule.Applies(input) && (!ok || rule.MoreSpecific(match)) {
+			match, ok = rule, true
+		}
+	}
+
+	return match, ok, nil
+}
+
+func TestFeeService_QuoteFee(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	s := NewFeeService(&memoryFees{}, logger)
+	for _, input := range []models.FeeRuleInput{
+		{Operation: models.FeeTransfer, Rate: 0.01, Fixed: 0.5},
+		{Operation: models.FeeTransfer, Currency: "usd", Rate: 0.02},
+		{Operation: models.FeeTransfer, MinAmount: 1000, Rate: 0.005, Fixed: 1},
+		{Operation: models.FeeTransfer, UserId: 7, Fixed: 0.1},
+		{Operation: models.FeeExchange, Rate: 0.003},
+	} {
+		_, err := s.CreateFeeRule(input)
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		name      string
+		input     models.FeeQuoteInput
+		want      models.FeeQuote
+		wantedErr string
+	}{
+		{
+			name:  "General rule",
+			input: models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 1, Amount: 100},
+			want: models.FeeQuote{Operation: models.FeeTransfer, UserId: 1, Amount: 100, Currency: "EUR", RuleId: 1,
+				Fee: 1.5, Total: 101.5},
+		},
+		{
+			name:  "Currency rule",
+			input: models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 1, Amount: 100, Currency: "usd"},
+			want: models.FeeQuote{Operation: models.FeeTransfer, UserId: 1, Amount: 100, Currency: "USD", RuleId: 2,
+				Fee: 2, Total: 102},
+		},
+		{
+			name:  "Amount bracket",
+			input: models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 1, Amount: 2000},
+			want: models.FeeQuote{Operation: models.FeeTransfer, UserId: 1, Amount: 2000, Currency: "EUR", RuleId: 3,
+				Fee: 11, Total: 2011},
+		},
+		{
+			name:  "Client rule",
+			input: models.FeeQuoteInput{Operation: models.FeeTransfer, UserId: 7, Amount: 2000},
+			want: models.FeeQuote{Operation: models.FeeTransfer, UserId: 7, Amount: 2000, Currency: "EUR", RuleId: 4,
+				Fee: 0.1, Total: 2000.1},
+		},
+		{
+			name:  "Rounded to cents",
+			input: models.FeeQuoteInput{Operation: models.FeeExchange, UserId: 1, Amount: 10},
+			want: models.FeeQuote{Operation: models.FeeExchange, UserId: 1, Amount: 10, Currency: "EUR", RuleId: 5,
+				Fee: 0.03, Total: 10.03},
+		},
+		{
+			name:      "Unsupported operation",
+			input:     models.FeeQuoteInput{Operation: models.LimitDebit, UserId: 1, Amount: 10},
+			wantedErr: `unsupported operation "debit"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.QuoteFee(tt.input)
+			if tt.wantedErr != "" {
+				assert.EqualError(t, err, tt.wantedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUserService_TransferFee(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2, 100)
+	fees := &memoryFees{}
+	payments := NewPayments(user, &memoryLimits{}, fees, &memoryPromo{},
+		PaymentsConfig{Fee: FeeConfig{RevenueAccount: 100}})
+	s := NewUserService(user, user, user, payments, nil, logger)
+
+	_, err = NewFeeService(fees, logger).CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer,
+		Rate: 0.01, Fixed: 0.5})
+	assert.NoError(t, err)
+
+	_, err = s.TopUp(models.Input{UserId: 1, Amount: 100, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	balance, err := s.Transfer(models.TransferInput{UserId: 1, ToId: 2, Amount: 50, Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(50), balance)
+
+	// the fee does not fit into the rest of the wallet, so nothing is transferred
+	_, err = s.Transfer(models.TransferInput{UserId: 1, ToId: 2, Amount: 49, Currency: "EUR"})
+	assert.EqualError(t, err, "not enough money to perform purchase")
+
+	sender, err := s.GetBalance(1, "")
+	assert.NoError(t, err)
+	assert.Equal(t, float32(49), sender.Wallets[0].Balance)
+
+	revenue, err := s.GetBalance(100, "")
+	assert.NoError(t, err)
+	assert.Equal(t, float32(1), revenue.Wallets[0].Balance)
+
+	transactions, err := s.GetTransactions(1, models.Page{Page: 1, Limit: 10, Sort: "date"})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 3)
+
+	fee := 0
+	for _, transaction := range transactions {
+		if transaction.Operation == "Transfer fee 1.000000EUR" {
+			fee++
+		}
+	}
+	assert.Equal(t, 1, fee)
+}
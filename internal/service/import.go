@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// importChunkSize is the number of lines applied between progress updates
+const importChunkSize = 100
+
+// errImportStopped fails imports interrupted by shutdown
+var errImportStopped = errors.New("import is interrupted by shutdown")
+
+type ImportService struct {
+	repo  repo.Import
+	batch *BatchService
+	log   logging.Logger
+
+	// stop interrupts background imports, running counts them, no import starts once stopped is set
+	mu      sync.Mutex
+	stopped bool
+	stop    chan struct{}
+	running sync.WaitGroup
+}
+
+func NewImportService(repo repo.Import, batch *BatchService, log logging.Logger) *ImportService {
+	return &ImportService{
+		repo:  repo,
+		batch: batch,
+		log:   log,
+		stop:  make(chan struct{}),
+	}
+}
+
+// ValidateImport is a dry run of import, nothing is applied
+func (s *ImportService) ValidateImport(r io.Reader) (models.ImportReport, error) {
+	_, report, err := models.ParseImport(r)
+	return report, err
+}
+
+// StartImport creates import job and applies it in background until StopImports is called
+func (s *ImportService) StartImport(r io.Reader) (models.ImportJob, error) {
+	job, lines, err := s.createJob(r)
+	if err != nil {
+		return models.ImportJob{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		s.fail(job, errImportStopped)
+		return models.ImportJob{}, errImportStopped
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.apply(job, lines)
+	}()
+
+	return job, nil
+}
+
+// StopImports interrupts background imports after the chunk they apply and waits for them,
+// interrupted imports are failed, so they are not left running
+func (s *ImportService) StopImports() {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	s.running.Wait()
+}
+
+// RunImport creates import job and waits until it is applied
+func (s *ImportService) RunImport(r io.Reader) (models.ImportJob, error) {
+	job, lines, err := s.createJob(r)
+	if err != nil {
+		return models.ImportJob{}, err
+	}
+
+	return s.apply(job, lines), nil
+}
+
+func (s *ImportService) GetImportJob(id string) (models.ImportJob, error) {
+	return s.repo.GetImportJob(id)
+}
+
+// createJob validates the whole file, import is started only if every line is valid
+func (s *ImportService) createJob(r io.Reader) (models.ImportJob, []models.ImportLine, error) {
+	lines, report, err := models.ParseImport(r)
+	if err != nil {
+		return models.ImportJob{}, nil, err
+	}
+
+	if len(report.Errors) > 0 {
+		return models.ImportJob{}, nil, fmt.Errorf("%w, %d of %d lines have errors, validate it to see them",
+			models.ErrInvalidImport, len(report.Errors), report.Lines)
+	}
+
+	job, err := s.repo.CreateImportJob(models.ImportJob{
+		Status: models.ImportPending,
+		Total:  len(lines),
+		Errors: models.ImportErrors{},
+	})
+	if err != nil {
+		return models.ImportJob{}, nil, err
+	}
+
+	return job, lines, nil
+}
+
+// apply applies lines in chunks, every line is applied separately, so failed lines do not stop the import.
+// Lines with external_ref are not applied twice if the import is repeated
+func (s *ImportService) apply(job models.ImportJob, lines []models.ImportLine) models.ImportJob {
+	job.Status = models.ImportRunning
+	if err := s.repo.UpdateImportJob(job); err != nil {
+		return s.fail(job, err)
+	}
+
+	for offset := 0; offset < len(lines); offset += importChunkSize {
+		select {
+		case <-s.stop:
+			return s.fail(job, errImportStopped)
+		default:
+		}
+
+		chunk := lines[offset:min(offset+importChunkSize, len(lines))]
+
+		items := make([]models.BatchItem, 0, len(chunk))
+		for _, line := range chunk {
+			items = append(items, line.Item)
+		}
+
+		output, err := s.batch.apply(models.BatchInput{
+			Mode:  models.BatchBestEffort,
+			Items: items,
+		})
+		if err != nil {
+			return s.fail(job, err)
+		}
+
+		for _, result := range output.Results {
+			if result.Status == models.BatchItemFailed {
+				job.Errors = append(job.Errors, models.ImportLineError{Line: chunk[result.Index].Line, Error: result.Error})
+			}
+		}
+
+		job.Processed += len(chunk)
+		job.Applied += output.Applied
+		job.Failed += output.Failed
+
+		if err := s.repo.UpdateImportJob(job); err != nil {
+			return s.fail(job, err)
+		}
+
+		s.log.Infof("import %s: %d of %d lines processed", job.ID, job.Processed, job.Total)
+	}
+
+	now := time.Now()
+	job.Status = models.ImportCompleted
+	job.FinishedAt = &now
+
+	if err := s.repo.UpdateImportJob(job); err != nil {
+		return s.fail(job, err)
+	}
+
+	return job
+}
+
+// fail marks job as failed, error is saved as an error of the first unprocessed line
+func (s *ImportService) fail(job models.ImportJob, err error) models.ImportJob {
+	now := time.Now()
+	job.Status = models.ImportFailed
+	job.FinishedAt = &now
+	job.Errors = append(job.Errors, models.ImportLineError{Error: err.Error()})
+
+	if err := s.repo.UpdateImportJob(job); err != nil {
+		s.log.Infof("failed to save import %s: %s", job.ID, err.Error())
+	}
+
+	return job
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
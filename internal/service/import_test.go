@@ -0,0 +1,67 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryImports keeps the last saved state of import jobs
+type memoryImports struct {
+	jobs map[string]models.ImportJob
+}
+
+func (r *memoryImports) CreateImportJob(job models.ImportJob) (models.ImportJob, error) {
+	job.ID = "job-1"
+	r.jobs[job.ID] = job
+	return job, nil
+}
+
+func (r *memoryImports) UpdateImportJob(job models.ImportJob) error {
+	r.jobs[job.ID] = job
+	return nil
+}
+
+func (r *memoryImports) GetImportJob(id string) (models.ImportJob, error) {
+	return r.jobs[id], nil
+}
+
+func TestImportService_StopImports(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1)
+	imports := &memoryImports{jobs: map[string]models.ImportJob{}}
+	batch := NewBatchService(user, memoryBatch{keys: map[string]float32{}}, user, testPayments(user),
+		BatchConfig{MaxItems: 10}, logger)
+	s := NewImportService(imports, batch, logger)
+
+	file := "user_id,amount,type,comment,external_ref\n1,10,top-up,,ref-1\n"
+
+	t.Run("Interrupted import fails", func(t *testing.T) {
+		job, lines, err := s.createJob(strings.NewReader(file))
+		assert.NoError(t, err)
+		assert.Equal(t, "import:ref-1", lines[0].Item.IdempotencyKey)
+
+		s.StopImports()
+
+		job = s.apply(job, lines)
+		assert.Equal(t, models.ImportFailed, job.Status)
+		assert.Equal(t, models.ImportErrors{{Error: "import is interrupted by shutdown"}}, job.Errors)
+		assert.Equal(t, job, imports.jobs[job.ID])
+		assert.Equal(t, 0, job.Applied)
+	})
+
+	t.Run("No import starts after stop", func(t *testing.T) {
+		_, err := s.StartImport(strings.NewReader(file))
+		assert.EqualError(t, err, "import is interrupted by shutdown")
+		assert.Equal(t, models.ImportFailed, imports.jobs["job-1"].Status)
+	})
+}
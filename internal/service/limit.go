@@ -0,0 +1,40 @@
+package service
+
+import (
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+type LimitService struct {
+	repo repo.Limit
+	log  logging.Logger
+}
+
+func NewLimitService(repo repo.Limit, log logging.Logger) *LimitService {
+	return &LimitService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// SetLimit caps debits, transfers or both of user per operation or per rolling window, setting the limit
+// with the same operation, period, counterparty and currency again replaces its amount
+func (s *LimitService) SetLimit(input models.LimitInput) (models.Limit, error) {
+	if err := input.Validate(); err != nil {
+		return models.Limit{}, err
+	}
+
+	return s.repo.SetLimit(input)
+}
+
+// GetLimits returns limits of user with amounts used and remaining in their windows ending now
+func (s *LimitService) GetLimits(userId int) ([]models.LimitUsage, error) {
+	return s.repo.GetLimitUsage(userId, time.Now().UTC())
+}
+
+func (s *LimitService) DeleteLimit(id int) error {
+	return s.repo.DeleteLimit(id)
+}
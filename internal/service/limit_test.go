@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryLimits checks spends like the postgres repository does, units of work of memory users
+// repository serialize the access
+type memoryLimits struct {
+	limits []models.Limit
+	spends []memorySpend
+}
+
+type memorySpend struct {
+	models.Spend
+	date time.Time
+}
+
+func (r *memoryLimits) SetLimit(input models.LimitInput) (models.Limit, error) {
+	limit := models.Limit{ID: len(r.limits) + 1, UserId: input.UserId, Operation: input.Operation,
+		Period: input.Period, CounterpartyId: input.CounterpartyId, Amount: input.Amount, Currency: input.Currency}
+	r.limits = append(r.limits, limit)
+	return limit, nil
+}
+
+func (r *memoryLimits) DeleteLimit(id int) error {
+	return models.ErrLimitNotFound
+}
+
+func (r *memoryLimits) GetLimitUsage(userId int, now time.Time) ([]models.LimitUsage, error) {
+	usage := []models.LimitUsage{}
+	for _, limit := range r.limits {
+		if limit.UserId == userId {
+			used := r.used(limit, now)
+			usage = append(usage, models.LimitUsage{Limit: limit, Used: used, Remaining: limit.Amount - used})
+		}
+	}
+
+	return usage, nil
+}
+
+func (r *memoryLimits) Spend(tx repo.Tx, spend models.Spend, now time.Time) error {
+	for _, limit := range r.limits {
+		if !limit.Applies(spend) {
+			continue
+		}
+
+		used := r.used(limit, now)
+		if used+spend.Amount > limit.Amount {
+			return fmt.Errorf("%w: %s, %.2f is remaining", models.ErrLimitExceeded, limit, limit.Amount-used)
+		}
+	}
+
+	r.spends = append(r.spends, memorySpend{Spend: spend, date: now})
+	return nil
+}
+
+func (r *memoryLimits) used(limit models.Limit, now time.Time) float32 {
+	window, ok := models.LimitWindows[limit.Period]
+	if !ok {
+		return 0
+	}
+
+	var used float32
+	for _, spend := range r.spends {
+		if spend.date.After(now.Add(-window)) && limit.Applies(spend.Spend) {
+			used += spend.Amount
+		}
+	}
+
+	return used
+}
+
+func TestUserService_Limits(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2, 3)
+	limits := &memoryLimits{}
+	payments := NewPayments(user, limits, &memoryFees{}, &memoryPromo{}, PaymentsConfig{})
+	s := NewUserService(user, user, user, payments, nil, logger)
+
+	_, err = s.TopUp(models.Input{UserId: 1, Amount: 1000, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	for _, input := range []models.LimitInput{
+		{UserId: 1, Operation: models.LimitDebit, Period: models.LimitDaily, Amount: 50, Currency: "EUR"},
+		{UserId: 1, Operation: models.LimitTransfer, Period: models.LimitPerOperation, Amount: 200, Currency: "EUR"},
+		{UserId: 1, Operation: models.LimitTransfer, Period: models.LimitMonthly, CounterpartyId: 3, Amount: 100,
+			Currency: "EUR"},
+	} {
+		_, err := NewLimitService(limits, logger).SetLimit(input)
+		assert.NoError(t, err)
+	}
+
+	t.Run("Daily debits", func(t *testing.T) {
+		_, err := s.Debit(models.Input{UserId: 1, Amount: 30, Currency: "EUR"})
+		assert.NoError(t, err)
+
+		_, err = s.Debit(models.Input{UserId: 1, Amount: 30, Currency: "EUR"})
+		assert.ErrorIs(t, err, models.ErrLimitExceeded)
+		assert.EqualError(t, err, "spending limit exceeded: limit #1 of 50.00 EUR per day on debits, 20.00 is remaining")
+
+		// other currencies are not limited
+		_, err = s.Debit(models.Input{UserId: 1, Amount: 30, Currency: "USD"})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+	})
+
+	t.Run("Single transfer", func(t *testing.T) {
+		_, err := s.Transfer(models.TransferInput{UserId: 1, ToId: 2, Amount: 250, Currency: "EUR"})
+		assert.EqualError(t, err,
+			"spending limit exceeded: limit #2 of 200.00 EUR per operation on transfers, 200.00 is remaining")
+
+		_, err = s.Transfer(models.TransferInput{UserId: 1, ToId: 2, Amount: 150, Currency: "EUR"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Transfers to counterparty", func(t *testing.T) {
+		_, err := s.Transfer(models.TransferInput{UserId: 1, ToId: 3, Amount: 80, Currency: "EUR"})
+		assert.NoError(t, err)
+
+		_, err = s.Transfer(models.TransferInput{UserId: 1, ToId: 3, Amount: 30, Currency: "EUR"})
+		assert.EqualError(t, err, "spending limit exceeded: limit #3 of 100.00 EUR per month on transfers to user 3, "+
+			"20.00 is remaining")
+
+		balance, err := s.GetBalance(3, "")
+		assert.NoError(t, err)
+		assert.Equal(t, float32(80), balance.Wallets[0].Balance)
+	})
+
+	t.Run("Negative amounts", func(t *testing.T) {
+		// they would credit the user and lower usage of the limits
+		_, err := s.Debit(models.Input{UserId: 1, Amount: -30, Currency: "EUR"})
+		assert.EqualError(t, err, "amount must be positive")
+
+		_, err = s.Transfer(models.TransferInput{UserId: 1, ToId: 3, Amount: -80, Currency: "EUR"})
+		assert.EqualError(t, err, "amount must be positive")
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		usage, err := NewLimitService(limits, logger).GetLimits(1)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(30), usage[0].Used)
+		assert.Equal(t, float32(20), usage[0].Remaining)
+		assert.Equal(t, float32(0), usage[1].Used)
+		assert.Equal(t, float32(80), usage[2].Used)
+	})
+}
@@ -5,7 +5,9 @@
 package mock_service
 
 import (
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/gavrylenkoIvan/balance-service/models"
 	gomock "github.com/golang/mock/gomock"
@@ -50,10 +52,10 @@ func (mr *MockUserMockRecorder) Debit(input interface{}) *gomock.Call {
 }
 
 // GetBalance mocks base method.
-func (m *MockUser) GetBalance(id int, currency string) (float32, error) {
+func (m *MockUser) GetBalance(id int, currency string) (models.Balance, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetBalance", id, currency)
-	ret0, _ := ret[0].(float32)
+	ret0, _ := ret[0].(models.Balance)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -64,6 +66,36 @@ func (mr *MockUserMockRecorder) GetBalance(id, currency interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockUser)(nil).GetBalance), id, currency)
 }
 
+// GetBalanceAt mocks base method.
+func (m *MockUser) GetBalanceAt(id int, at time.Time, currency string) (models.Balance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceAt", id, at, currency)
+	ret0, _ := ret[0].(models.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceAt indicates an expected call of GetBalanceAt.
+func (mr *MockUserMockRecorder) GetBalanceAt(id, at, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceAt", reflect.TypeOf((*MockUser)(nil).GetBalanceAt), id, at, currency)
+}
+
+// GetBalanceHistory mocks base method.
+func (m *MockUser) GetBalanceHistory(id int, wallet string, from, to time.Time, interval, currency string) ([]models.BalancePoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceHistory", id, wallet, from, to, interval, currency)
+	ret0, _ := ret[0].([]models.BalancePoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceHistory indicates an expected call of GetBalanceHistory.
+func (mr *MockUserMockRecorder) GetBalanceHistory(id, wallet, from, to, interval, currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceHistory", reflect.TypeOf((*MockUser)(nil).GetBalanceHistory), id, wallet, from, to, interval, currency)
+}
+
 // GetTransactions mocks base method.
 func (m *MockUser) GetTransactions(id int, page models.Page) ([]models.Transaction, error) {
 	m.ctrl.T.Helper()
@@ -108,3 +140,1457 @@ func (mr *MockUserMockRecorder) Transfer(input interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockUser)(nil).Transfer), input)
 }
+
+// MockExchange is a mock of Exchange interface.
+type MockExchange struct {
+	ctrl     *gomock.Controller
+	recorder *MockExchangeMockRecorder
+}
+
+// MockExchangeMockRecorder is the mock recorder for MockExchange.
+type MockExchangeMockRecorder struct {
+	mock *MockExchange
+}
+
+// NewMockExchange creates a new mock instance.
+func NewMockExchange(ctrl *gomock.Controller) *MockExchange {
+	mock := &MockExchange{ctrl: ctrl}
+	mock.recorder = &MockExchangeMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExchange) EXPECT() *MockExchangeMockRecorder {
+	return m.recorder
+}
+
+// ExecuteExchange mocks base method.
+func (m *MockExchange) ExecuteExchange(input models.ExchangeInput) (models.ExchangeResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecuteExchange", input)
+	ret0, _ := ret[0].(models.ExchangeResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecuteExchange indicates an expected call of ExecuteExchange.
+func (mr *MockExchangeMockRecorder) ExecuteExchange(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecuteExchange", reflect.TypeOf((*MockExchange)(nil).ExecuteExchange), input)
+}
+
+// QuoteExchange mocks base method.
+func (m *MockExchange) QuoteExchange(input models.ExchangeQuoteInput) (models.ExchangeQuote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuoteExchange", input)
+	ret0, _ := ret[0].(models.ExchangeQuote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuoteExchange indicates an expected call of QuoteExchange.
+func (mr *MockExchangeMockRecorder) QuoteExchange(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuoteExchange", reflect.TypeOf((*MockExchange)(nil).QuoteExchange), input)
+}
+
+// MockBatch is a mock of Batch interface.
+type MockBatch struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchMockRecorder
+}
+
+// MockBatchMockRecorder is the mock recorder for MockBatch.
+type MockBatchMockRecorder struct {
+	mock *MockBatch
+}
+
+// NewMockBatch creates a new mock instance.
+func NewMockBatch(ctrl *gomock.Controller) *MockBatch {
+	mock := &MockBatch{ctrl: ctrl}
+	mock.recorder = &MockBatchMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatch) EXPECT() *MockBatchMockRecorder {
+	return m.recorder
+}
+
+// ApplyBatch mocks base method.
+func (m *MockBatch) ApplyBatch(input models.BatchInput) (models.BatchOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyBatch", input)
+	ret0, _ := ret[0].(models.BatchOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyBatch indicates an expected call of ApplyBatch.
+func (mr *MockBatchMockRecorder) ApplyBatch(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyBatch", reflect.TypeOf((*MockBatch)(nil).ApplyBatch), input)
+}
+
+// MockImport is a mock of Import interface.
+type MockImport struct {
+	ctrl     *gomock.Controller
+	recorder *MockImportMockRecorder
+}
+
+// MockImportMockRecorder is the mock recorder for MockImport.
+type MockImportMockRecorder struct {
+	mock *MockImport
+}
+
+// NewMockImport creates a new mock instance.
+func NewMockImport(ctrl *gomock.Controller) *MockImport {
+	mock := &MockImport{ctrl: ctrl}
+	mock.recorder = &MockImportMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImport) EXPECT() *MockImportMockRecorder {
+	return m.recorder
+}
+
+// GetImportJob mocks base method.
+func (m *MockImport) GetImportJob(id string) (models.ImportJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImportJob", id)
+	ret0, _ := ret[0].(models.ImportJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImportJob indicates an expected call of GetImportJob.
+func (mr *MockImportMockRecorder) GetImportJob(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImportJob", reflect.TypeOf((*MockImport)(nil).GetImportJob), id)
+}
+
+// RunImport mocks base method.
+func (m *MockImport) RunImport(r io.Reader) (models.ImportJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunImport", r)
+	ret0, _ := ret[0].(models.ImportJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunImport indicates an expected call of RunImport.
+func (mr *MockImportMockRecorder) RunImport(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunImport", reflect.TypeOf((*MockImport)(nil).RunImport), r)
+}
+
+// StartImport mocks base method.
+func (m *MockImport) StartImport(r io.Reader) (models.ImportJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartImport", r)
+	ret0, _ := ret[0].(models.ImportJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartImport indicates an expected call of StartImport.
+func (mr *MockImportMockRecorder) StartImport(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartImport", reflect.TypeOf((*MockImport)(nil).StartImport), r)
+}
+
+// StopImports mocks base method.
+func (m *MockImport) StopImports() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StopImports")
+}
+
+// StopImports indicates an expected call of StopImports.
+func (mr *MockImportMockRecorder) StopImports() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopImports", reflect.TypeOf((*MockImport)(nil).StopImports))
+}
+
+// ValidateImport mocks base method.
+func (m *MockImport) ValidateImport(r io.Reader) (models.ImportReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateImport", r)
+	ret0, _ := ret[0].(models.ImportReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateImport indicates an expected call of ValidateImport.
+func (mr *MockImportMockRecorder) ValidateImport(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateImport", reflect.TypeOf((*MockImport)(nil).ValidateImport), r)
+}
+
+// MockAdjustment is a mock of Adjustment interface.
+type MockAdjustment struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdjustmentMockRecorder
+}
+
+// MockAdjustmentMockRecorder is the mock recorder for MockAdjustment.
+type MockAdjustmentMockRecorder struct {
+	mock *MockAdjustment
+}
+
+// NewMockAdjustment creates a new mock instance.
+func NewMockAdjustment(ctrl *gomock.Controller) *MockAdjustment {
+	mock := &MockAdjustment{ctrl: ctrl}
+	mock.recorder = &MockAdjustmentMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdjustment) EXPECT() *MockAdjustmentMockRecorder {
+	return m.recorder
+}
+
+// ApproveAdjustment mocks base method.
+func (m *MockAdjustment) ApproveAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveAdjustment", id, review)
+	ret0, _ := ret[0].(models.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveAdjustment indicates an expected call of ApproveAdjustment.
+func (mr *MockAdjustmentMockRecorder) ApproveAdjustment(id, review interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveAdjustment", reflect.TypeOf((*MockAdjustment)(nil).ApproveAdjustment), id, review)
+}
+
+// GetAdjustment mocks base method.
+func (m *MockAdjustment) GetAdjustment(id int) (models.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdjustment", id)
+	ret0, _ := ret[0].(models.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdjustment indicates an expected call of GetAdjustment.
+func (mr *MockAdjustmentMockRecorder) GetAdjustment(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdjustment", reflect.TypeOf((*MockAdjustment)(nil).GetAdjustment), id)
+}
+
+// ListAdjustments mocks base method.
+func (m *MockAdjustment) ListAdjustments(status string) ([]models.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAdjustments", status)
+	ret0, _ := ret[0].([]models.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAdjustments indicates an expected call of ListAdjustments.
+func (mr *MockAdjustmentMockRecorder) ListAdjustments(status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAdjustments", reflect.TypeOf((*MockAdjustment)(nil).ListAdjustments), status)
+}
+
+// ProposeAdjustment mocks base method.
+func (m *MockAdjustment) ProposeAdjustment(input models.AdjustmentInput) (models.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProposeAdjustment", input)
+	ret0, _ := ret[0].(models.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProposeAdjustment indicates an expected call of ProposeAdjustment.
+func (mr *MockAdjustmentMockRecorder) ProposeAdjustment(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProposeAdjustment", reflect.TypeOf((*MockAdjustment)(nil).ProposeAdjustment), input)
+}
+
+// RejectAdjustment mocks base method.
+func (m *MockAdjustment) RejectAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectAdjustment", id, review)
+	ret0, _ := ret[0].(models.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RejectAdjustment indicates an expected call of RejectAdjustment.
+func (mr *MockAdjustmentMockRecorder) RejectAdjustment(id, review interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectAdjustment", reflect.TypeOf((*MockAdjustment)(nil).RejectAdjustment), id, review)
+}
+
+// MockAccount is a mock of Account interface.
+type MockAccount struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountMockRecorder
+}
+
+// MockAccountMockRecorder is the mock recorder for MockAccount.
+type MockAccountMockRecorder struct {
+	mock *MockAccount
+}
+
+// NewMockAccount creates a new mock instance.
+func NewMockAccount(ctrl *gomock.Controller) *MockAccount {
+	mock := &MockAccount{ctrl: ctrl}
+	mock.recorder = &MockAccountMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccount) EXPECT() *MockAccountMockRecorder {
+	return m.recorder
+}
+
+// GetAccount mocks base method.
+func (m *MockAccount) GetAccount(id int) (models.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", id)
+	ret0, _ := ret[0].(models.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockAccountMockRecorder) GetAccount(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccount)(nil).GetAccount), id)
+}
+
+// SetAccountStatus mocks base method.
+func (m *MockAccount) SetAccountStatus(input models.AccountStatusInput) (models.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAccountStatus", input)
+	ret0, _ := ret[0].(models.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetAccountStatus indicates an expected call of SetAccountStatus.
+func (mr *MockAccountMockRecorder) SetAccountStatus(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccountStatus", reflect.TypeOf((*MockAccount)(nil).SetAccountStatus), input)
+}
+
+// MockReconciliation is a mock of Reconciliation interface.
+type MockReconciliation struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconciliationMockRecorder
+}
+
+// MockReconciliationMockRecorder is the mock recorder for MockReconciliation.
+type MockReconciliationMockRecorder struct {
+	mock *MockReconciliation
+}
+
+// NewMockReconciliation creates a new mock instance.
+func NewMockReconciliation(ctrl *gomock.Controller) *MockReconciliation {
+	mock := &MockReconciliation{ctrl: ctrl}
+	mock.recorder = &MockReconciliationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciliation) EXPECT() *MockReconciliationMockRecorder {
+	return m.recorder
+}
+
+// ApproveCorrections mocks base method.
+func (m *MockReconciliation) ApproveCorrections(id int, operator string) (models.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveCorrections", id, operator)
+	ret0, _ := ret[0].(models.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproveCorrections indicates an expected call of ApproveCorrections.
+func (mr *MockReconciliationMockRecorder) ApproveCorrections(id, operator interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveCorrections", reflect.TypeOf((*MockReconciliation)(nil).ApproveCorrections), id, operator)
+}
+
+// GetLastReconciliation mocks base method.
+func (m *MockReconciliation) GetLastReconciliation() (models.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastReconciliation")
+	ret0, _ := ret[0].(models.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastReconciliation indicates an expected call of GetLastReconciliation.
+func (mr *MockReconciliationMockRecorder) GetLastReconciliation() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastReconciliation", reflect.TypeOf((*MockReconciliation)(nil).GetLastReconciliation))
+}
+
+// GetReconciliation mocks base method.
+func (m *MockReconciliation) GetReconciliation(id int) (models.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliation", id)
+	ret0, _ := ret[0].(models.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliation indicates an expected call of GetReconciliation.
+func (mr *MockReconciliationMockRecorder) GetReconciliation(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliation", reflect.TypeOf((*MockReconciliation)(nil).GetReconciliation), id)
+}
+
+// Reconcile mocks base method.
+func (m *MockReconciliation) Reconcile(operator string) (models.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconcile", operator)
+	ret0, _ := ret[0].(models.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reconcile indicates an expected call of Reconcile.
+func (mr *MockReconciliationMockRecorder) Reconcile(operator interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockReconciliation)(nil).Reconcile), operator)
+}
+
+// ScheduleReconciliation mocks base method.
+func (m *MockReconciliation) ScheduleReconciliation(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ScheduleReconciliation", stop)
+}
+
+// ScheduleReconciliation indicates an expected call of ScheduleReconciliation.
+func (mr *MockReconciliationMockRecorder) ScheduleReconciliation(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleReconciliation", reflect.TypeOf((*MockReconciliation)(nil).ScheduleReconciliation), stop)
+}
+
+// MockAudit is a mock of Audit interface.
+type MockAudit struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditMockRecorder
+}
+
+// MockAuditMockRecorder is the mock recorder for MockAudit.
+type MockAuditMockRecorder struct {
+	mock *MockAudit
+}
+
+// NewMockAudit creates a new mock instance.
+func NewMockAudit(ctrl *gomock.Controller) *MockAudit {
+	mock := &MockAudit{ctrl: ctrl}
+	mock.recorder = &MockAuditMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAudit) EXPECT() *MockAuditMockRecorder {
+	return m.recorder
+}
+
+// CreateCheckpoint mocks base method.
+func (m *MockAudit) CreateCheckpoint() (models.Checkpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCheckpoint")
+	ret0, _ := ret[0].(models.Checkpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCheckpoint indicates an expected call of CreateCheckpoint.
+func (mr *MockAuditMockRecorder) CreateCheckpoint() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCheckpoint", reflect.TypeOf((*MockAudit)(nil).CreateCheckpoint))
+}
+
+// ScheduleCheckpoints mocks base method.
+func (m *MockAudit) ScheduleCheckpoints(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ScheduleCheckpoints", stop)
+}
+
+// ScheduleCheckpoints indicates an expected call of ScheduleCheckpoints.
+func (mr *MockAuditMockRecorder) ScheduleCheckpoints(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleCheckpoints", reflect.TypeOf((*MockAudit)(nil).ScheduleCheckpoints), stop)
+}
+
+// VerifyChain mocks base method.
+func (m *MockAudit) VerifyChain() (models.ChainVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyChain")
+	ret0, _ := ret[0].(models.ChainVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyChain indicates an expected call of VerifyChain.
+func (mr *MockAuditMockRecorder) VerifyChain() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyChain", reflect.TypeOf((*MockAudit)(nil).VerifyChain))
+}
+
+// MockStream is a mock of Stream interface.
+type MockStream struct {
+	ctrl     *gomock.Controller
+	recorder *MockStreamMockRecorder
+}
+
+// MockStreamMockRecorder is the mock recorder for MockStream.
+type MockStreamMockRecorder struct {
+	mock *MockStream
+}
+
+// NewMockStream creates a new mock instance.
+func NewMockStream(ctrl *gomock.Controller) *MockStream {
+	mock := &MockStream{ctrl: ctrl}
+	mock.recorder = &MockStreamMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStream) EXPECT() *MockStreamMockRecorder {
+	return m.recorder
+}
+
+// ListenBalanceChanges mocks base method.
+func (m *MockStream) ListenBalanceChanges(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListenBalanceChanges", stop)
+}
+
+// ListenBalanceChanges indicates an expected call of ListenBalanceChanges.
+func (mr *MockStreamMockRecorder) ListenBalanceChanges(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListenBalanceChanges", reflect.TypeOf((*MockStream)(nil).ListenBalanceChanges), stop)
+}
+
+// SubscribeBalance mocks base method.
+func (m *MockStream) SubscribeBalance(userId, lastEventId int, stop <-chan struct{}) (<-chan models.BalanceEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeBalance", userId, lastEventId, stop)
+	ret0, _ := ret[0].(<-chan models.BalanceEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeBalance indicates an expected call of SubscribeBalance.
+func (mr *MockStreamMockRecorder) SubscribeBalance(userId, lastEventId, stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeBalance", reflect.TypeOf((*MockStream)(nil).SubscribeBalance), userId, lastEventId, stop)
+}
+
+// MockSchedule is a mock of Schedule interface.
+type MockSchedule struct {
+	ctrl     *gomock.Controller
+	recorder *MockScheduleMockRecorder
+}
+
+// MockScheduleMockRecorder is the mock recorder for MockSchedule.
+type MockScheduleMockRecorder struct {
+	mock *MockSchedule
+}
+
+// NewMockSchedule creates a new mock instance.
+func NewMockSchedule(ctrl *gomock.Controller) *MockSchedule {
+	mock := &MockSchedule{ctrl: ctrl}
+	mock.recorder = &MockScheduleMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchedule) EXPECT() *MockScheduleMockRecorder {
+	return m.recorder
+}
+
+// CancelSchedule mocks base method.
+func (m *MockSchedule) CancelSchedule(id int) (models.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelSchedule", id)
+	ret0, _ := ret[0].(models.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelSchedule indicates an expected call of CancelSchedule.
+func (mr *MockScheduleMockRecorder) CancelSchedule(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSchedule", reflect.TypeOf((*MockSchedule)(nil).CancelSchedule), id)
+}
+
+// CreateSchedule mocks base method.
+func (m *MockSchedule) CreateSchedule(input models.ScheduleInput) (models.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", input)
+	ret0, _ := ret[0].(models.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockScheduleMockRecorder) CreateSchedule(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockSchedule)(nil).CreateSchedule), input)
+}
+
+// GetSchedule mocks base method.
+func (m *MockSchedule) GetSchedule(id int) (models.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedule", id)
+	ret0, _ := ret[0].(models.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockScheduleMockRecorder) GetSchedule(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockSchedule)(nil).GetSchedule), id)
+}
+
+// ListSchedules mocks base method.
+func (m *MockSchedule) ListSchedules(userId int, status string) ([]models.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSchedules", userId, status)
+	ret0, _ := ret[0].([]models.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSchedules indicates an expected call of ListSchedules.
+func (mr *MockScheduleMockRecorder) ListSchedules(userId, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSchedules", reflect.TypeOf((*MockSchedule)(nil).ListSchedules), userId, status)
+}
+
+// PauseSchedule mocks base method.
+func (m *MockSchedule) PauseSchedule(id int) (models.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PauseSchedule", id)
+	ret0, _ := ret[0].(models.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PauseSchedule indicates an expected call of PauseSchedule.
+func (mr *MockScheduleMockRecorder) PauseSchedule(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseSchedule", reflect.TypeOf((*MockSchedule)(nil).PauseSchedule), id)
+}
+
+// ResumeSchedule mocks base method.
+func (m *MockSchedule) ResumeSchedule(id int) (models.Schedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResumeSchedule", id)
+	ret0, _ := ret[0].(models.Schedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResumeSchedule indicates an expected call of ResumeSchedule.
+func (mr *MockScheduleMockRecorder) ResumeSchedule(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeSchedule", reflect.TypeOf((*MockSchedule)(nil).ResumeSchedule), id)
+}
+
+// RunSchedules mocks base method.
+func (m *MockSchedule) RunSchedules(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunSchedules", stop)
+}
+
+// RunSchedules indicates an expected call of RunSchedules.
+func (mr *MockScheduleMockRecorder) RunSchedules(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunSchedules", reflect.TypeOf((*MockSchedule)(nil).RunSchedules), stop)
+}
+
+// MockSubscription is a mock of Subscription interface.
+type MockSubscription struct {
+	ctrl     *gomock.Controller
+	recorder *MockSubscriptionMockRecorder
+}
+
+// MockSubscriptionMockRecorder is the mock recorder for MockSubscription.
+type MockSubscriptionMockRecorder struct {
+	mock *MockSubscription
+}
+
+// NewMockSubscription creates a new mock instance.
+func NewMockSubscription(ctrl *gomock.Controller) *MockSubscription {
+	mock := &MockSubscription{ctrl: ctrl}
+	mock.recorder = &MockSubscriptionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSubscription) EXPECT() *MockSubscriptionMockRecorder {
+	return m.recorder
+}
+
+// CancelSubscription mocks base method.
+func (m *MockSubscription) CancelSubscription(id int, input models.CancelSubscriptionInput) (models.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelSubscription", id, input)
+	ret0, _ := ret[0].(models.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelSubscription indicates an expected call of CancelSubscription.
+func (mr *MockSubscriptionMockRecorder) CancelSubscription(id, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSubscription", reflect.TypeOf((*MockSubscription)(nil).CancelSubscription), id, input)
+}
+
+// ChangePlan mocks base method.
+func (m *MockSubscription) ChangePlan(id int, input models.PlanChangeInput) (models.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePlan", id, input)
+	ret0, _ := ret[0].(models.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePlan indicates an expected call of ChangePlan.
+func (mr *MockSubscriptionMockRecorder) ChangePlan(id, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePlan", reflect.TypeOf((*MockSubscription)(nil).ChangePlan), id, input)
+}
+
+// CreatePlan mocks base method.
+func (m *MockSubscription) CreatePlan(input models.PlanInput) (models.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePlan", input)
+	ret0, _ := ret[0].(models.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePlan indicates an expected call of CreatePlan.
+func (mr *MockSubscriptionMockRecorder) CreatePlan(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePlan", reflect.TypeOf((*MockSubscription)(nil).CreatePlan), input)
+}
+
+// GetPlan mocks base method.
+func (m *MockSubscription) GetPlan(id int) (models.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlan", id)
+	ret0, _ := ret[0].(models.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlan indicates an expected call of GetPlan.
+func (mr *MockSubscriptionMockRecorder) GetPlan(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlan", reflect.TypeOf((*MockSubscription)(nil).GetPlan), id)
+}
+
+// GetSubscription mocks base method.
+func (m *MockSubscription) GetSubscription(id int) (models.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscription", id)
+	ret0, _ := ret[0].(models.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscription indicates an expected call of GetSubscription.
+func (mr *MockSubscriptionMockRecorder) GetSubscription(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscription", reflect.TypeOf((*MockSubscription)(nil).GetSubscription), id)
+}
+
+// GetSubscriptionEvents mocks base method.
+func (m *MockSubscription) GetSubscriptionEvents(afterId, limit int) ([]models.SubscriptionEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriptionEvents", afterId, limit)
+	ret0, _ := ret[0].([]models.SubscriptionEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriptionEvents indicates an expected call of GetSubscriptionEvents.
+func (mr *MockSubscriptionMockRecorder) GetSubscriptionEvents(afterId, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriptionEvents", reflect.TypeOf((*MockSubscription)(nil).GetSubscriptionEvents), afterId, limit)
+}
+
+// ListPlans mocks base method.
+func (m *MockSubscription) ListPlans() ([]models.Plan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPlans")
+	ret0, _ := ret[0].([]models.Plan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPlans indicates an expected call of ListPlans.
+func (mr *MockSubscriptionMockRecorder) ListPlans() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPlans", reflect.TypeOf((*MockSubscription)(nil).ListPlans))
+}
+
+// ListSubscriptions mocks base method.
+func (m *MockSubscription) ListSubscriptions(userId int, status string) ([]models.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubscriptions", userId, status)
+	ret0, _ := ret[0].([]models.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubscriptions indicates an expected call of ListSubscriptions.
+func (mr *MockSubscriptionMockRecorder) ListSubscriptions(userId, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubscriptions", reflect.TypeOf((*MockSubscription)(nil).ListSubscriptions), userId, status)
+}
+
+// RunRenewals mocks base method.
+func (m *MockSubscription) RunRenewals(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunRenewals", stop)
+}
+
+// RunRenewals indicates an expected call of RunRenewals.
+func (mr *MockSubscriptionMockRecorder) RunRenewals(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunRenewals", reflect.TypeOf((*MockSubscription)(nil).RunRenewals), stop)
+}
+
+// Subscribe mocks base method.
+func (m *MockSubscription) Subscribe(input models.SubscriptionInput) (models.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", input)
+	ret0, _ := ret[0].(models.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockSubscriptionMockRecorder) Subscribe(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockSubscription)(nil).Subscribe), input)
+}
+
+// MockLimit is a mock of Limit interface.
+type MockLimit struct {
+	ctrl     *gomock.Controller
+	recorder *MockLimitMockRecorder
+}
+
+// MockLimitMockRecorder is the mock recorder for MockLimit.
+type MockLimitMockRecorder struct {
+	mock *MockLimit
+}
+
+// NewMockLimit creates a new mock instance.
+func NewMockLimit(ctrl *gomock.Controller) *MockLimit {
+	mock := &MockLimit{ctrl: ctrl}
+	mock.recorder = &MockLimitMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLimit) EXPECT() *MockLimitMockRecorder {
+	return m.recorder
+}
+
+// DeleteLimit mocks base method.
+func (m *MockLimit) DeleteLimit(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLimit", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLimit indicates an expected call of DeleteLimit.
+func (mr *MockLimitMockRecorder) DeleteLimit(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLimit", reflect.TypeOf((*MockLimit)(nil).DeleteLimit), id)
+}
+
+// GetLimits mocks base method.
+func (m *MockLimit) GetLimits(userId int) ([]models.LimitUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLimits", userId)
+	ret0, _ := ret[0].([]models.LimitUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLimits indicates an expected call of GetLimits.
+func (mr *MockLimitMockRecorder) GetLimits(userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLimits", reflect.TypeOf((*MockLimit)(nil).GetLimits), userId)
+}
+
+// SetLimit mocks base method.
+func (m *MockLimit) SetLimit(input models.LimitInput) (models.Limit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLimit", input)
+	ret0, _ := ret[0].(models.Limit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetLimit indicates an expected call of SetLimit.
+func (mr *MockLimitMockRecorder) SetLimit(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLimit", reflect.TypeOf((*MockLimit)(nil).SetLimit), input)
+}
+
+// MockCreditLine is a mock of CreditLine interface.
+type MockCreditLine struct {
+	ctrl     *gomock.Controller
+	recorder *MockCreditLineMockRecorder
+}
+
+// MockCreditLineMockRecorder is the mock recorder for MockCreditLine.
+type MockCreditLineMockRecorder struct {
+	mock *MockCreditLine
+}
+
+// NewMockCreditLine creates a new mock instance.
+func NewMockCreditLine(ctrl *gomock.Controller) *MockCreditLine {
+	mock := &MockCreditLine{ctrl: ctrl}
+	mock.recorder = &MockCreditLineMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCreditLine) EXPECT() *MockCreditLineMockRecorder {
+	return m.recorder
+}
+
+// CaptureHold mocks base method.
+func (m *MockCreditLine) CaptureHold(id int) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", id)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockCreditLineMockRecorder) CaptureHold(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockCreditLine)(nil).CaptureHold), id)
+}
+
+// GetCreditLines mocks base method.
+func (m *MockCreditLine) GetCreditLines(userId int) ([]models.CreditLine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCreditLines", userId)
+	ret0, _ := ret[0].([]models.CreditLine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCreditLines indicates an expected call of GetCreditLines.
+func (mr *MockCreditLineMockRecorder) GetCreditLines(userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCreditLines", reflect.TypeOf((*MockCreditLine)(nil).GetCreditLines), userId)
+}
+
+// GetHold mocks base method.
+func (m *MockCreditLine) GetHold(id int) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", id)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockCreditLineMockRecorder) GetHold(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockCreditLine)(nil).GetHold), id)
+}
+
+// GetOverdraftReport mocks base method.
+func (m *MockCreditLine) GetOverdraftReport(currency string) (models.OverdraftReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOverdraftReport", currency)
+	ret0, _ := ret[0].(models.OverdraftReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOverdraftReport indicates an expected call of GetOverdraftReport.
+func (mr *MockCreditLineMockRecorder) GetOverdraftReport(currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOverdraftReport", reflect.TypeOf((*MockCreditLine)(nil).GetOverdraftReport), currency)
+}
+
+// ListHolds mocks base method.
+func (m *MockCreditLine) ListHolds(userId int, status string) ([]models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListHolds", userId, status)
+	ret0, _ := ret[0].([]models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListHolds indicates an expected call of ListHolds.
+func (mr *MockCreditLineMockRecorder) ListHolds(userId, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHolds", reflect.TypeOf((*MockCreditLine)(nil).ListHolds), userId, status)
+}
+
+// PlaceHold mocks base method.
+func (m *MockCreditLine) PlaceHold(input models.HoldInput) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlaceHold", input)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlaceHold indicates an expected call of PlaceHold.
+func (mr *MockCreditLineMockRecorder) PlaceHold(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlaceHold", reflect.TypeOf((*MockCreditLine)(nil).PlaceHold), input)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockCreditLine) ReleaseHold(id int) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", id)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockCreditLineMockRecorder) ReleaseHold(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockCreditLine)(nil).ReleaseHold), id)
+}
+
+// RunOverdraftCharges mocks base method.
+func (m *MockCreditLine) RunOverdraftCharges(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunOverdraftCharges", stop)
+}
+
+// RunOverdraftCharges indicates an expected call of RunOverdraftCharges.
+func (mr *MockCreditLineMockRecorder) RunOverdraftCharges(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunOverdraftCharges", reflect.TypeOf((*MockCreditLine)(nil).RunOverdraftCharges), stop)
+}
+
+// SetCreditLimit mocks base method.
+func (m *MockCreditLine) SetCreditLimit(input models.CreditLimitInput) (models.CreditLine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCreditLimit", input)
+	ret0, _ := ret[0].(models.CreditLine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetCreditLimit indicates an expected call of SetCreditLimit.
+func (mr *MockCreditLineMockRecorder) SetCreditLimit(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCreditLimit", reflect.TypeOf((*MockCreditLine)(nil).SetCreditLimit), input)
+}
+
+// MockFee is a mock of Fee interface.
+type MockFee struct {
+	ctrl     *gomock.Controller
+	recorder *MockFeeMockRecorder
+}
+
+// MockFeeMockRecorder is the mock recorder for MockFee.
+type MockFeeMockRecorder struct {
+	mock *MockFee
+}
+
+// NewMockFee creates a new mock instance.
+func NewMockFee(ctrl *gomock.Controller) *MockFee {
+	mock := &MockFee{ctrl: ctrl}
+	mock.recorder = &MockFeeMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFee) EXPECT() *MockFeeMockRecorder {
+	return m.recorder
+}
+
+// CreateFeeRule mocks base method.
+func (m *MockFee) CreateFeeRule(input models.FeeRuleInput) (models.FeeRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFeeRule", input)
+	ret0, _ := ret[0].(models.FeeRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFeeRule indicates an expected call of CreateFeeRule.
+func (mr *MockFeeMockRecorder) CreateFeeRule(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFeeRule", reflect.TypeOf((*MockFee)(nil).CreateFeeRule), input)
+}
+
+// DeleteFeeRule mocks base method.
+func (m *MockFee) DeleteFeeRule(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFeeRule", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFeeRule indicates an expected call of DeleteFeeRule.
+func (mr *MockFeeMockRecorder) DeleteFeeRule(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFeeRule", reflect.TypeOf((*MockFee)(nil).DeleteFeeRule), id)
+}
+
+// ListFeeRules mocks base method.
+func (m *MockFee) ListFeeRules(operation string) ([]models.FeeRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFeeRules", operation)
+	ret0, _ := ret[0].([]models.FeeRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFeeRules indicates an expected call of ListFeeRules.
+func (mr *MockFeeMockRecorder) ListFeeRules(operation interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFeeRules", reflect.TypeOf((*MockFee)(nil).ListFeeRules), operation)
+}
+
+// QuoteFee mocks base method.
+func (m *MockFee) QuoteFee(input models.FeeQuoteInput) (models.FeeQuote, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuoteFee", input)
+	ret0, _ := ret[0].(models.FeeQuote)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuoteFee indicates an expected call of QuoteFee.
+func (mr *MockFeeMockRecorder) QuoteFee(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuoteFee", reflect.TypeOf((*MockFee)(nil).QuoteFee), input)
+}
+
+// MockPromo is a mock of Promo interface.
+type MockPromo struct {
+	ctrl     *gomock.Controller
+	recorder *MockPromoMockRecorder
+}
+
+// MockPromoMockRecorder is the mock recorder for MockPromo.
+type MockPromoMockRecorder struct {
+	mock *MockPromo
+}
+
+// NewMockPromo creates a new mock instance.
+func NewMockPromo(ctrl *gomock.Controller) *MockPromo {
+	mock := &MockPromo{ctrl: ctrl}
+	mock.recorder = &MockPromoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPromo) EXPECT() *MockPromoMockRecorder {
+	return m.recorder
+}
+
+// GetPromoGrant mocks base method.
+func (m *MockPromo) GetPromoGrant(id int) (models.PromoGrant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPromoGrant", id)
+	ret0, _ := ret[0].(models.PromoGrant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPromoGrant indicates an expected call of GetPromoGrant.
+func (mr *MockPromoMockRecorder) GetPromoGrant(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPromoGrant", reflect.TypeOf((*MockPromo)(nil).GetPromoGrant), id)
+}
+
+// GrantPromo mocks base method.
+func (m *MockPromo) GrantPromo(input models.PromoGrantInput) (models.PromoGrant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantPromo", input)
+	ret0, _ := ret[0].(models.PromoGrant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GrantPromo indicates an expected call of GrantPromo.
+func (mr *MockPromoMockRecorder) GrantPromo(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantPromo", reflect.TypeOf((*MockPromo)(nil).GrantPromo), input)
+}
+
+// ListPromoGrants mocks base method.
+func (m *MockPromo) ListPromoGrants(userId int, status string) ([]models.PromoGrant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPromoGrants", userId, status)
+	ret0, _ := ret[0].([]models.PromoGrant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPromoGrants indicates an expected call of ListPromoGrants.
+func (mr *MockPromoMockRecorder) ListPromoGrants(userId, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPromoGrants", reflect.TypeOf((*MockPromo)(nil).ListPromoGrants), userId, status)
+}
+
+// RunPromoExpiry mocks base method.
+func (m *MockPromo) RunPromoExpiry(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunPromoExpiry", stop)
+}
+
+// RunPromoExpiry indicates an expected call of RunPromoExpiry.
+func (mr *MockPromoMockRecorder) RunPromoExpiry(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunPromoExpiry", reflect.TypeOf((*MockPromo)(nil).RunPromoExpiry), stop)
+}
+
+// MockVoucher is a mock of Voucher interface.
+type MockVoucher struct {
+	ctrl     *gomock.Controller
+	recorder *MockVoucherMockRecorder
+}
+
+// MockVoucherMockRecorder is the mock recorder for MockVoucher.
+type MockVoucherMockRecorder struct {
+	mock *MockVoucher
+}
+
+// NewMockVoucher creates a new mock instance.
+func NewMockVoucher(ctrl *gomock.Controller) *MockVoucher {
+	mock := &MockVoucher{ctrl: ctrl}
+	mock.recorder = &MockVoucherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVoucher) EXPECT() *MockVoucherMockRecorder {
+	return m.recorder
+}
+
+// CreateVoucherBatch mocks base method.
+func (m *MockVoucher) CreateVoucherBatch(input models.VoucherBatchInput) (models.VoucherBatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVoucherBatch", input)
+	ret0, _ := ret[0].(models.VoucherBatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVoucherBatch indicates an expected call of CreateVoucherBatch.
+func (mr *MockVoucherMockRecorder) CreateVoucherBatch(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVoucherBatch", reflect.TypeOf((*MockVoucher)(nil).CreateVoucherBatch), input)
+}
+
+// GetVoucherReport mocks base method.
+func (m *MockVoucher) GetVoucherReport(currency string) (models.VoucherReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVoucherReport", currency)
+	ret0, _ := ret[0].(models.VoucherReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVoucherReport indicates an expected call of GetVoucherReport.
+func (mr *MockVoucherMockRecorder) GetVoucherReport(currency interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVoucherReport", reflect.TypeOf((*MockVoucher)(nil).GetVoucherReport), currency)
+}
+
+// RedeemVoucher mocks base method.
+func (m *MockVoucher) RedeemVoucher(input models.VoucherRedeemInput) (models.VoucherRedemption, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemVoucher", input)
+	ret0, _ := ret[0].(models.VoucherRedemption)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemVoucher indicates an expected call of RedeemVoucher.
+func (mr *MockVoucherMockRecorder) RedeemVoucher(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemVoucher", reflect.TypeOf((*MockVoucher)(nil).RedeemVoucher), input)
+}
+
+// MockEscrow is a mock of Escrow interface.
+type MockEscrow struct {
+	ctrl     *gomock.Controller
+	recorder *MockEscrowMockRecorder
+}
+
+// MockEscrowMockRecorder is the mock recorder for MockEscrow.
+type MockEscrowMockRecorder struct {
+	mock *MockEscrow
+}
+
+// NewMockEscrow creates a new mock instance.
+func NewMockEscrow(ctrl *gomock.Controller) *MockEscrow {
+	mock := &MockEscrow{ctrl: ctrl}
+	mock.recorder = &MockEscrowMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEscrow) EXPECT() *MockEscrowMockRecorder {
+	return m.recorder
+}
+
+// CancelEscrow mocks base method.
+func (m *MockEscrow) CancelEscrow(id int, input models.EscrowActionInput) (models.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelEscrow", id, input)
+	ret0, _ := ret[0].(models.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelEscrow indicates an expected call of CancelEscrow.
+func (mr *MockEscrowMockRecorder) CancelEscrow(id, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelEscrow", reflect.TypeOf((*MockEscrow)(nil).CancelEscrow), id, input)
+}
+
+// ConfirmEscrow mocks base method.
+func (m *MockEscrow) ConfirmEscrow(id int, input models.EscrowActionInput) (models.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmEscrow", id, input)
+	ret0, _ := ret[0].(models.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmEscrow indicates an expected call of ConfirmEscrow.
+func (mr *MockEscrowMockRecorder) ConfirmEscrow(id, input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmEscrow", reflect.TypeOf((*MockEscrow)(nil).ConfirmEscrow), id, input)
+}
+
+// CreateEscrow mocks base method.
+func (m *MockEscrow) CreateEscrow(input models.EscrowInput) (models.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEscrow", input)
+	ret0, _ := ret[0].(models.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEscrow indicates an expected call of CreateEscrow.
+func (mr *MockEscrowMockRecorder) CreateEscrow(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEscrow", reflect.TypeOf((*MockEscrow)(nil).CreateEscrow), input)
+}
+
+// GetEscrow mocks base method.
+func (m *MockEscrow) GetEscrow(id int) (models.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEscrow", id)
+	ret0, _ := ret[0].(models.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEscrow indicates an expected call of GetEscrow.
+func (mr *MockEscrowMockRecorder) GetEscrow(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEscrow", reflect.TypeOf((*MockEscrow)(nil).GetEscrow), id)
+}
+
+// ListEscrows mocks base method.
+func (m *MockEscrow) ListEscrows(userId int, status string) ([]models.Escrow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEscrows", userId, status)
+	ret0, _ := ret[0].([]models.Escrow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEscrows indicates an expected call of ListEscrows.
+func (mr *MockEscrowMockRecorder) ListEscrows(userId, status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEscrows", reflect.TypeOf((*MockEscrow)(nil).ListEscrows), userId, status)
+}
+
+// RunEscrowExpiry mocks base method.
+func (m *MockEscrow) RunEscrowExpiry(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunEscrowExpiry", stop)
+}
+
+// RunEscrowExpiry indicates an expected call of RunEscrowExpiry.
+func (mr *MockEscrowMockRecorder) RunEscrowExpiry(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunEscrowExpiry", reflect.TypeOf((*MockEscrow)(nil).RunEscrowExpiry), stop)
+}
+
+// MockOutbox is a mock of Outbox interface.
+type MockOutbox struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxMockRecorder
+}
+
+// MockOutboxMockRecorder is the mock recorder for MockOutbox.
+type MockOutboxMockRecorder struct {
+	mock *MockOutbox
+}
+
+// NewMockOutbox creates a new mock instance.
+func NewMockOutbox(ctrl *gomock.Controller) *MockOutbox {
+	mock := &MockOutbox{ctrl: ctrl}
+	mock.recorder = &MockOutboxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutbox) EXPECT() *MockOutboxMockRecorder {
+	return m.recorder
+}
+
+// ReplayOutbox mocks base method.
+func (m *MockOutbox) ReplayOutbox(input models.OutboxReplayInput) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayOutbox", input)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplayOutbox indicates an expected call of ReplayOutbox.
+func (mr *MockOutboxMockRecorder) ReplayOutbox(input interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayOutbox", reflect.TypeOf((*MockOutbox)(nil).ReplayOutbox), input)
+}
+
+// RunOutbox mocks base method.
+func (m *MockOutbox) RunOutbox(stop <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RunOutbox", stop)
+}
+
+// RunOutbox indicates an expected call of RunOutbox.
+func (mr *MockOutboxMockRecorder) RunOutbox(stop interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunOutbox", reflect.TypeOf((*MockOutbox)(nil).RunOutbox), stop)
+}
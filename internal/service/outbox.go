@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/webhook"
+)
+
+type OutboxConfig struct {
+	// WebhookURL receives published events, events are not published when it is empty
+	WebhookURL string
+	// PollInterval between publications and prunes of events, zero disables the worker
+	PollInterval time.Duration
+	// BatchSize limits events published at once
+	BatchSize int
+	// Retention is how long events are kept after they are created, so they may be replayed.
+	// Undelivered events are kept longer unless there is no webhook
+	Retention time.Duration
+}
+
+type OutboxService struct {
+	tx repo.Transactor
+	// publisher is nil when events are not published
+	publisher webhook.Publisher
+	repo      repo.Outbox
+	cfg       OutboxConfig
+	log       logging.Logger
+}
+
+func NewOutboxService(tx repo.Transactor, repo repo.Outbox, publisher webhook.Publisher, cfg OutboxConfig,
+	log logging.Logger) *OutboxService {
+	return &OutboxService{
+		tx:        tx,
+		publisher: publisher,
+		repo:      repo,
+		cfg:       cfg,
+		log:       log,
+	}
+}
+
+// ReplayOutbox marks published events as unpublished, so the worker publishes them again,
+// and returns the number of replayed events
+func (s *OutboxService) ReplayOutbox(input models.OutboxReplayInput) (int, error) {
+	if err := input.Validate(); err != nil {
+		return 0, err
+	}
+
+	return s.repo.ReplayOutbox(input)
+}
+
+// RunOutbox publishes and prunes events until stop is closed
+func (s *OutboxService) RunOutbox(stop <-chan struct{}) {
+	if s.cfg.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			now := time.Now().UTC()
+			_, err := s.publishEvents(now)
+			if err == nil {
+				_, err = s.repo.PruneOutbox(now.Add(-s.cfg.Retention), s.publisher == nil)
+			}
+			if err != nil {
+				if errors.Is(err, repo.ErrNotSupported) {
+					s.log.Infof("outbox events are not published: %s", err.Error())
+					return
+				}
+
+				s.log.Infof("failed to publish outbox events: %s", err.Error())
+			}
+		}
+	}
+}
+
+// publishEvents publishes batches of events until all of them are published or the publisher fails,
+// and returns the number of published events. Events are locked while they are published,
+// so workers of several instances never publish the same event
+func (s *OutboxService) publishEvents(now time.Time) (int, error) {
+	if s.publisher == nil {
+		return 0, nil
+	}
+
+	published := 0
+	for {
+		var (
+			events []models.OutboxEvent
+			failed error
+		)
+
+		err := s.tx.WithinTx(func(tx repo.Tx) error {
+			var err error
+			events, err = s.repo.LockOutboxEvents(tx, s.cfg.BatchSize)
+			if err != nil || len(events) == 0 {
+				return err
+			}
+
+			ids := make([]int64, 0, len(events))
+			for _, event := range events {
+				ids = append(ids, event.ID)
+			}
+
+			// the failure is saved, so it is visible in the events until they are published
+			if failed = s.publisher.Publish(events); failed != nil {
+				return s.repo.FailOutboxEvents(tx, ids, failed.Error())
+			}
+
+			return s.repo.MarkOutboxPublished(tx, ids, now)
+		})
+		if err != nil {
+			return published, err
+		}
+
+		if failed != nil {
+			return published, failed
+		}
+
+		published += len(events)
+		if len(events) < s.cfg.BatchSize {
+			return published, nil
+		}
+	}
+}
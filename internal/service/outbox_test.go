@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryOutbox keeps outbox events like the postgres repository does, units of work of memory users
+// repository serialize the access
+type memoryOutbox struct {
+	events []models.OutboxEvent
+}
+
+func (r *memoryOutbox) LockOutboxEvents(tx repo.Tx, limit int) ([]models.OutboxEvent, error) {
+	events := []models.OutboxEvent{}
+	for _, event := range r.events {
+		if event.PublishedAt == nil && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (r *memoryOutbox) MarkOutboxPublished(tx repo.Tx, ids []int64, at time.Time) error {
+	for _, id := range ids {
+		r.events[id-1].PublishedAt = &at
+		r.events[id-1].LastError = ""
+	}
+
+	return nil
+}
+
+func (r *memoryOutbox) FailOutboxEvents(tx repo.Tx, ids []int64, reason string) error {
+	for _, id := range ids {
+		r.events[id-1].Attempts++
+		r.events[id-1].LastError = reason
+	}
+
+	return nil
+}
+
+func (r *memoryOutbox) ReplayOutbox(input models.OutboxReplayInput) (int, error) {
+	replayed := 0
+	for i, event := range r.events {
+		if event.ID >= input.FromId && (input.ToId == 0 || event.ID <= input.ToId) &&
+			(input.Type == "" || event.Type == input.Type) && event.PublishedAt != nil {
+			r.events[i].PublishedAt = nil
+			replayed++
+		}
+	}
+
+	return replayed, nil
+}
+
+func (r *memoryOutbox) PruneOutbox(before time.Time, undelivered bool) (int, error) {
+	return 0, nil
+}
+
+// memoryPublisher records published events, it fails while err is set
+type memoryPublisher struct {
+	published []int64
+	err       error
+}
+
+func (p *memoryPublisher) Publish(events []models.OutboxEvent) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	for _, event := range events {
+		p.published = append(p.published, event.ID)
+	}
+
+	return nil
+}
+
+func TestOutboxService(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	outbox := &memoryOutbox{}
+	for id := int64(1); id <= 5; id++ {
+		outbox.events = append(outbox.events, models.OutboxEvent{ID: id, Type: models.OutboxBalanceChanged})
+	}
+
+	publisher := &memoryPublisher{err: errors.New("webhook responded with 503 Service Unavailable")}
+	s := NewOutboxService(repo.NewMemoryUserRepo(logger), outbox, publisher, OutboxConfig{BatchSize: 2}, logger)
+	now := time.Date(2023, 9, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Failed publication", func(t *testing.T) {
+		published, err := s.publishEvents(now)
+		assert.EqualError(t, err, "webhook responded with 503 Service Unavailable")
+		assert.Equal(t, 0, published)
+		assert.Equal(t, 1, outbox.events[0].Attempts)
+		assert.Equal(t, "webhook responded with 503 Service Unavailable", outbox.events[1].LastError)
+		assert.Equal(t, 0, outbox.events[2].Attempts)
+	})
+
+	t.Run("Publish", func(t *testing.T) {
+		publisher.err = nil
+
+		published, err := s.publishEvents(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, published)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5}, publisher.published)
+		assert.Equal(t, "", outbox.events[1].LastError)
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		_, err := s.ReplayOutbox(models.OutboxReplayInput{FromId: 4, ToId: 2})
+		assert.EqualError(t, err, "to id must not be less than from id")
+
+		replayed, err := s.ReplayOutbox(models.OutboxReplayInput{FromId: 4})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, replayed)
+
+		published, err := s.publishEvents(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, published)
+		assert.Equal(t, []int64{1, 2, 3, 4, 5, 4, 5}, publisher.published)
+	})
+}
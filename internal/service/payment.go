@@ -0,0 +1,190 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+)
+
+// PaymentsConfig holds settings of debits and transfers
+type PaymentsConfig struct {
+	Fee   FeeConfig
+	Promo PromoConfig
+}
+
+// Payments takes money out of wallets of users. Debits and transfers made by hand, by batches, imports,
+// schedules, subscriptions, holds and escrows all go through it, so none of them gets around spending limits
+// or transfer fees. Debits spend promo first, transfers and escrows never move promo
+type Payments struct {
+	user   repo.User
+	limits repo.Limit
+	// fees charge transfers on top of the transferred amount
+	fees repo.Fee
+	// promo pays a part of debits, it is never transferred
+	promo repo.Promo
+	cfg   PaymentsConfig
+}
+
+func NewPayments(user repo.User, limits repo.Limit, fees repo.Fee, promo repo.Promo, cfg PaymentsConfig) *Payments {
+	return &Payments{
+		user:   user,
+		limits: limits,
+		fees:   fees,
+		promo:  promo,
+		cfg:    cfg,
+	}
+}
+
+// Debit takes amount from the wallet as a part of tx, the debit is counted against debit limits of the user
+// and paid by promo first if it is configured so
+func (p *Payments) Debit(tx repo.Tx, input models.Input, operation models.Operation) (float32, error) {
+	if err := input.Validate(); err != nil {
+		return 0, err
+	}
+
+	err := p.Spend(tx, models.Spend{
+		UserId:    input.UserId,
+		Operation: models.LimitDebit,
+		Amount:    input.Amount,
+		Currency:  input.Currency,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return p.pay(tx, input, operation)
+}
+
+// Capture takes amount which was counted against debit limits when it was held, promo is spent like by Debit
+func (p *Payments) Capture(tx repo.Tx, input models.Input, operation models.Operation) (float32, error) {
+	if err := input.Validate(); err != nil {
+		return 0, err
+	}
+
+	return p.pay(tx, input, operation)
+}
+
+// pay debits the wallet, promo pays its part first
+func (p *Payments) pay(tx repo.Tx, input models.Input, operation models.Operation) (float32, error) {
+	var err error
+	operation.Promo, err = p.promo.SpendPromo(tx, input.UserId, input.Currency, input.Amount, p.cfg.Promo.SpendFirst,
+		time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return p.user.Debit(tx, input, operation)
+}
+
+// Transfer moves amount from the sender to the recipient as a part of tx and returns balances of both,
+// the transfer is counted against transfer limits of the sender and charged the transfer fee on top of amount.
+// All transactions of the transfer are linked by linkId
+func (p *Payments) Transfer(tx repo.Tx, input models.TransferInput, linkId string) (float32, float32, error) {
+	return p.move(tx, input, input.ToId, models.Operation{
+		Comment: fmt.Sprintf("Debit by transfer %f%s", input.Amount, input.Currency),
+		LinkId:  linkId,
+	}, models.Operation{
+		Comment: fmt.Sprintf("Top-up by transfer %f%s", input.Amount, input.Currency),
+		LinkId:  linkId,
+	})
+}
+
+// Escrow moves amount from the sender to the escrow account as a part of tx and returns balance of the sender.
+// It is a transfer to the recipient of the escrow held on the way, so it is counted against transfer limits
+// to the recipient and charged the transfer fee, the fee is not refunded with the escrow
+func (p *Payments) Escrow(tx repo.Tx, input models.TransferInput, account int,
+	operation models.Operation) (float32, error) {
+	sender, _, err := p.move(tx, input, account, operation, operation)
+	return sender, err
+}
+
+// move takes amount and the transfer fee from the sender and credits amount to account, the move is limited
+// as a transfer to the recipient of input. Promo is never moved, the sender must have enough real money
+func (p *Payments) move(tx repo.Tx, input models.TransferInput, account int, debit,
+	credit models.Operation) (float32, float32, error) {
+	if err := input.Validate(); err != nil {
+		return 0, 0, err
+	}
+
+	fee, err := quoteFee(p.fees, models.FeeQuoteInput{
+		Operation: models.FeeTransfer,
+		UserId:    input.UserId,
+		Amount:    input.Amount,
+		Currency:  input.Currency,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = p.Spend(tx, models.Spend{
+		UserId:         input.UserId,
+		Operation:      models.LimitTransfer,
+		CounterpartyId: input.ToId,
+		Amount:         input.Amount,
+		Currency:       input.Currency,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sender, err := p.user.Debit(tx, models.Input{
+		UserId:   input.UserId,
+		Amount:   input.Amount,
+		Currency: input.Currency,
+	}, debit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if fee.Fee > 0 {
+		sender, err = p.chargeFee(tx, fee, debit.LinkId)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	recipient, err := p.user.Credit(tx, models.Input{
+		UserId:   account,
+		Amount:   input.Amount,
+		Currency: input.Currency,
+	}, credit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return sender, recipient, nil
+}
+
+// chargeFee debits the fee from the sender as a separate transaction and credits it to the revenue account,
+// it returns balance of the sender
+func (p *Payments) chargeFee(tx repo.Tx, fee models.FeeQuote, linkId string) (float32, error) {
+	balance, err := p.user.Debit(tx, models.Input{
+		UserId:   fee.UserId,
+		Amount:   fee.Fee,
+		Currency: fee.Currency,
+	}, models.Operation{
+		Comment: fmt.Sprintf("Transfer fee %f%s", fee.Fee, fee.Currency),
+		LinkId:  linkId,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = p.user.Credit(tx, models.Input{
+		UserId:   p.cfg.Fee.RevenueAccount,
+		Amount:   fee.Fee,
+		Currency: fee.Currency,
+	}, models.Operation{
+		Comment: fmt.Sprintf("Transfer fee %f%s from user %d", fee.Fee, fee.Currency, fee.UserId),
+		LinkId:  linkId,
+	})
+	return balance, err
+}
+
+// Spend counts spend against limits of its user as a part of tx, it is used by moves of money
+// which are neither plain debits nor transfers, e.g. escrows
+func (p *Payments) Spend(tx repo.Tx, spend models.Spend) error {
+	return p.limits.Spend(tx, spend, time.Now().UTC())
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPayments makes debits and transfers of user without limits and fees
+func testPayments(user repo.User) *Payments {
+	return NewPayments(user, &memoryLimits{}, &memoryFees{}, &memoryPromo{}, PaymentsConfig{})
+}
+
+func TestPayments_MemoryStorage(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	storage := repo.NewMemoryRepo(logger, 1, 2, 3)
+	payments := NewPayments(storage.User, storage.Limit, storage.Fee, storage.Promo,
+		PaymentsConfig{Fee: FeeConfig{RevenueAccount: 3}})
+	s := NewUserService(storage.Transactor, storage.User, storage.Replica, payments, nil, logger)
+
+	_, err = s.TopUp(models.Input{UserId: 1, Amount: 30, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	// memory storage keeps limits and fee rules like postgres does
+	_, err = NewLimitService(storage.Limit, logger).SetLimit(models.LimitInput{UserId: 1,
+		Operation: models.LimitDebit, Period: models.LimitDaily, Amount: 15, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	_, err = NewFeeService(storage.Fee, logger).CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer,
+		Currency: "EUR", Fixed: 1})
+	assert.NoError(t, err)
+
+	balance, err := s.Debit(models.Input{UserId: 1, Amount: 10, Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(20), balance)
+
+	_, err = s.Debit(models.Input{UserId: 1, Amount: 10, Currency: "EUR"})
+	assert.ErrorIs(t, err, models.ErrLimitExceeded)
+
+	balance, err = s.Transfer(models.TransferInput{UserId: 1, ToId: 2, Amount: 5, Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.Equal(t, float32(5), balance)
+
+	wallets, err := storage.GetWallets(1)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(14), wallets[0].Balance)
+
+	revenue, err := storage.GetWallets(3)
+	assert.NoError(t, err)
+	assert.Equal(t, float32(1), revenue[0].Balance)
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+type PromoConfig struct {
+	// TTL is how long a grant may be spent unless it sets its own expiry
+	TTL time.Duration
+	// SpendFirst makes purchases spend promo before real money, otherwise promo pays only what real money can not
+	SpendFirst bool
+	// PollInterval between checks for expired grants, zero disables the worker
+	PollInterval time.Duration
+	// BatchSize limits grants expired by one check
+	BatchSize int
+}
+
+type PromoService struct {
+	tx   repo.Transactor
+	repo repo.Promo
+	user repo.User
+	cfg  PromoConfig
+	log  logging.Logger
+}
+
+func NewPromoService(tx repo.Transactor, repo repo.Promo, user repo.User, cfg PromoConfig,
+	log logging.Logger) *PromoService {
+	return &PromoService{
+		tx:   tx,
+		repo: repo,
+		user: user,
+		cfg:  cfg,
+		log:  log,
+	}
+}
+
+// GrantPromo credits promotional money to the promo part of the wallet, the credit is linked by the grant key
+func (s *PromoService) GrantPromo(input models.PromoGrantInput) (models.PromoGrant, error) {
+	if err := input.Validate(); err != nil {
+		return models.PromoGrant{}, err
+	}
+
+	now := time.Now().UTC()
+	grant := models.PromoGrant{
+		UserId:     input.UserId,
+		Amount:     input.Amount,
+		Remaining:  input.Amount,
+		Currency:   input.Currency,
+		CampaignId: input.CampaignId,
+		Status:     models.PromoActive,
+		ExpiresAt:  now.Add(s.cfg.TTL),
+	}
+
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(now) {
+			return models.PromoGrant{}, errors.New("expiry must be in the future")
+		}
+
+		grant.ExpiresAt = input.ExpiresAt.UTC()
+	}
+
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		grant, err = s.repo.CreateGrant(tx, grant)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.user.Credit(tx, models.Input{
+			UserId:   grant.UserId,
+			Amount:   grant.Amount,
+			Currency: grant.Currency,
+		}, models.Operation{
+			Comment: fmt.Sprintf("Promo credit %f%s by campaign %s", grant.Amount, grant.Currency, grant.CampaignId),
+			LinkId:  grant.GrantKey(),
+			Promo:   grant.Amount,
+		})
+		return err
+	})
+	if err != nil {
+		return models.PromoGrant{}, err
+	}
+
+	return grant, nil
+}
+
+func (s *PromoService) GetPromoGrant(id int) (models.PromoGrant, error) {
+	return s.repo.GetGrant(id)
+}
+
+func (s *PromoService) ListPromoGrants(userId int, status string) ([]models.PromoGrant, error) {
+	switch status {
+	case "", models.PromoActive, models.PromoExpired:
+	default:
+		return nil, fmt.Errorf("unsupported status %q", status)
+	}
+
+	return s.repo.ListGrants(userId, status)
+}
+
+// RunPromoExpiry debits the rest of expired grants until stop is closed
+func (s *PromoService) RunPromoExpiry(stop <-chan struct{}) {
+	if s.cfg.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.expireGrants(time.Now().UTC()); err != nil {
+				s.log.Infof("failed to expire promo grants: %s", err.Error())
+			}
+		}
+	}
+}
+
+// expireGrants expires grants which are due at now and returns the number of expired ones
+func (s *PromoService) expireGrants(now time.Time) (int, error) {
+	ids, err := s.repo.ExpiredGrants(now, s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := s.expire(id, now)
+		if err != nil {
+			s.log.Infof("failed to expire promo grant %d: %s", id, err.Error())
+			continue
+		}
+
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// expire debits the unspent rest of the grant from the promo part of its wallet. The debit may overdraw
+// the wallet, the promo money is taken back even when real money is held or in overdraft
+func (s *PromoService) expire(id int, now time.Time) (bool, error) {
+	var expired bool
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		grant, ok, err := s.repo.LockExpiredGrant(tx, id, now)
+		if err != nil || !ok {
+			return err
+		}
+
+		if grant.Remaining > 0 {
+			_, err := s.user.Debit(tx, models.Input{
+				UserId:   grant.UserId,
+				Amount:   grant.Remaining,
+				Currency: grant.Currency,
+			}, models.Operation{
+				Comment:  fmt.Sprintf("Promo expired %f%s of campaign %s", grant.Remaining, grant.Currency, grant.CampaignId),
+				LinkId:   grant.GrantKey(),
+				Overdraw: true,
+				Promo:    grant.Remaining,
+				System:   true,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		grant.Status = models.PromoExpired
+		grant.Remaining = 0
+		grant.ExpiredAt = &now
+		expired = true
+		return s.repo.ExpireGrant(tx, grant)
+	})
+
+	return expired, err
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryPromo spends and expires grants like the postgres repository does, units of work of memory users
+// repository serialize the access. It does not see wallets, so promo is spent only when it is spent first
+type memoryPromo struct {
+	grants []models.PromoGrant
+}
+
+func (r *memoryPromo) CreateGrant(tx repo.Tx, grant models.PromoGrant) (models.PromoGrant, error) {
+	grant.ID = len(r.grants) + 1
+	r.grants = append(r.grants, grant)
+	return grant, nil
+}
+
+func (r *memoryPromo) GetGrant(id int) (models.PromoGrant, error) {
+	if id <= 0 || id > len(r.grants) {
+		return models.PromoGrant{}, models.ErrPromoGrantNotFound
+	}
+
+	return r.grants[id-1], nil
+}
+
+func (r *memoryPromo) ListGrants(userId int, status string) ([]models.PromoGrant, error) {
+	grants := []models.PromoGrant{}
+	for _, grant := range r.grants {
+		if grant.UserId == userId && (status == "" || grant.Status == status) {
+			grants = append(grants, grant)
+		}
+	}
+
+	return grants, nil
+}
+
+func (r *memoryPromo) SpendPromo(tx repo.Tx, userId int, currency string, amount float32, first bool,
+	now time.Time) (float32, error) {
+	if !first {
+		return 0, nil
+	}
+
+	var spent float32
+	for i := range r.grants {
+		grant := &r.grants[i]
+		if grant.UserId != userId || grant.Currency != currency || grant.Status != models.PromoActive ||
+			!grant.ExpiresAt.After(now) || spent >= amount {
+			continue
+		}
+
+		part := grant.Remaining
+		if part > amount-spent {
+			part = amount - spent
+		}
+
+		grant.Remaining -= part
+		spent += part
+	}
+
+	return spent, nil
+}
+
+func (r *memoryPromo) ExpiredGrants(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	for _, grant := range r.grants {
+		if grant.Status == models.PromoActive && !grant.ExpiresAt.After(now) && len(ids) < limit {
+			ids = append(ids, grant.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *memoryPromo) LockExpiredGrant(tx repo.Tx, id int, now time.Time) (models.PromoGrant, bool, error) {
+	grant, err := r.GetGrant(id)
+	if err != nil {
+		return models.PromoGrant{}, false, nil
+	}
+
+	return grant, grant.Status == models.PromoActive && !grant.ExpiresAt.After(now), nil
+}
+
+func (r *memoryPromo) ExpireGrant(tx repo.Tx, grant models.PromoGrant) error {
+	r.grants[grant.ID-1] = grant
+	return nil
+}
+
+func TestPromoService(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2)
+	promo := &memoryPromo{}
+	cfg := PromoConfig{TTL: 24 * time.Hour, SpendFirst: true, BatchSize: 10}
+	s := NewPromoService(user, promo, user, cfg, logger)
+	payments := NewPayments(user, &memoryLimits{}, &memoryFees{}, promo, PaymentsConfig{Promo: cfg})
+	users := NewUserService(user, user, user, payments, nil, logger)
+
+	_, err = users.TopUp(models.Input{UserId: 1, Amount: 100, Currency: "EUR"})
+	assert.NoError(t, err)
+
+	wallet := func(t *testing.T) models.Wallet {
+		balance, err := users.GetBalance(1, "")
+		assert.NoError(t, err)
+		return balance.Wallets[0]
+	}
+
+	t.Run("Grant", func(t *testing.T) {
+		_, err := s.GrantPromo(models.PromoGrantInput{UserId: 1, Amount: 30, Currency: "EUR"})
+		assert.EqualError(t, err, "campaign id is required")
+
+		past := time.Now().Add(-time.Hour)
+		_, err = s.GrantPromo(models.PromoGrantInput{UserId: 1, Amount: 30, CampaignId: "spring", ExpiresAt: &past})
+		assert.EqualError(t, err, "expiry must be in the future")
+
+		grant, err := s.GrantPromo(models.PromoGrantInput{UserId: 1, Amount: 30, CampaignId: "spring"})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(30), grant.Remaining)
+		assert.Equal(t, models.PromoActive, grant.Status)
+		assert.Equal(t, models.Wallet{UserId: 1, Currency: "EUR", Balance: 130, Promo: 30}, wallet(t))
+	})
+
+	t.Run("Transfer spends only real money", func(t *testing.T) {
+		_, err := users.Transfer(models.TransferInput{UserId: 1, ToId: 2, Amount: 110, Currency: "EUR"})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+
+		_, err = users.Transfer(models.TransferInput{UserId: 1, ToId: 2, Amount: 80, Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.Equal(t, models.Wallet{UserId: 1, Currency: "EUR", Balance: 50, Promo: 30}, wallet(t))
+	})
+
+	t.Run("Purchase spends promo first", func(t *testing.T) {
+		_, err := users.Debit(models.Input{UserId: 1, Amount: 10, Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.Equal(t, models.Wallet{UserId: 1, Currency: "EUR", Balance: 40, Promo: 20}, wallet(t))
+
+		grant, err := s.GetPromoGrant(1)
+		assert.NoError(t, err)
+		assert.Equal(t, float32(20), grant.Remaining)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		expired, err := s.expireGrants(time.Now().UTC())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, expired)
+
+		expired, err = s.expireGrants(time.Now().UTC().Add(25 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, expired)
+		assert.Equal(t, models.Wallet{UserId: 1, Currency: "EUR", Balance: 20}, wallet(t))
+
+		grants, err := s.ListPromoGrants(1, models.PromoExpired)
+		assert.NoError(t, err)
+		assert.Len(t, grants, 1)
+		assert.Equal(t, float32(0), grants[0].Remaining)
+
+		// the rest was debited by the transaction linked to the grant
+		history, err := user.GetTransactions(1, models.Page{Page: 1, Limit: 1, Sort: "id desc"})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "promo-1", history[0].LinkId)
+			assert.Equal(t, float32(20), history[0].Amount)
+		}
+
+		_, err = s.ListPromoGrants(1, "spent")
+		assert.EqualError(t, err, `unsupported status "spent"`)
+	})
+}
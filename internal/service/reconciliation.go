@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// ReconciliationScheduler is the actor of scheduled reconciliations
+const ReconciliationScheduler = "scheduler"
+
+type ReconciliationConfig struct {
+	// Interval between scheduled reconciliations, zero disables them
+	Interval time.Duration
+}
+
+type ReconciliationService struct {
+	repo repo.Reconciliation
+	cfg  ReconciliationConfig
+	log  logging.Logger
+}
+
+func NewReconciliationService(repo repo.Reconciliation, cfg ReconciliationConfig, log logging.Logger) *ReconciliationService {
+	return &ReconciliationService{
+		repo: repo,
+		cfg:  cfg,
+		log:  log,
+	}
+}
+
+// Reconcile compares every wallet balance with the sum of its transactions and saves the report
+func (s *ReconciliationService) Reconcile(operator string) (models.ReconciliationReport, error) {
+	if operator == "" {
+		return models.ReconciliationReport{}, errors.New("operator is required")
+	}
+
+	report, err := s.repo.Reconcile(operator)
+	if err != nil {
+		return models.ReconciliationReport{}, err
+	}
+
+	s.log.Infof("reconciliation %d: %d of %d wallets mismatched", report.ID, len(report.Mismatches), report.Wallets)
+	return report, nil
+}
+
+func (s *ReconciliationService) GetReconciliation(id int) (models.ReconciliationReport, error) {
+	return s.repo.GetReconciliation(id)
+}
+
+func (s *ReconciliationService) GetLastReconciliation() (models.ReconciliationReport, error) {
+	return s.repo.GetLastReconciliation()
+}
+
+// ApproveCorrections writes correcting entries of the report to the transaction log
+func (s *ReconciliationService) ApproveCorrections(id int, operator string) (models.ReconciliationReport, error) {
+	if operator == "" {
+		return models.ReconciliationReport{}, errors.New("operator is required")
+	}
+
+	return s.repo.ApproveCorrections(id, operator)
+}
+
+// ScheduleReconciliation runs reconciliation every configured interval until stop is closed
+func (s *ReconciliationService) ScheduleReconciliation(stop <-chan struct{}) {
+	if s.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.Reconcile(ReconciliationScheduler); err != nil {
+				s.log.Infof("scheduled reconciliation failed: %s", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/cron"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// maxScheduleError is the length of schedule errors column
+const maxScheduleError = 255
+
+// errRunFailed rolls back the unit of work of a failed run, the failure itself is saved separately
+var errRunFailed = errors.New("schedule run failed")
+
+type ScheduleConfig struct {
+	// PollInterval between checks for due schedules, zero disables the worker
+	PollInterval time.Duration
+	// BatchSize limits schedules run by one check
+	BatchSize int
+	// MaxRetries of a failed occurrence, e.g. on insufficient funds, the occurrence is skipped after them
+	MaxRetries int
+	// RetryInterval between attempts of a failed occurrence
+	RetryInterval time.Duration
+}
+
+type ScheduleService struct {
+	tx       repo.Transactor
+	repo     repo.Schedule
+	payments *Payments
+	cfg      ScheduleConfig
+	log      logging.Logger
+}
+
+func NewScheduleService(tx repo.Transactor, repo repo.Schedule, payments *Payments, cfg ScheduleConfig,
+	log logging.Logger) *ScheduleService {
+	return &ScheduleService{
+		tx:       tx,
+		repo:     repo,
+		payments: payments,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+// CreateSchedule saves active schedule. One-off schedule runs at run_at, interval schedule runs first at run_at
+// and then every interval, cron schedule runs at every time matching the expression
+func (s *ScheduleService) CreateSchedule(input models.ScheduleInput) (models.Schedule, error) {
+	if err := input.Validate(); err != nil {
+		return models.Schedule{}, err
+	}
+
+	now := time.Now().UTC()
+	first := now
+	if input.RunAt != nil && input.RunAt.After(now) {
+		first = input.RunAt.UTC()
+	}
+
+	if input.Cron != "" {
+		expression, err := cron.Parse(input.Cron)
+		if err != nil {
+			return models.Schedule{}, err
+		}
+
+		first = expression.Next(now)
+		if first.IsZero() {
+			return models.Schedule{}, fmt.Errorf("cron expression %q never matches", input.Cron)
+		}
+	}
+
+	return s.repo.CreateSchedule(models.Schedule{
+		Type:       input.Type,
+		UserId:     input.UserId,
+		ToId:       input.ToId,
+		Amount:     input.Amount,
+		Currency:   input.Currency,
+		Comment:    input.Comment,
+		Cron:       input.Cron,
+		Interval:   input.Interval,
+		Status:     models.ScheduleActive,
+		Occurrence: first,
+		NextRunAt:  first,
+	})
+}
+
+func (s *ScheduleService) GetSchedule(id int) (models.Schedule, error) {
+	return s.repo.GetSchedule(id)
+}
+
+func (s *ScheduleService) ListSchedules(userId int, status string) ([]models.Schedule, error) {
+	switch status {
+	case "", models.ScheduleActive, models.SchedulePaused, models.ScheduleCancelled, models.ScheduleCompleted,
+		models.ScheduleFailed:
+		return s.repo.ListSchedules(userId, status)
+	}
+
+	return nil, fmt.Errorf("unsupported status %q", status)
+}
+
+// PauseSchedule stops runs of active schedule until it is resumed
+func (s *ScheduleService) PauseSchedule(id int) (models.Schedule, error) {
+	return s.change(id, func(schedule *models.Schedule) error {
+		if schedule.Status != models.ScheduleActive {
+			return fmt.Errorf("schedule is %s", schedule.Status)
+		}
+
+		schedule.Status = models.SchedulePaused
+		return nil
+	})
+}
+
+// ResumeSchedule activates paused schedule. Occurrences missed while it was paused are skipped,
+// except for one-off schedule which runs right away
+func (s *ScheduleService) ResumeSchedule(id int) (models.Schedule, error) {
+	return s.change(id, func(schedule *models.Schedule) error {
+		if schedule.Status != models.SchedulePaused {
+			return fmt.Errorf("schedule is %s", schedule.Status)
+		}
+
+		schedule.Status = models.ScheduleActive
+		now := time.Now().UTC()
+		if schedule.NextRunAt.After(now) {
+			return nil
+		}
+
+		if !schedule.Recurring() {
+			schedule.NextRunAt = now
+			return nil
+		}
+
+		s.advance(schedule, now)
+		return nil
+	})
+}
+
+// CancelSchedule stops active or paused schedule for good
+func (s *ScheduleService) CancelSchedule(id int) (models.Schedule, error) {
+	return s.change(id, func(schedule *models.Schedule) error {
+		if schedule.Status != models.ScheduleActive && schedule.Status != models.SchedulePaused {
+			return fmt.Errorf("schedule is %s", schedule.Status)
+		}
+
+		schedule.Status = models.ScheduleCancelled
+		return nil
+	})
+}
+
+// change saves schedule changed by f, it waits for the run of schedule in progress
+func (s *ScheduleService) change(id int, f func(schedule *models.Schedule) error) (models.Schedule, error) {
+	var schedule models.Schedule
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		schedule, err = s.repo.LockSchedule(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := f(&schedule); err != nil {
+			return err
+		}
+
+		return s.repo.UpdateSchedule(tx, schedule)
+	})
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	return schedule, nil
+}
+
+// RunSchedules runs due schedules every poll interval until stop is closed
+func (s *ScheduleService) RunSchedules(stop <-chan struct{}) {
+	if s.cfg.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.runDue(time.Now().UTC()); err != nil {
+				s.log.Infof("failed to run schedules: %s", err.Error())
+			}
+		}
+	}
+}
+
+// runDue runs schedules due at now and returns the number of succeeded runs
+func (s *ScheduleService) runDue(now time.Time) (int, error) {
+	ids, err := s.repo.DueSchedules(now, s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, id := range ids {
+		ok, err := s.run(id, now)
+		if err != nil {
+			s.log.Infof("failed to run schedule %d: %s", id, err.Error())
+			continue
+		}
+
+		if ok {
+			succeeded++
+		}
+	}
+
+	return succeeded, nil
+}
+
+// run executes the current occurrence of schedule and moves it to the next one. If the occurrence fails,
+// money changes are rolled back and the failure is saved in a unit of work of its own
+func (s *ScheduleService) run(id int, now time.Time) (bool, error) {
+	var (
+		succeeded bool
+		failure   error
+	)
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		schedule, ok, err := s.repo.LockDueSchedule(tx, id, now)
+		if err != nil || !ok {
+			return err
+		}
+
+		balance, err := s.execute(tx, schedule)
+		if err != nil {
+			failure = err
+			return errRunFailed
+		}
+
+		// the run is saved with the money changes, a succeeded occurrence can be saved only once
+		err = s.repo.AddRun(tx, schedule.ID, models.ScheduleRun{
+			Occurrence:   schedule.Occurrence,
+			Attempt:      schedule.Attempts + 1,
+			Status:       models.ScheduleRunSucceeded,
+			BalanceAfter: &balance,
+			Date:         now,
+		})
+		if err != nil {
+			return err
+		}
+
+		s.advance(&schedule, now)
+		succeeded = true
+		return s.repo.UpdateSchedule(tx, schedule)
+	})
+	if failure == nil {
+		return succeeded && err == nil, err
+	}
+
+	return false, s.tx.WithinTx(func(tx repo.Tx) error {
+		schedule, ok, err := s.repo.LockDueSchedule(tx, id, now)
+		if err != nil || !ok {
+			return err
+		}
+
+		schedule.Attempts++
+		schedule.LastError = truncate(failure.Error(), maxScheduleError)
+
+		err = s.repo.AddRun(tx, schedule.ID, models.ScheduleRun{
+			Occurrence: schedule.Occurrence,
+			Attempt:    schedule.Attempts,
+			Status:     models.ScheduleRunFailed,
+			Error:      schedule.LastError,
+			Date:       now,
+		})
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case schedule.Attempts <= s.cfg.MaxRetries:
+			schedule.NextRunAt = now.Add(s.cfg.RetryInterval)
+		case schedule.Recurring():
+			s.log.Infof("occurrence %s of schedule %d is skipped after %d attempts", schedule.Occurrence,
+				schedule.ID, schedule.Attempts)
+			lastError := schedule.LastError
+			s.advance(&schedule, now)
+			schedule.LastError = lastError
+		default:
+			schedule.Status = models.ScheduleFailed
+		}
+
+		return s.repo.UpdateSchedule(tx, schedule)
+	})
+}
+
+// execute moves money of the current occurrence as a part of tx and returns balance of the sender,
+// the occurrence is made like a debit or transfer made by hand
+func (s *ScheduleService) execute(tx repo.Tx, schedule models.Schedule) (float32, error) {
+	if schedule.Type == models.ScheduleDebit {
+		comment := schedule.Comment
+		if comment == "" {
+			comment = fmt.Sprintf("Debit by schedule #%d %f%s", schedule.ID, schedule.Amount, schedule.Currency)
+		}
+
+		return s.payments.Debit(tx, models.Input{
+			UserId:   schedule.UserId,
+			Amount:   schedule.Amount,
+			Currency: schedule.Currency,
+		}, models.Operation{Comment: comment, LinkId: schedule.OccurrenceKey()})
+	}
+
+	balance, _, err := s.payments.Transfer(tx, models.TransferInput{
+		UserId:   schedule.UserId,
+		ToId:     schedule.ToId,
+		Amount:   schedule.Amount,
+		Currency: schedule.Currency,
+	}, schedule.OccurrenceKey())
+	return balance, err
+}
+
+// advance moves schedule to its first occurrence after now, one-off schedule and schedule
+// without further occurrences is completed. Occurrences missed by the worker are skipped
+func (s *ScheduleService) advance(schedule *models.Schedule, now time.Time) {
+	schedule.Attempts = 0
+	schedule.LastError = ""
+
+	var next time.Time
+	switch {
+	case schedule.Cron != "":
+		expression, err := cron.Parse(schedule.Cron)
+		if err == nil {
+			next = expression.Next(now)
+		}
+	case schedule.Interval != "":
+		interval, err := time.ParseDuration(schedule.Interval)
+		if err == nil && interval > 0 {
+			next = schedule.Occurrence.Add(interval)
+			if !next.After(now) {
+				next = next.Add((now.Sub(next)/interval + 1) * interval)
+			}
+		}
+	}
+
+	if next.IsZero() {
+		schedule.Status = models.ScheduleCompleted
+		return
+	}
+
+	schedule.Occurrence = next
+	schedule.NextRunAt = next
+}
+
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+
+	return s[:length]
+}
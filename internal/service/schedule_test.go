@@ -0,0 +1,360 @@
+package service
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memorySchedules keeps schedules in a map, units of work of memory users repository
+// serialize the access, so it needs no lock of its own
+type memorySchedules struct {
+	schedules map[int]models.Schedule
+}
+
+func (r *memorySchedules) CreateSchedule(schedule models.Schedule) (models.Schedule, error) {
+	schedule.ID = len(r.schedules) + 1
+	r.schedules[schedule.ID] = schedule
+	return schedule, nil
+}
+
+func (r *memorySchedules) GetSchedule(id int) (models.Schedule, error) {
+	schedule, ok := r.schedules[id]
+	if !ok {
+		return models.Schedule{}, models.ErrScheduleNotFound
+	}
+
+	return schedule, nil
+}
+
+func (r *memorySchedules) ListSchedules(userId int, status string) ([]models.Schedule, error) {
+	schedules := []models.Schedule{}
+	for _, schedule := range r.schedules {
+		if schedule.UserId == userId && (status == "" || schedule.Status == status) {
+			schedules = append(schedules, schedule)
+		}
+	}
+
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].ID < schedules[j].ID })
+	return schedules, nil
+}
+
+func (r *memorySchedules) DueSchedules(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	for id, schedule := range r.schedules {
+		if schedule.Status == models.ScheduleActive && !schedule.NextRunAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func (r *memorySchedules) LockSchedule(tx repo.Tx, id int) (models.Schedule, error) {
+	return r.GetSchedule(id)
+}
+
+func (r *memorySchedules) LockDueSchedule(tx repo.Tx, id int, now time.Time) (models.Schedule, bool, error) {
+	schedule, ok := r.schedules[id]
+	due := ok && schedule.Status == models.ScheduleActive && !schedule.NextRunAt.After(now)
+	return schedule, due, nil
+}
+
+func (r *memorySchedules) UpdateSchedule(tx repo.Tx, schedule models.Schedule) error {
+	runs := r.schedules[schedule.ID].Runs
+	schedule.Runs = runs
+	r.schedules[schedule.ID] = schedule
+	return nil
+}
+
+func (r *memorySchedules) AddRun(tx repo.Tx, id int, run models.ScheduleRun) error {
+	schedule := r.schedules[id]
+	schedule.Runs = append(schedule.Runs, run)
+	r.schedules[id] = schedule
+	return nil
+}
+
+func TestScheduleService_RunDue(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	cfg := ScheduleConfig{BatchSize: 10, MaxRetries: 1, RetryInterval: time.Hour}
+	occurrence := time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)
+
+	newService := func(balance float32) (*ScheduleService, *repo.MemoryUserRepo, *memorySchedules) {
+		user := repo.NewMemoryUserRepo(logger)
+		user.AddUsers(1, 2)
+		if balance > 0 {
+			err := user.WithinTx(func(tx repo.Tx) error {
+				_, err := user.Credit(tx, models.Input{UserId: 1, Amount: balance, Currency: "EUR"}, models.Operation{})
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		schedules := &memorySchedules{schedules: map[int]models.Schedule{}}
+		return NewScheduleService(user, schedules, testPayments(user), cfg, logger), user, schedules
+	}
+
+	transfer := models.Schedule{
+		Type:       models.ScheduleTransfer,
+		UserId:     1,
+		ToId:       2,
+		Amount:     10,
+		Currency:   "EUR",
+		Interval:   "24h",
+		Status:     models.ScheduleActive,
+		Occurrence: occurrence,
+		NextRunAt:  occurrence,
+	}
+
+	balance := func(t *testing.T, user *repo.MemoryUserRepo, id int) float32 {
+		wallets, err := user.GetWallets(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, wallet := range wallets {
+			if wallet.Currency == "EUR" {
+				return wallet.Balance
+			}
+		}
+
+		return 0
+	}
+
+	t.Run("Transfer", func(t *testing.T) {
+		s, user, schedules := newService(25)
+		schedule, _ := schedules.CreateSchedule(transfer)
+
+		succeeded, err := s.runDue(occurrence.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, succeeded)
+		assert.Equal(t, float32(15), balance(t, user, 1))
+		assert.Equal(t, float32(10), balance(t, user, 2))
+
+		got := schedules.schedules[schedule.ID]
+		assert.Equal(t, occurrence.Add(24*time.Hour), got.Occurrence)
+		assert.Equal(t, occurrence.Add(24*time.Hour), got.NextRunAt)
+		assert.Equal(t, models.ScheduleRunSucceeded, got.Runs[0].Status)
+		assert.Equal(t, float32(15), *got.Runs[0].BalanceAfter)
+
+		transactions, err := user.GetTransactions(2, models.Page{Page: 1, Limit: 10, Sort: "date"})
+		assert.NoError(t, err)
+		assert.Equal(t, schedule.OccurrenceKey(), transactions[0].LinkId)
+
+		// the occurrence is not run again
+		succeeded, err = s.runDue(occurrence.Add(2 * time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, succeeded)
+		assert.Equal(t, float32(15), balance(t, user, 1))
+	})
+
+	t.Run("Missed occurrences are skipped", func(t *testing.T) {
+		s, _, schedules := newService(25)
+		schedule, _ := schedules.CreateSchedule(transfer)
+
+		_, err := s.runDue(occurrence.Add(50 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, occurrence.Add(72*time.Hour), schedules.schedules[schedule.ID].Occurrence)
+	})
+
+	t.Run("Retry on insufficient funds", func(t *testing.T) {
+		s, user, schedules := newService(5)
+		schedule, _ := schedules.CreateSchedule(transfer)
+		now := occurrence.Add(time.Minute)
+
+		succeeded, err := s.runDue(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, succeeded)
+		assert.Equal(t, float32(5), balance(t, user, 1))
+		assert.Equal(t, float32(0), balance(t, user, 2))
+
+		got := schedules.schedules[schedule.ID]
+		assert.Equal(t, occurrence, got.Occurrence)
+		assert.Equal(t, now.Add(time.Hour), got.NextRunAt)
+		assert.Equal(t, 1, got.Attempts)
+		assert.Equal(t, "not enough money to perform purchase", got.LastError)
+		assert.Equal(t, models.ScheduleRunFailed, got.Runs[0].Status)
+
+		// retries are exhausted, the occurrence is skipped
+		now = now.Add(time.Hour)
+		_, err = s.runDue(now)
+		assert.NoError(t, err)
+
+		got = schedules.schedules[schedule.ID]
+		assert.Equal(t, models.ScheduleActive, got.Status)
+		assert.Equal(t, occurrence.Add(24*time.Hour), got.Occurrence)
+		assert.Equal(t, 0, got.Attempts)
+		assert.Equal(t, "not enough money to perform purchase", got.LastError)
+		assert.Len(t, got.Runs, 2)
+	})
+
+	t.Run("One-off fails after retries", func(t *testing.T) {
+		s, _, schedules := newService(0)
+		oneOff := transfer
+		oneOff.Type, oneOff.ToId, oneOff.Interval = models.ScheduleDebit, 0, ""
+		schedule, _ := schedules.CreateSchedule(oneOff)
+
+		now := occurrence
+		for i := 0; i <= cfg.MaxRetries; i++ {
+			_, err := s.runDue(now)
+			assert.NoError(t, err)
+			now = now.Add(cfg.RetryInterval)
+		}
+
+		assert.Equal(t, models.ScheduleFailed, schedules.schedules[schedule.ID].Status)
+	})
+
+	t.Run("One-off completes", func(t *testing.T) {
+		s, user, schedules := newService(25)
+		oneOff := transfer
+		oneOff.Type, oneOff.ToId, oneOff.Interval = models.ScheduleDebit, 0, ""
+		schedule, _ := schedules.CreateSchedule(oneOff)
+
+		succeeded, err := s.runDue(occurrence)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, succeeded)
+		assert.Equal(t, float32(15), balance(t, user, 1))
+		assert.Equal(t, models.ScheduleCompleted, schedules.schedules[schedule.ID].Status)
+	})
+}
+
+func TestScheduleService_TransferFee(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2, 100)
+	fees := &memoryFees{}
+	payments := NewPayments(user, &memoryLimits{}, fees, &memoryPromo{},
+		PaymentsConfig{Fee: FeeConfig{RevenueAccount: 100}})
+	schedules := &memorySchedules{schedules: map[int]models.Schedule{}}
+	s := NewScheduleService(user, schedules, payments, ScheduleConfig{BatchSize: 10}, logger)
+
+	_, err = fees.CreateFeeRule(models.FeeRuleInput{Operation: models.FeeTransfer, Rate: 0.1})
+	assert.NoError(t, err)
+
+	err = user.WithinTx(func(tx repo.Tx) error {
+		_, err := user.Credit(tx, models.Input{UserId: 1, Amount: 25, Currency: "EUR"}, models.Operation{})
+		return err
+	})
+	assert.NoError(t, err)
+
+	occurrence := time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC)
+	schedule, _ := schedules.CreateSchedule(models.Schedule{Type: models.ScheduleTransfer, UserId: 1, ToId: 2,
+		Amount: 10, Currency: "EUR", Status: models.ScheduleActive, Occurrence: occurrence, NextRunAt: occurrence})
+
+	succeeded, err := s.runDue(occurrence)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, float32(14), *schedules.schedules[schedule.ID].Runs[0].BalanceAfter)
+
+	revenue, err := user.GetTransactions(100, models.Page{Page: 1, Limit: 10, Sort: "date"})
+	assert.NoError(t, err)
+	assert.Len(t, revenue, 1)
+	assert.Equal(t, float32(1), revenue[0].Amount)
+	assert.Equal(t, schedule.OccurrenceKey(), revenue[0].LinkId)
+}
+
+func TestScheduleService_Promo(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1)
+	promo := &memoryPromo{}
+	cfg := PromoConfig{TTL: 24 * time.Hour, SpendFirst: true}
+	payments := NewPayments(user, &memoryLimits{}, &memoryFees{}, promo, PaymentsConfig{Promo: cfg})
+	schedules := &memorySchedules{schedules: map[int]models.Schedule{}}
+	s := NewScheduleService(user, schedules, payments, ScheduleConfig{BatchSize: 10}, logger)
+
+	_, err = NewPromoService(user, promo, user, cfg, logger).GrantPromo(models.PromoGrantInput{UserId: 1, Amount: 4,
+		Currency: "EUR", CampaignId: "spring"})
+	assert.NoError(t, err)
+
+	err = user.WithinTx(func(tx repo.Tx) error {
+		_, err := user.Credit(tx, models.Input{UserId: 1, Amount: 25, Currency: "EUR"}, models.Operation{})
+		return err
+	})
+	assert.NoError(t, err)
+
+	occurrence := time.Now().UTC()
+	schedules.CreateSchedule(models.Schedule{Type: models.ScheduleDebit, UserId: 1, Amount: 10, Currency: "EUR",
+		Status: models.ScheduleActive, Occurrence: occurrence, NextRunAt: occurrence})
+
+	succeeded, err := s.runDue(occurrence)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, succeeded)
+
+	// promo paid 4 of 10, so only 6 of real money was spent
+	wallets, err := user.GetWallets(1)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Wallet{UserId: 1, Currency: "EUR", Balance: 19}, wallets[0])
+}
+
+func TestScheduleService_ChangeStatus(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1)
+	schedules := &memorySchedules{schedules: map[int]models.Schedule{}}
+	s := NewScheduleService(user, schedules, testPayments(user), ScheduleConfig{}, logger)
+
+	schedule, err := s.CreateSchedule(models.ScheduleInput{
+		Type:     models.ScheduleDebit,
+		UserId:   1,
+		Amount:   10,
+		Currency: "eur",
+		Cron:     "0 9 1 * *",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", schedule.Currency)
+	assert.Equal(t, 1, schedule.NextRunAt.Day())
+	assert.True(t, schedule.NextRunAt.After(time.Now()))
+
+	_, err = s.ResumeSchedule(schedule.ID)
+	assert.EqualError(t, err, "schedule is active")
+
+	paused, err := s.PauseSchedule(schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SchedulePaused, paused.Status)
+
+	list, err := s.ListSchedules(1, models.SchedulePaused)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	resumed, err := s.ResumeSchedule(schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ScheduleActive, resumed.Status)
+
+	cancelled, err := s.CancelSchedule(schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ScheduleCancelled, cancelled.Status)
+
+	_, err = s.PauseSchedule(schedule.ID)
+	assert.EqualError(t, err, "schedule is cancelled")
+
+	_, err = s.CancelSchedule(2)
+	assert.ErrorIs(t, err, models.ErrScheduleNotFound)
+
+	_, err = s.CreateSchedule(models.ScheduleInput{Type: models.ScheduleDebit, UserId: 1, Amount: 10, Cron: "0 9 31 2 *"})
+	assert.EqualError(t, err, `cron expression "0 9 31 2 *" never matches`)
+}
@@ -1,27 +1,224 @@
 package service
 
 import (
+	"io"
+	"time"
+
 	"github.com/gavrylenkoIvan/balance-service/internal/repo"
 	"github.com/gavrylenkoIvan/balance-service/models"
 	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/rates"
+	"github.com/gavrylenkoIvan/balance-service/pkg/webhook"
 )
 
 //go:generate mockgen -source=service.go -destination=mocks/mock.go
 
 type Service struct {
 	User
+	// Primary reads users data from primary database bypassing cache, it is used when client must see its own writes
+	Primary User
+	Exchange
+	Batch
+	Import
+	Adjustment
+	Account
+	Reconciliation
+	Audit
+	Stream
+	Schedule
+	Subscription
+	Limit
+	CreditLine
+	Fee
+	Promo
+	Voucher
+	Escrow
+	Outbox
+}
+
+// Config holds business settings of services
+type Config struct {
+	Exchange       ExchangeConfig
+	Batch          BatchConfig
+	Reconciliation ReconciliationConfig
+	Audit          AuditConfig
+	Stream         StreamConfig
+	Schedule       ScheduleConfig
+	Subscription   SubscriptionConfig
+	Overdraft      OverdraftConfig
+	Fee            FeeConfig
+	Promo          PromoConfig
+	Escrow         EscrowConfig
+	Outbox         OutboxConfig
 }
 
 type User interface {
-	GetBalance(id int, currency string) (float32, error)
+	GetBalance(id int, currency string) (models.Balance, error)
 	GetTransactions(id int, page models.Page) ([]models.Transaction, error)
 	TopUp(input models.Input) (float32, error)
 	Debit(input models.Input) (float32, error)
 	Transfer(input models.TransferInput) (float32, error)
+	GetBalanceAt(id int, at time.Time, currency string) (models.Balance, error)
+	GetBalanceHistory(id int, wallet string, from, to time.Time, interval, currency string) ([]models.BalancePoint, error)
+}
+
+type Exchange interface {
+	QuoteExchange(input models.ExchangeQuoteInput) (models.ExchangeQuote, error)
+	ExecuteExchange(input models.ExchangeInput) (models.ExchangeResult, error)
+}
+
+type Batch interface {
+	ApplyBatch(input models.BatchInput) (models.BatchOutput, error)
+}
+
+type Import interface {
+	ValidateImport(r io.Reader) (models.ImportReport, error)
+	StartImport(r io.Reader) (models.ImportJob, error)
+	RunImport(r io.Reader) (models.ImportJob, error)
+	GetImportJob(id string) (models.ImportJob, error)
+	// StopImports interrupts background imports and waits for them, interrupted imports are failed
+	StopImports()
+}
+
+type Adjustment interface {
+	ProposeAdjustment(input models.AdjustmentInput) (models.Adjustment, error)
+	GetAdjustment(id int) (models.Adjustment, error)
+	ListAdjustments(status string) ([]models.Adjustment, error)
+	ApproveAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error)
+	RejectAdjustment(id int, review models.AdjustmentReview) (models.Adjustment, error)
+}
+
+type Account interface {
+	GetAccount(id int) (models.Account, error)
+	SetAccountStatus(input models.AccountStatusInput) (models.Account, error)
+}
+
+type Reconciliation interface {
+	Reconcile(operator string) (models.ReconciliationReport, error)
+	GetReconciliation(id int) (models.ReconciliationReport, error)
+	GetLastReconciliation() (models.ReconciliationReport, error)
+	ApproveCorrections(id int, operator string) (models.ReconciliationReport, error)
+	ScheduleReconciliation(stop <-chan struct{})
+}
+
+type Audit interface {
+	VerifyChain() (models.ChainVerification, error)
+	CreateCheckpoint() (models.Checkpoint, error)
+	ScheduleCheckpoints(stop <-chan struct{})
+}
+
+type Stream interface {
+	SubscribeBalance(userId, lastEventId int, stop <-chan struct{}) (<-chan models.BalanceEvent, error)
+	ListenBalanceChanges(stop <-chan struct{})
+}
+
+type Schedule interface {
+	CreateSchedule(input models.ScheduleInput) (models.Schedule, error)
+	GetSchedule(id int) (models.Schedule, error)
+	ListSchedules(userId int, status string) ([]models.Schedule, error)
+	PauseSchedule(id int) (models.Schedule, error)
+	ResumeSchedule(id int) (models.Schedule, error)
+	CancelSchedule(id int) (models.Schedule, error)
+	RunSchedules(stop <-chan struct{})
+}
+
+type Subscription interface {
+	CreatePlan(input models.PlanInput) (models.Plan, error)
+	GetPlan(id int) (models.Plan, error)
+	ListPlans() ([]models.Plan, error)
+	Subscribe(input models.SubscriptionInput) (models.Subscription, error)
+	GetSubscription(id int) (models.Subscription, error)
+	ListSubscriptions(userId int, status string) ([]models.Subscription, error)
+	GetSubscriptionEvents(afterId, limit int) ([]models.SubscriptionEvent, error)
+	ChangePlan(id int, input models.PlanChangeInput) (models.Subscription, error)
+	CancelSubscription(id int, input models.CancelSubscriptionInput) (models.Subscription, error)
+	RunRenewals(stop <-chan struct{})
+}
+
+type Limit interface {
+	SetLimit(input models.LimitInput) (models.Limit, error)
+	GetLimits(userId int) ([]models.LimitUsage, error)
+	DeleteLimit(id int) error
+}
+
+type CreditLine interface {
+	SetCreditLimit(input models.CreditLimitInput) (models.CreditLine, error)
+	GetCreditLines(userId int) ([]models.CreditLine, error)
+	GetOverdraftReport(currency string) (models.OverdraftReport, error)
+	PlaceHold(input models.HoldInput) (models.Hold, error)
+	GetHold(id int) (models.Hold, error)
+	ListHolds(userId int, status string) ([]models.Hold, error)
+	CaptureHold(id int) (models.Hold, error)
+	ReleaseHold(id int) (models.Hold, error)
+	RunOverdraftCharges(stop <-chan struct{})
+}
+
+type Fee interface {
+	CreateFeeRule(input models.FeeRuleInput) (models.FeeRule, error)
+	ListFeeRules(operation string) ([]models.FeeRule, error)
+	DeleteFeeRule(id int) error
+	QuoteFee(input models.FeeQuoteInput) (models.FeeQuote, error)
+}
+
+type Promo interface {
+	GrantPromo(input models.PromoGrantInput) (models.PromoGrant, error)
+	GetPromoGrant(id int) (models.PromoGrant, error)
+	ListPromoGrants(userId int, status string) ([]models.PromoGrant, error)
+	RunPromoExpiry(stop <-chan struct{})
+}
+
+type Voucher interface {
+	CreateVoucherBatch(input models.VoucherBatchInput) (models.VoucherBatch, error)
+	RedeemVoucher(input models.VoucherRedeemInput) (models.VoucherRedemption, error)
+	GetVoucherReport(currency string) (models.VoucherReport, error)
+}
+
+type Escrow interface {
+	CreateEscrow(input models.EscrowInput) (models.Escrow, error)
+	GetEscrow(id int) (models.Escrow, error)
+	ListEscrows(userId int, status string) ([]models.Escrow, error)
+	ConfirmEscrow(id int, input models.EscrowActionInput) (models.Escrow, error)
+	CancelEscrow(id int, input models.EscrowActionInput) (models.Escrow, error)
+	RunEscrowExpiry(stop <-chan struct{})
 }
 
-func NewService(repo *repo.Repo, log logging.Logger) *Service {
+type Outbox interface {
+	ReplayOutbox(input models.OutboxReplayInput) (int, error)
+	RunOutbox(stop <-chan struct{})
+}
+
+func NewService(repo *repo.Repo, rates rates.Provider, cfg Config, log logging.Logger) *Service {
+	var publisher webhook.Publisher
+	if cfg.Outbox.WebhookURL != "" {
+		publisher = webhook.NewClient(cfg.Outbox.WebhookURL)
+	}
+
+	payments := NewPayments(repo.User, repo.Limit, repo.Fee, repo.Promo, PaymentsConfig{Fee: cfg.Fee, Promo: cfg.Promo})
+	batch := NewBatchService(repo.Transactor, repo.Batch, repo.User, payments, cfg.Batch, log)
+	user := NewUserService(repo.Transactor, repo.User, repo.Replica, payments, rates, log)
+	primary := NewUserService(repo.Transactor, repo.User, repo.Primary, payments, rates, log)
+	subscription := NewSubscriptionService(repo.Transactor, repo.Subscription, repo.User, payments, cfg.Subscription,
+		log)
+
 	return &Service{
-		User: NewUserService(repo, log),
+		User:           user,
+		Primary:        primary,
+		Exchange:       NewExchangeService(repo.Transactor, repo.Exchange, repo.Fee, rates, cfg.Exchange, log),
+		Batch:          batch,
+		Import:         NewImportService(repo.Import, batch, log),
+		Adjustment:     NewAdjustmentService(repo.Adjustment, log),
+		Account:        NewAccountService(repo.Account, log),
+		Reconciliation: NewReconciliationService(repo.Reconciliation, cfg.Reconciliation, log),
+		Audit:          NewAuditService(repo.Audit, cfg.Audit, log),
+		Stream:         NewStreamService(repo.Primary, repo.Changes, cfg.Stream, log),
+		Schedule:       NewScheduleService(repo.Transactor, repo.Schedule, payments, cfg.Schedule, log),
+		Subscription:   subscription,
+		Limit:          NewLimitService(repo.Limit, log),
+		CreditLine:     NewCreditLineService(repo.Transactor, repo.CreditLine, repo.User, payments, cfg.Overdraft, log),
+		Fee:            NewFeeService(repo.Fee, log),
+		Promo:          NewPromoService(repo.Transactor, repo.Promo, repo.User, cfg.Promo, log),
+		Voucher:        NewVoucherService(repo.Transactor, repo.Voucher, repo.User, log),
+		Escrow:         NewEscrowService(repo.Transactor, repo.Escrow, repo.User, payments, cfg.Escrow, log),
+		Outbox:         NewOutboxService(repo.Transactor, repo.Outbox, publisher, cfg.Outbox, log),
 	}
 }
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+const (
+	// streamBuffer is the number of changes a stream may lag behind before it is closed
+	streamBuffer = 64
+	// streamReplayPage is the number of transactions read at once when a stream is resumed
+	streamReplayPage = 100
+	// streamRetryInterval is the pause before listening to changes again after a failure
+	streamRetryInterval = 5 * time.Second
+)
+
+type StreamConfig struct {
+	// MaxStreams limits concurrent streams of all users, zero means unlimited
+	MaxStreams int
+	// MaxStreamsPerUser limits concurrent streams of a single user, zero means unlimited
+	MaxStreamsPerUser int
+}
+
+// subscriber gets changes of a user, changes is closed when the subscriber is removed
+type subscriber struct {
+	userId  int
+	changes chan models.Transaction
+}
+
+type StreamService struct {
+	// reader must not lag behind changes, otherwise a resumed stream could miss transactions
+	reader  repo.User
+	changes repo.Changes
+	cfg     StreamConfig
+	log     logging.Logger
+
+	mu          sync.Mutex
+	subscribers map[int]map[*subscriber]struct{}
+	count       int
+}
+
+func NewStreamService(reader repo.User, changes repo.Changes, cfg StreamConfig, log logging.Logger) *StreamService {
+	return &StreamService{
+		reader:      reader,
+		changes:     changes,
+		cfg:         cfg,
+		log:         log,
+		subscribers: make(map[int]map[*subscriber]struct{}),
+	}
+}
+
+// SubscribeBalance streams balance changes of user until stop is closed. Transactions after lastEventId
+// are sent first if it is set. The stream is closed earlier if it lags behind or changes may have been lost,
+// the client resumes it with the id of the last received event
+func (s *StreamService) SubscribeBalance(userId, lastEventId int, stop <-chan struct{}) (<-chan models.BalanceEvent, error) {
+	if lastEventId < 0 {
+		return nil, fmt.Errorf("incorrect last event id %d", lastEventId)
+	}
+
+	if _, err := s.reader.GetWallets(userId); err != nil {
+		return nil, err
+	}
+
+	// subscriber is added before replay, so changes committed meanwhile are not missed
+	sub, err := s.subscribe(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan models.BalanceEvent)
+	go func() {
+		defer close(events)
+		defer s.unsubscribe(sub)
+
+		send := func(t models.Transaction) bool {
+			select {
+			case <-stop:
+				return false
+			case events <- models.NewBalanceEvent(t):
+				return true
+			}
+		}
+
+		replayed, ok := s.replay(userId, lastEventId, send)
+		if !ok {
+			return
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case t, ok := <-sub.changes:
+				if !ok {
+					return
+				}
+
+				if _, ok := replayed[t.ID]; ok {
+					continue
+				}
+
+				if !send(t) {
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// replay sends transactions of user after lastEventId and returns ids of sent transactions,
+// it returns false if the stream must be closed
+func (s *StreamService) replay(userId, lastEventId int, send func(t models.Transaction) bool) (map[int]struct{}, bool) {
+	replayed := make(map[int]struct{})
+	if lastEventId == 0 {
+		return replayed, true
+	}
+
+	for {
+		transactions, err := s.reader.GetTransactionsAfter(userId, lastEventId, streamReplayPage)
+		if err != nil {
+			s.log.Infof("failed to replay balance stream of user %d: %s", userId, err.Error())
+			return nil, false
+		}
+
+		for _, t := range transactions {
+			if !send(t) {
+				return nil, false
+			}
+
+			replayed[t.ID] = struct{}{}
+			lastEventId = t.ID
+		}
+
+		if len(transactions) < streamReplayPage {
+			return replayed, true
+		}
+	}
+}
+
+// ListenBalanceChanges sends committed changes to balance streams until stop is closed
+func (s *StreamService) ListenBalanceChanges(stop <-chan struct{}) {
+	if s.changes == nil {
+		return
+	}
+
+	for {
+		changes, err := s.changes.ListenChanges(stop)
+		if err != nil {
+			s.log.Infof("failed to listen balance changes: %s", err.Error())
+		} else {
+			for t := range changes {
+				s.dispatch(t)
+			}
+		}
+
+		// streams may have missed changes while nobody listened, they are resumed by clients
+		s.closeAll()
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(streamRetryInterval):
+		}
+	}
+}
+
+// dispatch sends t to streams of its user. Zero transaction means changes may have been lost, so every stream is closed
+func (s *StreamService) dispatch(t models.Transaction) {
+	if t.ID == 0 {
+		s.closeAll()
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers[t.UserId] {
+		select {
+		case sub.changes <- t:
+		default:
+			s.log.Infof("balance stream of user %d lags behind, it is closed", t.UserId)
+			s.remove(sub)
+		}
+	}
+}
+
+func (s *StreamService) subscribe(userId int) (*subscriber, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.MaxStreams > 0 && s.count >= s.cfg.MaxStreams {
+		return nil, fmt.Errorf("%w, limit is %d", models.ErrTooManyStreams, s.cfg.MaxStreams)
+	}
+
+	if s.cfg.MaxStreamsPerUser > 0 && len(s.subscribers[userId]) >= s.cfg.MaxStreamsPerUser {
+		return nil, fmt.Errorf("%w of user %d, limit is %d", models.ErrTooManyStreams, userId, s.cfg.MaxStreamsPerUser)
+	}
+
+	sub := &subscriber{
+		userId:  userId,
+		changes: make(chan models.Transaction, streamBuffer),
+	}
+
+	if s.subscribers[userId] == nil {
+		s.subscribers[userId] = make(map[*subscriber]struct{})
+	}
+	s.subscribers[userId][sub] = struct{}{}
+	s.count++
+
+	return sub, nil
+}
+
+func (s *StreamService) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(sub)
+}
+
+func (s *StreamService) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subs := range s.subscribers {
+		for sub := range subs {
+			s.remove(sub)
+		}
+	}
+}
+
+// remove closes changes of sub if it was not removed yet, it is called under the lock
+func (s *StreamService) remove(sub *subscriber) {
+	subs, ok := s.subscribers[sub.userId]
+	if !ok {
+		return
+	}
+
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s.subscribers, sub.userId)
+	}
+
+	s.count--
+	close(sub.changes)
+}
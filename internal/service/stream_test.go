@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamService_SubscribeBalance(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	eur := models.Input{UserId: 1, Amount: 10, Currency: "EUR"}
+
+	// newStream returns service dispatching changes of memory repository, it is stopped with the test
+	newStream := func(t *testing.T, cfg StreamConfig) (*StreamService, *UserService) {
+		user := repo.NewMemoryUserRepo(logger)
+		user.AddUsers(1, 2)
+
+		stop := make(chan struct{})
+		t.Cleanup(func() { close(stop) })
+
+		s := NewStreamService(user, user, cfg, logger)
+		changes, err := user.ListenChanges(stop)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go func() {
+			for t := range changes {
+				s.dispatch(t)
+			}
+		}()
+
+		return s, NewUserService(user, user, user, testPayments(user), nil, logger)
+	}
+
+	next := func(t *testing.T, events <-chan models.BalanceEvent) (models.BalanceEvent, bool) {
+		select {
+		case event, ok := <-events:
+			return event, ok
+		case <-time.After(time.Second):
+			t.Fatal("no balance event")
+			return models.BalanceEvent{}, false
+		}
+	}
+
+	t.Run("Live changes", func(t *testing.T) {
+		s, users := newStream(t, StreamConfig{})
+
+		events, err := s.SubscribeBalance(1, 0, make(chan struct{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := users.TopUp(models.Input{UserId: 2, Amount: 5, Currency: "EUR"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := users.TopUp(eur); err != nil {
+			t.Fatal(err)
+		}
+
+		event, ok := next(t, events)
+		assert.True(t, ok)
+		assert.Equal(t, 1, event.UserId)
+		assert.Equal(t, models.Wallet{UserId: 1, Currency: "EUR", Balance: 10}, event.Balance)
+		assert.Equal(t, event.ID, event.Transaction.ID)
+		assert.Equal(t, float32(10), event.Transaction.Amount)
+	})
+
+	t.Run("Resume after last event", func(t *testing.T) {
+		s, users := newStream(t, StreamConfig{})
+
+		first, err := s.SubscribeBalance(1, 0, make(chan struct{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := users.TopUp(eur); err != nil {
+			t.Fatal(err)
+		}
+		last, _ := next(t, first)
+
+		for i := 0; i < 2; i++ {
+			if _, err := users.TopUp(eur); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		events, err := s.SubscribeBalance(1, last.ID, make(chan struct{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range []float32{20, 30} {
+			event, _ := next(t, events)
+			assert.Equal(t, want, event.Balance.Balance)
+		}
+
+		if _, err := users.TopUp(eur); err != nil {
+			t.Fatal(err)
+		}
+
+		event, _ := next(t, events)
+		assert.Equal(t, float32(40), event.Balance.Balance)
+	})
+
+	t.Run("Lost changes close streams", func(t *testing.T) {
+		s, _ := newStream(t, StreamConfig{})
+
+		events, err := s.SubscribeBalance(1, 0, make(chan struct{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s.dispatch(models.Transaction{})
+
+		_, ok := next(t, events)
+		assert.False(t, ok)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		s, _ := newStream(t, StreamConfig{MaxStreams: 2, MaxStreamsPerUser: 1})
+
+		stop := make(chan struct{})
+		events, err := s.SubscribeBalance(1, 0, stop)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = s.SubscribeBalance(1, 0, make(chan struct{}))
+		assert.True(t, errors.Is(err, models.ErrTooManyStreams))
+		assert.EqualError(t, err, "too many balance streams of user 1, limit is 1")
+
+		if _, err := s.SubscribeBalance(2, 0, make(chan struct{})); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = s.SubscribeBalance(2, 0, make(chan struct{}))
+		assert.True(t, errors.Is(err, models.ErrTooManyStreams))
+
+		close(stop)
+		_, ok := next(t, events)
+		assert.False(t, ok)
+
+		_, err = s.SubscribeBalance(1, 0, make(chan struct{}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		s, _ := newStream(t, StreamConfig{})
+
+		_, err := s.SubscribeBalance(3, 0, make(chan struct{}))
+		assert.EqualError(t, err, "user not found")
+	})
+}
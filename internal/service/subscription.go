@@ -0,0 +1,431 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/utils"
+)
+
+// maxSubscriptionError is the length of subscription errors column
+const maxSubscriptionError = 255
+
+// errRenewalFailed rolls back the unit of work of a failed renewal, the failure itself is saved separately
+var errRenewalFailed = errors.New("subscription renewal failed")
+
+type SubscriptionConfig struct {
+	// PollInterval between checks for due renewals, zero disables the worker
+	PollInterval time.Duration
+	// BatchSize limits subscriptions renewed by one check
+	BatchSize int
+	// RetryInterval between renewal charges of a past due subscription
+	RetryInterval time.Duration
+	// GracePeriod after the end of the period, subscription is cancelled when it is not paid within it
+	GracePeriod time.Duration
+}
+
+type SubscriptionService struct {
+	tx   repo.Transactor
+	repo repo.Subscription
+	user repo.User
+	// payments debit charges like debits made by hand, refunds are credited by user
+	payments *Payments
+	cfg      SubscriptionConfig
+	log      logging.Logger
+}
+
+func NewSubscriptionService(tx repo.Transactor, repo repo.Subscription, user repo.User, payments *Payments,
+	cfg SubscriptionConfig, log logging.Logger) *SubscriptionService {
+	return &SubscriptionService{
+		tx:       tx,
+		repo:     repo,
+		user:     user,
+		payments: payments,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+func (s *SubscriptionService) CreatePlan(input models.PlanInput) (models.Plan, error) {
+	if err := input.Validate(); err != nil {
+		return models.Plan{}, err
+	}
+
+	return s.repo.CreatePlan(input)
+}
+
+func (s *SubscriptionService) GetPlan(id int) (models.Plan, error) {
+	return s.repo.GetPlan(id)
+}
+
+func (s *SubscriptionService) ListPlans() ([]models.Plan, error) {
+	return s.repo.ListPlans()
+}
+
+// Subscribe starts subscription of user to plan. Plan with trial starts with free trial period,
+// otherwise the first period is charged right away and subscription is not created if the charge fails
+func (s *SubscriptionService) Subscribe(input models.SubscriptionInput) (models.Subscription, error) {
+	if input.UserId <= 0 {
+		return models.Subscription{}, errors.New("incorrect user id")
+	}
+
+	plan, err := s.repo.GetPlan(input.PlanId)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	now := time.Now().UTC()
+	subscription := models.Subscription{
+		UserId:      input.UserId,
+		PlanId:      plan.ID,
+		Status:      models.SubscriptionActive,
+		PeriodStart: now,
+		PeriodEnd:   plan.PeriodEnd(now),
+	}
+
+	if plan.TrialDays > 0 {
+		subscription.Status = models.SubscriptionTrialing
+		subscription.PeriodEnd = now.AddDate(0, 0, plan.TrialDays)
+	}
+	subscription.NextAttemptAt = subscription.PeriodEnd
+
+	err = s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		subscription, err = s.repo.CreateSubscription(tx, subscription)
+		if err != nil {
+			return err
+		}
+
+		event := newSubscriptionEvent(subscription, plan, models.SubscriptionCreated, now)
+		if subscription.Status == models.SubscriptionActive {
+			if _, err := s.charge(tx, subscription, plan, plan.Price, subscription.PeriodKey()); err != nil {
+				return err
+			}
+
+			event.Amount = plan.Price
+		}
+
+		return s.repo.AddEvent(tx, event)
+	})
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	return subscription, nil
+}
+
+func (s *SubscriptionService) GetSubscription(id int) (models.Subscription, error) {
+	return s.repo.GetSubscription(id)
+}
+
+func (s *SubscriptionService) ListSubscriptions(userId int, status string) ([]models.Subscription, error) {
+	switch status {
+	case "", models.SubscriptionTrialing, models.SubscriptionActive, models.SubscriptionPastDue,
+		models.SubscriptionCancelled:
+		return s.repo.ListSubscriptions(userId, status)
+	}
+
+	return nil, fmt.Errorf("unsupported status %q", status)
+}
+
+// GetSubscriptionEvents returns events of all subscriptions after the event with afterId, consumers
+// remember the id of the last event they handled and continue after it
+func (s *SubscriptionService) GetSubscriptionEvents(afterId, limit int) ([]models.SubscriptionEvent, error) {
+	return s.repo.GetSubscriptionEvents(afterId, limit)
+}
+
+// ChangePlan moves trialing or active subscription to another plan of the same currency. Active subscription
+// is charged the difference of prices for the rest of the current period, or it is refunded when the new plan
+// is cheaper. The period is kept, the next renewal charges the price of the new plan
+func (s *SubscriptionService) ChangePlan(id int, input models.PlanChangeInput) (models.Subscription, error) {
+	plan, err := s.repo.GetPlan(input.PlanId)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	var subscription models.Subscription
+	err = s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		subscription, err = s.repo.LockSubscription(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if subscription.Status != models.SubscriptionTrialing && subscription.Status != models.SubscriptionActive {
+			return fmt.Errorf("subscription is %s", subscription.Status)
+		}
+
+		if subscription.PlanId == plan.ID {
+			return errors.New("subscription is already on the plan")
+		}
+
+		current, err := s.repo.GetPlan(subscription.PlanId)
+		if err != nil {
+			return err
+		}
+
+		if current.Currency != plan.Currency {
+			return fmt.Errorf("currency of the plan %s differs from %s", plan.Currency, current.Currency)
+		}
+
+		now := time.Now().UTC()
+		event := newSubscriptionEvent(subscription, plan, models.SubscriptionPlanChanged, now)
+		if subscription.Status == models.SubscriptionActive {
+			amount, err := prorate(subscription, current, plan, now)
+			if err != nil {
+				return err
+			}
+
+			if _, err := s.charge(tx, subscription, plan, amount,
+				fmt.Sprintf("subscription-%d-plan-%d", subscription.ID, now.Unix())); err != nil {
+				return err
+			}
+
+			event.Amount = amount
+		}
+
+		subscription.PlanId = plan.ID
+		if err := s.repo.UpdateSubscription(tx, subscription); err != nil {
+			return err
+		}
+
+		return s.repo.AddEvent(tx, event)
+	})
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// CancelSubscription cancels subscription right away or at the end of its period.
+// Past due subscription is always cancelled right away, the paid period is not refunded
+func (s *SubscriptionService) CancelSubscription(id int, input models.CancelSubscriptionInput) (models.Subscription, error) {
+	var subscription models.Subscription
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		subscription, err = s.repo.LockSubscription(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if !subscription.Renewing() {
+			return fmt.Errorf("subscription is %s", subscription.Status)
+		}
+
+		if input.AtPeriodEnd && subscription.Status != models.SubscriptionPastDue {
+			subscription.CancelAtPeriodEnd = true
+			return s.repo.UpdateSubscription(tx, subscription)
+		}
+
+		return s.cancel(tx, &subscription, "", time.Now().UTC())
+	})
+	if err != nil {
+		return models.Subscription{}, err
+	}
+
+	return subscription, nil
+}
+
+// RunRenewals renews due subscriptions every poll interval until stop is closed
+func (s *SubscriptionService) RunRenewals(stop <-chan struct{}) {
+	if s.cfg.PollInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.renewDue(time.Now().UTC()); err != nil {
+				s.log.Infof("failed to renew subscriptions: %s", err.Error())
+			}
+		}
+	}
+}
+
+// renewDue renews subscriptions due at now and returns the number of renewed ones
+func (s *SubscriptionService) renewDue(now time.Time) (int, error) {
+	ids, err := s.repo.DueSubscriptions(now, s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	renewed := 0
+	for _, id := range ids {
+		ok, err := s.renew(id, now)
+		if err != nil {
+			s.log.Infof("failed to renew subscription %d: %s", id, err.Error())
+			continue
+		}
+
+		if ok {
+			renewed++
+		}
+	}
+
+	return renewed, nil
+}
+
+// renew charges the next period of subscription, or cancels it when it is cancelled at period end.
+// If the charge fails, it is rolled back and the failure is saved in a unit of work of its own:
+// subscription becomes past due and the charge is retried until the grace period is over
+func (s *SubscriptionService) renew(id int, now time.Time) (bool, error) {
+	var (
+		renewed bool
+		failure error
+	)
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		subscription, ok, err := s.repo.LockDueSubscription(tx, id, now)
+		if err != nil || !ok {
+			return err
+		}
+
+		if subscription.CancelAtPeriodEnd {
+			return s.cancel(tx, &subscription, "", now)
+		}
+
+		plan, err := s.repo.GetPlan(subscription.PlanId)
+		if err != nil {
+			return err
+		}
+
+		// periods follow each other, unless the subscription was not renewed for the whole next period
+		subscription.PeriodStart = subscription.PeriodEnd
+		subscription.PeriodEnd = plan.PeriodEnd(subscription.PeriodStart)
+		if !subscription.PeriodEnd.After(now) {
+			subscription.PeriodStart = now
+			subscription.PeriodEnd = plan.PeriodEnd(now)
+		}
+
+		if _, err := s.charge(tx, subscription, plan, plan.Price, subscription.PeriodKey()); err != nil {
+			failure = err
+			return errRenewalFailed
+		}
+
+		subscription.Status = models.SubscriptionActive
+		subscription.NextAttemptAt = subscription.PeriodEnd
+		subscription.Attempts = 0
+		subscription.LastError = ""
+		if err := s.repo.UpdateSubscription(tx, subscription); err != nil {
+			return err
+		}
+
+		event := newSubscriptionEvent(subscription, plan, models.SubscriptionRenewed, now)
+		event.Amount = plan.Price
+		renewed = true
+		return s.repo.AddEvent(tx, event)
+	})
+	if failure == nil {
+		return renewed && err == nil, err
+	}
+
+	return false, s.tx.WithinTx(func(tx repo.Tx) error {
+		subscription, ok, err := s.repo.LockDueSubscription(tx, id, now)
+		if err != nil || !ok {
+			return err
+		}
+
+		plan, err := s.repo.GetPlan(subscription.PlanId)
+		if err != nil {
+			return err
+		}
+
+		subscription.Attempts++
+		subscription.LastError = truncate(failure.Error(), maxSubscriptionError)
+
+		event := newSubscriptionEvent(subscription, plan, models.SubscriptionRenewalFailed, now)
+		event.Error = subscription.LastError
+		if err := s.repo.AddEvent(tx, event); err != nil {
+			return err
+		}
+
+		graceEnd := subscription.PeriodEnd.Add(s.cfg.GracePeriod)
+		if !now.Before(graceEnd) {
+			s.log.Infof("subscription %d is cancelled after %d failed renewals", subscription.ID, subscription.Attempts)
+			return s.cancel(tx, &subscription, subscription.LastError, now)
+		}
+
+		subscription.Status = models.SubscriptionPastDue
+		subscription.NextAttemptAt = now.Add(s.cfg.RetryInterval)
+		if subscription.NextAttemptAt.After(graceEnd) {
+			subscription.NextAttemptAt = graceEnd
+		}
+
+		return s.repo.UpdateSubscription(tx, subscription)
+	})
+}
+
+// cancel ends subscription as a part of tx, reason is saved in the event
+func (s *SubscriptionService) cancel(tx repo.Tx, subscription *models.Subscription, reason string, now time.Time) error {
+	plan, err := s.repo.GetPlan(subscription.PlanId)
+	if err != nil {
+		return err
+	}
+
+	subscription.Status = models.SubscriptionCancelled
+	subscription.CancelledAt = &now
+	if err := s.repo.UpdateSubscription(tx, *subscription); err != nil {
+		return err
+	}
+
+	event := newSubscriptionEvent(*subscription, plan, models.SubscriptionEnded, now)
+	event.Error = reason
+	return s.repo.AddEvent(tx, event)
+}
+
+// charge debits amount from the wallet of the plan currency as a part of tx, negative amount is refunded
+func (s *SubscriptionService) charge(tx repo.Tx, subscription models.Subscription, plan models.Plan, amount float32,
+	linkId string) (float32, error) {
+	input := models.Input{
+		UserId:   subscription.UserId,
+		Amount:   amount,
+		Currency: plan.Currency,
+	}
+	operation := models.Operation{
+		Comment: fmt.Sprintf("Subscription #%d %s", subscription.ID, plan.Name),
+		LinkId:  linkId,
+	}
+
+	switch {
+	case amount > 0:
+		return s.payments.Debit(tx, input, operation)
+	case amount < 0:
+		input.Amount = -amount
+		operation.Comment = fmt.Sprintf("Refund of subscription #%d %s", subscription.ID, plan.Name)
+		return s.user.Credit(tx, input, operation)
+	}
+
+	return 0, nil
+}
+
+// prorate returns the difference of prices of plans for the rest of the current period
+func prorate(subscription models.Subscription, from, to models.Plan, now time.Time) (float32, error) {
+	remaining := subscription.PeriodEnd.Sub(now)
+	period := subscription.PeriodEnd.Sub(subscription.PeriodStart)
+	if remaining <= 0 || period <= 0 {
+		return 0, nil
+	}
+
+	return utils.ConvertWithRate(to.Price-from.Price, float32(float64(remaining)/float64(period)))
+}
+
+func newSubscriptionEvent(subscription models.Subscription, plan models.Plan, eventType string,
+	now time.Time) models.SubscriptionEvent {
+	return models.SubscriptionEvent{
+		SubscriptionId: subscription.ID,
+		UserId:         subscription.UserId,
+		PlanId:         plan.ID,
+		Type:           eventType,
+		Currency:       plan.Currency,
+		Date:           now,
+	}
+}
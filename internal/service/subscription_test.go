@@ -0,0 +1,370 @@
+package service
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memorySubscriptions keeps plans, subscriptions and events in maps, units of work of memory users
+// repository serialize the access. Changes of a unit of work which is rolled back are not undone
+type memorySubscriptions struct {
+	plans         map[int]models.Plan
+	subscriptions map[int]models.Subscription
+	events        []models.SubscriptionEvent
+}
+
+func newMemorySubscriptions(plans ...models.Plan) *memorySubscriptions {
+	r := &memorySubscriptions{plans: map[int]models.Plan{}, subscriptions: map[int]models.Subscription{}}
+	for _, plan := range plans {
+		r.plans[plan.ID] = plan
+	}
+
+	return r
+}
+
+func (r *memorySubscriptions) CreatePlan(input models.PlanInput) (models.Plan, error) {
+	plan := models.Plan{ID: len(r.plans) + 1, Name: input.Name, Price: input.Price, Currency: input.Currency,
+		Period: input.Period, TrialDays: input.TrialDays}
+	r.plans[plan.ID] = plan
+	return plan, nil
+}
+
+func (r *memorySubscriptions) GetPlan(id int) (models.Plan, error) {
+	plan, ok := r.plans[id]
+	if !ok {
+		return models.Plan{}, models.ErrPlanNotFound
+	}
+
+	return plan, nil
+}
+
+func (r *memorySubscriptions) ListPlans() ([]models.Plan, error) {
+	plans := []models.Plan{}
+	for _, plan := range r.plans {
+		plans = append(plans, plan)
+	}
+
+	return plans, nil
+}
+
+func (r *memorySubscriptions) CreateSubscription(tx repo.Tx, s models.Subscription) (models.Subscription, error) {
+	s.ID = len(r.subscriptions) + 1
+	r.subscriptions[s.ID] = s
+	return s, nil
+}
+
+func (r *memorySubscriptions) GetSubscription(id int) (models.Subscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return models.Subscription{}, models.ErrSubscriptionNotFound
+	}
+
+	for _, event := range r.events {
+		if event.SubscriptionId == id {
+			subscription.Events = append(subscription.Events, event)
+		}
+	}
+
+	return subscription, nil
+}
+
+func (r *memorySubscriptions) ListSubscriptions(userId int, status string) ([]models.Subscription, error) {
+	subscriptions := []models.Subscription{}
+	for _, s := range r.subscriptions {
+		if s.UserId == userId && (status == "" || s.Status == status) {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+func (r *memorySubscriptions) DueSubscriptions(now time.Time, limit int) ([]int, error) {
+	ids := []int{}
+	for id, s := range r.subscriptions {
+		if s.Status != models.SubscriptionCancelled && !s.NextAttemptAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func (r *memorySubscriptions) LockSubscription(tx repo.Tx, id int) (models.Subscription, error) {
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return models.Subscription{}, models.ErrSubscriptionNotFound
+	}
+
+	return subscription, nil
+}
+
+func (r *memorySubscriptions) LockDueSubscription(tx repo.Tx, id int, now time.Time) (models.Subscription, bool, error) {
+	s, ok := r.subscriptions[id]
+	return s, ok && s.Status != models.SubscriptionCancelled && !s.NextAttemptAt.After(now), nil
+}
+
+func (r *memorySubscriptions) UpdateSubscription(tx repo.Tx, s models.Subscription) error {
+	r.subscriptions[s.ID] = s
+	return nil
+}
+
+func (r *memorySubscriptions) AddEvent(tx repo.Tx, event models.SubscriptionEvent) error {
+	event.ID = len(r.events) + 1
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memorySubscriptions) GetSubscriptionEvents(afterId, limit int) ([]models.SubscriptionEvent, error) {
+	events := []models.SubscriptionEvent{}
+	for _, event := range r.events {
+		if event.ID > afterId && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// eventTypes returns types of events of all subscriptions in order
+func (r *memorySubscriptions) eventTypes() []string {
+	types := []string{}
+	for _, event := range r.events {
+		types = append(types, event.Type)
+	}
+
+	return types
+}
+
+func TestSubscriptionService(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	cfg := SubscriptionConfig{BatchSize: 10, RetryInterval: 24 * time.Hour, GracePeriod: 48 * time.Hour}
+	basic := models.Plan{ID: 1, Name: "Basic", Price: 10, Currency: "EUR", Period: models.PeriodMonth}
+	premium := models.Plan{ID: 2, Name: "Premium", Price: 30, Currency: "EUR", Period: models.PeriodMonth}
+	trial := models.Plan{ID: 3, Name: "Trial", Price: 10, Currency: "EUR", Period: models.PeriodMonth, TrialDays: 7}
+	dollars := models.Plan{ID: 4, Name: "Dollars", Price: 10, Currency: "USD", Period: models.PeriodMonth}
+
+	newService := func(balance float32) (*SubscriptionService, *repo.MemoryUserRepo, *memorySubscriptions) {
+		user := repo.NewMemoryUserRepo(logger)
+		user.AddUsers(1)
+		if balance > 0 {
+			err := user.WithinTx(func(tx repo.Tx) error {
+				_, err := user.Credit(tx, models.Input{UserId: 1, Amount: balance, Currency: "EUR"}, models.Operation{})
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		subscriptions := newMemorySubscriptions(basic, premium, trial, dollars)
+		return NewSubscriptionService(user, subscriptions, user, testPayments(user), cfg, logger),
+			user, subscriptions
+	}
+
+	balance := func(t *testing.T, user *repo.MemoryUserRepo) float32 {
+		wallets, err := user.GetWallets(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, wallet := range wallets {
+			if wallet.Currency == "EUR" {
+				return wallet.Balance
+			}
+		}
+
+		return 0
+	}
+
+	t.Run("Subscribe charges the first period", func(t *testing.T) {
+		s, user, subscriptions := newService(25)
+
+		subscription, err := s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: basic.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, models.SubscriptionActive, subscription.Status)
+		assert.Equal(t, subscription.PeriodStart.AddDate(0, 1, 0), subscription.PeriodEnd)
+		assert.Equal(t, subscription.PeriodEnd, subscription.NextAttemptAt)
+		assert.Equal(t, float32(15), balance(t, user))
+		assert.Equal(t, []string{models.SubscriptionCreated}, subscriptions.eventTypes())
+		assert.Equal(t, float32(10), subscriptions.events[0].Amount)
+	})
+
+	t.Run("Subscribe without money", func(t *testing.T) {
+		s, _, _ := newService(5)
+
+		_, err := s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: basic.ID})
+		assert.EqualError(t, err, "not enough money to perform purchase")
+
+		_, err = s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: 9})
+		assert.ErrorIs(t, err, models.ErrPlanNotFound)
+	})
+
+	t.Run("Trial is renewed at its end", func(t *testing.T) {
+		s, user, subscriptions := newService(25)
+
+		subscription, err := s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: trial.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, models.SubscriptionTrialing, subscription.Status)
+		assert.Equal(t, subscription.PeriodStart.AddDate(0, 0, 7), subscription.PeriodEnd)
+		assert.Equal(t, float32(25), balance(t, user))
+
+		renewed, err := s.renewDue(subscription.PeriodEnd)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, renewed)
+
+		got := subscriptions.subscriptions[subscription.ID]
+		assert.Equal(t, models.SubscriptionActive, got.Status)
+		assert.Equal(t, subscription.PeriodEnd, got.PeriodStart)
+		assert.Equal(t, subscription.PeriodEnd.AddDate(0, 1, 0), got.PeriodEnd)
+		assert.Equal(t, float32(15), balance(t, user))
+
+		transactions, err := user.GetTransactions(1, models.Page{Page: 1, Limit: 10, Sort: "date"})
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 2)
+		for _, transaction := range transactions {
+			if transaction.Amount == trial.Price {
+				assert.Equal(t, got.PeriodKey(), transaction.LinkId)
+			}
+		}
+
+		// the period is not charged again
+		renewed, err = s.renewDue(subscription.PeriodEnd.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, renewed)
+		assert.Equal(t, float32(15), balance(t, user))
+	})
+
+	t.Run("Dunning", func(t *testing.T) {
+		s, user, subscriptions := newService(10)
+
+		subscription, err := s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: basic.ID})
+		assert.NoError(t, err)
+		end := subscription.PeriodEnd
+
+		// renewal fails and is retried within the grace period
+		_, err = s.renewDue(end)
+		assert.NoError(t, err)
+
+		got := subscriptions.subscriptions[subscription.ID]
+		assert.Equal(t, models.SubscriptionPastDue, got.Status)
+		assert.Equal(t, end, got.PeriodEnd)
+		assert.Equal(t, end.Add(24*time.Hour), got.NextAttemptAt)
+		assert.Equal(t, 1, got.Attempts)
+		assert.Equal(t, "not enough money to perform purchase", got.LastError)
+
+		_, err = s.renewDue(end.Add(24 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, end.Add(48*time.Hour), subscriptions.subscriptions[subscription.ID].NextAttemptAt)
+
+		// renewal paid late keeps the periods contiguous
+		err = user.WithinTx(func(tx repo.Tx) error {
+			_, err := user.Credit(tx, models.Input{UserId: 1, Amount: 10, Currency: "EUR"}, models.Operation{})
+			return err
+		})
+		assert.NoError(t, err)
+
+		renewed, err := s.renewDue(end.Add(48 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, renewed)
+
+		got = subscriptions.subscriptions[subscription.ID]
+		assert.Equal(t, models.SubscriptionActive, got.Status)
+		assert.Equal(t, end, got.PeriodStart)
+		assert.Equal(t, 0, got.Attempts)
+		assert.Equal(t, float32(0), balance(t, user))
+		assert.Equal(t, []string{models.SubscriptionCreated, models.SubscriptionRenewalFailed,
+			models.SubscriptionRenewalFailed, models.SubscriptionRenewed}, subscriptions.eventTypes())
+	})
+
+	t.Run("Cancelled after grace period", func(t *testing.T) {
+		s, _, subscriptions := newService(10)
+
+		subscription, err := s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: basic.ID})
+		assert.NoError(t, err)
+
+		for _, after := range []time.Duration{0, 24 * time.Hour, 48 * time.Hour} {
+			_, err := s.renewDue(subscription.PeriodEnd.Add(after))
+			assert.NoError(t, err)
+		}
+
+		got := subscriptions.subscriptions[subscription.ID]
+		assert.Equal(t, models.SubscriptionCancelled, got.Status)
+		assert.NotNil(t, got.CancelledAt)
+		assert.Equal(t, models.SubscriptionEnded, subscriptions.events[len(subscriptions.events)-1].Type)
+		assert.Equal(t, "not enough money to perform purchase", subscriptions.events[len(subscriptions.events)-1].Error)
+	})
+
+	t.Run("Cancel at period end", func(t *testing.T) {
+		s, user, subscriptions := newService(25)
+
+		subscription, err := s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: basic.ID})
+		assert.NoError(t, err)
+
+		subscription, err = s.CancelSubscription(subscription.ID, models.CancelSubscriptionInput{AtPeriodEnd: true})
+		assert.NoError(t, err)
+		assert.Equal(t, models.SubscriptionActive, subscription.Status)
+		assert.True(t, subscription.CancelAtPeriodEnd)
+
+		_, err = s.renewDue(subscription.PeriodEnd)
+		assert.NoError(t, err)
+		assert.Equal(t, models.SubscriptionCancelled, subscriptions.subscriptions[subscription.ID].Status)
+		assert.Equal(t, float32(15), balance(t, user))
+
+		_, err = s.CancelSubscription(subscription.ID, models.CancelSubscriptionInput{})
+		assert.EqualError(t, err, "subscription is cancelled")
+	})
+
+	t.Run("Change plan", func(t *testing.T) {
+		s, user, subscriptions := newService(100)
+
+		subscription, err := s.Subscribe(models.SubscriptionInput{UserId: 1, PlanId: basic.ID})
+		assert.NoError(t, err)
+
+		// the whole period is left, so the difference of prices is charged
+		changed, err := s.ChangePlan(subscription.ID, models.PlanChangeInput{PlanId: premium.ID})
+		assert.NoError(t, err)
+		assert.Equal(t, premium.ID, changed.PlanId)
+		assert.Equal(t, subscription.PeriodEnd, changed.PeriodEnd)
+		assert.InDelta(t, 70, balance(t, user), 0.05)
+
+		_, err = s.ChangePlan(subscription.ID, models.PlanChangeInput{PlanId: basic.ID})
+		assert.NoError(t, err)
+		assert.InDelta(t, 90, balance(t, user), 0.05)
+		assert.InDelta(t, -20, subscriptions.events[len(subscriptions.events)-1].Amount, 0.05)
+
+		_, err = s.ChangePlan(subscription.ID, models.PlanChangeInput{PlanId: basic.ID})
+		assert.EqualError(t, err, "subscription is already on the plan")
+
+		_, err = s.ChangePlan(subscription.ID, models.PlanChangeInput{PlanId: dollars.ID})
+		assert.EqualError(t, err, "currency of the plan USD differs from EUR")
+	})
+
+	t.Run("Prorate", func(t *testing.T) {
+		start := time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC)
+		subscription := models.Subscription{PeriodStart: start, PeriodEnd: start.AddDate(0, 0, 30)}
+
+		amount, err := prorate(subscription, basic, premium, start.AddDate(0, 0, 15))
+		assert.NoError(t, err)
+		assert.Equal(t, float32(10), amount)
+
+		amount, err = prorate(subscription, premium, basic, start.AddDate(0, 0, 20))
+		assert.NoError(t, err)
+		assert.Equal(t, float32(-6.67), amount)
+
+		amount, err = prorate(subscription, basic, premium, start.AddDate(0, 0, 31))
+		assert.NoError(t, err)
+		assert.Equal(t, float32(0), amount)
+	})
+}
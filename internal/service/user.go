@@ -1,45 +1,218 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/gavrylenkoIvan/balance-service/internal/repo"
 	"github.com/gavrylenkoIvan/balance-service/models"
 	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/gavrylenkoIvan/balance-service/pkg/rates"
 	"github.com/gavrylenkoIvan/balance-service/pkg/utils"
 )
 
+// maxHistoryPoints limits the size of a single balance history response
+const maxHistoryPoints = 1000
+
+// historyIntervals maps supported history intervals to their length
+var historyIntervals = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
 type UserService struct {
+	tx   repo.Transactor
 	repo repo.User
-	log  logging.Logger
+	// reader serves balances, transactions and history, it may lag behind repo
+	reader repo.User
+	// payments check spending limits, spend promo and charge fees of debits and transfers in their units of work
+	payments *Payments
+	rates    rates.Provider
+	log      logging.Logger
 }
 
-func NewUserService(repo repo.User, log logging.Logger) *UserService {
+func NewUserService(tx repo.Transactor, repo, reader repo.User, payments *Payments, rates rates.Provider,
+	log logging.Logger) *UserService {
 	return &UserService{
-		repo: repo,
-		log:  log,
+		tx:       tx,
+		repo:     repo,
+		reader:   reader,
+		payments: payments,
+		rates:    rates,
+		log:      log,
 	}
 }
 
 func (s *UserService) TopUp(input models.Input) (float32, error) {
-	return s.repo.TopUp(input)
+	if err := input.Validate(); err != nil {
+		return 0, err
+	}
+
+	currency, err := models.ParseCurrency(input.Currency)
+	if err != nil {
+		return 0, err
+	}
+
+	input.Currency = currency
+
+	var balance float32
+	err = s.tx.WithinTx(func(tx repo.Tx) error {
+		balance, err = s.repo.Credit(tx, input, models.Operation{
+			Comment: fmt.Sprintf("Top-up by bank_card %f%s", input.Amount, input.Currency),
+		})
+		return err
+	})
+
+	return balance, err
 }
 
 func (s *UserService) Debit(input models.Input) (float32, error) {
-	return s.repo.Debit(input)
+	currency, err := models.ParseCurrency(input.Currency)
+	if err != nil {
+		return 0, err
+	}
+
+	input.Currency = currency
+
+	var balance float32
+	err = s.tx.WithinTx(func(tx repo.Tx) error {
+		balance, err = s.payments.Debit(tx, input, models.Operation{
+			Comment: fmt.Sprintf("Debit by purchase %f%s", input.Amount, input.Currency),
+		})
+		return err
+	})
+
+	return balance, err
 }
 
 func (s *UserService) Transfer(input models.TransferInput) (float32, error) {
-	return s.repo.Transfer(input)
+	currency, err := models.ParseCurrency(input.Currency)
+	if err != nil {
+		return 0, err
+	}
+
+	input.Currency = currency
+
+	// both sides and the fee are changed in one unit of work, so money is never debited without being credited
+	var balance float32
+	err = s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		_, balance, err = s.payments.Transfer(tx, input, "")
+		return err
+	})
+
+	return balance, err
 }
 
 func (s *UserService) GetTransactions(id int, page models.Page) ([]models.Transaction, error) {
-	return s.repo.GetTransactions(id, page)
+	return s.reader.GetTransactions(id, page)
 }
 
-func (s *UserService) GetBalance(id int, currency string) (float32, error) {
-	euro, err := s.repo.GetBalance(id)
+func (s *UserService) GetBalance(id int, currency string) (models.Balance, error) {
+	wallets, err := s.reader.GetWallets(id)
 	if err != nil {
-		return 0, err
+		return models.Balance{}, err
+	}
+
+	return s.toBalance(id, wallets, currency)
+}
+
+func (s *UserService) GetBalanceAt(id int, at time.Time, currency string) (models.Balance, error) {
+	wallets, err := s.reader.GetBalanceAt(id, at)
+	if err != nil {
+		return models.Balance{}, err
+	}
+
+	return s.toBalance(id, wallets, currency)
+}
+
+// toBalance lists wallets and, if currency is requested, sums them up converted to it
+func (s *UserService) toBalance(id int, wallets []models.Wallet, currency string) (models.Balance, error) {
+	balance := models.Balance{
+		UserId:  id,
+		Wallets: wallets,
+	}
+
+	if balance.Wallets == nil {
+		balance.Wallets = []models.Wallet{}
+	}
+
+	if currency == "" {
+		return balance, nil
+	}
+
+	currency, err := models.ParseCurrency(currency)
+	if err != nil {
+		return models.Balance{}, err
+	}
+
+	var total float32
+	for _, wallet := range wallets {
+		rate, err := s.rates.Rate(wallet.Currency, currency)
+		if err != nil {
+			return models.Balance{}, err
+		}
+
+		converted, err := utils.ConvertWithRate(wallet.Balance, rate)
+		if err != nil {
+			return models.Balance{}, err
+		}
+
+		total += converted
+	}
+
+	balance.Currency = currency
+	balance.Total = &total
+
+	return balance, nil
+}
+
+func (s *UserService) GetBalanceHistory(id int, wallet string, from, to time.Time, interval, currency string) ([]models.BalancePoint, error) {
+	step, ok := historyIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+
+	if to.Before(from) {
+		return nil, errors.New("from must be before to")
+	}
+
+	if to.Sub(from)/step >= maxHistoryPoints {
+		return nil, fmt.Errorf("history is limited to %d points, narrow the range or use a bigger interval", maxHistoryPoints)
+	}
+
+	wallet, err := models.ParseCurrency(wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := s.reader.GetBalanceHistory(id, wallet, from, to, "1 "+interval)
+	if err != nil {
+		return nil, err
+	}
+
+	if currency == "" {
+		return points, nil
+	}
+
+	currency, err = models.ParseCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := s.rates.Rate(wallet, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range points {
+		points[i].Balance, err = utils.ConvertWithRate(points[i].Balance, rate)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return utils.Convert(euro, currency)
+	return points, nil
 }
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+)
+
+// voucherAlphabet has no characters which are easy to confuse, like 0 and O or 1 and I
+const voucherAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// voucherCodeGroups of voucherGroupLength characters make 80 random bits of a code
+const (
+	voucherCodeGroups  = 4
+	voucherGroupLength = 4
+)
+
+type VoucherService struct {
+	tx   repo.Transactor
+	repo repo.Voucher
+	user repo.User
+	log  logging.Logger
+}
+
+func NewVoucherService(tx repo.Transactor, repo repo.Voucher, user repo.User, log logging.Logger) *VoucherService {
+	return &VoucherService{
+		tx:   tx,
+		repo: repo,
+		user: user,
+		log:  log,
+	}
+}
+
+// CreateVoucherBatch generates random codes of the batch, only their hashes are saved, so the codes are
+// returned once
+func (s *VoucherService) CreateVoucherBatch(input models.VoucherBatchInput) (models.VoucherBatch, error) {
+	if err := input.Validate(); err != nil {
+		return models.VoucherBatch{}, err
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return models.VoucherBatch{}, errors.New("expiry must be in the future")
+	}
+
+	codes := make([]string, 0, input.Count)
+	hashes := make([]string, 0, input.Count)
+	for len(codes) < input.Count {
+		code, err := newVoucherCode()
+		if err != nil {
+			return models.VoucherBatch{}, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, models.HashVoucherCode(code))
+	}
+
+	batch := models.VoucherBatch{
+		Amount:    input.Amount,
+		Currency:  input.Currency,
+		MaxUses:   input.MaxUses,
+		ExpiresAt: input.ExpiresAt,
+	}
+
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		var err error
+		batch, err = s.repo.CreateBatch(tx, batch, hashes)
+		return err
+	})
+	if err != nil {
+		return models.VoucherBatch{}, err
+	}
+
+	batch.Codes = codes
+	return batch, nil
+}
+
+// RedeemVoucher tops up the user by the voucher amount in the unit of work which counts the use of the voucher,
+// the top-up is linked by the redemption key
+func (s *VoucherService) RedeemVoucher(input models.VoucherRedeemInput) (models.VoucherRedemption, error) {
+	if err := input.Validate(); err != nil {
+		return models.VoucherRedemption{}, err
+	}
+
+	var redemption models.VoucherRedemption
+	err := s.tx.WithinTx(func(tx repo.Tx) error {
+		voucher, err := s.repo.LockVoucher(tx, models.HashVoucherCode(input.Code))
+		if err != nil {
+			return err
+		}
+
+		if err := voucher.Redeemable(time.Now()); err != nil {
+			return err
+		}
+
+		redemption, err = s.repo.Redeem(tx, voucher, input.UserId)
+		if err != nil {
+			return err
+		}
+
+		redemption.Balance, err = s.user.Credit(tx, models.Input{
+			UserId:   input.UserId,
+			Amount:   voucher.Amount,
+			Currency: voucher.Currency,
+		}, models.Operation{
+			Comment: fmt.Sprintf("Top-up by voucher %f%s", voucher.Amount, voucher.Currency),
+			LinkId:  redemption.RedemptionKey(),
+		})
+		return err
+	})
+	if err != nil {
+		return models.VoucherRedemption{}, err
+	}
+
+	return redemption, nil
+}
+
+func (s *VoucherService) GetVoucherReport(currency string) (models.VoucherReport, error) {
+	currency, err := models.ParseCurrency(currency)
+	if err != nil {
+		return models.VoucherReport{}, err
+	}
+
+	return s.repo.GetVoucherReport(currency, time.Now())
+}
+
+// newVoucherCode returns a random code grouped by dashes, e.g. ABCD-EFGH-JKLM-NPQR
+func newVoucherCode() (string, error) {
+	groups := make([]string, 0, voucherCodeGroups)
+	max := big.NewInt(int64(len(voucherAlphabet)))
+	for i := 0; i < voucherCodeGroups; i++ {
+		group := make([]byte, voucherGroupLength)
+		for j := range group {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+
+			group[j] = voucherAlphabet[n.Int64()]
+		}
+
+		groups = append(groups, string(group))
+	}
+
+	return strings.Join(groups, "-"), nil
+}
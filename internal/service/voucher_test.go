@@ -0,0 +1,195 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/internal/repo"
+	"github.com/gavrylenkoIvan/balance-service/models"
+	"github.com/gavrylenkoIvan/balance-service/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryVouchers redeems vouchers like the postgres repository does, units of work of memory users
+// repository serialize the access. Changes are not reverted when the unit of work fails
+type memoryVouchers struct {
+	batches     []models.VoucherBatch
+	vouchers    map[string]*models.Voucher
+	redemptions []models.VoucherRedemption
+}
+
+func (r *memoryVouchers) CreateBatch(tx repo.Tx, batch models.VoucherBatch,
+	hashes []string) (models.VoucherBatch, error) {
+	if r.vouchers == nil {
+		r.vouchers = map[string]*models.Voucher{}
+	}
+
+	batch.ID = len(r.batches) + 1
+	batch.Count = len(hashes)
+	r.batches = append(r.batches, batch)
+	for _, hash := range hashes {
+		r.vouchers[hash] = &models.Voucher{ID: len(r.vouchers) + 1, BatchId: batch.ID, Amount: batch.Amount,
+			Currency: batch.Currency, MaxUses: batch.MaxUses, ExpiresAt: batch.ExpiresAt}
+	}
+
+	return batch, nil
+}
+
+func (r *memoryVouchers) LockVoucher(tx repo.Tx, hash string) (models.Voucher, error) {
+	voucher, ok := r.vouchers[hash]
+	if !ok {
+		return models.Voucher{}, models.ErrVoucherNotFound
+	}
+
+	return *voucher, nil
+}
+
+func (r *memoryVouchers) Redeem(tx repo.Tx, voucher models.Voucher, userId int) (models.VoucherRedemption, error) {
+	for _, redemption := range r.redemptions {
+		if redemption.VoucherId == voucher.ID && redemption.UserId == userId {
+			return models.VoucherRedemption{}, fmt.Errorf("%w: voucher is already redeemed by the user",
+				models.ErrVoucherNotRedeemable)
+		}
+	}
+
+	redemption := models.VoucherRedemption{ID: len(r.redemptions) + 1, VoucherId: voucher.ID,
+		BatchId: voucher.BatchId, UserId: userId, Amount: voucher.Amount, Currency: voucher.Currency}
+	r.redemptions = append(r.redemptions, redemption)
+	for _, v := range r.vouchers {
+		if v.ID == voucher.ID {
+			v.Uses++
+		}
+	}
+
+	return redemption, nil
+}
+
+func (r *memoryVouchers) GetVoucherReport(currency string, now time.Time) (models.VoucherReport, error) {
+	report := models.VoucherReport{Currency: currency, Batches: []models.VoucherBatch{}}
+	for _, batch := range r.batches {
+		if batch.Currency != currency {
+			continue
+		}
+
+		for _, redemption := range r.redemptions {
+			if redemption.BatchId == batch.ID {
+				batch.Redemptions++
+				batch.RedeemedValue += redemption.Amount
+			}
+		}
+
+		report.Batches = append(report.Batches, batch)
+		report.IssuedValue += batch.IssuedValue()
+		report.RedeemedValue += batch.RedeemedValue
+		if batch.Expired(now) {
+			report.ExpiredValue += batch.IssuedValue() - batch.RedeemedValue
+		} else {
+			report.OutstandingValue += batch.IssuedValue() - batch.RedeemedValue
+		}
+	}
+
+	return report, nil
+}
+
+func TestVoucherService(t *testing.T) {
+	logger, err := logging.InitLogger()
+	if err != nil {
+		t.Error(err)
+	}
+
+	user := repo.NewMemoryUserRepo(logger)
+	user.AddUsers(1, 2)
+	vouchers := &memoryVouchers{}
+	s := NewVoucherService(user, vouchers, user, logger)
+
+	t.Run("Create batch", func(t *testing.T) {
+		_, err := s.CreateVoucherBatch(models.VoucherBatchInput{Amount: 10, Count: 1001})
+		assert.EqualError(t, err, "batch is too large: at most 1000 codes")
+
+		past := time.Now().Add(-time.Hour)
+		_, err = s.CreateVoucherBatch(models.VoucherBatchInput{Amount: 10, Count: 1, ExpiresAt: &past})
+		assert.EqualError(t, err, "expiry must be in the future")
+
+		batch, err := s.CreateVoucherBatch(models.VoucherBatchInput{Amount: 25, Currency: "usd", Count: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, batch.MaxUses)
+		assert.Len(t, batch.Codes, 3)
+		for _, code := range batch.Codes {
+			assert.Regexp(t, "^[A-Z2-9]{4}-[A-Z2-9]{4}-[A-Z2-9]{4}-[A-Z2-9]{4}$", code)
+			_, ok := vouchers.vouchers[models.HashVoucherCode(code)]
+			assert.True(t, ok)
+		}
+
+		assert.NotEqual(t, batch.Codes[0], batch.Codes[1])
+	})
+
+	single := vouchers.batches[0]
+	t.Run("Redeem single use", func(t *testing.T) {
+		batch, err := s.CreateVoucherBatch(models.VoucherBatchInput{Amount: 25, Currency: "USD", Count: 1})
+		assert.NoError(t, err)
+
+		_, err = s.RedeemVoucher(models.VoucherRedeemInput{UserId: 1, Code: "AAAA-AAAA-AAAA-AAAA"})
+		assert.ErrorIs(t, err, models.ErrVoucherNotFound)
+
+		// codes may be typed without dashes and in lower case
+		code := strings.ToLower(strings.ReplaceAll(batch.Codes[0], "-", ""))
+		redemption, err := s.RedeemVoucher(models.VoucherRedeemInput{UserId: 1, Code: code})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(25), redemption.Balance)
+		assert.Equal(t, "USD", redemption.Currency)
+
+		_, err = s.RedeemVoucher(models.VoucherRedeemInput{UserId: 2, Code: batch.Codes[0]})
+		assert.ErrorIs(t, err, models.ErrVoucherNotRedeemable)
+		assert.EqualError(t, err, "voucher can not be redeemed: voucher is used up")
+	})
+
+	t.Run("Redeem multi use", func(t *testing.T) {
+		batch, err := s.CreateVoucherBatch(models.VoucherBatchInput{Amount: 5, Currency: "USD", Count: 1,
+			MaxUses: 2})
+		assert.NoError(t, err)
+
+		_, err = s.RedeemVoucher(models.VoucherRedeemInput{UserId: 1, Code: batch.Codes[0]})
+		assert.NoError(t, err)
+
+		_, err = s.RedeemVoucher(models.VoucherRedeemInput{UserId: 1, Code: batch.Codes[0]})
+		assert.EqualError(t, err, "voucher can not be redeemed: voucher is already redeemed by the user")
+
+		redemption, err := s.RedeemVoucher(models.VoucherRedeemInput{UserId: 2, Code: batch.Codes[0]})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(5), redemption.Balance)
+	})
+
+	t.Run("Redeem expired", func(t *testing.T) {
+		soon := time.Now().Add(time.Hour)
+		batch, err := s.CreateVoucherBatch(models.VoucherBatchInput{Amount: 5, Currency: "USD", Count: 1,
+			ExpiresAt: &soon})
+		assert.NoError(t, err)
+
+		voucher := vouchers.vouchers[models.HashVoucherCode(batch.Codes[0])]
+		expired := time.Now().Add(-time.Minute)
+		voucher.ExpiresAt = &expired
+		vouchers.batches[batch.ID-1].ExpiresAt = &expired
+
+		_, err = s.RedeemVoucher(models.VoucherRedeemInput{UserId: 1, Code: batch.Codes[0]})
+		assert.EqualError(t, err, "voucher can not be redeemed: voucher is expired")
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		report, err := s.GetVoucherReport("usd")
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", report.Currency)
+		assert.Len(t, report.Batches, 4)
+		assert.Equal(t, float32(3*25+25+2*5+5), report.IssuedValue)
+		assert.Equal(t, float32(25+2*5), report.RedeemedValue)
+		assert.Equal(t, float32(5), report.ExpiredValue)
+		assert.Equal(t, float32(3*25), report.OutstandingValue)
+		assert.Equal(t, single.ID, report.Batches[0].ID)
+		assert.Equal(t, 2, report.Batches[2].Redemptions)
+
+		balance, err := NewUserService(user, user, user, testPayments(user), nil, logger).GetBalance(1, "")
+		assert.NoError(t, err)
+		assert.Equal(t, float32(30), balance.Wallets[0].Balance)
+	})
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Account statuses. Debit blocked and frozen accounts reject debits, frozen accounts
+// may also reject credits. Closed accounts reject everything and can not be reopened
+const (
+	AccountActive       = "active"
+	AccountDebitBlocked = "debit_blocked"
+	AccountFrozen       = "frozen"
+	AccountClosed       = "closed"
+)
+
+type AccountStatusInput struct {
+	UserId int    `json:"-"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	// BlockCredits makes frozen account reject credits too
+	BlockCredits bool `json:"block_credits"`
+	// FinalPayout debits all remaining money when account is closed
+	FinalPayout bool   `json:"final_payout"`
+	Operator    string `json:"-"`
+}
+
+type Account struct {
+	UserId          int                   `json:"user_id" db:"id"`
+	Status          string                `json:"status" db:"status"`
+	CreditsBlocked  bool                  `json:"credits_blocked" db:"credits_blocked"`
+	Reason          string                `json:"reason,omitempty" db:"status_reason"`
+	Actor           string                `json:"actor,omitempty" db:"status_actor"`
+	StatusChangedAt *time.Time            `json:"status_changed_at,omitempty" db:"status_changed_at"`
+	History         []AccountStatusChange `json:"history,omitempty" db:"-"`
+}
+
+type AccountStatusChange struct {
+	Status         string    `json:"status" db:"status"`
+	CreditsBlocked bool      `json:"credits_blocked" db:"credits_blocked"`
+	Reason         string    `json:"reason,omitempty" db:"reason"`
+	Actor          string    `json:"actor" db:"actor"`
+	Date           time.Time `json:"date" db:"date"`
+}
+
+func (i *AccountStatusInput) Validate() error {
+	if i.Operator == "" {
+		return errors.New("operator is required")
+	}
+
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	switch i.Status {
+	case AccountActive, AccountDebitBlocked, AccountFrozen, AccountClosed:
+	default:
+		return fmt.Errorf("unsupported status %q", i.Status)
+	}
+
+	if i.Status != AccountActive && i.Reason == "" {
+		return errors.New("reason is required")
+	}
+
+	if len(i.Reason) > maxCommentLength {
+		return fmt.Errorf("reason is longer than %d characters", maxCommentLength)
+	}
+
+	if i.BlockCredits && i.Status != AccountFrozen {
+		return errors.New("credits can be blocked only for frozen account")
+	}
+
+	if i.FinalPayout && i.Status != AccountClosed {
+		return errors.New("final payout is allowed only when account is closed")
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Directions of adjustments
+const (
+	AdjustmentCredit = "credit"
+	AdjustmentDebit  = "debit"
+)
+
+// Statuses of adjustments, only pending adjustments can be reviewed
+const (
+	AdjustmentPending  = "pending"
+	AdjustmentApproved = "approved"
+	AdjustmentRejected = "rejected"
+)
+
+// Actions saved in adjustment audit trail
+const (
+	AdjustmentProposed = "proposed"
+)
+
+// Lengths of adjustments columns
+const (
+	maxReasonLength = 200
+	maxTicketLength = 64
+)
+
+// ErrSameOperator is returned when the operator who made a change reviews it, it is wrapped with the change
+var ErrSameOperator = errors.New("must be reviewed by another operator")
+
+type AdjustmentInput struct {
+	UserId    int     `json:"user_id"`
+	Direction string  `json:"direction"`
+	Amount    float32 `json:"amount"`
+	Currency  string  `json:"currency"`
+	Reason    string  `json:"reason"`
+	Ticket    string  `json:"ticket"`
+	Operator  string  `json:"-"`
+}
+
+type AdjustmentReview struct {
+	Comment  string `json:"comment"`
+	Operator string `json:"-"`
+}
+
+type Adjustment struct {
+	ID            int               `json:"id" db:"id"`
+	UserId        int               `json:"user_id" db:"user_id"`
+	Direction     string            `json:"direction" db:"direction"`
+	Amount        float32           `json:"amount" db:"amount"`
+	Currency      string            `json:"currency" db:"currency"`
+	Reason        string            `json:"reason" db:"reason"`
+	Ticket        string            `json:"ticket" db:"ticket"`
+	Status        string            `json:"status" db:"status"`
+	CreatedBy     string            `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+	ReviewedBy    string            `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt    *time.Time        `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewComment string            `json:"review_comment,omitempty" db:"review_comment"`
+	BalanceAfter  *float32          `json:"balance_after,omitempty" db:"balance_after"`
+	Events        []AdjustmentEvent `json:"events,omitempty" db:"-"`
+}
+
+// AdjustmentEvent is a record of adjustment audit trail
+type AdjustmentEvent struct {
+	Action  string    `json:"action" db:"action"`
+	Actor   string    `json:"actor" db:"actor"`
+	Comment string    `json:"comment,omitempty" db:"comment"`
+	Date    time.Time `json:"date" db:"date"`
+}
+
+// Validate checks adjustment input, currency is normalized in place
+func (i *AdjustmentInput) Validate() error {
+	if i.Operator == "" {
+		return errors.New("operator is required")
+	}
+
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.Direction != AdjustmentCredit && i.Direction != AdjustmentDebit {
+		return fmt.Errorf("unsupported direction %q", i.Direction)
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	if i.Reason == "" {
+		return errors.New("reason is required")
+	}
+
+	if len(i.Reason) > maxReasonLength {
+		return fmt.Errorf("reason is longer than %d characters", maxReasonLength)
+	}
+
+	if len(i.Ticket) > maxTicketLength {
+		return fmt.Errorf("ticket is longer than %d characters", maxTicketLength)
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
+
+func (r *AdjustmentReview) Validate() error {
+	if r.Operator == "" {
+		return errors.New("operator is required")
+	}
+
+	if len(r.Comment) > maxCommentLength {
+		return fmt.Errorf("comment is longer than %d characters", maxCommentLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TransactionDateLayout is the layout dates of transactions are written and hashed with
+const TransactionDateLayout = "01-02-2006 15:04:05"
+
+// Reasons of chain breaks
+const (
+	ChainHashMismatch     = "hash does not match transaction content"
+	ChainPrevHashMismatch = "prev_hash does not match hash of the previous transaction"
+	ChainNotHashed        = "transaction is not hashed"
+	ChainHeadMismatch     = "wallet head does not match hash of the last transaction"
+)
+
+// ChainLink is a transaction as it is hashed. Transactions of every wallet form a chain,
+// each of them includes hash of the previous one, the first one has empty prev_hash
+type ChainLink struct {
+	ID           int       `db:"id"`
+	UserId       int       `db:"user_id"`
+	Currency     string    `db:"currency"`
+	Direction    int       `db:"direction"`
+	Amount       float32   `db:"amount"`
+	Operation    string    `db:"operation"`
+	Date         time.Time `db:"date"`
+	BalanceAfter float32   `db:"balance_after"`
+	LinkId       string    `db:"link_id"`
+	PrevHash     string    `db:"prev_hash"`
+	Hash         string    `db:"hash"`
+}
+
+// ComputeHash returns hex encoded sha256 of the link content and its prev_hash
+func (l ChainLink) ComputeHash() string {
+	content := fmt.Sprintf("%s|%d|%s|%d|%f|%s|%s|%f|%s", l.PrevHash, l.UserId, l.Currency, l.Direction, l.Amount,
+		l.Operation, l.Date.Format(TransactionDateLayout), l.BalanceAfter, l.LinkId)
+
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainBreak is the first transaction where the chain is broken
+type ChainBreak struct {
+	TransactionId int    `json:"transaction_id,omitempty"`
+	UserId        int    `json:"user_id"`
+	Currency      string `json:"currency"`
+	Reason        string `json:"reason"`
+}
+
+type ChainVerification struct {
+	Valid        bool `json:"valid"`
+	Transactions int  `json:"transactions"`
+	Wallets      int  `json:"wallets"`
+	// Unchained are transactions written before hashing was introduced, they precede the chain of their wallet
+	Unchained  int         `json:"unchained"`
+	Break      *ChainBreak `json:"break,omitempty"`
+	VerifiedAt time.Time   `json:"verified_at"`
+}
+
+// ChainHead is the hash of the last transaction of the wallet
+type ChainHead struct {
+	UserId   int    `json:"user_id" db:"user_id"`
+	Currency string `json:"currency" db:"currency"`
+	Hash     string `json:"hash" db:"last_hash"`
+}
+
+// Checkpoint is a signed snapshot of all chain heads. Checkpoints are exported outside of the database,
+// so rewriting the whole chain can be detected too
+type Checkpoint struct {
+	CreatedAt         time.Time   `json:"created_at"`
+	LastTransactionId int         `json:"last_transaction_id"`
+	Heads             []ChainHead `json:"heads"`
+	// Root is hex encoded sha256 of all heads
+	Root      string `json:"root"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// ComputeRoot returns hex encoded sha256 of the checkpoint heads
+func (c Checkpoint) ComputeRoot() string {
+	var b strings.Builder
+	for _, head := range c.Heads {
+		fmt.Fprintf(&b, "%d|%s|%s\n", head.UserId, head.Currency, head.Hash)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignedContent is the message which is signed by checkpoint signature
+func (c Checkpoint) SignedContent() []byte {
+	return []byte(fmt.Sprintf("%s|%d|%s", c.CreatedAt.UTC().Format(time.RFC3339Nano), c.LastTransactionId, c.Root))
+}
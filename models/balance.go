@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// BalancePoint is a user`s balance at the end of one history interval
+type BalancePoint struct {
+	Date    time.Time `json:"date" db:"date"`
+	Balance float32   `json:"balance" db:"balance"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Types of batch items
+const (
+	BatchTopUp    = "top-up"
+	BatchDebit    = "debit"
+	BatchTransfer = "transfer"
+)
+
+// Batch modes. Atomic batch is applied in a single transaction,
+// best effort batch applies every item separately
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "best_effort"
+)
+
+// Statuses of batch item results
+const (
+	BatchItemOk         = "ok"
+	BatchItemDuplicate  = "duplicate"
+	BatchItemFailed     = "failed"
+	BatchItemRolledBack = "rolled_back"
+)
+
+// ErrBatchTooLarge is returned when batch has more items than allowed
+var ErrBatchTooLarge = errors.New("batch is too large")
+
+// maxIdempotencyKeyLength matches idempotency_keys.key column
+const maxIdempotencyKeyLength = 64
+
+// maxCommentLength matches transactions.operation column
+const maxCommentLength = 255
+
+type BatchItem struct {
+	Type           string  `json:"type"`
+	IdempotencyKey string  `json:"idempotency_key"`
+	UserId         int     `json:"user_id"`
+	ToId           int     `json:"to_id,omitempty"`
+	Amount         float32 `json:"amount"`
+	Currency       string  `json:"currency"`
+	Comment        string  `json:"comment,omitempty"`
+}
+
+type BatchInput struct {
+	Mode  string      `json:"mode"`
+	Items []BatchItem `json:"items"`
+}
+
+type BatchResult struct {
+	Index          int     `json:"index"`
+	IdempotencyKey string  `json:"idempotency_key,omitempty"`
+	Status         string  `json:"status"`
+	Balance        float32 `json:"balance"`
+	Error          string  `json:"error,omitempty"`
+}
+
+type BatchOutput struct {
+	Mode    string        `json:"mode"`
+	Applied int           `json:"applied"`
+	Failed  int           `json:"failed"`
+	Results []BatchResult `json:"results"`
+}
+
+// Validate checks batch mode and every item, currencies are normalized in place
+func (b *BatchInput) Validate() error {
+	if b.Mode != BatchAtomic && b.Mode != BatchBestEffort {
+		return fmt.Errorf("unsupported batch mode %q", b.Mode)
+	}
+
+	if len(b.Items) == 0 {
+		return errors.New("batch is empty")
+	}
+
+	for i := range b.Items {
+		if err := b.Items[i].Validate(); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *BatchItem) Validate() error {
+	if i.Type != BatchTopUp && i.Type != BatchDebit && i.Type != BatchTransfer {
+		return fmt.Errorf("unsupported type %q", i.Type)
+	}
+
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.Type == BatchTransfer && (i.ToId <= 0 || i.ToId == i.UserId) {
+		return errors.New("incorrect to id")
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	if len(i.IdempotencyKey) > maxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key is longer than %d characters", maxIdempotencyKeyLength)
+	}
+
+	if len(i.Comment) > maxCommentLength {
+		return fmt.Errorf("comment is longer than %d characters", maxCommentLength)
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Hold statuses. Active hold reserves money of its wallet, captured hold is debited and released hold
+// returns the money to available balance
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+)
+
+// ErrHoldNotFound is returned when there is no hold with the id
+var ErrHoldNotFound = errors.New("hold not found")
+
+type CreditLimitInput struct {
+	UserId   int    `json:"-"`
+	Currency string `json:"currency"`
+	// CreditLimit is how far below zero the wallet may go, zero forbids overdraft
+	CreditLimit float32 `json:"credit_limit"`
+}
+
+// CreditLine is a wallet with its credit limit. Available = balance - promo + credit limit - held is real money
+// which may be spent, overdraft is the part of credit limit in use
+type CreditLine struct {
+	UserId      int     `json:"user_id" db:"user_id"`
+	Currency    string  `json:"currency" db:"currency"`
+	Balance     float32 `json:"balance" db:"balance"`
+	CreditLimit float32 `json:"credit_limit" db:"credit_limit"`
+	Held        float32 `json:"held" db:"held"`
+	Available   float32 `json:"available" db:"available"`
+	Overdraft   float32 `json:"overdraft" db:"overdraft"`
+	// Charged is the sum of overdraft fees and interest charged to the wallet
+	Charged float32 `json:"charged" db:"charged"`
+}
+
+// OverdraftReport lists overdrawn wallets of one currency
+type OverdraftReport struct {
+	Currency       string       `json:"currency"`
+	Wallets        []CreditLine `json:"wallets"`
+	TotalOverdraft float32      `json:"total_overdraft"`
+	TotalCharged   float32      `json:"total_charged"`
+}
+
+type HoldInput struct {
+	UserId   int     `json:"user_id"`
+	Amount   float32 `json:"amount"`
+	Currency string  `json:"currency"`
+	Comment  string  `json:"comment"`
+}
+
+type Hold struct {
+	ID        int        `json:"id" db:"id"`
+	UserId    int        `json:"user_id" db:"user_id"`
+	Amount    float32    `json:"amount" db:"amount"`
+	Currency  string     `json:"currency" db:"currency"`
+	Comment   string     `json:"comment,omitempty" db:"comment"`
+	Status    string     `json:"status" db:"status"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+}
+
+// OverdraftCharge is the daily fee and interest charged for the negative balance of a wallet
+type OverdraftCharge struct {
+	UserId   int       `json:"user_id" db:"user_id"`
+	Currency string    `json:"currency" db:"currency"`
+	Date     time.Time `json:"date" db:"date"`
+	Balance  float32   `json:"balance" db:"balance"`
+	Amount   float32   `json:"amount" db:"amount"`
+}
+
+// Validate checks credit limit input, currency is normalized in place
+func (i *CreditLimitInput) Validate() error {
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.CreditLimit < 0 {
+		return errors.New("credit limit must not be negative")
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
+
+// Validate checks hold input, currency is normalized in place
+func (i *HoldInput) Validate() error {
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	if len(i.Comment) > maxCommentLength {
+		return fmt.Errorf("comment is longer than %d characters", maxCommentLength)
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
+
+// CaptureKey links the debit of captured hold to it
+func (h Hold) CaptureKey() string {
+	return fmt.Sprintf("hold-%d", h.ID)
+}
+
+// ChargeKey links the debit of charge to its wallet and day, e.g. "overdraft-3-EUR-2023-08-15"
+func (c OverdraftCharge) ChargeKey() string {
+	return fmt.Sprintf("overdraft-%d-%s-%s", c.UserId, c.Currency, c.Date.Format(time.DateOnly))
+}
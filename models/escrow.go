@@ -0,0 +1,134 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Statuses of escrows. Held money is kept by the escrow account until it is released to the recipient
+// or refunded to the sender, released and refunded escrows are final
+const (
+	EscrowHeld     = "held"
+	EscrowReleased = "released"
+	EscrowRefunded = "refunded"
+)
+
+// Types of escrow events
+const (
+	EscrowCreated   = "created"
+	EscrowConfirmed = "confirmed"
+	EscrowCancelled = "cancelled"
+	EscrowExpired   = "expired"
+)
+
+// escrowTransitions maps statuses to events allowed in them and to the statuses the events lead to
+var escrowTransitions = map[string]map[string]string{
+	EscrowHeld: {
+		EscrowConfirmed: EscrowReleased,
+		EscrowCancelled: EscrowRefunded,
+		EscrowExpired:   EscrowRefunded,
+	},
+}
+
+var (
+	// ErrEscrowNotFound is returned when there is no escrow with the id
+	ErrEscrowNotFound = errors.New("escrow not found")
+	// ErrEscrowNotAllowed is returned when the escrow can not change by the event or by the user
+	ErrEscrowNotAllowed = errors.New("escrow change is not allowed")
+)
+
+type EscrowInput struct {
+	SenderId    int     `json:"sender_id"`
+	RecipientId int     `json:"recipient_id"`
+	Amount      float32 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Description string  `json:"description"`
+	// ExpiresAt is escrow.ttl after the escrow is created by default, held escrow is refunded then
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// EscrowActionInput names the party which confirms or cancels the escrow
+type EscrowActionInput struct {
+	UserId int `json:"user_id"`
+}
+
+// Escrow is money the sender transferred to the escrow account for the recipient, the sender confirms
+// the escrow to release it, the recipient cancels it to refund it
+type Escrow struct {
+	ID          int           `json:"id" db:"id"`
+	SenderId    int           `json:"sender_id" db:"sender_id"`
+	RecipientId int           `json:"recipient_id" db:"recipient_id"`
+	Amount      float32       `json:"amount" db:"amount"`
+	Currency    string        `json:"currency" db:"currency"`
+	Description string        `json:"description,omitempty" db:"description"`
+	Status      string        `json:"status" db:"status"`
+	ExpiresAt   time.Time     `json:"expires_at" db:"expires_at"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	ClosedAt    *time.Time    `json:"closed_at,omitempty" db:"closed_at"`
+	Events      []EscrowEvent `json:"events,omitempty" db:"-"`
+}
+
+// EscrowEvent is a record of a change of escrow, Status is the status the change led to
+type EscrowEvent struct {
+	ID       int    `json:"id" db:"id"`
+	EscrowId int    `json:"escrow_id" db:"escrow_id"`
+	Type     string `json:"type" db:"type"`
+	// UserId is the party which made the change, 0 when the service did it
+	UserId int       `json:"user_id" db:"user_id"`
+	Status string    `json:"status" db:"status"`
+	Date   time.Time `json:"date" db:"date"`
+}
+
+// Validate checks escrow input, currency is normalized in place
+func (i *EscrowInput) Validate() error {
+	if i.SenderId <= 0 || i.RecipientId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.SenderId == i.RecipientId {
+		return errors.New("sender and recipient must differ")
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+
+	if len(i.Description) > maxCommentLength {
+		return fmt.Errorf("description is longer than %d characters", maxCommentLength)
+	}
+
+	return nil
+}
+
+// Next returns the status escrow changes to by the event made by the user, 0 is the service.
+// Only the sender confirms escrow and only the recipient cancels it
+func (e Escrow) Next(event string, userId int) (string, error) {
+	status, ok := escrowTransitions[e.Status][event]
+	if !ok {
+		return "", fmt.Errorf("%w: escrow is %s", ErrEscrowNotAllowed, e.Status)
+	}
+
+	switch {
+	case event == EscrowConfirmed && userId != e.SenderId:
+		return "", fmt.Errorf("%w: only the sender confirms escrow", ErrEscrowNotAllowed)
+	case event == EscrowCancelled && userId != e.RecipientId:
+		return "", fmt.Errorf("%w: only the recipient cancels escrow", ErrEscrowNotAllowed)
+	case event == EscrowExpired && userId != 0:
+		return "", fmt.Errorf("%w: escrow expires by itself", ErrEscrowNotAllowed)
+	}
+
+	return status, nil
+}
+
+// EscrowKey links all transactions of the escrow
+func (e Escrow) EscrowKey() string {
+	return fmt.Sprintf("escrow-%d", e.ID)
+}
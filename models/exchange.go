@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type ExchangeQuoteInput struct {
+	UserId int     `json:"user_id"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float32 `json:"amount"`
+}
+
+// ExchangeQuote locks exchange rate for the user until ExpiresAt
+type ExchangeQuote struct {
+	ID       string  `json:"id"`
+	UserId   int     `json:"user_id" db:"user_id"`
+	From     string  `json:"from" db:"from_currency"`
+	To       string  `json:"to" db:"to_currency"`
+	Amount   float32 `json:"amount"`
+	Rate     float32 `json:"rate"`
+	Fee      float32 `json:"fee"`
+	ToAmount float32 `json:"to_amount" db:"to_amount"`
+	// FromFee is charged by fee rules in from currency on top of Amount, Fee is the spread in to currency
+	FromFee   float32    `json:"from_fee,omitempty" db:"from_fee"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"-" db:"used_at"`
+}
+
+type ExchangeInput struct {
+	UserId  int    `json:"user_id"`
+	QuoteId string `json:"quote_id"`
+}
+
+// ExchangeResult contains balances of both wallets after the exchange.
+// LinkId links all transactions written by the exchange
+type ExchangeResult struct {
+	UserId int    `json:"user_id"`
+	LinkId string `json:"link_id"`
+	From   Wallet `json:"from"`
+	To     Wallet `json:"to"`
+}
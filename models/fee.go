@@ -0,0 +1,168 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Operations charged by fee rules
+const (
+	FeeTransfer = "transfer"
+	FeeExchange = "exchange"
+)
+
+// ErrFeeRuleNotFound is returned when there is no fee rule with the id
+var ErrFeeRuleNotFound = errors.New("fee rule not found")
+
+type FeeRuleInput struct {
+	// Operation is transfer or exchange
+	Operation string `json:"operation"`
+	// UserId restricts the rule to one client, the rule applies to every client when it is empty
+	UserId int `json:"user_id,omitempty"`
+	// Currency restricts the rule to one currency, the rule applies to every currency when it is empty
+	Currency string `json:"currency,omitempty"`
+	// MinAmount and MaxAmount bracket amounts the rule applies to, MaxAmount is excluded, zero leaves it open
+	MinAmount float32 `json:"min_amount"`
+	MaxAmount float32 `json:"max_amount"`
+	// Rate is a part of the amount taken as a fee, e.g. 0.01 is 1%
+	Rate float32 `json:"rate"`
+	// Fixed is added to the fee, it is in the currency of the operation
+	Fixed float32 `json:"fixed"`
+}
+
+type FeeRule struct {
+	ID        int       `json:"id" db:"id"`
+	Operation string    `json:"operation" db:"operation"`
+	UserId    int       `json:"user_id,omitempty" db:"user_id"`
+	Currency  string    `json:"currency,omitempty" db:"currency"`
+	MinAmount float32   `json:"min_amount" db:"min_amount"`
+	MaxAmount float32   `json:"max_amount" db:"max_amount"`
+	Rate      float32   `json:"rate" db:"rate"`
+	Fixed     float32   `json:"fixed" db:"fixed"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// FeeQuoteInput is an operation to be charged. For exchanges it is the exchanged amount in the source currency
+type FeeQuoteInput struct {
+	Operation string  `json:"operation" query:"operation"`
+	UserId    int     `json:"user_id" query:"user_id"`
+	Amount    float32 `json:"amount" query:"amount"`
+	Currency  string  `json:"currency" query:"currency"`
+}
+
+// FeeQuote is the fee of an operation, it is charged on top of the amount. RuleId is empty when no rule applies
+type FeeQuote struct {
+	Operation string  `json:"operation"`
+	UserId    int     `json:"user_id"`
+	Amount    float32 `json:"amount"`
+	Currency  string  `json:"currency"`
+	RuleId    int     `json:"rule_id,omitempty"`
+	Fee       float32 `json:"fee"`
+	Total     float32 `json:"total"`
+}
+
+// Validate checks fee rule input, currency is normalized in place
+func (i *FeeRuleInput) Validate() error {
+	if err := validateFeeOperation(i.Operation); err != nil {
+		return err
+	}
+
+	if i.UserId < 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.Currency != "" {
+		currency, err := ParseCurrency(i.Currency)
+		if err != nil {
+			return err
+		}
+
+		i.Currency = currency
+	}
+
+	if i.MinAmount < 0 || i.MaxAmount < 0 {
+		return errors.New("amount bracket must not be negative")
+	}
+
+	if i.MaxAmount != 0 && i.MaxAmount <= i.MinAmount {
+		return errors.New("max amount must be greater than min amount")
+	}
+
+	if i.Rate < 0 || i.Rate >= 1 {
+		return errors.New("rate must be in [0, 1)")
+	}
+
+	if i.Fixed < 0 {
+		return errors.New("fixed fee must not be negative")
+	}
+
+	if i.Rate == 0 && i.Fixed == 0 {
+		return errors.New("rule charges nothing")
+	}
+
+	return nil
+}
+
+// Validate checks fee quote input, currency is normalized in place
+func (i *FeeQuoteInput) Validate() error {
+	if err := validateFeeOperation(i.Operation); err != nil {
+		return err
+	}
+
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
+
+// Applies tells if the rule charges the operation
+func (r FeeRule) Applies(input FeeQuoteInput) bool {
+	if r.Operation != input.Operation {
+		return false
+	}
+
+	if (r.UserId != 0 && r.UserId != input.UserId) || (r.Currency != "" && r.Currency != input.Currency) {
+		return false
+	}
+
+	return input.Amount >= r.MinAmount && (r.MaxAmount == 0 || input.Amount < r.MaxAmount)
+}
+
+// MoreSpecific tells if the rule wins over other when both apply: client rule wins over general one,
+// then currency rule over any currency one, then the rule with the higher bracket, then the newer rule
+func (r FeeRule) MoreSpecific(other FeeRule) bool {
+	if (r.UserId != 0) != (other.UserId != 0) {
+		return r.UserId != 0
+	}
+
+	if (r.Currency != "") != (other.Currency != "") {
+		return r.Currency != ""
+	}
+
+	if r.MinAmount != other.MinAmount {
+		return r.MinAmount > other.MinAmount
+	}
+
+	return r.ID > other.ID
+}
+
+func validateFeeOperation(operation string) error {
+	switch operation {
+	case FeeTransfer, FeeExchange:
+		return nil
+	default:
+		return fmt.Errorf("unsupported operation %q", operation)
+	}
+}
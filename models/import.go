@@ -0,0 +1,216 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of import jobs
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportColumns are columns of import CSV file, comment and external_ref may be omitted
+var ImportColumns = []string{"user_id", "amount", "type", "comment", "external_ref"}
+
+// importKeyPrefix puts external_ref into its own namespace of idempotency keys, so an import line is never
+// taken for a batch item with the same key
+const importKeyPrefix = "import:"
+
+// ErrInvalidImport is returned when import file has invalid lines
+var ErrInvalidImport = errors.New("import file is invalid")
+
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportErrors are stored as jsonb
+type ImportErrors []ImportLineError
+
+func (e ImportErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(e)
+}
+
+func (e *ImportErrors) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, e)
+	case string:
+		return json.Unmarshal([]byte(src), e)
+	case nil:
+		*e = nil
+		return nil
+	}
+
+	return fmt.Errorf("cannot scan %T into import errors", src)
+}
+
+// ImportLine is a parsed line of import file
+type ImportLine struct {
+	Line int
+	Item BatchItem
+}
+
+// ImportReport is a result of import file validation
+type ImportReport struct {
+	Lines  int          `json:"lines"`
+	Valid  int          `json:"valid"`
+	Errors ImportErrors `json:"errors"`
+}
+
+type ImportJob struct {
+	ID         string       `json:"id" db:"id"`
+	Status     string       `json:"status" db:"status"`
+	Total      int          `json:"total" db:"total"`
+	Processed  int          `json:"processed" db:"processed"`
+	Applied    int          `json:"applied" db:"applied"`
+	Failed     int          `json:"failed" db:"failed"`
+	Errors     ImportErrors `json:"errors" db:"errors"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// ParseImport reads import CSV file. Lines are validated one by one, invalid lines
+// are listed in the report. Error is returned only if the file itself can not be read
+func ParseImport(r io.Reader) ([]ImportLine, ImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ImportReport{}, errors.New("import file is empty")
+	}
+	if err != nil {
+		return nil, ImportReport{}, err
+	}
+
+	columns, err := importColumns(header)
+	if err != nil {
+		return nil, ImportReport{}, err
+	}
+
+	var (
+		lines  []ImportLine
+		report = ImportReport{Errors: ImportErrors{}}
+		refs   = make(map[string]int)
+	)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, ImportReport{}, err
+			}
+
+			report.Lines++
+			report.Errors = append(report.Errors, ImportLineError{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+
+		report.Lines++
+		line, _ := reader.FieldPos(0)
+		item, err := parseImportRecord(record, columns)
+		if err == nil && item.IdempotencyKey != "" {
+			if first, ok := refs[item.IdempotencyKey]; ok {
+				err = fmt.Errorf("external_ref is already used on line %d", first)
+			} else {
+				refs[item.IdempotencyKey] = line
+			}
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, ImportLineError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		lines = append(lines, ImportLine{Line: line, Item: item})
+	}
+
+	report.Valid = len(lines)
+	return lines, report, nil
+}
+
+// importColumns maps column names to their positions in the header
+func importColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range ImportColumns[:3] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %q is required", name)
+		}
+	}
+
+	return columns, nil
+}
+
+func parseImportRecord(record []string, columns map[string]int) (BatchItem, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	userId, err := strconv.Atoi(field("user_id"))
+	if err != nil {
+		return BatchItem{}, fmt.Errorf("incorrect user_id %q", field("user_id"))
+	}
+
+	amount, err := strconv.ParseFloat(field("amount"), 32)
+	if err != nil {
+		return BatchItem{}, fmt.Errorf("incorrect amount %q", field("amount"))
+	}
+
+	ref := field("external_ref")
+	if len(ref) > maxIdempotencyKeyLength-len(importKeyPrefix) {
+		return BatchItem{}, fmt.Errorf("external_ref is longer than %d characters",
+			maxIdempotencyKeyLength-len(importKeyPrefix))
+	}
+
+	item := BatchItem{
+		Type:    field("type"),
+		UserId:  userId,
+		Amount:  float32(amount),
+		Comment: field("comment"),
+	}
+
+	if ref != "" {
+		item.IdempotencyKey = importKeyPrefix + ref
+	}
+
+	// transfers need to_id which is not a column of import file
+	if item.Type == BatchTransfer {
+		return BatchItem{}, fmt.Errorf("unsupported type %q", item.Type)
+	}
+
+	if err := item.Validate(); err != nil {
+		return BatchItem{}, err
+	}
+
+	return item, nil
+}
@@ -1,12 +1,34 @@
 package models
 
+import "errors"
+
 type Input struct {
-	UserId int     `json:"user_id"`
-	Amount float32 `json:"amount"`
+	UserId   int     `json:"user_id"`
+	Amount   float32 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// Validate rejects amounts which are not positive, a negative debit would credit the user
+func (i Input) Validate() error {
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	return nil
 }
 
 type TransferInput struct {
-	ToId   int     `json:"to_id"`
-	UserId int     `json:"user_id"`
-	Amount float32 `json:"amount"`
+	ToId     int     `json:"to_id"`
+	UserId   int     `json:"user_id"`
+	Amount   float32 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// Validate rejects amounts which are not positive, a negative transfer would take money from the recipient
+func (i TransferInput) Validate() error {
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	return nil
 }
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Operations restricted by spending limits
+const (
+	LimitDebit    = "debit"
+	LimitTransfer = "transfer"
+	// LimitAny restricts debits and transfers together
+	LimitAny = "any"
+)
+
+// Periods of spending limits. Operation limit caps a single operation, the others cap the sum of operations
+// within a rolling window ending now
+const (
+	LimitPerOperation = "operation"
+	LimitDaily        = "day"
+	LimitWeekly       = "week"
+	LimitMonthly      = "month"
+)
+
+// LimitWindows maps periods of spending limits to the length of their rolling windows
+var LimitWindows = map[string]time.Duration{
+	LimitDaily:   24 * time.Hour,
+	LimitWeekly:  7 * 24 * time.Hour,
+	LimitMonthly: 30 * 24 * time.Hour,
+}
+
+var (
+	// ErrLimitExceeded is returned when an operation would go over a spending limit
+	ErrLimitExceeded = errors.New("spending limit exceeded")
+	// ErrLimitNotFound is returned when there is no spending limit with the id
+	ErrLimitNotFound = errors.New("spending limit not found")
+)
+
+type LimitInput struct {
+	UserId int `json:"user_id"`
+	// Operation is debit, transfer or any
+	Operation string `json:"operation"`
+	// Period is operation, day, week or month
+	Period string `json:"period"`
+	// CounterpartyId restricts only transfers to this user, all transfers when it is empty
+	CounterpartyId int     `json:"counterparty_id,omitempty"`
+	Amount         float32 `json:"amount"`
+	Currency       string  `json:"currency"`
+}
+
+type Limit struct {
+	ID             int       `json:"id" db:"id"`
+	UserId         int       `json:"user_id" db:"user_id"`
+	Operation      string    `json:"operation" db:"operation"`
+	Period         string    `json:"period" db:"period"`
+	CounterpartyId int       `json:"counterparty_id,omitempty" db:"counterparty_id"`
+	Amount         float32   `json:"amount" db:"amount"`
+	Currency       string    `json:"currency" db:"currency"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// LimitUsage is a spending limit with the amount spent within its window
+type LimitUsage struct {
+	Limit
+	Used      float32 `json:"used" db:"used"`
+	Remaining float32 `json:"remaining" db:"-"`
+}
+
+// Spend is a debit or transfer counted against spending limits
+type Spend struct {
+	UserId         int
+	Operation      string
+	CounterpartyId int
+	Amount         float32
+	Currency       string
+}
+
+// Validate checks limit input, currency is normalized in place
+func (i *LimitInput) Validate() error {
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	switch i.Operation {
+	case LimitDebit, LimitAny:
+		if i.CounterpartyId != 0 {
+			return errors.New("counterparty is allowed only for transfer limit")
+		}
+	case LimitTransfer:
+		if i.CounterpartyId < 0 || i.CounterpartyId == i.UserId {
+			return errors.New("incorrect counterparty id")
+		}
+	default:
+		return fmt.Errorf("unsupported operation %q", i.Operation)
+	}
+
+	if _, ok := LimitWindows[i.Period]; !ok && i.Period != LimitPerOperation {
+		return fmt.Errorf("unsupported period %q", i.Period)
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
+
+// Applies tells if spend is restricted by limit
+func (l Limit) Applies(spend Spend) bool {
+	if l.UserId != spend.UserId || l.Currency != spend.Currency {
+		return false
+	}
+
+	if l.Operation != LimitAny && l.Operation != spend.Operation {
+		return false
+	}
+
+	return l.CounterpartyId == 0 || l.CounterpartyId == spend.CounterpartyId
+}
+
+// String names limit in errors, e.g. "limit #3 of 100.00 EUR per day on transfers to user 2"
+func (l Limit) String() string {
+	period := "per " + l.Period
+	if l.Period == LimitPerOperation {
+		period = "per operation"
+	}
+
+	operation := "debits and transfers"
+	switch l.Operation {
+	case LimitDebit:
+		operation = "debits"
+	case LimitTransfer:
+		operation = "transfers"
+		if l.CounterpartyId != 0 {
+			operation = fmt.Sprintf("transfers to user %d", l.CounterpartyId)
+		}
+	}
+
+	return fmt.Sprintf("limit #%d of %.2f %s %s on %s", l.ID, l.Amount, l.Currency, period, operation)
+}
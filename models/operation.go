@@ -0,0 +1,17 @@
+package models
+
+// Operation describes the transaction written along with a balance change
+type Operation struct {
+	Comment string
+	// LinkId is shared by all transactions of one multi-leg operation, e.g. an exchange
+	LinkId string
+	// Overdraw lets debit go beyond the credit limit of the wallet, overdraft charges are debited even
+	// when nothing is available
+	Overdraw bool
+	// Promo is the part of the amount which is promotional money, credit adds it to the promo part of the wallet
+	// and debit spends it from there. Debit may spend only real money besides it
+	Promo float32
+	// System marks postings the service makes on its own, e.g. overdraft charges and promo expiry. They are made
+	// on frozen and debit blocked accounts too, closed accounts reject them like any other change
+	System bool
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Types of outbox events
+const (
+	// OutboxBalanceChanged is added with every transaction, the payload is the transaction
+	OutboxBalanceChanged = "balance.changed"
+)
+
+// OutboxEvent is an event for external consumers saved in the transaction of the change,
+// it is published at least once after the change is committed
+type OutboxEvent struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// Key orders events of one aggregate, e.g. user id for balance changes
+	Key         string          `json:"key"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+	PublishedAt *time.Time      `json:"published_at,omitempty"`
+	Attempts    int             `json:"attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// OutboxReplayInput selects events published again, events from FromId to ToId, up to the last one
+// if ToId is 0, of the type, all types if it is empty
+type OutboxReplayInput struct {
+	FromId int64  `json:"from_id"`
+	ToId   int64  `json:"to_id,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+func (i OutboxReplayInput) Validate() error {
+	if i.FromId <= 0 {
+		return errors.New("incorrect from id")
+	}
+
+	if i.ToId != 0 && i.ToId < i.FromId {
+		return errors.New("to id must not be less than from id")
+	}
+
+	return nil
+}
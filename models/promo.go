@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Promo grant statuses. Active grant may be spent by purchases until it expires, the rest of expired grant
+// is debited from its wallet
+const (
+	PromoActive  = "active"
+	PromoExpired = "expired"
+)
+
+// maxCampaignIdLength matches promo_grants.campaign_id column
+const maxCampaignIdLength = 64
+
+// ErrPromoGrantNotFound is returned when there is no promo grant with the id
+var ErrPromoGrantNotFound = errors.New("promo grant not found")
+
+type PromoGrantInput struct {
+	UserId     int     `json:"user_id"`
+	Amount     float32 `json:"amount"`
+	Currency   string  `json:"currency"`
+	CampaignId string  `json:"campaign_id"`
+	// ExpiresAt is promo.ttl after the grant by default
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// PromoGrant is promotional money credited to the promo part of a wallet, Remaining is not spent yet
+type PromoGrant struct {
+	ID         int        `json:"id" db:"id"`
+	UserId     int        `json:"user_id" db:"user_id"`
+	Amount     float32    `json:"amount" db:"amount"`
+	Remaining  float32    `json:"remaining" db:"remaining"`
+	Currency   string     `json:"currency" db:"currency"`
+	CampaignId string     `json:"campaign_id" db:"campaign_id"`
+	Status     string     `json:"status" db:"status"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiredAt  *time.Time `json:"expired_at,omitempty" db:"expired_at"`
+}
+
+// Validate checks promo grant input, currency is normalized in place
+func (i *PromoGrantInput) Validate() error {
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+
+	if i.CampaignId == "" {
+		return errors.New("campaign id is required")
+	}
+
+	if len(i.CampaignId) > maxCampaignIdLength {
+		return fmt.Errorf("campaign id is longer than %d characters", maxCampaignIdLength)
+	}
+
+	return nil
+}
+
+// GrantKey links the credit of the grant and the debit of its expired rest
+func (g PromoGrant) GrantKey() string {
+	return fmt.Sprintf("promo-%d", g.ID)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Statuses of reconciliation reports. Mismatched report becomes corrected when its correcting entries are approved
+const (
+	ReconciliationOk         = "ok"
+	ReconciliationMismatched = "mismatched"
+	ReconciliationCorrected  = "corrected"
+)
+
+// ReconciliationTolerance is the largest difference between wallet balance and its transactions
+// which is not reported, balances are stored as floats
+const ReconciliationTolerance = 0.005
+
+// ErrReconciliationNotFound is returned when there is no reconciliation report
+var ErrReconciliationNotFound = errors.New("reconciliation report not found")
+
+// ReconciliationMismatch is a wallet whose balance differs from the sum of its transactions
+type ReconciliationMismatch struct {
+	UserId   int     `json:"user_id" db:"user_id"`
+	Currency string  `json:"currency" db:"currency"`
+	Balance  float32 `json:"balance" db:"balance"`
+	// Expected is the balance recomputed from the transaction log
+	Expected float32 `json:"expected" db:"expected"`
+	// Difference is balance minus expected, the correcting entry has the same amount
+	Difference   float32 `json:"difference" db:"difference"`
+	Transactions int     `json:"transactions" db:"transactions"`
+}
+
+// ReconciliationMismatches are stored as jsonb
+type ReconciliationMismatches []ReconciliationMismatch
+
+func (m ReconciliationMismatches) Value() (driver.Value, error) {
+	if m == nil {
+		return []byte("[]"), nil
+	}
+
+	return json.Marshal(m)
+}
+
+func (m *ReconciliationMismatches) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, m)
+	case string:
+		return json.Unmarshal([]byte(src), m)
+	case nil:
+		*m = nil
+		return nil
+	}
+
+	return fmt.Errorf("cannot scan %T into reconciliation mismatches", src)
+}
+
+type ReconciliationReport struct {
+	ID         int                      `json:"id" db:"id"`
+	Status     string                   `json:"status" db:"status"`
+	Wallets    int                      `json:"wallets" db:"wallets"`
+	Mismatches ReconciliationMismatches `json:"mismatches" db:"mismatches"`
+	CreatedBy  string                   `json:"created_by" db:"created_by"`
+	StartedAt  time.Time                `json:"started_at" db:"started_at"`
+	FinishedAt time.Time                `json:"finished_at" db:"finished_at"`
+	ApprovedBy string                   `json:"approved_by,omitempty" db:"approved_by"`
+	ApprovedAt *time.Time               `json:"approved_at,omitempty" db:"approved_at"`
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Types of scheduled operations
+const (
+	ScheduleTransfer = "transfer"
+	ScheduleDebit    = "debit"
+)
+
+// Statuses of schedules. Only active schedules are executed, cancelled, completed and failed ones are final
+const (
+	ScheduleActive    = "active"
+	SchedulePaused    = "paused"
+	ScheduleCancelled = "cancelled"
+	ScheduleCompleted = "completed"
+	ScheduleFailed    = "failed"
+)
+
+// Statuses of schedule runs
+const (
+	ScheduleRunSucceeded = "succeeded"
+	ScheduleRunFailed    = "failed"
+)
+
+// ErrScheduleNotFound is returned when there is no schedule with the id
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// minScheduleInterval keeps interval schedules from flooding the log
+const minScheduleInterval = time.Minute
+
+type ScheduleInput struct {
+	Type     string  `json:"type"`
+	UserId   int     `json:"user_id"`
+	ToId     int     `json:"to_id,omitempty"`
+	Amount   float32 `json:"amount"`
+	Currency string  `json:"currency"`
+	Comment  string  `json:"comment,omitempty"`
+	// RunAt is the time of one-off schedule or the first occurrence of interval schedule, now by default
+	RunAt *time.Time `json:"run_at,omitempty"`
+	// Cron is an expression of recurring schedule in UTC, e.g. "0 9 1 * *" is monthly
+	Cron string `json:"cron,omitempty"`
+	// Interval between occurrences of recurring schedule, e.g. "24h"
+	Interval string `json:"interval,omitempty"`
+}
+
+type Schedule struct {
+	ID       int     `json:"id" db:"id"`
+	Type     string  `json:"type" db:"type"`
+	UserId   int     `json:"user_id" db:"user_id"`
+	ToId     int     `json:"to_id,omitempty" db:"to_id"`
+	Amount   float32 `json:"amount" db:"amount"`
+	Currency string  `json:"currency" db:"currency"`
+	Comment  string  `json:"comment,omitempty" db:"comment"`
+	Cron     string  `json:"cron,omitempty" db:"cron"`
+	Interval string  `json:"interval,omitempty" db:"repeat_interval"`
+	Status   string  `json:"status" db:"status"`
+	// Occurrence is the planned time of the current occurrence, it is executed at most once
+	Occurrence time.Time `json:"occurrence" db:"occurrence"`
+	// NextRunAt is the time of the next attempt, it is later than Occurrence when the occurrence is retried
+	NextRunAt time.Time `json:"next_run_at" db:"next_run_at"`
+	// Attempts is the number of failed attempts of the current occurrence
+	Attempts  int           `json:"attempts" db:"attempts"`
+	LastError string        `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	Runs      []ScheduleRun `json:"runs,omitempty" db:"-"`
+}
+
+// ScheduleRun is a record of an attempt to execute an occurrence
+type ScheduleRun struct {
+	Occurrence   time.Time `json:"occurrence" db:"occurrence"`
+	Attempt      int       `json:"attempt" db:"attempt"`
+	Status       string    `json:"status" db:"status"`
+	Error        string    `json:"error,omitempty" db:"error"`
+	BalanceAfter *float32  `json:"balance_after,omitempty" db:"balance_after"`
+	Date         time.Time `json:"date" db:"date"`
+}
+
+// Recurring tells if schedule has more than one occurrence
+func (s Schedule) Recurring() bool {
+	return s.Cron != "" || s.Interval != ""
+}
+
+// OccurrenceKey identifies the current occurrence, transactions of the occurrence are linked by it
+func (s Schedule) OccurrenceKey() string {
+	return fmt.Sprintf("schedule-%d-%d", s.ID, s.Occurrence.Unix())
+}
+
+// Validate checks schedule input, currency is normalized in place. Cron expression is checked by the service
+func (i *ScheduleInput) Validate() error {
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	switch i.Type {
+	case ScheduleTransfer:
+		if i.ToId <= 0 {
+			return errors.New("incorrect receiver id")
+		}
+
+		if i.ToId == i.UserId {
+			return errors.New("transfer to the same user")
+		}
+	case ScheduleDebit:
+		if i.ToId != 0 {
+			return errors.New("receiver is allowed only for transfer")
+		}
+	default:
+		return fmt.Errorf("unsupported type %q", i.Type)
+	}
+
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	if len(i.Comment) > maxCommentLength {
+		return fmt.Errorf("comment is longer than %d characters", maxCommentLength)
+	}
+
+	if i.Cron != "" && i.Interval != "" {
+		return errors.New("cron and interval are mutually exclusive")
+	}
+
+	if i.Cron != "" && i.RunAt != nil {
+		return errors.New("run_at is not allowed with cron")
+	}
+
+	if i.Interval != "" {
+		interval, err := time.ParseDuration(i.Interval)
+		if err != nil {
+			return fmt.Errorf("incorrect interval %q", i.Interval)
+		}
+
+		if interval < minScheduleInterval {
+			return fmt.Errorf("interval must be at least %s", minScheduleInterval)
+		}
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
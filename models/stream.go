@@ -0,0 +1,24 @@
+package models
+
+import "errors"
+
+// ErrTooManyStreams is returned when a limit of concurrent balance streams is reached
+var ErrTooManyStreams = errors.New("too many balance streams")
+
+// BalanceEvent is sent to balance stream when a wallet of user is changed. ID is the id of the transaction,
+// a client resumes the stream after it
+type BalanceEvent struct {
+	ID          int            `json:"id"`
+	UserId      int            `json:"user_id"`
+	Balance     Wallet         `json:"balance"`
+	Transaction TransactionDTO `json:"transaction"`
+}
+
+func NewBalanceEvent(t Transaction) BalanceEvent {
+	return BalanceEvent{
+		ID:          t.ID,
+		UserId:      t.UserId,
+		Balance:     Wallet{UserId: t.UserId, Currency: t.Currency, Balance: t.BalanceAfter},
+		Transaction: t.ToTransactionDTO(),
+	}
+}
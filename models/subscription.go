@@ -0,0 +1,171 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Billing periods of plans
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodYear  = "year"
+)
+
+// Statuses of subscriptions. Trialing, active and past due subscriptions are renewed, cancelled ones are final
+const (
+	SubscriptionTrialing  = "trialing"
+	SubscriptionActive    = "active"
+	SubscriptionPastDue   = "past_due"
+	SubscriptionCancelled = "cancelled"
+)
+
+// Types of subscription events
+const (
+	SubscriptionCreated       = "created"
+	SubscriptionRenewed       = "renewed"
+	SubscriptionRenewalFailed = "renewal_failed"
+	SubscriptionPlanChanged   = "plan_changed"
+	SubscriptionEnded         = "cancelled"
+)
+
+var (
+	// ErrPlanNotFound is returned when there is no plan with the id
+	ErrPlanNotFound = errors.New("plan not found")
+	// ErrSubscriptionNotFound is returned when there is no subscription with the id
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// Limits of plans
+const (
+	maxPlanName  = 100
+	maxTrialDays = 365
+)
+
+type PlanInput struct {
+	Name     string  `json:"name"`
+	Price    float32 `json:"price"`
+	Currency string  `json:"currency"`
+	// Period is day, week, month or year
+	Period string `json:"period"`
+	// TrialDays are free days before the first charge
+	TrialDays int `json:"trial_days,omitempty"`
+}
+
+type Plan struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Price     float32   `json:"price" db:"price"`
+	Currency  string    `json:"currency" db:"currency"`
+	Period    string    `json:"period" db:"period"`
+	TrialDays int       `json:"trial_days" db:"trial_days"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type SubscriptionInput struct {
+	UserId int `json:"user_id"`
+	PlanId int `json:"plan_id"`
+}
+
+type PlanChangeInput struct {
+	PlanId int `json:"plan_id"`
+}
+
+type CancelSubscriptionInput struct {
+	// AtPeriodEnd keeps subscription until the end of the paid period, otherwise it is cancelled right away
+	AtPeriodEnd bool `json:"at_period_end"`
+}
+
+type Subscription struct {
+	ID     int    `json:"id" db:"id"`
+	UserId int    `json:"user_id" db:"user_id"`
+	PlanId int    `json:"plan_id" db:"plan_id"`
+	Status string `json:"status" db:"status"`
+	// PeriodStart and PeriodEnd bound the current paid or trial period
+	PeriodStart time.Time `json:"period_start" db:"period_start"`
+	PeriodEnd   time.Time `json:"period_end" db:"period_end"`
+	// NextAttemptAt is the time of the next renewal charge, it is later than PeriodEnd while the renewal is retried
+	NextAttemptAt     time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+	CancelAtPeriodEnd bool      `json:"cancel_at_period_end" db:"cancel_at_period_end"`
+	// Attempts is the number of failed renewal charges of the current period
+	Attempts    int                 `json:"attempts" db:"attempts"`
+	LastError   string              `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+	CancelledAt *time.Time          `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	Events      []SubscriptionEvent `json:"events,omitempty" db:"-"`
+}
+
+// SubscriptionEvent is a record of a change of subscription, other services read them in id order
+type SubscriptionEvent struct {
+	ID             int    `json:"id" db:"id"`
+	SubscriptionId int    `json:"subscription_id" db:"subscription_id"`
+	UserId         int    `json:"user_id" db:"user_id"`
+	PlanId         int    `json:"plan_id" db:"plan_id"`
+	Type           string `json:"type" db:"type"`
+	// Amount is charged from the wallet, negative amount is refunded to it
+	Amount   float32   `json:"amount" db:"amount"`
+	Currency string    `json:"currency" db:"currency"`
+	Error    string    `json:"error,omitempty" db:"error"`
+	Date     time.Time `json:"date" db:"date"`
+}
+
+// Validate checks plan input, name is trimmed and currency is normalized in place
+func (i *PlanInput) Validate() error {
+	i.Name = strings.TrimSpace(i.Name)
+	if i.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(i.Name) > maxPlanName {
+		return fmt.Errorf("name is longer than %d characters", maxPlanName)
+	}
+
+	if i.Price <= 0 {
+		return errors.New("price must be positive")
+	}
+
+	switch i.Period {
+	case PeriodDay, PeriodWeek, PeriodMonth, PeriodYear:
+	default:
+		return fmt.Errorf("unsupported period %q", i.Period)
+	}
+
+	if i.TrialDays < 0 || i.TrialDays > maxTrialDays {
+		return fmt.Errorf("trial days must be between 0 and %d", maxTrialDays)
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+	return nil
+}
+
+// PeriodEnd returns the end of the period of plan starting at start
+func (p Plan) PeriodEnd(start time.Time) time.Time {
+	switch p.Period {
+	case PeriodDay:
+		return start.AddDate(0, 0, 1)
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodYear:
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// Renewing tells if subscription is charged at the end of its period
+func (s Subscription) Renewing() bool {
+	return s.Status == SubscriptionTrialing || s.Status == SubscriptionActive || s.Status == SubscriptionPastDue
+}
+
+// PeriodKey identifies the charge of the current period, its transaction is linked by it
+func (s Subscription) PeriodKey() string {
+	return fmt.Sprintf("subscription-%d-%d", s.ID, s.PeriodStart.Unix())
+}
@@ -3,29 +3,38 @@ package models
 import "time"
 
 type Transaction struct {
-	ID        int       `json:"id"`
-	UserId    int       `json:"user_id" db:"user_id"`
-	Amount    float32   `json:"amount"`
-	Operation string    `json:"operation"`
-	Date      time.Time `json:"date"`
+	ID           int       `json:"id"`
+	UserId       int       `json:"user_id" db:"user_id"`
+	Amount       float32   `json:"amount"`
+	Currency     string    `json:"currency"`
+	BalanceAfter float32   `json:"balance_after" db:"balance_after"`
+	Operation    string    `json:"operation"`
+	LinkId       string    `json:"link_id,omitempty" db:"link_id"`
+	Date         time.Time `json:"date"`
 }
 
 func (t Transaction) ToTransactionDTO() TransactionDTO {
 	return TransactionDTO{
-		ID:        t.ID,
-		UserId:    t.UserId,
-		Amount:    t.Amount,
-		Operation: t.Operation,
-		Date:      t.Date.Format(time.DateTime),
+		ID:           t.ID,
+		UserId:       t.UserId,
+		Amount:       t.Amount,
+		Currency:     t.Currency,
+		BalanceAfter: t.BalanceAfter,
+		Operation:    t.Operation,
+		LinkId:       t.LinkId,
+		Date:         t.Date.Format(time.DateTime),
 	}
 }
 
 type TransactionDTO struct {
-	ID        int     `json:"id"`
-	UserId    int     `json:"user_id" db:"user_id"`
-	Amount    float32 `json:"amount"`
-	Operation string  `json:"operation"`
-	Date      string  `json:"date"`
+	ID           int     `json:"id"`
+	UserId       int     `json:"user_id" db:"user_id"`
+	Amount       float32 `json:"amount"`
+	Currency     string  `json:"currency"`
+	BalanceAfter float32 `json:"balance_after" db:"balance_after"`
+	Operation    string  `json:"operation"`
+	LinkId       string  `json:"link_id,omitempty" db:"link_id"`
+	Date         string  `json:"date"`
 }
 
 func (t TransactionDTO) ToTransaction() (Transaction, error) {
@@ -35,10 +44,13 @@ func (t TransactionDTO) ToTransaction() (Transaction, error) {
 	}
 
 	return Transaction{
-		ID:        t.ID,
-		UserId:    t.UserId,
-		Amount:    t.Amount,
-		Operation: t.Operation,
-		Date:      date,
+		ID:           t.ID,
+		UserId:       t.UserId,
+		Amount:       t.Amount,
+		Currency:     t.Currency,
+		BalanceAfter: t.BalanceAfter,
+		Operation:    t.Operation,
+		LinkId:       t.LinkId,
+		Date:         date,
 	}, nil
 }
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxVoucherBatchSize limits codes generated by one batch
+const maxVoucherBatchSize = 1000
+
+var (
+	// ErrVoucherNotFound is returned when no voucher has the code
+	ErrVoucherNotFound = errors.New("voucher not found")
+	// ErrVoucherNotRedeemable is returned when the voucher is expired, used up or already redeemed by the user
+	ErrVoucherNotRedeemable = errors.New("voucher can not be redeemed")
+)
+
+type VoucherBatchInput struct {
+	Amount   float32 `json:"amount"`
+	Currency string  `json:"currency"`
+	// Count is the number of codes generated
+	Count int `json:"count"`
+	// MaxUses is how many users may redeem every code, 1 by default makes single use codes
+	MaxUses   int        `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// VoucherBatch is a set of codes which credit the same amount on redemption. Codes are returned only when
+// the batch is generated, redemptions and their value are returned by report
+type VoucherBatch struct {
+	ID            int        `json:"id" db:"id"`
+	Amount        float32    `json:"amount" db:"amount"`
+	Currency      string     `json:"currency" db:"currency"`
+	Count         int        `json:"count" db:"codes"`
+	MaxUses       int        `json:"max_uses" db:"max_uses"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	Codes         []string   `json:"codes,omitempty" db:"-"`
+	Redemptions   int        `json:"redemptions" db:"redemptions"`
+	RedeemedValue float32    `json:"redeemed_value" db:"redeemed_value"`
+}
+
+// Voucher is a code of a batch locked for redemption
+type Voucher struct {
+	ID        int        `db:"id"`
+	BatchId   int        `db:"batch_id"`
+	Uses      int        `db:"uses"`
+	Amount    float32    `db:"amount"`
+	Currency  string     `db:"currency"`
+	MaxUses   int        `db:"max_uses"`
+	ExpiresAt *time.Time `db:"expires_at"`
+}
+
+type VoucherRedeemInput struct {
+	UserId int    `json:"user_id"`
+	Code   string `json:"code"`
+}
+
+type VoucherRedemption struct {
+	ID         int       `json:"id"`
+	VoucherId  int       `json:"voucher_id"`
+	BatchId    int       `json:"batch_id"`
+	UserId     int       `json:"user_id"`
+	Amount     float32   `json:"amount"`
+	Currency   string    `json:"currency"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+	// Balance is the wallet balance after the redemption
+	Balance float32 `json:"balance"`
+}
+
+// VoucherReport compares issued and redeemed value of voucher batches of one currency. Issued value counts
+// every use of every code, the part not redeemed is expired or still outstanding
+type VoucherReport struct {
+	Currency         string         `json:"currency"`
+	Batches          []VoucherBatch `json:"batches"`
+	IssuedValue      float32        `json:"issued_value"`
+	RedeemedValue    float32        `json:"redeemed_value"`
+	ExpiredValue     float32        `json:"expired_value"`
+	OutstandingValue float32        `json:"outstanding_value"`
+}
+
+// Validate checks voucher batch input, currency and max uses are normalized in place
+func (i *VoucherBatchInput) Validate() error {
+	if i.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	currency, err := ParseCurrency(i.Currency)
+	if err != nil {
+		return err
+	}
+
+	i.Currency = currency
+
+	if i.Count <= 0 {
+		return errors.New("count must be positive")
+	}
+
+	if i.Count > maxVoucherBatchSize {
+		return fmt.Errorf("%w: at most %d codes", ErrBatchTooLarge, maxVoucherBatchSize)
+	}
+
+	if i.MaxUses < 0 {
+		return errors.New("max uses must not be negative")
+	}
+
+	if i.MaxUses == 0 {
+		i.MaxUses = 1
+	}
+
+	return nil
+}
+
+func (i VoucherRedeemInput) Validate() error {
+	if i.UserId <= 0 {
+		return errors.New("incorrect user id")
+	}
+
+	if NormalizeVoucherCode(i.Code) == "" {
+		return errors.New("code is required")
+	}
+
+	return nil
+}
+
+// IssuedValue is the value credited if every code is redeemed by as many users as it may be
+func (b VoucherBatch) IssuedValue() float32 {
+	return b.Amount * float32(b.Count*b.MaxUses)
+}
+
+// Expired reports if codes of the batch can not be redeemed at now anymore
+func (b VoucherBatch) Expired(now time.Time) bool {
+	return b.ExpiresAt != nil && !b.ExpiresAt.After(now)
+}
+
+// Redeemable tells why the voucher can not be redeemed at now, nil means it can
+func (v Voucher) Redeemable(now time.Time) error {
+	if v.ExpiresAt != nil && !v.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: voucher is expired", ErrVoucherNotRedeemable)
+	}
+
+	if v.Uses >= v.MaxUses {
+		return fmt.Errorf("%w: voucher is used up", ErrVoucherNotRedeemable)
+	}
+
+	return nil
+}
+
+// RedemptionKey links the credit to the redemption
+func (r VoucherRedemption) RedemptionKey() string {
+	return fmt.Sprintf("voucher-%d", r.ID)
+}
+
+// NormalizeVoucherCode drops separators and case, so a code may be typed as it is printed or not
+func NormalizeVoucherCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// HashVoucherCode returns hex encoded sha256 of the normalized code, codes are stored only as hashes.
+// Codes are random enough to make salting unnecessary
+func HashVoucherCode(code string) string {
+	sum := sha256.Sum256([]byte(NormalizeVoucherCode(code)))
+	return hex.EncodeToString(sum[:])
+}
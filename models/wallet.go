@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BaseCurrency is used when no currency is specified
+const BaseCurrency = "EUR"
+
+type Wallet struct {
+	UserId   int     `json:"-" db:"user_id"`
+	Currency string  `json:"currency"`
+	Balance  float32 `json:"balance"`
+	// Promo is the part of balance which is promotional money, the rest is real money
+	Promo float32 `json:"promo,omitempty" db:"promo"`
+}
+
+// Balance lists all user`s wallets. Total is set only when balance is requested in some currency
+type Balance struct {
+	UserId   int      `json:"user_id"`
+	Wallets  []Wallet `json:"wallets"`
+	Currency string   `json:"currency,omitempty"`
+	Total    *float32 `json:"total,omitempty"`
+}
+
+// ParseCurrency validates ISO 4217 currency code, empty code means BaseCurrency
+func ParseCurrency(currency string) (string, error) {
+	if currency == "" {
+		return BaseCurrency, nil
+	}
+
+	currency = strings.ToUpper(currency)
+	if len(currency) != 3 {
+		return "", fmt.Errorf("incorrect currency %q", currency)
+	}
+
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("incorrect currency %q", currency)
+		}
+	}
+
+	return currency, nil
+}
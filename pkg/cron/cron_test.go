@@ -0,0 +1,88 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2023, 7, 25, 12, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		spec string
+		want time.Time
+	}{
+		{
+			name: "Every minute",
+			spec: "* * * * *",
+			want: time.Date(2023, 7, 25, 12, 31, 0, 0, time.UTC),
+		},
+		{
+			name: "Monthly",
+			spec: "0 9 1 * *",
+			want: time.Date(2023, 8, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Step",
+			spec: "*/15 * * * *",
+			want: time.Date(2023, 7, 25, 12, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "Weekdays",
+			spec: "0 8 * * 1-5",
+			want: time.Date(2023, 7, 26, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Sunday as 7",
+			spec: "0 0 * * 7",
+			want: time.Date(2023, 7, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Day of month or day of week",
+			spec: "0 0 31 * 6",
+			want: time.Date(2023, 7, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Next year",
+			spec: "0 0 1 1,6 *",
+			want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Never",
+			spec: "0 0 30 2 *",
+			want: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(from))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{spec: "* * * *", wantErr: "cron expression must have 5 fields, got 4"},
+		{spec: "60 * * * *", wantErr: `minute "60" is out of range 0-59`},
+		{spec: "* * 0 * *", wantErr: `day of month "0" is out of range 1-31`},
+		{spec: "* 5-2 * * *", wantErr: `hour "5-2" is out of range 0-23`},
+		{spec: "*/0 * * * *", wantErr: `incorrect minute "*/0"`},
+		{spec: "* * * jan *", wantErr: `incorrect month "jan"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
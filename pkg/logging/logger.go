@@ -6,6 +6,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 type Logger interface {
@@ -43,6 +44,35 @@ func InitLogger() (*logger, error) {
 	}, nil
 }
 
+// Config selects level and format of logs, format is console or json
+type Config struct {
+	Level  string
+	Format string
+}
+
+// NewLogger builds logger from config, json format is meant for production
+func NewLogger(cfg Config) (*logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	zapCfg := zap.NewDevelopmentConfig()
+	if cfg.Format == "json" {
+		zapCfg = zap.NewProductionConfig()
+	}
+	zapCfg.Level = zap.NewAtomicLevelAt(level)
+
+	l, err := zapCfg.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logger{
+		logger: l.Sugar(),
+	}, nil
+}
+
 func (l *logger) Fatal(msg string) {
 	l.logger.Fatal(ln(msg))
 }
@@ -0,0 +1,82 @@
+package rates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+)
+
+// DefaultURL is the exchangeratesapi.io endpoint with the latest rates
+const DefaultURL = "http://api.exchangeratesapi.io/v1/latest"
+
+// base is the only base currency available on the free exchangeratesapi.io plan
+const base = "EUR"
+
+// Provider returns exchange rates between currencies
+type Provider interface {
+	// Rate returns how much of to one unit of from costs
+	Rate(from, to string) (float32, error)
+}
+
+type ExchangeRatesAPI struct {
+	url       string
+	accessKey string
+	client    *http.Client
+}
+
+func NewExchangeRatesAPI(url, accessKey string) *ExchangeRatesAPI {
+	return &ExchangeRatesAPI{
+		url:       url,
+		accessKey: accessKey,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *ExchangeRatesAPI) Rate(from, to string) (float32, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	query := url.Values{}
+	query.Set("access_key", p.accessKey)
+	query.Set("base", base)
+	query.Set("symbols", strings.Join([]string{from, to}, ","))
+
+	resp, err := p.client.Get(p.url + "?" + query.Encode())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var get models.Response
+	if err := json.NewDecoder(resp.Body).Decode(&get); err != nil {
+		return 0, err
+	}
+
+	if !get.Success {
+		return 0, errors.New("failed to get exchange rates")
+	}
+
+	if get.Rates == nil {
+		get.Rates = make(map[string]float32)
+	}
+	get.Rates[base] = 1
+
+	fromRate, ok := get.Rates[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown currency %s", from)
+	}
+
+	toRate, ok := get.Rates[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown currency %s", to)
+	}
+
+	return toRate / fromRate, nil
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gavrylenkoIvan/balance-service/models"
+)
+
+// Publisher delivers outbox events to their consumers
+type Publisher interface {
+	// Publish delivers events in their order, either all of them are delivered or error is returned
+	Publish(events []models.OutboxEvent) error
+}
+
+// Client posts events as a JSON array to the url, any 2xx response means they are delivered.
+// Consumers deduplicate events by id, events may be delivered again after a failure or a replay
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) Publish(events []models.OutboxEvent) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,2 @@
+DROP INDEX transactions_user_id_date_idx;
+ALTER TABLE transactions DROP COLUMN balance_after;
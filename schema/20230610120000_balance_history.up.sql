@@ -0,0 +1,15 @@
+ALTER TABLE transactions ADD COLUMN balance_after float;
+
+UPDATE transactions t
+SET balance_after = s.running
+FROM (
+    SELECT id,
+           SUM(CASE WHEN operation LIKE 'Debit%' THEN -amount ELSE amount END)
+               OVER (PARTITION BY user_id ORDER BY date, id) AS running
+    FROM transactions
+) s
+WHERE t.id = s.id;
+
+ALTER TABLE transactions ALTER COLUMN balance_after SET NOT NULL;
+
+CREATE INDEX transactions_user_id_date_idx ON transactions (user_id, date);
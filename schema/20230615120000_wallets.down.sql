@@ -0,0 +1,10 @@
+DROP INDEX transactions_user_id_currency_date_idx;
+CREATE INDEX transactions_user_id_date_idx ON transactions (user_id, date);
+
+ALTER TABLE transactions DROP COLUMN currency;
+
+ALTER TABLE users ADD COLUMN balance float not null default 0;
+
+UPDATE users u SET balance = w.balance FROM wallets w WHERE w.user_id = u.id AND w.currency = 'EUR';
+
+DROP TABLE wallets;
@@ -0,0 +1,16 @@
+CREATE TABLE wallets
+(
+    user_id  int        not null references users (id),
+    currency varchar(3) not null,
+    balance  float      not null default 0,
+    primary key (user_id, currency)
+);
+
+INSERT INTO wallets (user_id, currency, balance) SELECT id, 'EUR', balance FROM users;
+
+ALTER TABLE users DROP COLUMN balance;
+
+ALTER TABLE transactions ADD COLUMN currency varchar(3) not null default 'EUR';
+
+DROP INDEX transactions_user_id_date_idx;
+CREATE INDEX transactions_user_id_currency_date_idx ON transactions (user_id, currency, date);
@@ -0,0 +1,4 @@
+DROP TABLE exchange_quotes;
+
+DROP INDEX transactions_link_id_idx;
+ALTER TABLE transactions DROP COLUMN link_id;
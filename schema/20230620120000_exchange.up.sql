@@ -0,0 +1,19 @@
+ALTER TABLE transactions ADD COLUMN link_id varchar(36) not null default '';
+CREATE INDEX transactions_link_id_idx ON transactions (link_id) WHERE link_id <> '';
+
+CREATE TABLE exchange_quotes
+(
+    id            uuid primary key default gen_random_uuid(),
+    user_id       int         not null references users (id),
+    from_currency varchar(3)  not null,
+    to_currency   varchar(3)  not null,
+    amount        float       not null,
+    rate          float       not null,
+    fee           float       not null,
+    to_amount     float       not null,
+    expires_at    timestamptz not null,
+    used_at       timestamptz
+);
+
+-- system account that collects exchange fees
+INSERT INTO users (id) VALUES (0) ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+CREATE TABLE idempotency_keys
+(
+    key        varchar(64) primary key,
+    balance    float       not null default 0,
+    created_at timestamptz not null default now()
+);
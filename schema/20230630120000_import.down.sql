@@ -0,0 +1,3 @@
+DROP TABLE import_jobs;
+
+ALTER TABLE transactions ALTER COLUMN operation TYPE varchar(40) USING left(operation, 40);
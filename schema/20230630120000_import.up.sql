@@ -0,0 +1,14 @@
+ALTER TABLE transactions ALTER COLUMN operation TYPE varchar(255);
+
+CREATE TABLE import_jobs
+(
+    id          uuid primary key     default gen_random_uuid(),
+    status      varchar(16) not null,
+    total       int         not null,
+    processed   int         not null default 0,
+    applied     int         not null default 0,
+    failed      int         not null default 0,
+    errors      jsonb       not null default '[]',
+    created_at  timestamptz not null default now(),
+    finished_at timestamptz
+);
@@ -0,0 +1,2 @@
+DROP TABLE adjustment_events;
+DROP TABLE adjustments;
@@ -0,0 +1,31 @@
+CREATE TABLE adjustments
+(
+    id             serial primary key,
+    user_id        int          not null references users (id),
+    direction      varchar(6)   not null,
+    amount         float        not null,
+    currency       varchar(3)   not null,
+    reason         varchar(200) not null,
+    ticket         varchar(64)  not null default '',
+    status         varchar(16)  not null,
+    created_by     varchar(64)  not null,
+    created_at     timestamptz  not null default now(),
+    reviewed_by    varchar(64)  not null default '',
+    reviewed_at    timestamptz,
+    review_comment varchar(255) not null default '',
+    balance_after  float
+);
+
+CREATE INDEX adjustments_status_idx ON adjustments (status);
+
+CREATE TABLE adjustment_events
+(
+    id            serial primary key,
+    adjustment_id int          not null references adjustments (id),
+    action        varchar(16)  not null,
+    actor         varchar(64)  not null,
+    comment       varchar(255) not null default '',
+    date          timestamptz  not null default now()
+);
+
+CREATE INDEX adjustment_events_adjustment_id_idx ON adjustment_events (adjustment_id);
@@ -0,0 +1,8 @@
+DROP TABLE account_status_history;
+
+ALTER TABLE users
+    DROP COLUMN status,
+    DROP COLUMN credits_blocked,
+    DROP COLUMN status_reason,
+    DROP COLUMN status_actor,
+    DROP COLUMN status_changed_at;
@@ -0,0 +1,19 @@
+ALTER TABLE users
+    ADD COLUMN status            varchar(16)  not null default 'active',
+    ADD COLUMN credits_blocked   boolean      not null default false,
+    ADD COLUMN status_reason     varchar(255) not null default '',
+    ADD COLUMN status_actor      varchar(64)  not null default '',
+    ADD COLUMN status_changed_at timestamptz;
+
+CREATE TABLE account_status_history
+(
+    id              serial primary key,
+    user_id         int          not null references users (id),
+    status          varchar(16)  not null,
+    credits_blocked boolean      not null default false,
+    reason          varchar(255) not null default '',
+    actor           varchar(64)  not null,
+    date            timestamptz  not null default now()
+);
+
+CREATE INDEX account_status_history_user_id_idx ON account_status_history (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE reconciliation_reports;
+
+ALTER TABLE transactions DROP COLUMN direction;
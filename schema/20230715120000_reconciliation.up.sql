@@ -0,0 +1,24 @@
+-- direction of the balance change: 1 for credits and -1 for debits, amount stays positive
+ALTER TABLE transactions ADD COLUMN direction smallint not null default 1;
+
+UPDATE transactions t
+SET direction = -1
+FROM (
+    SELECT id,
+           balance_after < COALESCE(lag(balance_after) OVER (PARTITION BY user_id, currency ORDER BY date, id), 0) AS debit
+    FROM transactions
+) s
+WHERE t.id = s.id AND s.debit;
+
+CREATE TABLE reconciliation_reports
+(
+    id          serial primary key,
+    status      varchar(16) not null,
+    wallets     int         not null,
+    mismatches  jsonb       not null default '[]',
+    created_by  varchar(64) not null,
+    started_at  timestamptz not null,
+    finished_at timestamptz not null,
+    approved_by varchar(64) not null default '',
+    approved_at timestamptz
+);
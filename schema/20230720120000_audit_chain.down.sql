@@ -0,0 +1,7 @@
+DROP INDEX transactions_user_id_currency_id_idx;
+
+ALTER TABLE wallets DROP COLUMN last_hash;
+
+ALTER TABLE transactions
+    DROP COLUMN prev_hash,
+    DROP COLUMN hash;
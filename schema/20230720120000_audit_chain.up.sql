@@ -0,0 +1,9 @@
+-- transactions of every wallet form a hash chain, wallet keeps the hash of its last transaction.
+-- Transactions written before are left unhashed and precede the chain
+ALTER TABLE transactions
+    ADD COLUMN prev_hash varchar(64) not null default '',
+    ADD COLUMN hash      varchar(64) not null default '';
+
+ALTER TABLE wallets ADD COLUMN last_hash varchar(64) not null default '';
+
+CREATE INDEX transactions_user_id_currency_id_idx ON transactions (user_id, currency, id);
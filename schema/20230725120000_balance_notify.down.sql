@@ -0,0 +1,3 @@
+DROP TRIGGER transactions_notify_balance_change ON transactions;
+
+DROP FUNCTION notify_balance_change();
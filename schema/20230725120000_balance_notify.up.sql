@@ -0,0 +1,11 @@
+-- every inserted transaction notifies balance_changes channel with "user_id:id",
+-- postgres delivers notifications only after commit and in commit order
+CREATE FUNCTION notify_balance_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('balance_changes', NEW.user_id || ':' || NEW.id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER transactions_notify_balance_change AFTER INSERT ON transactions
+    FOR EACH ROW EXECUTE FUNCTION notify_balance_change();
@@ -0,0 +1,37 @@
+CREATE TABLE schedules
+(
+    id              serial primary key,
+    type            varchar(16)  not null,
+    user_id         int          not null references users (id),
+    to_id           int          not null default 0,
+    amount          float        not null,
+    currency        varchar(3)   not null,
+    comment         varchar(255) not null default '',
+    cron            varchar(100) not null default '',
+    repeat_interval varchar(32)  not null default '',
+    status          varchar(16)  not null,
+    occurrence      timestamptz  not null,
+    next_run_at     timestamptz  not null,
+    attempts        int          not null default 0,
+    last_error      varchar(255) not null default '',
+    created_at      timestamptz  not null default now()
+);
+
+CREATE INDEX schedules_user_id_idx ON schedules (user_id);
+CREATE INDEX schedules_due_idx ON schedules (next_run_at) WHERE status = 'active';
+
+CREATE TABLE schedule_runs
+(
+    id            serial primary key,
+    schedule_id   int          not null references schedules (id),
+    occurrence    timestamptz  not null,
+    attempt       int          not null,
+    status        varchar(16)  not null,
+    error         varchar(255) not null default '',
+    balance_after float,
+    date          timestamptz  not null default now()
+);
+
+CREATE INDEX schedule_runs_schedule_id_idx ON schedule_runs (schedule_id);
+-- an occurrence is executed at most once, a second successful run fails the unit of work moving the money
+CREATE UNIQUE INDEX schedule_runs_occurrence_idx ON schedule_runs (schedule_id, occurrence) WHERE status = 'succeeded';
@@ -0,0 +1,3 @@
+DROP TABLE subscription_events;
+DROP TABLE subscriptions;
+DROP TABLE plans;
@@ -0,0 +1,44 @@
+CREATE TABLE plans
+(
+    id         serial primary key,
+    name       varchar(100) not null,
+    price      float        not null,
+    currency   varchar(3)   not null,
+    period     varchar(8)   not null,
+    trial_days int          not null default 0,
+    created_at timestamptz  not null default now()
+);
+
+CREATE TABLE subscriptions
+(
+    id                   serial primary key,
+    user_id              int          not null references users (id),
+    plan_id              int          not null references plans (id),
+    status               varchar(16)  not null,
+    period_start         timestamptz  not null,
+    period_end           timestamptz  not null,
+    next_attempt_at      timestamptz  not null,
+    cancel_at_period_end boolean      not null default false,
+    attempts             int          not null default 0,
+    last_error           varchar(255) not null default '',
+    created_at           timestamptz  not null default now(),
+    cancelled_at         timestamptz
+);
+
+CREATE INDEX subscriptions_user_id_idx ON subscriptions (user_id);
+CREATE INDEX subscriptions_due_idx ON subscriptions (next_attempt_at) WHERE status <> 'cancelled';
+
+CREATE TABLE subscription_events
+(
+    id              serial primary key,
+    subscription_id int          not null references subscriptions (id),
+    user_id         int          not null,
+    plan_id         int          not null,
+    type            varchar(16)  not null,
+    amount          float        not null default 0,
+    currency        varchar(3)   not null,
+    error           varchar(255) not null default '',
+    date            timestamptz  not null default now()
+);
+
+CREATE INDEX subscription_events_subscription_id_idx ON subscription_events (subscription_id);
@@ -0,0 +1,28 @@
+CREATE TABLE spending_limits
+(
+    id              serial primary key,
+    user_id         int         not null references users (id),
+    operation       varchar(16) not null,
+    period          varchar(16) not null,
+    counterparty_id int         not null default 0,
+    amount          float       not null,
+    currency        varchar(3)  not null,
+    created_at      timestamptz not null default now()
+);
+
+-- a limit is replaced by setting it again
+CREATE UNIQUE INDEX spending_limits_key_idx ON spending_limits (user_id, operation, period, counterparty_id, currency);
+
+-- debits and transfers counted against limits, usage is summed over rolling windows
+CREATE TABLE spends
+(
+    id              serial primary key,
+    user_id         int         not null,
+    operation       varchar(16) not null,
+    counterparty_id int         not null default 0,
+    amount          float       not null,
+    currency        varchar(3)  not null,
+    date            timestamptz not null default now()
+);
+
+CREATE INDEX spends_user_id_date_idx ON spends (user_id, date);
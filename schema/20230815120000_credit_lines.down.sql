@@ -0,0 +1,6 @@
+DROP TABLE overdraft_charges;
+DROP TABLE holds;
+
+ALTER TABLE wallets
+    DROP COLUMN held,
+    DROP COLUMN credit_limit;
@@ -0,0 +1,29 @@
+-- wallets may go below zero down to -credit_limit, held money is not available for debits
+ALTER TABLE wallets
+    ADD COLUMN credit_limit float not null default 0,
+    ADD COLUMN held         float not null default 0;
+
+CREATE TABLE holds
+(
+    id         serial primary key,
+    user_id    int          not null references users (id),
+    amount     float        not null,
+    currency   varchar(3)   not null,
+    comment    varchar(255) not null default '',
+    status     varchar(16)  not null,
+    created_at timestamptz  not null default now(),
+    closed_at  timestamptz
+);
+
+CREATE INDEX holds_user_id_idx ON holds (user_id);
+
+-- overdraft of a wallet is charged at most once a day
+CREATE TABLE overdraft_charges
+(
+    user_id  int        not null references users (id),
+    currency varchar(3) not null,
+    date     date       not null,
+    balance  float      not null,
+    amount   float      not null,
+    primary key (user_id, currency, date)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE exchange_quotes DROP COLUMN from_fee;
+DROP TABLE fee_rules;
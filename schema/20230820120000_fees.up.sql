@@ -0,0 +1,19 @@
+-- fee rules of transfers and exchanges, user_id 0 applies to every client, empty currency to every currency
+-- and max_amount 0 leaves the amount bracket open
+CREATE TABLE fee_rules
+(
+    id         serial primary key,
+    operation  varchar(16) not null,
+    user_id    int         not null default 0,
+    currency   varchar(3)  not null default '',
+    min_amount float       not null default 0,
+    max_amount float       not null default 0,
+    rate       float       not null default 0,
+    fixed      float       not null default 0,
+    created_at timestamptz not null default now()
+);
+
+CREATE INDEX fee_rules_operation_idx ON fee_rules (operation);
+
+-- fee charged by fee rules in the source currency on top of the exchanged amount
+ALTER TABLE exchange_quotes ADD COLUMN from_fee float not null default 0;
@@ -0,0 +1,3 @@
+DROP TABLE promo_grants;
+
+ALTER TABLE wallets DROP COLUMN promo;
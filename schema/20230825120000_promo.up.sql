@@ -0,0 +1,19 @@
+-- promo is the part of balance granted as promotional money, it is spent only by purchases and never transferred
+ALTER TABLE wallets ADD COLUMN promo float not null default 0;
+
+CREATE TABLE promo_grants
+(
+    id          serial primary key,
+    user_id     int         not null references users (id),
+    amount      float       not null,
+    remaining   float       not null,
+    currency    varchar(3)  not null,
+    campaign_id varchar(64) not null,
+    status      varchar(16) not null,
+    expires_at  timestamptz not null,
+    created_at  timestamptz not null default now(),
+    expired_at  timestamptz
+);
+
+CREATE INDEX promo_grants_user_id_idx ON promo_grants (user_id, currency);
+CREATE INDEX promo_grants_expires_at_idx ON promo_grants (expires_at) WHERE status = 'active';
@@ -0,0 +1,3 @@
+DROP TABLE voucher_redemptions;
+DROP TABLE vouchers;
+DROP TABLE voucher_batches;
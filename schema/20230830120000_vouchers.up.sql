@@ -0,0 +1,33 @@
+-- codes of vouchers are known only to their holders, the service keeps sha256 of every code
+CREATE TABLE voucher_batches
+(
+    id         serial primary key,
+    amount     float       not null,
+    currency   varchar(3)  not null,
+    codes      int         not null,
+    max_uses   int         not null,
+    expires_at timestamptz,
+    created_at timestamptz not null default now()
+);
+
+CREATE TABLE vouchers
+(
+    id        serial primary key,
+    batch_id  int         not null references voucher_batches (id),
+    code_hash varchar(64) not null unique,
+    uses      int         not null default 0
+);
+
+CREATE TABLE voucher_redemptions
+(
+    id          serial primary key,
+    voucher_id  int         not null references vouchers (id),
+    batch_id    int         not null references voucher_batches (id),
+    user_id     int         not null references users (id),
+    amount      float       not null,
+    currency    varchar(3)  not null,
+    redeemed_at timestamptz not null default now(),
+    unique (voucher_id, user_id)
+);
+
+CREATE INDEX voucher_redemptions_batch_id_idx ON voucher_redemptions (batch_id);
@@ -0,0 +1,2 @@
+DROP TABLE escrow_events;
+DROP TABLE escrows;
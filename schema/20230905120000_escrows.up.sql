@@ -0,0 +1,33 @@
+CREATE TABLE escrows
+(
+    id           serial primary key,
+    sender_id    int          not null references users (id),
+    recipient_id int          not null references users (id),
+    amount       float        not null,
+    currency     varchar(3)   not null,
+    description  varchar(255) not null default '',
+    status       varchar(16)  not null,
+    expires_at   timestamptz  not null,
+    created_at   timestamptz  not null default now(),
+    closed_at    timestamptz
+);
+
+CREATE INDEX escrows_sender_id_idx ON escrows (sender_id);
+CREATE INDEX escrows_recipient_id_idx ON escrows (recipient_id);
+CREATE INDEX escrows_expires_at_idx ON escrows (expires_at) WHERE status = 'held';
+
+CREATE TABLE escrow_events
+(
+    id        serial primary key,
+    escrow_id int         not null references escrows (id),
+    type      varchar(16) not null,
+    -- user_id is the party which made the change, 0 when the service did it
+    user_id   int         not null default 0,
+    status    varchar(16) not null,
+    date      timestamptz not null default now()
+);
+
+CREATE INDEX escrow_events_escrow_id_idx ON escrow_events (escrow_id);
+
+-- system account that holds escrowed money until it is released or refunded
+INSERT INTO users (id) VALUES (-1) ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+DROP TRIGGER transactions_outbox_balance_change ON transactions;
+
+DROP FUNCTION outbox_balance_change();
+
+DROP TABLE outbox_events;
@@ -0,0 +1,31 @@
+-- outbox keeps events for external consumers, they are written in the transaction of the change
+-- and published by the service afterwards, so a committed change is never lost for consumers
+CREATE TABLE outbox_events
+(
+    id           bigserial primary key,
+    type         varchar(64) not null,
+    -- key orders events of one aggregate, e.g. user id for balance changes
+    key          varchar(64) not null,
+    payload      jsonb       not null,
+    created_at   timestamptz not null default now(),
+    published_at timestamptz,
+    attempts     int         not null default 0,
+    last_error   text        not null default ''
+);
+
+CREATE INDEX outbox_events_pending_idx ON outbox_events (id) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_created_at_idx ON outbox_events (created_at);
+
+-- every inserted transaction adds balance.changed event with the transaction
+CREATE FUNCTION outbox_balance_change() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO outbox_events (type, key, payload) VALUES ('balance.changed', NEW.user_id::text, jsonb_build_object(
+        'transaction_id', NEW.id, 'user_id', NEW.user_id, 'amount', NEW.amount * NEW.direction,
+        'currency', NEW.currency, 'balance_after', NEW.balance_after, 'operation', NEW.operation,
+        'link_id', NEW.link_id, 'date', NEW.date));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER transactions_outbox_balance_change AFTER INSERT ON transactions
+    FOR EACH ROW EXECUTE FUNCTION outbox_balance_change();
@@ -0,0 +1,21 @@
+-- Test users for development databases, every wallet is opened by a single unhashed transaction,
+-- so reconciliation and chain verification pass. Applying the fixture again changes nothing
+INSERT INTO users (id) VALUES (1), (2), (3), (4), (5), (6), (7) ON CONFLICT DO NOTHING;
+
+SELECT setval('users_id_seq', (SELECT MAX(id) FROM users));
+
+INSERT INTO wallets (user_id, currency, balance)
+VALUES (1, 'EUR', 4.13),
+       (2, 'EUR', 32),
+       (3, 'EUR', 11.321),
+       (4, 'EUR', 41.12),
+       (5, 'EUR', 1.32),
+       (6, 'EUR', 541.32),
+       (7, 'EUR', 339.012)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO transactions (user_id, amount, currency, operation, date, balance_after)
+SELECT w.user_id, w.balance, w.currency, 'Top-up by fixture', NOW(), w.balance
+FROM wallets w
+WHERE w.user_id BETWEEN 1 AND 7
+  AND NOT EXISTS(SELECT 1 FROM transactions t WHERE t.user_id = w.user_id AND t.currency = w.currency);
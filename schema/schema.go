@@ -0,0 +1,12 @@
+// Package schema contains database migrations, they are embedded into the service and applied by it
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var Migrations embed.FS
+
+// DevFixture fills development database with test users, it must not be used in production
+//
+//go:embed fixtures/dev.sql
+var DevFixture string